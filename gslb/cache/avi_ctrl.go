@@ -15,13 +15,16 @@
 package cache
 
 import (
+	"encoding/json"
 	"errors"
+	"net/url"
 	"os"
 	"sync"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 
 	"github.com/avinetworks/sdk/go/clients"
+	"github.com/avinetworks/sdk/go/models"
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
 )
 
@@ -153,3 +156,39 @@ func GetGslbLeaderUuid(client *clients.AviClient) (string, error) {
 		leaderUUID)
 	return leaderUUID, nil
 }
+
+// GetTenantUuid returns the uuid of the Avi tenant with name tenant, and false if the tenant doesn't
+// exist.
+func GetTenantUuid(client *clients.AviClient, tenant string) (string, bool, error) {
+	uri := "/api/tenant?name=" + url.QueryEscape(tenant)
+	result, err := AviGetCollectionRaw(client, uri)
+	if err != nil {
+		gslbutils.Logf("object: Tenant, msg: tenant get URI %s returned error %s", uri, err.Error())
+		return "", false, err
+	}
+	if result.Count == 0 {
+		return "", false, nil
+	}
+	var tenants []models.Tenant
+	if err := json.Unmarshal(result.Results, &tenants); err != nil {
+		return "", false, errors.New("failed to unmarshal tenant data, err: " + err.Error())
+	}
+	if len(tenants) == 0 || tenants[0].UUID == nil {
+		return "", false, errors.New("uuid not present in the tenant response")
+	}
+	return *tenants[0].UUID, true, nil
+}
+
+// ValidateClusterTenants verifies that the Avi tenants of the member clusters exist in the
+// controller, the clusters with unknown tenants fall back to the default tenant.
+func ValidateClusterTenants() {
+	aviRestClientPool := SharedAviClients()
+	if len(aviRestClientPool.AviClient) < 1 {
+		gslbutils.Errf("no avi clients initialized, can't validate the tenants")
+		return
+	}
+	aviClient := aviRestClientPool.AviClient[0]
+	gslbutils.ValidateClusterTenants(func(tenant string) (string, bool, error) {
+		return GetTenantUuid(aviClient, tenant)
+	})
+}
@@ -18,14 +18,11 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 
-	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
-
 	"github.com/avinetworks/sdk/go/clients"
 	"github.com/avinetworks/sdk/go/models"
 	"github.com/avinetworks/sdk/go/session"
@@ -178,11 +175,17 @@ func (h *AviHmCache) AviHmObjCachePopulate(client *clients.AviClient, hmname ...
 				continue
 			}
 
-			k := TenantName{Tenant: utils.ADMIN_NS, Name: *hm.Name}
-			cksum := gslbutils.GetGSLBHmChecksum(*hm.Name, *hm.Type, *hm.MonitorPort)
+			tenant := getTenantFromRef(hm.TenantRef)
+			k := TenantName{Tenant: tenant, Name: *hm.Name}
+			var serverName string
+			if hm.HTTPSMonitor != nil && hm.HTTPSMonitor.SslAttributes != nil &&
+				hm.HTTPSMonitor.SslAttributes.ServerName != nil {
+				serverName = *hm.HTTPSMonitor.SslAttributes.ServerName
+			}
+			cksum := gslbutils.GetGSLBHmChecksum(*hm.Name, *hm.Type, *hm.MonitorPort, serverName)
 			hmCacheObj := AviHmObj{
 				Name:             *hm.Name,
-				Tenant:           utils.ADMIN_NS,
+				Tenant:           tenant,
 				UUID:             *hm.UUID,
 				Port:             *hm.MonitorPort,
 				CloudConfigCksum: cksum,
@@ -349,6 +352,15 @@ func AviGetCollectionRaw(client *clients.AviClient, uri string) (session.AviColl
 	return result, nil
 }
 
+// getTenantFromRef returns the tenant of an Avi object from its tenant ref, the default tenant if
+// the object has no tenant ref.
+func getTenantFromRef(tenantRef *string) string {
+	if tenantRef == nil || *tenantRef == "" {
+		return gslbutils.DefaultTenant
+	}
+	return gslbutils.GetTenantFromRef(*tenantRef)
+}
+
 func parseGSObject(c *AviCache, gsObj models.GslbService, gsname []string) {
 	var name, uuid string
 	if gsObj.Name == nil || gsObj.UUID == nil {
@@ -369,10 +381,11 @@ func parseGSObject(c *AviCache, gsObj models.GslbService, gsname []string) {
 			return
 		}
 	}
-	k := TenantName{Tenant: utils.ADMIN_NS, Name: name}
+	tenant := getTenantFromRef(gsObj.TenantRef)
+	k := TenantName{Tenant: tenant, Name: name}
 	gsCacheObj := AviGSCache{
 		Name:               name,
-		Tenant:             utils.ADMIN_NS,
+		Tenant:             tenant,
 		Uuid:               uuid,
 		Members:            gsMembers,
 		K8sObjects:         memberObjs,
@@ -394,22 +407,18 @@ func parseDescription(description string) ([]string, error) {
 	}
 	for _, obj := range objList {
 		seg := strings.Split(obj, "/")
-		switch seg[0] {
-		case gdpv1alpha1.IngressObj:
-			if len(seg) != 5 {
-				return []string{}, errors.New("description field has malformed ingress: " + description)
-			}
-		case gdpv1alpha1.LBSvcObj:
-			if len(seg) != 4 {
-				return []string{}, errors.New("description field has malformed LB service: " + description)
-			}
-		case gdpv1alpha1.RouteObj:
-			if len(seg) != 4 {
-				return []string{}, errors.New("description field has malformed route: " + description)
-			}
-		default:
+		handlers, ok := gslbutils.GetObjTypeHandlers(seg[0])
+		if !ok {
 			return []string{}, errors.New("description has unrecognised objects: " + description)
 		}
+		// object type, cluster, namespace and name, followed by the hostname for the host scoped types
+		segLen := 4
+		if handlers.HostScoped {
+			segLen = 5
+		}
+		if len(seg) != segLen {
+			return []string{}, errors.New("description field has malformed " + handlers.Kind + ": " + description)
+		}
 	}
 	return objList, nil
 }
@@ -462,8 +471,16 @@ func GetDetailsFromAviGSLBFormatted(gsObj models.GslbService) (uint32, []GSMembe
 		hms = append(hms, hm)
 	}
 
+	var poolAlgorithm string
 	for _, val := range groups {
 		group := *val
+		if group.Algorithm != nil {
+			poolAlgorithm = *group.Algorithm
+		}
+		priority := int32(gslbutils.DefaultPriority)
+		if group.Priority != nil {
+			priority = *group.Priority
+		}
 		members := group.Members
 		if len(members) == 0 {
 			gslbutils.Warnf("no members in gslb pool: %v", group)
@@ -471,7 +488,13 @@ func GetDetailsFromAviGSLBFormatted(gsObj models.GslbService) (uint32, []GSMembe
 		}
 		for _, memberVal := range members {
 			member := *memberVal
-			ipAddr := *member.IP.Addr
+			var ipAddr string
+			if member.Fqdn != nil && *member.Fqdn != "" {
+				// members with an fqdn are identified by the fqdn, the IP is resolved by the controller
+				ipAddr = *member.Fqdn
+			} else if member.IP != nil && member.IP.Addr != nil {
+				ipAddr = *member.IP.Addr
+			}
 			if ipAddr == "" {
 				gslbutils.Warnf("couldn't get member addr: %v", member)
 				continue
@@ -481,7 +504,11 @@ func GetDetailsFromAviGSLBFormatted(gsObj models.GslbService) (uint32, []GSMembe
 				gslbutils.Warnf("invalid weight present, assigning 0: %v", member)
 				weight = 0
 			}
-			ipList = append(ipList, ipAddr+"-"+strconv.Itoa(int(weight)))
+			if member.Enabled != nil && !*member.Enabled {
+				// disabled members are the members with a weight of 0
+				weight = 0
+			}
+			ipList = append(ipList, gslbutils.GetGSMemberKey(ipAddr, weight, priority))
 			gsMember := GSMember{
 				IPAddr: ipAddr,
 				Weight: weight,
@@ -494,7 +521,8 @@ func GetDetailsFromAviGSLBFormatted(gsObj models.GslbService) (uint32, []GSMembe
 		gslbutils.Errf("object: GSLBService, msg: error while parsing description field: %s", err)
 	}
 	// calculate the checksum
-	checksum := gslbutils.GetGSLBServiceChecksum(ipList, domainList, memberObjs, hms)
+	checksum := gslbutils.GetGSLBServiceChecksum(ipList, domainList, memberObjs, hms) +
+		gslbutils.GetGSLBServicePropsChecksum(gsObj.TTL, poolAlgorithm)
 	return checksum, gsMembers, memberObjs, hms, nil
 }
 
@@ -538,12 +566,20 @@ func GetDetailsFromAviGSLB(gslbSvcMap map[string]interface{}) (uint32, []GSMembe
 		gslbutils.Debugf("gslbsvcmap: %v, health_monitor_refs absent in gslb service", gslbSvcMap)
 	}
 
+	var poolAlgorithm string
 	for _, val := range groups {
 		group, ok := val.(map[string]interface{})
 		if !ok {
 			gslbutils.Warnf("couldn't parse group: %v", val)
 			continue
 		}
+		if algorithm, ok := group["algorithm"].(string); ok {
+			poolAlgorithm = algorithm
+		}
+		priority := int32(gslbutils.DefaultPriority)
+		if priorityVal, ok := group["priority"].(float64); ok {
+			priority = int32(priorityVal)
+		}
 		members, ok := group["members"].([]interface{})
 		if !ok {
 			gslbutils.Warnf("couldn't parse group members: %v", group)
@@ -555,23 +591,30 @@ func GetDetailsFromAviGSLB(gslbSvcMap map[string]interface{}) (uint32, []GSMembe
 				gslbutils.Warnf("couldn't parse member: %v", memberVal)
 				continue
 			}
-			ip, ok := member["ip"].(map[string]interface{})
-			if !ok {
-				gslbutils.Warnf("couldn't parse IP: %v", member)
-				continue
-			}
-			ipAddr, ok := ip["addr"].(string)
-			if !ok {
-				gslbutils.Warnf("couldn't parse addr: %v", member)
-				continue
+			ipAddr, ok := member["fqdn"].(string)
+			if !ok || ipAddr == "" {
+				ip, ok := member["ip"].(map[string]interface{})
+				if !ok {
+					gslbutils.Warnf("couldn't parse IP: %v", member)
+					continue
+				}
+				ipAddr, ok = ip["addr"].(string)
+				if !ok {
+					gslbutils.Warnf("couldn't parse addr: %v", member)
+					continue
+				}
 			}
 			weight, ok := member["ratio"].(float64)
 			if !ok {
 				gslbutils.Warnf("couldn't parse the weight, assigning 0: %v", member)
 				weight = 0
 			}
+			if enabled, ok := member["enabled"].(bool); ok && !enabled {
+				// disabled members are the members with a weight of 0
+				weight = 0
+			}
 			weightI := int32(weight)
-			ipList = append(ipList, ipAddr+"-"+strconv.Itoa(int(weightI)))
+			ipList = append(ipList, gslbutils.GetGSMemberKey(ipAddr, weightI, priority))
 			gsMember := GSMember{
 				IPAddr: ipAddr,
 				Weight: weightI,
@@ -583,8 +626,14 @@ func GetDetailsFromAviGSLB(gslbSvcMap map[string]interface{}) (uint32, []GSMembe
 	if err != nil {
 		gslbutils.Errf("object: GSLBService, msg: error while parsing description field: %s", err)
 	}
+	var ttl *int32
+	if ttlVal, ok := gslbSvcMap["ttl"].(float64); ok {
+		ttlI := int32(ttlVal)
+		ttl = &ttlI
+	}
 	// calculate the checksum
-	checksum := gslbutils.GetGSLBServiceChecksum(ipList, domainList, memberObjs, hms)
+	checksum := gslbutils.GetGSLBServiceChecksum(ipList, domainList, memberObjs, hms) +
+		gslbutils.GetGSLBServicePropsChecksum(ttl, poolAlgorithm)
 	return checksum, gsMembers, memberObjs, hms, nil
 }
 
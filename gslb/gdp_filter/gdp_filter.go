@@ -33,14 +33,41 @@ func ApplyFilter(obj interface{}, cname string) bool {
 		gslbutils.Warnf("cname: %s, msg: not a meta object, returning", cname)
 		return false
 	}
+	// the objects with the deny label are rejected before any of the selectors are evaluated
+	if k8sobjects.RejectIfExplicitlyDisabled(obj) {
+		gslbutils.RecordFilterDecision(false)
+		gslbutils.RecordDisabledObj()
+		return false
+	}
+	// the objects without a hostname can't be a part of any GSLB service
+	if k8sobjects.RejectIfEmptyHostname(obj) {
+		gslbutils.RecordFilterDecision(false)
+		return false
+	}
+	// the private addresses must not be advertised, if the non-routable IP filter is enabled
+	if k8sobjects.RejectIfNonRoutableIP(obj) {
+		gslbutils.RecordFilterDecision(false)
+		return false
+	}
+	if policyCheck := gf.CheckPolicy(); !policyCheck.Passed {
+		gslbutils.RecordFilterDecision(false)
+		k8sobjects.NotifyFilterDecision(obj, false, policyCheck.Reason())
+		return false
+	}
 
 	// First see, if there's a namespace filter set for this object's namespace, if not, apply
-	// the global filter.
+	// the global filter. The lock is released before applying the filter, since the object's
+	// ApplyFilter takes the same lock.
 	gf.GlobalLock.RLock()
-	defer gf.GlobalLock.RUnlock()
+	noFilter := gf.AppFilter == nil && gf.NSFilter == nil && gf.SelfScopeNamespace == ""
+	gf.GlobalLock.RUnlock()
 
-	if gf.AppFilter == nil && gf.NSFilter == nil {
+	if noFilter {
+		gslbutils.RecordFilterDecision(false)
+		k8sobjects.NotifyFilterDecision(obj, false, "rejected because no appSelector or namespaceSelector")
 		return false
 	}
-	return metaobj.ApplyFilter()
+	accepted := metaobj.ApplyFilter()
+	gslbutils.RecordFilterDecision(accepted)
+	return accepted
 }
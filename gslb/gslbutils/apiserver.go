@@ -22,7 +22,13 @@ import (
 var amkoAPI *api.ApiServer
 
 func InitAmkoAPIServer() {
-	amkoAPIServer := api.NewServer("8080", []models.ApiModel{})
+	apiModels := []models.ApiModel{&ReadinessModel{}, &FilterExplainModel{}, &MetricsModel{}, &RejectedObjsModel{},
+		&FilterViewModel{}}
+	// the resync endpoint is unauthenticated, so it's served only if explicitly enabled
+	if ResyncAPIEnabled() {
+		apiModels = append(apiModels, &ForceResyncModel{})
+	}
+	amkoAPIServer := api.NewServer("8080", apiModels)
 	amkoAPIServer.InitApi()
 	amkoAPI = amkoAPIServer
 }
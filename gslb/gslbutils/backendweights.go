@@ -0,0 +1,94 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"time"
+
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+)
+
+const (
+	// DefaultWeightRecomputeInterval is the default interval (in seconds) at which the weights of
+	// the clusters are recomputed in the backends weight mode
+	DefaultWeightRecomputeInterval = 60
+	// MinWeightRecomputeInterval and MaxWeightRecomputeInterval bound the recompute interval
+	MinWeightRecomputeInterval = 10
+	MaxWeightRecomputeInterval = 3600
+)
+
+// GetWeightRecomputeInterval returns the interval at which the weights of the clusters are
+// recomputed in the backends weight mode, as set in the GDP object, or the default interval.
+func (gf *GlobalFilter) GetWeightRecomputeInterval() time.Duration {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	if gf.WeightRecomputeInterval != nil {
+		return time.Duration(*gf.WeightRecomputeInterval) * time.Second
+	}
+	return DefaultWeightRecomputeInterval * time.Second
+}
+
+// SetBackendCounts replaces the number of ready backends of the clusters, computed for the
+// backends weight mode. The clusters without a count fall back to the traffic splits. Returns
+// true if the counts changed.
+func (gf *GlobalFilter) SetBackendCounts(counts map[string]int32) bool {
+	gf.GlobalLock.Lock()
+	defer gf.GlobalLock.Unlock()
+	changed := len(counts) != len(gf.backendCounts)
+	for cname, count := range counts {
+		if oldCount, ok := gf.backendCounts[cname]; !ok || oldCount != count {
+			changed = true
+		}
+	}
+	gf.backendCounts = make(map[string]int32, len(counts))
+	for cname, count := range counts {
+		gf.backendCounts[cname] = count
+	}
+	return changed
+}
+
+// GetBackendCount returns the number of ready backends of cluster cname, as last computed for the
+// backends weight mode.
+func (gf *GlobalFilter) GetBackendCount(cname string) (int32, bool) {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	count, ok := gf.backendCounts[cname]
+	return count, ok
+}
+
+// getBackendWeight returns the ratio of cluster cname in the backends weight mode, proportional to
+// its ready backends, with the cluster having the most backends getting MaxRatio. A cluster with
+// ready backends gets at least a ratio of 1, and a cluster without any gets 0, its members are
+// disabled. Returns false if the backends of the cluster are not known, the callers must lock the
+// filter.
+func (gf *GlobalFilter) getBackendWeight(cname string) (int32, bool) {
+	if gf.WeightMode != gdpv1alpha1.WeightModeBackends {
+		return 0, false
+	}
+	count, ok := gf.backendCounts[cname]
+	if !ok {
+		return 0, false
+	}
+	if count <= 0 {
+		return 0, true
+	}
+	var maxCount int32
+	for _, c := range gf.backendCounts {
+		if c > maxCount {
+			maxCount = c
+		}
+	}
+	return (count*MaxRatio + maxCount - 1) / maxCount, true
+}
@@ -0,0 +1,62 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+)
+
+// ChecksumBuilder builds a checksum out of a set of components, each component made of one or more
+// fields. The checksum doesn't depend on the order in which the components are added, but the
+// fields of a component, and the boundaries between them, are a part of the checksum. So, unlike a
+// sum of the hashes, cluster "c1" with weight 11 and cluster "c11" with weight 1 don't collide.
+type ChecksumBuilder struct {
+	components []string
+}
+
+// writeLengthPrefixed writes s prefixed by its length, so that the boundaries of s can't be
+// confused with the contents of the neighbouring strings.
+func writeLengthPrefixed(sb *strings.Builder, s string) {
+	sb.WriteString(strconv.Itoa(len(s)))
+	sb.WriteByte(':')
+	sb.WriteString(s)
+}
+
+// Add adds a component made of fields to the checksum.
+func (b *ChecksumBuilder) Add(fields ...string) {
+	var sb strings.Builder
+	for _, field := range fields {
+		writeLengthPrefixed(&sb, field)
+	}
+	b.components = append(b.components, sb.String())
+}
+
+// Sum returns the checksum of the components added so far, 0 if no components were added.
+func (b *ChecksumBuilder) Sum() uint32 {
+	if len(b.components) == 0 {
+		return 0
+	}
+	components := append([]string{}, b.components...)
+	sort.Strings(components)
+	var sb strings.Builder
+	for _, component := range components {
+		writeLengthPrefixed(&sb, component)
+	}
+	return utils.Hash(sb.String())
+}
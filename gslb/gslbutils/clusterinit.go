@@ -0,0 +1,107 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ClusterUnreachableFail fails the initialization of the GSLB config if a member cluster is
+	// unreachable at startup
+	ClusterUnreachableFail = "fail"
+	// ClusterUnreachableDegrade continues with the reachable member clusters at startup, and
+	// retries the unreachable ones in the background
+	ClusterUnreachableDegrade = "degrade"
+
+	// ClusterRetryInitialDelay and ClusterRetryMaxDelay bound the backoff between the retries for
+	// an unreachable member cluster, the delay is doubled after every failed retry.
+	ClusterRetryInitialDelay = 10 * time.Second
+	ClusterRetryMaxDelay     = 5 * time.Minute
+)
+
+var clusterUnreachablePolicy = ClusterUnreachableFail
+var clusterUnreachableLock sync.RWMutex
+
+// SetClusterUnreachablePolicy sets the behaviour for the member clusters which are unreachable at
+// startup, policy must be one of ClusterUnreachableFail or ClusterUnreachableDegrade.
+func SetClusterUnreachablePolicy(policy string) error {
+	policy = strings.ToLower(policy)
+	if policy != ClusterUnreachableFail && policy != ClusterUnreachableDegrade {
+		return errors.New("invalid cluster unreachable policy " + policy)
+	}
+	clusterUnreachableLock.Lock()
+	defer clusterUnreachableLock.Unlock()
+	clusterUnreachablePolicy = policy
+	return nil
+}
+
+// GetClusterUnreachablePolicy returns the behaviour for the member clusters which are unreachable
+// at startup.
+func GetClusterUnreachablePolicy() string {
+	clusterUnreachableLock.RLock()
+	defer clusterUnreachableLock.RUnlock()
+	return clusterUnreachablePolicy
+}
+
+// NextClusterRetryDelay returns the delay before the next retry for an unreachable member cluster,
+// after a retry with delay failed.
+func NextClusterRetryDelay(delay time.Duration) time.Duration {
+	if delay <= 0 {
+		return ClusterRetryInitialDelay
+	}
+	delay *= 2
+	if delay > ClusterRetryMaxDelay {
+		return ClusterRetryMaxDelay
+	}
+	return delay
+}
+
+// memberClusters are the cluster contexts of the member clusters in the GSLBConfig object, whether
+// initialized or not.
+var memberClusters = struct {
+	clusters []string
+	lock     sync.RWMutex
+}{}
+
+// SetMemberClusters sets the cluster contexts of the member clusters in the GSLBConfig object.
+func SetMemberClusters(clusters []string) {
+	memberClusters.lock.Lock()
+	defer memberClusters.lock.Unlock()
+	memberClusters.clusters = append([]string{}, clusters...)
+}
+
+// IsMemberCluster returns true if cc is a member cluster in the GSLBConfig object, the member
+// cluster may not be initialized yet.
+func IsMemberCluster(cc string) bool {
+	memberClusters.lock.RLock()
+	defer memberClusters.lock.RUnlock()
+	return PresentInList(cc, memberClusters.clusters)
+}
+
+// GetUnconnectedClusters returns the clusters out of clusters, which are the member clusters in the
+// GSLBConfig object, but aren't initialized yet, so no objects are received from these clusters.
+func GetUnconnectedClusters(clusters []string) []string {
+	var unconnected []string
+	for _, cc := range clusters {
+		if IsMemberCluster(cc) && !IsClusterContextPresent(cc) && !PresentInList(cc, unconnected) {
+			unconnected = append(unconnected, cc)
+		}
+	}
+	return unconnected
+}
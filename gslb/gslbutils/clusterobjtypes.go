@@ -0,0 +1,77 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"sync"
+)
+
+// clusterObjTypes maps the member clusters to the types of the objects watched in them, the
+// clusters without an entry watch the types detected from the APIs they serve.
+var clusterObjTypes = struct {
+	types map[string][]string
+	lock  sync.RWMutex
+}{types: make(map[string][]string)}
+
+// ValidateClusterObjectTypes returns an error if objTypes has a type which can't be watched in a
+// member cluster, or a repeated type.
+func ValidateClusterObjectTypes(objTypes []string) error {
+	for idx, objType := range objTypes {
+		switch objType {
+		case RouteType, IngressType, SvcType, HTTPRouteType:
+		default:
+			return errors.New("invalid object type " + objType + " in objectTypes")
+		}
+		if PresentInList(objType, objTypes[:idx]) {
+			return errors.New("object type " + objType + " repeated in objectTypes")
+		}
+	}
+	return nil
+}
+
+// SetClusterObjectTypes sets the types of the objects watched in cluster cname, the types are
+// detected from the APIs served by the cluster if objTypes is empty. If objTypes is invalid, the
+// types set earlier for the cluster are cleared, so that the types are detected from the APIs
+// served by the cluster, and the validation error is returned.
+func SetClusterObjectTypes(cname string, objTypes []string) error {
+	err := ValidateClusterObjectTypes(objTypes)
+	clusterObjTypes.lock.Lock()
+	defer clusterObjTypes.lock.Unlock()
+	if err != nil || len(objTypes) == 0 {
+		delete(clusterObjTypes.types, cname)
+		return err
+	}
+	clusterObjTypes.types[cname] = append([]string{}, objTypes...)
+	return nil
+}
+
+// GetClusterObjectTypes returns the types of the objects watched in cluster cname, nil if these
+// are detected from the APIs served by the cluster.
+func GetClusterObjectTypes(cname string) []string {
+	clusterObjTypes.lock.RLock()
+	defer clusterObjTypes.lock.RUnlock()
+	objTypes, ok := clusterObjTypes.types[cname]
+	if !ok {
+		return nil
+	}
+	return append([]string{}, objTypes...)
+}
+
+// IsObjectTypeWatched returns true if the objects of type objType are to be watched in a cluster
+// watching objTypes, all the types are watched if objTypes is empty.
+func IsObjectTypeWatched(objType string, objTypes []string) bool {
+	return len(objTypes) == 0 || PresentInList(objType, objTypes)
+}
@@ -0,0 +1,65 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// DisabledReason is the reason of the filter decision for the objects with the deny label.
+const DisabledReason = "explicitly disabled"
+
+// denyLabel is the label which opts an object out of the GSLB services, an object with this label
+// is rejected even if it matches the selectors.
+var denyLabel = struct {
+	label Label
+	lock  sync.RWMutex
+}{}
+
+// SetDenyLabel sets the deny label from a "key=value" string, an empty string removes the deny
+// label.
+func SetDenyLabel(lbl string) error {
+	var newLabel Label
+	if lbl != "" {
+		kv := strings.SplitN(lbl, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return errors.New("deny label " + lbl + " must be of the form key=value")
+		}
+		newLabel = Label{Key: strings.TrimSpace(kv[0]), Value: strings.TrimSpace(kv[1])}
+	}
+	denyLabel.lock.Lock()
+	defer denyLabel.lock.Unlock()
+	denyLabel.label = newLabel
+	return nil
+}
+
+// GetDenyLabel returns the deny label, the key is empty if no deny label is set.
+func GetDenyLabel() Label {
+	denyLabel.lock.RLock()
+	defer denyLabel.lock.RUnlock()
+	return denyLabel.label
+}
+
+// IsExplicitlyDisabled returns true if labels have the deny label.
+func IsExplicitlyDisabled(labels map[string]string) bool {
+	lbl := GetDenyLabel()
+	if lbl.Key == "" {
+		return false
+	}
+	v, ok := labels[lbl.Key]
+	return ok && v == lbl.Value
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+// Event reasons for the events recorded on the member cluster objects
+const (
+	GSLBServiceSyncFailed = "GSLBServiceSyncFailed"
+	// EmptyHostname is recorded on the routes and the ingresses which have an object or a rule
+	// without a host, these can't be a part of any GSLB service
+	EmptyHostname = "EmptyHostname"
+)
+
+type clusterEventRecorders struct {
+	recorders map[string]record.EventRecorder
+	lock      sync.RWMutex
+}
+
+var eventRecorders clusterEventRecorders
+var eventRecordersOnce sync.Once
+
+func getClusterEventRecorders() *clusterEventRecorders {
+	eventRecordersOnce.Do(func() {
+		eventRecorders.recorders = make(map[string]record.EventRecorder)
+	})
+	return &eventRecorders
+}
+
+// SetClusterEventRecorder saves the event recorder for the member cluster cname.
+func SetClusterEventRecorder(cname string, recorder record.EventRecorder) {
+	ers := getClusterEventRecorders()
+	ers.lock.Lock()
+	defer ers.lock.Unlock()
+	ers.recorders[cname] = recorder
+}
+
+// GetClusterEventRecorder returns the event recorder for the member cluster cname.
+func GetClusterEventRecorder(cname string) (record.EventRecorder, bool) {
+	ers := getClusterEventRecorders()
+	ers.lock.RLock()
+	defer ers.lock.RUnlock()
+	recorder, ok := ers.recorders[cname]
+	return recorder, ok
+}
+
+// getObjRef returns the reference to the object objName of type objType in namespace ns. For the
+// host scoped object types, the object name is of the format name/hostname, so only the object's
+// name is referred to.
+func getObjRef(objType, ns, objName string, uid types.UID) *corev1.ObjectReference {
+	objRef := &corev1.ObjectReference{
+		Kind:      objType,
+		Namespace: ns,
+		Name:      objName,
+		UID:       uid,
+	}
+	handlers, ok := GetObjTypeHandlers(objType)
+	if !ok {
+		return objRef
+	}
+	objRef.Kind, objRef.APIVersion = handlers.Kind, handlers.APIVersion
+	if handlers.HostScoped {
+		objRef.Name = strings.Split(objName, "/")[0]
+	}
+	return objRef
+}
+
+// RecordObjectEvent records an event on the object objName of type objType in namespace ns
+// of the member cluster cname. uid is the UID of the object, empty if it isn't known.
+func RecordObjectEvent(cname, ns, objName, objType string, uid types.UID, eventType, reason, msg string) {
+	recorder, ok := GetClusterEventRecorder(cname)
+	if !ok {
+		Debugf("cluster: %s, ns: %s, objName: %s, msg: no event recorder for cluster, can't record event",
+			cname, ns, objName)
+		return
+	}
+	recorder.Event(getObjRef(objType, ns, objName, uid), eventType, reason, msg)
+}
@@ -0,0 +1,530 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/api/models"
+)
+
+const (
+	FilterExplainPath = "/api/filter/explain"
+
+	// names of the checks in a FilterExplanation, in the order of evaluation
+	FilterCheckDenyLabel   = "denyLabel"
+	FilterCheckPaths       = "paths"
+	FilterCheckHostname    = "hostname"
+	FilterCheckRoutableIP  = "routableIP"
+	FilterCheckPolicy      = "policy"
+	FilterCheckDeletion    = "deletion"
+	FilterCheckPortNames   = "portNames"
+	FilterCheckCluster     = "cluster"
+	FilterCheckObjectType  = "objectTypes"
+	FilterCheckGslbDomain  = "gslbDomain"
+	FilterCheckReadiness   = "requireReady"
+	FilterCheckSelfScope   = "selfScope"
+	FilterCheckNamespace   = "namespaceSelector"
+	FilterCheckApp         = "appSelector"
+	FilterCheckPredicates  = "acceptancePredicates"
+	filterCheckObjNotFound = "object"
+
+	// EmptyHostnameReason is the reason of the filter decision for the objects without a hostname.
+	EmptyHostnameReason = "rejected because hostname is empty"
+	// NoPolicyReason is the reason of the filter decision for all the objects, if no GDP object is
+	// applied.
+	NoPolicyReason = "rejected because no GDP object is applied"
+)
+
+// FilterCheck is the result of one of the checks of the global filter for an object, along with
+// the values compared by the check.
+type FilterCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	// Expected is the value required by the filter, Actual is the value of the object
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+	Message  string `json:"message,omitempty"`
+	// reason is the reason of the filter decision for an object which fails the check
+	reason string
+}
+
+// Reason returns the reason of the filter decision for an object which fails the check.
+func (check FilterCheck) Reason() string {
+	return check.reason
+}
+
+// FilterExplanation is a step by step trace of the evaluation of the global filter for an object.
+// The checks after the first failed check are still evaluated, so that all the reasons for the
+// rejection of an object are known. Reason is the reason of the decision of the filter, i.e. the
+// reason of the first failed check, if any.
+type FilterExplanation struct {
+	ObjType   string        `json:"objType"`
+	Cluster   string        `json:"cluster"`
+	Namespace string        `json:"namespace"`
+	Name      string        `json:"name,omitempty"`
+	Accepted  bool          `json:"accepted"`
+	Reason    string        `json:"reason,omitempty"`
+	Checks    []FilterCheck `json:"checks"`
+}
+
+func (fe *FilterExplanation) addCheck(check FilterCheck) {
+	fe.Checks = append(fe.Checks, check)
+	if !check.Passed && fe.Accepted {
+		fe.Accepted = false
+		fe.Reason = check.reason
+	}
+}
+
+// FilterObject is implemented by the meta objects evaluated by the global filter.
+type FilterObject interface {
+	GetType() string
+	GetCluster() string
+	GetNamespace() string
+	GetName() string
+	GetLabels() map[string]string
+	GetHostname() string
+	GetIPAddr() string
+	IsReady() bool
+}
+
+// vipsObject is implemented by the objects which can expose more than one VIP.
+type vipsObject interface {
+	GetVIPs() []VIP
+}
+
+// portNamesObject is implemented by the objects whose ports are selected via their names.
+type portNamesObject interface {
+	GetPortNames() []string
+}
+
+// pathsObject is implemented by the objects which are rejected if all their paths are excluded.
+type pathsObject interface {
+	AllPathsExcluded() bool
+}
+
+// deletingObject is implemented by the objects which are rejected while they are being deleted.
+type deletingObject interface {
+	IsDeleting() bool
+}
+
+// GetFilterObjectIPs returns the IP address of obj, followed by its VIPs, if it exposes more than
+// one VIP.
+func GetFilterObjectIPs(obj FilterObject) []string {
+	var ips []string
+	if obj.GetIPAddr() != "" {
+		ips = append(ips, obj.GetIPAddr())
+	}
+	if vo, ok := obj.(vipsObject); ok {
+		for _, vip := range vo.GetVIPs() {
+			ips = append(ips, vip.IP)
+		}
+	}
+	return ips
+}
+
+// CheckDenyLabel checks that labels don't have the deny label, the check passes if no deny label
+// is set.
+func CheckDenyLabel(labels map[string]string) FilterCheck {
+	check := FilterCheck{Name: FilterCheckDenyLabel, Expected: labelString(GetDenyLabel()), Actual: labelsString(labels)}
+	check.Passed = !IsExplicitlyDisabled(labels)
+	if check.Passed {
+		check.Message = "object doesn't have the deny label"
+	} else {
+		check.Message = DisabledReason
+		check.reason = DisabledReason
+	}
+	return check
+}
+
+// CheckHostname checks that an object has a hostname, a GSLB service can't be built for an object
+// without one.
+func CheckHostname(hostname string) FilterCheck {
+	check := FilterCheck{Name: FilterCheckHostname, Actual: hostname, Passed: hostname != ""}
+	if check.Passed {
+		check.Message = "object has a hostname"
+	} else {
+		check.Message = "hostname is empty"
+		check.reason = EmptyHostnameReason
+	}
+	return check
+}
+
+// CheckRoutableIPs checks that none of ips is non-routable, as per the disallowed ranges of the
+// non-routable IP filter. For a failed check, Actual is the first non-routable IP.
+func CheckRoutableIPs(ips []string) FilterCheck {
+	check := FilterCheck{Name: FilterCheckRoutableIP, Expected: "not in " + strings.Join(GetNonRoutableCIDRs(), ","),
+		Actual: strings.Join(ips, ","), Passed: true, Message: "IP addresses are routable"}
+	for _, ip := range ips {
+		if IsNonRoutableIP(ip) {
+			check.Passed = false
+			check.Actual = ip
+			check.Message = "the IP " + ip + " is non-routable"
+			check.reason = NonRoutableIPReason
+			break
+		}
+	}
+	return check
+}
+
+// CheckPolicy checks that a GDP object is applied to the filter, all the objects are rejected
+// otherwise.
+func (gf *GlobalFilter) CheckPolicy() FilterCheck {
+	if !gf.HasPolicy() {
+		return FilterCheck{Name: FilterCheckPolicy, Message: "no GDP object is applied", reason: NoPolicyReason}
+	}
+	return FilterCheck{Name: FilterCheckPolicy, Passed: true, Message: "GDP object is applied"}
+}
+
+// Explain returns the trace of the evaluation of the global filter for an object of objType in
+// cluster and namespace with labels. Only the checks which don't need the object itself are run,
+// see EvaluateObject.
+func (gf *GlobalFilter) Explain(objType, cluster, namespace string, labels map[string]string) FilterExplanation {
+	return gf.evaluate(objType, cluster, namespace, labels, nil)
+}
+
+// EvaluateObject returns the trace of the evaluation of the global filter for obj, the filter
+// decides as per the result of this evaluation. Apart from the checks of Explain, the excluded
+// paths, the hostname, the IP addresses, the deletion, the port names, the GSLB domains and the
+// readiness of the object are checked.
+func (gf *GlobalFilter) EvaluateObject(obj FilterObject) FilterExplanation {
+	fe := gf.evaluate(obj.GetType(), obj.GetCluster(), obj.GetNamespace(), obj.GetLabels(), obj)
+	fe.Name = obj.GetName()
+	return fe
+}
+
+// evaluate runs the checks of the global filter in the order of their evaluation by the filter: the
+// object must not have the deny label (if set), must not have all its paths excluded, must have a
+// hostname and routable IPs (if the non-routable IP filter is enabled), and a GDP object must be
+// applied. Then, the cluster and the object type have to be selected, the hostname has to be in the
+// GSLB domains, and the object has to be ready if required. A self scoped filter only selects the
+// objects in its namespace. If a namespace filter is present, the namespace has to be selected and
+// the object has to pass the app filter (if any), with the namespace override selector mode, the
+// objects in the selected namespaces pass the app filter regardless of their labels. Without a
+// namespace filter, the object has to pass the app filter. The checks which need the object are
+// skipped if obj is nil.
+func (gf *GlobalFilter) evaluate(objType, cluster, namespace string, labels map[string]string,
+	obj FilterObject) FilterExplanation {
+	fe := FilterExplanation{ObjType: objType, Cluster: cluster, Namespace: namespace, Accepted: true}
+
+	if GetDenyLabel().Key != "" {
+		fe.addCheck(CheckDenyLabel(labels))
+	}
+	if obj != nil {
+		if po, ok := obj.(pathsObject); ok {
+			pathsCheck := FilterCheck{Name: FilterCheckPaths, Passed: !po.AllPathsExcluded()}
+			if pathsCheck.Passed {
+				pathsCheck.Message = "object has paths"
+			} else {
+				pathsCheck.Message = "all paths are excluded"
+				pathsCheck.reason = "rejected because all paths are excluded"
+			}
+			fe.addCheck(pathsCheck)
+		}
+		fe.addCheck(CheckHostname(obj.GetHostname()))
+		if IsNonRoutableIPFilterEnabled() {
+			fe.addCheck(CheckRoutableIPs(GetFilterObjectIPs(obj)))
+		}
+	}
+	policyCheck := gf.CheckPolicy()
+	fe.addCheck(policyCheck)
+	if !policyCheck.Passed {
+		// no policy, nothing else is evaluated
+		return fe
+	}
+
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+
+	if obj != nil {
+		if do, ok := obj.(deletingObject); ok {
+			deletionCheck := FilterCheck{Name: FilterCheckDeletion, Passed: !do.IsDeleting()}
+			if deletionCheck.Passed {
+				deletionCheck.Message = "object is not being deleted"
+			} else {
+				deletionCheck.Message = "object is being deleted"
+				deletionCheck.reason = "rejected because the object is being deleted"
+			}
+			fe.addCheck(deletionCheck)
+		}
+		if pno, ok := obj.(portNamesObject); ok && len(gf.PortNames) != 0 {
+			objPortNames := pno.GetPortNames()
+			portCheck := FilterCheck{Name: FilterCheckPortNames, Expected: strings.Join(gf.PortNames, ","),
+				Actual: strings.Join(objPortNames, ",")}
+			for _, portName := range objPortNames {
+				if PresentInList(portName, gf.PortNames) {
+					portCheck.Passed = true
+					break
+				}
+			}
+			if portCheck.Passed {
+				portCheck.Message = "port name matched"
+			} else {
+				portCheck.Message = "no port matches portNames"
+				portCheck.reason = "rejected because no port matches portNames"
+			}
+			fe.addCheck(portCheck)
+		}
+	}
+
+	clusterCheck := FilterCheck{Name: FilterCheckCluster, Expected: strings.Join(gf.ApplicableClusters, ","),
+		Actual: cluster}
+	clusterCheck.Passed = PresentInList(cluster, gf.ApplicableClusters)
+	if clusterCheck.Passed {
+		clusterCheck.Message = "cluster is selected"
+	} else {
+		clusterCheck.Message = "cluster is not selected"
+		clusterCheck.reason = "rejected because cluster is not selected"
+	}
+	fe.addCheck(clusterCheck)
+
+	if len(gf.ObjectTypes) != 0 {
+		objTypeCheck := FilterCheck{Name: FilterCheckObjectType, Expected: strings.Join(gf.ObjectTypes, ","),
+			Actual: objType}
+		objTypeCheck.Passed = gf.IsObjTypeSelected(objType)
+		if objTypeCheck.Passed {
+			objTypeCheck.Message = "object type is selected"
+		} else {
+			objTypeCheck.Message = "object type not selected"
+			objTypeCheck.reason = "object type not selected"
+		}
+		fe.addCheck(objTypeCheck)
+	}
+
+	if obj != nil {
+		domainCheck := FilterCheck{Name: FilterCheckGslbDomain, Expected: strings.Join(GetGslbDomains(), ","),
+			Actual: obj.GetHostname()}
+		domainCheck.Passed = IsHostnameInGslbDomain(obj.GetHostname())
+		if domainCheck.Passed {
+			domainCheck.Message = "hostname is allowed"
+		} else {
+			domainCheck.Message = "hostname outside GSLB domain"
+			domainCheck.reason = "hostname outside GSLB domain"
+		}
+		fe.addCheck(domainCheck)
+
+		if gf.RequireReady {
+			readyCheck := FilterCheck{Name: FilterCheckReadiness, Passed: obj.IsReady()}
+			if readyCheck.Passed {
+				readyCheck.Message = "object is ready"
+			} else {
+				readyCheck.Message = "object is not ready"
+				readyCheck.reason = "rejected because object is not ready"
+			}
+			fe.addCheck(readyCheck)
+		}
+	}
+
+	if gf.SelfScopeNamespace != "" {
+		selfScopeCheck := FilterCheck{Name: FilterCheckSelfScope, Expected: gf.SelfScopeNamespace, Actual: namespace}
+		selfScopeCheck.Passed = namespace == gf.SelfScopeNamespace
+		if selfScopeCheck.Passed {
+			selfScopeCheck.Message = "namespace is the namespace of the GDP object"
+		} else {
+			selfScopeCheck.Message = "namespace is not the namespace of the self scoped GDP object"
+			selfScopeCheck.reason = "rejected because namespace is not the namespace of the self scoped GDP object"
+		}
+		fe.addCheck(selfScopeCheck)
+	}
+
+	// with the namespace override selector mode, a selected namespace overrides the appSelector
+	nsOverride := gf.NSFilter != nil && gf.AppFilter != nil && gf.SelectorMode == gdpv1alpha1.SelectorModeNamespaceOverride
+	nsSelected := false
+	if gf.NSFilter != nil {
+		gf.NSFilter.Lock.RLock()
+		nsCheck := FilterCheck{Name: FilterCheckNamespace, Expected: nsFilterString(gf.NSFilter),
+			Actual: namespace}
+		nsCheck.Passed = gf.NSFilter.IsNSSelected(cluster, namespace)
+		gf.NSFilter.Lock.RUnlock()
+		nsSelected = nsCheck.Passed
+		if nsCheck.Passed {
+			nsCheck.Message = "namespace is selected"
+		} else {
+			nsCheck.Message = "namespace is not selected"
+			nsCheck.reason = "rejected because namespace is not selected"
+		}
+		fe.addCheck(nsCheck)
+	}
+
+	appCheck := FilterCheck{Name: FilterCheckApp, Actual: labelsString(labels)}
+	var acceptedReason string
+	switch {
+	case gf.AppFilter != nil:
+		appCheck.Expected = appFilterString(gf.AppFilter)
+		appCheck.Passed = gf.AppFilter.Matches(labels)
+		if appCheck.Passed {
+			appCheck.Message = "labels match the appSelector"
+		} else {
+			appCheck.Message = "labels don't match the appSelector"
+			appCheck.reason = "rejected because of appSelector"
+		}
+		acceptedReason = "accepted because of appSelector"
+		if gf.NSFilter != nil {
+			acceptedReason = "accepted because of namespaceSelector and appSelector"
+		}
+	case gf.NSFilter != nil:
+		appCheck.Passed = true
+		appCheck.Message = "no appSelector, selected via the namespaceSelector"
+		acceptedReason = "accepted because of namespaceSelector"
+	case gf.SelfScopeNamespace != "":
+		appCheck.Passed = true
+		appCheck.Message = "no appSelector, selected via the selfScope"
+		acceptedReason = "accepted because of selfScope"
+	default:
+		appCheck.Message = "no appSelector or namespaceSelector"
+		appCheck.reason = "rejected because no appSelector or namespaceSelector"
+	}
+	if nsOverride && nsSelected {
+		appCheck.Passed = true
+		appCheck.Message = "namespace is selected, overrides the appSelector"
+		acceptedReason = "accepted because of namespaceSelector, overrides appSelector"
+	}
+	fe.addCheck(appCheck)
+
+	if fe.Accepted {
+		fe.Reason = acceptedReason
+	}
+	return fe
+}
+
+// ExplainObject returns the trace of the evaluation of the global filter for the object name of
+// objType in cluster and namespace, as saved in the accepted or the rejected store. The checks are
+// the ones of EvaluateObject, followed by the acceptance predicates.
+func ExplainObject(objType, cluster, namespace, name string) (FilterExplanation, bool) {
+	obj, found := getObjFromStores(objType, cluster, namespace, name)
+	if !found {
+		fe := FilterExplanation{ObjType: objType, Cluster: cluster, Namespace: namespace, Name: name}
+		fe.addCheck(FilterCheck{Name: filterCheckObjNotFound, Message: "object not found in the accepted or the rejected store"})
+		return fe, false
+	}
+	fe := GetGlobalFilter().EvaluateObject(obj)
+	if lastCheck := fe.Checks[len(fe.Checks)-1]; lastCheck.Name == FilterCheckPolicy && !lastCheck.Passed {
+		// no policy, nothing else is evaluated
+		return fe, true
+	}
+
+	if HasAcceptancePredicates() {
+		labels := make(map[string]string, len(obj.GetLabels()))
+		for k, v := range obj.GetLabels() {
+			labels[k] = v
+		}
+		predicateCheck := FilterCheck{Name: FilterCheckPredicates}
+		predicateCheck.Passed, predicateCheck.Message = EvaluateAcceptancePredicates(AcceptanceMeta{
+			ObjType: objType, Cluster: cluster, Namespace: namespace, Name: name, Hostname: obj.GetHostname(),
+			Labels: labels})
+		if predicateCheck.Passed {
+			predicateCheck.Message = "accepted by all the acceptance predicates"
+		} else {
+			predicateCheck.reason = predicateCheck.Message
+		}
+		fe.addCheck(predicateCheck)
+	}
+	return fe, true
+}
+
+func getObjFromStores(objType, cluster, namespace, name string) (FilterObject, bool) {
+	handlers, ok := GetObjTypeHandlers(objType)
+	if !ok {
+		return nil, false
+	}
+	stores := []*ClusterStore{handlers.AcceptedStore(), handlers.RejectedStore()}
+	for _, store := range stores {
+		obj, found := store.GetClusterNSObjectByName(cluster, namespace, name)
+		if !found {
+			continue
+		}
+		if fo, ok := obj.(FilterObject); ok {
+			return fo, true
+		}
+	}
+	return nil, false
+}
+
+func labelString(lbl Label) string {
+	if lbl.Key == "" {
+		return ""
+	}
+	return lbl.Key + "=" + lbl.Value
+}
+
+func nsFilterString(nsFilter *NamespaceFilter) string {
+	lblList := make([]string, 0, len(nsFilter.Labels))
+	for _, lbl := range nsFilter.Labels {
+		lblList = append(lblList, labelString(lbl))
+	}
+	lbls := ignoreCaseString(strings.Join(lblList, " "+nsFilter.Operator+" "), nsFilter.IgnoreCase)
+	if nsFilter.Global && lbls != "" {
+		return lbls + " (global)"
+	}
+	return lbls
+}
+
+// ignoreCaseString marks the labels of a selector which matches the values case-insensitively.
+func ignoreCaseString(lbls string, ignoreCase bool) string {
+	if ignoreCase && lbls != "" {
+		return lbls + " (ignoreCase)"
+	}
+	return lbls
+}
+
+func labelsString(labels map[string]string) string {
+	lblList := make([]string, 0, len(labels))
+	for k, v := range labels {
+		lblList = append(lblList, k+"="+v)
+	}
+	sort.Strings(lblList)
+	return strings.Join(lblList, ",")
+}
+
+// FilterExplainModel implements ApiModel for the debug endpoint explaining the decision of the
+// global filter for an object.
+type FilterExplainModel struct{}
+
+func (f *FilterExplainModel) InitModel() {}
+
+func (f *FilterExplainModel) ApiOperationMap() []models.OperationMap {
+	get := models.OperationMap{
+		Route:   FilterExplainPath,
+		Method:  "GET",
+		Handler: FilterExplainHandler,
+	}
+	return []models.OperationMap{get}
+}
+
+// FilterExplainHandler responds with the FilterExplanation of an object identified by the
+// "objtype", "cluster", "namespace" and "name" query parameters. Ingress hosts are named as
+// <ingress name>/<hostname>. Responds with 404 if the object is not found in the stores.
+func FilterExplainHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	objType, cluster, namespace, name := strings.ToUpper(query.Get("objtype")), query.Get("cluster"),
+		query.Get("namespace"), query.Get("name")
+	w.Header().Set("Content-Type", "application/json")
+	if objType == "" || cluster == "" || namespace == "" || name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "objtype, cluster, namespace and name are required"})
+		return
+	}
+	fe, found := ExplainObject(objType, cluster, namespace, name)
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+	} else {
+		w.WriteHeader(http.StatusOK)
+	}
+	json.NewEncoder(w).Encode(fe)
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// names of the fields of a FieldChange
+const (
+	FilterFieldAppSelector       = "appSelector"
+	FilterFieldNamespaceSelector = "namespaceSelector"
+	FilterFieldRequireReady      = "requireReady"
+	FilterFieldWeightMode        = "weightMode"
+	FilterFieldPortNames         = "portNames"
+	FilterFieldObjectTypes       = "objectTypes"
+	FilterFieldHostOnlyTypes     = "hostOnlyObjectTypes"
+	FilterFieldSelfScope         = "selfScope"
+	FilterFieldMinAge            = "minAge"
+	FilterFieldSelectorMode      = "selectorMode"
+	FilterFieldTrafficRules      = "trafficRules"
+	FilterFieldGracePeriod       = "memberRemovalGracePeriod"
+	FilterFieldRecomputeInterval = "weightRecomputeInterval"
+	FilterFieldMinHealthy        = "minHealthyClusters"
+)
+
+// FieldChange is a change of one of the selectors or the settings of a global filter.
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// TrafficChange is a change of the traffic split for a cluster. A cluster added to the traffic split
+// has no old weight and priority, and a cluster removed from it has no new weight and priority.
+type TrafficChange struct {
+	Cluster     string `json:"cluster"`
+	OldWeight   *int32 `json:"oldWeight,omitempty"`
+	NewWeight   *int32 `json:"newWeight,omitempty"`
+	OldPriority *int   `json:"oldPriority,omitempty"`
+	NewPriority *int   `json:"newPriority,omitempty"`
+}
+
+// FilterDiff is the difference between two global filters.
+type FilterDiff struct {
+	// AddedClusters and RemovedClusters are the clusters added to and removed from the applicable
+	// clusters
+	AddedClusters   []string        `json:"addedClusters,omitempty"`
+	RemovedClusters []string        `json:"removedClusters,omitempty"`
+	TrafficChanges  []TrafficChange `json:"trafficChanges,omitempty"`
+	FieldChanges    []FieldChange   `json:"fieldChanges,omitempty"`
+}
+
+// IsEmpty returns true if the filters compared are the same.
+func (d FilterDiff) IsEmpty() bool {
+	return len(d.AddedClusters) == 0 && len(d.RemovedClusters) == 0 && len(d.TrafficChanges) == 0 &&
+		len(d.FieldChanges) == 0
+}
+
+func optionalString(val string) string {
+	if val == "" {
+		return "<none>"
+	}
+	return val
+}
+
+// String returns the diff in a form fit for the logs.
+func (d FilterDiff) String() string {
+	if d.IsEmpty() {
+		return "no changes"
+	}
+	var changes []string
+	if len(d.AddedClusters) != 0 {
+		changes = append(changes, "clusters added: "+strings.Join(d.AddedClusters, ","))
+	}
+	if len(d.RemovedClusters) != 0 {
+		changes = append(changes, "clusters removed: "+strings.Join(d.RemovedClusters, ","))
+	}
+	for _, tc := range d.TrafficChanges {
+		switch {
+		case tc.OldWeight == nil:
+			changes = append(changes, "traffic split added for "+tc.Cluster+": weight "+
+				strconv.Itoa(int(*tc.NewWeight))+", priority "+strconv.Itoa(*tc.NewPriority))
+		case tc.NewWeight == nil:
+			changes = append(changes, "traffic split removed for "+tc.Cluster)
+		default:
+			changes = append(changes, "traffic split changed for "+tc.Cluster+": weight "+
+				strconv.Itoa(int(*tc.OldWeight))+" -> "+strconv.Itoa(int(*tc.NewWeight))+", priority "+
+				strconv.Itoa(*tc.OldPriority)+" -> "+strconv.Itoa(*tc.NewPriority))
+		}
+	}
+	for _, fc := range d.FieldChanges {
+		changes = append(changes, fc.Field+": "+optionalString(fc.Old)+" -> "+optionalString(fc.New))
+	}
+	return strings.Join(changes, "; ")
+}
+
+// Diff returns the changes from gf to other, i.e., gf is the older filter.
+func (gf *GlobalFilter) Diff(other *GlobalFilter) FilterDiff {
+	if gf == other {
+		return FilterDiff{}
+	}
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	other.GlobalLock.RLock()
+	defer other.GlobalLock.RUnlock()
+	return gf.diff(other)
+}
+
+// diff returns the changes from gf to other, the callers must lock both the filters.
+func (gf *GlobalFilter) diff(other *GlobalFilter) FilterDiff {
+	var d FilterDiff
+	for _, c := range other.ApplicableClusters {
+		if !PresentInList(c, gf.ApplicableClusters) {
+			d.AddedClusters = append(d.AddedClusters, c)
+		}
+	}
+	for _, c := range gf.ApplicableClusters {
+		if !PresentInList(c, other.ApplicableClusters) {
+			d.RemovedClusters = append(d.RemovedClusters, c)
+		}
+	}
+	d.TrafficChanges = diffTrafficSplits(gf.TrafficSplit, other.TrafficSplit)
+
+	fields := []FieldChange{
+		{Field: FilterFieldAppSelector, Old: appFilterString(gf.AppFilter), New: appFilterString(other.AppFilter)},
+		{Field: FilterFieldNamespaceSelector, Old: nsFilterDiffString(gf.NSFilter), New: nsFilterDiffString(other.NSFilter)},
+		{Field: FilterFieldRequireReady, Old: strconv.FormatBool(gf.RequireReady), New: strconv.FormatBool(other.RequireReady)},
+		{Field: FilterFieldWeightMode, Old: gf.WeightMode, New: other.WeightMode},
+		{Field: FilterFieldPortNames, Old: strings.Join(gf.PortNames, ","), New: strings.Join(other.PortNames, ",")},
+		{Field: FilterFieldObjectTypes, Old: strings.Join(gf.ObjectTypes, ","), New: strings.Join(other.ObjectTypes, ",")},
+		{Field: FilterFieldHostOnlyTypes, Old: strings.Join(gf.HostOnlyObjectTypes, ","),
+			New: strings.Join(other.HostOnlyObjectTypes, ",")},
+		{Field: FilterFieldSelfScope, Old: gf.SelfScopeNamespace, New: other.SelfScopeNamespace},
+		{Field: FilterFieldMinAge, Old: optionalIntString(gf.MinAge), New: optionalIntString(other.MinAge)},
+		{Field: FilterFieldSelectorMode, Old: gf.SelectorMode, New: other.SelectorMode},
+		{Field: FilterFieldTrafficRules, Old: trafficRulesString(gf.TrafficRules), New: trafficRulesString(other.TrafficRules)},
+		{Field: FilterFieldGracePeriod, Old: optionalIntString(gf.MemberRemovalGracePeriod),
+			New: optionalIntString(other.MemberRemovalGracePeriod)},
+		{Field: FilterFieldRecomputeInterval, Old: optionalIntString(gf.WeightRecomputeInterval),
+			New: optionalIntString(other.WeightRecomputeInterval)},
+		{Field: FilterFieldMinHealthy, Old: optionalIntString(gf.MinHealthyClusters),
+			New: optionalIntString(other.MinHealthyClusters)},
+	}
+	for _, fc := range fields {
+		if fc.Old != fc.New {
+			d.FieldChanges = append(d.FieldChanges, fc)
+		}
+	}
+	return d
+}
+
+func diffTrafficSplits(oldSplit, newSplit []ClusterTraffic) []TrafficChange {
+	oldTraffic := make(map[string]ClusterTraffic, len(oldSplit))
+	for _, ct := range oldSplit {
+		oldTraffic[ct.ClusterName] = ct
+	}
+	newTraffic := make(map[string]ClusterTraffic, len(newSplit))
+	for _, ct := range newSplit {
+		newTraffic[ct.ClusterName] = ct
+	}
+
+	var changes []TrafficChange
+	for _, newCt := range newSplit {
+		newCt := newCt
+		oldCt, ok := oldTraffic[newCt.ClusterName]
+		if !ok {
+			changes = append(changes, TrafficChange{Cluster: newCt.ClusterName, NewWeight: &newCt.Weight,
+				NewPriority: &newCt.Priority})
+			continue
+		}
+		if oldCt.Weight != newCt.Weight || oldCt.Priority != newCt.Priority {
+			changes = append(changes, TrafficChange{Cluster: newCt.ClusterName, OldWeight: &oldCt.Weight,
+				NewWeight: &newCt.Weight, OldPriority: &oldCt.Priority, NewPriority: &newCt.Priority})
+		}
+	}
+	for _, oldCt := range oldSplit {
+		oldCt := oldCt
+		if _, ok := newTraffic[oldCt.ClusterName]; !ok {
+			changes = append(changes, TrafficChange{Cluster: oldCt.ClusterName, OldWeight: &oldCt.Weight,
+				OldPriority: &oldCt.Priority})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Cluster < changes[j].Cluster })
+	return changes
+}
+
+func optionalIntString(val *int) string {
+	if val == nil {
+		return ""
+	}
+	return strconv.Itoa(*val)
+}
+
+func appFilterString(af *AppFilter) string {
+	if af == nil {
+		return ""
+	}
+	return ignoreCaseString(labelString(af.Label), af.IgnoreCase)
+}
+
+func nsFilterDiffString(nsFilter *NamespaceFilter) string {
+	if nsFilter == nil {
+		return ""
+	}
+	return nsFilterString(nsFilter)
+}
+
+func trafficRulesString(rules []AppTrafficRule) string {
+	ruleList := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		splitList := make([]string, 0, len(rule.TrafficSplit))
+		for _, ct := range rule.TrafficSplit {
+			splitList = append(splitList, ct.ClusterName+":"+strconv.Itoa(int(ct.Weight))+"/"+strconv.Itoa(ct.Priority))
+		}
+		ruleList = append(ruleList, appFilterString(&rule.AppFilter)+"["+strings.Join(splitList, ",")+"]")
+	}
+	return strings.Join(ruleList, ",")
+}
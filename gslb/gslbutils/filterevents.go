@@ -0,0 +1,126 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// GSLBAccepted is the event reason for the objects accepted by the filter
+	GSLBAccepted = "GSLBAccepted"
+	// GSLBRejected is the event reason for the objects rejected by the filter
+	GSLBRejected = "GSLBRejected"
+	// DefaultFilterEventInterval is the default interval within which the same filter decision
+	// isn't recorded again as an event on an object
+	DefaultFilterEventInterval = 10 * time.Minute
+)
+
+type filterEvent struct {
+	accepted   bool
+	reason     string
+	recordedAt time.Time
+}
+
+// filterEvents are the last filter decisions recorded as events on the objects, keyed by the object
+// types and the keys of the objects. A decision is recorded again only if it changes, or if the
+// interval has passed since it was last recorded, so that the resyncs don't flood the events. An
+// interval of 0 disables the events.
+var filterEvents = struct {
+	decisions  map[string]filterEvent
+	interval   time.Duration
+	lastPruned time.Time
+	lock       sync.Mutex
+}{decisions: make(map[string]filterEvent), interval: DefaultFilterEventInterval}
+
+// SetFilterEventInterval sets the interval within which the same filter decision isn't recorded
+// again on an object, 0 disables the filter decision events.
+func SetFilterEventInterval(interval time.Duration) error {
+	if interval < 0 {
+		return errors.New("filter event interval " + interval.String() + " can't be negative")
+	}
+	filterEvents.lock.Lock()
+	defer filterEvents.lock.Unlock()
+	filterEvents.interval = interval
+	filterEvents.decisions = make(map[string]filterEvent)
+	return nil
+}
+
+// GetFilterEventInterval returns the interval within which the same filter decision isn't recorded
+// again on an object.
+func GetFilterEventInterval() time.Duration {
+	filterEvents.lock.Lock()
+	defer filterEvents.lock.Unlock()
+	return filterEvents.interval
+}
+
+// pruneFilterEvents removes the decisions recorded before the interval, at most once per interval,
+// so that the decisions of the deleted objects don't pile up. The caller must hold the lock.
+func pruneFilterEvents(now time.Time) {
+	if now.Sub(filterEvents.lastPruned) < filterEvents.interval {
+		return
+	}
+	for key, event := range filterEvents.decisions {
+		if now.Sub(event.recordedAt) >= filterEvents.interval {
+			delete(filterEvents.decisions, key)
+		}
+	}
+	filterEvents.lastPruned = now
+}
+
+// shouldRecordFilterEvent returns true if decision has to be recorded as an event, and saves it as
+// the last recorded decision of the object.
+func shouldRecordFilterEvent(decision FilterDecision) bool {
+	filterEvents.lock.Lock()
+	defer filterEvents.lock.Unlock()
+	if filterEvents.interval == 0 {
+		return false
+	}
+	now := time.Now()
+	pruneFilterEvents(now)
+	key := rejectedObjKey(decision.ObjType, decision.Key)
+	if last, ok := filterEvents.decisions[key]; ok && last.accepted == decision.Accepted &&
+		last.reason == decision.Reason && now.Sub(last.recordedAt) < filterEvents.interval {
+		return false
+	}
+	filterEvents.decisions[key] = filterEvent{accepted: decision.Accepted, reason: decision.Reason, recordedAt: now}
+	return true
+}
+
+// RecordFilterDecisionEvent records the filter decision for an object as an event on the object in
+// its member cluster, a Normal GSLBAccepted event for an accepted object and a Warning event for a
+// rejected one, with the reason of the decision. The reason of the Warning event is the EventReason
+// of the decision, GSLBRejected by default. The same decision is recorded again on an object only
+// once the filter event interval has passed.
+func RecordFilterDecisionEvent(decision FilterDecision) {
+	if !shouldRecordFilterEvent(decision) {
+		return
+	}
+	if decision.Accepted {
+		RecordObjectEvent(decision.Cluster, decision.Namespace, decision.Name, decision.ObjType, decision.UID,
+			corev1.EventTypeNormal, GSLBAccepted, "selected for a GSLB service, "+decision.Reason)
+		return
+	}
+	eventReason := decision.EventReason
+	if eventReason == "" {
+		eventReason = GSLBRejected
+	}
+	RecordObjectEvent(decision.Cluster, decision.Namespace, decision.Name, decision.ObjType, decision.UID,
+		corev1.EventTypeWarning, eventReason, "not selected for a GSLB service, reason="+decision.Reason)
+}
@@ -0,0 +1,152 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// FilterSummaryInterval is the interval at which a summary of the filter decisions is logged.
+	FilterSummaryInterval = 30 * time.Second
+)
+
+// The per-object filter decisions are logged at the debug level, the number of accepted and
+// rejected objects are logged at the info level once every FilterSummaryInterval.
+var acceptedObjCount, rejectedObjCount, disabledObjCount uint64
+
+// RecordFilterDecision counts an object accepted or rejected by the filter.
+func RecordFilterDecision(accepted bool) {
+	if accepted {
+		atomic.AddUint64(&acceptedObjCount, 1)
+		return
+	}
+	atomic.AddUint64(&rejectedObjCount, 1)
+}
+
+// RecordDisabledObj counts an object rejected by the filter because of the deny label, these
+// objects are counted in the rejected objects as well.
+func RecordDisabledObj() {
+	atomic.AddUint64(&disabledObjCount, 1)
+}
+
+// GetAndResetDisabledObjs returns the number of objects rejected because of the deny label since
+// the last call and resets the count.
+func GetAndResetDisabledObjs() uint64 {
+	return atomic.SwapUint64(&disabledObjCount, 0)
+}
+
+// GetAndResetFilterDecisions returns the number of accepted and rejected objects since the last
+// call and resets the counts.
+func GetAndResetFilterDecisions() (uint64, uint64) {
+	return atomic.SwapUint64(&acceptedObjCount, 0), atomic.SwapUint64(&rejectedObjCount, 0)
+}
+
+// LogFilterDecisionSummary logs the number of accepted and rejected objects every interval, if
+// any objects were evaluated, till stopCh is closed.
+func LogFilterDecisionSummary(interval time.Duration, stopCh <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+			accepted, rejected := GetAndResetFilterDecisions()
+			disabled := GetAndResetDisabledObjs()
+			if accepted == 0 && rejected == 0 {
+				continue
+			}
+			Logf("msg: filter accepted %d objects, rejected %d objects (%d explicitly disabled) in the last %s",
+				accepted, rejected, disabled, interval.String())
+		}
+	}
+}
+
+// FilterDecision is the decision of the filter for an object, as sent to the filter observers.
+type FilterDecision struct {
+	// Key identifies the object within its type, cluster/namespace/name for the meta objects and
+	// cluster/name for the namespaces
+	Key       string
+	ObjType   string
+	Cluster   string
+	Namespace string
+	Name      string
+	Accepted  bool
+	// Reason is a human readable reason for the decision
+	Reason string
+	// UID is the UID of the object, if known, the events for the decision refer to it
+	UID types.UID
+	// EventReason is the reason of the warning event recorded for a rejection, GSLBRejected if empty
+	EventReason string
+}
+
+var (
+	// filterObservers holds a []func(FilterDecision), it is replaced on every registration, so that
+	// the observers can be invoked without any locks
+	filterObservers    atomic.Value
+	filterObserverLock sync.Mutex
+)
+
+// RegisterFilterObserver registers observer to be invoked with every decision of the filter. The
+// observers are invoked synchronously, in the order of their registration, from the event handlers
+// of the member clusters. So, an observer must not block, and must hand off any expensive work
+// (e.g. writing to a remote audit trail) to its own goroutine.
+func RegisterFilterObserver(observer func(FilterDecision)) {
+	filterObserverLock.Lock()
+	defer filterObserverLock.Unlock()
+	existing, _ := filterObservers.Load().([]func(FilterDecision))
+	observers := make([]func(FilterDecision), len(existing), len(existing)+1)
+	copy(observers, existing)
+	filterObservers.Store(append(observers, observer))
+}
+
+// ClearFilterObservers removes all the registered filter observers.
+func ClearFilterObservers() {
+	filterObserverLock.Lock()
+	defer filterObserverLock.Unlock()
+	filterObservers.Store([]func(FilterDecision){})
+}
+
+// HasFilterObservers returns true if any filter observers are registered, so that the callers can
+// skip building a FilterDecision.
+func HasFilterObservers() bool {
+	observers, _ := filterObservers.Load().([]func(FilterDecision))
+	return len(observers) != 0
+}
+
+// NotifyFilterObservers invokes all the registered filter observers with decision.
+func NotifyFilterObservers(decision FilterDecision) {
+	observers, _ := filterObservers.Load().([]func(FilterDecision))
+	for _, observer := range observers {
+		observer(decision)
+	}
+}
+
+// SetLogLevel sets the log level for AMKO, the per-object filter decisions are visible only with
+// the DEBUG log level.
+func SetLogLevel(level string) error {
+	if !IsLogLevelValid(level) {
+		return errors.New("log level " + level + " unrecognized")
+	}
+	utils.AviLog.SetLevel(level)
+	return nil
+}
@@ -0,0 +1,216 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/api/models"
+)
+
+const FilterViewPath = "/api/filter"
+
+// FilterView is the JSON view of the global filter served by the debug endpoint. The lists are
+// sorted and never null, and the fields which aren't set are omitted only if these are optional
+// in the GDP object, so that the structure stays stable for the tools consuming it.
+type FilterView struct {
+	PolicyApplied bool `json:"policyApplied"`
+	// Clusters are the clusters on which the filter is applicable
+	Clusters            []string               `json:"clusters"`
+	AppSelector         *AppSelectorView       `json:"appSelector,omitempty"`
+	NamespaceSelector   *NamespaceSelectorView `json:"namespaceSelector,omitempty"`
+	TrafficSplit        []ClusterTrafficView   `json:"trafficSplit"`
+	TrafficRules        []AppTrafficRuleView   `json:"trafficRules"`
+	DefaultWeightPolicy string                 `json:"defaultWeightPolicy,omitempty"`
+	WeightMode          string                 `json:"weightMode,omitempty"`
+	RequireReady        bool                   `json:"requireReady"`
+	PortNames           []string               `json:"portNames"`
+	ObjectTypes         []string               `json:"objectTypes"`
+	HostOnlyObjectTypes []string               `json:"hostOnlyObjectTypes"`
+	SelfScopeNamespace  string                 `json:"selfScopeNamespace,omitempty"`
+	SelectorMode        string                 `json:"selectorMode,omitempty"`
+	// WeightRecomputeInterval, MemberRemovalGracePeriod and MinAge are in seconds, as set in the
+	// GDP object
+	WeightRecomputeInterval  *int                `json:"weightRecomputeInterval,omitempty"`
+	MemberRemovalGracePeriod *int                `json:"memberRemovalGracePeriod,omitempty"`
+	MinAge                   *int                `json:"minAge,omitempty"`
+	MinHealthyClusters       *int                `json:"minHealthyClusters,omitempty"`
+	TrafficSplitOverridden   bool                `json:"trafficSplitOverridden"`
+	Checksum                 uint32              `json:"checksum"`
+	Checksums                FilterChecksumsView `json:"checksums"`
+}
+
+// AppSelectorView is the JSON view of an app selector.
+type AppSelectorView struct {
+	Key        string `json:"key"`
+	Value      string `json:"value"`
+	IgnoreCase bool   `json:"ignoreCase"`
+}
+
+// LabelView is the JSON view of a label of a namespace selector.
+type LabelView struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// NamespaceSelectorView is the JSON view of the namespace selector, along with the namespaces
+// currently selected in each cluster.
+type NamespaceSelectorView struct {
+	Labels     []LabelView `json:"labels"`
+	Operator   string      `json:"operator"`
+	IgnoreCase bool        `json:"ignoreCase"`
+	Global     bool        `json:"global"`
+	// SelectedNamespaces are the selected namespaces keyed by the clusters, AllClustersNSKey for a
+	// global selector
+	SelectedNamespaces map[string][]string `json:"selectedNamespaces"`
+	Checksum           uint32              `json:"checksum"`
+}
+
+// ClusterTrafficView is the JSON view of the traffic split for a cluster.
+type ClusterTrafficView struct {
+	Cluster  string `json:"cluster"`
+	Weight   int32  `json:"weight"`
+	Priority int    `json:"priority"`
+	Region   string `json:"region,omitempty"`
+}
+
+// AppTrafficRuleView is the JSON view of a traffic rule.
+type AppTrafficRuleView struct {
+	AppSelector  AppSelectorView      `json:"appSelector"`
+	TrafficSplit []ClusterTrafficView `json:"trafficSplit"`
+}
+
+// FilterChecksumsView is the JSON view of the checksums of the components of the filter.
+type FilterChecksumsView struct {
+	App          uint32 `json:"app"`
+	NS           uint32 `json:"ns"`
+	Clusters     uint32 `json:"clusters"`
+	Traffic      uint32 `json:"traffic"`
+	MatchOptions uint32 `json:"matchOptions"`
+	Settings     uint32 `json:"settings"`
+}
+
+func getSortedCopy(list []string) []string {
+	sorted := append([]string{}, list...)
+	sort.Strings(sorted)
+	return sorted
+}
+
+func getAppSelectorView(af AppFilter) AppSelectorView {
+	return AppSelectorView{Key: af.Key, Value: af.Value, IgnoreCase: af.IgnoreCase}
+}
+
+func getClusterTrafficView(trafficSplit []ClusterTraffic) []ClusterTrafficView {
+	views := make([]ClusterTrafficView, 0, len(trafficSplit))
+	for _, ct := range trafficSplit {
+		views = append(views, ClusterTrafficView{Cluster: ct.ClusterName, Weight: ct.Weight, Priority: ct.Priority,
+			Region: ct.Region})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].Cluster < views[j].Cluster })
+	return views
+}
+
+func (nsFilter *NamespaceFilter) getView() *NamespaceSelectorView {
+	nsFilter.Lock.RLock()
+	defer nsFilter.Lock.RUnlock()
+	view := NamespaceSelectorView{
+		Labels:             make([]LabelView, 0, len(nsFilter.Labels)),
+		Operator:           nsFilter.Operator,
+		IgnoreCase:         nsFilter.IgnoreCase,
+		Global:             nsFilter.Global,
+		SelectedNamespaces: make(map[string][]string, len(nsFilter.SelectedNS)),
+		Checksum:           nsFilter.Checksum,
+	}
+	for _, lbl := range nsFilter.Labels {
+		view.Labels = append(view.Labels, LabelView{Key: lbl.Key, Value: lbl.Value})
+	}
+	for cname, namespaces := range nsFilter.SelectedNS {
+		view.SelectedNamespaces[cname] = getSortedCopy(namespaces)
+	}
+	return &view
+}
+
+// GetView returns the JSON view of the global filter.
+func (gf *GlobalFilter) GetView() FilterView {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	view := FilterView{
+		PolicyApplied:            gf.PolicyApplied,
+		Clusters:                 getSortedCopy(gf.ApplicableClusters),
+		TrafficSplit:             getClusterTrafficView(gf.TrafficSplit),
+		TrafficSplitOverridden:   gf.TrafficSplitOverridden,
+		TrafficRules:             make([]AppTrafficRuleView, 0, len(gf.TrafficRules)),
+		DefaultWeightPolicy:      gf.DefaultWeightPolicy,
+		WeightMode:               gf.WeightMode,
+		RequireReady:             gf.RequireReady,
+		PortNames:                append([]string{}, gf.PortNames...),
+		ObjectTypes:              getSortedCopy(gf.ObjectTypes),
+		HostOnlyObjectTypes:      getSortedCopy(gf.HostOnlyObjectTypes),
+		SelfScopeNamespace:       gf.SelfScopeNamespace,
+		SelectorMode:             gf.SelectorMode,
+		WeightRecomputeInterval:  gf.WeightRecomputeInterval,
+		MemberRemovalGracePeriod: gf.MemberRemovalGracePeriod,
+		MinAge:                   gf.MinAge,
+		MinHealthyClusters:       gf.MinHealthyClusters,
+		Checksum:                 gf.Checksum,
+		Checksums: FilterChecksumsView{
+			App:          gf.Checksums.App,
+			NS:           gf.Checksums.NS,
+			Clusters:     gf.Checksums.Clusters,
+			Traffic:      gf.Checksums.Traffic,
+			MatchOptions: gf.Checksums.MatchOptions,
+			Settings:     gf.Checksums.Settings,
+		},
+	}
+	if gf.AppFilter != nil {
+		appSelector := getAppSelectorView(*gf.AppFilter)
+		view.AppSelector = &appSelector
+	}
+	if gf.NSFilter != nil {
+		view.NamespaceSelector = gf.NSFilter.getView()
+	}
+	// the traffic rules are evaluated in order, so these are not sorted
+	for _, rule := range gf.TrafficRules {
+		view.TrafficRules = append(view.TrafficRules, AppTrafficRuleView{
+			AppSelector:  getAppSelectorView(rule.AppFilter),
+			TrafficSplit: getClusterTrafficView(rule.TrafficSplit),
+		})
+	}
+	return view
+}
+
+// FilterViewModel implements ApiModel for the debug endpoint serving the global filter.
+type FilterViewModel struct{}
+
+func (f *FilterViewModel) InitModel() {}
+
+func (f *FilterViewModel) ApiOperationMap() []models.OperationMap {
+	get := models.OperationMap{
+		Route:   FilterViewPath,
+		Method:  "GET",
+		Handler: FilterViewHandler,
+	}
+	return []models.OperationMap{get}
+}
+
+// FilterViewHandler responds with the JSON view of the global filter.
+func FilterViewHandler(w http.ResponseWriter, r *http.Request) {
+	view := GetGlobalFilter().GetView()
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(view)
+}
@@ -0,0 +1,184 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sort"
+	"sync"
+
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+)
+
+// GDPStore holds the GDP objects keyed by their namespaces and names, along with the name and
+// namespace of the accepted GDP object. The store keeps its own copies of the GDP objects, the
+// objects returned by it must not be modified.
+type GDPStore struct {
+	gdps map[string]*gdpv1alpha1.GlobalDeploymentPolicy
+	// acceptedName and acceptedNs are empty if no GDP object is accepted
+	acceptedName string
+	acceptedNs   string
+	lock         sync.RWMutex
+}
+
+// NewGDPStore returns an empty GDP store.
+func NewGDPStore() *GDPStore {
+	return &GDPStore{gdps: make(map[string]*gdpv1alpha1.GlobalDeploymentPolicy)}
+}
+
+func gdpStoreKey(ns, name string) string {
+	return ns + "/" + name
+}
+
+// Add adds the GDP object gdp to the store, returns false if a GDP object with the same namespace
+// and name is already present.
+func (s *GDPStore) Add(gdp *gdpv1alpha1.GlobalDeploymentPolicy) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := gdpStoreKey(gdp.Namespace, gdp.Name)
+	if _, ok := s.gdps[key]; ok {
+		return false
+	}
+	s.gdps[key] = gdp.DeepCopy()
+	return true
+}
+
+// Update replaces the GDP object with the same namespace and name as gdp, returns false if no such
+// GDP object is present.
+func (s *GDPStore) Update(gdp *gdpv1alpha1.GlobalDeploymentPolicy) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := gdpStoreKey(gdp.Namespace, gdp.Name)
+	if _, ok := s.gdps[key]; !ok {
+		return false
+	}
+	s.gdps[key] = gdp.DeepCopy()
+	return true
+}
+
+// AddOrUpdate adds the GDP object gdp to the store, or replaces the GDP object with the same
+// namespace and name.
+func (s *GDPStore) AddOrUpdate(gdp *gdpv1alpha1.GlobalDeploymentPolicy) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.gdps[gdpStoreKey(gdp.Namespace, gdp.Name)] = gdp.DeepCopy()
+}
+
+// Delete removes the GDP object ns/name from the store, returns false if it isn't present.
+func (s *GDPStore) Delete(ns, name string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := gdpStoreKey(ns, name)
+	if _, ok := s.gdps[key]; !ok {
+		return false
+	}
+	delete(s.gdps, key)
+	return true
+}
+
+// Get returns the GDP object ns/name, if present.
+func (s *GDPStore) Get(ns, name string) (*gdpv1alpha1.GlobalDeploymentPolicy, bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	gdp, ok := s.gdps[gdpStoreKey(ns, name)]
+	return gdp, ok
+}
+
+// List returns the GDP objects in the store, sorted by their namespaces and names.
+func (s *GDPStore) List() []*gdpv1alpha1.GlobalDeploymentPolicy {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.list()
+}
+
+func (s *GDPStore) list() []*gdpv1alpha1.GlobalDeploymentPolicy {
+	gdps := make([]*gdpv1alpha1.GlobalDeploymentPolicy, 0, len(s.gdps))
+	for _, gdp := range s.gdps {
+		gdps = append(gdps, gdp)
+	}
+	sort.Slice(gdps, func(i, j int) bool {
+		if gdps[i].Namespace != gdps[j].Namespace {
+			return gdps[i].Namespace < gdps[j].Namespace
+		}
+		return gdps[i].Name < gdps[j].Name
+	})
+	return gdps
+}
+
+// Len returns the number of GDP objects in the store.
+func (s *GDPStore) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.gdps)
+}
+
+// GetAccepted returns the name and namespace of the accepted GDP object, empty if none is accepted.
+func (s *GDPStore) GetAccepted() (string, string) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.acceptedName, s.acceptedNs
+}
+
+// setAccepted sets the name and namespace of the accepted GDP object, empty values mean that no
+// GDP object is accepted. The previously accepted GDP object, if different, is removed from the
+// store. The caller must hold the lock.
+func (s *GDPStore) setAccepted(name, ns string) {
+	if s.acceptedName == name && s.acceptedNs == ns {
+		return
+	}
+	if s.acceptedName != "" || s.acceptedNs != "" {
+		delete(s.gdps, gdpStoreKey(s.acceptedNs, s.acceptedName))
+	}
+	s.acceptedName, s.acceptedNs = name, ns
+}
+
+// defaultGDPStore holds the accepted GDP object, only one GDP object is accepted as of now. The
+// accepted GDP object is claimed via CompareAndSetGDPObj, and then added to the store.
+var defaultGDPStore = NewGDPStore()
+
+// GetDefaultGDPStore returns the store of the accepted GDP objects.
+func GetDefaultGDPStore() *GDPStore {
+	return defaultGDPStore
+}
+
+// SetGDPObj sets the name and namespace of the accepted GDP object, empty values mean that no GDP
+// object is accepted. The previously accepted GDP object is removed from the store.
+func SetGDPObj(name, ns string) {
+	defaultGDPStore.lock.Lock()
+	defer defaultGDPStore.lock.Unlock()
+	defaultGDPStore.setAccepted(name, ns)
+}
+
+// GetGDPObj returns the name and namespace of the accepted GDP object, empty if none is accepted.
+func GetGDPObj() (string, string) {
+	return defaultGDPStore.GetAccepted()
+}
+
+// CompareAndSetGDPObj sets the name and namespace of the accepted GDP object to newName and newNs,
+// only if the current values are expectedName and expectedNs. Returns true if the values were set.
+func CompareAndSetGDPObj(expectedName, expectedNs, newName, newNs string) bool {
+	defaultGDPStore.lock.Lock()
+	defer defaultGDPStore.lock.Unlock()
+	if defaultGDPStore.acceptedName != expectedName || defaultGDPStore.acceptedNs != expectedNs {
+		return false
+	}
+	defaultGDPStore.setAccepted(newName, newNs)
+	return true
+}
+
+// IsEmpty returns true if no GDP object is accepted.
+func IsEmpty() bool {
+	name, ns := defaultGDPStore.GetAccepted()
+	return name == "" && ns == ""
+}
@@ -16,43 +16,68 @@ package gslbutils
 
 import (
 	"errors"
+	"fmt"
+	"sort"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
-
-	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
 )
 
-type GDPObj struct {
-	Namespace string
-	Name      string
-	GDPLock   sync.RWMutex
-}
+// The errors returned by the getters of the global filter, wrapped with the context, the callers
+// can check them via errors.Is.
+var (
+	// ErrNoTrafficWeight is returned if no weight is available for a cluster.
+	ErrNoTrafficWeight = errors.New("no weight available")
+	// ErrNoNSFilter is returned if the global filter has no namespace filter.
+	ErrNoNSFilter = errors.New("no NSFilter present")
+	// ErrNoAppFilter is returned if the global filter has no app filter.
+	ErrNoAppFilter = errors.New("no appFilter present")
+)
 
-var gdpObj GDPObj
+// gdpNamespaces are the namespaces in which the GDP objects are accepted, AVISystem by default.
+var gdpNamespaces = struct {
+	namespaces []string
+	lock       sync.RWMutex
+}{namespaces: []string{AVISystem}}
 
-func SetGDPObj(name, ns string) {
-	gdpObj.GDPLock.Lock()
-	defer gdpObj.GDPLock.Unlock()
-	gdpObj.Name = name
-	gdpObj.Namespace = ns
+// SetGDPNamespaces sets the namespaces in which the GDP objects are accepted, the GDP objects in
+// all the other namespaces are ignored.
+func SetGDPNamespaces(namespaces []string) error {
+	var nsList []string
+	for _, ns := range namespaces {
+		ns = strings.TrimSpace(ns)
+		if ns == "" {
+			return errors.New("empty namespace in the GDP namespaces")
+		}
+		if !PresentInList(ns, nsList) {
+			nsList = append(nsList, ns)
+		}
+	}
+	if len(nsList) == 0 {
+		return errors.New("no GDP namespaces")
+	}
+	gdpNamespaces.lock.Lock()
+	defer gdpNamespaces.lock.Unlock()
+	gdpNamespaces.namespaces = nsList
+	return nil
 }
 
-func GetGDPObj() (string, string) {
-	gdpObj.GDPLock.RLock()
-	defer gdpObj.GDPLock.RUnlock()
-	return gdpObj.Name, gdpObj.Namespace
+// GetGDPNamespaces returns the namespaces in which the GDP objects are accepted.
+func GetGDPNamespaces() []string {
+	gdpNamespaces.lock.RLock()
+	defer gdpNamespaces.lock.RUnlock()
+	return append([]string{}, gdpNamespaces.namespaces...)
 }
 
-func IsEmpty() bool {
-	gdpObj.GDPLock.RLock()
-	defer gdpObj.GDPLock.RUnlock()
-
-	if gdpObj.Name == "" && gdpObj.Namespace == "" {
-		return true
-	}
-	return false
+// IsGDPNamespaceAllowed returns true if the GDP objects are accepted in namespace ns.
+func IsGDPNamespaceAllowed(ns string) bool {
+	gdpNamespaces.lock.RLock()
+	defer gdpNamespaces.lock.RUnlock()
+	return PresentInList(ns, gdpNamespaces.namespaces)
 }
 
 var (
@@ -70,10 +95,67 @@ type GlobalFilter struct {
 	NSFilter *NamespaceFilter
 	// TrafficSplit provides weights of traffic routed to different clusters
 	TrafficSplit []ClusterTraffic
+	// TrafficSplitOverridden is set if TrafficSplit comes from the traffic split ConfigMap instead
+	// of the GDP object.
+	TrafficSplitOverridden bool
+	// GDPTrafficSplit is the traffic split of the accepted GDP object, which applies if the traffic
+	// split isn't overridden.
+	GDPTrafficSplit []gdpv1alpha1.TrafficSplitElem
+	// TrafficRules provide traffic splits for applications selected via their labels,
+	// applications not selected by any of the rules follow TrafficSplit
+	TrafficRules []AppTrafficRule
 	// ApplicableClusters contain the list of clusters on which the filters
 	// will be applicable
 	ApplicableClusters []string
-	Checksum           uint32
+	// DefaultWeightPolicy determines the weight of a cluster which doesn't have an
+	// entry in TrafficSplit.
+	DefaultWeightPolicy string
+	// RequireReady rejects the objects which are not ready, as per their status.
+	RequireReady bool
+	// PortNames are the names of the service ports to be used for the GSLB members of the services,
+	// services without any of these ports are rejected. All the ports qualify if empty.
+	PortNames []string
+	// ObjectTypes are the types of the objects selected by the filter, sorted, all the types are
+	// selected if empty.
+	ObjectTypes []string
+	// HostOnlyObjectTypes are the types of the objects which are advertised with the host only if
+	// these don't have any paths, sorted. The objects without any paths get the default path "/"
+	// if their types aren't in this list.
+	HostOnlyObjectTypes []string
+	// SelfScopeNamespace is the namespace of a self scoped GDP object, only the objects in this
+	// namespace are selected by the filter. Empty if the GDP object isn't self scoped.
+	SelfScopeNamespace string
+	// SelectorMode combines AppFilter and NSFilter, if both are set: SelectorModeAnd requires both
+	// to select an object, with SelectorModeNamespaceOverride, NSFilter selects the objects
+	// regardless of AppFilter, and the objects in the other namespaces are rejected.
+	SelectorMode string
+	// WeightMode is either WeightModeWeight for the relative weights, WeightModePercentage for
+	// the traffic splits expressed as percentages, or WeightModeBackends for the weights computed
+	// from the ready backends of the clusters.
+	WeightMode string
+	// WeightRecomputeInterval is the interval (in seconds) set in the GDP object, at which the
+	// weights are recomputed in the backends weight mode. The default interval applies if nil.
+	WeightRecomputeInterval *int
+	// backendCounts are the ready backends of each cluster, as last computed for the backends
+	// weight mode, these are not a part of the GDP object.
+	backendCounts map[string]int32
+	// MemberRemovalGracePeriod is the grace period (in seconds) set in the GDP object, for which
+	// the GSLB members of the deleted objects are retained. The default grace period applies if nil.
+	MemberRemovalGracePeriod *int
+	// MinAge is the min age (in seconds) of the objects to be advertised, as set in the GDP object.
+	// The default min age applies if nil.
+	MinAge *int
+	// MinHealthyClusters is the min number of healthy clusters of a GSLB service for it to be
+	// advertised, as set in the GDP object. All the GSLB services are advertised if nil.
+	MinHealthyClusters *int
+	// PolicyApplied is set when a GDP object is added to the filter, and reset when it is deleted.
+	PolicyApplied bool
+	// objCounter caps the number of objects accepted from each member cluster, the limit is not
+	// a part of the GDP object, so it stays as it is across the GDP changes.
+	objCounter *clusterObjCounter
+	Checksum   uint32
+	// Checksums are the checksums of the components of the filter, Checksum is computed over these
+	Checksums FilterChecksums
 	// Respective filters for the namespaces.
 	// NSFilterMap map[string]*NSFilter
 	// GlobalLock is locked before accessing any of the filters.
@@ -88,15 +170,57 @@ func GetGlobalFilter() *GlobalFilter {
 	return Gfi
 }
 
-func (gf *GlobalFilter) GetNSFilterLabel() (Label, error) {
+// ResetGlobalFilter replaces the global filter with a new and empty filter, so that the
+// tests don't share the filter state. The callers must not hold on to the older filter.
+func ResetGlobalFilter() {
+	gfOnce.Do(func() {})
+	Gfi = GetNewGlobalFilter()
+	atomic.StoreInt32(&noPolicyLogged, 0)
+}
+
+// noPolicyLogged is set once the absence of a GDP object is logged, and reset once a GDP
+// object is applied, so that the absence is not logged for every object.
+var noPolicyLogged int32
+
+// HasPolicy returns true if a GDP object has been applied to the global filter. Without a
+// policy, all the objects are rejected, so callers can skip the filter evaluation.
+func (gf *GlobalFilter) HasPolicy() bool {
+	gf.GlobalLock.RLock()
+	policyApplied := gf.PolicyApplied
+	gf.GlobalLock.RUnlock()
+
+	if !policyApplied {
+		if atomic.CompareAndSwapInt32(&noPolicyLogged, 0, 1) {
+			Logf("msg: no GDP object configured, all objects will be rejected till a GDP object is applied")
+		}
+		return false
+	}
+	atomic.StoreInt32(&noPolicyLogged, 0)
+	return true
+}
+
+func (gf *GlobalFilter) GetNSFilterLabels() ([]Label, error) {
 	gf.GlobalLock.RLock()
 	defer gf.GlobalLock.RUnlock()
 
 	if gf.NSFilter == nil {
-		return Label{}, errors.New("no NSFilter present")
+		return nil, ErrNoNSFilter
 	}
 
-	return gf.NSFilter.GetFilterLabel(), nil
+	return gf.NSFilter.GetFilterLabels(), nil
+}
+
+// GetNamespacesForCluster returns a copy of the namespaces of cluster cname selected by the
+// namespace filter, an error if there's no namespace filter.
+func (gf *GlobalFilter) GetNamespacesForCluster(cname string) ([]string, error) {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+
+	if gf.NSFilter == nil {
+		return nil, ErrNoNSFilter
+	}
+
+	return gf.NSFilter.GetNamespacesForCluster(cname), nil
 }
 
 func (gf *GlobalFilter) GetAppFilterLabel() (Label, error) {
@@ -104,12 +228,82 @@ func (gf *GlobalFilter) GetAppFilterLabel() (Label, error) {
 	defer gf.GlobalLock.RUnlock()
 
 	if gf.AppFilter == nil {
-		return Label{}, errors.New("no appFilter present")
+		return Label{}, ErrNoAppFilter
 	}
 
 	return gf.AppFilter.Label, nil
 }
 
+// defaultGracePeriod is the grace period (in seconds) for the removal of the GSLB members, if the
+// GDP object doesn't set one.
+var defaultGracePeriod int32 = DefaultMemberRemovalGracePeriod
+
+// SetDefaultMemberRemovalGracePeriod sets the grace period (in seconds) for the removal of the GSLB
+// members, which applies if the GDP object doesn't set one.
+func SetDefaultMemberRemovalGracePeriod(seconds int) error {
+	if seconds < 0 || seconds > MaxMemberRemovalGracePeriod {
+		return errors.New("member removal grace period " + strconv.Itoa(seconds) + " must be between 0 and " +
+			strconv.Itoa(MaxMemberRemovalGracePeriod))
+	}
+	atomic.StoreInt32(&defaultGracePeriod, int32(seconds))
+	return nil
+}
+
+// GetMinHealthyClusters returns the min number of healthy clusters of a GSLB service for it to be
+// advertised, 0 if not set in the GDP object.
+func (gf *GlobalFilter) GetMinHealthyClusters() int {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	if gf.MinHealthyClusters != nil {
+		return *gf.MinHealthyClusters
+	}
+	return 0
+}
+
+// GetMemberRemovalGracePeriod returns the grace period for which the GSLB member of a deleted object
+// is retained, as set in the GDP object, or the default grace period.
+func (gf *GlobalFilter) GetMemberRemovalGracePeriod() time.Duration {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	if gf.MemberRemovalGracePeriod != nil {
+		return time.Duration(*gf.MemberRemovalGracePeriod) * time.Second
+	}
+	return time.Duration(atomic.LoadInt32(&defaultGracePeriod)) * time.Second
+}
+
+// defaultMinAge is the min age (in seconds) of the objects to be advertised, if the GDP object
+// doesn't set one.
+var defaultMinAge int32
+
+// SetDefaultMinObjectAge sets the min age (in seconds) of the objects to be advertised, which
+// applies if the GDP object doesn't set one.
+func SetDefaultMinObjectAge(seconds int) error {
+	if seconds < 0 || seconds > MaxMinObjectAge {
+		return errors.New("min object age " + strconv.Itoa(seconds) + " must be between 0 and " +
+			strconv.Itoa(MaxMinObjectAge))
+	}
+	atomic.StoreInt32(&defaultMinAge, int32(seconds))
+	return nil
+}
+
+// GetMinObjectAge returns the min age of the objects to be advertised, as set in the GDP object, or
+// the default min age. The objects younger than this are deferred till they are old enough.
+func (gf *GlobalFilter) GetMinObjectAge() time.Duration {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	if gf.MinAge != nil {
+		return time.Duration(*gf.MinAge) * time.Second
+	}
+	return time.Duration(atomic.LoadInt32(&defaultMinAge)) * time.Second
+}
+
+// GetPortNames returns the names of the service ports selected by the GDP object.
+func (gf *GlobalFilter) GetPortNames() []string {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	return append([]string{}, gf.PortNames...)
+}
+
 func (gf *GlobalFilter) IsClusterAllowed(cname string) bool {
 	gf.GlobalLock.RLock()
 	defer gf.GlobalLock.RUnlock()
@@ -125,7 +319,7 @@ func (gf *GlobalFilter) AddNSToNSFilter(cname, ns string) error {
 	defer gf.GlobalLock.Unlock()
 
 	if gf.NSFilter == nil {
-		return errors.New("NSFilter empty in GlobalFilter, can't add namespace")
+		return fmt.Errorf("can't add namespace %s of cluster %s: %w", ns, cname, ErrNoNSFilter)
 	}
 	gf.NSFilter.AddNS(cname, ns)
 
@@ -134,10 +328,59 @@ func (gf *GlobalFilter) AddNSToNSFilter(cname, ns string) error {
 
 type AppFilter struct {
 	Label
+	// IgnoreCase matches the label value case-insensitively
+	IgnoreCase bool
+}
+
+// Matches returns true if the label of the app filter is present in labels.
+func (af *AppFilter) Matches(labels map[string]string) bool {
+	v, ok := labels[af.Key]
+	return ok && labelValueMatches(v, af.Value, af.IgnoreCase)
+}
+
+// labelValueMatches compares the value of a label with the value expected by a selector, the
+// case is ignored if ignoreCase is set.
+func labelValueMatches(value, expected string, ignoreCase bool) bool {
+	if ignoreCase {
+		return strings.EqualFold(value, expected)
+	}
+	return value == expected
+}
+
+// AppTrafficRule determines the weights of traffic routed to different clusters for the
+// applications selected via AppFilter.
+type AppTrafficRule struct {
+	AppFilter    AppFilter
+	TrafficSplit []ClusterTraffic
+}
+
+func getClusterTraffic(trafficSplit []gdpv1alpha1.TrafficSplitElem) []ClusterTraffic {
+	ctList := []ClusterTraffic{}
+	for _, ts := range trafficSplit {
+		ct := ClusterTraffic{
+			ClusterName: ts.Cluster,
+			Weight:      int32(ts.Weight),
+			Priority:    ts.Priority,
+			Region:      GetClusterRegion(ts.Cluster),
+		}
+		if ct.Priority == 0 {
+			ct.Priority = DefaultPriority
+		}
+		ctList = append(ctList, ct)
+	}
+	return ctList
 }
 
 type NamespaceFilter struct {
-	Label
+	// Labels are the label pairs selecting the namespaces, combined as per Operator. These are
+	// not changed once the filter is created.
+	Labels   []Label
+	Operator string
+	// IgnoreCase matches the label values case-insensitively
+	IgnoreCase bool
+	// Global selects the namespaces by their names across all the clusters, the selected
+	// namespaces are saved only under AllClustersNSKey
+	Global bool
 	// SelectedNS contains a list of namespaces selected via this filter
 	// updated by the namespace event handlers
 	SelectedNS map[string][]string
@@ -153,25 +396,73 @@ func (nsFilter *NamespaceFilter) GetChecksum() uint32 {
 	return nsFilter.Checksum
 }
 
-func (nsFilter *NamespaceFilter) GetFilterLabel() Label {
+// AllClustersNSKey is the key of SelectedNS for the namespaces selected across all the clusters by
+// a global namespace filter.
+const AllClustersNSKey = "*"
+
+// SelectionKey returns the key of SelectedNS under which the selected namespaces of cluster cname
+// are saved.
+func (nsFilter *NamespaceFilter) SelectionKey(cname string) string {
+	if nsFilter.Global {
+		return AllClustersNSKey
+	}
+	return cname
+}
+
+// IsNSSelected returns true if the namespace ns of cluster cname is selected, the caller must hold
+// the lock.
+func (nsFilter *NamespaceFilter) IsNSSelected(cname, ns string) bool {
+	return PresentInList(ns, nsFilter.SelectedNS[nsFilter.SelectionKey(cname)])
+}
+
+// GetNamespacesForCluster returns a copy of the namespaces of cluster cname selected by the filter,
+// these are the namespaces selected across all the clusters for a global filter.
+func (nsFilter *NamespaceFilter) GetNamespacesForCluster(cname string) []string {
 	nsFilter.Lock.RLock()
 	defer nsFilter.Lock.RUnlock()
-	return nsFilter.Label
+	return append([]string{}, nsFilter.SelectedNS[nsFilter.SelectionKey(cname)]...)
+}
+
+func (nsFilter *NamespaceFilter) GetFilterLabels() []Label {
+	nsFilter.Lock.RLock()
+	defer nsFilter.Lock.RUnlock()
+	return append([]Label{}, nsFilter.Labels...)
+}
+
+// Matches returns true if the namespace labels have all the label pairs of the filter for the
+// "AND" operator, or any of them for the "OR" operator.
+func (nsFilter *NamespaceFilter) Matches(labels map[string]string) bool {
+	if len(nsFilter.Labels) == 0 {
+		return false
+	}
+	anyOf := nsFilter.Operator == gdpv1alpha1.LabelOperatorOr
+	for _, lbl := range nsFilter.Labels {
+		v, ok := labels[lbl.Key]
+		if matched := ok && labelValueMatches(v, lbl.Value, nsFilter.IgnoreCase); matched == anyOf {
+			// the first match for "OR", or the first mismatch for "AND" decides
+			return matched
+		}
+	}
+	return !anyOf
 }
 
 func (nsFilter *NamespaceFilter) AddNS(cname, ns string) {
 	nsFilter.Lock.Lock()
 	defer nsFilter.Lock.Unlock()
 
-	nsList, ok := nsFilter.SelectedNS[cname]
+	if nsFilter.SelectedNS == nil {
+		nsFilter.SelectedNS = make(map[string][]string)
+	}
+	key := nsFilter.SelectionKey(cname)
+	nsList, ok := nsFilter.SelectedNS[key]
 	if !ok {
-		nsFilter.SelectedNS[cname] = []string{ns}
+		nsFilter.SelectedNS[key] = []string{ns}
 		return
 	}
 
 	if !PresentInList(ns, nsList) {
 		nsList = append(nsList, ns)
-		nsFilter.SelectedNS[cname] = nsList
+		nsFilter.SelectedNS[key] = nsList
 	}
 }
 
@@ -187,18 +478,32 @@ func getLabelKeyAndValue(lbl map[string]string) (string, string) {
 	return "", ""
 }
 
-func createNewNSFilter(lbl map[string]string) *NamespaceFilter {
-	k, v := getLabelKeyAndValue(lbl)
-	nsFilter := NamespaceFilter{
-		Label: Label{
-			Key:   k,
-			Value: v,
-		},
+func createNewNSFilter(lbl map[string]string, operator string, ignoreCase bool, scope string) *NamespaceFilter {
+	if operator == "" {
+		operator = gdpv1alpha1.LabelOperatorAnd
+	}
+	nsFilter := NamespaceFilter{Operator: operator, IgnoreCase: ignoreCase,
+		Global: scope == gdpv1alpha1.NamespaceScopeGlobal}
+	keys := make([]string, 0, len(lbl))
+	for k := range lbl {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	// checksum for NSFilter only accounts for the labels, the operator, the case sensitivity and
+	// the scope i.e., wrt any GDP changes and not namespace changes
+	var cb ChecksumBuilder
+	cb.Add("operator", operator)
+	if ignoreCase {
+		cb.Add("ignoreCase")
+	}
+	if nsFilter.Global {
+		cb.Add("globalScope")
+	}
+	for _, k := range keys {
+		nsFilter.Labels = append(nsFilter.Labels, Label{Key: k, Value: lbl[k]})
+		cb.Add("label", k, lbl[k])
 	}
-	// checksum for NSFilter only accounts for the key and label i.e., wrt
-	// any GDP changes and not namespace changes
-	cksum := utils.Hash(k + v)
-	nsFilter.Checksum = cksum
+	nsFilter.Checksum = cb.Sum()
 	return &nsFilter
 }
 
@@ -208,6 +513,12 @@ func createNewNSFilter(lbl map[string]string) *NamespaceFilter {
 func (gf *GlobalFilter) AddToFilter(gdp *gdpv1alpha1.GlobalDeploymentPolicy) {
 	gf.GlobalLock.Lock()
 	defer gf.GlobalLock.Unlock()
+	gf.addToFilter(gdp)
+	gf.logViolations(gdp)
+}
+
+// addToFilter builds the filter from gdp, the caller must hold the lock.
+func (gf *GlobalFilter) addToFilter(gdp *gdpv1alpha1.GlobalDeploymentPolicy) {
 	if len(gdp.Spec.MatchRules.AppSelector.Label) == 1 {
 		k, v := getLabelKeyAndValue(gdp.Spec.MatchRules.AppSelector.Label)
 		appFilter := AppFilter{
@@ -215,87 +526,552 @@ func (gf *GlobalFilter) AddToFilter(gdp *gdpv1alpha1.GlobalDeploymentPolicy) {
 				Key:   k,
 				Value: v,
 			},
+			IgnoreCase: gdp.Spec.MatchRules.AppSelector.IgnoreCase,
 		}
 		gf.AppFilter = &appFilter
 	}
-	if len(gdp.Spec.MatchRules.NamespaceSelector.Label) == 1 {
-		gf.NSFilter = createNewNSFilter(gdp.Spec.MatchRules.NamespaceSelector.Label)
+	if len(gdp.Spec.MatchRules.NamespaceSelector.Label) > 0 {
+		gf.NSFilter = createNewNSFilter(gdp.Spec.MatchRules.NamespaceSelector.Label,
+			gdp.Spec.MatchRules.NamespaceSelector.Operator, gdp.Spec.MatchRules.NamespaceSelector.IgnoreCase,
+			gdp.Spec.MatchRules.NamespaceSelector.Scope)
+	}
+	gf.RequireReady = gdp.Spec.MatchRules.RequireReady
+	gf.PortNames = append([]string{}, gdp.Spec.MatchRules.PortNames...)
+	gf.ObjectTypes = []string{}
+	for _, objType := range gdp.Spec.MatchRules.ObjectTypes {
+		if !PresentInList(objType, gf.ObjectTypes) {
+			gf.ObjectTypes = append(gf.ObjectTypes, objType)
+		}
+	}
+	sort.Strings(gf.ObjectTypes)
+	gf.HostOnlyObjectTypes = []string{}
+	for _, objType := range gdp.Spec.HostOnlyObjectTypes {
+		if !PresentInList(objType, gf.HostOnlyObjectTypes) {
+			gf.HostOnlyObjectTypes = append(gf.HostOnlyObjectTypes, objType)
+		}
+	}
+	sort.Strings(gf.HostOnlyObjectTypes)
+	gf.SelfScopeNamespace = ""
+	if gdp.Spec.MatchRules.SelfScope {
+		gf.SelfScopeNamespace = gdp.ObjectMeta.Namespace
+	}
+	gf.SelectorMode = gdp.Spec.MatchRules.SelectorMode
+	if gf.SelectorMode == "" {
+		gf.SelectorMode = gdpv1alpha1.SelectorModeAnd
+	}
+	if gdp.Spec.MemberRemovalGracePeriod != nil {
+		gracePeriod := *gdp.Spec.MemberRemovalGracePeriod
+		gf.MemberRemovalGracePeriod = &gracePeriod
+	}
+	if gdp.Spec.MinHealthyClusters != nil {
+		minHealthy := *gdp.Spec.MinHealthyClusters
+		gf.MinHealthyClusters = &minHealthy
+	}
+	if gdp.Spec.MatchRules.MinAge != nil {
+		minAge := *gdp.Spec.MatchRules.MinAge
+		gf.MinAge = &minAge
+	}
+	gf.WeightMode = gdp.Spec.WeightMode
+	if gf.WeightMode == "" {
+		gf.WeightMode = gdpv1alpha1.WeightModeWeight
+	}
+	if gdp.Spec.WeightRecomputeInterval != nil {
+		interval := *gdp.Spec.WeightRecomputeInterval
+		gf.WeightRecomputeInterval = &interval
 	}
 	// Add applicable clusters
 	gf.ApplicableClusters = gdp.Spec.MatchClusters
-	// Add traffic split
-	for _, ts := range gdp.Spec.TrafficSplit {
-		ct := ClusterTraffic{
-			ClusterName: ts.Cluster,
-			Weight:      int32(ts.Weight),
+	// Add traffic split, the traffic split override takes precedence over the one of the GDP object
+	gf.GDPTrafficSplit = append([]gdpv1alpha1.TrafficSplitElem(nil), gdp.Spec.TrafficSplit...)
+	gf.setTrafficSplit()
+	// Add traffic rules, each rule selects applications via a single label
+	for _, tr := range gdp.Spec.TrafficRules {
+		if len(tr.AppSelector.Label) != 1 {
+			continue
 		}
-		gf.TrafficSplit = append(gf.TrafficSplit, ct)
+		k, v := getLabelKeyAndValue(tr.AppSelector.Label)
+		rule := AppTrafficRule{
+			AppFilter: AppFilter{
+				Label: Label{
+					Key:   k,
+					Value: v,
+				},
+				IgnoreCase: tr.AppSelector.IgnoreCase,
+			},
+			TrafficSplit: getClusterTraffic(tr.TrafficSplit),
+		}
+		gf.TrafficRules = append(gf.TrafficRules, rule)
 	}
+	gf.PolicyApplied = true
 	gf.ComputeChecksum()
 	Logf("ns: %s, object: NSFilter, msg: added/changed the global filter", gdp.ObjectMeta.Namespace)
 }
 
+// FilterValidationError lists the violations of the internal consistency of a global filter.
+type FilterValidationError struct {
+	Violations []string
+}
+
+func (e *FilterValidationError) Error() string {
+	return "invalid filter: " + strings.Join(e.Violations, "; ")
+}
+
+// Validate checks the internal consistency of the filter: the clusters of the traffic split and of
+// the traffic rules must be applicable clusters, the namespaces must be selected only for the
+// applicable clusters, and an applied policy must have at least one selector. Returns a
+// *FilterValidationError with all the violations, or nil.
+func (gf *GlobalFilter) Validate() error {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	return gf.validate()
+}
+
+// validate is Validate for the callers holding the lock.
+func (gf *GlobalFilter) validate() error {
+	var violations []string
+	for _, ct := range gf.TrafficSplit {
+		if !PresentInList(ct.ClusterName, gf.ApplicableClusters) {
+			violations = append(violations, "cluster "+ct.ClusterName+" of the traffic split is not an applicable cluster")
+		}
+	}
+	for _, rule := range gf.TrafficRules {
+		for _, ct := range rule.TrafficSplit {
+			if !PresentInList(ct.ClusterName, gf.ApplicableClusters) {
+				violations = append(violations, "cluster "+ct.ClusterName+" of the traffic rule for "+
+					rule.AppFilter.Label.Key+"="+rule.AppFilter.Label.Value+" is not an applicable cluster")
+			}
+		}
+	}
+	if gf.NSFilter != nil {
+		gf.NSFilter.Lock.RLock()
+		keys := make([]string, 0, len(gf.NSFilter.SelectedNS))
+		for key := range gf.NSFilter.SelectedNS {
+			keys = append(keys, key)
+		}
+		global := gf.NSFilter.Global
+		gf.NSFilter.Lock.RUnlock()
+		// sorted, so that the same violations are reported the same way
+		sort.Strings(keys)
+		for _, key := range keys {
+			if global && key != AllClustersNSKey {
+				violations = append(violations, "namespaces selected for cluster "+key+" by a global namespace selector")
+			} else if !global && !PresentInList(key, gf.ApplicableClusters) {
+				violations = append(violations, "namespaces selected for cluster "+key+" which is not an applicable cluster")
+			}
+		}
+	}
+	if gf.PolicyApplied && gf.AppFilter == nil && gf.NSFilter == nil && gf.SelfScopeNamespace == "" {
+		violations = append(violations, "no app selector or namespace selector, no objects can be selected")
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	return &FilterValidationError{Violations: violations}
+}
+
+// logViolations logs the violations of the internal consistency of the filter built from gdp, the
+// caller must hold the lock.
+func (gf *GlobalFilter) logViolations(gdp *gdpv1alpha1.GlobalDeploymentPolicy) {
+	if err := gf.validate(); err != nil {
+		Warnf("ns: %s, gdp: %s, object: filter, msg: %v", gdp.ObjectMeta.Namespace, gdp.ObjectMeta.Name, err)
+	}
+}
+
+// FilterChecksums are the checksums of the components of a global filter, so that a change to the
+// GDP object can be narrowed down to the components it changed.
+type FilterChecksums struct {
+	// App is the checksum of the app selector
+	App uint32
+	// NS is the checksum of the namespace selector
+	NS uint32
+	// Clusters is the checksum of the applicable clusters
+	Clusters uint32
+	// Traffic is the checksum of the traffic split, the traffic rules and the weight mode
+	Traffic uint32
+	// MatchOptions is the checksum of the rest of the match rules, requireReady, the port names,
+	// the object types and the selector mode
+	MatchOptions uint32
+	// Settings is the checksum of the settings which are read as and when needed, and don't
+	// affect the objects selected or their weights, the grace period and the recompute interval
+	Settings uint32
+}
+
+// FilterChange is a set of the components of a global filter changed by a GDP update.
+type FilterChange uint32
+
+const (
+	FilterChangeApp FilterChange = 1 << iota
+	FilterChangeNS
+	FilterChangeClusters
+	FilterChangeTraffic
+	FilterChangeMatchOptions
+	FilterChangeSettings
+)
+
+var filterChangeNames = []struct {
+	change FilterChange
+	name   string
+}{
+	{FilterChangeApp, "app"},
+	{FilterChangeNS, "namespace"},
+	{FilterChangeClusters, "clusters"},
+	{FilterChangeTraffic, "traffic"},
+	{FilterChangeMatchOptions, "matchOptions"},
+	{FilterChangeSettings, "settings"},
+}
+
+// Has returns true if any of the components in change are changed.
+func (fc FilterChange) Has(change FilterChange) bool {
+	return fc&change != 0
+}
+
+// NeedsReevaluation returns true if the objects selected by the filter may have changed, and so
+// all the objects have to go through the filter again.
+func (fc FilterChange) NeedsReevaluation() bool {
+	return fc.Has(FilterChangeApp | FilterChangeNS | FilterChangeClusters | FilterChangeMatchOptions)
+}
+
+// String returns the names of the changed components, for the logs.
+func (fc FilterChange) String() string {
+	var names []string
+	for _, n := range filterChangeNames {
+		if fc.Has(n.change) {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "none"
+	}
+	return strings.Join(names, ",")
+}
+
+// Changes returns the components whose checksums differ between cs and other.
+func (cs FilterChecksums) Changes(other FilterChecksums) FilterChange {
+	var fc FilterChange
+	if cs.App != other.App {
+		fc |= FilterChangeApp
+	}
+	if cs.NS != other.NS {
+		fc |= FilterChangeNS
+	}
+	if cs.Clusters != other.Clusters {
+		fc |= FilterChangeClusters
+	}
+	if cs.Traffic != other.Traffic {
+		fc |= FilterChangeTraffic
+	}
+	if cs.MatchOptions != other.MatchOptions {
+		fc |= FilterChangeMatchOptions
+	}
+	if cs.Settings != other.Settings {
+		fc |= FilterChangeSettings
+	}
+	return fc
+}
+
+func (cs FilterChecksums) sum() uint32 {
+	var cb ChecksumBuilder
+	cb.Add("app", strconv.FormatUint(uint64(cs.App), 10))
+	cb.Add("namespace", strconv.FormatUint(uint64(cs.NS), 10))
+	cb.Add("clusters", strconv.FormatUint(uint64(cs.Clusters), 10))
+	cb.Add("traffic", strconv.FormatUint(uint64(cs.Traffic), 10))
+	cb.Add("matchOptions", strconv.FormatUint(uint64(cs.MatchOptions), 10))
+	cb.Add("settings", strconv.FormatUint(uint64(cs.Settings), 10))
+	return cb.Sum()
+}
+
+// addClusterTraffic adds a cluster of a traffic split to cb, prefixed by prefix.
+func addClusterTraffic(cb *ChecksumBuilder, ts ClusterTraffic, prefix ...string) {
+	fields := append([]string{}, prefix...)
+	cb.Add(append(fields, ts.ClusterName, strconv.Itoa(int(ts.Weight)), strconv.Itoa(ts.Priority))...)
+}
+
+// ComputeChecksum computes the checksums of the components of the filter, and the checksum of the
+// filter over these. The checksums don't depend on the order of the clusters, the port names and the
+// object types, but do depend on the order of the traffic rules.
 func (gf *GlobalFilter) ComputeChecksum() {
-	var cksum uint32
+	var cs FilterChecksums
 
+	var app ChecksumBuilder
 	if gf.AppFilter != nil {
-		cksum += utils.Hash(gf.AppFilter.Key + gf.AppFilter.Value)
+		app.Add("app", gf.AppFilter.Key, gf.AppFilter.Value)
+		if gf.AppFilter.IgnoreCase {
+			app.Add("appIgnoreCase")
+		}
 	}
+	cs.App = app.Sum()
 	if gf.NSFilter != nil {
-		cksum += gf.NSFilter.GetChecksum()
+		cs.NS = gf.NSFilter.GetChecksum()
 	}
+	var clusters ChecksumBuilder
 	for _, c := range gf.ApplicableClusters {
-		cksum += utils.Hash(c)
+		clusters.Add("cluster", c)
+	}
+	cs.Clusters = clusters.Sum()
+
+	var matchOptions ChecksumBuilder
+	if gf.RequireReady {
+		matchOptions.Add("requireReady")
+	}
+	// the order of the port names decides the port of a service with more than one of these ports
+	for idx, portName := range gf.PortNames {
+		matchOptions.Add("port", strconv.Itoa(idx), portName)
+	}
+	for _, objType := range gf.ObjectTypes {
+		matchOptions.Add("objType", objType)
+	}
+	// the paths of the objects change with the host only types, so the objects have to be sent again
+	for _, objType := range gf.HostOnlyObjectTypes {
+		matchOptions.Add("hostOnly", objType)
+	}
+	if gf.SelfScopeNamespace != "" {
+		matchOptions.Add("selfScope", gf.SelfScopeNamespace)
+	}
+	if gf.MinAge != nil {
+		matchOptions.Add("minAge", strconv.Itoa(*gf.MinAge))
+	}
+	if gf.SelectorMode != gdpv1alpha1.SelectorModeAnd {
+		matchOptions.Add("selectorMode", gf.SelectorMode)
+	}
+	cs.MatchOptions = matchOptions.Sum()
+
+	var settings ChecksumBuilder
+	if gf.WeightRecomputeInterval != nil {
+		settings.Add("recomputeInterval", strconv.Itoa(*gf.WeightRecomputeInterval))
+	}
+	if gf.MemberRemovalGracePeriod != nil {
+		settings.Add("gracePeriod", strconv.Itoa(*gf.MemberRemovalGracePeriod))
+	}
+	if gf.MinHealthyClusters != nil {
+		settings.Add("minHealthyClusters", strconv.Itoa(*gf.MinHealthyClusters))
+	}
+	cs.Settings = settings.Sum()
+
+	var traffic ChecksumBuilder
+	if gf.WeightMode != gdpv1alpha1.WeightModeWeight {
+		traffic.Add("weightMode", gf.WeightMode)
+	}
+	if gf.TrafficSplitOverridden {
+		traffic.Add("splitOverridden")
 	}
 	for _, ts := range gf.TrafficSplit {
-		cksum += utils.Hash(ts.ClusterName + strconv.Itoa(int(ts.Weight)))
+		addClusterTraffic(&traffic, ts, "split")
+	}
+	for idx, tr := range gf.TrafficRules {
+		// the order of the rules matters, so the index is a part of the checksum
+		prefix := []string{"rule", strconv.Itoa(idx), tr.AppFilter.Key, tr.AppFilter.Value,
+			strconv.FormatBool(tr.AppFilter.IgnoreCase)}
+		for _, ts := range tr.TrafficSplit {
+			addClusterTraffic(&traffic, ts, prefix...)
+		}
+		if len(tr.TrafficSplit) == 0 {
+			traffic.Add(prefix...)
+		}
 	}
-	gf.Checksum = cksum
+	cs.Traffic = traffic.Sum()
+
+	gf.Checksums = cs
+	gf.Checksum = cs.sum()
+}
+
+// IsObjTypeSelected returns true if the objects of objType are selected by the filter, the caller
+// must hold the GlobalLock.
+func (gf *GlobalFilter) IsObjTypeSelected(objType string) bool {
+	return len(gf.ObjectTypes) == 0 || PresentInList(objType, gf.ObjectTypes)
 }
 
-func (gf *GlobalFilter) GetTrafficWeight(ns, cname string) (int32, error) {
+// IsHostOnlyObjType returns true if the objects of objType without any paths are advertised with
+// the host only, instead of the default path "/".
+func IsHostOnlyObjType(objType string) bool {
+	gf := GetGlobalFilter()
 	gf.GlobalLock.RLock()
 	defer gf.GlobalLock.RUnlock()
-	for _, ts := range gf.TrafficSplit {
+	return PresentInList(objType, gf.HostOnlyObjectTypes)
+}
+
+// IsNSFilterGlobal returns true if the namespace filter selects the namespaces across all the
+// clusters.
+func (gf *GlobalFilter) IsNSFilterGlobal() bool {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	return gf.NSFilter != nil && gf.NSFilter.Global
+}
+
+// GetChecksum returns the checksum of the global filter, the readers outside of the filter must use
+// this instead of reading the Checksum field, as it is written by the GDP updates.
+func (gf *GlobalFilter) GetChecksum() uint32 {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	return gf.Checksum
+}
+
+// SetDefaultWeightPolicy sets the policy to be followed by GetTrafficWeight for clusters
+// which don't have a weight in the traffic split.
+func (gf *GlobalFilter) SetDefaultWeightPolicy(policy string) error {
+	if !IsDefaultWeightPolicyValid(policy) {
+		return errors.New("invalid default weight policy " + policy)
+	}
+	gf.GlobalLock.Lock()
+	defer gf.GlobalLock.Unlock()
+	gf.DefaultWeightPolicy = policy
+	return nil
+}
+
+// GetDefaultWeightPolicy returns the default weight policy of the global filter.
+func (gf *GlobalFilter) GetDefaultWeightPolicy() string {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	return gf.DefaultWeightPolicy
+}
+
+// getTrafficSplit returns the traffic split of the first traffic rule matching the labels of
+// an object. If no rule matches, the default traffic split is returned.
+func (gf *GlobalFilter) getTrafficSplit(labels map[string]string) []ClusterTraffic {
+	for idx := range gf.TrafficRules {
+		if gf.TrafficRules[idx].AppFilter.Matches(labels) {
+			return gf.TrafficRules[idx].TrafficSplit
+		}
+	}
+	return gf.TrafficSplit
+}
+
+// GetTrafficWeight returns the weight for cluster cname from the traffic split applicable to
+// an object with labels. If the cluster doesn't have an entry, the DefaultWeightPolicy decides
+// the weight. In the percentage mode, the percentage is returned as the weight, and a cluster
+// without an entry gets 0, as the traffic split accounts for all of the traffic. In the backends
+// mode, the weight is computed from the ready backends of the cluster, if known. The weight of the
+// object objWeight, if set (non-zero), takes precedence over the weight of the cluster, except in
+// the percentage mode, where the percentages of the clusters must account for all of the traffic.
+func (gf *GlobalFilter) GetTrafficWeight(ns, cname string, labels map[string]string, objWeight int32) (int32, error) {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	if objWeight != 0 && gf.WeightMode != gdpv1alpha1.WeightModePercentage {
+		return objWeight, nil
+	}
+	if weight, ok := gf.getBackendWeight(cname); ok {
+		return weight, nil
+	}
+	trafficSplit := gf.getTrafficSplit(labels)
+	for _, ts := range trafficSplit {
 		if ts.ClusterName == cname {
 			return ts.Weight, nil
 		}
 	}
+	if gf.WeightMode == gdpv1alpha1.WeightModePercentage && len(trafficSplit) > 0 {
+		Debugf("cname: %s, msg: no percentage available for this cluster, using 0", cname)
+		return 0, nil
+	}
+	switch gf.DefaultWeightPolicy {
+	case DefaultWeightZero:
+		Debugf("cname: %s, msg: no weight available for this cluster, using weight 0", cname)
+		return 0, nil
+	case DefaultWeightEqualShare:
+		weight := getEqualShareWeight(trafficSplit)
+		Debugf("cname: %s, msg: no weight available for this cluster, using equal share weight %d",
+			cname, weight)
+		return weight, nil
+	}
 	Logf("cname: %s, msg: no weight available for this cluster", cname)
-	return 0, errors.New("no weight available for cluster " + cname)
+	return 0, fmt.Errorf("cluster %s: %w", cname, ErrNoTrafficWeight)
 }
 
-func PresentInList(key string, strList []string) bool {
-	for _, str := range strList {
-		if str == key {
-			return true
+// GetWeightMode returns the mode of the weights of the traffic splits, WeightModeWeight or
+// WeightModePercentage.
+func (gf *GlobalFilter) GetWeightMode() string {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	return gf.WeightMode
+}
+
+// PercentageToRatio converts a percentage of the traffic to the ratio of a GSLB pool member,
+// which ranges from 1 to MaxRatio. The ratio is rounded up, so a non-zero percentage never gets
+// a ratio of 0. A percentage of 0 (a cluster without an entry) gets a ratio of 0, the members with
+// a ratio of 0 are disabled in the GSLB pool.
+func PercentageToRatio(percentage int32) int32 {
+	if percentage <= 0 {
+		return 0
+	}
+	if percentage >= 100 {
+		return MaxRatio
+	}
+	return (percentage*MaxRatio + 99) / 100
+}
+
+// GetTrafficPriority returns the priority for cluster cname from the traffic split applicable to
+// an object with labels. Clusters without an entry get the DefaultPriority.
+func (gf *GlobalFilter) GetTrafficPriority(cname string, labels map[string]string) int {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	for _, ts := range gf.getTrafficSplit(labels) {
+		if ts.ClusterName == cname {
+			return ts.Priority
 		}
 	}
-	return false
+	return DefaultPriority
 }
 
-func isTrafficWeightChanged(new, old *gdpv1alpha1.GlobalDeploymentPolicy) bool {
-	// There are 3 conditions when a cluster traffic ratio is different between the old
-	// and new GDP objects:
-	// 1. Length of the Traffic Split elements is different between the two.
-	// 2. Length is same, but a member from the old list is not found in the new list.
-	// 3. Length is same, but a member has different ratios across both the objects.
+// GetPriorityGroups groups the clusters of the traffic split applicable to an object with labels
+// by their priorities. Within a priority group, the traffic is split as per the weights of the
+// clusters.
+func (gf *GlobalFilter) GetPriorityGroups(labels map[string]string) map[int][]ClusterTraffic {
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	groups := make(map[int][]ClusterTraffic)
+	for _, ts := range gf.getTrafficSplit(labels) {
+		groups[ts.Priority] = append(groups[ts.Priority], ts)
+	}
+	return groups
+}
 
-	if len(old.Spec.TrafficSplit) != len(new.Spec.TrafficSplit) {
-		return true
+// GetSameRegionClusters returns the applicable clusters of the GDP object which are in region,
+// whether or not these have an entry in the traffic split. Only the clusters which are a part of
+// the GSLB cluster are returned.
+func (gf *GlobalFilter) GetSameRegionClusters(region string) []string {
+	if region == "" {
+		return nil
 	}
-	for _, oldMember := range old.Spec.TrafficSplit {
-		found := false
-		for _, newMember := range new.Spec.TrafficSplit {
-			if oldMember.Cluster == newMember.Cluster {
-				found = true
-				if oldMember.Weight != newMember.Weight {
-					return true
-				}
-			}
+	gf.GlobalLock.RLock()
+	defer gf.GlobalLock.RUnlock()
+	var clusters []string
+	for _, cname := range gf.ApplicableClusters {
+		if GetClusterRegion(cname) != region || !IsClusterContextPresent(cname) {
+			continue
 		}
-		if found == false {
-			// this member was not found in the new GDP, so return true
+		clusters = append(clusters, cname)
+	}
+	return clusters
+}
+
+// getEqualShareWeight returns the average of all the weights in the traffic split, so that a
+// cluster without a weight gets the same share as any other cluster. If no weights are present,
+// all the clusters get a weight of 1.
+func getEqualShareWeight(trafficSplit []ClusterTraffic) int32 {
+	if len(trafficSplit) == 0 {
+		return 1
+	}
+	var total int32
+	for _, ts := range trafficSplit {
+		total += ts.Weight
+	}
+	weight := total / int32(len(trafficSplit))
+	if weight < 1 {
+		return 1
+	}
+	return weight
+}
+
+// IsDefaultWeightPolicyValid checks if policy is one of the supported default weight policies.
+func IsDefaultWeightPolicyValid(policy string) bool {
+	switch policy {
+	case DefaultWeightZero, DefaultWeightEqualShare, DefaultWeightError:
+		return true
+	}
+	return false
+}
+
+func PresentInList(key string, strList []string) bool {
+	for _, str := range strList {
+		if str == key {
 			return true
 		}
 	}
@@ -304,32 +1080,91 @@ func isTrafficWeightChanged(new, old *gdpv1alpha1.GlobalDeploymentPolicy) bool {
 
 // UpdateGlobalFilter takes two arguments: the old and the new GDP objects, and verifies
 // whether a change is required to any of the filters. If yes, it changes either the cluster
-// filter or one of the namespace filters.
+// filter or one of the namespace filters. Returns true if the filter changed, and true if the
+// traffic weights changed.
 func (gf *GlobalFilter) UpdateGlobalFilter(oldGDP, newGDP *gdpv1alpha1.GlobalDeploymentPolicy) (bool, bool) {
-	// Need to check for the NSFilterMap
+	changes := gf.UpdateFilter(oldGDP, newGDP)
+	return changes != 0, changes.Has(FilterChangeTraffic)
+}
+
+// UpdateFilter updates the filter from the new GDP object, and returns the components of the
+// filter changed by the update, as found by comparing the checksums of the components. The new
+// filter is built fully before taking the lock and swapped in a single lock section, so the readers
+// see either the old or the new filter, and the unchanged traffic weights persist across the update.
+func (gf *GlobalFilter) UpdateFilter(oldGDP, newGDP *gdpv1alpha1.GlobalDeploymentPolicy) FilterChange {
 	nf := GetNewGlobalFilter()
-	nf.AddToFilter(newGDP)
+	// nf isn't shared yet, so it needn't be locked, and it is validated once it's applied
+	nf.addToFilter(newGDP)
 
 	Logf("ns: %s, gdp: %s, msg: %s", oldGDP.ObjectMeta.Namespace, oldGDP.ObjectMeta.Name,
 		"got an update event")
 	gf.GlobalLock.Lock()
 	defer gf.GlobalLock.Unlock()
-	Debugf("old checksum: %d, new checksum: %d", gf.Checksum, nf.Checksum)
-	if gf.Checksum == nf.Checksum {
+	Debugf("old checksums: %+v, new checksums: %+v", gf.Checksums, nf.Checksums)
+	changes := gf.Checksums.Changes(nf.Checksums)
+	if changes == 0 {
 		// No updates needed, just return
-		return false, false
+		return 0
 	}
-	Logf("ns: %s, gdp: %s, object: filter, msg: %s", oldGDP.ObjectMeta.Namespace, oldGDP.ObjectMeta.Name,
-		"filter changed, will update filter and re-evaluate objects")
+	// nf isn't shared yet, so it needn't be locked for the diff
+	Logf("ns: %s, gdp: %s, object: filter, changed: %s, diff: %s, msg: %s", oldGDP.ObjectMeta.Namespace,
+		oldGDP.ObjectMeta.Name, changes.String(), gf.diff(nf), "filter changed, will update the filter")
 	// update the filter if the checksums changed
 	gf.AppFilter = nf.AppFilter
 	gf.NSFilter = nf.NSFilter
 	gf.TrafficSplit = nf.TrafficSplit
+	gf.TrafficSplitOverridden = nf.TrafficSplitOverridden
+	gf.GDPTrafficSplit = nf.GDPTrafficSplit
+	gf.TrafficRules = nf.TrafficRules
 	gf.ApplicableClusters = nf.ApplicableClusters
+	gf.RequireReady = nf.RequireReady
+	gf.PortNames = nf.PortNames
+	gf.ObjectTypes = nf.ObjectTypes
+	gf.HostOnlyObjectTypes = nf.HostOnlyObjectTypes
+	gf.SelfScopeNamespace = nf.SelfScopeNamespace
+	gf.SelectorMode = nf.SelectorMode
+	gf.MemberRemovalGracePeriod = nf.MemberRemovalGracePeriod
+	gf.MinHealthyClusters = nf.MinHealthyClusters
+	gf.MinAge = nf.MinAge
+	if gf.WeightMode != nf.WeightMode {
+		// the backends are counted again for the new mode
+		gf.backendCounts = nil
+	}
+	gf.WeightMode = nf.WeightMode
+	gf.WeightRecomputeInterval = nf.WeightRecomputeInterval
+	gf.Checksums = nf.Checksums
 	gf.Checksum = nf.Checksum
+	// DefaultWeightPolicy is not a part of the GDP object, so it stays as it is
+	gf.logViolations(newGDP)
 
-	trafficWeightChanged := isTrafficWeightChanged(newGDP, oldGDP)
-	return true, trafficWeightChanged
+	return changes
+}
+
+// setTrafficSplit sets the traffic split from the traffic split override, or from the traffic split
+// of the GDP object if there's no override. The caller must hold the lock, if the filter is shared.
+func (gf *GlobalFilter) setTrafficSplit() {
+	trafficSplit := gf.GDPTrafficSplit
+	gf.TrafficSplitOverridden = false
+	if override, ok := GetTrafficSplitOverride(); ok {
+		trafficSplit = override
+		gf.TrafficSplitOverridden = true
+	}
+	gf.TrafficSplit = getClusterTraffic(trafficSplit)
+}
+
+// ApplyTrafficSplitOverride rebuilds the traffic split of the filter after the traffic split override
+// was set or removed, and returns the components of the filter changed. The rest of the filter stays
+// as per the accepted GDP object, so only FilterChangeTraffic can be returned.
+func (gf *GlobalFilter) ApplyTrafficSplitOverride() FilterChange {
+	gf.GlobalLock.Lock()
+	defer gf.GlobalLock.Unlock()
+	if !gf.PolicyApplied {
+		return 0
+	}
+	oldChecksums := gf.Checksums
+	gf.setTrafficSplit()
+	gf.ComputeChecksum()
+	return oldChecksums.Changes(gf.Checksums)
 }
 
 // DeleteFromGlobalFilter deletes a filter pertaining to gdp.
@@ -340,7 +1175,26 @@ func (gf *GlobalFilter) DeleteFromGlobalFilter(gdp *gdpv1alpha1.GlobalDeployment
 	gf.NSFilter = nil
 	gf.ApplicableClusters = []string{}
 	gf.Checksum = 0
+	gf.Checksums = FilterChecksums{}
 	gf.TrafficSplit = []ClusterTraffic{}
+	gf.TrafficSplitOverridden = false
+	gf.GDPTrafficSplit = nil
+	gf.TrafficRules = []AppTrafficRule{}
+	gf.RequireReady = false
+	gf.PortNames = []string{}
+	gf.ObjectTypes = []string{}
+	gf.HostOnlyObjectTypes = []string{}
+	gf.SelfScopeNamespace = ""
+	gf.SelectorMode = gdpv1alpha1.SelectorModeAnd
+	gf.MemberRemovalGracePeriod = nil
+	gf.MinHealthyClusters = nil
+	gf.MinAge = nil
+	gf.WeightMode = gdpv1alpha1.WeightModeWeight
+	gf.WeightRecomputeInterval = nil
+	gf.backendCounts = nil
+	gf.PolicyApplied = false
+	// all the objects get rejected without a GDP object
+	gf.objCounter.reset()
 }
 
 // GetNewGlobalFilter returns a new GlobalFilter. It is to be called only once with the
@@ -348,16 +1202,51 @@ func (gf *GlobalFilter) DeleteFromGlobalFilter(gdp *gdpv1alpha1.GlobalDeployment
 // or its some other namespace. Based on that this GlobalFilter is created.
 func GetNewGlobalFilter() *GlobalFilter {
 	gf := &GlobalFilter{
-		AppFilter:          nil,
-		NSFilter:           nil,
-		TrafficSplit:       []ClusterTraffic{},
-		ApplicableClusters: []string{},
+		AppFilter:           nil,
+		NSFilter:            nil,
+		TrafficSplit:        []ClusterTraffic{},
+		TrafficRules:        []AppTrafficRule{},
+		ApplicableClusters:  []string{},
+		DefaultWeightPolicy: DefaultWeightEqualShare,
+		SelectorMode:        gdpv1alpha1.SelectorModeAnd,
+		WeightMode:          gdpv1alpha1.WeightModeWeight,
+		objCounter:          newClusterObjCounter(0),
 	}
 	return gf
 }
 
-// ClusterTraffic determines the "Weight" of traffic routed to a cluster with name "ClusterName"
+const (
+	// DefaultWeightZero gives a weight of 0 to clusters missing in the traffic split
+	DefaultWeightZero = "zero"
+	// DefaultWeightEqualShare gives an equal share of traffic to clusters missing in the traffic split
+	DefaultWeightEqualShare = "equal-share"
+	// DefaultWeightError returns an error for clusters missing in the traffic split
+	DefaultWeightError = "error"
+)
+
+const (
+	// DefaultPriority is the priority of the clusters which don't have a priority in the traffic split
+	DefaultPriority = 10
+	// MaxPriority is the highest priority allowed for a cluster, same as the GSLB pool priority
+	MaxPriority = 100
+	// DefaultMemberRemovalGracePeriod is the default grace period (in seconds) for which the GSLB
+	// member of a deleted object is retained
+	DefaultMemberRemovalGracePeriod = 5
+	// MaxMemberRemovalGracePeriod is the longest grace period (in seconds) allowed for the removal
+	// of a GSLB member
+	MaxMemberRemovalGracePeriod = 300
+	// MaxMinObjectAge is the longest min age (in seconds) allowed for the objects to be advertised
+	MaxMinObjectAge = 86400
+	// MaxRatio is the highest ratio allowed for a GSLB pool member
+	MaxRatio = 20
+)
+
+// ClusterTraffic determines the "Weight" of traffic routed to a cluster with name "ClusterName".
+// Clusters with the highest "Priority" get all the traffic, till they have healthy members.
 type ClusterTraffic struct {
 	ClusterName string
 	Weight      int32
+	Priority    int
+	// Region of the cluster, as set for the member cluster in the GSLBConfig object
+	Region string
 }
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"strings"
+	"sync"
+)
+
+// gslbDomains holds the DNS subdomains under which the GSLB services are allowed. All the
+// hostnames are allowed if the list is empty.
+type gslbDomains struct {
+	domains []string
+	lock    sync.RWMutex
+}
+
+var allowedGslbDomains gslbDomains
+
+func normalizeDomain(domain string) string {
+	return strings.ToLower(strings.Trim(strings.TrimSpace(domain), "."))
+}
+
+// ValidateGslbDomains returns an error if any of the domains is empty.
+func ValidateGslbDomains(domains []string) error {
+	for _, domain := range domains {
+		if normalizeDomain(domain) == "" {
+			return errors.New("gslb domain can't be empty")
+		}
+	}
+	return nil
+}
+
+// SetGslbDomains replaces the list of the allowed GSLB subdomains, it can be called at runtime.
+// An empty list allows all the hostnames. Returns true if the list changed.
+func SetGslbDomains(domains []string) (bool, error) {
+	if err := ValidateGslbDomains(domains); err != nil {
+		return false, err
+	}
+	newDomains := []string{}
+	for _, domain := range domains {
+		domain = normalizeDomain(domain)
+		if !PresentInList(domain, newDomains) {
+			newDomains = append(newDomains, domain)
+		}
+	}
+	allowedGslbDomains.lock.Lock()
+	defer allowedGslbDomains.lock.Unlock()
+	if len(newDomains) == len(allowedGslbDomains.domains) {
+		changed := false
+		for _, domain := range newDomains {
+			if !PresentInList(domain, allowedGslbDomains.domains) {
+				changed = true
+				break
+			}
+		}
+		if !changed {
+			return false, nil
+		}
+	}
+	allowedGslbDomains.domains = newDomains
+	return true, nil
+}
+
+// GetGslbDomains returns a copy of the list of the allowed GSLB subdomains.
+func GetGslbDomains() []string {
+	allowedGslbDomains.lock.RLock()
+	defer allowedGslbDomains.lock.RUnlock()
+	return append([]string{}, allowedGslbDomains.domains...)
+}
+
+// IsHostnameInGslbDomain returns true if fqdn is one of the allowed GSLB subdomains or ends in
+// one of them, or if no GSLB subdomains are configured.
+func IsHostnameInGslbDomain(fqdn string) bool {
+	allowedGslbDomains.lock.RLock()
+	defer allowedGslbDomains.lock.RUnlock()
+	if len(allowedGslbDomains.domains) == 0 {
+		return true
+	}
+	fqdn = normalizeDomain(fqdn)
+	for _, domain := range allowedGslbDomains.domains {
+		if fqdn == domain || strings.HasSuffix(fqdn, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
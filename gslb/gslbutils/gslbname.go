@@ -0,0 +1,115 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+)
+
+const (
+	// GslbNameCollisionHashSuffix suffixes the GSLB service names of all but the first hostname
+	// with a hash of the hostname
+	GslbNameCollisionHashSuffix = "hash-suffix"
+	// GslbNameCollisionReject rejects all but the first hostname
+	GslbNameCollisionReject = "reject"
+	// GslbNameCollisionMerge merges all the hostnames into the same GSLB service
+	GslbNameCollisionMerge = "merge"
+	// DefaultGslbNameCollisionStrategy keeps a single GSLB service per name, as before the
+	// strategies were added
+	DefaultGslbNameCollisionStrategy = GslbNameCollisionMerge
+
+	gslbNameHashLen = 8
+)
+
+// ErrGslbNameCollision is returned by ResolveGslbName if the hostname is rejected because its GSLB
+// service name belongs to another hostname.
+var ErrGslbNameCollision = errors.New("gslb service name is already used by another hostname")
+
+var gslbNameCollision = struct {
+	strategy string
+	lock     sync.RWMutex
+}{strategy: DefaultGslbNameCollisionStrategy}
+
+// SetGslbNameCollisionStrategy sets the strategy used by ResolveGslbName.
+func SetGslbNameCollisionStrategy(strategy string) error {
+	if strategy != GslbNameCollisionHashSuffix && strategy != GslbNameCollisionReject &&
+		strategy != GslbNameCollisionMerge {
+		return errors.New("gslb name collision strategy " + strategy + " must be one of " +
+			GslbNameCollisionHashSuffix + ", " + GslbNameCollisionReject + ", " + GslbNameCollisionMerge)
+	}
+	gslbNameCollision.lock.Lock()
+	defer gslbNameCollision.lock.Unlock()
+	gslbNameCollision.strategy = strategy
+	return nil
+}
+
+// GetGslbNameCollisionStrategy returns the strategy used by ResolveGslbName.
+func GetGslbNameCollisionStrategy() string {
+	gslbNameCollision.lock.RLock()
+	defer gslbNameCollision.lock.RUnlock()
+	return gslbNameCollision.strategy
+}
+
+// GslbNameForHostname returns the GSLB service name that a hostname maps to, the hostnames which
+// differ only in case or in the leading and trailing dots map to the same name.
+func GslbNameForHostname(hostname string) string {
+	return normalizeDomain(hostname)
+}
+
+// ResolveGslbName returns the GSLB service name for candidates[0], where candidates are the
+// hostnames which map to the same GSLB service name. The first hostname in the sorted order owns
+// the name, so that the resolution doesn't depend on the order in which the hostnames are seen.
+// The other hostnames are resolved as per the collision strategy: suffixed with a hash of the
+// hostname, rejected with ErrGslbNameCollision, or merged into the same GSLB service.
+func ResolveGslbName(candidates []string) (string, error) {
+	if len(candidates) == 0 {
+		return "", errors.New("no hostnames to resolve the gslb service name")
+	}
+	hostname := candidates[0]
+	name := GslbNameForHostname(hostname)
+	if name == "" {
+		return "", errors.New("hostname " + hostname + " maps to an empty gslb service name")
+	}
+	sorted := append([]string{}, candidates...)
+	sort.Strings(sorted)
+	for _, candidate := range sorted {
+		if GslbNameForHostname(candidate) != name {
+			return "", errors.New("hostnames " + hostname + " and " + candidate +
+				" don't map to the same gslb service name")
+		}
+	}
+	if sorted[0] == hostname {
+		return name, nil
+	}
+	return ResolveGslbNameCollision(hostname)
+}
+
+// ResolveGslbNameCollision returns the GSLB service name for hostname, whose name is owned by a
+// different hostname, as per the collision strategy.
+func ResolveGslbNameCollision(hostname string) (string, error) {
+	name := GslbNameForHostname(hostname)
+	switch GetGslbNameCollisionStrategy() {
+	case GslbNameCollisionHashSuffix:
+		hash := sha1.Sum([]byte(hostname))
+		return name + "-" + hex.EncodeToString(hash[:])[:gslbNameHashLen], nil
+	case GslbNameCollisionReject:
+		return "", ErrGslbNameCollision
+	}
+	return name, nil
+}
@@ -30,6 +30,7 @@ import (
 
 	routev1 "github.com/openshift/api/route/v1"
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
 	"k8s.io/client-go/kubernetes"
 )
@@ -45,20 +46,52 @@ const (
 	ObjectAdd    = "ADD"
 	ObjectDelete = "DELETE"
 	ObjectUpdate = "UPDATE"
+	// ObjectRatioUpdate only updates the weight and the priority of the GSLB members of an object
+	ObjectRatioUpdate = "RATIOUPDATE"
+	// ObjectPortUpdate only updates the port and the protocol of the GSLB members of an LB service,
+	// and the health monitors
+	ObjectPortUpdate = "PORTUPDATE"
+	// ObjectDelayedDelete deletes the GSLB members of a deleted object once its member removal grace
+	// period is over
+	ObjectDelayedDelete = "DELAYEDDELETE"
+	// ObjectStaleDelete deletes the GSLB members of a stale object, found by the GC sweep of the
+	// host maps without a backing object in the stores
+	ObjectStaleDelete = "STALEDELETE"
 	// Ingestion layer objects
 	RouteType        = gslbalphav1.RouteObj
 	IngressType      = gslbalphav1.IngressObj
+	HTTPRouteType    = gslbalphav1.HTTPRouteObj
 	SvcType          = gslbalphav1.LBSvcObj
 	PassthroughRoute = "passthrough"
+	// SNIHostsAnnotation lists the additional SNI hosts (comma separated) served by a passthrough route
+	SNIHostsAnnotation = "amko.vmware.com/sni-hosts"
+	// AdditionalHostsAnnotation lists the additional hosts (comma separated) served by a route, a
+	// GSLB service is created for each of these hosts along with the route's host
+	AdditionalHostsAnnotation = "amko.vmware.com/additional-hosts"
+	// ExcludePathsAnnotation lists the paths (comma separated) of an ingress which are not a part
+	// of the GSLB services for its hosts
+	ExcludePathsAnnotation = "amko.vmware.com/exclude-paths"
+	// IncludeHostsAnnotation lists the hosts (comma separated) of an ingress which are a part of the
+	// GSLB services, the other hosts of the ingress are left out, all the hosts are included if not
+	// specified
+	IncludeHostsAnnotation = "amko.vmware.com/include-hosts"
+	// ExcludeHostsAnnotation lists the hosts (comma separated) of an ingress which are not a part of
+	// the GSLB services
+	ExcludeHostsAnnotation = "amko.vmware.com/exclude-hosts"
+	// HmSNIHostAnnotation is the SNI host sent by the HTTPS health monitors of a TLS route, the
+	// route's host is sent if not specified
+	HmSNIHostAnnotation = "amko.vmware.com/hm-sni-host"
+	// IngressAllowHTTPAnnotation set to "false" disables the plain HTTP for the TLS hosts of an
+	// ingress, these hosts are served only on the HTTPS port
+	IngressAllowHTTPAnnotation = "kubernetes.io/ingress.allow-http"
 	// Refresh cycle for AVI cache in seconds
 	DefaultRefreshInterval = 600
 	// Store types
 	AcceptedStore = "Accepted"
 	RejectedStore = "Rejected"
 
-	// Multi-cluster key lengths
-	IngMultiClusterKeyLen = 6
-	MultiClusterKeyLen    = 5
+	// Multi-cluster key length
+	MultiClusterKeyLen = 5
 
 	// Default values for Retry Operations
 	SlowSyncTime      = 120
@@ -80,6 +113,8 @@ const (
 	SystemGslbHealthMonitorTCP   = "System-GSLB-TCP"
 	SystemGslbHealthMonitorHTTP  = "HEALTH_MONITOR_HTTP"
 	SystemGslbHealthMonitorHTTPS = "HEALTH_MONITOR_HTTPS"
+	// SSL profile of the HTTPS health monitors which send an SNI host
+	SystemHealthMonitorSSLProfile = "System-Standard"
 
 	// default passthrough health monitor (TCP), to be used for all passthrough routes
 	SystemGslbHealthMonitorPassthrough = "amko--passthrough-hm-tcp"
@@ -110,58 +145,55 @@ func GetInformersPerCluster(clusterName string) *utils.Informers {
 }
 
 func MultiClusterKey(operation, objType, clusterName, ns, objName string) string {
-	compositeObjName := clusterName + "/" + ns + "/" + objName
-	return MultiClusterKeyWithObjName(operation, objType, compositeObjName)
+	return MultiClusterKeyWithObjName(operation, objType, GetClusterKey(clusterName, ns, objName))
 }
 
 func MultiClusterKeyWithObjName(operation, objType, compositeName string) string {
-	return operation + "/" + objType + "/" + compositeName
+	return JoinKey(operation, objType) + KeyDelimiter + compositeName
 }
 
+// ExtractMultiClusterKey reverses MultiClusterKey, returns empty strings if the key is malformed.
 func ExtractMultiClusterKey(key string) (string, string, string, string, string) {
-	segments := strings.Split(key, "/")
-	var operation, objType, cluster, ns, name, hostname string
-	if segments[1] == IngressType {
-		if len(segments) == IngMultiClusterKeyLen {
-			operation, objType, cluster, ns, name, hostname = segments[0], segments[1], segments[2], segments[3], segments[4], segments[5]
-			name += "/" + hostname
-		}
-	} else if len(segments) == MultiClusterKeyLen {
-		operation, objType, cluster, ns, name = segments[0], segments[1], segments[2], segments[3], segments[4]
+	segments, err := SplitKey(key)
+	if err != nil || len(segments) != MultiClusterKeyLen {
+		return "", "", "", "", ""
 	}
-	return operation, objType, cluster, ns, name
+	return segments[0], segments[1], segments[2], segments[3], segments[4]
 }
 
 func SplitMultiClusterObjectName(name string) (string, string, string, error) {
 	if name == "" {
 		return "", "", "", errors.New("multi-cluster route/svc name is empty")
 	}
-	reqList := strings.Split(name, "/")
-
-	if len(reqList) != 3 {
+	cname, ns, objName, err := ParseClusterKey(name)
+	if err != nil {
 		return "", "", "", errors.New("multi-cluster route/svc name format is unexpected")
 	}
-	return reqList[0], reqList[1], reqList[2], nil
+	return cname, ns, objName, nil
 }
 
 func SplitMultiClusterIngHostName(name string) (string, string, string, string, error) {
 	if name == "" {
 		return "", "", "", "", errors.New("multi-cluster ingress host name is empty")
 	}
-	reqList := strings.Split(name, "/")
-
-	if len(reqList) != 4 {
+	cname, ns, objName, err := ParseClusterKey(name)
+	if err != nil {
 		return "", "", "", "", errors.New("multi-cluster ingress name format is unexpected")
 	}
-	return reqList[0], reqList[1], reqList[2], reqList[3], nil
+	// ingress host objects are named as <ingress name>/<hostname>
+	reqList := strings.Split(objName, "/")
+	if len(reqList) != 2 {
+		return "", "", "", "", errors.New("multi-cluster ingress name format is unexpected")
+	}
+	return cname, ns, reqList[0], reqList[1], nil
 }
 
 func SplitMultiClusterNS(name string) (string, string, error) {
 	if name == "" {
 		return "", "", errors.New("multi-cluster namespace is empty")
 	}
-	reqList := strings.Split(name, "/")
-	if len(reqList) != 2 {
+	reqList, err := SplitKey(name)
+	if err != nil || len(reqList) != 2 {
 		return "", "", errors.New("multi-cluster namespace format is unexpected")
 	}
 	return reqList[0], reqList[1], nil
@@ -196,13 +228,54 @@ func RouteGetIPAddr(route *routev1.Route) (string, bool) {
 	return "", false
 }
 
+// IsRouteAdmitted returns true if the route was admitted by a router for its host, and not
+// rejected by any of the routers.
+func IsRouteAdmitted(route *routev1.Route) bool {
+	admitted := false
+	for _, ingr := range route.Status.Ingress {
+		if ingr.Host != route.Spec.Host {
+			continue
+		}
+		for _, condition := range ingr.Conditions {
+			if condition.Type != routev1.RouteAdmitted {
+				continue
+			}
+			if condition.Status != corev1.ConditionTrue {
+				return false
+			}
+			admitted = true
+		}
+	}
+	return admitted
+}
+
+// IsIngressReady returns true if the ingress controller populated the status of the ingress, or,
+// if the annotation is one of the ingress ip sources, the annotation has an IP address.
+func IsIngressReady(ingress *v1beta1.Ingress) bool {
+	if len(ingress.Status.LoadBalancer.Ingress) > 0 {
+		return true
+	}
+	ingressIPSrc.lock.RLock()
+	defer ingressIPSrc.lock.RUnlock()
+	if !PresentInList(IngressIPSourceAnnotation, ingressIPSrc.sources) {
+		return false
+	}
+	_, _, ok := getIngressAnnotationAddr(ingress, ingressIPSrc.annotation)
+	return ok
+}
+
 type IngressHostIP struct {
 	Hostname string
 	IPAddr   string
+	// IPAddrs are all the IP addresses of the host, IPAddr is the first of these
+	IPAddrs []string
+	// LBHostname is the hostname of the load balancer, if the load balancer doesn't expose an IP
+	// address
+	LBHostname string
 }
 
 func getHostListFromIngress(ingress *v1beta1.Ingress) []string {
-	hostList := []string{}
+	hostList := make([]string, 0, len(ingress.Spec.Rules))
 	for _, rule := range ingress.Spec.Rules {
 		if rule.Host != "" {
 			hostList = append(hostList, rule.Host)
@@ -211,33 +284,29 @@ func getHostListFromIngress(ingress *v1beta1.Ingress) []string {
 	return hostList
 }
 
+// IngressGetIPAddrs returns the IP addresses of the hosts of an ingress, as per the configured
+// ingress ip sources.
 func IngressGetIPAddrs(ingress *v1beta1.Ingress) []IngressHostIP {
-	ingHostIP := []IngressHostIP{}
 	hostList := getHostListFromIngress(ingress)
-	ingStatus := ingress.Status
-	ingList := ingStatus.LoadBalancer.Ingress
-	if len(ingList) == 0 {
-		Logf("Ingress %s doesn't have the status field populated", ingress.GetObjectMeta().GetName())
-		Debugf("Ingress: %v", ingress)
-		return ingHostIP
-	}
-	for _, ingr := range ingList {
-		// Check if this is a IP address
-		addr := net.ParseIP(ingr.IP)
-		if addr == nil {
-			Warnf("Address %s is not an IP address: %s", addr)
-			continue
-		}
-		// Found an IP address, return
-		if ingr.Hostname == "" {
-			Warnf("Hostname is empty in ingress %s", ingress.Name)
-			continue
+	ingressIPSrc.lock.RLock()
+	defer ingressIPSrc.lock.RUnlock()
+	if len(ingressIPSrc.sources) == 1 && ingressIPSrc.sources[0] == IngressIPSourceStatus {
+		return getIPAddrsFromStatus(ingress, hostList)
+	}
+	ingHostIP := []IngressHostIP{}
+	for _, source := range ingressIPSrc.sources {
+		var hostIPs []IngressHostIP
+		switch source {
+		case IngressIPSourceStatus:
+			hostIPs = getIPAddrsFromStatus(ingress, hostList)
+		case IngressIPSourceAnnotation:
+			hostIPs = getIPAddrsFromAnnotation(ingress, hostList, ingressIPSrc.annotation)
 		}
-		if utils.HasElem(hostList, ingr.Hostname) {
-			ingHostIP = append(ingHostIP, IngressHostIP{
-				Hostname: ingr.Hostname,
-				IPAddr:   ingr.IP,
-			})
+		// the hosts already picked up from a source with a higher precedence are skipped
+		for _, hip := range hostIPs {
+			if !isHostInHostIPs(hip.Hostname, ingHostIP) {
+				ingHostIP = append(ingHostIP, hip)
+			}
 		}
 	}
 	return ingHostIP
@@ -263,8 +332,11 @@ var (
 	RejectedLBSvcStore   *ClusterStore
 	AcceptedIngressStore *ClusterStore
 	RejectedIngressStore *ClusterStore
-	AcceptedNSStore      *ObjectStore
-	RejectedNSStore      *ObjectStore
+	// HTTPRoute stores have the HTTPRoute host objects, keyed on <route name>/<hostname>
+	AcceptedHTTPRouteStore *ClusterStore
+	RejectedHTTPRouteStore *ClusterStore
+	AcceptedNSStore        *ObjectStore
+	RejectedNSStore        *ObjectStore
 )
 
 func GetGSLBServiceChecksum(ipList, domainList, memberObjs []string, hmNames []string) uint32 {
@@ -281,9 +353,40 @@ func GetGSLBServiceChecksum(ipList, domainList, memberObjs []string, hmNames []s
 		utils.Hash(utils.Stringify(hmNames))
 }
 
-func GetGSLBHmChecksum(name, hmType string, port int32) uint32 {
+// GetGSMemberKey returns the entry of a GSLB service member, as used in the GSLB service checksum.
+// The default priority is not a part of the entry, so that the checksum doesn't change for members
+// without a priority.
+func GetGSMemberKey(addr string, weight, priority int32) string {
+	key := addr + "-" + strconv.Itoa(int(weight))
+	if priority != DefaultPriority {
+		key += "-" + strconv.Itoa(int(priority))
+	}
+	return key
+}
+
+// GetGSLBServicePropsChecksum returns the checksum of the GSLB service parameters which can be
+// overridden via a HostOverride object. The default values don't change the checksum.
+func GetGSLBServicePropsChecksum(ttl *int32, poolAlgorithm string) uint32 {
+	var cksum uint32
+	if ttl != nil {
+		cksum += utils.Hash("ttl" + strconv.Itoa(int(*ttl)))
+	}
+	if poolAlgorithm != "" && poolAlgorithm != DefaultPoolAlgorithm {
+		cksum += utils.Hash(poolAlgorithm)
+	}
+	return cksum
+}
+
+// GetGSLBHmChecksum returns the checksum of a health monitor. The SNI host serverName of an HTTPS
+// health monitor, if any, is a part of the checksum, so that a change of the host updates the
+// health monitor.
+func GetGSLBHmChecksum(name, hmType string, port int32, serverName string) uint32 {
 	portStr := strconv.FormatInt(int64(port), 10)
-	return utils.Hash(name) + utils.Hash(hmType) + utils.Hash(portStr)
+	cksum := utils.Hash(name) + utils.Hash(hmType) + utils.Hash(portStr)
+	if serverName != "" {
+		cksum += utils.Hash("sni" + serverName)
+	}
+	return cksum
 }
 
 func GetAviAdminTenantRef() string {
@@ -350,6 +453,32 @@ func UpdateGSLBConfigStatus(msg string) error {
 	return nil
 }
 
+// updateGSLBConfigInformerErrors updates the status of the GSLBConfig object with the last errors of
+// the informers of the member clusters.
+func updateGSLBConfigInformerErrors() {
+	if !PublishGSLBStatus {
+		return
+	}
+	var errs []string
+	for _, ie := range GetInformerErrors() {
+		errs = append(errs, ie.String())
+	}
+	gcObj.configLock.Lock()
+	if gcObj.configObj == nil {
+		gcObj.configLock.Unlock()
+		return
+	}
+	gc := gcObj.configObj.DeepCopy()
+	gcObj.configLock.Unlock()
+	gc.Status.InformerErrors = errs
+	updatedGC, updateErr := GlobalGslbClient.AmkoV1alpha1().GSLBConfigs(gc.ObjectMeta.Namespace).Update(gc)
+	if updateErr != nil {
+		Errf("error in updating the informer errors in the GSLBConfig object: %s", updateErr.Error())
+		return
+	}
+	SetGSLBConfigObj(updatedGC)
+}
+
 // gslbConfigSet and its setter and getter functions, to be used by the AddGSLBConfig method. This value
 // is set to true once a GSLB Configuration has been successfully done.
 var gslbConfigSet bool = false
@@ -365,6 +494,7 @@ func SetGSLBConfig(value bool) {
 var GlobalKubeClient *kubernetes.Clientset
 var GlobalGslbClient *gslbcs.Clientset
 var PublishGDPStatus bool
+var PublishHostOverrideStatus bool
 var PublishGSLBStatus bool
 
 type AviControllerConfig struct {
@@ -395,14 +525,24 @@ func GetAviConfig() AviControllerConfig {
 
 var initializedClusterContexts []string
 
+// clusterContextLock guards initializedClusterContexts, member clusters which were unreachable at
+// startup are added later on
+var clusterContextLock sync.RWMutex
+
 func AddClusterContext(cc string) {
-	if IsClusterContextPresent(cc) {
-		return
+	clusterContextLock.Lock()
+	defer clusterContextLock.Unlock()
+	for _, context := range initializedClusterContexts {
+		if context == cc {
+			return
+		}
 	}
 	initializedClusterContexts = append(initializedClusterContexts, cc)
 }
 
 func IsClusterContextPresent(cc string) bool {
+	clusterContextLock.RLock()
+	defer clusterContextLock.RUnlock()
 	for _, context := range initializedClusterContexts {
 		if context == cc {
 			return true
@@ -411,6 +551,34 @@ func IsClusterContextPresent(cc string) bool {
 	return false
 }
 
+var clusterRegions = struct {
+	sync.RWMutex
+	regions map[string]string
+}{regions: make(map[string]string)}
+
+// SetClusterRegion sets the region of an initialized cluster context. Returns an error if the
+// cluster context is not a part of the GSLB cluster.
+func SetClusterRegion(cc, region string) error {
+	if !IsClusterContextPresent(cc) {
+		return errors.New("cluster " + cc + " is not a member cluster, can't set region " + region)
+	}
+	clusterRegions.Lock()
+	defer clusterRegions.Unlock()
+	if region == "" {
+		delete(clusterRegions.regions, cc)
+		return nil
+	}
+	clusterRegions.regions[cc] = region
+	return nil
+}
+
+// GetClusterRegion returns the region of a cluster context, empty if no region is set.
+func GetClusterRegion(cc string) string {
+	clusterRegions.RLock()
+	defer clusterRegions.RUnlock()
+	return clusterRegions.regions[cc]
+}
+
 var controllerIsLeader bool
 
 func SetControllerAsLeader() {
@@ -547,6 +715,12 @@ func GetPathFromHmName(hmName string) string {
 	return hmNameSplit[3]
 }
 
+// IsPathHmName returns true if hmName is the name of a path based health monitor of gsName.
+func IsPathHmName(gsName, hmName string) bool {
+	return strings.HasPrefix(hmName, BuildHmPathName(gsName, "", false)) ||
+		strings.HasPrefix(hmName, BuildHmPathName(gsName, "", true))
+}
+
 func BuildNonPathHmName(gsName string) string {
 	return "amko--" + gsName
 }
@@ -0,0 +1,99 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+)
+
+// DefaultHashRingReplicas is the default number of points of each cluster on a hash ring, more
+// points spread the keys more evenly across the clusters.
+const DefaultHashRingReplicas = 100
+
+type ringPoint struct {
+	hash    uint32
+	cluster string
+}
+
+// HashRing places the clusters on a consistent hash ring keyed by their names. The placement of a
+// cluster depends only on its name, so adding or removing a cluster moves only the keys of that
+// cluster, about 1/n of all the keys for n clusters.
+type HashRing struct {
+	points   []ringPoint
+	clusters []string
+}
+
+// NewHashRing returns a hash ring of clusters, each cluster placed at replicas points. The
+// duplicate cluster names are ignored, and DefaultHashRingReplicas is used if replicas isn't
+// positive.
+func NewHashRing(clusters []string, replicas int) *HashRing {
+	if replicas <= 0 {
+		replicas = DefaultHashRingReplicas
+	}
+	ring := &HashRing{}
+	for _, cname := range clusters {
+		if PresentInList(cname, ring.clusters) {
+			continue
+		}
+		ring.clusters = append(ring.clusters, cname)
+		for i := 0; i < replicas; i++ {
+			ring.points = append(ring.points, ringPoint{hash: utils.Hash(cname + "#" + strconv.Itoa(i)), cluster: cname})
+		}
+	}
+	// the cluster names break the ties, so that the ring doesn't depend on the order of clusters
+	sort.Slice(ring.points, func(i, j int) bool {
+		if ring.points[i].hash != ring.points[j].hash {
+			return ring.points[i].hash < ring.points[j].hash
+		}
+		return ring.points[i].cluster < ring.points[j].cluster
+	})
+	sort.Slice(ring.clusters, func(i, j int) bool {
+		return HashRingLess(ring.clusters[i], ring.clusters[j])
+	})
+	return ring
+}
+
+// HashRingLess returns true if cluster c1 comes before cluster c2 in the stable order of a hash
+// ring, i.e. the order of the first of their points on the ring.
+func HashRingLess(c1, c2 string) bool {
+	h1, h2 := utils.Hash(c1+"#0"), utils.Hash(c2+"#0")
+	if h1 != h2 {
+		return h1 < h2
+	}
+	return c1 < c2
+}
+
+// GetCluster returns the cluster on the ring which owns key, the first cluster clockwise from the
+// hash of key. Returns an empty string for an empty ring.
+func (ring *HashRing) GetCluster(key string) string {
+	if len(ring.points) == 0 {
+		return ""
+	}
+	h := utils.Hash(key)
+	idx := sort.Search(len(ring.points), func(i int) bool { return ring.points[i].hash >= h })
+	if idx == len(ring.points) {
+		idx = 0
+	}
+	return ring.points[idx].cluster
+}
+
+// GetClusters returns the clusters in the stable order of the ring. The relative order of any two
+// clusters depends only on their names, so adding or removing a cluster doesn't reorder the others.
+func (ring *HashRing) GetClusters() []string {
+	return append([]string{}, ring.clusters...)
+}
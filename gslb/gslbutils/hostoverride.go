@@ -0,0 +1,201 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+)
+
+const (
+	// DefaultPoolAlgorithm is the load balancing algorithm for the GSLB service pools, if not
+	// overridden by a HostOverride object.
+	DefaultPoolAlgorithm = "GSLB_ALGORITHM_ROUND_ROBIN"
+	MaxGSTTL             = 86400
+
+	// ConsistentHashPoolAlgorithm picks the members by a hash of the client's IP address, the
+	// members are ordered by the hash ring of their clusters
+	ConsistentHashPoolAlgorithm = "GSLB_ALGORITHM_CONSISTENT_HASH"
+)
+
+var allowedPoolAlgorithms = []string{
+	DefaultPoolAlgorithm,
+	"GSLB_ALGORITHM_GEO",
+	"GSLB_ALGORITHM_TOPOLOGY",
+	ConsistentHashPoolAlgorithm,
+}
+
+// HostOverride holds the GSLB service parameters of a hostname, as specified in a HostOverride
+// object. The parameters which are not set, retain their default values.
+type HostOverride struct {
+	// Name and Namespace of the HostOverride object
+	Name      string
+	Namespace string
+	Fqdn      string
+	TTL       *int32
+	// HealthMonitorRefs are the names of the health monitors for the GSLB service
+	HealthMonitorRefs []string
+	PoolAlgorithm     string
+	// Aliases are the additional domain names of the GSLB service of Fqdn, sorted
+	Aliases []string
+}
+
+// GetHostOverrideFromObj validates a HostOverride object and builds a HostOverride from it.
+func GetHostOverrideFromObj(obj *gdpv1alpha1.HostOverride) (*HostOverride, error) {
+	spec := obj.Spec
+	if spec.Fqdn == "" {
+		return nil, errors.New("fqdn can't be empty in a HostOverride object")
+	}
+	ho := HostOverride{
+		Name:      obj.ObjectMeta.Name,
+		Namespace: obj.ObjectMeta.Namespace,
+		Fqdn:      spec.Fqdn,
+	}
+	if spec.TTL != nil {
+		if *spec.TTL < 0 || *spec.TTL > MaxGSTTL {
+			return nil, errors.New("ttl " + strconv.Itoa(*spec.TTL) + " is invalid, allowed values are 0-" +
+				strconv.Itoa(MaxGSTTL))
+		}
+		ttl := int32(*spec.TTL)
+		ho.TTL = &ttl
+	}
+	if spec.PoolAlgorithm != "" && !PresentInList(spec.PoolAlgorithm, allowedPoolAlgorithms) {
+		return nil, errors.New("pool algorithm " + spec.PoolAlgorithm + " is not supported")
+	}
+	ho.PoolAlgorithm = spec.PoolAlgorithm
+	for _, hmRef := range spec.HealthMonitorRefs {
+		if hmRef == "" {
+			return nil, errors.New("health monitor ref can't be empty")
+		}
+		ho.HealthMonitorRefs = append(ho.HealthMonitorRefs, hmRef)
+	}
+	for _, alias := range spec.Aliases {
+		if alias == "" {
+			return nil, errors.New("alias can't be empty")
+		}
+		if alias == spec.Fqdn {
+			return nil, errors.New("alias " + alias + " can't be the fqdn itself")
+		}
+		if !PresentInList(alias, ho.Aliases) {
+			ho.Aliases = append(ho.Aliases, alias)
+		}
+	}
+	sort.Strings(ho.Aliases)
+	return &ho, nil
+}
+
+func (ho *HostOverride) getCopy() *HostOverride {
+	hoCopy := *ho
+	if ho.TTL != nil {
+		ttl := *ho.TTL
+		hoCopy.TTL = &ttl
+	}
+	hoCopy.HealthMonitorRefs = make([]string, len(ho.HealthMonitorRefs))
+	copy(hoCopy.HealthMonitorRefs, ho.HealthMonitorRefs)
+	if ho.Aliases != nil {
+		hoCopy.Aliases = append([]string{}, ho.Aliases...)
+	}
+	return &hoCopy
+}
+
+type hostOverrideCache struct {
+	// overrides is a map of the fqdns to their HostOverrides
+	overrides map[string]*HostOverride
+	lock      sync.RWMutex
+}
+
+var hoCache hostOverrideCache
+var hoCacheOnce sync.Once
+
+func getHostOverrideCache() *hostOverrideCache {
+	hoCacheOnce.Do(func() {
+		hoCache.overrides = make(map[string]*HostOverride)
+	})
+	return &hoCache
+}
+
+// GetHostOverride returns a copy of the HostOverride for the hostname fqdn, if present.
+func GetHostOverride(fqdn string) (*HostOverride, bool) {
+	hc := getHostOverrideCache()
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+	ho, ok := hc.overrides[fqdn]
+	if !ok {
+		return nil, false
+	}
+	return ho.getCopy(), true
+}
+
+// GetAliasedFqdn returns the fqdn whose HostOverride has alias as one of its aliases, if any.
+func GetAliasedFqdn(alias string) (string, bool) {
+	hc := getHostOverrideCache()
+	hc.lock.RLock()
+	defer hc.lock.RUnlock()
+	for fqdn, ho := range hc.overrides {
+		if PresentInList(alias, ho.Aliases) {
+			return fqdn, true
+		}
+	}
+	return "", false
+}
+
+// AddOrUpdateHostOverride saves the HostOverride for its fqdn. Only one HostOverride object is
+// allowed per fqdn, returns an error if the fqdn is already overridden by another object. A hostname
+// can be an alias of only one fqdn, and an overridden fqdn can't be an alias.
+func AddOrUpdateHostOverride(ho *HostOverride) error {
+	hc := getHostOverrideCache()
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+	if existing, ok := hc.overrides[ho.Fqdn]; ok && (existing.Name != ho.Name || existing.Namespace != ho.Namespace) {
+		return errors.New("fqdn " + ho.Fqdn + " is already overridden by " + existing.Namespace + "/" + existing.Name)
+	}
+	for fqdn, existing := range hc.overrides {
+		if fqdn == ho.Fqdn {
+			continue
+		}
+		if PresentInList(ho.Fqdn, existing.Aliases) {
+			return errors.New("fqdn " + ho.Fqdn + " is already an alias of " + fqdn)
+		}
+		for _, alias := range ho.Aliases {
+			if alias == fqdn {
+				return errors.New("alias " + alias + " is already overridden by " + existing.Namespace + "/" +
+					existing.Name)
+			}
+			if PresentInList(alias, existing.Aliases) {
+				return errors.New("alias " + alias + " is already an alias of " + fqdn)
+			}
+		}
+	}
+	hc.overrides[ho.Fqdn] = ho.getCopy()
+	return nil
+}
+
+// DeleteHostOverride removes the HostOverride for fqdn, only if it was saved for the object
+// ns/name. Returns true if the HostOverride was removed.
+func DeleteHostOverride(fqdn, ns, name string) bool {
+	hc := getHostOverrideCache()
+	hc.lock.Lock()
+	defer hc.lock.Unlock()
+	existing, ok := hc.overrides[fqdn]
+	if !ok || existing.Name != name || existing.Namespace != ns {
+		return false
+	}
+	delete(hc.overrides, fqdn)
+	return true
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+)
+
+// restInFlight bounds the number of the rest calls to the Avi controller in flight at once, across
+// the rest workers of the graph and the retry layers. A max of 0 leaves the calls unbounded.
+var restInFlight = struct {
+	count int
+	max   int
+	lock  sync.Mutex
+	cond  *sync.Cond
+}{}
+
+func init() {
+	restInFlight.cond = sync.NewCond(&restInFlight.lock)
+}
+
+// SetRestMaxInFlight sets the max number of the rest calls in flight at once, 0 leaves the calls
+// unbounded. The calls blocked on the older max are re-evaluated against the new one.
+func SetRestMaxInFlight(max int) error {
+	if max < 0 {
+		return errors.New("max rest calls in flight " + strconv.Itoa(max) + " can't be negative")
+	}
+	restInFlight.lock.Lock()
+	defer restInFlight.lock.Unlock()
+	restInFlight.max = max
+	restInFlight.cond.Broadcast()
+	return nil
+}
+
+// GetRestMaxInFlight returns the max number of the rest calls in flight at once, 0 if unbounded.
+func GetRestMaxInFlight() int {
+	restInFlight.lock.Lock()
+	defer restInFlight.lock.Unlock()
+	return restInFlight.max
+}
+
+// GetRestInFlight returns the number of the rest calls in flight.
+func GetRestInFlight() int {
+	restInFlight.lock.Lock()
+	defer restInFlight.lock.Unlock()
+	return restInFlight.count
+}
+
+// AcquireRestSlot blocks till a rest call for key can be made within the max number of the calls
+// in flight, and returns true if the call had to wait. Each call to AcquireRestSlot must be
+// followed by a call to ReleaseRestSlot once the rest call completes.
+func AcquireRestSlot(key string) bool {
+	restInFlight.lock.Lock()
+	defer restInFlight.lock.Unlock()
+	waited := false
+	for restInFlight.max != 0 && restInFlight.count >= restInFlight.max {
+		if !waited {
+			Debugf("key: %s, inFlight: %d, max: %d, msg: waiting for a rest call in flight to complete", key,
+				restInFlight.count, restInFlight.max)
+			waited = true
+		}
+		restInFlight.cond.Wait()
+	}
+	restInFlight.count++
+	return waited
+}
+
+// ReleaseRestSlot frees up the slot of a completed rest call.
+func ReleaseRestSlot() {
+	restInFlight.lock.Lock()
+	defer restInFlight.lock.Unlock()
+	if restInFlight.count > 0 {
+		restInFlight.count--
+	}
+	restInFlight.cond.Signal()
+}
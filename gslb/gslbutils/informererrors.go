@@ -0,0 +1,109 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sort"
+	"sync"
+)
+
+// InformerError is the last error of an informer of a member cluster in listing or watching its
+// objects.
+type InformerError struct {
+	Cluster  string
+	Informer string
+	Error    string
+}
+
+func (ie InformerError) String() string {
+	return ie.Informer + " informer for " + ie.Cluster + ": " + ie.Error
+}
+
+// informerErrors are the last errors of the informers, keyed by the cluster and the informer, an
+// informer has an entry only till it lists or watches its objects successfully again.
+var informerErrors = struct {
+	errs map[string]map[string]string
+	lock sync.Mutex
+}{errs: make(map[string]map[string]string)}
+
+// RecordInformerError records errMsg as the last error of informer for cluster. The errors are
+// surfaced in the status of the GSLBConfig object as and when they change.
+func RecordInformerError(cluster, informer, errMsg string) {
+	informerErrors.lock.Lock()
+	clusterErrs, ok := informerErrors.errs[cluster]
+	if !ok {
+		clusterErrs = make(map[string]string)
+		informerErrors.errs[cluster] = clusterErrs
+	}
+	if clusterErrs[informer] == errMsg {
+		informerErrors.lock.Unlock()
+		return
+	}
+	clusterErrs[informer] = errMsg
+	informerErrors.lock.Unlock()
+
+	Warnf("cluster: %s, informer: %s, msg: informer can't list or watch the objects, %s", cluster, informer, errMsg)
+	updateGSLBConfigInformerErrors()
+}
+
+// ClearInformerError clears the last error of informer for cluster, once the informer lists or
+// watches its objects successfully.
+func ClearInformerError(cluster, informer string) {
+	informerErrors.lock.Lock()
+	if _, ok := informerErrors.errs[cluster][informer]; !ok {
+		informerErrors.lock.Unlock()
+		return
+	}
+	delete(informerErrors.errs[cluster], informer)
+	if len(informerErrors.errs[cluster]) == 0 {
+		delete(informerErrors.errs, cluster)
+	}
+	informerErrors.lock.Unlock()
+
+	Logf("cluster: %s, informer: %s, msg: informer recovered", cluster, informer)
+	updateGSLBConfigInformerErrors()
+}
+
+// ClearClusterInformerErrors clears the errors of all the informers of cluster, e.g. once the
+// cluster is removed.
+func ClearClusterInformerErrors(cluster string) {
+	informerErrors.lock.Lock()
+	_, ok := informerErrors.errs[cluster]
+	delete(informerErrors.errs, cluster)
+	informerErrors.lock.Unlock()
+	if ok {
+		updateGSLBConfigInformerErrors()
+	}
+}
+
+// GetInformerErrors returns the last errors of the informers, sorted by the clusters and the
+// informers.
+func GetInformerErrors() []InformerError {
+	informerErrors.lock.Lock()
+	defer informerErrors.lock.Unlock()
+	var errs []InformerError
+	for cluster, clusterErrs := range informerErrors.errs {
+		for informer, errMsg := range clusterErrs {
+			errs = append(errs, InformerError{Cluster: cluster, Informer: informer, Error: errMsg})
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool {
+		if errs[i].Cluster != errs[j].Cluster {
+			return errs[i].Cluster < errs[j].Cluster
+		}
+		return errs[i].Informer < errs[j].Informer
+	})
+	return errs
+}
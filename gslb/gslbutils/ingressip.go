@@ -0,0 +1,203 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+
+	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// IngressIPSourceStatus picks the IP addresses of an ingress's hosts from status.loadBalancer
+	IngressIPSourceStatus = "status"
+	// IngressIPSourceAnnotation picks the IP address of an ingress from an annotation, set by an
+	// external controller, the address is used for all the hosts of the ingress
+	IngressIPSourceAnnotation = "annotation"
+	// DefaultIngressIPAnnotation is the annotation consulted for the IngressIPSourceAnnotation
+	// source, if no other annotation is configured
+	DefaultIngressIPAnnotation = "amko.vmware.com/ingress-vip"
+)
+
+// ingressIPSource holds the sources of the IP addresses of the ingresses, in the order of their
+// precedence.
+type ingressIPSource struct {
+	sources    []string
+	annotation string
+	lock       sync.RWMutex
+}
+
+var ingressIPSrc = ingressIPSource{
+	sources:    []string{IngressIPSourceStatus},
+	annotation: DefaultIngressIPAnnotation,
+}
+
+// SetIngressIPSource sets the sources of the IP addresses of the ingresses, in the order of their
+// precedence: for each host, the address is taken from the first source which has one. annotation
+// is the key of the annotation for the IngressIPSourceAnnotation source, DefaultIngressIPAnnotation
+// is used if it's empty.
+func SetIngressIPSource(sources []string, annotation string) error {
+	if len(sources) == 0 {
+		return errors.New("at least one ingress ip source is required")
+	}
+	for idx, source := range sources {
+		if source != IngressIPSourceStatus && source != IngressIPSourceAnnotation {
+			return errors.New("ingress ip source " + source + " unrecognized")
+		}
+		if PresentInList(source, sources[:idx]) {
+			return errors.New("ingress ip source " + source + " repeated")
+		}
+	}
+	if annotation == "" {
+		annotation = DefaultIngressIPAnnotation
+	}
+	ingressIPSrc.lock.Lock()
+	defer ingressIPSrc.lock.Unlock()
+	ingressIPSrc.sources = append([]string{}, sources...)
+	ingressIPSrc.annotation = annotation
+	return nil
+}
+
+// GetIngressIPSource returns the sources of the IP addresses of the ingresses and the annotation
+// key for the IngressIPSourceAnnotation source.
+func GetIngressIPSource() ([]string, string) {
+	ingressIPSrc.lock.RLock()
+	defer ingressIPSrc.lock.RUnlock()
+	return append([]string{}, ingressIPSrc.sources...), ingressIPSrc.annotation
+}
+
+// ParseIngressIPSource parses a comma separated list of the ingress ip sources, e.g.
+// "annotation,status".
+func ParseIngressIPSource(val string) []string {
+	sources := []string{}
+	for _, source := range strings.Split(val, ",") {
+		if source = strings.ToLower(strings.TrimSpace(source)); source != "" {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+func getIPAddrsFromStatus(ingress *v1beta1.Ingress, hostList []string) []IngressHostIP {
+	ingHostIP := []IngressHostIP{}
+	ingList := ingress.Status.LoadBalancer.Ingress
+	if len(ingList) == 0 {
+		Logf("Ingress %s doesn't have the status field populated", ingress.GetObjectMeta().GetName())
+		Debugf("Ingress: %v", ingress)
+		return ingHostIP
+	}
+	for _, ingr := range ingList {
+		// Check if this is a IP address
+		addr := net.ParseIP(ingr.IP)
+		if addr == nil {
+			Warnf("Address %s is not an IP address", ingr.IP)
+			continue
+		}
+		// Found an IP address, return
+		if ingr.Hostname == "" {
+			Warnf("Hostname is empty in ingress %s", ingress.Name)
+			continue
+		}
+		host, ok := matchIngressRuleHost(ingr.Hostname, hostList)
+		if !ok {
+			continue
+		}
+		// each host gets the addresses of its own status entries, the first of these is the
+		// address of the host
+		if idx := getHostIPIndex(host, ingHostIP); idx >= 0 {
+			Debugf("ingress: %s/%s, host: %s, ip: %s, msg: additional status entry of the host",
+				ingress.Namespace, ingress.Name, host, ingr.IP)
+			ingHostIP[idx].IPAddrs = append(ingHostIP[idx].IPAddrs, ingr.IP)
+			continue
+		}
+		ingHostIP = append(ingHostIP, IngressHostIP{
+			Hostname: host,
+			IPAddr:   ingr.IP,
+			IPAddrs:  []string{ingr.IP},
+		})
+	}
+	return ingHostIP
+}
+
+// matchIngressRuleHost returns the rule host in hostList which a hostname in the status of an
+// ingress belongs to. The hostnames are compared case insensitively, and a trailing dot of a fully
+// qualified hostname is ignored.
+func matchIngressRuleHost(hostname string, hostList []string) (string, bool) {
+	// the exact match is checked first, which avoids any allocations for the common case
+	if PresentInList(hostname, hostList) {
+		return hostname, true
+	}
+	hostname = strings.TrimSuffix(hostname, ".")
+	for _, host := range hostList {
+		if strings.EqualFold(hostname, strings.TrimSuffix(host, ".")) {
+			return host, true
+		}
+	}
+	return "", false
+}
+
+// getIngressAnnotationAddr returns the address in the annotation of an ingress, either an IP
+// address, or the hostname of a load balancer which doesn't expose an IP address. An annotation
+// which is neither is ignored.
+func getIngressAnnotationAddr(ingress *v1beta1.Ingress, annotation string) (string, string, bool) {
+	val, ok := ingress.GetAnnotations()[annotation]
+	if !ok {
+		return "", "", false
+	}
+	val = strings.TrimSpace(val)
+	if net.ParseIP(val) != nil {
+		return val, "", true
+	}
+	if len(validation.IsDNS1123Subdomain(val)) == 0 {
+		return "", val, true
+	}
+	Warnf("ingress: %s/%s, annotation: %s, value: %s, msg: annotation value is not an IP address or a hostname, ignoring",
+		ingress.Namespace, ingress.Name, annotation, val)
+	return "", "", false
+}
+
+func getIPAddrsFromAnnotation(ingress *v1beta1.Ingress, hostList []string, annotation string) []IngressHostIP {
+	ip, lbHostname, ok := getIngressAnnotationAddr(ingress, annotation)
+	if !ok {
+		return nil
+	}
+	ingHostIP := make([]IngressHostIP, 0, len(hostList))
+	for _, host := range hostList {
+		if lbHostname != "" {
+			ingHostIP = append(ingHostIP, IngressHostIP{Hostname: host, LBHostname: lbHostname})
+			continue
+		}
+		ingHostIP = append(ingHostIP, IngressHostIP{Hostname: host, IPAddr: ip, IPAddrs: []string{ip}})
+	}
+	return ingHostIP
+}
+
+func isHostInHostIPs(hostname string, hostIPs []IngressHostIP) bool {
+	return getHostIPIndex(hostname, hostIPs) >= 0
+}
+
+// getHostIPIndex returns the index of hostname in hostIPs, -1 if not present.
+func getHostIPIndex(hostname string, hostIPs []IngressHostIP) int {
+	for idx, hip := range hostIPs {
+		if hip.Hostname == hostname {
+			return idx
+		}
+	}
+	return -1
+}
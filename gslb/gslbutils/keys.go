@@ -0,0 +1,171 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"strings"
+)
+
+// All the composite keys (multi-cluster keys, cluster keys, model names etc.) are built
+// from segments separated by KeyDelimiter. To keep the keys unambiguous and reversible,
+// each segment is escaped before joining: "%" becomes "%25" and KeyDelimiter becomes "%2F".
+// Segments without these characters are unchanged.
+const (
+	KeyDelimiter = "/"
+)
+
+var keySegmentEscaper = strings.NewReplacer("%", "%25", KeyDelimiter, "%2F")
+
+// EscapeKeySegment escapes a segment so that it can be a part of a composite key.
+func EscapeKeySegment(segment string) string {
+	return keySegmentEscaper.Replace(segment)
+}
+
+// UnescapeKeySegment reverses EscapeKeySegment.
+func UnescapeKeySegment(segment string) (string, error) {
+	if !strings.Contains(segment, "%") {
+		return segment, nil
+	}
+	var result strings.Builder
+	for i := 0; i < len(segment); i++ {
+		if segment[i] != '%' {
+			result.WriteByte(segment[i])
+			continue
+		}
+		if i+2 >= len(segment) {
+			return "", errors.New("incomplete escape sequence in key segment " + segment)
+		}
+		switch segment[i+1 : i+3] {
+		case "25":
+			result.WriteByte('%')
+		case "2F":
+			result.WriteString(KeyDelimiter)
+		default:
+			return "", errors.New("invalid escape sequence in key segment " + segment)
+		}
+		i += 2
+	}
+	return result.String(), nil
+}
+
+// JoinKey escapes each of the segments and joins them to form a composite key.
+func JoinKey(segments ...string) string {
+	escaped := make([]string, len(segments))
+	for idx, segment := range segments {
+		escaped[idx] = EscapeKeySegment(segment)
+	}
+	return strings.Join(escaped, KeyDelimiter)
+}
+
+// SplitKey splits a composite key built via JoinKey into its unescaped segments.
+func SplitKey(key string) ([]string, error) {
+	segments := strings.Split(key, KeyDelimiter)
+	for idx, segment := range segments {
+		unescaped, err := UnescapeKeySegment(segment)
+		if err != nil {
+			return nil, err
+		}
+		segments[idx] = unescaped
+	}
+	return segments, nil
+}
+
+// GetClusterKey builds the key for an object objName in namespace ns of cluster.
+func GetClusterKey(cluster, ns, objName string) string {
+	return JoinKey(cluster, ns, objName)
+}
+
+// ParseClusterKey reverses GetClusterKey.
+func ParseClusterKey(key string) (string, string, string, error) {
+	if key == "" {
+		return "", "", "", errors.New("cluster key is empty")
+	}
+	segments, err := SplitKey(key)
+	if err != nil {
+		return "", "", "", err
+	}
+	if len(segments) != 3 {
+		return "", "", "", errors.New("cluster key format is unexpected: " + key)
+	}
+	return segments[0], segments[1], segments[2], nil
+}
+
+// GetModelKey builds the key for a GS graph with name gsName in tenant.
+func GetModelKey(tenant, gsName string) string {
+	return JoinKey(tenant, gsName)
+}
+
+// GSKey is the parsed key of a GS graph, the key of the rest and the retry layers. The key schema is
+// "<tenant>/<gsName>", or just "<gsName>" for a GS in the default tenant, with the segments escaped
+// as per JoinKey. The tenant is a part of the key only to keep the GS graphs of different Avi
+// tenants apart, it isn't derived from the namespaces of the member objects.
+type GSKey struct {
+	// Tenant is empty if the key has no tenant
+	Tenant string
+	GSName string
+}
+
+// GetTenant returns the tenant of the key, DefaultTenant if the key has no tenant.
+func (k GSKey) GetTenant() string {
+	if k.Tenant == "" {
+		return DefaultTenant
+	}
+	return k.Tenant
+}
+
+// String returns the key in the schema of GSKey.
+func (k GSKey) String() string {
+	if k.Tenant == "" {
+		return EscapeKeySegment(k.GSName)
+	}
+	return JoinKey(k.Tenant, k.GSName)
+}
+
+// ParseGSKey parses a key in the schema of GSKey, i.e. a key built via GetModelKey or a key with
+// just the GS name.
+func ParseGSKey(key string) (GSKey, error) {
+	if key == "" {
+		return GSKey{}, errors.New("GS key is empty")
+	}
+	segments, err := SplitKey(key)
+	if err != nil {
+		return GSKey{}, err
+	}
+	var gsKey GSKey
+	switch len(segments) {
+	case 1:
+		gsKey.GSName = segments[0]
+	case 2:
+		gsKey.Tenant, gsKey.GSName = segments[0], segments[1]
+	default:
+		return GSKey{}, errors.New("GS key format is unexpected, expecting [<tenant>/]<gsName>: " + key)
+	}
+	if gsKey.GSName == "" {
+		return GSKey{}, errors.New("GS name is empty in GS key " + key)
+	}
+	return gsKey, nil
+}
+
+// ExtractTenantAndGSName reverses GetModelKey, returns empty strings if the key is malformed. The
+// tenant is DefaultTenant for a key without a tenant.
+func ExtractTenantAndGSName(key string) (string, string) {
+	gsKey, err := ParseGSKey(key)
+	if err != nil {
+		Warnf("key: %s, msg: wrong key format, %v", key, err)
+		return "", ""
+	}
+	return gsKey.GetTenant(), gsKey.GSName
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/api/models"
+)
+
+const MetricsPath = "/metrics"
+
+// writeMetric writes a metric in the Prometheus text format.
+func writeMetric(resp *strings.Builder, name, metricType, help string, value interface{}) {
+	fmt.Fprintf(resp, "# HELP %s %s\n# TYPE %s %s\n%s %v\n", name, help, name, metricType, name, value)
+}
+
+// writeInformerErrorMetrics writes the number of the informers with errors, and a sample for each of
+// these informers labelled with the cluster and the informer.
+func writeInformerErrorMetrics(resp *strings.Builder) {
+	informerErrs := GetInformerErrors()
+	writeMetric(resp, "amko_informer_errors", "gauge",
+		"Number of informers of the member clusters which can't list or watch their objects.", len(informerErrs))
+	name := "amko_informer_error"
+	fmt.Fprintf(resp, "# HELP %s %s\n# TYPE %s %s\n", name,
+		"Set for an informer of a member cluster which can't list or watch its objects.", name, "gauge")
+	for _, ie := range informerErrs {
+		fmt.Fprintf(resp, "%s{cluster=%q,informer=%q} 1\n", name, ie.Cluster, ie.Informer)
+	}
+}
+
+// MetricsModel implements ApiModel for the metrics of AMKO.
+type MetricsModel struct{}
+
+func (m *MetricsModel) InitModel() {}
+
+func (m *MetricsModel) ApiOperationMap() []models.OperationMap {
+	get := models.OperationMap{
+		Route:   MetricsPath,
+		Method:  "GET",
+		Handler: MetricsHandler,
+	}
+	return []models.OperationMap{get}
+}
+
+// MetricsHandler responds with the metrics of AMKO in the Prometheus text format.
+func MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	var resp strings.Builder
+	writeMetric(&resp, "amko_retry_queue_depth", "gauge",
+		"Number of keys pending retry across the retry queues.", GetRetryQueueDepth())
+	writeMetric(&resp, "amko_retry_queue_max_depth", "gauge",
+		"Max number of keys pending retry, 0 if the retry queues are unbounded.", GetRetryQueueMaxDepth())
+	writeMetric(&resp, "amko_retry_queue_overflows_total", "counter",
+		"Number of keys rejected or dropped because the retry queues were full.", GetRetryQueueOverflows())
+	writeMetric(&resp, "amko_retry_queue_drops_total", "counter",
+		"Number of keys dropped by the drop-oldest overflow policy of the retry queues.", GetRetryQueueDrops())
+	writeMetric(&resp, "amko_slow_retry_queue_depth", "gauge",
+		"Number of keys pending retry in the slow retry queue.", GetRetryQueueDepthByName(SlowRetryQueue))
+	writeMetric(&resp, "amko_fast_retry_queue_depth", "gauge",
+		"Number of keys pending retry in the fast retry queue.", GetRetryQueueDepthByName(FastRetryQueue))
+	writeMetric(&resp, "amko_retry_workers", "gauge",
+		"Number of workers of each of the retry queues.", GetNumRetryWorkers())
+	writeMetric(&resp, "amko_rest_in_flight", "gauge",
+		"Number of rest calls to the Avi controller in flight.", GetRestInFlight())
+	writeMetric(&resp, "amko_rest_max_in_flight", "gauge",
+		"Max number of rest calls to the Avi controller in flight, 0 if unbounded.", GetRestMaxInFlight())
+
+	writeInformerErrorMetrics(&resp)
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(resp.String()))
+}
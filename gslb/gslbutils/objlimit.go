@@ -0,0 +1,123 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"sync"
+)
+
+// Event reason for the objects rejected because of the cluster object limit
+const (
+	ClusterObjLimitExceeded = "ClusterObjectLimitExceeded"
+)
+
+// clusterObjCounter tracks the objects accepted from each member cluster, so that the number
+// of accepted objects of a cluster can be capped. The objects are tracked by their keys, so an
+// object evaluated multiple times is counted once.
+type clusterObjCounter struct {
+	limit int
+	objs  map[string]map[string]struct{}
+	lock  sync.Mutex
+}
+
+func newClusterObjCounter(limit int) *clusterObjCounter {
+	return &clusterObjCounter{
+		limit: limit,
+		objs:  make(map[string]map[string]struct{}),
+	}
+}
+
+// SetClusterObjLimit sets the maximum number of objects which can be accepted from a member
+// cluster, 0 removes the limit. The objects already accepted are not re-evaluated.
+func (gf *GlobalFilter) SetClusterObjLimit(limit int) error {
+	if limit < 0 {
+		return errors.New("cluster object limit can't be negative")
+	}
+	gf.objCounter.lock.Lock()
+	defer gf.objCounter.lock.Unlock()
+	gf.objCounter.limit = limit
+	return nil
+}
+
+// GetClusterObjLimit returns the maximum number of objects which can be accepted from a member
+// cluster, 0 if there's no limit.
+func (gf *GlobalFilter) GetClusterObjLimit() int {
+	gf.objCounter.lock.Lock()
+	defer gf.objCounter.lock.Unlock()
+	return gf.objCounter.limit
+}
+
+// AdmitClusterObj counts an object accepted by the filter against the limit of its cluster.
+// Returns false if the object is not already counted and the cluster has reached its limit.
+func (gf *GlobalFilter) AdmitClusterObj(cname, objType, ns, objName string) bool {
+	oc := gf.objCounter
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	key := JoinKey(objType, ns, objName)
+	clusterObjs, ok := oc.objs[cname]
+	if !ok {
+		clusterObjs = make(map[string]struct{})
+		oc.objs[cname] = clusterObjs
+	}
+	if _, counted := clusterObjs[key]; counted {
+		return true
+	}
+	if oc.limit > 0 && len(clusterObjs) >= oc.limit {
+		return false
+	}
+	clusterObjs[key] = struct{}{}
+	return true
+}
+
+// ReleaseClusterObj removes an object, which was deleted or rejected by the filter, from the
+// count of its cluster.
+func (gf *GlobalFilter) ReleaseClusterObj(cname, objType, ns, objName string) {
+	oc := gf.objCounter
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	clusterObjs, ok := oc.objs[cname]
+	if !ok {
+		return
+	}
+	delete(clusterObjs, JoinKey(objType, ns, objName))
+	if len(clusterObjs) == 0 {
+		delete(oc.objs, cname)
+	}
+}
+
+// GetClusterObjCount returns the number of objects counted against the limit of cluster cname.
+func (gf *GlobalFilter) GetClusterObjCount(cname string) int {
+	gf.objCounter.lock.Lock()
+	defer gf.objCounter.lock.Unlock()
+	return len(gf.objCounter.objs[cname])
+}
+
+// reset removes all the counted objects, the limit is retained.
+func (oc *clusterObjCounter) reset() {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	oc.objs = make(map[string]map[string]struct{})
+}
+
+// rename moves the objects counted for the cluster oldName to newName.
+func (oc *clusterObjCounter) rename(oldName, newName string) {
+	oc.lock.Lock()
+	defer oc.lock.Unlock()
+	if clusterObjs, ok := oc.objs[oldName]; ok {
+		delete(oc.objs, oldName)
+		oc.objs[newName] = clusterObjs
+	}
+}
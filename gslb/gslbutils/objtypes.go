@@ -0,0 +1,113 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sync"
+)
+
+// IPHostname is an entry of a host map, the address and the hostnames of an object whose GSLB
+// members were built.
+type IPHostname struct {
+	IP       string
+	Hostname string
+	// LBHostname is the hostname exposed by the load balancer of a service or an ingress, when
+	// it doesn't expose an IP
+	LBHostname string
+	// ExtraHosts are the hostnames of a route other than its host, i.e. the SNI hosts and the
+	// additional hosts
+	ExtraHosts []string
+}
+
+// ObjHostMap stores a mapping between cluster+ns+objName to it's hostname
+type ObjHostMap struct {
+	HostMap map[string]IPHostname
+	Lock    sync.Mutex
+}
+
+// ObjTypeHandlers describes an object type from which the GSLB members are built, so that the
+// layers can dispatch on the object type, as returned by GetType() of its meta objects, without
+// a switch over all the object types.
+type ObjTypeHandlers struct {
+	// ObjType is the object type, as returned by GetType() of its meta objects
+	ObjType string
+	// Kind is the kubernetes kind of the objects
+	Kind string
+	// APIVersion is the API version of the objects, as set in the references to the objects
+	APIVersion string
+	// HostScoped is set for the object types which have a meta object per hostname, the names of
+	// these meta objects are of the format <object name>/<hostname>
+	HostScoped bool
+	// AcceptedStore and RejectedStore return the stores of the accepted and the rejected meta objects
+	AcceptedStore func() *ClusterStore
+	RejectedStore func() *ClusterStore
+	// NewMeta returns an empty meta object of the type
+	NewMeta func() interface{}
+	// HostMap returns the host map of the type, the entries of the host map are the objects whose
+	// GSLB members were built
+	HostMap func() *ObjHostMap
+}
+
+type objTypeRegistry struct {
+	handlers map[string]ObjTypeHandlers
+	// objTypes are the registered object types, in the order of their registration
+	objTypes []string
+	lock     sync.RWMutex
+}
+
+var objTypes = objTypeRegistry{handlers: make(map[string]ObjTypeHandlers)}
+
+// RegisterObjType registers the handlers of an object type, an already registered object type
+// is replaced, retaining its order.
+func RegisterObjType(handlers ObjTypeHandlers) {
+	objTypes.lock.Lock()
+	defer objTypes.lock.Unlock()
+	if _, ok := objTypes.handlers[handlers.ObjType]; !ok {
+		objTypes.objTypes = append(objTypes.objTypes, handlers.ObjType)
+	}
+	objTypes.handlers[handlers.ObjType] = handlers
+}
+
+// GetObjTypeHandlers returns the handlers registered for objType.
+func GetObjTypeHandlers(objType string) (ObjTypeHandlers, bool) {
+	objTypes.lock.RLock()
+	defer objTypes.lock.RUnlock()
+	handlers, ok := objTypes.handlers[objType]
+	return handlers, ok
+}
+
+// GetObjTypes returns all the registered object types, in the order of their registration.
+func GetObjTypes() []string {
+	objTypes.lock.RLock()
+	defer objTypes.lock.RUnlock()
+	return append([]string{}, objTypes.objTypes...)
+}
+
+// IsObjTypeRegistered returns true if handlers are registered for objType.
+func IsObjTypeRegistered(objType string) bool {
+	_, ok := GetObjTypeHandlers(objType)
+	return ok
+}
+
+// getObjTypeStores returns the accepted and the rejected stores of all the registered object
+// types, in the order of their registration.
+func getObjTypeStores() []*ClusterStore {
+	var stores []*ClusterStore
+	for _, objType := range GetObjTypes() {
+		handlers, _ := GetObjTypeHandlers(objType)
+		stores = append(stores, handlers.AcceptedStore(), handlers.RejectedStore())
+	}
+	return stores
+}
@@ -0,0 +1,61 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+const (
+	// ObjectWeightAnnotation sets the weight of the GSLB members of an object, in place of the
+	// weight of its cluster in the traffic split of the GDP object, e.g. "5"
+	ObjectWeightAnnotation = "amko.vmware.com/weight"
+	// MinObjectWeight and MaxObjectWeight bound the weight of an object
+	MinObjectWeight = 1
+	MaxObjectWeight = MaxRatio
+)
+
+// ParseObjectWeight parses the value of ObjectWeightAnnotation, the weight must be between
+// MinObjectWeight and MaxObjectWeight.
+func ParseObjectWeight(val string) (int32, error) {
+	weight, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		return 0, errors.New("object weight " + val + " is not a number")
+	}
+	if weight < MinObjectWeight || weight > MaxObjectWeight {
+		return 0, errors.New("object weight " + strconv.Itoa(weight) + " must be between " +
+			strconv.Itoa(MinObjectWeight) + " and " + strconv.Itoa(MaxObjectWeight))
+	}
+	return int32(weight), nil
+}
+
+// GetObjectWeight returns the weight of an object as per the ObjectWeightAnnotation in annotations,
+// 0 if the annotation isn't set. An invalid annotation is ignored, i.e. the object gets the weight
+// of its cluster.
+func GetObjectWeight(annotations map[string]string) int32 {
+	val, ok := annotations[ObjectWeightAnnotation]
+	if !ok {
+		return 0
+	}
+	weight, err := ParseObjectWeight(val)
+	if err != nil {
+		Warnf("annotation: %s, value: %s, msg: invalid object weight, will use the weight of the cluster, %s",
+			ObjectWeightAnnotation, val, err.Error())
+		return 0
+	}
+	return weight
+}
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// pathsPerHostLimit caps the number of paths of a host of an object, 0 means no limit. A host
+// with thousands of paths bloats its health monitors and checksums, so its paths are truncated.
+var pathsPerHostLimit = struct {
+	limit int
+	lock  sync.RWMutex
+}{}
+
+// SetPathsPerHostLimit sets the max number of paths of a host, 0 removes the limit.
+func SetPathsPerHostLimit(limit int) error {
+	if limit < 0 {
+		return errors.New("paths per host limit " + strconv.Itoa(limit) + " can't be negative")
+	}
+	pathsPerHostLimit.lock.Lock()
+	defer pathsPerHostLimit.lock.Unlock()
+	pathsPerHostLimit.limit = limit
+	return nil
+}
+
+// GetPathsPerHostLimit returns the max number of paths of a host, 0 if there's no limit.
+func GetPathsPerHostLimit() int {
+	pathsPerHostLimit.lock.RLock()
+	defer pathsPerHostLimit.lock.RUnlock()
+	return pathsPerHostLimit.limit
+}
+
+// truncatedPaths keeps the checksum of the paths of the hosts truncated to the limit, keyed by the
+// object name, so that a host is warned about only once till its paths or the limit change.
+var truncatedPaths = struct {
+	cksums map[string]uint32
+	lock   sync.Mutex
+}{cksums: make(map[string]uint32)}
+
+// warnTruncation returns true if the truncation of the paths of objName hasn't been warned about
+// yet for the same paths and limit. A host within the limit is forgotten, so that it's warned
+// about again if its paths exceed the limit later.
+func warnTruncation(objName string, paths []string, limit int) bool {
+	truncatedPaths.lock.Lock()
+	defer truncatedPaths.lock.Unlock()
+	if len(paths) <= limit {
+		delete(truncatedPaths.cksums, objName)
+		return false
+	}
+	// the paths are added as separate components, so that their order doesn't change the checksum
+	var cb ChecksumBuilder
+	for _, path := range paths {
+		cb.Add(path)
+	}
+	cb.Add("limit:" + strconv.Itoa(limit))
+	cksum := cb.Sum()
+	if prev, ok := truncatedPaths.cksums[objName]; ok && prev == cksum {
+		return false
+	}
+	truncatedPaths.cksums[objName] = cksum
+	return true
+}
+
+// LimitPaths truncates the paths of a host of the object objName to the paths per host limit. The paths
+// are sorted before they are truncated, so that the same paths are retained irrespective of their
+// order in the object. The paths are returned as is if they are within the limit. The truncation is
+// warned about once for the same paths of the host, and logged at debug level after that.
+func LimitPaths(objName string, paths []string) []string {
+	limit := GetPathsPerHostLimit()
+	if limit == 0 {
+		return paths
+	}
+	warn := warnTruncation(objName, paths, limit)
+	if len(paths) <= limit {
+		return paths
+	}
+	sortedPaths := make([]string, len(paths))
+	copy(sortedPaths, paths)
+	sort.Strings(sortedPaths)
+	logf := Debugf
+	if warn {
+		logf = Warnf
+	}
+	logf("object: %s, paths: %d, limit: %d, msg: too many paths for the host, only the first %d paths in the sorted order will be used",
+		objName, len(paths), limit, limit)
+	return sortedPaths[:limit]
+}
@@ -0,0 +1,86 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// AcceptanceMeta is the view of an object passed to the acceptance predicates.
+type AcceptanceMeta struct {
+	ObjType   string
+	Cluster   string
+	Namespace string
+	Name      string
+	Hostname  string
+	// Labels is a copy of the labels of the object
+	Labels map[string]string
+}
+
+// AcceptancePredicate decides whether an object accepted by the built-in checks of the filter is
+// accepted, and returns the reason for a rejection.
+type AcceptancePredicate func(meta AcceptanceMeta) (bool, string)
+
+var (
+	// acceptancePredicates holds a []AcceptancePredicate, it is replaced on every registration, so
+	// that the predicates can be evaluated without any locks
+	acceptancePredicates    atomic.Value
+	acceptancePredicateLock sync.Mutex
+)
+
+// AddAcceptancePredicate registers predicate to be evaluated by the filter for the objects which
+// pass all the built-in checks. The predicates are evaluated in the order of their registration,
+// and the first predicate returning false rejects the object with its reason, the later ones are
+// not evaluated. The predicates are evaluated synchronously from the event handlers of the member
+// clusters, before the object limit of the cluster is applied. So, a predicate must be fast, must
+// not block, and must treat the object as read-only.
+func AddAcceptancePredicate(predicate AcceptancePredicate) {
+	acceptancePredicateLock.Lock()
+	defer acceptancePredicateLock.Unlock()
+	existing, _ := acceptancePredicates.Load().([]AcceptancePredicate)
+	predicates := make([]AcceptancePredicate, len(existing), len(existing)+1)
+	copy(predicates, existing)
+	acceptancePredicates.Store(append(predicates, predicate))
+}
+
+// ClearAcceptancePredicates removes all the registered acceptance predicates.
+func ClearAcceptancePredicates() {
+	acceptancePredicateLock.Lock()
+	defer acceptancePredicateLock.Unlock()
+	acceptancePredicates.Store([]AcceptancePredicate{})
+}
+
+// HasAcceptancePredicates returns true if any acceptance predicates are registered, so that the
+// callers can skip building an AcceptanceMeta.
+func HasAcceptancePredicates() bool {
+	predicates, _ := acceptancePredicates.Load().([]AcceptancePredicate)
+	return len(predicates) != 0
+}
+
+// EvaluateAcceptancePredicates evaluates the registered acceptance predicates for meta in order,
+// and returns false along with the reason of the first predicate which rejects the object.
+func EvaluateAcceptancePredicates(meta AcceptanceMeta) (bool, string) {
+	predicates, _ := acceptancePredicates.Load().([]AcceptancePredicate)
+	for _, predicate := range predicates {
+		if accepted, reason := predicate(meta); !accepted {
+			if reason == "" {
+				reason = "rejected by an acceptance predicate"
+			}
+			return false, reason
+		}
+	}
+	return true, ""
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"k8s.io/client-go/util/flowcontrol"
+)
+
+const (
+	// DefaultRestQPS and DefaultRestBurst are the default limits for the rest calls to the Avi
+	// controller, the rest calls are made at DefaultRestQPS requests per second on average,
+	// with bursts of up to DefaultRestBurst requests.
+	DefaultRestQPS   = 10
+	DefaultRestBurst = 20
+)
+
+// restRateLimiter throttles the rest calls (from the graph and the retry layers) to the Avi
+// controller.
+type restRateLimiter struct {
+	limiter flowcontrol.RateLimiter
+	qps     float32
+	burst   int
+	lock    sync.RWMutex
+	// throttling is set while the rest calls are being throttled, so that only the start and the
+	// end of the throttling are logged
+	throttling int32
+}
+
+var restLimiter restRateLimiter
+var restLimiterOnce sync.Once
+
+func getRestRateLimiter() *restRateLimiter {
+	restLimiterOnce.Do(func() {
+		restLimiter.qps = DefaultRestQPS
+		restLimiter.burst = DefaultRestBurst
+		restLimiter.limiter = flowcontrol.NewTokenBucketRateLimiter(DefaultRestQPS, DefaultRestBurst)
+	})
+	return &restLimiter
+}
+
+// SetRestRateLimit sets the limits for the rest calls to the Avi controller, qps is the average
+// number of requests per second and burst is the maximum number of requests at once.
+func SetRestRateLimit(qps float32, burst int) error {
+	if qps <= 0 {
+		return errors.New("rest qps must be greater than 0")
+	}
+	if burst <= 0 {
+		return errors.New("rest burst must be greater than 0")
+	}
+	rl := getRestRateLimiter()
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+	rl.qps = qps
+	rl.burst = burst
+	rl.limiter = flowcontrol.NewTokenBucketRateLimiter(qps, burst)
+	return nil
+}
+
+// GetRestRateLimit returns the requests per second and the burst for the rest calls.
+func GetRestRateLimit() (float32, int) {
+	rl := getRestRateLimiter()
+	rl.lock.RLock()
+	defer rl.lock.RUnlock()
+	return rl.qps, rl.burst
+}
+
+// WaitForRestRateLimit blocks till a rest call for key is allowed as per the rate limits. Returns
+// true if the call had to wait.
+func WaitForRestRateLimit(key string) bool {
+	rl := getRestRateLimiter()
+	rl.lock.RLock()
+	limiter := rl.limiter
+	rl.lock.RUnlock()
+
+	if limiter.TryAccept() {
+		if atomic.CompareAndSwapInt32(&rl.throttling, 1, 0) {
+			Logf("key: %s, msg: rest calls to the controller are not being throttled anymore", key)
+		}
+		return false
+	}
+	if atomic.CompareAndSwapInt32(&rl.throttling, 0, 1) {
+		qps, burst := GetRestRateLimit()
+		Warnf("key: %s, qps: %v, burst: %d, msg: rate limit reached, throttling the rest calls to the controller",
+			key, qps, burst)
+	}
+	limiter.Accept()
+	return true
+}
@@ -0,0 +1,164 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/api/models"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	ReadyzPath = "/readyz"
+)
+
+// clusterSyncState holds the informer sync functions of a member cluster. A cluster is
+// synced only after its informers are started and all of them have synced.
+type clusterSyncState struct {
+	started   bool
+	syncFuncs []cache.InformerSynced
+}
+
+func (state *clusterSyncState) hasSynced() bool {
+	if !state.started {
+		return false
+	}
+	for _, synced := range state.syncFuncs {
+		if !synced() {
+			return false
+		}
+	}
+	return true
+}
+
+type clusterSyncStates struct {
+	states map[string]*clusterSyncState
+	lock   sync.RWMutex
+}
+
+var syncStates clusterSyncStates
+var syncStatesOnce sync.Once
+
+func getClusterSyncStates() *clusterSyncStates {
+	syncStatesOnce.Do(func() {
+		syncStates.states = make(map[string]*clusterSyncState)
+	})
+	return &syncStates
+}
+
+// RegisterClusterForReadiness adds a member cluster cname to the readiness checks, AMKO
+// is not ready until the informers of this cluster are synced.
+func RegisterClusterForReadiness(cname string) {
+	cs := getClusterSyncStates()
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.states[cname] = &clusterSyncState{}
+}
+
+// SetClusterInformersSynced saves the sync functions of the informers started for a member
+// cluster cname.
+func SetClusterInformersSynced(cname string, syncFuncs []cache.InformerSynced) {
+	cs := getClusterSyncStates()
+	cs.lock.Lock()
+	defer cs.lock.Unlock()
+	cs.states[cname] = &clusterSyncState{
+		started:   true,
+		syncFuncs: syncFuncs,
+	}
+}
+
+// GetClusterSyncStates returns the sync state of the informers for each member cluster.
+func GetClusterSyncStates() map[string]bool {
+	cs := getClusterSyncStates()
+	cs.lock.RLock()
+	defer cs.lock.RUnlock()
+	result := make(map[string]bool, len(cs.states))
+	for cname, state := range cs.states {
+		result[cname] = state.hasSynced()
+	}
+	return result
+}
+
+// IsAmkoReady returns true if member clusters are initialized and the informers for all of
+// them are synced.
+func IsAmkoReady() bool {
+	return isReady(GetClusterSyncStates())
+}
+
+func isReady(states map[string]bool) bool {
+	if len(states) == 0 {
+		return false
+	}
+	for _, synced := range states {
+		if !synced {
+			return false
+		}
+	}
+	return true
+}
+
+// ReadinessModel implements ApiModel for the readiness probe of AMKO.
+type ReadinessModel struct{}
+
+func (r *ReadinessModel) InitModel() {}
+
+func (r *ReadinessModel) ApiOperationMap() []models.OperationMap {
+	get := models.OperationMap{
+		Route:   ReadyzPath,
+		Method:  "GET",
+		Handler: ReadyzHandler,
+	}
+	return []models.OperationMap{get}
+}
+
+// ReadyzHandler responds with 200 if AMKO is ready, and with 503 otherwise. If the "verbose"
+// query parameter is set, the sync state of each of the member clusters is also written.
+func ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	states := GetClusterSyncStates()
+	ready := isReady(states)
+
+	var resp strings.Builder
+	if _, verbose := r.URL.Query()["verbose"]; verbose {
+		clusters := make([]string, 0, len(states))
+		for cname := range states {
+			clusters = append(clusters, cname)
+		}
+		sort.Strings(clusters)
+		if len(clusters) == 0 {
+			resp.WriteString("[-]no member clusters initialized\n")
+		}
+		for _, cname := range clusters {
+			if states[cname] {
+				resp.WriteString("[+]" + cname + " synced\n")
+			} else {
+				resp.WriteString("[-]" + cname + " not synced\n")
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		resp.WriteString("readyz check failed\n")
+	} else {
+		w.WriteHeader(http.StatusOK)
+		resp.WriteString("ok\n")
+	}
+	w.Write([]byte(resp.String()))
+}
@@ -0,0 +1,195 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/api/models"
+)
+
+const (
+	RejectedObjsPath = "/api/filter/rejected"
+
+	// DefaultRejectedObjsCacheSize is the default max number of rejected objects kept in the cache
+	DefaultRejectedObjsCacheSize = 1000
+)
+
+// RejectedObj is an object currently rejected by the filter, along with the reason and the time of
+// the last rejection.
+type RejectedObj struct {
+	ObjType   string    `json:"objType"`
+	Cluster   string    `json:"cluster"`
+	Namespace string    `json:"namespace,omitempty"`
+	Name      string    `json:"name"`
+	Reason    string    `json:"reason"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// rejectedObjs caches the objects rejected by the filter, ordered from the least to the most
+// recently rejected. An object is removed when it is accepted or deleted, or its cluster is removed,
+// and the least recently rejected objects are evicted once the cache is full.
+var rejectedObjs = struct {
+	objs    map[string]*list.Element
+	order   *list.List
+	maxSize int
+	lock    sync.Mutex
+}{objs: make(map[string]*list.Element), order: list.New(), maxSize: DefaultRejectedObjsCacheSize}
+
+func rejectedObjKey(objType, key string) string {
+	return objType + "/" + key
+}
+
+// SetRejectedObjsCacheSize sets the max number of rejected objects kept in the cache, the least
+// recently rejected objects are evicted if the cache has more.
+func SetRejectedObjsCacheSize(size int) error {
+	if size <= 0 {
+		return errors.New("rejected objects cache size " + strconv.Itoa(size) + " must be positive")
+	}
+	rejectedObjs.lock.Lock()
+	defer rejectedObjs.lock.Unlock()
+	rejectedObjs.maxSize = size
+	evictRejectedObjs()
+	return nil
+}
+
+// GetRejectedObjsCacheSize returns the max number of rejected objects kept in the cache.
+func GetRejectedObjsCacheSize() int {
+	rejectedObjs.lock.Lock()
+	defer rejectedObjs.lock.Unlock()
+	return rejectedObjs.maxSize
+}
+
+// evictRejectedObjs evicts the least recently rejected objects till the cache fits its max size,
+// the caller must hold the lock.
+func evictRejectedObjs() {
+	for rejectedObjs.order.Len() > rejectedObjs.maxSize {
+		oldest := rejectedObjs.order.Front()
+		rejectedObjs.order.Remove(oldest)
+		obj := oldest.Value.(RejectedObj)
+		delete(rejectedObjs.objs, rejectedObjKey(obj.ObjType, GetClusterKey(obj.Cluster, obj.Namespace, obj.Name)))
+	}
+}
+
+// RecordRejectedObj updates the cache of the rejected objects with a decision of the filter. A
+// rejected object is added (or refreshed), and an accepted object is removed.
+func RecordRejectedObj(decision FilterDecision) {
+	key := rejectedObjKey(decision.ObjType, GetClusterKey(decision.Cluster, decision.Namespace, decision.Name))
+	rejectedObjs.lock.Lock()
+	defer rejectedObjs.lock.Unlock()
+
+	if elem, ok := rejectedObjs.objs[key]; ok {
+		rejectedObjs.order.Remove(elem)
+		delete(rejectedObjs.objs, key)
+	}
+	if decision.Accepted {
+		return
+	}
+	rejectedObjs.objs[key] = rejectedObjs.order.PushBack(RejectedObj{
+		ObjType:   decision.ObjType,
+		Cluster:   decision.Cluster,
+		Namespace: decision.Namespace,
+		Name:      decision.Name,
+		Reason:    decision.Reason,
+		Timestamp: time.Now(),
+	})
+	evictRejectedObjs()
+}
+
+// DeleteRejectedObj removes a deleted object from the cache of the rejected objects.
+func DeleteRejectedObj(objType, cname, ns, objName string) {
+	key := rejectedObjKey(objType, GetClusterKey(cname, ns, objName))
+	rejectedObjs.lock.Lock()
+	defer rejectedObjs.lock.Unlock()
+	if elem, ok := rejectedObjs.objs[key]; ok {
+		rejectedObjs.order.Remove(elem)
+		delete(rejectedObjs.objs, key)
+	}
+}
+
+// DeleteClusterRejectedObjs removes all the objects of the cluster cname from the cache of the
+// rejected objects, once the cluster is removed.
+func DeleteClusterRejectedObjs(cname string) {
+	rejectedObjs.lock.Lock()
+	defer rejectedObjs.lock.Unlock()
+	var next *list.Element
+	for elem := rejectedObjs.order.Front(); elem != nil; elem = next {
+		next = elem.Next()
+		obj := elem.Value.(RejectedObj)
+		if obj.Cluster != cname {
+			continue
+		}
+		rejectedObjs.order.Remove(elem)
+		delete(rejectedObjs.objs, rejectedObjKey(obj.ObjType, GetClusterKey(obj.Cluster, obj.Namespace, obj.Name)))
+	}
+}
+
+// GetRejectedObjs returns the rejected objects in the cache, most recently rejected first.
+func GetRejectedObjs() []RejectedObj {
+	rejectedObjs.lock.Lock()
+	defer rejectedObjs.lock.Unlock()
+	objs := make([]RejectedObj, 0, rejectedObjs.order.Len())
+	for elem := rejectedObjs.order.Back(); elem != nil; elem = elem.Prev() {
+		objs = append(objs, elem.Value.(RejectedObj))
+	}
+	return objs
+}
+
+// ClearRejectedObjs removes all the objects from the cache of the rejected objects.
+func ClearRejectedObjs() {
+	rejectedObjs.lock.Lock()
+	defer rejectedObjs.lock.Unlock()
+	rejectedObjs.objs = make(map[string]*list.Element)
+	rejectedObjs.order.Init()
+}
+
+// RejectedObjsModel implements ApiModel for the debug endpoint listing the objects currently
+// rejected by the filter.
+type RejectedObjsModel struct{}
+
+func (r *RejectedObjsModel) InitModel() {}
+
+func (r *RejectedObjsModel) ApiOperationMap() []models.OperationMap {
+	get := models.OperationMap{
+		Route:   RejectedObjsPath,
+		Method:  "GET",
+		Handler: RejectedObjsHandler,
+	}
+	return []models.OperationMap{get}
+}
+
+// RejectedObjsHandler responds with the rejected objects in the cache, most recently rejected
+// first. The optional "objtype" and "cluster" query parameters narrow down the list.
+func RejectedObjsHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	objType, cluster := query.Get("objtype"), query.Get("cluster")
+	objs := []RejectedObj{}
+	for _, obj := range GetRejectedObjs() {
+		if (objType != "" && !strings.EqualFold(obj.ObjType, objType)) || (cluster != "" && obj.Cluster != cluster) {
+			continue
+		}
+		objs = append(objs, obj)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(objs)
+}
@@ -0,0 +1,364 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"sync"
+)
+
+// ClusterRenamer is implemented by the objects in the stores which save the name of their
+// cluster, so that RenameCluster can update the name.
+type ClusterRenamer interface {
+	// CopyWithCluster returns a copy of the object with cname as the name of its cluster.
+	CopyWithCluster(cname string) interface{}
+}
+
+// clusterNamesLock makes the rename of a cluster atomic relative to the ingestion. The event
+// handlers of the member controllers and the graph layer hold it for reading while they process an
+// object, RenameCluster holds it for writing for the whole rename, including the rename hooks.
+var clusterNamesLock sync.RWMutex
+
+// RLockClusterNames locks the names of the member clusters for reading, no cluster is renamed till
+// RUnlockClusterNames is called. Must not be called by the rename hooks.
+func RLockClusterNames() {
+	clusterNamesLock.RLock()
+}
+
+// RUnlockClusterNames undoes a call to RLockClusterNames.
+func RUnlockClusterNames() {
+	clusterNamesLock.RUnlock()
+}
+
+// renamedClusters maps the previous names of the renamed clusters to their new names, so that the
+// keys queued with a previous name before the rename are processed for the renamed cluster.
+var renamedClusters = struct {
+	names map[string]string
+	lock  sync.RWMutex
+}{names: make(map[string]string)}
+
+// ResolveClusterName returns the current name of cluster cname, cname itself if it wasn't renamed.
+func ResolveClusterName(cname string) string {
+	renamedClusters.lock.RLock()
+	defer renamedClusters.lock.RUnlock()
+	// a cluster renamed more than once is resolved via its intermediate names
+	for i := 0; i < len(renamedClusters.names); i++ {
+		newName, ok := renamedClusters.names[cname]
+		if !ok {
+			break
+		}
+		cname = newName
+	}
+	return cname
+}
+
+func recordRenamedCluster(oldName, newName string) {
+	renamedClusters.lock.Lock()
+	defer renamedClusters.lock.Unlock()
+	// newName is the current name of a cluster now, so it no longer resolves to any other name
+	delete(renamedClusters.names, newName)
+	renamedClusters.names[oldName] = newName
+}
+
+// clusterRenameHooks are the hooks which rename a cluster in the state saved outside of the stores
+// and the global filter.
+var clusterRenameHooks = struct {
+	hooks []func(oldName, newName string)
+	lock  sync.Mutex
+}{}
+
+// RegisterClusterRenameHook registers hook to be called by RenameCluster, to rename the cluster
+// oldName to newName in the state saved by the other layers, e.g. the host maps of the object types
+// and the members of the GS graphs.
+func RegisterClusterRenameHook(hook func(oldName, newName string)) {
+	clusterRenameHooks.lock.Lock()
+	defer clusterRenameHooks.lock.Unlock()
+	clusterRenameHooks.hooks = append(clusterRenameHooks.hooks, hook)
+}
+
+func getClusterRenameHooks() []func(oldName, newName string) {
+	clusterRenameHooks.lock.Lock()
+	defer clusterRenameHooks.lock.Unlock()
+	return append([]func(oldName, newName string){}, clusterRenameHooks.hooks...)
+}
+
+func (o *ObjectMapStore) hasObjects() bool {
+	o.ObjLock.RLock()
+	defer o.ObjLock.RUnlock()
+	return len(o.ObjectMap) != 0
+}
+
+func (store *ObjectStore) hasObjects() bool {
+	store.NSLock.RLock()
+	defer store.NSLock.RUnlock()
+	for _, mapStore := range store.NSObjectMap {
+		if mapStore.hasObjects() {
+			return true
+		}
+	}
+	return false
+}
+
+func renameObjsInMapStore(mapStore *ObjectMapStore, newName string) {
+	mapStore.ObjLock.Lock()
+	defer mapStore.ObjLock.Unlock()
+	for objName, obj := range mapStore.ObjectMap {
+		if renamer, ok := obj.(ClusterRenamer); ok {
+			mapStore.ObjectMap[objName] = renamer.CopyWithCluster(newName)
+		}
+	}
+}
+
+// getMapStoreEvents returns the events of eventType for the objects of mapStore.
+func getMapStoreEvents(mapStore *ObjectMapStore, eventType, cname, ns string) []StoreEvent {
+	mapStore.ObjLock.RLock()
+	defer mapStore.ObjLock.RUnlock()
+	events := make([]StoreEvent, 0, len(mapStore.ObjectMap))
+	for objName, obj := range mapStore.ObjectMap {
+		events = append(events, StoreEvent{Type: eventType, Cluster: cname, Namespace: ns, Name: objName, Obj: obj})
+	}
+	return events
+}
+
+// renameInClusterStore moves the objects of the cluster oldName to newName, the caller must hold
+// the eventLock and the ClusterLock of clusterStore. Returns the events for the subscribers of
+// the store, a delete of each object under oldName followed by its add under newName.
+func renameInClusterStore(clusterStore *ClusterStore, oldName, newName string) []StoreEvent {
+	objStore, ok := clusterStore.ClusterObjectMap[oldName]
+	if !ok {
+		return nil
+	}
+	var deletes, adds []StoreEvent
+	objStore.NSLock.RLock()
+	for ns, mapStore := range objStore.NSObjectMap {
+		deletes = append(deletes, getMapStoreEvents(mapStore, StoreEventDelete, oldName, ns)...)
+		renameObjsInMapStore(mapStore, newName)
+		adds = append(adds, getMapStoreEvents(mapStore, StoreEventAdd, newName, ns)...)
+	}
+	objStore.NSLock.RUnlock()
+	delete(clusterStore.ClusterObjectMap, oldName)
+	clusterStore.ClusterObjectMap[newName] = objStore
+	return append(deletes, adds...)
+}
+
+// renameInNSStore moves the namespaces of the cluster oldName to newName, the caller must hold the
+// NSLock of nsStore. The namespace stores are keyed by the cluster names.
+func renameInNSStore(nsStore *ObjectStore, oldName, newName string) {
+	mapStore, ok := nsStore.NSObjectMap[oldName]
+	if !ok {
+		return
+	}
+	renameObjsInMapStore(mapStore, newName)
+	delete(nsStore.NSObjectMap, oldName)
+	nsStore.NSObjectMap[newName] = mapStore
+}
+
+func renameInClusterTraffic(trafficSplit []ClusterTraffic, oldName, newName string) {
+	for idx := range trafficSplit {
+		if trafficSplit[idx].ClusterName == oldName {
+			trafficSplit[idx].ClusterName = newName
+		}
+	}
+}
+
+// renameInFilter renames the cluster oldName in the global filter, the caller must hold the
+// GlobalLock of gf.
+func (gf *GlobalFilter) renameInFilter(oldName, newName string) {
+	// ApplicableClusters may be shared with the GDP object, so it is replaced instead of being
+	// updated in place
+	applicableClusters := make([]string, 0, len(gf.ApplicableClusters))
+	for _, cname := range gf.ApplicableClusters {
+		if cname == oldName {
+			cname = newName
+		}
+		applicableClusters = append(applicableClusters, cname)
+	}
+	gf.ApplicableClusters = applicableClusters
+	renameInClusterTraffic(gf.TrafficSplit, oldName, newName)
+	gf.objCounter.rename(oldName, newName)
+	for _, tr := range gf.TrafficRules {
+		renameInClusterTraffic(tr.TrafficSplit, oldName, newName)
+	}
+	if gf.NSFilter != nil {
+		gf.NSFilter.Lock.Lock()
+		if nsList, ok := gf.NSFilter.SelectedNS[oldName]; ok {
+			delete(gf.NSFilter.SelectedNS, oldName)
+			gf.NSFilter.SelectedNS[newName] = nsList
+		}
+		gf.NSFilter.Lock.Unlock()
+	}
+	gf.ComputeChecksum()
+}
+
+// isClusterKnown returns true if cname is present in any of the stores or the global filter, the
+// caller must hold the locks of all of them.
+func (gf *GlobalFilter) isClusterKnown(cname string, clusterStores []*ClusterStore, nsStores []*ObjectStore) bool {
+	// the lookups in the stores initialize empty entries, so only the entries with objects count
+	for _, clusterStore := range clusterStores {
+		if objStore, ok := clusterStore.ClusterObjectMap[cname]; ok && objStore.hasObjects() {
+			return true
+		}
+	}
+	for _, nsStore := range nsStores {
+		if mapStore, ok := nsStore.NSObjectMap[cname]; ok && mapStore.hasObjects() {
+			return true
+		}
+	}
+	if PresentInList(cname, gf.ApplicableClusters) {
+		return true
+	}
+	if gf.NSFilter != nil {
+		gf.NSFilter.Lock.RLock()
+		_, ok := gf.NSFilter.SelectedNS[cname]
+		gf.NSFilter.Lock.RUnlock()
+		if ok {
+			return true
+		}
+	}
+	return IsClusterContextPresent(cname)
+}
+
+// RenameCluster migrates the state saved for the member cluster oldName to newName, when the
+// context of a member cluster is renamed. The objects of the cluster in the accepted and rejected
+// stores, the selected namespaces of the namespace filter, the traffic splits and the applicable
+// clusters of the global filter, and the per cluster settings (e.g. the tenant and the event
+// recorder) are re-keyed to newName. The registered rename hooks then rename the cluster in the
+// other layers, e.g. the member controller of the cluster and the GS graphs. The cluster names are
+// locked for writing for the whole rename, so the ingestion never sees a partially renamed
+// cluster, and the keys queued with oldName are resolved to newName via ResolveClusterName. Returns
+// an error if newName is already in use or oldName is not known.
+func RenameCluster(oldName, newName string) error {
+	if oldName == "" || newName == "" {
+		return errors.New("cluster names can't be empty")
+	}
+	if oldName == newName {
+		return nil
+	}
+	clusterNamesLock.Lock()
+	defer clusterNamesLock.Unlock()
+
+	if err := renameClusterInStores(oldName, newName); err != nil {
+		return err
+	}
+	renameClusterSettings(oldName, newName)
+	recordRenamedCluster(oldName, newName)
+	for _, hook := range getClusterRenameHooks() {
+		hook(oldName, newName)
+	}
+	Logf("cluster: %s, newName: %s, msg: renamed the cluster", oldName, newName)
+	return nil
+}
+
+// renameClusterSettings re-keys the settings and the state saved per member cluster from oldName
+// to newName.
+func renameClusterSettings(oldName, newName string) {
+	clusterTenants.lock.Lock()
+	if tenant, ok := clusterTenants.tenants[oldName]; ok {
+		delete(clusterTenants.tenants, oldName)
+		clusterTenants.tenants[newName] = tenant
+	}
+	clusterTenants.lock.Unlock()
+
+	clusterObjTypes.lock.Lock()
+	if objTypes, ok := clusterObjTypes.types[oldName]; ok {
+		delete(clusterObjTypes.types, oldName)
+		clusterObjTypes.types[newName] = objTypes
+	}
+	clusterObjTypes.lock.Unlock()
+
+	ers := getClusterEventRecorders()
+	ers.lock.Lock()
+	if recorder, ok := ers.recorders[oldName]; ok {
+		delete(ers.recorders, oldName)
+		ers.recorders[newName] = recorder
+	}
+	ers.lock.Unlock()
+
+	informerErrors.lock.Lock()
+	if errs, ok := informerErrors.errs[oldName]; ok {
+		delete(informerErrors.errs, oldName)
+		informerErrors.errs[newName] = errs
+	}
+	informerErrors.lock.Unlock()
+
+	cs := getClusterSyncStates()
+	cs.lock.Lock()
+	if state, ok := cs.states[oldName]; ok {
+		delete(cs.states, oldName)
+		cs.states[newName] = state
+	}
+	cs.lock.Unlock()
+}
+
+// renameClusterInStores renames the cluster oldName to newName in the stores, the global filter and
+// the member clusters, holding all of their locks.
+func renameClusterInStores(oldName, newName string) error {
+	clusterStores := getObjTypeStores()
+	nsStores := []*ObjectStore{GetAcceptedNSStore(), GetRejectedNSStore()}
+
+	// the stores are locked before the global filter, as the filter gets evaluated while holding
+	// the locks of the stores
+	for _, clusterStore := range clusterStores {
+		clusterStore.eventLock.Lock()
+		defer clusterStore.eventLock.Unlock()
+		clusterStore.ClusterLock.Lock()
+		defer clusterStore.ClusterLock.Unlock()
+	}
+	for _, nsStore := range nsStores {
+		nsStore.NSLock.Lock()
+		defer nsStore.NSLock.Unlock()
+	}
+	gf := GetGlobalFilter()
+	gf.GlobalLock.Lock()
+	defer gf.GlobalLock.Unlock()
+
+	if gf.isClusterKnown(newName, clusterStores, nsStores) {
+		return errors.New("cluster " + newName + " already exists, can't rename cluster " + oldName)
+	}
+	if !gf.isClusterKnown(oldName, clusterStores, nsStores) {
+		return errors.New("cluster " + oldName + " not found")
+	}
+
+	for _, clusterStore := range clusterStores {
+		for _, event := range renameInClusterStore(clusterStore, oldName, newName) {
+			clusterStore.publish(event)
+		}
+	}
+	for _, nsStore := range nsStores {
+		renameInNSStore(nsStore, oldName, newName)
+	}
+	gf.renameInFilter(oldName, newName)
+	clusterContextLock.Lock()
+	for idx := range initializedClusterContexts {
+		if initializedClusterContexts[idx] == oldName {
+			initializedClusterContexts[idx] = newName
+		}
+	}
+	clusterContextLock.Unlock()
+	memberClusters.lock.Lock()
+	for idx := range memberClusters.clusters {
+		if memberClusters.clusters[idx] == oldName {
+			memberClusters.clusters[idx] = newName
+		}
+	}
+	memberClusters.lock.Unlock()
+	clusterRegions.Lock()
+	if region, ok := clusterRegions.regions[oldName]; ok {
+		delete(clusterRegions.regions, oldName)
+		clusterRegions.regions[newName] = region
+	}
+	clusterRegions.Unlock()
+	return nil
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/api/models"
+)
+
+const (
+	ForceResyncPath = "/api/resync"
+	// EnableResyncAPIEnv is the environment variable to serve the resync endpoint, disabled by default.
+	EnableResyncAPIEnv = "ENABLE_RESYNC_API"
+)
+
+// ResyncAPIEnabled returns true if the resync endpoint is enabled via its environment variable.
+func ResyncAPIEnabled() bool {
+	val := os.Getenv(EnableResyncAPIEnv)
+	if val == "" {
+		return false
+	}
+	enabled, err := strconv.ParseBool(val)
+	if err != nil {
+		Warnf("env: %s, value: %s, msg: invalid value, resync endpoint will be disabled", EnableResyncAPIEnv, val)
+		return false
+	}
+	return enabled
+}
+
+// resyncState coalesces the forced resyncs, a resync requested while another one is running
+// is run once after the running one completes, irrespective of the number of requests.
+type resyncState struct {
+	syncFunc func()
+	running  bool
+	pending  bool
+	lock     sync.Mutex
+}
+
+var forcedResync resyncState
+
+// SetForceResyncFunc sets the function which re-evaluates all the objects for a forced resync.
+func SetForceResyncFunc(syncFunc func()) {
+	forcedResync.lock.Lock()
+	defer forcedResync.lock.Unlock()
+	forcedResync.syncFunc = syncFunc
+}
+
+// ForceResync re-evaluates all the objects of all the member clusters. If a resync is already
+// running, the request is coalesced into a single resync after the running one, and false is
+// returned without waiting. Returns an error if the resync function is not set.
+func ForceResync() (bool, error) {
+	forcedResync.lock.Lock()
+	if forcedResync.syncFunc == nil {
+		forcedResync.lock.Unlock()
+		return false, errors.New("resync not initialized")
+	}
+	if forcedResync.running {
+		forcedResync.pending = true
+		forcedResync.lock.Unlock()
+		Logf("msg: a resync is already running, will resync again once it completes")
+		return false, nil
+	}
+	forcedResync.running = true
+	for {
+		syncFunc := forcedResync.syncFunc
+		forcedResync.pending = false
+		forcedResync.lock.Unlock()
+
+		Logf("msg: starting a forced resync of all the objects")
+		syncFunc()
+		Logf("msg: forced resync of all the objects completed")
+
+		forcedResync.lock.Lock()
+		if !forcedResync.pending {
+			forcedResync.running = false
+			forcedResync.lock.Unlock()
+			return true, nil
+		}
+	}
+}
+
+// ForceResyncModel implements ApiModel for the endpoint forcing a resync of all the objects.
+type ForceResyncModel struct{}
+
+func (f *ForceResyncModel) InitModel() {}
+
+func (f *ForceResyncModel) ApiOperationMap() []models.OperationMap {
+	post := models.OperationMap{
+		Route:   ForceResyncPath,
+		Method:  "POST",
+		Handler: ForceResyncHandler,
+	}
+	return []models.OperationMap{post}
+}
+
+// ForceResyncHandler starts a forced resync in the background and responds with 202, with 405 for
+// a method other than POST, or with 503 if AMKO isn't initialized yet.
+func ForceResyncHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(map[string]string{"error": "method not allowed"})
+		return
+	}
+	forcedResync.lock.Lock()
+	initialized := forcedResync.syncFunc != nil
+	forcedResync.lock.Unlock()
+	if !initialized {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"error": "resync not initialized"})
+		return
+	}
+	go func() {
+		if _, err := ForceResync(); err != nil {
+			Errf("msg: error in forcing a resync, %s", err.Error())
+		}
+	}()
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"status": "resync requested"})
+}
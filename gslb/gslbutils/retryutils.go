@@ -0,0 +1,481 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+)
+
+// RestError is the typed error carried from the rest layer to the retry layer for a key. It
+// determines whether the key will be retried or moved to the dead letter store.
+type RestError struct {
+	// StatusCode is the HTTP status code of the failed operation, 0 if there was no response
+	StatusCode int
+	Message    string
+	Transient  bool
+}
+
+func (restErr RestError) Error() string {
+	return "status code: " + strconv.Itoa(restErr.StatusCode) + ", error: " + restErr.Message
+}
+
+var (
+	// transientStatusCodes are the status codes, for which a retry may succeed
+	transientStatusCodes = []int{404, 409, 500, 501, 502, 503, 504}
+	transientCodesLock   sync.RWMutex
+)
+
+// SetTransientStatusCodes overrides the list of status codes for which the operations are retried.
+func SetTransientStatusCodes(codes []int) {
+	transientCodesLock.Lock()
+	defer transientCodesLock.Unlock()
+	transientStatusCodes = make([]int, len(codes))
+	copy(transientStatusCodes, codes)
+}
+
+// ParseTransientStatusCodes parses a comma separated list of HTTP status codes, e.g. "500,503".
+func ParseTransientStatusCodes(val string) ([]int, error) {
+	codes := []int{}
+	for _, field := range strings.Split(val, ",") {
+		code, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, errors.New("status code " + field + " is not a number")
+		}
+		if code < 400 || code > 599 {
+			return nil, errors.New("status code " + strconv.Itoa(code) + " must be between 400 and 599")
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// IsTransientStatusCode returns true if an operation failing with status code "code" can be retried.
+func IsTransientStatusCode(code int) bool {
+	transientCodesLock.RLock()
+	defer transientCodesLock.RUnlock()
+	for _, c := range transientStatusCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// NewRestError builds a RestError for a status code and classifies it as transient or permanent.
+func NewRestError(code int, msg string) RestError {
+	return RestError{
+		StatusCode: code,
+		Message:    msg,
+		Transient:  IsTransientStatusCode(code),
+	}
+}
+
+type retryErrors struct {
+	errMap map[string]RestError
+	lock   sync.Mutex
+}
+
+var retryErrs retryErrors
+var retryErrsOnce sync.Once
+
+func getRetryErrors() *retryErrors {
+	retryErrsOnce.Do(func() {
+		retryErrs.errMap = make(map[string]RestError)
+	})
+	return &retryErrs
+}
+
+// SetRetryError saves the error due to which key is being published to the retry layer.
+func SetRetryError(key string, restErr RestError) {
+	re := getRetryErrors()
+	re.lock.Lock()
+	defer re.lock.Unlock()
+	re.errMap[key] = restErr
+}
+
+// GetAndDeleteRetryError returns the error (if any) due to which key was published to the retry
+// layer, and removes it.
+func GetAndDeleteRetryError(key string) (RestError, bool) {
+	re := getRetryErrors()
+	re.lock.Lock()
+	defer re.lock.Unlock()
+	restErr, ok := re.errMap[key]
+	if ok {
+		delete(re.errMap, key)
+	}
+	return restErr, ok
+}
+
+const (
+	// RetryQueueOverflowRejectNew rejects the keys published to the full retry queues
+	RetryQueueOverflowRejectNew = "reject-new"
+	// RetryQueueOverflowDropOldest drops the oldest key pending retry to make room for a new key
+	RetryQueueOverflowDropOldest = "drop-oldest"
+	// retryQueueHighWaterMark is the percentage of the max depth of the retry queues, above which
+	// a warning is logged
+	retryQueueHighWaterMark = 80
+)
+
+// retryQueueLimits bound the number of keys pending retry across the retry queues, a max depth of
+// 0 leaves the retry queues unbounded.
+var retryQueueLimits = struct {
+	maxDepth int
+	policy   string
+	lock     sync.RWMutex
+}{policy: RetryQueueOverflowRejectNew}
+
+// SetRetryQueueMaxDepth sets the max number of keys pending retry across the retry queues, 0 leaves
+// the retry queues unbounded.
+func SetRetryQueueMaxDepth(depth int) error {
+	if depth < 0 {
+		return errors.New("retry queue max depth " + strconv.Itoa(depth) + " can't be negative")
+	}
+	retryQueueLimits.lock.Lock()
+	defer retryQueueLimits.lock.Unlock()
+	retryQueueLimits.maxDepth = depth
+	return nil
+}
+
+const (
+	// DefaultNumRetryWorkers is the default number of workers of each of the retry queues
+	DefaultNumRetryWorkers = 1
+	// MaxNumRetryWorkers is the max number of workers of each of the retry queues
+	MaxNumRetryWorkers = 8
+)
+
+// numRetryWorkers is the number of workers of each of the retry queues, these are separate from the
+// workers of the ingestion and the graph layers.
+var numRetryWorkers uint32 = DefaultNumRetryWorkers
+
+// SetNumRetryWorkers sets the number of workers of each of the retry queues. It must be called
+// before the retry queues are created, the workers of the existing queues aren't changed.
+func SetNumRetryWorkers(workers int) error {
+	if workers < 1 || workers > MaxNumRetryWorkers {
+		return errors.New("retry workers " + strconv.Itoa(workers) + " must be between 1 and " +
+			strconv.Itoa(MaxNumRetryWorkers))
+	}
+	atomic.StoreUint32(&numRetryWorkers, uint32(workers))
+	return nil
+}
+
+// GetNumRetryWorkers returns the number of workers of each of the retry queues.
+func GetNumRetryWorkers() uint32 {
+	return atomic.LoadUint32(&numRetryWorkers)
+}
+
+// GetRetryQueueParams returns the parameters of the slow and the fast retry queues, with the
+// configured number of workers.
+func GetRetryQueueParams() []utils.WorkerQueue {
+	workers := GetNumRetryWorkers()
+	return []utils.WorkerQueue{
+		{NumWorkers: workers, WorkqueueName: SlowRetryQueue, SlowSyncTime: SlowSyncTime},
+		{NumWorkers: workers, WorkqueueName: FastRetryQueue},
+	}
+}
+
+// GetRetryQueueMaxDepth returns the max number of keys pending retry, 0 if unbounded.
+func GetRetryQueueMaxDepth() int {
+	retryQueueLimits.lock.RLock()
+	defer retryQueueLimits.lock.RUnlock()
+	return retryQueueLimits.maxDepth
+}
+
+// SetRetryQueueOverflowPolicy sets the policy for the keys published to the full retry queues.
+func SetRetryQueueOverflowPolicy(policy string) error {
+	if policy != RetryQueueOverflowRejectNew && policy != RetryQueueOverflowDropOldest {
+		return errors.New("retry queue overflow policy " + policy + " must be one of " +
+			RetryQueueOverflowRejectNew + ", " + RetryQueueOverflowDropOldest)
+	}
+	retryQueueLimits.lock.Lock()
+	defer retryQueueLimits.lock.Unlock()
+	retryQueueLimits.policy = policy
+	return nil
+}
+
+// GetRetryQueueOverflowPolicy returns the policy for the keys published to the full retry queues.
+func GetRetryQueueOverflowPolicy() string {
+	retryQueueLimits.lock.RLock()
+	defer retryQueueLimits.lock.RUnlock()
+	return retryQueueLimits.policy
+}
+
+type pendingRetry struct {
+	queueName string
+	// seq orders the keys by the time they were published
+	seq uint64
+}
+
+type pendingRetries struct {
+	// keys maps a key pending retry to the retry queue it's pending in
+	keys map[string]pendingRetry
+	// dropped are the keys dropped by the drop-oldest policy, which are still in the retry queues,
+	// the retry layer skips these keys
+	dropped map[string]bool
+	nextSeq uint64
+	// aboveHighWater is set once the high water mark warning is logged, and reset once the depth
+	// falls below the high water mark
+	aboveHighWater bool
+	lock           sync.Mutex
+}
+
+var retriesPending pendingRetries
+var retriesPendingOnce sync.Once
+
+// retryOverflows is the number of keys rejected or dropped because the retry queues were full.
+var retryOverflows uint64
+
+// retryDrops is the number of keys dropped by the drop-oldest policy.
+var retryDrops uint64
+
+func getPendingRetries() *pendingRetries {
+	retriesPendingOnce.Do(func() {
+		retriesPending.keys = make(map[string]pendingRetry)
+		retriesPending.dropped = make(map[string]bool)
+	})
+	return &retriesPending
+}
+
+type retryAdmission int
+
+// pendingRetryKey is a key pending retry along with the retry queue it's pending in.
+type pendingRetryKey struct {
+	key       string
+	queueName string
+}
+
+const (
+	retryAdmitted retryAdmission = iota
+	retryAlreadyPending
+	retryRejected
+)
+
+// oldestKey returns the key pending retry for the longest time, the caller must hold the lock.
+func (pr *pendingRetries) oldestKey() string {
+	var oldest string
+	var oldestSeq uint64
+	for key, pending := range pr.keys {
+		if oldest == "" || pending.seq < oldestSeq {
+			oldest, oldestSeq = key, pending.seq
+		}
+	}
+	return oldest
+}
+
+// checkHighWater logs a warning once the depth crosses the high water mark of maxDepth, the caller
+// must hold the lock.
+func (pr *pendingRetries) checkHighWater(maxDepth int) {
+	if maxDepth == 0 {
+		return
+	}
+	above := len(pr.keys)*100 >= maxDepth*retryQueueHighWaterMark
+	if above && !pr.aboveHighWater {
+		Warnf("depth: %d, maxDepth: %d, msg: retry queues are above %d%% of the max depth", len(pr.keys),
+			maxDepth, retryQueueHighWaterMark)
+	}
+	pr.aboveHighWater = above
+}
+
+// markRetryPending marks key as pending retry in the queue queueName. Returns retryAlreadyPending
+// if the key is already pending retry in any of the retry queues, and retryRejected if the retry
+// queues are full and the overflow policy rejects the new keys. With the drop-oldest policy, the
+// oldest key pending retry is dropped to make room for key, and returned along with its queue.
+func markRetryPending(key, queueName string) (retryAdmission, pendingRetryKey) {
+	maxDepth, policy := GetRetryQueueMaxDepth(), GetRetryQueueOverflowPolicy()
+	pr := getPendingRetries()
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	var dropped pendingRetryKey
+	if _, ok := pr.keys[key]; ok {
+		return retryAlreadyPending, dropped
+	}
+	if maxDepth != 0 && len(pr.keys) >= maxDepth {
+		atomic.AddUint64(&retryOverflows, 1)
+		if policy != RetryQueueOverflowDropOldest {
+			return retryRejected, dropped
+		}
+		dropped.key = pr.oldestKey()
+		dropped.queueName = pr.keys[dropped.key].queueName
+		delete(pr.keys, dropped.key)
+		pr.dropped[dropped.key] = true
+		atomic.AddUint64(&retryDrops, 1)
+	}
+	delete(pr.dropped, key)
+	pr.keys[key] = pendingRetry{queueName: queueName, seq: pr.nextSeq}
+	pr.nextSeq++
+	pr.checkHighWater(maxDepth)
+	return retryAdmitted, dropped
+}
+
+// ClearRetryPending is called by the retry layer when it dequeues key, after which the key can be
+// published to the retry queues again. Returns false if the key was dropped from the retry queues
+// by the drop-oldest policy, the retry layer mustn't retry such keys.
+func ClearRetryPending(key string) bool {
+	pr := getPendingRetries()
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	if pr.dropped[key] {
+		delete(pr.dropped, key)
+		return false
+	}
+	delete(pr.keys, key)
+	pr.checkHighWater(GetRetryQueueMaxDepth())
+	return true
+}
+
+// GetRetryPendingQueue returns the retry queue in which key is pending retry, if any.
+func GetRetryPendingQueue(key string) (string, bool) {
+	pr := getPendingRetries()
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	pending, ok := pr.keys[key]
+	return pending.queueName, ok
+}
+
+// GetRetryQueueDepth returns the number of keys pending retry across the retry queues.
+func GetRetryQueueDepth() int {
+	pr := getPendingRetries()
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	return len(pr.keys)
+}
+
+// GetRetryQueueDepthByName returns the number of keys pending retry in the retry queue queueName.
+func GetRetryQueueDepthByName(queueName string) int {
+	pr := getPendingRetries()
+	pr.lock.Lock()
+	defer pr.lock.Unlock()
+	depth := 0
+	for _, pending := range pr.keys {
+		if pending.queueName == queueName {
+			depth++
+		}
+	}
+	return depth
+}
+
+// GetRetryQueueOverflows returns the number of keys rejected or dropped so far, because the retry
+// queues were full.
+func GetRetryQueueOverflows() uint64 {
+	return atomic.LoadUint64(&retryOverflows)
+}
+
+// GetRetryQueueDrops returns the number of keys dropped so far by the drop-oldest policy.
+func GetRetryQueueDrops() uint64 {
+	return atomic.LoadUint64(&retryDrops)
+}
+
+// PublishToRetryQueue records the error restErr for the key and publishes the key to the retry
+// queue queueName. The retry layer decides on the basis of restErr, whether to retry the key.
+// A key which is already pending retry (in any of the retry queues) isn't published again, only
+// its error is updated: the retries for a GS coalesce into a single retry, which picks the latest
+// GS graph when it's processed. Within a queue, the rate limiting queue already de-duplicates the
+// keys and backs off the keys which fail repeatedly. If the retry queues are full, the key is
+// either rejected or the oldest key is dropped, as per the overflow policy. A dropped key is
+// forgotten by the rate limiter of its queue, and skipped by the retry layer when it's dequeued, as
+// a workqueue can't remove a key once added. The keys which aren't retried are synced again by the
+// next full sync.
+func PublishToRetryQueue(queueName, key string, restErr RestError) {
+	SetRetryError(key, restErr)
+	admission, dropped := markRetryPending(key, queueName)
+	switch admission {
+	case retryAlreadyPending:
+		pendingQueue, _ := GetRetryPendingQueue(key)
+		Logf("key: %s, queue: %s, pendingQueue: %s, msg: key already pending retry, won't publish again", key,
+			queueName, pendingQueue)
+		return
+	case retryRejected:
+		GetAndDeleteRetryError(key)
+		Warnf("key: %s, queue: %s, maxDepth: %d, msg: retry queues are full, won't retry the key", key, queueName,
+			GetRetryQueueMaxDepth())
+		return
+	}
+	if dropped.key != "" {
+		GetAndDeleteRetryError(dropped.key)
+		droppedQueue := utils.SharedWorkQueue().GetQueueByName(dropped.queueName)
+		droppedQueue.Workqueue[utils.Bkt(dropped.key, droppedQueue.NumWorkers)].Forget(dropped.key)
+		Warnf("key: %s, droppedKey: %s, droppedQueue: %s, maxDepth: %d, msg: retry queues are full, dropped the oldest key",
+			key, dropped.key, dropped.queueName, GetRetryQueueMaxDepth())
+	}
+	retryQueue := utils.SharedWorkQueue().GetQueueByName(queueName)
+	// a key always goes to the same worker, so that the retries of a key are never concurrent
+	bkt := utils.Bkt(key, retryQueue.NumWorkers)
+	retryQueue.Workqueue[bkt].AddRateLimited(key)
+	Logf("key: %s, queue: %s, bkt: %d, msg: Published key to retry queue", key, queueName, bkt)
+}
+
+// DeadLetterEntry is a key which won't be retried because of a permanent error.
+type DeadLetterEntry struct {
+	Key       string
+	Err       RestError
+	Timestamp time.Time
+}
+
+type deadLetterStore struct {
+	entries map[string]DeadLetterEntry
+	lock    sync.RWMutex
+}
+
+var deadLetters deadLetterStore
+var deadLettersOnce sync.Once
+
+func getDeadLetterStore() *deadLetterStore {
+	deadLettersOnce.Do(func() {
+		deadLetters.entries = make(map[string]DeadLetterEntry)
+	})
+	return &deadLetters
+}
+
+// AddToDeadLetter adds the key with the permanent error restErr to the dead letter store.
+func AddToDeadLetter(key string, restErr RestError) {
+	dls := getDeadLetterStore()
+	dls.lock.Lock()
+	defer dls.lock.Unlock()
+	dls.entries[key] = DeadLetterEntry{
+		Key:       key,
+		Err:       restErr,
+		Timestamp: time.Now(),
+	}
+}
+
+// DeleteFromDeadLetter removes the key from the dead letter store, returns true if it was present.
+func DeleteFromDeadLetter(key string) bool {
+	dls := getDeadLetterStore()
+	dls.lock.Lock()
+	defer dls.lock.Unlock()
+	if _, ok := dls.entries[key]; !ok {
+		return false
+	}
+	delete(dls.entries, key)
+	return true
+}
+
+// GetDeadLetterEntries returns a copy of all the entries in the dead letter store.
+func GetDeadLetterEntries() []DeadLetterEntry {
+	dls := getDeadLetterStore()
+	dls.lock.RLock()
+	defer dls.lock.RUnlock()
+	entries := make([]DeadLetterEntry, 0, len(dls.entries))
+	for _, entry := range dls.entries {
+		entries = append(entries, entry)
+	}
+	return entries
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+)
+
+const (
+	// NonRoutableIPReason is the reason of the filter decision for the objects with a non-routable IP.
+	NonRoutableIPReason = "non-routable IP"
+	// NonRoutableIP is the event reason for the objects rejected because of a non-routable IP.
+	NonRoutableIP = "NonRoutableIP"
+)
+
+// DefaultNonRoutableCIDRs are the private IPv4 ranges (RFC 1918) and the IPv6 unique local
+// addresses (RFC 4193), these are rejected if no other ranges are configured.
+var DefaultNonRoutableCIDRs = []string{"10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7"}
+
+// nonRoutableIPFilter rejects the objects whose IP addresses are in any of the disallowed ranges,
+// so that the private addresses aren't advertised via the GSLB services.
+var nonRoutableIPFilter = struct {
+	enabled bool
+	cidrs   []*net.IPNet
+	lock    sync.RWMutex
+}{}
+
+// SetNonRoutableIPFilter enables or disables the rejection of the objects with non-routable IP
+// addresses. cidrs are the disallowed ranges, DefaultNonRoutableCIDRs are used if it's empty.
+func SetNonRoutableIPFilter(enabled bool, cidrs []string) error {
+	if len(cidrs) == 0 {
+		cidrs = DefaultNonRoutableCIDRs
+	}
+	ipNets := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, ipNet, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return errors.New("invalid CIDR " + cidr + " for the non-routable IPs")
+		}
+		ipNets = append(ipNets, ipNet)
+	}
+	nonRoutableIPFilter.lock.Lock()
+	defer nonRoutableIPFilter.lock.Unlock()
+	nonRoutableIPFilter.enabled = enabled
+	nonRoutableIPFilter.cidrs = ipNets
+	return nil
+}
+
+// IsNonRoutableIPFilterEnabled returns true if the objects with non-routable IP addresses are
+// rejected.
+func IsNonRoutableIPFilterEnabled() bool {
+	nonRoutableIPFilter.lock.RLock()
+	defer nonRoutableIPFilter.lock.RUnlock()
+	return nonRoutableIPFilter.enabled
+}
+
+// GetNonRoutableCIDRs returns the disallowed ranges of the non-routable IP filter.
+func GetNonRoutableCIDRs() []string {
+	nonRoutableIPFilter.lock.RLock()
+	defer nonRoutableIPFilter.lock.RUnlock()
+	cidrs := make([]string, 0, len(nonRoutableIPFilter.cidrs))
+	for _, ipNet := range nonRoutableIPFilter.cidrs {
+		cidrs = append(cidrs, ipNet.String())
+	}
+	return cidrs
+}
+
+// IsNonRoutableIP returns true if the non-routable IP filter is enabled and ip is in one of its
+// disallowed ranges. An empty ip, or one which isn't an IP address (e.g. a hostname of a load
+// balancer) is never non-routable.
+func IsNonRoutableIP(ip string) bool {
+	parsedIP := net.ParseIP(ip)
+	if parsedIP == nil {
+		return false
+	}
+	nonRoutableIPFilter.lock.RLock()
+	defer nonRoutableIPFilter.lock.RUnlock()
+	if !nonRoutableIPFilter.enabled {
+		return false
+	}
+	for _, ipNet := range nonRoutableIPFilter.cidrs {
+		if ipNet.Contains(parsedIP) {
+			return true
+		}
+	}
+	return false
+}
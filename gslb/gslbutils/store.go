@@ -19,7 +19,9 @@
 package gslbutils
 
 import (
+	"sort"
 	"sync"
+	"time"
 
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
 )
@@ -27,6 +29,15 @@ import (
 type ClusterStore struct {
 	ClusterObjectMap map[string]*ObjectStore
 	ClusterLock      sync.RWMutex
+
+	// subscribers are notified of every change in this store, see Subscribe.
+	subscribers   []chan StoreEvent
+	subLock       sync.RWMutex
+	droppedEvents uint64
+	// eventLock is held by the writers of the store across a change and the publishing of its
+	// event, so that the events are published in the order of the changes. It is taken before
+	// ClusterLock.
+	eventLock sync.Mutex
 }
 
 // Filterfn is a type of a function used to filter out objects.
@@ -92,6 +103,26 @@ func GetRejectedIngressStore() *ClusterStore {
 	return RejectedIngressStore
 }
 
+var acceptedHTTPRouteOnce sync.Once
+
+// GetAcceptedHTTPRouteStore initializes and returns a new accepted HTTPRoute store.
+func GetAcceptedHTTPRouteStore() *ClusterStore {
+	acceptedHTTPRouteOnce.Do(func() {
+		AcceptedHTTPRouteStore = NewClusterStore()
+	})
+	return AcceptedHTTPRouteStore
+}
+
+var rejectedHTTPRouteOnce sync.Once
+
+// GetRejectedHTTPRouteStore initializes and returns a new rejected HTTPRoute store.
+func GetRejectedHTTPRouteStore() *ClusterStore {
+	rejectedHTTPRouteOnce.Do(func() {
+		RejectedHTTPRouteStore = NewClusterStore()
+	})
+	return RejectedHTTPRouteStore
+}
+
 var acceptedNSOnce sync.Once
 
 // GetAcceptedNSStore initializes and returns a new accepted NSStore.
@@ -169,10 +200,10 @@ func (clusterStore *ClusterStore) GetAllFilteredClusterNSObjects(applyFilter Fil
 		nsObjListAcc, nsObjListRej := clusterMap.GetAllFilteredNSObjects(applyFilter, cname)
 		for _, nsObj := range nsObjListAcc {
 			// Prefix the cluster name to the ns+obj name
-			acceptedList = append(acceptedList, cname+"/"+nsObj)
+			acceptedList = append(acceptedList, EscapeKeySegment(cname)+KeyDelimiter+nsObj)
 		}
 		for _, nsObj := range nsObjListRej {
-			rejectedList = append(rejectedList, cname+"/"+nsObj)
+			rejectedList = append(rejectedList, EscapeKeySegment(cname)+KeyDelimiter+nsObj)
 		}
 	}
 	return acceptedList, rejectedList
@@ -190,7 +221,7 @@ func (clusterStore *ClusterStore) GetAllClusterNSObjects() []string {
 		}
 		nsObjs := objStore.GetAllNSObjects()
 		for _, nsObj := range nsObjs {
-			result = append(result, cname+"/"+nsObj)
+			result = append(result, EscapeKeySegment(cname)+KeyDelimiter+nsObj)
 		}
 	}
 	return result
@@ -199,17 +230,58 @@ func (clusterStore *ClusterStore) GetAllClusterNSObjects() []string {
 // AddOrUpdate fetches the right cluster store and then updates the object inside the
 // namespace store inside the cluster store.
 func (clusterStore *ClusterStore) AddOrUpdate(obj interface{}, cname, ns, objName string) {
+	clusterStore.eventLock.Lock()
+	defer clusterStore.eventLock.Unlock()
 	clusterStoreMap := clusterStore.GetClusterStore(cname)
 	// Updating an object inside the cluster store map requires a read lock.
 	clusterStore.ClusterLock.RLock()
-	defer clusterStore.ClusterLock.RUnlock()
-	clusterStoreMap.AddOrUpdate(ns, objName, obj)
+	updated := clusterStoreMap.AddOrUpdate(ns, objName, obj)
+	clusterStore.ClusterLock.RUnlock()
+	clusterStore.publish(StoreEvent{Type: storeEventType(updated), Cluster: cname, Namespace: ns,
+		Name: objName, Obj: obj})
+}
+
+// StoreItem is a single object to be added to a cluster store along with the namespace
+// and name it has to be keyed on.
+type StoreItem struct {
+	Namespace string
+	ObjName   string
+	Obj       interface{}
+}
+
+// AddOrUpdateBatch adds or updates a list of objects for cluster cname. Unlike AddOrUpdate,
+// the cluster and namespace locks are taken only once for the whole batch, which makes it
+// suitable for the initial list of objects during a full sync.
+func (clusterStore *ClusterStore) AddOrUpdateBatch(cname string, items []StoreItem) {
+	if len(items) == 0 {
+		return
+	}
+	clusterStore.eventLock.Lock()
+	defer clusterStore.eventLock.Unlock()
+	clusterStore.ClusterLock.Lock()
+	clusterStoreMap, ok := clusterStore.ClusterObjectMap[cname]
+	if !ok {
+		clusterStoreMap = NewObjectStore()
+		clusterStore.ClusterObjectMap[cname] = clusterStoreMap
+	}
+	updated := clusterStoreMap.AddOrUpdateBatch(items)
+	clusterStore.ClusterLock.Unlock()
+
+	if !clusterStore.hasSubscribers() {
+		return
+	}
+	for idx, item := range items {
+		clusterStore.publish(StoreEvent{Type: storeEventType(updated[idx]), Cluster: cname,
+			Namespace: item.Namespace, Name: item.ObjName, Obj: item.Obj})
+	}
 }
 
 // DeleteClusterNSObj deletes the object from the object map in namespace store
 // in the cluster store. It also checks if the cluster is empty and not required
 // anymore and removes it.
 func (clusterStore *ClusterStore) DeleteClusterNSObj(cname, ns, objName string) (interface{}, bool) {
+	clusterStore.eventLock.Lock()
+	defer clusterStore.eventLock.Unlock()
 	clusterStoreMap := clusterStore.GetClusterStore(cname)
 	if clusterStoreMap == nil {
 		return nil, false
@@ -225,6 +297,10 @@ func (clusterStore *ClusterStore) DeleteClusterNSObj(cname, ns, objName string)
 		// No more namespaces present, just remove the cluster.
 		clusterStore.DeleteClusterStore(cname)
 	}
+	if ok {
+		clusterStore.publish(StoreEvent{Type: StoreEventDelete, Cluster: cname, Namespace: ns,
+			Name: objName, Obj: obj})
+	}
 	return obj, ok
 }
 
@@ -237,6 +313,68 @@ func (clusterStore *ClusterStore) GetClusterNSObjectByName(cname, ns, objName st
 	return obj, ok
 }
 
+// GetClusterNSObjectLastUpdated returns the time at which the object objName of namespace ns in
+// cluster cname was last added or updated in the store.
+func (clusterStore *ClusterStore) GetClusterNSObjectLastUpdated(cname, ns, objName string) (time.Time, bool) {
+	clusterStore.ClusterLock.RLock()
+	defer clusterStore.ClusterLock.RUnlock()
+	objStore, ok := clusterStore.ClusterObjectMap[cname]
+	if !ok {
+		return time.Time{}, false
+	}
+	objStore.NSLock.RLock()
+	defer objStore.NSLock.RUnlock()
+	nsStore, ok := objStore.NSObjectMap[ns]
+	if !ok {
+		return time.Time{}, false
+	}
+	return nsStore.GetLastUpdated(objName)
+}
+
+// StoreObjectInfo is an object in a cluster store, along with the time at which it was last added
+// or updated in the store.
+type StoreObjectInfo struct {
+	Cluster     string
+	Namespace   string
+	Name        string
+	Obj         interface{}
+	LastUpdated time.Time
+}
+
+// GetSnapshot returns all the objects in the cluster store along with their last updated times,
+// sorted by the cluster, namespace and name of the objects.
+func (clusterStore *ClusterStore) GetSnapshot() []StoreObjectInfo {
+	snapshot := []StoreObjectInfo{}
+	clusterStore.ClusterLock.RLock()
+	for cname, objStore := range clusterStore.ClusterObjectMap {
+		if objStore == nil {
+			continue
+		}
+		objStore.NSLock.RLock()
+		for ns, nsStore := range objStore.NSObjectMap {
+			nsStore.ObjLock.RLock()
+			for objName, obj := range nsStore.ObjectMap {
+				snapshot = append(snapshot, StoreObjectInfo{Cluster: cname, Namespace: ns, Name: objName,
+					Obj: obj, LastUpdated: nsStore.lastUpdated[objName]})
+			}
+			nsStore.ObjLock.RUnlock()
+		}
+		objStore.NSLock.RUnlock()
+	}
+	clusterStore.ClusterLock.RUnlock()
+
+	sort.Slice(snapshot, func(i, j int) bool {
+		if snapshot[i].Cluster != snapshot[j].Cluster {
+			return snapshot[i].Cluster < snapshot[j].Cluster
+		}
+		if snapshot[i].Namespace != snapshot[j].Namespace {
+			return snapshot[i].Namespace < snapshot[j].Namespace
+		}
+		return snapshot[i].Name < snapshot[j].Name
+	})
+	return snapshot
+}
+
 // ObjectStore consists of a map of string and ObjectMapStore and a lock.
 type ObjectStore struct {
 	NSObjectMap map[string]*ObjectMapStore
@@ -294,13 +432,32 @@ func (store *ObjectStore) GetAllNamespaces() []string {
 
 }
 
-// AddOrUpdate fetches the right NS Store and then updates the object map store.
-func (store *ObjectStore) AddOrUpdate(key, objName string, obj interface{}) {
+// AddOrUpdate fetches the right NS Store and then updates the object map store. Returns
+// true if an existing object was updated.
+func (store *ObjectStore) AddOrUpdate(key, objName string, obj interface{}) bool {
 	objStore := store.GetNSStore(key)
 	// Updating an object inside the object map requires a read lock on the ns store.
 	store.NSLock.RLock()
 	store.NSLock.RUnlock()
-	objStore.AddOrUpdate(objName, obj)
+	return objStore.AddOrUpdate(objName, obj)
+}
+
+// AddOrUpdateBatch adds or updates all the items in their respective NS stores, taking the
+// NS lock only once. The returned list tells, for each item, whether an existing object
+// was updated.
+func (store *ObjectStore) AddOrUpdateBatch(items []StoreItem) []bool {
+	store.NSLock.Lock()
+	defer store.NSLock.Unlock()
+	updated := make([]bool, len(items))
+	for idx, item := range items {
+		nsObjStore, ok := store.NSObjectMap[item.Namespace]
+		if !ok {
+			nsObjStore = NewObjectMapStore()
+			store.NSObjectMap[item.Namespace] = nsObjStore
+		}
+		updated[idx] = nsObjStore.AddOrUpdate(item.ObjName, item.Obj)
+	}
+	return updated
 }
 
 func (store *ObjectStore) GetAllFilteredNamespaces(applyFilter Filterfn) ([]string, []string) {
@@ -312,11 +469,11 @@ func (store *ObjectStore) GetAllFilteredNamespaces(applyFilter Filterfn) ([]stri
 		nsListAcc, nsListRej := clusterNSMap.GetAllFilteredObjects(applyFilter, cluster)
 		for _, ns := range nsListAcc {
 			// Prefix a cluster name to the list of objects
-			acceptedList = append(acceptedList, cluster+"/"+ns)
+			acceptedList = append(acceptedList, JoinKey(cluster, ns))
 		}
 		for _, ns := range nsListRej {
 			// Prefix a cluster name to the list of objects
-			rejectedList = append(rejectedList, cluster+"/"+ns)
+			rejectedList = append(rejectedList, JoinKey(cluster, ns))
 		}
 	}
 	return acceptedList, rejectedList
@@ -333,11 +490,11 @@ func (store *ObjectStore) GetAllFilteredNSObjects(applyFilter Filterfn,
 		objListAcc, objListRej := nsObjMap.GetAllFilteredObjects(applyFilter, cname)
 		for _, obj := range objListAcc {
 			// Prefixes a namespace to the list of objects
-			acceptedList = append(acceptedList, ns+"/"+obj)
+			acceptedList = append(acceptedList, JoinKey(ns, obj))
 		}
 		for _, obj := range objListRej {
 			// Prefix a namespace to the list of the objects
-			rejectedList = append(rejectedList, ns+"/"+obj)
+			rejectedList = append(rejectedList, JoinKey(ns, obj))
 		}
 	}
 	return acceptedList, rejectedList
@@ -354,7 +511,7 @@ func (store *ObjectStore) GetAllNSObjects() []string {
 		}
 		objs := nsObjMap.GetAllObjectNames()
 		for _, obj := range objs {
-			nsObjs = append(nsObjs, ns+"/"+obj)
+			nsObjs = append(nsObjs, JoinKey(ns, obj))
 		}
 	}
 	return nsObjs
@@ -391,20 +548,35 @@ func (store *ObjectStore) GetNSObjectByName(ns, objName string) (interface{}, bo
 type ObjectMapStore struct {
 	ObjectMap map[string]interface{}
 	ObjLock   sync.RWMutex
+	// lastUpdated is the time at which each object was last added or updated
+	lastUpdated map[string]time.Time
 }
 
 // NewObjectMapStore initializes and returns a new ObjectMapStore.
 func NewObjectMapStore() *ObjectMapStore {
 	nsObjStore := &ObjectMapStore{}
 	nsObjStore.ObjectMap = make(map[string]interface{})
+	nsObjStore.lastUpdated = make(map[string]time.Time)
 	return nsObjStore
 }
 
-// AddOrUpdate adds or updates the object objName in object map store.
-func (o *ObjectMapStore) AddOrUpdate(objName string, obj interface{}) {
+// AddOrUpdate adds or updates the object objName in object map store. Returns true if
+// the object was already present.
+func (o *ObjectMapStore) AddOrUpdate(objName string, obj interface{}) bool {
 	o.ObjLock.Lock()
 	defer o.ObjLock.Unlock()
+	_, present := o.ObjectMap[objName]
 	o.ObjectMap[objName] = obj
+	o.lastUpdated[objName] = time.Now()
+	return present
+}
+
+// GetLastUpdated returns the time at which the object objName was last added or updated.
+func (o *ObjectMapStore) GetLastUpdated(objName string) (time.Time, bool) {
+	o.ObjLock.RLock()
+	defer o.ObjLock.RUnlock()
+	lastUpdated, ok := o.lastUpdated[objName]
+	return lastUpdated, ok
 }
 
 // Delete deletes the key and the value from the map store and returns that object
@@ -415,6 +587,7 @@ func (o *ObjectMapStore) Delete(objName string) (interface{}, bool) {
 	obj, ok := o.ObjectMap[objName]
 	if ok {
 		delete(o.ObjectMap, objName)
+		delete(o.lastUpdated, objName)
 		return obj, true
 	}
 	utils.AviLog.Warnf("Object Not found in store. Nothing to delete: %s ", objName)
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sync/atomic"
+)
+
+const (
+	StoreEventAdd    = "ADD"
+	StoreEventUpdate = "UPDATE"
+	StoreEventDelete = "DELETE"
+
+	// StoreEventBufferSize is the number of events buffered for each subscriber of a
+	// cluster store.
+	StoreEventBufferSize = 1024
+)
+
+// StoreEvent describes a change to an object in a cluster store. For deletes, Obj is the
+// object that was removed from the store.
+type StoreEvent struct {
+	Type      string
+	Cluster   string
+	Namespace string
+	Name      string
+	Obj       interface{}
+}
+
+// Subscribe returns a channel on which all the subsequent adds, updates and deletes on
+// the cluster store are published. The channel is buffered with StoreEventBufferSize
+// events. Publishing never blocks the writers of the store: if a subscriber falls behind
+// and its buffer is full, the event is dropped for that subscriber and counted in
+// DroppedEvents. Subscribers which can't afford to miss events should re-list the store
+// once they detect drops. The events are published in the order of the changes, a rename of
+// a cluster is published as a delete of each of its objects followed by an add under the new
+// cluster name.
+func (clusterStore *ClusterStore) Subscribe() <-chan StoreEvent {
+	ch := make(chan StoreEvent, StoreEventBufferSize)
+	clusterStore.subLock.Lock()
+	defer clusterStore.subLock.Unlock()
+	clusterStore.subscribers = append(clusterStore.subscribers, ch)
+	return ch
+}
+
+// Unsubscribe removes a subscription returned by Subscribe and closes its channel.
+func (clusterStore *ClusterStore) Unsubscribe(sub <-chan StoreEvent) {
+	clusterStore.subLock.Lock()
+	defer clusterStore.subLock.Unlock()
+	for idx, ch := range clusterStore.subscribers {
+		if ch == sub {
+			clusterStore.subscribers = append(clusterStore.subscribers[:idx], clusterStore.subscribers[idx+1:]...)
+			close(ch)
+			return
+		}
+	}
+}
+
+// DroppedEvents returns the number of events dropped across all the subscribers because
+// of a full buffer.
+func (clusterStore *ClusterStore) DroppedEvents() uint64 {
+	return atomic.LoadUint64(&clusterStore.droppedEvents)
+}
+
+func (clusterStore *ClusterStore) hasSubscribers() bool {
+	clusterStore.subLock.RLock()
+	defer clusterStore.subLock.RUnlock()
+	return len(clusterStore.subscribers) > 0
+}
+
+func (clusterStore *ClusterStore) publish(event StoreEvent) {
+	clusterStore.subLock.RLock()
+	defer clusterStore.subLock.RUnlock()
+	for _, ch := range clusterStore.subscribers {
+		select {
+		case ch <- event:
+		default:
+			atomic.AddUint64(&clusterStore.droppedEvents, 1)
+			Debugf("cluster: %s, ns: %s, name: %s, event: %s, msg: subscriber buffer full, dropping store event",
+				event.Cluster, event.Namespace, event.Name, event.Type)
+		}
+	}
+}
+
+func storeEventType(updated bool) string {
+	if updated {
+		return StoreEventUpdate
+	}
+	return StoreEventAdd
+}
@@ -0,0 +1,133 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+)
+
+// DefaultTenant is the Avi tenant of the GSLB services of the member clusters without a tenant.
+const DefaultTenant = utils.ADMIN_NS
+
+// clusterTenants maps the member clusters to the Avi tenants of their GSLB services, and the UUIDs
+// of the validated tenants to their names.
+var clusterTenants = struct {
+	tenants map[string]string
+	uuids   map[string]string
+	lock    sync.RWMutex
+}{tenants: make(map[string]string), uuids: make(map[string]string)}
+
+// SetClusterTenant maps cluster cname to an Avi tenant, an empty tenant maps the cluster to the
+// default tenant.
+func SetClusterTenant(cname, tenant string) {
+	clusterTenants.lock.Lock()
+	defer clusterTenants.lock.Unlock()
+	if tenant == "" || tenant == DefaultTenant {
+		delete(clusterTenants.tenants, cname)
+		return
+	}
+	clusterTenants.tenants[cname] = tenant
+}
+
+// GetClusterTenant returns the Avi tenant of cluster cname, the default tenant if the cluster isn't
+// mapped to a tenant.
+func GetClusterTenant(cname string) string {
+	clusterTenants.lock.RLock()
+	defer clusterTenants.lock.RUnlock()
+	if tenant, ok := clusterTenants.tenants[cname]; ok {
+		return tenant
+	}
+	return DefaultTenant
+}
+
+// GetAllTenants returns the sorted Avi tenants of the member clusters, including the default tenant.
+func GetAllTenants() []string {
+	clusterTenants.lock.RLock()
+	defer clusterTenants.lock.RUnlock()
+	tenants := []string{DefaultTenant}
+	for _, tenant := range clusterTenants.tenants {
+		if !PresentInList(tenant, tenants) {
+			tenants = append(tenants, tenant)
+		}
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// TenantLookup returns the UUID of an Avi tenant, and false if the tenant doesn't exist.
+type TenantLookup func(tenant string) (string, bool, error)
+
+// ValidateClusterTenants looks up the Avi tenants of the member clusters, and maps the clusters
+// whose tenants don't exist (or couldn't be looked up) back to the default tenant.
+func ValidateClusterTenants(lookup TenantLookup) {
+	clusterTenants.lock.Lock()
+	defer clusterTenants.lock.Unlock()
+	validated := make(map[string]bool)
+	for cname, tenant := range clusterTenants.tenants {
+		valid, ok := validated[tenant]
+		if !ok {
+			uuid, found, err := lookup(tenant)
+			if err != nil {
+				Errf("cluster: %s, tenant: %s, msg: couldn't look up the tenant, %s", cname, tenant, err)
+			} else if !found {
+				Errf("cluster: %s, tenant: %s, msg: tenant doesn't exist in the Avi controller", cname, tenant)
+			} else {
+				clusterTenants.uuids[uuid] = tenant
+			}
+			valid = err == nil && found
+			validated[tenant] = valid
+		}
+		if !valid {
+			Warnf("cluster: %s, tenant: %s, msg: will use the default tenant %s", cname, tenant, DefaultTenant)
+			delete(clusterTenants.tenants, cname)
+			continue
+		}
+		Logf("cluster: %s, tenant: %s, msg: validated the tenant of the cluster", cname, tenant)
+	}
+}
+
+// GetTenantFromRef returns the name of the Avi tenant of a tenant ref, the ref is either of the form
+// ".../api/tenant/<uuid>" or ".../api/tenant/<uuid>#<name>". The tenants which weren't validated
+// are reported as the default tenant.
+func GetTenantFromRef(tenantRef string) string {
+	segments := strings.SplitN(tenantRef, "#", 2)
+	if len(segments) == 2 && segments[1] != "" {
+		return segments[1]
+	}
+	uuid := segments[0][strings.LastIndex(segments[0], "/")+1:]
+	if uuid == DefaultTenant {
+		return DefaultTenant
+	}
+	clusterTenants.lock.RLock()
+	defer clusterTenants.lock.RUnlock()
+	if tenant, ok := clusterTenants.uuids[uuid]; ok {
+		return tenant
+	}
+	return DefaultTenant
+}
+
+// GetAviTenantRef returns the ref of an Avi tenant for the objects created in the tenant.
+func GetAviTenantRef(tenant string) string {
+	if tenant == DefaultTenant {
+		return GetAviAdminTenantRef()
+	}
+	return "https://" + os.Getenv("GSLB_CTRL_IPADDRESS") + "/api/tenant/?name=" + url.QueryEscape(tenant)
+}
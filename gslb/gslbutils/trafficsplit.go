@@ -0,0 +1,57 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"sync"
+
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+)
+
+// trafficSplitOverride is the traffic split set via the traffic split ConfigMap, it takes
+// precedence over the traffic split of the GDP object while it's set.
+var trafficSplitOverride = struct {
+	split []gdpv1alpha1.TrafficSplitElem
+	set   bool
+	lock  sync.RWMutex
+}{}
+
+// SetTrafficSplitOverride sets split as the traffic split to be used instead of the traffic split of
+// the GDP object. It applies to the filter built from the GDP object next.
+func SetTrafficSplitOverride(split []gdpv1alpha1.TrafficSplitElem) {
+	trafficSplitOverride.lock.Lock()
+	defer trafficSplitOverride.lock.Unlock()
+	trafficSplitOverride.split = append([]gdpv1alpha1.TrafficSplitElem{}, split...)
+	trafficSplitOverride.set = true
+}
+
+// ClearTrafficSplitOverride removes the traffic split override, the traffic split of the GDP object
+// applies to the filter built next.
+func ClearTrafficSplitOverride() {
+	trafficSplitOverride.lock.Lock()
+	defer trafficSplitOverride.lock.Unlock()
+	trafficSplitOverride.split = nil
+	trafficSplitOverride.set = false
+}
+
+// GetTrafficSplitOverride returns a copy of the traffic split override, and false if it isn't set.
+func GetTrafficSplitOverride() ([]gdpv1alpha1.TrafficSplitElem, bool) {
+	trafficSplitOverride.lock.RLock()
+	defer trafficSplitOverride.lock.RUnlock()
+	if !trafficSplitOverride.set {
+		return nil, false
+	}
+	return append([]gdpv1alpha1.TrafficSplitElem{}, trafficSplitOverride.split...), true
+}
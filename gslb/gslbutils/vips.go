@@ -0,0 +1,180 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// VIPWeightsAnnotation sets the relative weights (comma separated ip=weight pairs) of the VIPs
+	// of a service or an ingress exposing more than one VIP, e.g. "10.10.10.1=3,10.10.10.2=1", the
+	// VIPs not listed get DefaultVIPWeight
+	VIPWeightsAnnotation = "amko.vmware.com/vip-weights"
+	// DefaultVIPWeight is the weight of a VIP without a weight in VIPWeightsAnnotation, so that
+	// all the VIPs of an object get an equal share of its traffic by default
+	DefaultVIPWeight = 1
+	// MinVIPWeight and MaxVIPWeight bound the weight of a VIP
+	MinVIPWeight = 1
+	MaxVIPWeight = MaxRatio
+)
+
+// VIP is a virtual IP address exposed by an object along with its weight relative to the other
+// VIPs of the object. Each VIP of an object is a separate member of the GSLB service.
+type VIP struct {
+	IP     string
+	Weight int32
+}
+
+// ParseVIPWeights parses the value of VIPWeightsAnnotation into the weights of the VIPs, keyed by
+// their IP addresses. Each weight must be between MinVIPWeight and MaxVIPWeight.
+func ParseVIPWeights(val string) (map[string]int32, error) {
+	weights := make(map[string]int32)
+	for _, entry := range strings.Split(val, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kv := strings.SplitN(entry, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.New("vip weight " + entry + " must be of the form ip=weight")
+		}
+		ip := strings.TrimSpace(kv[0])
+		if net.ParseIP(ip) == nil {
+			return nil, errors.New("vip " + ip + " is not an IP address")
+		}
+		if _, ok := weights[ip]; ok {
+			return nil, errors.New("vip " + ip + " repeated")
+		}
+		weight, err := strconv.Atoi(strings.TrimSpace(kv[1]))
+		if err != nil {
+			return nil, errors.New("weight of vip " + ip + " is not a number")
+		}
+		if weight < MinVIPWeight || weight > MaxVIPWeight {
+			return nil, errors.New("weight " + strconv.Itoa(weight) + " of vip " + ip + " must be between " +
+				strconv.Itoa(MinVIPWeight) + " and " + strconv.Itoa(MaxVIPWeight))
+		}
+		weights[ip] = int32(weight)
+	}
+	return weights, nil
+}
+
+// GetVIPs returns the VIPs for the IP addresses ips of an object, in order and without duplicates,
+// weighted as per the VIPWeightsAnnotation in annotations. An invalid annotation is ignored, i.e.
+// all the VIPs get DefaultVIPWeight.
+func GetVIPs(ips []string, annotations map[string]string) []VIP {
+	var weights map[string]int32
+	if val, ok := annotations[VIPWeightsAnnotation]; ok {
+		var err error
+		if weights, err = ParseVIPWeights(val); err != nil {
+			Warnf("annotation: %s, value: %s, msg: invalid vip weights, all the vips get equal weights, %s",
+				VIPWeightsAnnotation, val, err.Error())
+			weights = nil
+		}
+	}
+	vips := make([]VIP, 0, len(ips))
+	for _, ip := range ips {
+		if ip == "" || isVIPPresent(ip, vips) {
+			continue
+		}
+		weight, ok := weights[ip]
+		if !ok {
+			weight = DefaultVIPWeight
+		}
+		vips = append(vips, VIP{IP: ip, Weight: weight})
+	}
+	return vips
+}
+
+func isVIPPresent(ip string, vips []VIP) bool {
+	for _, vip := range vips {
+		if vip.IP == ip {
+			return true
+		}
+	}
+	return false
+}
+
+// GetVIPsKey returns a string for the VIPs of an object, to be used in the checksums.
+func GetVIPsKey(vips []VIP) string {
+	entries := make([]string, 0, len(vips))
+	for _, vip := range vips {
+		entries = append(entries, vip.IP+"="+strconv.Itoa(int(vip.Weight)))
+	}
+	return strings.Join(entries, ",")
+}
+
+// GetVIPRatios splits the member weight memberWeight across vips in proportion to their weights,
+// so that the ratios of the GSLB members of the VIPs add up to memberWeight. The shares left over
+// after rounding down go to the VIPs with the largest remainders. If memberWeight is at least the
+// number of the VIPs, a VIP is never left out with a ratio of 0, it gets 1 from the VIP with the
+// highest ratio instead.
+func GetVIPRatios(memberWeight int32, vips []VIP) []int32 {
+	ratios := make([]int32, len(vips))
+	if memberWeight <= 0 || len(vips) == 0 {
+		return ratios
+	}
+	var totalWeight int64
+	for _, vip := range vips {
+		totalWeight += getVIPWeight(vip)
+	}
+	remainders := make([]int64, len(vips))
+	leftover := memberWeight
+	for idx, vip := range vips {
+		share := int64(memberWeight) * getVIPWeight(vip)
+		ratios[idx] = int32(share / totalWeight)
+		remainders[idx] = share % totalWeight
+		leftover -= ratios[idx]
+	}
+	order := make([]int, len(vips))
+	for idx := range order {
+		order[idx] = idx
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return remainders[order[i]] > remainders[order[j]]
+	})
+	for _, idx := range order[:leftover] {
+		ratios[idx]++
+	}
+
+	if int(memberWeight) < len(vips) {
+		return ratios
+	}
+	for idx := range ratios {
+		if ratios[idx] != 0 {
+			continue
+		}
+		highest := 0
+		for other := range ratios {
+			if ratios[other] > ratios[highest] {
+				highest = other
+			}
+		}
+		ratios[highest]--
+		ratios[idx]++
+	}
+	return ratios
+}
+
+func getVIPWeight(vip VIP) int64 {
+	if vip.Weight < MinVIPWeight {
+		return DefaultVIPWeight
+	}
+	return int64(vip.Weight)
+}
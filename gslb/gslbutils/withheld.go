@@ -0,0 +1,141 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package gslbutils
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// withheldKey identifies a GSLB service by its tenant and its name.
+type withheldKey struct {
+	tenant string
+	name   string
+}
+
+// withheldServices are the GSLB services withheld for having fewer healthy clusters than the min
+// healthy clusters of the GDP object, with their healthy cluster counts. These are surfaced in the
+// status of the GDP object as and when they change.
+var withheldServices = struct {
+	services map[withheldKey]int
+	lock     sync.Mutex
+}{services: make(map[withheldKey]int)}
+
+// SetWithheldService records the GSLB service gsName of tenant as withheld with healthy clusters.
+func SetWithheldService(tenant, gsName string, healthy int) {
+	key := withheldKey{tenant: tenant, name: gsName}
+	withheldServices.lock.Lock()
+	if prev, ok := withheldServices.services[key]; ok && prev == healthy {
+		withheldServices.lock.Unlock()
+		return
+	}
+	withheldServices.services[key] = healthy
+	withheldServices.lock.Unlock()
+	RefreshWithheldStatus()
+}
+
+// ClearWithheldService records the GSLB service gsName of tenant as not withheld, e.g. once it has
+// enough healthy clusters or once it is deleted.
+func ClearWithheldService(tenant, gsName string) {
+	key := withheldKey{tenant: tenant, name: gsName}
+	withheldServices.lock.Lock()
+	if _, ok := withheldServices.services[key]; !ok {
+		withheldServices.lock.Unlock()
+		return
+	}
+	delete(withheldServices.services, key)
+	withheldServices.lock.Unlock()
+	RefreshWithheldStatus()
+}
+
+// GetWithheldServices returns the withheld GSLB services, sorted by their tenants and names.
+func GetWithheldServices() []gdpv1alpha1.WithheldService {
+	withheldServices.lock.Lock()
+	services := make([]gdpv1alpha1.WithheldService, 0, len(withheldServices.services))
+	for key, healthy := range withheldServices.services {
+		services = append(services, gdpv1alpha1.WithheldService{Name: key.name, Tenant: key.tenant,
+			HealthyClusters: healthy})
+	}
+	withheldServices.lock.Unlock()
+	sort.Slice(services, func(i, j int) bool {
+		if services[i].Tenant != services[j].Tenant {
+			return services[i].Tenant < services[j].Tenant
+		}
+		return services[i].Name < services[j].Name
+	})
+	return services
+}
+
+// withheldStatusUpdater updates the status of the GDP object with the withheld GSLB services in
+// the background, so that the rest layer workers which record these don't wait on the API server.
+// pending holds at most one refresh, the refreshes requested while one is pending are coalesced.
+var withheldStatusUpdater = struct {
+	pending chan struct{}
+	once    sync.Once
+}{pending: make(chan struct{}, 1)}
+
+// RefreshWithheldStatus requests an update of the min healthy clusters and the withheld GSLB
+// services in the status of the accepted GDP object, if these changed. The update is done
+// asynchronously, and is retried if the GDP object changed in the meantime.
+func RefreshWithheldStatus() {
+	if !PublishGDPStatus {
+		return
+	}
+	withheldStatusUpdater.once.Do(func() {
+		go func() {
+			for range withheldStatusUpdater.pending {
+				updateWithheldStatus()
+			}
+		}()
+	})
+	select {
+	case withheldStatusUpdater.pending <- struct{}{}:
+	default:
+		// a refresh is already pending, it picks up this change as well
+	}
+}
+
+func updateWithheldStatus() {
+	name, ns := GetGDPObj()
+	if name == "" {
+		return
+	}
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		gdp, err := GlobalGslbClient.AmkoV1alpha1().GlobalDeploymentPolicies(ns).Get(name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		minHealthy := GetGlobalFilter().GetMinHealthyClusters()
+		services := GetWithheldServices()
+		if len(services) == 0 {
+			services = nil
+		}
+		if gdp.Status.MinHealthyClusters == minHealthy && reflect.DeepEqual(gdp.Status.WithheldServices, services) {
+			return nil
+		}
+		gdp.Status.MinHealthyClusters = minHealthy
+		gdp.Status.WithheldServices = services
+		_, err = GlobalGslbClient.AmkoV1alpha1().GlobalDeploymentPolicies(ns).Update(gdp)
+		return err
+	})
+	if err != nil {
+		Errf("ns: %s, gdp: %s, msg: error in updating the withheld GSLB services in the status: %s", ns, name, err)
+	}
+}
@@ -0,0 +1,162 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"sync"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gdpalphav1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/util/workqueue"
+)
+
+// EndpointsGetter returns the endpoints of the service name in namespace ns of a member cluster.
+type EndpointsGetter func(ns, name string) (*corev1.Endpoints, error)
+
+var clusterEndpoints = struct {
+	getters map[string]EndpointsGetter
+	lock    sync.RWMutex
+}{getters: make(map[string]EndpointsGetter)}
+
+// RegisterEndpointsGetter registers the getter for the endpoints of member cluster cname, the ready
+// backends of the clusters are counted via these getters in the backends weight mode.
+func RegisterEndpointsGetter(cname string, getter EndpointsGetter) {
+	clusterEndpoints.lock.Lock()
+	defer clusterEndpoints.lock.Unlock()
+	clusterEndpoints.getters[cname] = getter
+}
+
+// DeregisterEndpointsGetter removes the getter for the endpoints of member cluster cname.
+func DeregisterEndpointsGetter(cname string) {
+	clusterEndpoints.lock.Lock()
+	defer clusterEndpoints.lock.Unlock()
+	delete(clusterEndpoints.getters, cname)
+}
+
+func getEndpointsGetters() map[string]EndpointsGetter {
+	clusterEndpoints.lock.RLock()
+	defer clusterEndpoints.lock.RUnlock()
+	getters := make(map[string]EndpointsGetter, len(clusterEndpoints.getters))
+	for cname, getter := range clusterEndpoints.getters {
+		getters[cname] = getter
+	}
+	return getters
+}
+
+type backendService struct {
+	namespace string
+	name      string
+}
+
+// getBackendServices returns the services backing the accepted objects of cluster cname.
+func getBackendServices(cname string) []backendService {
+	var services []backendService
+	seen := make(map[backendService]bool)
+	for _, objType := range gslbutils.GetObjTypes() {
+		handlers, _ := gslbutils.GetObjTypeHandlers(objType)
+		store := handlers.AcceptedStore()
+		for _, multiClusterObjName := range store.GetAllClusterNSObjects() {
+			objCluster, ns, objName, err := splitName(objType, multiClusterObjName)
+			if err != nil || objCluster != cname {
+				continue
+			}
+			obj, ok := store.GetClusterNSObjectByName(cname, ns, objName)
+			if !ok {
+				continue
+			}
+			bso, ok := obj.(k8sobjects.BackendServicesObject)
+			if !ok {
+				continue
+			}
+			for _, name := range bso.GetBackendServices() {
+				svc := backendService{namespace: ns, name: name}
+				if !seen[svc] {
+					seen[svc] = true
+					services = append(services, svc)
+				}
+			}
+		}
+	}
+	return services
+}
+
+// countReadyBackends returns the number of ready addresses of the endpoints of the services backing
+// the accepted objects of cluster cname. A service without endpoints has no ready backends.
+// Returns false if the endpoints of any of the services couldn't be fetched.
+func countReadyBackends(cname string, getEndpoints EndpointsGetter) (int32, bool) {
+	var count int32
+	for _, svc := range getBackendServices(cname) {
+		ns, name := svc.namespace, svc.name
+		endpoints, err := getEndpoints(ns, name)
+		if k8serrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			gslbutils.Warnf("cluster: %s, ns: %s, svc: %s, msg: couldn't fetch the endpoints, %s", cname, ns, name,
+				err.Error())
+			return 0, false
+		}
+		for _, subset := range endpoints.Subsets {
+			count += int32(len(subset.Addresses))
+		}
+	}
+	return count, true
+}
+
+// RecomputeBackendWeights counts the ready backends of all the member clusters in the backends
+// weight mode, and writes the ratio update keys for the accepted objects if the counts changed.
+// The clusters whose backends couldn't be counted fall back to the traffic splits.
+func RecomputeBackendWeights(k8swq []workqueue.RateLimitingInterface, numWorkers uint32) {
+	gf := gslbutils.GetGlobalFilter()
+	if !gf.HasPolicy() || gf.GetWeightMode() != gdpalphav1.WeightModeBackends {
+		return
+	}
+	counts := make(map[string]int32)
+	for cname, getter := range getEndpointsGetters() {
+		if !gf.IsClusterAllowed(cname) {
+			continue
+		}
+		count, ok := countReadyBackends(cname, getter)
+		if !ok {
+			gslbutils.Warnf("cluster: %s, msg: couldn't count the ready backends, will use the traffic split", cname)
+			continue
+		}
+		counts[cname] = count
+	}
+	if !gf.SetBackendCounts(counts) {
+		return
+	}
+	gslbutils.Logf("backendCounts: %v, msg: ready backends of the clusters changed, will update the member ratios",
+		counts)
+	WriteRatioUpdatesToQueue(k8swq, numWorkers)
+}
+
+// RunBackendWeightsRecompute recomputes the weights of the clusters in the backends weight mode at
+// the recompute interval of the GDP object, till stopCh is closed.
+func RunBackendWeightsRecompute(k8swq []workqueue.RateLimitingInterface, numWorkers uint32, stopCh <-chan struct{}) {
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(gslbutils.GetGlobalFilter().GetWeightRecomputeInterval()):
+		}
+		RecomputeBackendWeights(k8swq, numWorkers)
+	}
+}
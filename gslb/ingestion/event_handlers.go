@@ -51,7 +51,7 @@ func AddLBSvcEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 			}
 			if !filter.ApplyFilter(svcMeta, c.name) {
 				AddOrUpdateLBSvcStore(rejectedLBSvcStore, svc, c.name)
-				gslbutils.Logf("cluster: %s, ns: %s, svc: %s, msg: %s\n", c.name,
+				gslbutils.Debugf("cluster: %s, ns: %s, svc: %s, msg: %s\n", c.name,
 					svc.ObjectMeta.Namespace, svc.ObjectMeta.Name, "rejected ADD svc key because it couldn't pass through filter")
 				return
 			}
@@ -70,6 +70,7 @@ func AddLBSvcEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 			}
 			DeleteFromLBSvcStore(acceptedLBSvcStore, svc, c.name)
 			DeleteFromLBSvcStore(rejectedLBSvcStore, svc, c.name)
+			gslbutils.DeleteRejectedObj(gslbutils.SvcType, c.name, svc.ObjectMeta.Namespace, svc.ObjectMeta.Name)
 
 			// For services, where the status field was deleted, won't contain the hostname in that case
 			hostName := ""
@@ -87,6 +88,13 @@ func AddLBSvcEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 			svc := curr.(*corev1.Service)
 			if oldSvc.ResourceVersion != svc.ResourceVersion {
 				svcMeta, ok := k8sobjects.GetSvcMeta(svc, c.name)
+				oldSvcMeta, oldOk := k8sobjects.GetSvcMeta(oldSvc, c.name)
+				if ok && oldOk && isSvcTypeLB(svc) && isSvcTypeLB(oldSvc) &&
+					svcMeta.GetSvcCksum() == oldSvcMeta.GetSvcCksum() {
+					gslbutils.Debugf("cluster: %s, ns: %s, svc: %s, msg: no changes in the service, ignoring update",
+						c.name, svc.ObjectMeta.Namespace, svc.ObjectMeta.Name)
+					return
+				}
 				if !ok || !isSvcTypeLB(svc) || !filter.ApplyFilter(svcMeta, c.name) {
 					// See if the svc was already accepted, if yes, need to delete the key
 					fetchedObj, ok := acceptedLBSvcStore.GetClusterNSObjectByName(c.name,
@@ -107,39 +115,59 @@ func AddLBSvcEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 						fetchedSvc.Name, gslbutils.ObjectDelete, fetchedSvc.Hostname, c.workqueue)
 					return
 				}
+				op := gslbutils.ObjectUpdate
+				// a service which was already accepted, and changed only its ports, only needs the port
+				// of its GSLB member and the health monitor updated
+				if _, accepted := acceptedLBSvcStore.GetClusterNSObjectByName(c.name, svc.ObjectMeta.Namespace,
+					svc.ObjectMeta.Name); accepted && oldOk && isSvcTypeLB(oldSvc) && svcMeta.IsPortOnlyChange(oldSvcMeta) {
+					op = gslbutils.ObjectPortUpdate
+				}
 				AddOrUpdateLBSvcStore(acceptedLBSvcStore, svc, c.name)
 				// If the svc was already part of rejected store, we need to remove
 				// this svc from the rejected store.
 				rejectedLBSvcStore.DeleteClusterNSObj(c.name, svc.ObjectMeta.Namespace, svc.ObjectMeta.Name)
 				// Add the key for this svc to the queue.
 				publishKeyToGraphLayer(numWorkers, gslbutils.SvcType, c.name, svc.ObjectMeta.Namespace,
-					svc.ObjectMeta.Name, gslbutils.ObjectUpdate, svcMeta.Hostname, c.workqueue)
+					svc.ObjectMeta.Name, op, svcMeta.Hostname, c.workqueue)
 			}
 		},
 	}
 	return svcEventHandler
 }
 
-func filterAndAddIngressMeta(ingressHostMetaObjs []k8sobjects.IngressHostMeta, c *GSLBMemberController,
-	acceptedIngStore, rejectedIngStore *gslbutils.ClusterStore, numWorkers uint32, fullsync bool) {
+// filterIngressMeta applies the filter to the ingress hosts of the cluster of c, and returns the
+// accepted and the rejected hosts. The hosts without an address or a hostname are skipped.
+func filterIngressMeta(ingressHostMetaObjs []k8sobjects.IngressHostMeta,
+	c *GSLBMemberController) ([]k8sobjects.IngressHostMeta, []k8sobjects.IngressHostMeta) {
+	var accepted, rejected []k8sobjects.IngressHostMeta
 	for _, ihm := range ingressHostMetaObjs {
-		if ihm.IPAddr == "" || ihm.Hostname == "" {
+		if !ihm.HasAddr() || ihm.Hostname == "" {
 			gslbutils.Debugf("cluster: %s, ns: %s, ingress: %s, msg: %s\n",
 				c.name, ihm.Namespace, ihm.IngName,
 				"rejected ADD ingress because IP address/Hostname not found in status field")
 			continue
 		}
 		if !filter.ApplyFilter(ihm, c.name) {
-			AddOrUpdateIngressStore(rejectedIngStore, ihm, c.name)
-			gslbutils.Logf("cluster: %s, ns: %s, ingress: %s, msg: %s, ing: %v\n", c.name, ihm.Namespace,
+			rejected = append(rejected, ihm)
+			gslbutils.Debugf("cluster: %s, ns: %s, ingress: %s, msg: %s, ing: %v\n", c.name, ihm.Namespace,
 				ihm.ObjName, "rejected ADD ingress key because it couldn't pass through the filter", ihm)
 			continue
 		}
+		accepted = append(accepted, ihm)
+	}
+	return accepted, rejected
+}
+
+func filterAndAddIngressMeta(ingressHostMetaObjs []k8sobjects.IngressHostMeta, c *GSLBMemberController,
+	acceptedIngStore, rejectedIngStore *gslbutils.ClusterStore, numWorkers uint32) {
+	accepted, rejected := filterIngressMeta(ingressHostMetaObjs, c)
+	for _, ihm := range rejected {
+		AddOrUpdateIngressStore(rejectedIngStore, ihm, c.name)
+	}
+	for _, ihm := range accepted {
 		AddOrUpdateIngressStore(acceptedIngStore, ihm, c.name)
-		if !fullsync {
-			publishKeyToGraphLayer(numWorkers, gslbutils.IngressType, c.name,
-				ihm.Namespace, ihm.ObjName, gslbutils.ObjectAdd, ihm.Hostname, c.workqueue)
-		}
+		publishKeyToGraphLayer(numWorkers, gslbutils.IngressType, c.name,
+			ihm.Namespace, ihm.ObjName, gslbutils.ObjectAdd, ihm.Hostname, c.workqueue)
 	}
 }
 
@@ -148,6 +176,7 @@ func deleteIngressMeta(ingressHostMetaObjs []k8sobjects.IngressHostMeta, c *GSLB
 	for _, ihm := range ingressHostMetaObjs {
 		present := DeleteFromIngressStore(acceptedIngStore, ihm, c.name)
 		DeleteFromIngressStore(rejectedIngStore, ihm, c.name)
+		gslbutils.DeleteRejectedObj(gslbutils.IngressType, c.name, ihm.Namespace, ihm.ObjName)
 
 		// Only if the ihm object was part of the accepted list previously, we will send a delete key
 		// otherwise we will assume that the object was already deleted
@@ -165,12 +194,13 @@ func filterAndUpdateIngressMeta(oldIngMetaObjs, newIngMetaObjs []k8sobjects.Ingr
 		// Check whether this exists in the new ingressHost list, if not, we need
 		// to delete this ingressHost object
 		newIhm, found := ihm.IngressHostInList(newIngMetaObjs)
-		if !found {
-			// ingressHost doesn't exist anymore, delete this ingressHost object
+		if !found || !newIhm.HasAddr() {
+			// ingressHost doesn't exist anymore or lost its address, delete this ingressHost object
 			_, isAccepted := acceptedIngStore.GetClusterNSObjectByName(c.name, ihm.Namespace,
 				ihm.ObjName)
 			DeleteFromIngressStore(acceptedIngStore, ihm, c.name)
 			DeleteFromIngressStore(rejectedIngStore, ihm, c.name)
+			gslbutils.DeleteRejectedObj(gslbutils.IngressType, c.name, ihm.Namespace, ihm.ObjName)
 			// If part of accepted store, only then publish the delete key
 			if isAccepted {
 				publishKeyToGraphLayer(numWorkers, gslbutils.IngressType, c.name,
@@ -229,7 +259,7 @@ func filterAndUpdateIngressMeta(oldIngMetaObjs, newIngMetaObjs []k8sobjects.Ingr
 		// only the new ones will be considered, because the old ones
 		// have been taken care of already
 		// Add this ingressHost object
-		if ihm.IPAddr == "" || ihm.Hostname == "" {
+		if !ihm.HasAddr() || ihm.Hostname == "" {
 			gslbutils.Logf("cluster: %s, ns: %s, ingress: %s, msg: %s",
 				c.name, ihm.Namespace, ihm.ObjName,
 				"rejected ADD ingress because IP address/Hostname not found in status field")
@@ -237,7 +267,7 @@ func filterAndUpdateIngressMeta(oldIngMetaObjs, newIngMetaObjs []k8sobjects.Ingr
 		}
 		if !filter.ApplyFilter(ihm, c.name) {
 			AddOrUpdateIngressStore(rejectedIngStore, ihm, c.name)
-			gslbutils.Logf("cluster: %s, ns: %s, ingress: %s, msg: %s\n", c.name, ihm.Namespace,
+			gslbutils.Debugf("cluster: %s, ns: %s, ingress: %s, msg: %s\n", c.name, ihm.Namespace,
 				ihm.ObjName, "rejected ADD ingress key because it couldn't pass through the filter")
 			continue
 		}
@@ -253,6 +283,9 @@ func AddIngressEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Re
 	rejectedIngStore := gslbutils.GetRejectedIngressStore()
 
 	gslbutils.Logf("Adding Ingress handler")
+	// scratch buffers for the update events, the informer calls the update handler from a single
+	// goroutine, so these are never used concurrently
+	var oldIngMetaBuf, newIngMetaBuf []k8sobjects.IngressHostMeta
 	ingressEventHandler := cache.ResourceEventHandlerFuncs{
 		AddFunc: func(obj interface{}) {
 			ingr, ok := utils.ToNetworkingIngress(obj)
@@ -260,10 +293,11 @@ func AddIngressEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Re
 				containerutils.AviLog.Errorf("Unable to convert obj type interface to networking/v1beta1 ingress")
 				return
 			}
+			k8sobjects.RejectEmptyHostRules(ingr, c.name)
 			// Don't add this ingr if there's no status field present or no IP is allocated in this
 			// status field
 			ingressHostMetaObjs := k8sobjects.GetIngressHostMeta(ingr, c.name)
-			filterAndAddIngressMeta(ingressHostMetaObjs, c, acceptedIngStore, rejectedIngStore, numWorkers, false)
+			filterAndAddIngressMeta(ingressHostMetaObjs, c, acceptedIngStore, rejectedIngStore, numWorkers)
 		},
 		DeleteFunc: func(obj interface{}) {
 			ingr, ok := utils.ToNetworkingIngress(obj)
@@ -283,8 +317,10 @@ func AddIngressEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Re
 				return
 			}
 			if oldIngr.ResourceVersion != ingr.ResourceVersion {
-				oldIngMetaObjs := k8sobjects.GetIngressHostMeta(oldIngr, c.name)
-				newIngMetaObjs := k8sobjects.GetIngressHostMeta(ingr, c.name)
+				k8sobjects.RejectEmptyHostRules(ingr, c.name)
+				oldIngMetaBuf = k8sobjects.GetIngressHostMetaInto(oldIngMetaBuf, oldIngr, c.name)
+				newIngMetaBuf = k8sobjects.GetIngressHostMetaInto(newIngMetaBuf, ingr, c.name)
+				oldIngMetaObjs, newIngMetaObjs := oldIngMetaBuf, newIngMetaBuf
 				filterAndUpdateIngressMeta(oldIngMetaObjs, newIngMetaObjs, c, acceptedIngStore, rejectedIngStore,
 					numWorkers)
 			}
@@ -310,7 +346,7 @@ func AddRouteEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 			routeMeta := k8sobjects.GetRouteMeta(route, c.name)
 			if !filter.ApplyFilter(routeMeta, c.name) {
 				AddOrUpdateRouteStore(rejectedRouteStore, route, c.name)
-				gslbutils.Logf("cluster: %s, ns: %s, route: %s, msg: %s\n", c.name,
+				gslbutils.Debugf("cluster: %s, ns: %s, route: %s, msg: %s\n", c.name,
 					route.ObjectMeta.Namespace, route.ObjectMeta.Name, "rejected ADD route key because it couldn't pass through filter")
 				return
 			}
@@ -327,6 +363,7 @@ func AddRouteEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 			// Delete from all route stores
 			present := DeleteFromRouteStore(acceptedRouteStore, route, c.name)
 			DeleteFromRouteStore(rejectedRouteStore, route, c.name)
+			gslbutils.DeleteRejectedObj(gslbutils.RouteType, c.name, route.ObjectMeta.Namespace, route.ObjectMeta.Name)
 			routeMeta := k8sobjects.GetRouteMeta(route, c.name)
 			if present {
 				publishKeyToGraphLayer(numWorkers, gslbutils.RouteType, c.name, route.ObjectMeta.Namespace,
@@ -338,6 +375,12 @@ func AddRouteEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 			route := curr.(*routev1.Route)
 			if oldRoute.ResourceVersion != route.ResourceVersion {
 				routeMeta := k8sobjects.GetRouteMeta(route, c.name)
+				oldRouteMeta := k8sobjects.GetRouteMeta(oldRoute, c.name)
+				if routeMeta.GetRouteCksum() == oldRouteMeta.GetRouteCksum() {
+					gslbutils.Debugf("cluster: %s, ns: %s, route: %s, msg: no changes in the route, ignoring update",
+						c.name, route.ObjectMeta.Namespace, route.ObjectMeta.Name)
+					return
+				}
 				if _, ok := gslbutils.RouteGetIPAddr(route); !ok || !filter.ApplyFilter(routeMeta, c.name) {
 					// See if the route was already accepted, if yes, need to delete the key
 					fetchedObj, ok := acceptedRouteStore.GetClusterNSObjectByName(c.name,
@@ -363,6 +406,10 @@ func AddRouteEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 					route.GetObjectMeta().GetName()); !ok {
 					op = gslbutils.ObjectAdd
 				}
+				// If the hostname of this route changed, the route has to be removed from the GS of the
+				// old hostname first.
+				publishDeleteForChangedHostname(numWorkers, gslbutils.RouteType, c.name, route.ObjectMeta.Namespace,
+					route.ObjectMeta.Name, routeMeta, c.workqueue)
 				AddOrUpdateRouteStore(acceptedRouteStore, route, c.name)
 				// If the route was already part of rejected store, we need to remove this
 				// route from the rejected store.
@@ -376,6 +423,22 @@ func AddRouteEventHandler(numWorkers uint32, c *GSLBMemberController) cache.Reso
 	return routeEventHandler
 }
 
+// publishDeleteForChangedHostname checks the hostname saved in the host map for an object against
+// the hostname of the new meta object. If they differ, a DELETE key is published for the object,
+// so that the object is removed from the GS of the old hostname. The DELETE key is published to
+// the bucket of the new hostname, so that it gets processed before the key for the new hostname.
+func publishDeleteForChangedHostname(numWorkers uint32, objType, cname, namespace, name string,
+	metaObj k8sobjects.MetaObject, wq []workqueue.RateLimitingInterface) {
+	oldHostname := metaObj.GetHostnameFromHostMap(gslbutils.GetClusterKey(cname, namespace, name))
+	if oldHostname == "" || oldHostname == metaObj.GetHostname() {
+		return
+	}
+	gslbutils.Logf("cluster: %s, ns: %s, objType: %s, objName: %s, oldHostname: %s, newHostname: %s, msg: hostname changed",
+		cname, namespace, objType, name, oldHostname, metaObj.GetHostname())
+	publishKeyToGraphLayer(numWorkers, objType, cname, namespace, name, gslbutils.ObjectDelete,
+		metaObj.GetHostname(), wq)
+}
+
 func publishKeyToGraphLayer(numWorkers uint32, objType, cname, namespace, name, op, hostname string, wq []workqueue.RateLimitingInterface) {
 	key := gslbutils.MultiClusterKey(op, objType, cname, namespace, name)
 	bkt := containerutils.Bkt(hostname, numWorkers)
@@ -399,7 +462,7 @@ func AddNamespaceEventHandler(numWorkers uint32, c *GSLBMemberController) cache.
 			nsMeta := k8sobjects.GetNSMeta(ns, c.name)
 			if !filter.ApplyFilter(nsMeta, c.name) {
 				AddOrUpdateNSStore(rejectedNSStore, ns, c.name)
-				gslbutils.Logf("cluster: %s, ns: %s, msg: %s\n", c.name, nsMeta.Name,
+				gslbutils.Debugf("cluster: %s, ns: %s, msg: %s\n", c.name, nsMeta.Name,
 					"ns didn't pass through the filter, adding to rejected list")
 				return
 			}
@@ -415,11 +478,15 @@ func AddNamespaceEventHandler(numWorkers uint32, c *GSLBMemberController) cache.
 			nsMeta := k8sobjects.GetNSMeta(ns, c.name)
 			if !nsMeta.DeleteFromFilter() {
 				gslbutils.Debugf("no namespace exists in the filter, nothing to change")
+			} else if gslbutils.GetGlobalFilter().IsNSFilterGlobal() {
+				// the namespaces with the same name in the other clusters are no longer selected
+				WriteChangedObjsToQueue(c.workqueue, numWorkers, false)
 			}
 			// ns deleted from the filter, delete all existing objects from all stores for this namespace
 			DeleteNamespacedObjsFromAllStores(c.workqueue, numWorkers, nsMeta)
 			DeleteFromNSStore(acceptedNSStore, ns, c.name)
 			DeleteFromNSStore(rejectedNSStore, ns, c.name)
+			gslbutils.DeleteRejectedObj(nsMeta.GetType(), c.name, "", ns.Name)
 		},
 		UpdateFunc: func(old, curr interface{}) {
 			oldNS, okOld := old.(*corev1.Namespace)
@@ -431,26 +498,15 @@ func AddNamespaceEventHandler(numWorkers uint32, c *GSLBMemberController) cache.
 			if oldNS.ResourceVersion != ns.ResourceVersion {
 				oldNSMeta := k8sobjects.GetNSMeta(oldNS, c.name)
 				newNSMeta := k8sobjects.GetNSMeta(ns, c.name)
+				// the namespace is moved to the accepted or the rejected store as per the filter
 				if !newNSMeta.UpdateFilter(oldNSMeta) {
 					// no changes, nothing to be dome
 					gslbutils.Debugf("ns didn't change, nothing to be done")
-					// change the namespace label if updated only in the rejection list, for all other
-					// cases, it will be updated
-					AddOrUpdateNSStore(rejectedNSStore, ns, c.name)
 					return
 				}
 				// filter changed, re-apply
 				gslbutils.Logf("namespace: %s, msg: namespace changed in filter, will re-apply", ns.Name)
 				WriteChangedObjsToQueue(c.workqueue, numWorkers, false)
-
-				// determine if the new namespace is accepted or rejected
-				if newNSMeta.ApplyFilter() {
-					MoveNSObjs([]string{c.name + "/" + ns.Name}, rejectedNSStore, acceptedNSStore)
-					AddOrUpdateNSStore(acceptedNSStore, ns, c.name)
-				} else {
-					MoveNSObjs([]string{c.name + "/" + ns.Name}, acceptedNSStore, rejectedNSStore)
-					AddOrUpdateNSStore(rejectedNSStore, ns, c.name)
-				}
 			}
 		},
 	}
@@ -74,16 +74,31 @@ func fetchAndApplyAllIngresses(c *GSLBMemberController, nsList *corev1.Namespace
 			}
 		}
 	}
+	// the ingress hosts are filtered as in the ADD handler, but added to the stores in batches, and
+	// the GS graphs are generated from the accepted store once all the objects are fetched
+	var acceptedItems, rejectedItems []gslbutils.StoreItem
 	for _, ing := range ingList {
-		ihms := k8sobjects.GetIngressHostMeta(ing, c.GetName())
-		filterAndAddIngressMeta(ihms, c, acceptedIngStore, rejectedIngStore, 0, true)
+		accepted, rejected := filterIngressMeta(k8sobjects.GetIngressHostMeta(ing, c.GetName()), c)
+		acceptedItems = appendIngressStoreItems(acceptedItems, accepted)
+		rejectedItems = appendIngressStoreItems(rejectedItems, rejected)
 	}
+	acceptedIngStore.AddOrUpdateBatch(c.name, acceptedItems)
+	rejectedIngStore.AddOrUpdateBatch(c.name, rejectedItems)
+}
+
+// appendIngressStoreItems appends the store items for the ingress hosts ihms to items.
+func appendIngressStoreItems(items []gslbutils.StoreItem, ihms []k8sobjects.IngressHostMeta) []gslbutils.StoreItem {
+	for _, ihm := range ihms {
+		items = append(items, gslbutils.StoreItem{Namespace: ihm.Namespace, ObjName: ihm.ObjName, Obj: ihm})
+	}
+	return items
 }
 
 func fetchAndApplyAllServices(c *GSLBMemberController, nsList *corev1.NamespaceList) {
 	acceptedLBSvcStore := gslbutils.GetAcceptedLBSvcStore()
 	rejectedLBSvcStore := gslbutils.GetRejectedLBSvcStore()
 
+	var acceptedItems, rejectedItems []gslbutils.StoreItem
 	for _, namespace := range nsList.Items {
 		svcList, err := c.informers.ClientSet.CoreV1().Services(namespace.Name).List(metav1.ListOptions{})
 		if err != nil {
@@ -101,21 +116,25 @@ func fetchAndApplyAllServices(c *GSLBMemberController, nsList *corev1.NamespaceL
 					c.GetName(), namespace.Name, svc.Name)
 				continue
 			}
+			item := gslbutils.StoreItem{Namespace: svc.Namespace, ObjName: svc.Name, Obj: svcMeta}
 			if !filter.ApplyFilter(svcMeta, c.GetName()) {
-				AddOrUpdateLBSvcStore(rejectedLBSvcStore, &svc, c.GetName())
+				rejectedItems = append(rejectedItems, item)
 				gslbutils.Logf("cluster: %s, ns: %s, svc: %s, msg: %s", c.GetName(), namespace.Name,
 					svc.Name, "rejected ADD svc key because it couldn't pass through the filter")
 				continue
 			}
-			AddOrUpdateLBSvcStore(acceptedLBSvcStore, &svc, c.GetName())
+			acceptedItems = append(acceptedItems, item)
 		}
 	}
+	acceptedLBSvcStore.AddOrUpdateBatch(c.GetName(), acceptedItems)
+	rejectedLBSvcStore.AddOrUpdateBatch(c.GetName(), rejectedItems)
 }
 
 func fetchAndApplyAllRoutes(c *GSLBMemberController, nsList *corev1.NamespaceList) {
 	acceptedRouteStore := gslbutils.GetAcceptedRouteStore()
 	rejectedRotueStore := gslbutils.GetRejectedRouteStore()
 
+	var acceptedItems, rejectedItems []gslbutils.StoreItem
 	for _, namespace := range nsList.Items {
 		routeList, err := c.informers.OshiftClient.RouteV1().Routes(namespace.Name).List(metav1.ListOptions{})
 		if err != nil {
@@ -130,39 +149,46 @@ func fetchAndApplyAllRoutes(c *GSLBMemberController, nsList *corev1.NamespaceLis
 					routeMeta.Name, "rejected ADD route because IP address/hostname not found in status field")
 				continue
 			}
+			item := gslbutils.StoreItem{Namespace: route.Namespace, ObjName: route.Name, Obj: routeMeta}
 			if !filter.ApplyFilter(routeMeta, c.name) {
-				AddOrUpdateRouteStore(rejectedRotueStore, &route, c.name)
+				rejectedItems = append(rejectedItems, item)
 				gslbutils.Logf("cluster: %s, ns: %s, route: %s, msg: %s, routeObj: %v", c.name, routeMeta.Namespace,
 					routeMeta.Name, "rejected ADD route key because it couldn't pass through the filter", routeMeta)
 				continue
 			}
-			AddOrUpdateRouteStore(acceptedRouteStore, &route, c.name)
+			acceptedItems = append(acceptedItems, item)
 		}
 	}
+	acceptedRouteStore.AddOrUpdateBatch(c.name, acceptedItems)
+	rejectedRotueStore.AddOrUpdateBatch(c.name, rejectedItems)
 }
 
 func checkGDPsAndInitialize() error {
-	gdpList, err := gslbutils.GlobalGslbClient.AmkoV1alpha1().GlobalDeploymentPolicies(gslbutils.AVISystem).List(metav1.ListOptions{})
-	if err != nil {
-		return nil
+	var gdpItems []gslbalphav1.GlobalDeploymentPolicy
+	for _, ns := range gslbutils.GetGDPNamespaces() {
+		gdpList, err := gslbutils.GlobalGslbClient.AmkoV1alpha1().GlobalDeploymentPolicies(ns).List(metav1.ListOptions{})
+		if err != nil {
+			return nil
+		}
+		gdpItems = append(gdpItems, gdpList.Items...)
 	}
 
 	// if no GDP objects, then simply return
-	if len(gdpList.Items) == 0 {
+	if len(gdpItems) == 0 {
 		return nil
 	}
 
 	// check if any of these GDP objects have "success" in their fields
 	var successGDP *gslbalphav1.GlobalDeploymentPolicy
 
-	for _, gdp := range gdpList.Items {
+	for _, gdp := range gdpItems {
 		if gdp.Status.ErrorStatus == GDPSuccess {
 			if successGDP == nil {
 				successGDP = &gdp
 			} else {
 				// there are more than two accepted GDPs, which pertains to an undefined state
-				gslbutils.Errf("ns: %s, msg: more than one GDP objects which were accepted, undefined state, can't do a full sync",
-					gslbutils.AVISystem)
+				gslbutils.Errf("ns: %v, msg: more than one GDP objects which were accepted, undefined state, can't do a full sync",
+					gslbutils.GetGDPNamespaces())
 				return errors.New("more than one GDP objects in accepted state")
 			}
 		}
@@ -173,11 +199,11 @@ func checkGDPsAndInitialize() error {
 	}
 
 	// no success GDPs, check if only one exists
-	if len(gdpList.Items) > 1 {
+	if len(gdpItems) > 1 {
 		return errors.New("more than one GDP objects")
 	}
 
-	AddGDPObj(&gdpList.Items[0], nil, 0)
+	AddGDPObj(&gdpItems[0], nil, 0)
 	return nil
 }
 
@@ -192,6 +218,11 @@ func bootupSync(ctrlList []*GSLBMemberController, gsCache *avicache.AviCache) {
 	}
 
 	gf := gslbutils.GetGlobalFilter()
+	if !gf.HasPolicy() {
+		// all objects will be rejected anyway, the objects will be evaluated once a GDP object is applied
+		gslbutils.Logf("no GDP object configured, skipping the sync of objects from the member clusters")
+		ctrlList = nil
+	}
 
 	acceptedNSStore := gslbutils.GetAcceptedNSStore()
 	rejectedNSStore := gslbutils.GetRejectedNSStore()
@@ -215,7 +246,7 @@ func bootupSync(ctrlList []*GSLBMemberController, gsCache *avicache.AviCache) {
 		}
 
 		for _, ns := range selectedNamespaces.Items {
-			_, err := gf.GetNSFilterLabel()
+			_, err := gf.GetNSFilterLabels()
 			if err == nil {
 				nsMeta := k8sobjects.GetNSMeta(&ns, c.GetName())
 				if !filter.ApplyFilter(nsMeta, c.GetName()) {
@@ -229,16 +260,19 @@ func bootupSync(ctrlList []*GSLBMemberController, gsCache *avicache.AviCache) {
 				gslbutils.Debugf("no namespace filter present, will sync the applications now")
 			}
 		}
-		if c.informers.IngressInformer != nil {
+		if c.informers.IngressInformer != nil && c.watchesObjectType(gslbutils.IngressType) {
 			fetchAndApplyAllIngresses(c, selectedNamespaces)
 		}
 
-		if c.informers.ServiceInformer != nil {
+		if c.informers.ServiceInformer != nil && c.watchesObjectType(gslbutils.SvcType) {
 			fetchAndApplyAllServices(c, selectedNamespaces)
 		}
-		if c.informers.RouteInformer != nil {
+		if c.informers.RouteInformer != nil && c.watchesObjectType(gslbutils.RouteType) {
 			fetchAndApplyAllRoutes(c, selectedNamespaces)
 		}
+		if c.gwInformers != nil && c.watchesObjectType(gslbutils.HTTPRouteType) {
+			fetchAndApplyAllHTTPRoutes(c)
+		}
 	}
 
 	// Generate models
@@ -248,26 +282,11 @@ func bootupSync(ctrlList []*GSLBMemberController, gsCache *avicache.AviCache) {
 
 func GenerateModels(gsCache *avicache.AviCache) {
 	gslbutils.Logf("will generate GS graphs from all accepted lists")
-	acceptedIngStore := gslbutils.GetAcceptedIngressStore()
-	acceptedLBSvcStore := gslbutils.GetAcceptedLBSvcStore()
-	acceptedRouteStore := gslbutils.GetAcceptedRouteStore()
-
-	ingList := acceptedIngStore.GetAllClusterNSObjects()
-	for _, ingName := range ingList {
-		nodes.DequeueIngestion(gslbutils.MultiClusterKeyWithObjName(gslbutils.ObjectAdd,
-			gslbutils.IngressType, ingName))
-	}
-
-	svcList := acceptedLBSvcStore.GetAllClusterNSObjects()
-	for _, svcName := range svcList {
-		nodes.DequeueIngestion(gslbutils.MultiClusterKeyWithObjName(gslbutils.ObjectAdd,
-			gslbutils.SvcType, svcName))
-	}
-
-	routeList := acceptedRouteStore.GetAllClusterNSObjects()
-	for _, routeName := range routeList {
-		nodes.DequeueIngestion(gslbutils.MultiClusterKeyWithObjName(gslbutils.ObjectAdd,
-			gslbutils.RouteType, routeName))
+	for _, objType := range gslbutils.GetObjTypes() {
+		handlers, _ := gslbutils.GetObjTypeHandlers(objType)
+		for _, objName := range handlers.AcceptedStore().GetAllClusterNSObjects() {
+			nodes.DequeueIngestion(gslbutils.MultiClusterKeyWithObjName(gslbutils.ObjectAdd, objType, objName))
+		}
 	}
 
 	gslbutils.Logf("keys for GS graphs published to layer 3")
@@ -279,7 +298,7 @@ func GenerateModels(gsCache *avicache.AviCache) {
 	agl := nodes.SharedAviGSGraphLister()
 	dgl := nodes.SharedDeleteGSGraphLister()
 	for _, gsKey := range gsKeys {
-		key := gsKey.Tenant + "/" + gsKey.Name
+		key := gslbutils.GetModelKey(gsKey.Tenant, gsKey.Name)
 		found, _ := agl.Get(key)
 		if found {
 			continue
@@ -313,7 +332,7 @@ func GenerateModels(gsCache *avicache.AviCache) {
 			gslbutils.Debugf("key: %v, msg: can't get gs name from hm", hmKey)
 			continue
 		}
-		gsKey := tenant + "/" + gsName
+		gsKey := gslbutils.GetModelKey(tenant, gsName)
 		found, _ := agl.Get(gsKey)
 		if found {
 			continue
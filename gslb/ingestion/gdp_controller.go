@@ -16,10 +16,12 @@ package ingestion
 
 import (
 	"errors"
+	"reflect"
 	"strconv"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 	"github.com/avinetworks/amko/gslb/k8sobjects"
+	"github.com/avinetworks/amko/gslb/nodes"
 
 	filter "github.com/avinetworks/amko/gslb/gdp_filter"
 
@@ -32,6 +34,7 @@ import (
 	"github.com/openshift/client-go/route/clientset/versioned/scheme"
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
@@ -98,30 +101,13 @@ func MoveNSObjs(nsObjs []string, fromStore *gslbutils.ObjectStore, toStore *gslb
 }
 
 // MoveObjs moves the objects in "objList" from "fromStore" to "toStore".
-// TODO: call this function via an interface, so we can remove dependency
-//       on objType.
 func MoveObjs(objList []string, fromStore *gslbutils.ClusterStore, toStore *gslbutils.ClusterStore, objType string) {
-	var cname, ns, objName string
-	var err error
 	for _, multiClusterObjName := range objList {
-		if objType == gslbutils.IngressType {
-			var hostName string
-			cname, ns, objName, hostName, err = gslbutils.SplitMultiClusterIngHostName(multiClusterObjName)
-			if err != nil {
-				gslbutils.Errf("objType: %s, object: %s, msg: processing error, %s", objType,
-					objName, err)
-				continue
-			}
-			objName += "/" + hostName
-		} else {
-			// for routes and services
-			// objName consists of cluster name, namespace and the route/service name
-			cname, ns, objName, err = gslbutils.SplitMultiClusterObjectName(multiClusterObjName)
-			if err != nil {
-				gslbutils.Errf("objType: %s, object: %s, msg: processing error, %s", objType,
-					objName, err)
-				continue
-			}
+		cname, ns, objName, err := splitName(objType, multiClusterObjName)
+		if err != nil {
+			gslbutils.Errf("objType: %s, object: %s, msg: processing error, %s", objType,
+				multiClusterObjName, err)
+			continue
 		}
 		obj, ok := fromStore.DeleteClusterNSObj(cname, ns, objName)
 		if ok {
@@ -131,10 +117,12 @@ func MoveObjs(objList []string, fromStore *gslbutils.ClusterStore, toStore *gslb
 	}
 }
 
+// splitName splits the multi-cluster name of an object into its cluster, namespace and name. The
+// name of an object of a host scoped object type includes its hostname.
 func splitName(objType, objName string) (string, string, string, error) {
 	var cname, ns, sname, hostname string
 	var err error
-	if objType == gdpalphav1.IngressObj {
+	if handlers, ok := gslbutils.GetObjTypeHandlers(objType); ok && handlers.HostScoped {
 		cname, ns, sname, hostname, err = gslbutils.SplitMultiClusterIngHostName(objName)
 		sname += "/" + hostname
 	} else {
@@ -144,27 +132,12 @@ func splitName(objType, objName string) (string, string, string, error) {
 }
 
 func GetObjTypeStores(objType string) (string, *gslbutils.ClusterStore, *gslbutils.ClusterStore, error) {
-	var objKey string
-	var acceptedObjStore *gslbutils.ClusterStore
-	var rejectedObjStore *gslbutils.ClusterStore
-
-	if objType == gdpalphav1.RouteObj {
-		acceptedObjStore = gslbutils.GetAcceptedRouteStore()
-		rejectedObjStore = gslbutils.GetRejectedRouteStore()
-		objKey = gslbutils.RouteType
-	} else if objType == gdpalphav1.LBSvcObj {
-		acceptedObjStore = gslbutils.GetAcceptedLBSvcStore()
-		rejectedObjStore = gslbutils.GetRejectedLBSvcStore()
-		objKey = gslbutils.SvcType
-	} else if objType == gdpalphav1.IngressObj {
-		acceptedObjStore = gslbutils.GetAcceptedIngressStore()
-		rejectedObjStore = gslbutils.GetRejectedIngressStore()
-		objKey = gslbutils.IngressType
-	} else {
+	handlers, ok := gslbutils.GetObjTypeHandlers(objType)
+	if !ok {
 		gslbutils.Errf("Unknown Object type: %s", objType)
 		return "", nil, nil, errors.New("unknown object type " + objType)
 	}
-	return objKey, acceptedObjStore, rejectedObjStore, nil
+	return handlers.ObjType, handlers.AcceptedStore(), handlers.RejectedStore(), nil
 }
 
 func writeChangedObjToQueue(objType string, k8swq []workqueue.RateLimitingInterface, numWorkers uint32, trafficWeightChanged bool) {
@@ -242,11 +215,42 @@ func writeChangedObjToQueue(objType string, k8swq []workqueue.RateLimitingInterf
 	}
 }
 
-func validObjectType(objType string) bool {
-	if objType == gdpalphav1.IngressObj || objType == gdpalphav1.LBSvcObj || objType == gdpalphav1.RouteObj {
-		return true
+// warnMissingPortNames warns about the port names of the GDP object which aren't present on any of
+// the selected services.
+func warnMissingPortNames(gdp *gdpalphav1.GlobalDeploymentPolicy) {
+	portNames := gdp.Spec.MatchRules.PortNames
+	if len(portNames) == 0 {
+		return
+	}
+	found := make(map[string]bool)
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	for _, objName := range acceptedSvcStore.GetAllClusterNSObjects() {
+		cname, ns, sname, err := splitName(gdpalphav1.LBSvcObj, objName)
+		if err != nil {
+			continue
+		}
+		obj, ok := acceptedSvcStore.GetClusterNSObjectByName(cname, ns, sname)
+		if !ok {
+			continue
+		}
+		svc, ok := obj.(k8sobjects.SvcMeta)
+		if !ok {
+			continue
+		}
+		for _, portName := range svc.GetPortNames() {
+			found[portName] = true
+		}
+	}
+	for _, portName := range portNames {
+		if !found[portName] {
+			gslbutils.Warnf("ns: %s, gdp: %s, portName: %s, msg: port name not present on any of the selected services",
+				gdp.Namespace, gdp.Name, portName)
+		}
 	}
-	return false
+}
+
+func validObjectType(objType string) bool {
+	return gslbutils.IsObjTypeRegistered(objType)
 }
 
 func validLabel(label map[string]string) error {
@@ -275,26 +279,168 @@ func GDPSanityChecks(gdp *gdpalphav1.GlobalDeploymentPolicy) error {
 			return errors.New(err.Error() + "for namespaceSelector")
 		}
 	}
+	switch mr.NamespaceSelector.Operator {
+	case "", gdpalphav1.LabelOperatorAnd, gdpalphav1.LabelOperatorOr:
+	default:
+		return errors.New("invalid operator " + mr.NamespaceSelector.Operator + " for namespaceSelector")
+	}
+	switch mr.SelectorMode {
+	case "", gdpalphav1.SelectorModeAnd, gdpalphav1.SelectorModeNamespaceOverride:
+	default:
+		return errors.New("invalid selector mode " + mr.SelectorMode)
+	}
+	switch mr.NamespaceSelector.Scope {
+	case "", gdpalphav1.NamespaceScopeCluster, gdpalphav1.NamespaceScopeGlobal:
+	default:
+		return errors.New("invalid scope " + mr.NamespaceSelector.Scope + " for namespaceSelector")
+	}
+	for _, portName := range mr.PortNames {
+		if portName == "" {
+			return errors.New("empty port name in portNames")
+		}
+	}
+	for _, objType := range mr.ObjectTypes {
+		switch objType {
+		case gdpalphav1.RouteObj, gdpalphav1.IngressObj, gdpalphav1.HTTPRouteObj, gdpalphav1.LBSvcObj:
+		default:
+			return errors.New("invalid object type " + objType + " in objectTypes")
+		}
+	}
+	for _, objType := range gdp.Spec.HostOnlyObjectTypes {
+		switch objType {
+		case gdpalphav1.RouteObj, gdpalphav1.IngressObj, gdpalphav1.HTTPRouteObj:
+		default:
+			return errors.New("invalid object type " + objType + " in hostOnlyObjectTypes")
+		}
+	}
 
-	// MatchClusters checks, empty matchClusters are allowed
+	// MatchClusters checks, empty matchClusters are allowed, the member clusters which aren't
+	// connected yet are allowed too, these are warned about in the GDP status
 	for _, cluster := range gdp.Spec.MatchClusters {
-		if !gslbutils.IsClusterContextPresent(cluster) {
+		if !isConfiguredCluster(cluster) {
 			return errors.New("cluster context " + cluster + " not present in GSLBConfig")
 		}
 	}
 
 	// TrafficSplit checks
-	for _, tp := range gdp.Spec.TrafficSplit {
-		if !gslbutils.IsClusterContextPresent(tp.Cluster) {
+	switch gdp.Spec.WeightMode {
+	case "", gdpalphav1.WeightModeWeight, gdpalphav1.WeightModePercentage, gdpalphav1.WeightModeBackends:
+	default:
+		return errors.New("invalid weight mode " + gdp.Spec.WeightMode)
+	}
+	if ri := gdp.Spec.WeightRecomputeInterval; ri != nil &&
+		(*ri < gslbutils.MinWeightRecomputeInterval || *ri > gslbutils.MaxWeightRecomputeInterval) {
+		return errors.New("weight recompute interval " + strconv.Itoa(*ri) + " must be between " +
+			strconv.Itoa(gslbutils.MinWeightRecomputeInterval) + " and " + strconv.Itoa(gslbutils.MaxWeightRecomputeInterval))
+	}
+	if err := validTrafficSplit(gdp.Spec.TrafficSplit, gdp.Spec.WeightMode); err != nil {
+		return err
+	}
+
+	if gp := gdp.Spec.MemberRemovalGracePeriod; gp != nil && (*gp < 0 || *gp > gslbutils.MaxMemberRemovalGracePeriod) {
+		return errors.New("member removal grace period " + strconv.Itoa(*gp) + " must be between 0 and " +
+			strconv.Itoa(gslbutils.MaxMemberRemovalGracePeriod))
+	}
+	if mh := gdp.Spec.MinHealthyClusters; mh != nil && (*mh < 0 || *mh > len(gdp.Spec.MatchClusters)) {
+		return errors.New("min healthy clusters " + strconv.Itoa(*mh) + " must be between 0 and " +
+			strconv.Itoa(len(gdp.Spec.MatchClusters)) + ", the number of matchClusters")
+	}
+	if minAge := gdp.Spec.MatchRules.MinAge; minAge != nil && (*minAge < 0 || *minAge > gslbutils.MaxMinObjectAge) {
+		return errors.New("min age " + strconv.Itoa(*minAge) + " must be between 0 and " +
+			strconv.Itoa(gslbutils.MaxMinObjectAge))
+	}
+
+	// TrafficRules checks, each rule must select the applications via a single label
+	for idx, tr := range gdp.Spec.TrafficRules {
+		if len(tr.AppSelector.Label) != 1 {
+			return errors.New("traffic rule " + strconv.Itoa(idx) + " must have exactly one label in appSelector")
+		}
+		if err := validLabel(tr.AppSelector.Label); err != nil {
+			return errors.New(err.Error() + " for traffic rule " + strconv.Itoa(idx))
+		}
+		if err := validTrafficSplit(tr.TrafficSplit, gdp.Spec.WeightMode); err != nil {
+			return errors.New(err.Error() + " for traffic rule " + strconv.Itoa(idx))
+		}
+	}
+	return nil
+}
+
+func validTrafficSplit(trafficSplit []gdpalphav1.TrafficSplitElem, weightMode string) error {
+	var total uint32
+	for _, tp := range trafficSplit {
+		if !isConfiguredCluster(tp.Cluster) {
 			return errors.New("cluster " + tp.Cluster + " in traffic policy not present in GSLBConfig")
 		}
-		if tp.Weight < 1 || tp.Weight > 20 {
+		if weightMode == gdpalphav1.WeightModePercentage {
+			if tp.Weight < 1 || tp.Weight > 100 {
+				return errors.New("traffic percentage " + strconv.Itoa(int(tp.Weight)) + " must be between 1 and 100")
+			}
+		} else if tp.Weight < 1 || tp.Weight > 20 {
 			return errors.New("traffic weight " + strconv.Itoa(int(tp.Weight)) + " must be between 1 and 20")
 		}
+		total += tp.Weight
+		if tp.Priority < 0 || tp.Priority > gslbutils.MaxPriority {
+			return errors.New("traffic priority " + strconv.Itoa(tp.Priority) + " must be between 0 and " +
+				strconv.Itoa(gslbutils.MaxPriority))
+		}
+	}
+	if weightMode == gdpalphav1.WeightModePercentage && len(trafficSplit) > 0 && total != 100 {
+		return errors.New("traffic percentages sum to " + strconv.Itoa(int(total)) + ", must sum to 100")
 	}
 	return nil
 }
 
+// isConfiguredCluster returns true if cluster is an initialized member cluster, or a member cluster
+// in the GSLBConfig object which isn't connected yet.
+func isConfiguredCluster(cluster string) bool {
+	return gslbutils.IsClusterContextPresent(cluster) || gslbutils.IsMemberCluster(cluster)
+}
+
+// getGDPWarnings returns a warning for each cluster in the matchClusters of gdp, which is configured
+// as a member cluster, but isn't connected, so no objects are received from the cluster.
+func getGDPWarnings(gdp *gdpalphav1.GlobalDeploymentPolicy) []string {
+	var warnings []string
+	for _, cluster := range gslbutils.GetUnconnectedClusters(gdp.Spec.MatchClusters) {
+		warnings = append(warnings, "cluster "+cluster+" is configured in matchClusters but not connected")
+	}
+	return warnings
+}
+
+// setGDPWarnings sets the warnings of an accepted gdp object in its status, and returns true if
+// the warnings changed.
+func setGDPWarnings(gdp *gdpalphav1.GlobalDeploymentPolicy) bool {
+	warnings := getGDPWarnings(gdp)
+	for _, warning := range warnings {
+		gslbutils.Warnf("ns: %s, gdp: %s, msg: %s", gdp.ObjectMeta.Namespace, gdp.ObjectMeta.Name, warning)
+	}
+	if reflect.DeepEqual(warnings, gdp.Status.Warnings) {
+		return false
+	}
+	gdp.Status.Warnings = warnings
+	return true
+}
+
+// RefreshGDPStatusWarnings re-evaluates the warnings of the accepted GDP object, and updates its
+// status if these changed, e.g. after a member cluster got connected.
+func RefreshGDPStatusWarnings() {
+	if !gslbutils.PublishGDPStatus {
+		return
+	}
+	name, ns := gslbutils.GetGDPObj()
+	if name == "" {
+		return
+	}
+	gdp, err := gslbutils.GlobalGslbClient.AmkoV1alpha1().GlobalDeploymentPolicies(ns).Get(name, metav1.GetOptions{})
+	if err != nil {
+		gslbutils.Errf("ns: %s, gdp: %s, msg: can't fetch the GDP object to refresh its warnings: %s", ns, name, err)
+		return
+	}
+	if !setGDPWarnings(gdp) {
+		return
+	}
+	updateGDPStatus(gdp, gdp.Status.ErrorStatus)
+}
+
 func updateGDPStatus(gdp *gdpalphav1.GlobalDeploymentPolicy, msg string) {
 	gdp.Status.ErrorStatus = msg
 
@@ -352,6 +498,7 @@ func deleteNamespacedObjsAndWriteToQueue(objType string, k8swq []workqueue.RateL
 				continue
 			}
 			acceptedObjStore.DeleteClusterNSObj(cname, ns, sname)
+			gslbutils.GetGlobalFilter().ReleaseClusterObj(cname, objType, ns, sname)
 			// publish the delete keys for these objects
 			bkt := utils.Bkt(ns, numWorkers)
 			key := gslbutils.MultiClusterKey(gslbutils.ObjectDelete, objKey, cluster, namespace, sname)
@@ -378,15 +525,112 @@ func deleteNamespacedObjsAndWriteToQueue(objType string, k8swq []workqueue.RateL
 }
 
 func DeleteNamespacedObjsFromAllStores(k8swq []workqueue.RateLimitingInterface, numWorkers uint32, nsMeta k8sobjects.NSMeta) {
-	deleteNamespacedObjsAndWriteToQueue(gdpalphav1.RouteObj, k8swq, numWorkers, nsMeta.Cluster, nsMeta.Name)
-	deleteNamespacedObjsAndWriteToQueue(gdpalphav1.LBSvcObj, k8swq, numWorkers, nsMeta.Cluster, nsMeta.Name)
-	deleteNamespacedObjsAndWriteToQueue(gdpalphav1.IngressObj, k8swq, numWorkers, nsMeta.Cluster, nsMeta.Name)
+	for _, objType := range gslbutils.GetObjTypes() {
+		deleteNamespacedObjsAndWriteToQueue(objType, k8swq, numWorkers, nsMeta.Cluster, nsMeta.Name)
+	}
+}
+
+// deleteAcceptedObjsAndWriteToQueue moves all the accepted objects of objType to the rejected store,
+// without passing them through the filter, and writes their DELETE keys. The rejection of each
+// object is notified with reason, like a decision of the filter. Returns the number of objects
+// deleted.
+func deleteAcceptedObjsAndWriteToQueue(objType string, k8swq []workqueue.RateLimitingInterface, numWorkers uint32,
+	reason string) int {
+	objKey, acceptedObjStore, rejectedObjStore, err := GetObjTypeStores(objType)
+	if err != nil {
+		gslbutils.Errf("objtype error: %s", err.Error())
+		return 0
+	}
+	if acceptedObjStore == nil {
+		return 0
+	}
+	objs := acceptedObjStore.GetAllClusterNSObjects()
+	if rejectedObjStore != nil {
+		MoveObjs(objs, acceptedObjStore, rejectedObjStore, objKey)
+	}
+	deleted := 0
+	for _, objName := range objs {
+		cname, ns, sname, err := splitName(objType, objName)
+		if err != nil {
+			gslbutils.Errf("objName: %s, msg: processing error, %s", objName, err)
+			continue
+		}
+		var obj interface{}
+		if rejectedObjStore == nil {
+			obj, _ = acceptedObjStore.DeleteClusterNSObj(cname, ns, sname)
+		} else {
+			obj, _ = rejectedObjStore.GetClusterNSObjectByName(cname, ns, sname)
+		}
+		if metaObj, ok := obj.(k8sobjects.MetaObject); ok {
+			gslbutils.GetGlobalFilter().ReleaseClusterObj(cname, objType, ns, sname)
+			k8sobjects.NotifyFilterDecision(metaObj, false, reason)
+		}
+		bkt := utils.Bkt(ns, numWorkers)
+		key := gslbutils.MultiClusterKey(gslbutils.ObjectDelete, objKey, cname, ns, sname)
+		k8swq[bkt].AddRateLimited(key)
+		gslbutils.Logf("cluster: %s, ns: %s, objType: %s, name: %s, key: %s, msg: added DELETE obj key",
+			cname, ns, objType, sname, key)
+		deleted++
+	}
+	return deleted
+}
+
+// DeleteAllAcceptedObjs writes the DELETE keys of all the accepted objects of all the types, so that
+// the GSLB members and services built from these are removed right away, e.g. when the GDP object is
+// deleted. The objects are moved to the rejected stores, to be selected again by a new GDP object,
+// and their rejections are notified with reason. Returns the number of objects deleted.
+func DeleteAllAcceptedObjs(k8swq []workqueue.RateLimitingInterface, numWorkers uint32, reason string) int {
+	deleted := 0
+	for _, objType := range gslbutils.GetObjTypes() {
+		deleted += deleteAcceptedObjsAndWriteToQueue(objType, k8swq, numWorkers, reason)
+	}
+	return deleted
 }
 
 func WriteChangedObjsToQueue(k8swq []workqueue.RateLimitingInterface, numWorkers uint32, trafficWeightChanged bool) {
-	writeChangedObjToQueue(gdpalphav1.RouteObj, k8swq, numWorkers, trafficWeightChanged)
-	writeChangedObjToQueue(gdpalphav1.LBSvcObj, k8swq, numWorkers, trafficWeightChanged)
-	writeChangedObjToQueue(gdpalphav1.IngressObj, k8swq, numWorkers, trafficWeightChanged)
+	for _, objType := range gslbutils.GetObjTypes() {
+		writeChangedObjToQueue(objType, k8swq, numWorkers, trafficWeightChanged)
+	}
+}
+
+// WriteRatioUpdatesToQueue writes a ratio update key for each of the accepted objects, for which
+// the nodes layer only updates the weights and the priorities of the existing GSLB members. The
+// objects are not passed through the filter again.
+func WriteRatioUpdatesToQueue(k8swq []workqueue.RateLimitingInterface, numWorkers uint32) {
+	for _, objType := range gslbutils.GetObjTypes() {
+		objKey, acceptedObjStore, _, err := GetObjTypeStores(objType)
+		if err != nil {
+			gslbutils.Errf("objtype error: %s", err.Error())
+			continue
+		}
+		if acceptedObjStore == nil {
+			continue
+		}
+		for _, objName := range acceptedObjStore.GetAllClusterNSObjects() {
+			cname, ns, sname, err := splitName(objType, objName)
+			if err != nil {
+				gslbutils.Errf("msg: couldn't split the key: %s, error, %s", objName, err)
+				continue
+			}
+			bkt := utils.Bkt(ns, numWorkers)
+			key := gslbutils.MultiClusterKey(gslbutils.ObjectRatioUpdate, objKey, cname, ns, sname)
+			k8swq[bkt].AddRateLimited(key)
+			gslbutils.Logf("cluster: %s, ns: %s, objtype: %s, name: %s, key: %s, msg: added ratio update key",
+				cname, ns, objType, sname, key)
+		}
+	}
+}
+
+// ResyncAllObjects passes all the namespaces and the objects in the stores through the filter
+// again, the accepted objects are written to the queue again, so that their GSLB members are
+// re-published.
+func ResyncAllObjects(k8swq []workqueue.RateLimitingInterface, numWorkers uint32) {
+	if !gslbutils.GetGlobalFilter().HasPolicy() {
+		gslbutils.Logf("msg: no GDP object configured, nothing to resync")
+		return
+	}
+	applyAndUpdateNamespaces()
+	WriteChangedObjsToQueue(k8swq, numWorkers, true)
 }
 
 func applyAndUpdateNamespaces() {
@@ -455,6 +699,17 @@ func applyAndAcceptNamespaces() {
 	gslbutils.Logf("objList: %v, msg: moved these namespaces from rejected to accepted store", acceptedList)
 }
 
+// isGDPNamespaceAllowed returns true if the GDP object is in one of the namespaces in which the GDP
+// objects are accepted, the operations on the other GDP objects are logged and ignored.
+func isGDPNamespaceAllowed(gdp *gdpalphav1.GlobalDeploymentPolicy, op string) bool {
+	if gslbutils.IsGDPNamespaceAllowed(gdp.ObjectMeta.Namespace) {
+		return true
+	}
+	gslbutils.Logf("ns: %s, gdp: %s, allowedNamespaces: %v, msg: GDP object outside the allowed namespaces, ignoring the %s",
+		gdp.ObjectMeta.Namespace, gdp.ObjectMeta.Name, gslbutils.GetGDPNamespaces(), op)
+	return false
+}
+
 // AddGDPObj creates a new GlobalFilter if not present on the first GDP object. Subsequent
 // adds for GDP objects must fail as only one GDP object is allowed globally.
 func AddGDPObj(obj interface{}, k8swq []workqueue.RateLimitingInterface, numWorkers uint32) {
@@ -465,7 +720,7 @@ func AddGDPObj(obj interface{}, k8swq []workqueue.RateLimitingInterface, numWork
 	}
 
 	// GDPs for all other namespaces are rejected
-	if gdp.ObjectMeta.Namespace != gslbutils.AVISystem {
+	if !isGDPNamespaceAllowed(gdp, "add") {
 		return
 	}
 
@@ -488,12 +743,28 @@ func AddGDPObj(obj interface{}, k8swq []workqueue.RateLimitingInterface, numWork
 		updateGDPStatus(gdp, err.Error())
 		return
 	}
+	// Only one GDP object can be accepted, claim it before creating the filter, so that out of
+	// two GDP objects being added at the same time, only one is accepted.
+	if !gslbutils.CompareAndSetGDPObj("", "", gdp.GetObjectMeta().GetName(), gdp.GetObjectMeta().GetNamespace()) {
+		name, ns := gslbutils.GetGDPObj()
+		if name == gdp.ObjectMeta.GetName() && ns == gdp.ObjectMeta.GetNamespace() {
+			return
+		}
+		msg := "a GDP object already exists, can't add another"
+		gslbutils.Errf(msg)
+		updateGDPStatus(gdp, msg)
+		return
+	}
+	// keep the whole claimed GDP object in the store
+	gslbutils.GetDefaultGDPStore().AddOrUpdate(gdp)
+	setGDPWarnings(gdp)
 	updateGDPStatus(gdp, GDPSuccess)
 
 	gslbutils.Logf("ns: %s, gdp: %s, msg: %s", gdp.ObjectMeta.Namespace, gdp.ObjectMeta.Name,
 		"GDP object added")
 
 	gslbutils.Logf("creating a new filter")
+	checkTrafficSplitOverride(gdp)
 	gf.AddToFilter(gdp)
 	// First apply the filter on the namespaces
 	applyAndAcceptNamespaces()
@@ -501,8 +772,8 @@ func AddGDPObj(obj interface{}, k8swq []workqueue.RateLimitingInterface, numWork
 	// care of by the bootupSync function
 	if k8swq != nil {
 		WriteChangedObjsToQueue(k8swq, numWorkers, false)
+		warnMissingPortNames(gdp)
 	}
-	gslbutils.SetGDPObj(gdp.GetObjectMeta().GetName(), gdp.GetObjectMeta().GetNamespace())
 }
 
 // UpdateGDPObj updates the global and the namespace filters if a the GDP object
@@ -516,6 +787,9 @@ func UpdateGDPObj(old, new interface{}, k8swq []workqueue.RateLimitingInterface,
 	if oldGdp.ObjectMeta.ResourceVersion == newGdp.ObjectMeta.ResourceVersion {
 		return
 	}
+	if !isGDPNamespaceAllowed(newGdp, "update") {
+		return
+	}
 
 	// update only the accepted GDP
 	if name, ns := gslbutils.GetGDPObj(); name != newGdp.GetObjectMeta().GetName() && ns != newGdp.GetObjectMeta().GetNamespace() {
@@ -529,6 +803,8 @@ func UpdateGDPObj(old, new interface{}, k8swq []workqueue.RateLimitingInterface,
 		updateGDPStatus(newGdp, err.Error())
 		return
 	}
+	gslbutils.GetDefaultGDPStore().Update(newGdp)
+	setGDPWarnings(newGdp)
 	updateGDPStatus(newGdp, "success")
 
 	gf := gslbutils.GetGlobalFilter()
@@ -537,20 +813,48 @@ func UpdateGDPObj(old, new interface{}, k8swq []workqueue.RateLimitingInterface,
 		gslbutils.Errf("object: GlobalFilter, msg: global filter not initialized, can't update")
 		return
 	}
-	if gdpChanged, trafficWeightChanged := gf.UpdateGlobalFilter(oldGdp, newGdp); gdpChanged {
-		gslbutils.Logf("GDP object changed, will go through the objects again")
-		// first apply and update the namespaces in the filter
-		applyAndUpdateNamespaces()
-		WriteChangedObjsToQueue(k8swq, numWorkers, trafficWeightChanged)
+	checkTrafficSplitOverride(newGdp)
+	oldMinHealthy := gf.GetMinHealthyClusters()
+	changes := gf.UpdateFilter(oldGdp, newGdp)
+	if changes == 0 {
+		return
+	}
+	if oldMinHealthy != gf.GetMinHealthyClusters() {
+		// the GS graphs stay the same, but the withheld GSs have to be evaluated again
+		gslbutils.Logf("old: %d, new: %d, msg: min healthy clusters changed, will publish all GS graphs",
+			oldMinHealthy, gf.GetMinHealthyClusters())
+		gslbutils.RefreshWithheldStatus()
+		nodes.PublishAllGraphKeys()
+	}
+	if !changes.NeedsReevaluation() {
+		if changes.Has(gslbutils.FilterChangeTraffic) {
+			// the selected objects stay the same, so only the member ratios need an update
+			gslbutils.Logf("GDP object changed only for the traffic weights, will update the member ratios")
+			WriteRatioUpdatesToQueue(k8swq, numWorkers)
+			return
+		}
+		// the settings are read as and when needed, so nothing has to be re-evaluated
+		gslbutils.Logf("changed: %s, msg: GDP object changed only for the settings, no objects to re-evaluate",
+			changes.String())
+		return
 	}
+	gslbutils.Logf("changed: %s, msg: GDP object changed, will go through the objects again", changes.String())
+	// first apply and update the namespaces in the filter
+	applyAndUpdateNamespaces()
+	// the ports of the accepted services may change with the port names, so the accepted objects
+	// have to be sent again
+	WriteChangedObjsToQueue(k8swq, numWorkers, changes.Has(gslbutils.FilterChangeTraffic|gslbutils.FilterChangeMatchOptions))
+	warnMissingPortNames(newGdp)
 }
 
 // DeleteGDPObj requires to delete the filters that were previously created. If a GDP
-// object is deleted, the previously accepted and rejected objects need to pass through
-// this filter again to find out which filter is applicable, the global one or the
-// local one.
+// object is deleted, none of the objects are selected anymore, so the DELETE keys of all the
+// previously accepted objects are written to the queue, to remove their GSLB services.
 func DeleteGDPObj(obj interface{}, k8swq []workqueue.RateLimitingInterface, numWorkers uint32) {
 	gdp := obj.(*gdpalphav1.GlobalDeploymentPolicy)
+	if !isGDPNamespaceAllowed(gdp, "delete") {
+		return
+	}
 	gslbutils.Logf("ns: %s, gdp: %s, msg: %s", gdp.ObjectMeta.Namespace, gdp.ObjectMeta.Name,
 		"deleted GDP object")
 
@@ -566,9 +870,13 @@ func DeleteGDPObj(obj interface{}, k8swq []workqueue.RateLimitingInterface, numW
 	}
 	applyAndRejectNamespaces(gf, gdp)
 	gf.DeleteFromGlobalFilter(gdp)
-	// remove all namespaces from filter and re-apply
+	// remove all namespaces from filter
 	k8sobjects.RemoveAllSelectedNamespaces()
-	WriteChangedObjsToQueue(k8swq, numWorkers, false)
+	// no objects are selected without a GDP object, so all the accepted objects are deleted without
+	// passing them through the filter again, which tears down their GSLB services
+	deleted := DeleteAllAcceptedObjs(k8swq, numWorkers, gslbutils.NoPolicyReason)
+	gslbutils.Logf("ns: %s, gdp: %s, objects: %d, msg: deleted all the accepted objects", gdp.ObjectMeta.Namespace,
+		gdp.ObjectMeta.Name, deleted)
 
 	gslbutils.SetGDPObj("", "")
 }
@@ -600,6 +908,9 @@ func InitializeGDPController(kubeclientset kubernetes.Interface,
 		// workqueue:     workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "gdps"),
 		//recorder:      recorder,
 	}
+	gslbutils.SetForceResyncFunc(func() {
+		ResyncAllObjects(k8sWorkqueue, numWorkers)
+	})
 	gslbutils.Logf("object: GDPController, msg: %s", "setting up event handlers")
 	// Event handlers for GDP change
 	gdpInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
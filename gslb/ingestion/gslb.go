@@ -18,8 +18,10 @@ import (
 	"errors"
 	"flag"
 	"os"
+	"reflect"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -78,6 +80,7 @@ type kubeClusterDetails struct {
 	kubeconfig  string
 	kubeapi     string
 	informers   *utils.Informers
+	region      string
 }
 
 type K8SInformers struct {
@@ -120,6 +123,8 @@ type GSLBConfigController struct {
 func (gslbController *GSLBConfigController) Cleanup() {
 	gslbutils.Logf("object: GSLBConfigController, msg: %s", "cleaning up the entire GSLB configuration")
 
+	// stop the informers of the member clusters, the next GSLB config object starts its own
+	StopAll()
 	// unset GSLBConfig and be prepared to take in the next GSLB config object
 	gslbutils.SetGSLBConfig(false)
 }
@@ -161,6 +166,58 @@ func getGSLBConfigChecksum(gc *gslbalphav1.GSLBConfig) uint32 {
 	return cksum
 }
 
+// GetRenamedMemberClusters returns the member clusters of oldGc whose contexts are renamed in newGc,
+// keyed by their previous contexts. A member cluster is renamed if only its context changed at the
+// same position in the member clusters, and neither of the contexts is a member in the other object.
+func GetRenamedMemberClusters(oldGc, newGc *gslbalphav1.GSLBConfig) map[string]string {
+	renamed := make(map[string]string)
+	if len(oldGc.Spec.MemberClusters) != len(newGc.Spec.MemberClusters) {
+		return renamed
+	}
+	oldContexts, newContexts := []string{}, []string{}
+	for idx := range oldGc.Spec.MemberClusters {
+		oldContexts = append(oldContexts, oldGc.Spec.MemberClusters[idx].ClusterContext)
+		newContexts = append(newContexts, newGc.Spec.MemberClusters[idx].ClusterContext)
+	}
+	for idx, oldMember := range oldGc.Spec.MemberClusters {
+		newMember := newGc.Spec.MemberClusters[idx]
+		if oldMember.ClusterContext == newMember.ClusterContext || gslbutils.PresentInList(oldMember.ClusterContext, newContexts) ||
+			gslbutils.PresentInList(newMember.ClusterContext, oldContexts) {
+			continue
+		}
+		oldMember.ClusterContext = newMember.ClusterContext
+		if !reflect.DeepEqual(oldMember, newMember) {
+			continue
+		}
+		renamed[oldContexts[idx]] = newMember.ClusterContext
+	}
+	return renamed
+}
+
+// renameMemberClusters renames the member clusters of oldGc which are renamed in newGc, see
+// gslbutils.RenameCluster. Returns a copy of oldGc with the contexts of the renamed clusters
+// updated, so that the other changes can be checked for.
+func renameMemberClusters(oldGc, newGc *gslbalphav1.GSLBConfig) *gslbalphav1.GSLBConfig {
+	renamed := GetRenamedMemberClusters(oldGc, newGc)
+	if len(renamed) == 0 {
+		return oldGc
+	}
+	gc := oldGc.DeepCopy()
+	for idx, member := range gc.Spec.MemberClusters {
+		newName, ok := renamed[member.ClusterContext]
+		if !ok {
+			continue
+		}
+		if err := gslbutils.RenameCluster(member.ClusterContext, newName); err != nil {
+			gslbutils.Errf("cluster: %s, newName: %s, msg: couldn't rename the member cluster, %s",
+				member.ClusterContext, newName, err.Error())
+			continue
+		}
+		gc.Spec.MemberClusters[idx].ClusterContext = newName
+	}
+	return gc
+}
+
 // GetNewController builds the GSLB Controller which has an informer for GSLB Config object
 func GetNewController(kubeclientset kubernetes.Interface, gslbclientset gslbcs.Interface,
 	gslbInformerFactory gslbinformers.SharedInformerFactory,
@@ -213,14 +270,24 @@ func GetNewController(kubeclientset kubernetes.Interface, gslbclientset gslbcs.I
 
 			if oldGc.Spec.LogLevel != newGc.Spec.LogLevel {
 				gslbutils.Logf("log level changed")
-				if gslbutils.IsLogLevelValid(newGc.Spec.LogLevel) {
-					utils.AviLog.SetLevel(newGc.Spec.LogLevel)
-					gslbutils.Logf("setting the new log level as %s", newGc.Spec.LogLevel)
+				if err := gslbutils.SetLogLevel(newGc.Spec.LogLevel); err != nil {
+					gslbutils.Errf("msg: %s", err.Error())
 				} else {
-					gslbutils.Errf("log level %s unrecognized", newGc.Spec.LogLevel)
+					gslbutils.Logf("setting the new log level as %s", newGc.Spec.LogLevel)
 				}
 			}
 
+			if changed, err := gslbutils.SetGslbDomains(newGc.Spec.GSLBDomains); err != nil {
+				gslbutils.Errf("msg: %s", err.Error())
+			} else if changed {
+				gslbutils.Logf("gslbDomains: %v, msg: GSLB domains changed, will go through the objects again",
+					newGc.Spec.GSLBDomains)
+				k8sQueue := utils.SharedWorkQueue().GetQueueByName(utils.ObjectIngestionLayer)
+				WriteChangedObjsToQueue(k8sQueue.Workqueue, k8sQueue.NumWorkers, false)
+			}
+
+			// the renamed member clusters are migrated without a reboot
+			oldGc = renameMemberClusters(oldGc, newGc)
 			if getGSLBConfigChecksum(oldGc) == getGSLBConfigChecksum(newGc) {
 				return
 			}
@@ -414,17 +481,23 @@ func CacheRefreshRoutine() {
 // GenerateKubeConfig reads the kubeconfig given through the environment variable
 // decodes it and then writes to a temporary file.
 func GenerateKubeConfig() error {
-	membersKubeConfig = os.Getenv("GSLB_CONFIG")
-	if membersKubeConfig == "" {
+	kubeconfig := os.Getenv("GSLB_CONFIG")
+	if kubeconfig == "" {
 		utils.AviLog.Fatal("GSLB_CONFIG environment variable not set, exiting...")
 		return errors.New("GSLB_CONFIG environment variable not set, exiting")
 	}
+	return setMembersKubeConfig(kubeconfig)
+}
+
+// writeKubeConfig writes kubeconfig of the member clusters to the GSLB kubeconfig path.
+func writeKubeConfig(kubeconfig string) error {
 	f, err := os.Create(gslbutils.GSLBKubePath)
 	if err != nil {
 		return errors.New("Error in creating file: " + err.Error())
 	}
+	defer f.Close()
 
-	_, err = f.WriteString(membersKubeConfig)
+	_, err = f.WriteString(kubeconfig)
 	if err != nil {
 		return errors.New("Error in writing to config file: " + err.Error())
 	}
@@ -495,7 +568,12 @@ func AddGSLBConfigObject(obj interface{}) {
 		gslbutils.UpdateGSLBConfigStatus(InvalidConfigMsg + err.Error())
 		return
 	}
-	utils.AviLog.SetLevel(gc.Spec.LogLevel)
+	if err := gslbutils.SetLogLevel(gc.Spec.LogLevel); err != nil {
+		gslbutils.Warnf("ns: %s, gslbConfig: %s, msg: %s", gc.ObjectMeta.Namespace, gc.ObjectMeta.Name, err.Error())
+	}
+	if _, err := gslbutils.SetGslbDomains(gc.Spec.GSLBDomains); err != nil {
+		gslbutils.Warnf("ns: %s, gslbConfig: %s, msg: %s", gc.ObjectMeta.Namespace, gc.ObjectMeta.Name, err.Error())
+	}
 
 	gslbutils.Debugf("ns: %s, gslbConfig: %s, msg: %s", gc.ObjectMeta.Namespace, gc.ObjectMeta.Name,
 		"got an add event")
@@ -541,7 +619,22 @@ func AddGSLBConfigObject(obj interface{}) {
 		return
 	}
 
-	aviCtrlList, err := InitializeGSLBClusters(gslbutils.GSLBKubePath, gc.Spec.MemberClusters)
+	memberClusterNames := make([]string, 0, len(gc.Spec.MemberClusters))
+	for _, memberCluster := range gc.Spec.MemberClusters {
+		gslbutils.SetClusterTenant(memberCluster.ClusterContext, memberCluster.Tenant)
+		if err := gslbutils.SetClusterObjectTypes(memberCluster.ClusterContext, memberCluster.ObjectTypes); err != nil {
+			gslbutils.Warnf("cluster: %s, msg: %s, will watch the object types served by the cluster",
+				memberCluster.ClusterContext, err.Error())
+		}
+		memberClusterNames = append(memberClusterNames, memberCluster.ClusterContext)
+	}
+	gslbutils.SetMemberClusters(memberClusterNames)
+	avicache.ValidateClusterTenants()
+	if err := SetMemberCredentials(getMembersKubeConfig(), memberClusterNames); err != nil {
+		gslbutils.Warnf("msg: couldn't read the credentials of the member clusters, %s", err.Error())
+	}
+
+	aviCtrlList, unreachableClusters, err := InitializeGSLBClusters(gslbutils.GSLBKubePath, gc.Spec.MemberClusters)
 	if err != nil {
 		gslbutils.Errf("couldn't initialize the kubernetes/openshift clusters: %s, returning", err.Error())
 		gslbutils.UpdateGSLBConfigStatus(ClusterHealthCheckErr + err.Error())
@@ -568,6 +661,13 @@ func AddGSLBConfigObject(obj interface{}) {
 	resyncNodesWorker.SyncFunction = ResyncNodesToRestLayer
 	go resyncNodesWorker.Run()
 
+	// Initialize a periodic worker reclaiming the stale objects
+	if interval := nodes.GetStaleObjectsGCInterval(); interval > 0 {
+		staleObjectsGCWorker := gslbutils.NewFullSyncThread(time.Duration(interval))
+		staleObjectsGCWorker.SyncFunction = func() { nodes.SweepStaleObjects() }
+		go staleObjectsGCWorker.Run()
+	}
+
 	gcChan := gslbutils.GetGSLBConfigObjectChan()
 	*gcChan <- true
 
@@ -575,6 +675,10 @@ func AddGSLBConfigObject(obj interface{}) {
 	for _, aviCtrl := range aviCtrlList {
 		aviCtrl.Start(stopCh)
 	}
+	// the unreachable clusters join once they are reachable
+	retryGSLBClusters(gslbutils.GSLBKubePath, unreachableClusters, stopCh)
+	// the member clusters are reloaded as and when their credentials change
+	StartMembersSecretInformer(gslbutils.GlobalKubeClient, stopCh)
 
 	// GSLB Configuration successfully done
 	gslbutils.SetGSLBConfig(true)
@@ -638,15 +742,197 @@ func Initialize() {
 	// status of the GDP object. Always check this flag before updating the status.
 	gslbutils.PublishGDPStatus = true
 	gslbutils.PublishGSLBStatus = true
+	gslbutils.PublishHostOverrideStatus = true
 
 	SetInformerListTimeout(120)
 
+	if policy := os.Getenv("DEFAULT_WEIGHT_POLICY"); policy != "" {
+		if err := gslbutils.GetGlobalFilter().SetDefaultWeightPolicy(policy); err != nil {
+			gslbutils.Warnf("object: main, msg: %s, will use the default policy %s", err.Error(),
+				gslbutils.GetGlobalFilter().GetDefaultWeightPolicy())
+		}
+	}
+
+	if val := os.Getenv("CLUSTER_OBJECT_LIMIT"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err == nil {
+			err = gslbutils.GetGlobalFilter().SetClusterObjLimit(limit)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: CLUSTER_OBJECT_LIMIT, value: %s, msg: invalid cluster object limit, no limit will be set",
+				val)
+		}
+	}
+
+	if val := os.Getenv("PATHS_PER_HOST_LIMIT"); val != "" {
+		limit, err := strconv.Atoi(val)
+		if err == nil {
+			err = gslbutils.SetPathsPerHostLimit(limit)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: PATHS_PER_HOST_LIMIT, value: %s, msg: invalid paths per host limit, no limit will be set",
+				val)
+		}
+	}
+
+	if val := os.Getenv("DENY_LABEL"); val != "" {
+		if err := gslbutils.SetDenyLabel(val); err != nil {
+			gslbutils.Warnf("env: DENY_LABEL, value: %s, msg: invalid deny label, no deny label will be set, %s", val,
+				err.Error())
+		}
+	}
+
+	if val := os.Getenv("FILTER_EVENT_INTERVAL"); val != "" {
+		seconds, err := strconv.Atoi(val)
+		if err == nil {
+			err = gslbutils.SetFilterEventInterval(time.Duration(seconds) * time.Second)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: FILTER_EVENT_INTERVAL, value: %s, msg: invalid interval, will use %s", val,
+				gslbutils.DefaultFilterEventInterval)
+		}
+	}
+
+	if val := os.Getenv("REJECT_NON_ROUTABLE_IPS"); val != "" {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			gslbutils.Warnf("env: REJECT_NON_ROUTABLE_IPS, value: %s, msg: invalid value, non-routable IPs won't be rejected",
+				val)
+		}
+		var cidrs []string
+		if cidrVal := os.Getenv("NON_ROUTABLE_CIDRS"); cidrVal != "" {
+			cidrs = strings.Split(cidrVal, ",")
+		}
+		if err := gslbutils.SetNonRoutableIPFilter(enabled, cidrs); err != nil {
+			gslbutils.Warnf("env: NON_ROUTABLE_CIDRS, msg: %s, will use the default CIDRs %v", err.Error(),
+				gslbutils.DefaultNonRoutableCIDRs)
+			gslbutils.SetNonRoutableIPFilter(enabled, nil)
+		}
+	}
+
+	if val := os.Getenv("GSLB_NAME_COLLISION_STRATEGY"); val != "" {
+		if err := gslbutils.SetGslbNameCollisionStrategy(val); err != nil {
+			gslbutils.Warnf("env: GSLB_NAME_COLLISION_STRATEGY, value: %s, msg: %s, will use %s", val, err.Error(),
+				gslbutils.GetGslbNameCollisionStrategy())
+		}
+	}
+
+	if val := os.Getenv("MEMBER_REMOVAL_GRACE_PERIOD"); val != "" {
+		seconds, err := strconv.Atoi(val)
+		if err == nil {
+			err = gslbutils.SetDefaultMemberRemovalGracePeriod(seconds)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: MEMBER_REMOVAL_GRACE_PERIOD, value: %s, msg: invalid grace period, will use %d seconds",
+				val, gslbutils.DefaultMemberRemovalGracePeriod)
+		}
+	}
+
+	if val := os.Getenv("MIN_OBJECT_AGE"); val != "" {
+		seconds, err := strconv.Atoi(val)
+		if err == nil {
+			err = gslbutils.SetDefaultMinObjectAge(seconds)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: MIN_OBJECT_AGE, value: %s, msg: invalid min age, the objects will be advertised right away",
+				val)
+		}
+	}
+
+	if val := os.Getenv("STALE_OBJECTS_GC_INTERVAL"); val != "" {
+		seconds, err := strconv.Atoi(val)
+		if err == nil {
+			err = nodes.SetStaleObjectsGCInterval(seconds)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: STALE_OBJECTS_GC_INTERVAL, value: %s, msg: invalid interval, will use %d seconds",
+				val, nodes.DefaultStaleObjectsGCInterval)
+		}
+	}
+
+	if val := os.Getenv("RETRY_QUEUE_MAX_DEPTH"); val != "" {
+		depth, err := strconv.Atoi(val)
+		if err == nil {
+			err = gslbutils.SetRetryQueueMaxDepth(depth)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: RETRY_QUEUE_MAX_DEPTH, value: %s, msg: invalid max depth, the retry queues will be unbounded",
+				val)
+		}
+	}
+
+	if val := os.Getenv("RETRY_QUEUE_OVERFLOW_POLICY"); val != "" {
+		if err := gslbutils.SetRetryQueueOverflowPolicy(val); err != nil {
+			gslbutils.Warnf("env: RETRY_QUEUE_OVERFLOW_POLICY, value: %s, msg: %s, will use %s", val, err.Error(),
+				gslbutils.GetRetryQueueOverflowPolicy())
+		}
+	}
+
+	if val := os.Getenv("TRANSIENT_STATUS_CODES"); val != "" {
+		codes, err := gslbutils.ParseTransientStatusCodes(val)
+		if err != nil {
+			gslbutils.Warnf("env: TRANSIENT_STATUS_CODES, value: %s, msg: %s, will use the default status codes", val,
+				err.Error())
+		} else {
+			gslbutils.SetTransientStatusCodes(codes)
+		}
+	}
+
+	if val := os.Getenv("RETRY_WORKERS"); val != "" {
+		workers, err := strconv.Atoi(val)
+		if err == nil {
+			err = gslbutils.SetNumRetryWorkers(workers)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: RETRY_WORKERS, value: %s, msg: invalid number of retry workers, will use %d", val,
+				gslbutils.GetNumRetryWorkers())
+		}
+	}
+
+	if val := os.Getenv("REJECTED_OBJECTS_CACHE_SIZE"); val != "" {
+		size, err := strconv.Atoi(val)
+		if err == nil {
+			err = gslbutils.SetRejectedObjsCacheSize(size)
+		}
+		if err != nil {
+			gslbutils.Warnf("env: REJECTED_OBJECTS_CACHE_SIZE, value: %s, msg: invalid cache size, will use %d", val,
+				gslbutils.GetRejectedObjsCacheSize())
+		}
+	}
+
+	if val := os.Getenv("GDP_NAMESPACES"); val != "" {
+		if err := gslbutils.SetGDPNamespaces(strings.Split(val, ",")); err != nil {
+			gslbutils.Warnf("env: GDP_NAMESPACES, value: %s, msg: %s, will use the namespaces %v", val, err.Error(),
+				gslbutils.GetGDPNamespaces())
+		}
+	}
+
+	setRestRateLimit()
+	setRestMaxInFlight()
+	setIngressIPSource()
+
+	if val := os.Getenv("ENABLE_GATEWAY_API"); val != "" {
+		enabled, err := strconv.ParseBool(val)
+		if err != nil {
+			gslbutils.Warnf("env: ENABLE_GATEWAY_API, value: %s, msg: invalid value, gateway API will be disabled", val)
+		}
+		SetGatewayAPIEnabled(enabled)
+	}
+
+	if policy := os.Getenv("CLUSTER_UNREACHABLE_POLICY"); policy != "" {
+		if err := gslbutils.SetClusterUnreachablePolicy(policy); err != nil {
+			gslbutils.Warnf("object: main, msg: %s, will use the default policy %s", err.Error(),
+				gslbutils.GetClusterUnreachablePolicy())
+		}
+	}
+
 	ingestionQueueParams := utils.WorkerQueue{NumWorkers: utils.NumWorkersIngestion, WorkqueueName: utils.ObjectIngestionLayer}
 	graphQueueParams := utils.WorkerQueue{NumWorkers: gslbutils.NumRestWorkers, WorkqueueName: utils.GraphLayer}
-	slowRetryQParams := utils.WorkerQueue{NumWorkers: 1, WorkqueueName: gslbutils.SlowRetryQueue, SlowSyncTime: gslbutils.SlowSyncTime}
-	fastRetryQParams := utils.WorkerQueue{NumWorkers: 1, WorkqueueName: gslbutils.FastRetryQueue}
+	// the retry queues have their own workers, so that the retries and the fresh updates don't starve
+	// each other
+	queueParams := append([]utils.WorkerQueue{ingestionQueueParams, graphQueueParams}, gslbutils.GetRetryQueueParams()...)
 
-	utils.SharedWorkQueue(ingestionQueueParams, graphQueueParams, slowRetryQParams, fastRetryQParams)
+	utils.SharedWorkQueue(queueParams...)
 
 	// Set workers for layer 3 (REST layer)
 	graphSharedQueue := utils.SharedWorkQueue().GetQueueByName(utils.GraphLayer)
@@ -690,7 +976,16 @@ func Initialize() {
 	gdpInformer := gslbInformerFactory.Amko().V1alpha1().GlobalDeploymentPolicies()
 	go gdpInformer.Informer().Run(stopCh)
 
+	// Start the informer for the HostOverride objects
+	InitializeHostOverrideController(gslbInformerFactory)
+	hoInformer := gslbInformerFactory.Amko().V1alpha1().HostOverrides()
+	go hoInformer.Informer().Run(stopCh)
+
 	go RunGDPAndGSLBControllers(gslbController, gdpCtrl, stopCh)
+	ingestionQueue := utils.SharedWorkQueue().GetQueueByName(utils.ObjectIngestionLayer)
+	StartTrafficSplitConfigMapInformer(kubeClient, ingestionQueue.Workqueue, ingestionQueue.NumWorkers, stopCh)
+	go RunBackendWeightsRecompute(ingestionQueue.Workqueue, ingestionQueue.NumWorkers, stopCh)
+	go gslbutils.LogFilterDecisionSummary(gslbutils.FilterSummaryInterval, stopCh)
 	<-stopCh
 	gslbutils.WaitForWorkersToExit()
 }
@@ -714,7 +1009,11 @@ func BuildContextConfig(kubeconfigPath, context string) (*restclient.Config, err
 		}).ClientConfig()
 }
 
-func InformersToRegister(oclient *oshiftclient.Clientset, kclient *kubernetes.Clientset, cname string) ([]string, error) {
+// InformersToRegister returns the informers to be run for cluster cname. If objTypes are set for the
+// cluster, only the informers of these types are run, else the route or the ingress informer is
+// picked as per the APIs served by the cluster.
+func InformersToRegister(oclient *oshiftclient.Clientset, kclient *kubernetes.Clientset, cname string,
+	objTypes []string) ([]string, error) {
 
 	allInformers := []string{}
 	_, err := kclient.CoreV1().Services("").List(metav1.ListOptions{TimeoutSeconds: &informerTimeout})
@@ -722,6 +1021,9 @@ func InformersToRegister(oclient *oshiftclient.Clientset, kclient *kubernetes.Cl
 		gslbutils.Errf("cname: %s, cluster api server health check failed, can't access the services api", cname)
 		return allInformers, errors.New("cluster " + cname + " health check failed, can't access the services api")
 	}
+	if len(objTypes) > 0 {
+		return getInformersForObjectTypes(objTypes), nil
+	}
 	_, err = oclient.RouteV1().Routes("").List(metav1.ListOptions{TimeoutSeconds: &informerTimeout})
 	gslbutils.Debugf("cluster: %s, msg: checking if cluster has a route informer %v", cname, err)
 	if err == nil {
@@ -737,65 +1039,243 @@ func InformersToRegister(oclient *oshiftclient.Clientset, kclient *kubernetes.Cl
 	return allInformers, nil
 }
 
-// InitializeGSLBClusters initializes the GSLB member clusters
-func InitializeGSLBClusters(membersKubeConfig string, memberClusters []gslbalphav1.MemberCluster) ([]*GSLBMemberController, error) {
-	clusterDetails := loadClusterAccess(membersKubeConfig, memberClusters)
-	clients := make(map[string]*kubernetes.Clientset)
+// getInformersForObjectTypes returns the informers watching the objects of objTypes, along with the
+// namespace informer. The Gateway API informers are set up separately.
+func getInformersForObjectTypes(objTypes []string) []string {
+	allInformers := []string{}
+	if gslbutils.PresentInList(gslbutils.RouteType, objTypes) {
+		allInformers = append(allInformers, utils.RouteInformer)
+	}
+	if gslbutils.PresentInList(gslbutils.IngressType, objTypes) {
+		allInformers = append(allInformers, utils.IngressInformer)
+	}
+	if gslbutils.PresentInList(gslbutils.SvcType, objTypes) {
+		allInformers = append(allInformers, utils.ServiceInformer)
+	}
+	return append(allInformers, utils.NSInformer)
+}
 
-	informersArg := make(map[string]interface{})
+// informerResyncEnvs are the environment variables for the resync periods (in seconds) of the
+// informer types.
+var informerResyncEnvs = map[string]string{
+	utils.RouteInformer:   "ROUTE_RESYNC_PERIOD",
+	utils.IngressInformer: "INGRESS_RESYNC_PERIOD",
+	utils.ServiceInformer: "SERVICE_RESYNC_PERIOD",
+	utils.NSInformer:      "NAMESPACE_RESYNC_PERIOD",
+	GatewayInformer:       "GATEWAY_RESYNC_PERIOD",
+	HTTPRouteInformer:     "HTTPROUTE_RESYNC_PERIOD",
+}
 
-	aviCtrlList := make([]*GSLBMemberController, 0)
-	for _, cluster := range clusterDetails {
-		gslbutils.Logf("cluster: %s, msg: %s", cluster.clusterName, "initializing")
-		cfg, err := BuildContextConfig(cluster.kubeconfig, cluster.clusterName)
-		if err != nil {
-			gslbutils.Warnf("cluster: %s, msg: %s, %s", cluster.clusterName, "error in connecting to kubernetes API",
-				err)
+// GetInformerResyncPeriods returns the resync periods of the informer types, as set in their
+// environment variables. A value of 0 disables the periodic resync for that type, informer types
+// with an unset or invalid value use the default resync period.
+func GetInformerResyncPeriods() InformerResyncPeriods {
+	resyncPeriods := make(InformerResyncPeriods)
+	for informerType, env := range informerResyncEnvs {
+		val := os.Getenv(env)
+		if val == "" {
 			continue
-		} else {
-			gslbutils.Logf("cluster: %s, msg: %s", cluster.clusterName, "successfully connected to kubernetes API")
 		}
-		kubeClient, err := kubernetes.NewForConfig(cfg)
-		if err != nil {
-			gslbutils.Warnf("cluster: %s, msg: %s, %s", cluster.clusterName, "error in creating kubernetes clientset",
-				err)
+		seconds, err := strconv.Atoi(val)
+		if err != nil || seconds < 0 {
+			gslbutils.Warnf("informer: %s, env: %s, value: %s, msg: invalid resync period, will use the default",
+				informerType, env, val)
 			continue
 		}
-		oshiftClient, err := oshiftclient.NewForConfig(cfg)
-		if err != nil {
-			gslbutils.Warnf("cluster: %s, msg: %s, %s", cluster.clusterName, "error in creating openshift clientset")
-			continue
+		resyncPeriods[informerType] = time.Duration(seconds) * time.Second
+	}
+	return resyncPeriods
+}
+
+// setRestRateLimit sets the limits for the rest calls to the Avi controller from the REST_QPS and
+// REST_BURST environment variables, the defaults are used for the unset or invalid values.
+func setRestRateLimit() {
+	qps, burst := gslbutils.GetRestRateLimit()
+	if val := os.Getenv("REST_QPS"); val != "" {
+		parsed, err := strconv.ParseFloat(val, 32)
+		if err != nil || parsed <= 0 {
+			gslbutils.Warnf("env: REST_QPS, value: %s, msg: invalid rest qps, will use %v", val, qps)
+		} else {
+			qps = float32(parsed)
 		}
-		informersArg[utils.INFORMERS_OPENSHIFT_CLIENT] = oshiftClient
-		informersArg[utils.INFORMERS_INSTANTIATE_ONCE] = false
-		registeredInformers, err := InformersToRegister(oshiftClient, kubeClient, cluster.clusterName)
-		if err != nil {
-			gslbutils.Errf("error in initializing informers")
-			return aviCtrlList, err
+	}
+	if val := os.Getenv("REST_BURST"); val != "" {
+		parsed, err := strconv.Atoi(val)
+		if err != nil || parsed <= 0 {
+			gslbutils.Warnf("env: REST_BURST, value: %s, msg: invalid rest burst, will use %d", val, burst)
+		} else {
+			burst = parsed
 		}
-		if len(registeredInformers) == 0 {
-			gslbutils.Errf("No informers available for this cluster %s, returning", cluster.clusterName)
+	}
+	if err := gslbutils.SetRestRateLimit(qps, burst); err != nil {
+		gslbutils.Warnf("msg: %s", err.Error())
+		return
+	}
+	gslbutils.Logf("qps: %v, burst: %d, msg: rate limits set for the rest calls", qps, burst)
+}
+
+// setRestMaxInFlight sets the max number of rest calls to the Avi controller in flight from the
+// REST_MAX_IN_FLIGHT environment variable, the rest calls in flight are unbounded if it's unset or
+// invalid.
+func setRestMaxInFlight() {
+	val := os.Getenv("REST_MAX_IN_FLIGHT")
+	if val == "" {
+		return
+	}
+	maxInFlight, err := strconv.Atoi(val)
+	if err == nil {
+		err = gslbutils.SetRestMaxInFlight(maxInFlight)
+	}
+	if err != nil {
+		gslbutils.Warnf("env: REST_MAX_IN_FLIGHT, value: %s, msg: invalid max in flight, the rest calls in flight will be unbounded",
+			val)
+		return
+	}
+	gslbutils.Logf("maxInFlight: %d, msg: max set for the rest calls in flight", maxInFlight)
+}
+
+// setIngressIPSource sets the sources of the IP addresses of the ingresses from the
+// INGRESS_IP_SOURCE and INGRESS_IP_ANNOTATION environment variables, the status of the ingresses is
+// used if these are unset or invalid.
+func setIngressIPSource() {
+	val := os.Getenv("INGRESS_IP_SOURCE")
+	annotation := os.Getenv("INGRESS_IP_ANNOTATION")
+	if val == "" && annotation == "" {
+		return
+	}
+	sources := []string{gslbutils.IngressIPSourceStatus}
+	if val != "" {
+		sources = gslbutils.ParseIngressIPSource(val)
+	}
+	if err := gslbutils.SetIngressIPSource(sources, annotation); err != nil {
+		gslbutils.Warnf("env: INGRESS_IP_SOURCE, value: %s, msg: %s, will use the ingress status", val, err.Error())
+		return
+	}
+	sources, annotation = gslbutils.GetIngressIPSource()
+	gslbutils.Logf("sources: %v, annotation: %s, msg: ingress ip sources set", sources, annotation)
+}
+
+// InitializeGSLBClusters initializes the GSLB member clusters. If a member cluster can't be
+// initialized, the cluster unreachable policy decides whether an error is returned, or the member
+// cluster is returned in the list of unreachable clusters to be retried later.
+func InitializeGSLBClusters(membersKubeConfig string, memberClusters []gslbalphav1.MemberCluster) ([]*GSLBMemberController,
+	[]gslbalphav1.MemberCluster, error) {
+	clusterDetails := loadClusterAccess(membersKubeConfig, memberClusters)
+	resyncPeriods := GetInformerResyncPeriods()
+	policy := gslbutils.GetClusterUnreachablePolicy()
+
+	aviCtrlList := make([]*GSLBMemberController, 0)
+	var unreachableClusters []gslbalphav1.MemberCluster
+	for idx, cluster := range clusterDetails {
+		aviCtrl, err := initializeGSLBCluster(cluster, resyncPeriods)
+		if err == nil {
+			aviCtrlList = append(aviCtrlList, aviCtrl)
 			continue
 		}
-		gslbutils.Logf("Informers for cluster %s: %v", cluster.clusterName, registeredInformers)
-		informerInstance := utils.NewInformers(utils.KubeClientIntf{
-			ClientSet: kubeClient},
-			registeredInformers,
-			informersArg)
-		clients[cluster.clusterName] = kubeClient
-		aviCtrl := GetGSLBMemberController(cluster.clusterName, informerInstance)
-		gslbutils.AddClusterContext(cluster.clusterName)
-		aviCtrl.SetupEventHandlers(K8SInformers{Cs: clients[cluster.clusterName]})
-		aviCtrlList = append(aviCtrlList, &aviCtrl)
+		if policy == gslbutils.ClusterUnreachableFail {
+			gslbutils.Errf("cluster: %s, msg: error in initializing the cluster, %s", cluster.clusterName, err)
+			return aviCtrlList, unreachableClusters, err
+		}
+		gslbutils.Warnf("cluster: %s, msg: error in initializing the cluster, will continue with the other clusters and retry, %s",
+			cluster.clusterName, err)
+		unreachableClusters = append(unreachableClusters, memberClusters[idx])
+	}
+	return aviCtrlList, unreachableClusters, nil
+}
+
+// initializeGSLBCluster connects to a member cluster and sets up the member controller along with
+// the informers for the cluster.
+func initializeGSLBCluster(cluster kubeClusterDetails, resyncPeriods InformerResyncPeriods) (*GSLBMemberController, error) {
+	gslbutils.Logf("cluster: %s, msg: %s", cluster.clusterName, "initializing")
+	cfg, err := BuildContextConfig(cluster.kubeconfig, cluster.clusterName)
+	if err != nil {
+		gslbutils.Warnf("cluster: %s, msg: %s, %s", cluster.clusterName, "error in connecting to kubernetes API",
+			err)
+		return nil, errors.New("cluster " + cluster.clusterName + " error in connecting to kubernetes API: " + err.Error())
+	}
+	gslbutils.Logf("cluster: %s, msg: %s", cluster.clusterName, "successfully connected to kubernetes API")
+	ObserveInformerErrors(cfg, cluster.clusterName)
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		gslbutils.Warnf("cluster: %s, msg: %s, %s", cluster.clusterName, "error in creating kubernetes clientset",
+			err)
+		return nil, errors.New("cluster " + cluster.clusterName + " error in creating kubernetes clientset: " + err.Error())
+	}
+	oshiftClient, err := oshiftclient.NewForConfig(cfg)
+	if err != nil {
+		gslbutils.Warnf("cluster: %s, msg: %s, %s", cluster.clusterName, "error in creating openshift clientset", err)
+		return nil, errors.New("cluster " + cluster.clusterName + " error in creating openshift clientset: " + err.Error())
+	}
+	informersArg := make(map[string]interface{})
+	informersArg[utils.INFORMERS_OPENSHIFT_CLIENT] = oshiftClient
+	informersArg[utils.INFORMERS_INSTANTIATE_ONCE] = false
+	objTypes := gslbutils.GetClusterObjectTypes(cluster.clusterName)
+	registeredInformers, err := InformersToRegister(oshiftClient, kubeClient, cluster.clusterName, objTypes)
+	if err != nil {
+		gslbutils.Errf("cluster: %s, msg: error in initializing informers", cluster.clusterName)
+		return nil, err
+	}
+	if len(registeredInformers) == 0 {
+		gslbutils.Errf("No informers available for this cluster %s, returning", cluster.clusterName)
+		return nil, errors.New("no informers available for cluster " + cluster.clusterName)
+	}
+	gslbutils.Logf("Informers for cluster %s: %v", cluster.clusterName, registeredInformers)
+	informerInstance := utils.NewInformers(utils.KubeClientIntf{
+		ClientSet: kubeClient},
+		registeredInformers,
+		informersArg)
+	aviCtrl := GetGSLBMemberController(cluster.clusterName, informerInstance, resyncPeriods)
+	if gslbutils.IsObjectTypeWatched(gslbutils.HTTPRouteType, objTypes) {
+		aviCtrl.SetGatewayInformers(NewGatewayInformers(cfg, cluster.clusterName))
+	}
+	gslbutils.AddClusterContext(cluster.clusterName)
+	if err := gslbutils.SetClusterRegion(cluster.clusterName, cluster.region); err != nil {
+		gslbutils.Warnf("cluster: %s, msg: couldn't set the region, %s", cluster.clusterName, err)
+	}
+	gslbutils.RegisterClusterForReadiness(cluster.clusterName)
+	aviCtrl.SetupEventHandlers(K8SInformers{Cs: kubeClient})
+	return &aviCtrl, nil
+}
+
+// retryGSLBClusters retries the initialization of the unreachable member clusters in the
+// background, each cluster joins the GSLB cluster once it is reachable.
+func retryGSLBClusters(membersKubeConfig string, memberClusters []gslbalphav1.MemberCluster, stopCh <-chan struct{}) {
+	resyncPeriods := GetInformerResyncPeriods()
+	for _, cluster := range loadClusterAccess(membersKubeConfig, memberClusters) {
+		go retryGSLBCluster(cluster, resyncPeriods, stopCh)
+	}
+}
+
+// retryGSLBCluster retries the initialization of a member cluster with an exponential backoff,
+// till it succeeds or stopCh is closed. The informers of the cluster are started once it is
+// initialized.
+func retryGSLBCluster(cluster kubeClusterDetails, resyncPeriods InformerResyncPeriods, stopCh <-chan struct{}) {
+	delay := gslbutils.ClusterRetryInitialDelay
+	for {
+		select {
+		case <-stopCh:
+			return
+		case <-time.After(delay):
+		}
+		aviCtrl, err := initializeGSLBCluster(cluster, resyncPeriods)
+		if err == nil {
+			gslbutils.Logf("cluster: %s, msg: cluster is reachable now, starting the informers", cluster.clusterName)
+			aviCtrl.Start(stopCh)
+			// the GDP object may be waiting for this cluster
+			RefreshGDPStatusWarnings()
+			return
+		}
+		delay = gslbutils.NextClusterRetryDelay(delay)
+		gslbutils.Warnf("cluster: %s, msg: cluster is still unreachable, will retry after %v, %s", cluster.clusterName,
+			delay, err)
 	}
-	return aviCtrlList, nil
 }
 
 func loadClusterAccess(membersKubeConfig string, memberClusters []gslbalphav1.MemberCluster) []kubeClusterDetails {
 	var clusterDetails []kubeClusterDetails
 	for _, memberCluster := range memberClusters {
 		clusterDetails = append(clusterDetails, kubeClusterDetails{memberCluster.ClusterContext,
-			membersKubeConfig, "", nil})
+			membersKubeConfig, "", nil, memberCluster.Region})
 		gslbutils.Logf("cluster: %s, msg: %s", memberCluster.ClusterContext, "loaded cluster access")
 	}
 	return clusterDetails
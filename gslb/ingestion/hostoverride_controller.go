@@ -0,0 +1,196 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/nodes"
+
+	gdpalphav1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+	gslbinformers "github.com/avinetworks/amko/internal/client/informers/externalversions"
+
+	"k8s.io/client-go/tools/cache"
+)
+
+// HostOverrideSuccess is the status of an accepted HostOverride object.
+const HostOverrideSuccess = "success"
+
+// pendingHostOverrides holds the HostOverride objects rejected because of a conflict with another
+// HostOverride object, keyed by their namespaces and names. These are applied again once a
+// HostOverride object is deleted, as the conflict may be gone.
+var pendingHostOverrides = struct {
+	objs map[string]*gdpalphav1.HostOverride
+	lock sync.Mutex
+}{objs: make(map[string]*gdpalphav1.HostOverride)}
+
+// setHostOverridePending adds hoObj to the pending HostOverride objects, or removes it if it's not
+// pending.
+func setHostOverridePending(hoObj *gdpalphav1.HostOverride, pending bool) {
+	key := gslbutils.JoinKey(hoObj.ObjectMeta.Namespace, hoObj.ObjectMeta.Name)
+	pendingHostOverrides.lock.Lock()
+	defer pendingHostOverrides.lock.Unlock()
+	if !pending {
+		delete(pendingHostOverrides.objs, key)
+		return
+	}
+	pendingHostOverrides.objs[key] = hoObj.DeepCopy()
+}
+
+// applyPendingHostOverrides applies the pending HostOverride objects again, the oldest first, so
+// that the object which lost a conflict takes over once the winning object is deleted. The objects
+// which still conflict remain pending.
+func applyPendingHostOverrides() {
+	pendingHostOverrides.lock.Lock()
+	hoObjs := make([]*gdpalphav1.HostOverride, 0, len(pendingHostOverrides.objs))
+	for _, hoObj := range pendingHostOverrides.objs {
+		hoObjs = append(hoObjs, hoObj)
+	}
+	pendingHostOverrides.lock.Unlock()
+
+	sort.Slice(hoObjs, func(i, j int) bool {
+		ti, tj := hoObjs[i].ObjectMeta.CreationTimestamp, hoObjs[j].ObjectMeta.CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return hoObjs[i].ObjectMeta.Name < hoObjs[j].ObjectMeta.Name
+	})
+	for _, hoObj := range hoObjs {
+		gslbutils.Logf("ns: %s, hostOverride: %s, msg: applying the pending HostOverride object again",
+			hoObj.ObjectMeta.Namespace, hoObj.ObjectMeta.Name)
+		AddHostOverrideObj(hoObj)
+	}
+}
+
+// AddHostOverrideObj saves the HostOverride for its fqdn and applies it to the GS graph of the
+// fqdn. Only the HostOverride objects in the AVISystem namespace are accepted. An object which
+// conflicts with another HostOverride object is kept pending till a HostOverride object is deleted.
+func AddHostOverrideObj(obj interface{}) {
+	hoObj, ok := obj.(*gdpalphav1.HostOverride)
+	if !ok {
+		gslbutils.Errf("object added is not of type HostOverride")
+		return
+	}
+	if hoObj.ObjectMeta.Namespace != gslbutils.AVISystem {
+		gslbutils.Warnf("ns: %s, hostOverride: %s, msg: HostOverride objects are only accepted in %s namespace",
+			hoObj.ObjectMeta.Namespace, hoObj.ObjectMeta.Name, gslbutils.AVISystem)
+		return
+	}
+	ho, err := gslbutils.GetHostOverrideFromObj(hoObj)
+	if err == nil {
+		err = validateHostOverrideAliases(ho)
+	}
+	conflict := false
+	if err == nil {
+		err = gslbutils.AddOrUpdateHostOverride(ho)
+		conflict = err != nil
+	}
+	setHostOverridePending(hoObj, conflict)
+	if err != nil {
+		gslbutils.Errf("ns: %s, hostOverride: %s, msg: error in accepting HostOverride object: %s",
+			hoObj.ObjectMeta.Namespace, hoObj.ObjectMeta.Name, err.Error())
+		updateHostOverrideStatus(hoObj, err.Error())
+		return
+	}
+	gslbutils.Logf("ns: %s, hostOverride: %s, fqdn: %s, msg: HostOverride object added", ho.Namespace,
+		ho.Name, ho.Fqdn)
+	updateHostOverrideStatus(hoObj, HostOverrideSuccess)
+	nodes.ApplyHostOverride(ho.Fqdn)
+}
+
+// validateHostOverrideAliases returns an error if an alias of ho is the hostname of a GS member, as
+// such a hostname is the domain name of its own GS.
+func validateHostOverrideAliases(ho *gslbutils.HostOverride) error {
+	for _, alias := range ho.Aliases {
+		if nodes.IsGSMemberHostname(alias) {
+			return errors.New("alias " + alias + " is the hostname of a selected object")
+		}
+	}
+	return nil
+}
+
+// updateHostOverrideStatus sets the error status of the HostOverride object to msg, if it changed.
+func updateHostOverrideStatus(hoObj *gdpalphav1.HostOverride, msg string) {
+	// the fake client of the unit tests can't update the CRDs, see updateGDPStatus
+	if !gslbutils.PublishHostOverrideStatus || hoObj.Status.ErrorStatus == msg {
+		return
+	}
+	hoObj = hoObj.DeepCopy()
+	hoObj.Status.ErrorStatus = msg
+	_, err := gslbutils.GlobalGslbClient.AmkoV1alpha1().HostOverrides(hoObj.ObjectMeta.Namespace).Update(hoObj)
+	if err != nil {
+		gslbutils.Errf("ns: %s, hostOverride: %s, msg: error in updating the status: %s", hoObj.ObjectMeta.Namespace,
+			hoObj.ObjectMeta.Name, err)
+	}
+}
+
+// UpdateHostOverrideObj updates the HostOverride for the fqdn. If the fqdn of the object changed or
+// the object is not valid anymore, the older HostOverride is removed.
+func UpdateHostOverrideObj(old, new interface{}) {
+	oldObj := old.(*gdpalphav1.HostOverride)
+	newObj := new.(*gdpalphav1.HostOverride)
+	if oldObj.ObjectMeta.ResourceVersion == newObj.ObjectMeta.ResourceVersion {
+		return
+	}
+	if oldObj.Spec.Fqdn != newObj.Spec.Fqdn {
+		DeleteHostOverrideObj(oldObj)
+	} else if _, err := gslbutils.GetHostOverrideFromObj(newObj); err != nil {
+		// the older HostOverride for this fqdn is not applicable anymore
+		DeleteHostOverrideObj(oldObj)
+	}
+	AddHostOverrideObj(newObj)
+}
+
+// DeleteHostOverrideObj removes the HostOverride for the fqdn, the GS graph of the fqdn gets the
+// default parameters. The pending HostOverride objects are applied again, e.g. another object for
+// the same fqdn.
+func DeleteHostOverrideObj(obj interface{}) {
+	hoObj, ok := obj.(*gdpalphav1.HostOverride)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			gslbutils.Errf("object deleted is not of type HostOverride")
+			return
+		}
+		hoObj, ok = tombstone.Obj.(*gdpalphav1.HostOverride)
+		if !ok {
+			gslbutils.Errf("object deleted is not of type HostOverride")
+			return
+		}
+	}
+	setHostOverridePending(hoObj, false)
+	fqdn := hoObj.Spec.Fqdn
+	if !gslbutils.DeleteHostOverride(fqdn, hoObj.ObjectMeta.Namespace, hoObj.ObjectMeta.Name) {
+		return
+	}
+	gslbutils.Logf("ns: %s, hostOverride: %s, fqdn: %s, msg: HostOverride object deleted",
+		hoObj.ObjectMeta.Namespace, hoObj.ObjectMeta.Name, fqdn)
+	nodes.ApplyHostOverride(fqdn)
+	applyPendingHostOverrides()
+}
+
+// InitializeHostOverrideController sets up the event handlers for the HostOverride objects.
+func InitializeHostOverrideController(gslbInformerFactory gslbinformers.SharedInformerFactory) {
+	hoInformer := gslbInformerFactory.Amko().V1alpha1().HostOverrides()
+	gslbutils.Logf("object: HostOverrideController, msg: %s", "setting up event handlers")
+	hoInformer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    AddHostOverrideObj,
+		UpdateFunc: UpdateHostOverrideObj,
+		DeleteFunc: DeleteHostOverrideObj,
+	})
+}
@@ -0,0 +1,412 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	filter "github.com/avinetworks/amko/gslb/gdp_filter"
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gwv1 "github.com/avinetworks/amko/internal/apis/gateway/v1"
+
+	containerutils "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// GatewayInformer and HTTPRouteInformer are the informer types for the Gateway API objects,
+	// these informers are not a part of the AKO informers.
+	GatewayInformer   = "GatewayInformer"
+	HTTPRouteInformer = "HTTPRouteInformer"
+
+	gatewayResource   = "gateways"
+	httpRouteResource = "httproutes"
+	// gatewayInformerResync is the default resync period of the Gateway API informers
+	gatewayInformerResync = 30 * time.Second
+)
+
+var gatewayScheme = runtime.NewScheme()
+
+func init() {
+	if err := gwv1.AddToScheme(gatewayScheme); err != nil {
+		panic("error in adding the gateway API types to the scheme: " + err.Error())
+	}
+}
+
+var gatewayAPIEnabled bool
+
+// SetGatewayAPIEnabled enables the ingestion of the Gateway API objects (Gateways and HTTPRoutes)
+// for the member clusters initialized after this call.
+func SetGatewayAPIEnabled(val bool) {
+	gatewayAPIEnabled = val
+}
+
+// GatewayInformers are the informers for the Gateway API objects of a member cluster. The objects
+// are only read, AMKO doesn't update their status.
+type GatewayInformers struct {
+	client            restclient.Interface
+	GatewayInformer   cache.SharedIndexInformer
+	HTTPRouteInformer cache.SharedIndexInformer
+}
+
+// newGatewayRESTClient returns a REST client for the standard channel group version of the
+// Gateway API.
+func newGatewayRESTClient(cfg *restclient.Config) (restclient.Interface, error) {
+	config := *cfg
+	config.GroupVersion = &gwv1.SchemeGroupVersion
+	config.APIPath = "/apis"
+	config.NegotiatedSerializer = serializer.NewCodecFactory(gatewayScheme).WithoutConversion()
+	if config.UserAgent == "" {
+		config.UserAgent = restclient.DefaultKubernetesUserAgent()
+	}
+	return restclient.RESTClientFor(&config)
+}
+
+// NewGatewayInformers returns the Gateway API informers for a member cluster, if the cluster
+// serves the Gateway API. A nil value is returned if the Gateway API isn't enabled or the cluster
+// doesn't serve it.
+func NewGatewayInformers(cfg *restclient.Config, cname string) *GatewayInformers {
+	if !gatewayAPIEnabled {
+		return nil
+	}
+	client, err := newGatewayRESTClient(cfg)
+	if err != nil {
+		gslbutils.Warnf("cluster: %s, msg: error in creating the gateway API client, %s", cname, err)
+		return nil
+	}
+	var routeList gwv1.HTTPRouteList
+	err = client.Get().Resource(httpRouteResource).
+		VersionedParams(&metav1.ListOptions{TimeoutSeconds: &informerTimeout, Limit: 1}, metav1.ParameterCodec).
+		Do().Into(&routeList)
+	if err != nil {
+		gslbutils.Logf("cluster: %s, msg: gateway API not available, won't watch HTTPRoutes, %s", cname, err)
+		return nil
+	}
+	gslbutils.Logf("cluster: %s, msg: gateway API available, will watch the Gateways and HTTPRoutes", cname)
+	return &GatewayInformers{
+		client: client,
+		GatewayInformer: cache.NewSharedIndexInformer(
+			cache.NewListWatchFromClient(client, gatewayResource, metav1.NamespaceAll, fields.Everything()),
+			&gwv1.Gateway{}, gatewayInformerResync, cache.Indexers{}),
+		HTTPRouteInformer: cache.NewSharedIndexInformer(
+			cache.NewListWatchFromClient(client, httpRouteResource, metav1.NamespaceAll, fields.Everything()),
+			&gwv1.HTTPRoute{}, gatewayInformerResync, cache.Indexers{}),
+	}
+}
+
+// getGateway returns the Gateway from the informer cache, implements k8sobjects.GatewayGetter.
+func (gi *GatewayInformers) getGateway(ns, name string) (*gwv1.Gateway, bool) {
+	obj, exists, err := gi.GatewayInformer.GetIndexer().GetByKey(ns + "/" + name)
+	if err != nil || !exists {
+		return nil, false
+	}
+	gw, ok := obj.(*gwv1.Gateway)
+	return gw, ok
+}
+
+// AddOrUpdateHTTPRouteStore adds/updates the HTTPRoute host object in the cluster store for the
+// cluster cname.
+func AddOrUpdateHTTPRouteStore(clusterStore *gslbutils.ClusterStore, hrh k8sobjects.HTTPRouteHostMeta, cname string) {
+	clusterStore.AddOrUpdate(hrh, cname, hrh.Namespace, hrh.ObjName)
+}
+
+// DeleteFromHTTPRouteStore deletes the HTTPRoute host object from the cluster store for the
+// cluster cname, returns true if the object was present.
+func DeleteFromHTTPRouteStore(clusterStore *gslbutils.ClusterStore, hrh k8sobjects.HTTPRouteHostMeta,
+	cname string) bool {
+	if clusterStore == nil {
+		return false
+	}
+	gslbutils.GetGlobalFilter().ReleaseClusterObj(cname, gslbutils.HTTPRouteType, hrh.Namespace, hrh.ObjName)
+	_, present := clusterStore.DeleteClusterNSObj(cname, hrh.Namespace, hrh.ObjName)
+	return present
+}
+
+// getStoredHTTPRouteHostMetas returns the host objects of the HTTPRoute ns/routeName in store.
+func getStoredHTTPRouteHostMetas(store *gslbutils.ClusterStore, cname, ns, routeName string) []k8sobjects.HTTPRouteHostMeta {
+	hrhList := []k8sobjects.HTTPRouteHostMeta{}
+	nsStore := store.GetClusterStore(cname).GetNSStore(ns)
+	for _, objName := range nsStore.GetAllObjectNames() {
+		if !strings.HasPrefix(objName, routeName+"/") {
+			continue
+		}
+		if ok, obj := nsStore.Get(objName); ok {
+			if hrh, isHrh := obj.(k8sobjects.HTTPRouteHostMeta); isHrh && hrh.RouteName == routeName {
+				hrhList = append(hrhList, hrh)
+			}
+		}
+	}
+	return hrhList
+}
+
+// httpRouteLock serializes the syncs of an HTTPRoute, refs are the syncs holding or waiting for it.
+type httpRouteLock struct {
+	lock sync.Mutex
+	refs int
+}
+
+// httpRouteLocks are the locks of the HTTPRoutes being synced, keyed by the cluster, the namespace
+// and the name of the route. A route is synced from the events of both the route and its gateways,
+// which are handled concurrently.
+var httpRouteLocks = struct {
+	locks map[string]*httpRouteLock
+	lock  sync.Mutex
+}{locks: make(map[string]*httpRouteLock)}
+
+// lockHTTPRoute locks the HTTPRoute ns/routeName of cluster cname, and returns the function which
+// unlocks it.
+func lockHTTPRoute(cname, ns, routeName string) func() {
+	key := gslbutils.JoinKey(cname, ns, routeName)
+	httpRouteLocks.lock.Lock()
+	routeLock, ok := httpRouteLocks.locks[key]
+	if !ok {
+		routeLock = &httpRouteLock{}
+		httpRouteLocks.locks[key] = routeLock
+	}
+	routeLock.refs++
+	httpRouteLocks.lock.Unlock()
+
+	routeLock.lock.Lock()
+	return func() {
+		routeLock.lock.Unlock()
+		httpRouteLocks.lock.Lock()
+		defer httpRouteLocks.lock.Unlock()
+		routeLock.refs--
+		if routeLock.refs == 0 {
+			delete(httpRouteLocks.locks, key)
+		}
+	}
+}
+
+// syncHTTPRoute brings the stores in sync with newHrhs, the current host objects of the HTTPRoute
+// ns/routeName. The host objects present in the stores are compared against the new ones, the
+// hosts which are gone (or have no IP address) are deleted, and the new or changed hosts are passed
+// through the filter. The keys are published only for the changes in the accepted store. The syncs
+// of a route are serialized, as the stores are read and then updated.
+func syncHTTPRoute(c *GSLBMemberController, numWorkers uint32, ns, routeName string,
+	newHrhs []k8sobjects.HTTPRouteHostMeta) {
+	unlock := lockHTTPRoute(c.name, ns, routeName)
+	defer unlock()
+
+	acceptedStore := gslbutils.GetAcceptedHTTPRouteStore()
+	rejectedStore := gslbutils.GetRejectedHTTPRouteStore()
+	acceptedHrhs := getStoredHTTPRouteHostMetas(acceptedStore, c.name, ns, routeName)
+	rejectedHrhs := getStoredHTTPRouteHostMetas(rejectedStore, c.name, ns, routeName)
+
+	validHrhs := []k8sobjects.HTTPRouteHostMeta{}
+	for _, hrh := range newHrhs {
+		if hrh.IPAddr == "" || hrh.Hostname == "" {
+			gslbutils.Debugf("cluster: %s, ns: %s, httproute: %s, msg: %s", c.name, hrh.Namespace, hrh.ObjName,
+				"rejected HTTPRoute host because IP address/Hostname not found in the gateway status")
+			continue
+		}
+		validHrhs = append(validHrhs, hrh)
+	}
+
+	// delete the hosts which are not present anymore
+	for _, hrh := range acceptedHrhs {
+		if _, found := hrh.HTTPRouteHostInList(validHrhs); found {
+			continue
+		}
+		DeleteFromHTTPRouteStore(acceptedStore, hrh, c.name)
+		gslbutils.DeleteRejectedObj(gslbutils.HTTPRouteType, c.name, hrh.Namespace, hrh.ObjName)
+		publishKeyToGraphLayer(numWorkers, gslbutils.HTTPRouteType, c.name, hrh.Namespace, hrh.ObjName,
+			gslbutils.ObjectDelete, hrh.Hostname, c.workqueue)
+	}
+	for _, hrh := range rejectedHrhs {
+		if _, found := hrh.HTTPRouteHostInList(validHrhs); !found {
+			DeleteFromHTTPRouteStore(rejectedStore, hrh, c.name)
+			gslbutils.DeleteRejectedObj(gslbutils.HTTPRouteType, c.name, hrh.Namespace, hrh.ObjName)
+		}
+	}
+
+	for _, hrh := range validHrhs {
+		acceptedHrh, isAccepted := hrh.HTTPRouteHostInList(acceptedHrhs)
+		if isAccepted && acceptedHrh.GetHTTPRouteHostCksum() == hrh.GetHTTPRouteHostCksum() {
+			continue
+		}
+		rejectedHrh, isRejected := hrh.HTTPRouteHostInList(rejectedHrhs)
+		if isRejected && rejectedHrh.GetHTTPRouteHostCksum() == hrh.GetHTTPRouteHostCksum() {
+			continue
+		}
+		if !filter.ApplyFilter(hrh, c.name) {
+			AddOrUpdateHTTPRouteStore(rejectedStore, hrh, c.name)
+			if isAccepted {
+				DeleteFromHTTPRouteStore(acceptedStore, acceptedHrh, c.name)
+				publishKeyToGraphLayer(numWorkers, gslbutils.HTTPRouteType, c.name, acceptedHrh.Namespace,
+					acceptedHrh.ObjName, gslbutils.ObjectDelete, acceptedHrh.Hostname, c.workqueue)
+			}
+			gslbutils.Debugf("cluster: %s, ns: %s, httproute: %s, msg: %s", c.name, hrh.Namespace, hrh.ObjName,
+				"rejected HTTPRoute host because it couldn't pass through the filter")
+			continue
+		}
+		oper := gslbutils.ObjectAdd
+		if isAccepted {
+			oper = gslbutils.ObjectUpdate
+		}
+		AddOrUpdateHTTPRouteStore(acceptedStore, hrh, c.name)
+		if isRejected {
+			rejectedStore.DeleteClusterNSObj(c.name, hrh.Namespace, hrh.ObjName)
+		}
+		publishKeyToGraphLayer(numWorkers, gslbutils.HTTPRouteType, c.name, hrh.Namespace, hrh.ObjName,
+			oper, hrh.Hostname, c.workqueue)
+	}
+}
+
+// AddHTTPRouteEventHandler returns the event handler for the HTTPRoutes of a member cluster.
+func AddHTTPRouteEventHandler(numWorkers uint32, c *GSLBMemberController) cache.ResourceEventHandler {
+	gslbutils.Logf("Adding HTTPRoute handler")
+	syncRoute := func(obj interface{}) {
+		route, ok := obj.(*gwv1.HTTPRoute)
+		if !ok {
+			containerutils.AviLog.Errorf("Unable to convert obj type interface to gateway/v1 HTTPRoute")
+			return
+		}
+		hrhs := k8sobjects.GetHTTPRouteHostMeta(route, c.gwInformers.getGateway, c.name)
+		syncHTTPRoute(c, numWorkers, route.Namespace, route.Name, hrhs)
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: syncRoute,
+		UpdateFunc: func(old, cur interface{}) {
+			syncRoute(cur)
+		},
+		DeleteFunc: func(obj interface{}) {
+			route, ok := obj.(*gwv1.HTTPRoute)
+			if !ok {
+				tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown)
+				if !isTombstone {
+					containerutils.AviLog.Errorf("couldn't get object from tombstone %#v", obj)
+					return
+				}
+				if route, ok = tombstone.Obj.(*gwv1.HTTPRoute); !ok {
+					containerutils.AviLog.Errorf("Tombstone contained object that is not an HTTPRoute: %#v", obj)
+					return
+				}
+			}
+			syncHTTPRoute(c, numWorkers, route.Namespace, route.Name, nil)
+		},
+	}
+}
+
+// AddGatewayEventHandler returns the event handler for the Gateways of a member cluster. The
+// HTTPRoutes attached to a Gateway are synced again on every change to the Gateway, as their
+// IP addresses come from its status.
+func AddGatewayEventHandler(numWorkers uint32, c *GSLBMemberController) cache.ResourceEventHandler {
+	gslbutils.Logf("Adding Gateway handler")
+	syncAttachedRoutes := func(obj interface{}) {
+		gw, ok := obj.(*gwv1.Gateway)
+		if !ok {
+			tombstone, isTombstone := obj.(cache.DeletedFinalStateUnknown)
+			if !isTombstone {
+				containerutils.AviLog.Errorf("Unable to convert obj type interface to gateway/v1 Gateway")
+				return
+			}
+			if gw, ok = tombstone.Obj.(*gwv1.Gateway); !ok {
+				containerutils.AviLog.Errorf("Tombstone contained object that is not a Gateway: %#v", obj)
+				return
+			}
+		}
+		for _, obj := range c.gwInformers.HTTPRouteInformer.GetIndexer().List() {
+			route, ok := obj.(*gwv1.HTTPRoute)
+			if !ok || !isRouteAttachedToGateway(route, gw) {
+				continue
+			}
+			hrhs := k8sobjects.GetHTTPRouteHostMeta(route, c.gwInformers.getGateway, c.name)
+			syncHTTPRoute(c, numWorkers, route.Namespace, route.Name, hrhs)
+		}
+	}
+	return cache.ResourceEventHandlerFuncs{
+		AddFunc: syncAttachedRoutes,
+		UpdateFunc: func(old, cur interface{}) {
+			oldGw, oldOk := old.(*gwv1.Gateway)
+			curGw, curOk := cur.(*gwv1.Gateway)
+			if oldOk && curOk && oldGw.ResourceVersion == curGw.ResourceVersion {
+				return
+			}
+			syncAttachedRoutes(cur)
+		},
+		DeleteFunc: syncAttachedRoutes,
+	}
+}
+
+// isRouteAttachedToGateway returns true if gw is a parent of route.
+func isRouteAttachedToGateway(route *gwv1.HTTPRoute, gw *gwv1.Gateway) bool {
+	for _, ref := range route.Spec.ParentRefs {
+		if ref.Group != nil && *ref.Group != gwv1.GroupName {
+			continue
+		}
+		if ref.Kind != nil && *ref.Kind != gwv1.KindGateway {
+			continue
+		}
+		ns := route.Namespace
+		if ref.Namespace != nil {
+			ns = *ref.Namespace
+		}
+		if ns == gw.Namespace && ref.Name == gw.Name {
+			return true
+		}
+	}
+	return false
+}
+
+// fetchAndApplyAllHTTPRoutes lists all the HTTPRoutes and Gateways of a member cluster and adds the
+// HTTPRoute host objects to the stores, during the bootup sync.
+func fetchAndApplyAllHTTPRoutes(c *GSLBMemberController) {
+	var gwList gwv1.GatewayList
+	if err := c.gwInformers.client.Get().Resource(gatewayResource).Do().Into(&gwList); err != nil {
+		gslbutils.Errf("process: fullsync, cluster: %s, msg: error in fetching the gateway list, %s", c.name, err)
+		return
+	}
+	var routeList gwv1.HTTPRouteList
+	if err := c.gwInformers.client.Get().Resource(httpRouteResource).Do().Into(&routeList); err != nil {
+		gslbutils.Errf("process: fullsync, cluster: %s, msg: error in fetching the HTTPRoute list, %s", c.name, err)
+		return
+	}
+	getGateway := func(ns, name string) (*gwv1.Gateway, bool) {
+		for i := range gwList.Items {
+			if gwList.Items[i].Namespace == ns && gwList.Items[i].Name == name {
+				return &gwList.Items[i], true
+			}
+		}
+		return nil, false
+	}
+
+	var acceptedItems, rejectedItems []gslbutils.StoreItem
+	for i := range routeList.Items {
+		for _, hrh := range k8sobjects.GetHTTPRouteHostMeta(&routeList.Items[i], getGateway, c.name) {
+			if hrh.IPAddr == "" || hrh.Hostname == "" {
+				gslbutils.Debugf("cluster: %s, ns: %s, httproute: %s, msg: %s", c.name, hrh.Namespace, hrh.ObjName,
+					"rejected HTTPRoute host because IP address/Hostname not found in the gateway status")
+				continue
+			}
+			item := gslbutils.StoreItem{Namespace: hrh.Namespace, ObjName: hrh.ObjName, Obj: hrh}
+			if !filter.ApplyFilter(hrh, c.name) {
+				rejectedItems = append(rejectedItems, item)
+				continue
+			}
+			acceptedItems = append(acceptedItems, item)
+		}
+	}
+	gslbutils.GetAcceptedHTTPRouteStore().AddOrUpdateBatch(c.name, acceptedItems)
+	gslbutils.GetRejectedHTTPRouteStore().AddOrUpdateBatch(c.name, rejectedItems)
+}
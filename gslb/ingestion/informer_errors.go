@@ -0,0 +1,111 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+
+	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/transport"
+)
+
+// informerResources maps the resources listed and watched by the informers of a member cluster to
+// the names of the informers.
+var informerResources = map[string]string{
+	"routes":     "route",
+	"ingresses":  "ingress",
+	"services":   "service",
+	"namespaces": "namespace",
+	"httproutes": "httproute",
+	"gateways":   "gateway",
+}
+
+// informerErrorTransport observes the list and watch requests of the informers of a member cluster,
+// and records the errors of these requests per informer. The informers of the vendored client-go
+// can't be given a watch error handler, their errors are only logged via runtime.HandleError, so
+// the errors are observed on the requests instead.
+type informerErrorTransport struct {
+	cluster string
+	rt      http.RoundTripper
+}
+
+// getInformerForRequest returns the name of the informer which made req, if req lists or watches
+// the objects of an informer.
+func getInformerForRequest(req *http.Request) (string, bool) {
+	if req.Method != http.MethodGet || req.URL == nil {
+		return "", false
+	}
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	// the collections are at /api/<version>/[namespaces/<ns>/]<resource> and at
+	// /apis/<group>/<version>/[namespaces/<ns>/]<resource>
+	prefixLen := 2
+	if segments[0] == "apis" {
+		prefixLen = 3
+	}
+	if len(segments) <= prefixLen {
+		return "", false
+	}
+	resources := segments[prefixLen:]
+	if len(resources) == 3 && resources[0] == "namespaces" {
+		resources = resources[2:]
+	}
+	if len(resources) != 1 {
+		return "", false
+	}
+	informer, ok := informerResources[resources[0]]
+	return informer, ok
+}
+
+// getInformerErrorForStatus returns the error of an informer for the status code of a list or watch
+// request, and false if the status code isn't an error. An expired resource version isn't an error,
+// the informer just lists its objects again.
+func getInformerErrorForStatus(statusCode int) (string, bool) {
+	if statusCode < http.StatusBadRequest || statusCode == http.StatusGone {
+		return "", false
+	}
+	return strings.ToLower(http.StatusText(statusCode)), true
+}
+
+func (t *informerErrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.rt.RoundTrip(req)
+	informer, ok := getInformerForRequest(req)
+	if !ok {
+		return resp, err
+	}
+	if err != nil {
+		// the requests of the informers being stopped are cancelled
+		if req.Context().Err() == nil {
+			gslbutils.RecordInformerError(t.cluster, informer, err.Error())
+		}
+		return resp, err
+	}
+	if errMsg, isErr := getInformerErrorForStatus(resp.StatusCode); isErr {
+		gslbutils.RecordInformerError(t.cluster, informer, errMsg)
+	} else if resp.StatusCode < http.StatusBadRequest {
+		gslbutils.ClearInformerError(t.cluster, informer)
+	}
+	return resp, err
+}
+
+// ObserveInformerErrors sets up cfg of a member cluster to record the errors of its informers in
+// listing or watching their objects.
+func ObserveInformerErrors(cfg *restclient.Config, cluster string) {
+	cfg.WrapTransport = transport.Wrappers(cfg.WrapTransport, func(rt http.RoundTripper) http.RoundTripper {
+		return &informerErrorTransport{cluster: cluster, rt: rt}
+	})
+}
@@ -17,6 +17,7 @@ package ingestion
 import (
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/avinetworks/amko/gslb/k8sobjects"
 
@@ -25,7 +26,9 @@ import (
 	routev1 "github.com/openshift/api/route/v1"
 	containerutils "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
 	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/tools/record"
@@ -40,15 +43,85 @@ type GSLBMemberController struct {
 	worker_id_mutex sync.Mutex
 	informers       *containerutils.Informers
 	workqueue       []workqueue.RateLimitingInterface
+	// resyncPeriods contains the resync periods of the event handlers for each informer type
+	resyncPeriods InformerResyncPeriods
+	// gwInformers are the Gateway API informers, nil if the cluster doesn't serve the Gateway API
+	gwInformers *GatewayInformers
+	// objectTypes are the types of the objects watched in the cluster, as set in the GSLBConfig
+	// object, all the types with informers are watched if empty
+	objectTypes []string
+	// syncFuncs are the sync functions of the informers started for the controller
+	syncFuncs []cache.InformerSynced
 }
 
+// InformerResyncPeriods maps an informer type (containerutils.RouteInformer, containerutils.IngressInformer,
+// containerutils.ServiceInformer, containerutils.NSInformer, GatewayInformer or HTTPRouteInformer) to the
+// resync period for its objects. A resync period of 0 disables the periodic resync for that type. Informer
+// types without an entry are resynced as per the default resync period of the informers.
+type InformerResyncPeriods map[string]time.Duration
+
 // GetAviController sets config for an AviController
-func GetGSLBMemberController(clusterName string, informersInstance *containerutils.Informers) GSLBMemberController {
+func GetGSLBMemberController(clusterName string, informersInstance *containerutils.Informers,
+	resyncPeriods InformerResyncPeriods) GSLBMemberController {
 	return GSLBMemberController{
-		name:      clusterName,
-		worker_id: (uint32(1) << containerutils.NumWorkersIngestion) - 1,
-		informers: informersInstance,
+		name:          clusterName,
+		worker_id:     (uint32(1) << containerutils.NumWorkersIngestion) - 1,
+		informers:     informersInstance,
+		resyncPeriods: resyncPeriods,
+		objectTypes:   gslbutils.GetClusterObjectTypes(clusterName),
+	}
+}
+
+// watchesObjectType returns true if the objects of type objType are watched in the cluster of
+// the controller, the informers of the other types are neither set up nor started.
+func (c *GSLBMemberController) watchesObjectType(objType string) bool {
+	return gslbutils.IsObjectTypeWatched(objType, c.objectTypes)
+}
+
+// quiescedEventHandler locks the names of the member clusters for reading while handler handles an
+// event, so that the cluster of the controller isn't renamed midway, see gslbutils.RenameCluster.
+type quiescedEventHandler struct {
+	handler cache.ResourceEventHandler
+}
+
+func (h quiescedEventHandler) OnAdd(obj interface{}) {
+	gslbutils.RLockClusterNames()
+	defer gslbutils.RUnlockClusterNames()
+	h.handler.OnAdd(obj)
+}
+
+func (h quiescedEventHandler) OnUpdate(oldObj, newObj interface{}) {
+	gslbutils.RLockClusterNames()
+	defer gslbutils.RUnlockClusterNames()
+	h.handler.OnUpdate(oldObj, newObj)
+}
+
+func (h quiescedEventHandler) OnDelete(obj interface{}) {
+	gslbutils.RLockClusterNames()
+	defer gslbutils.RUnlockClusterNames()
+	h.handler.OnDelete(obj)
+}
+
+// addEventHandler adds handler to informer with the resync period configured for informerType.
+// The resync periods must be set before the informers are started, as the informers can't resync
+// more often than the smallest resync period they were started with.
+func (c *GSLBMemberController) addEventHandler(informer cache.SharedIndexInformer, informerType string,
+	handler cache.ResourceEventHandler) {
+	handler = quiescedEventHandler{handler: handler}
+	resyncPeriod, ok := c.resyncPeriods[informerType]
+	if !ok {
+		informer.AddEventHandler(handler)
+		return
 	}
+	gslbutils.Logf("k8scontroller: %s, informer: %s, resyncPeriod: %s, msg: adding event handler with resync period",
+		c.name, informerType, resyncPeriod.String())
+	informer.AddEventHandlerWithResyncPeriod(handler, resyncPeriod)
+}
+
+// SetGatewayInformers sets the Gateway API informers of the member cluster, must be called before
+// the event handlers are set up.
+func (c *GSLBMemberController) SetGatewayInformers(gwInformers *GatewayInformers) {
+	c.gwInformers = gwInformers
 }
 
 func (ctrl GSLBMemberController) GetName() string {
@@ -76,6 +149,7 @@ func DeleteFromRouteStore(clusterRouteStore *gslbutils.ClusterStore,
 	}
 	ns := route.ObjectMeta.Namespace
 	routeName := route.ObjectMeta.Name
+	gslbutils.GetGlobalFilter().ReleaseClusterObj(cname, gslbutils.RouteType, ns, routeName)
 	_, present := clusterRouteStore.DeleteClusterNSObj(cname, ns, routeName)
 	return present
 }
@@ -97,7 +171,8 @@ func DeleteFromIngressStore(clusterIngStore *gslbutils.ClusterStore,
 		// Store is empty, so, noop
 		return false
 	}
-	_, present := clusterIngStore.DeleteClusterNSObj(ingHost.Cluster, ingHost.Namespace, ingHost.ObjName)
+	gslbutils.GetGlobalFilter().ReleaseClusterObj(cname, gslbutils.IngressType, ingHost.Namespace, ingHost.ObjName)
+	_, present := clusterIngStore.DeleteClusterNSObj(cname, ingHost.Namespace, ingHost.ObjName)
 	return present
 }
 
@@ -109,28 +184,35 @@ func (c *GSLBMemberController) SetupEventHandlers(k8sinfo K8SInformers) {
 	eventBroadcaster := record.NewBroadcaster()
 	eventBroadcaster.StartLogging(containerutils.AviLog.Infof)
 	eventBroadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: cs.CoreV1().Events("")})
+	recorder := eventBroadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{Component: gslbutils.AmkoUser})
+	gslbutils.SetClusterEventRecorder(c.name, recorder)
 
 	k8sQueue := containerutils.SharedWorkQueue().GetQueueByName(containerutils.ObjectIngestionLayer)
 	c.workqueue = k8sQueue.Workqueue
 	numWorkers := k8sQueue.NumWorkers
 
-	if c.informers.IngressInformer != nil {
+	if c.informers.IngressInformer != nil && c.watchesObjectType(gslbutils.IngressType) {
 		ingressEventHandler := AddIngressEventHandler(numWorkers, c)
-		c.informers.IngressInformer.Informer().AddEventHandler(ingressEventHandler)
+		c.addEventHandler(c.informers.IngressInformer.Informer(), containerutils.IngressInformer, ingressEventHandler)
 	}
-	if c.informers.RouteInformer != nil {
+	if c.informers.RouteInformer != nil && c.watchesObjectType(gslbutils.RouteType) {
 		routeEventHandler := AddRouteEventHandler(numWorkers, c)
-		c.informers.RouteInformer.Informer().AddEventHandler(routeEventHandler)
+		c.addEventHandler(c.informers.RouteInformer.Informer(), containerutils.RouteInformer, routeEventHandler)
 	}
 
-	if c.informers.ServiceInformer != nil {
+	if c.informers.ServiceInformer != nil && c.watchesObjectType(gslbutils.SvcType) {
 		lbsvcEventHandler := AddLBSvcEventHandler(numWorkers, c)
-		c.informers.ServiceInformer.Informer().AddEventHandler(lbsvcEventHandler)
+		c.addEventHandler(c.informers.ServiceInformer.Informer(), containerutils.ServiceInformer, lbsvcEventHandler)
 	}
 
 	if c.informers.NSInformer != nil {
 		nsEventHandler := AddNamespaceEventHandler(numWorkers, c)
-		c.informers.NSInformer.Informer().AddEventHandler(nsEventHandler)
+		c.addEventHandler(c.informers.NSInformer.Informer(), containerutils.NSInformer, nsEventHandler)
+	}
+
+	if c.gwInformers != nil && c.watchesObjectType(gslbutils.HTTPRouteType) {
+		c.addEventHandler(c.gwInformers.HTTPRouteInformer, HTTPRouteInformer, AddHTTPRouteEventHandler(numWorkers, c))
+		c.addEventHandler(c.gwInformers.GatewayInformer, GatewayInformer, AddGatewayEventHandler(numWorkers, c))
 	}
 }
 
@@ -160,25 +242,67 @@ func DeleteFromLBSvcStore(clusterSvcStore *gslbutils.ClusterStore,
 		// Store is empty, so, noop
 		return
 	}
+	gslbutils.GetGlobalFilter().ReleaseClusterObj(cname, gslbutils.SvcType, svc.ObjectMeta.Namespace, svc.ObjectMeta.Name)
 	clusterSvcStore.DeleteClusterNSObj(cname, svc.ObjectMeta.Namespace, svc.ObjectMeta.Name)
 }
 
-func (c *GSLBMemberController) Start(stopCh <-chan struct{}) {
+// register registers the controller and returns its stop channel. An unregistered controller must
+// not run, so the error is logged and returned to the caller.
+func (c *GSLBMemberController) register(stopCh <-chan struct{}) (<-chan struct{}, error) {
+	ctrlStopCh, err := RegisterMemberController(c, stopCh)
+	if err != nil {
+		gslbutils.Warnf("cluster: %s, msg: couldn't register the member controller, %s", c.name, err.Error())
+		return nil, err
+	}
+	return ctrlStopCh, nil
+}
+
+// Start registers the controller and starts its informers, the informers run till stopCh is
+// closed or the controller is deregistered. Nothing is started if the controller can't be
+// registered, e.g. ErrMemberControllerRegistered is returned if a different controller is
+// registered for the cluster.
+func (c *GSLBMemberController) Start(stopCh <-chan struct{}) error {
+	stopCh, err := c.register(stopCh)
+	if err != nil {
+		return err
+	}
+	c.registerEndpointsGetter()
+	cacheSyncParam := c.startInformers(stopCh)
+	gslbutils.SetClusterInformersSynced(c.name, cacheSyncParam)
+	if !cache.WaitForCacheSync(stopCh, cacheSyncParam...) {
+		runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
+	} else {
+		gslbutils.Logf("cluster: %s, msg: %s", c.name, "caches synced")
+	}
+	return nil
+}
+
+func (c *GSLBMemberController) registerEndpointsGetter() {
+	if clientSet := c.informers.ClientSet; clientSet != nil {
+		RegisterEndpointsGetter(c.name, func(ns, name string) (*corev1.Endpoints, error) {
+			return clientSet.CoreV1().Endpoints(ns).Get(name, metav1.GetOptions{})
+		})
+	}
+}
+
+// startInformers runs the informers of the controller till stopCh is closed, and returns their
+// sync functions.
+func (c *GSLBMemberController) startInformers(stopCh <-chan struct{}) []cache.InformerSynced {
 	var cacheSyncParam []cache.InformerSynced
 
-	if c.informers.IngressInformer != nil {
+	if c.informers.IngressInformer != nil && c.watchesObjectType(gslbutils.IngressType) {
 		gslbutils.Logf("cluster: %s, msg: %s", c.name, "starting Ingress informer")
 		go c.informers.IngressInformer.Informer().Run(stopCh)
 		cacheSyncParam = append(cacheSyncParam, c.informers.IngressInformer.Informer().HasSynced)
 	}
 
-	if c.informers.RouteInformer != nil {
+	if c.informers.RouteInformer != nil && c.watchesObjectType(gslbutils.RouteType) {
 		gslbutils.Logf("cluster: %s, msg: %s", c.name, "starting route informer")
 		go c.informers.RouteInformer.Informer().Run(stopCh)
 		cacheSyncParam = append(cacheSyncParam, c.informers.RouteInformer.Informer().HasSynced)
 	}
 
-	if c.informers.ServiceInformer != nil {
+	if c.informers.ServiceInformer != nil && c.watchesObjectType(gslbutils.SvcType) {
 		gslbutils.Logf("cluster: %s, msg: %s", c.name, "starting service informer")
 		go c.informers.ServiceInformer.Informer().Run(stopCh)
 		cacheSyncParam = append(cacheSyncParam, c.informers.ServiceInformer.Informer().HasSynced)
@@ -190,16 +314,25 @@ func (c *GSLBMemberController) Start(stopCh <-chan struct{}) {
 		cacheSyncParam = append(cacheSyncParam, c.informers.NSInformer.Informer().HasSynced)
 	}
 
-	if !cache.WaitForCacheSync(stopCh, cacheSyncParam...) {
-		runtime.HandleError(fmt.Errorf("Timed out waiting for caches to sync"))
-	} else {
-		gslbutils.Logf("cluster: %s, msg: %s", c.name, "caches synced")
+	if c.gwInformers != nil && c.watchesObjectType(gslbutils.HTTPRouteType) {
+		gslbutils.Logf("cluster: %s, msg: %s", c.name, "starting gateway and HTTPRoute informers")
+		go c.gwInformers.GatewayInformer.Run(stopCh)
+		go c.gwInformers.HTTPRouteInformer.Run(stopCh)
+		cacheSyncParam = append(cacheSyncParam, c.gwInformers.GatewayInformer.HasSynced,
+			c.gwInformers.HTTPRouteInformer.HasSynced)
 	}
+	c.syncFuncs = cacheSyncParam
+	return cacheSyncParam
 }
 
+// Run registers the controller and blocks till stopCh is closed or the controller is deregistered.
 func (c *GSLBMemberController) Run(stopCh <-chan struct{}) error {
 	defer runtime.HandleCrash()
 
+	stopCh, err := c.register(stopCh)
+	if err != nil {
+		return err
+	}
 	gslbutils.Logf("cluster: %s, msg: %s", c.name, "started the kubernetes controller")
 	<-stopCh
 	gslbutils.Logf("cluster: %s, msg: %s", c.name, "shutting down the kubernetes controller")
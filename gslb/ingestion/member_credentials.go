@@ -0,0 +1,336 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+const (
+	// MembersSecretName is the secret with the kubeconfig of the member clusters, the kubeconfig is
+	// also set as the GSLB_CONFIG environment variable.
+	MembersSecretName = "gslb-config-secret"
+	// MembersSecretKey is the key of the kubeconfig in the members secret.
+	MembersSecretKey = "gslb-members"
+	// MembersSecretResyncPeriod is the resync period of the members secret informer, the reloads
+	// which failed are retried on a resync.
+	MembersSecretResyncPeriod = 60 * time.Second
+	// MemberReloadSyncTimeout is how long the informers of a reloaded member cluster are waited
+	// for to sync, the existing informers of the cluster are retained if they don't sync in time.
+	MemberReloadSyncTimeout = 120 * time.Second
+)
+
+// memberCredentials are the checksums of the credentials used by the member controllers, keyed by
+// the cluster contexts. The reload lock serializes the reloads of the member clusters, and is held
+// while the new informers sync (for at most MemberReloadSyncTimeout per cluster), unlike the lock of
+// the checksums.
+var memberCredentials = struct {
+	checksums  map[string]uint32
+	lock       sync.Mutex
+	reloadLock sync.Mutex
+}{checksums: make(map[string]uint32)}
+
+// membersKubeConfigLock guards membersKubeConfig and the kubeconfig file written from it.
+var membersKubeConfigLock sync.RWMutex
+
+// setMembersKubeConfig sets the kubeconfig of the member clusters and writes it to the GSLB
+// kubeconfig path.
+func setMembersKubeConfig(kubeconfig string) error {
+	membersKubeConfigLock.Lock()
+	defer membersKubeConfigLock.Unlock()
+	if err := writeKubeConfig(kubeconfig); err != nil {
+		return err
+	}
+	membersKubeConfig = kubeconfig
+	return nil
+}
+
+// getMembersKubeConfig returns the kubeconfig of the member clusters.
+func getMembersKubeConfig() string {
+	membersKubeConfigLock.RLock()
+	defer membersKubeConfigLock.RUnlock()
+	return membersKubeConfig
+}
+
+// getCredentialChecksums returns the checksums of the credentials of the contexts in kubeconfig,
+// i.e. of the cluster and the user of each context.
+func getCredentialChecksums(kubeconfig string) (map[string]uint32, error) {
+	cfg, err := clientcmd.Load([]byte(kubeconfig))
+	if err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]uint32, len(cfg.Contexts))
+	for name, context := range cfg.Contexts {
+		credentials := struct {
+			Cluster   interface{}
+			User      interface{}
+			Namespace string
+		}{cfg.Clusters[context.Cluster], cfg.AuthInfos[context.AuthInfo], context.Namespace}
+		data, err := json.Marshal(credentials)
+		if err != nil {
+			return nil, err
+		}
+		checksums[name] = utils.Hash(string(data))
+	}
+	return checksums, nil
+}
+
+// SetMemberCredentials records the credentials of the member clusters in kubeconfig as the ones
+// used by their member controllers.
+func SetMemberCredentials(kubeconfig string, clusters []string) error {
+	checksums, err := getCredentialChecksums(kubeconfig)
+	if err != nil {
+		return err
+	}
+	memberCredentials.lock.Lock()
+	defer memberCredentials.lock.Unlock()
+	memberCredentials.checksums = make(map[string]uint32, len(clusters))
+	for _, cname := range clusters {
+		memberCredentials.checksums[cname] = checksums[cname]
+	}
+	return nil
+}
+
+// GetChangedMemberClusters returns the sorted member clusters whose credentials in kubeconfig differ
+// from the ones used by their member controllers.
+func GetChangedMemberClusters(kubeconfig string) ([]string, error) {
+	checksums, err := getCredentialChecksums(kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+	memberCredentials.lock.Lock()
+	defer memberCredentials.lock.Unlock()
+	return getChangedMemberClusters(checksums), nil
+}
+
+func getChangedMemberClusters(checksums map[string]uint32) []string {
+	var changed []string
+	for cname, checksum := range memberCredentials.checksums {
+		if checksums[cname] != checksum {
+			changed = append(changed, cname)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// ReloadMemberClusters reloads the member clusters whose credentials changed in kubeconfig. The
+// informers of such a cluster are stopped and new ones are started with the new credentials. Once
+// the new informers sync, the existing ones are stopped and the objects in the stores which are no
+// longer in the cluster are deleted, as their delete events may have been missed while the informers
+// were replaced. If a cluster can't be initialized with the new credentials, or its new informers
+// don't sync within MemberReloadSyncTimeout, its existing informers are left running and the reload
+// is tried again on the next call. The clusters which are yet to be initialized pick up the new
+// credentials on their next retry. Returns the clusters which were reloaded.
+func ReloadMemberClusters(kubeconfig string) ([]string, error) {
+	checksums, err := getCredentialChecksums(kubeconfig)
+	if err != nil {
+		return nil, errors.New("invalid kubeconfig for the member clusters: " + err.Error())
+	}
+	memberCredentials.reloadLock.Lock()
+	defer memberCredentials.reloadLock.Unlock()
+
+	memberCredentials.lock.Lock()
+	changed := getChangedMemberClusters(checksums)
+	memberCredentials.lock.Unlock()
+	if len(changed) == 0 {
+		return nil, nil
+	}
+	if err := setMembersKubeConfig(kubeconfig); err != nil {
+		return nil, err
+	}
+
+	resyncPeriods := GetInformerResyncPeriods()
+	var reloaded, failed []string
+	for _, cname := range changed {
+		if _, ok := GetMemberController(cname); !ok {
+			gslbutils.Logf("cluster: %s, msg: credentials changed for a cluster not initialized yet, will be used on the next retry",
+				cname)
+			setMemberCredentialChecksum(cname, checksums[cname])
+			continue
+		}
+		cluster := kubeClusterDetails{cname, gslbutils.GSLBKubePath, "", nil, gslbutils.GetClusterRegion(cname)}
+		aviCtrl, err := initializeGSLBCluster(cluster, resyncPeriods)
+		if err != nil {
+			gslbutils.Warnf("cluster: %s, msg: couldn't reload the cluster with the new credentials, will continue with the existing informers, %s",
+				cname, err)
+			failed = append(failed, cname)
+			continue
+		}
+		if err := ReplaceMemberController(aviCtrl, stopCh, MemberReloadSyncTimeout); err != nil {
+			gslbutils.Warnf("cluster: %s, msg: couldn't replace the member controller, will continue with the existing informers, %s",
+				cname, err)
+			failed = append(failed, cname)
+			continue
+		}
+		setMemberCredentialChecksum(cname, checksums[cname])
+		deleted := DeleteStaleClusterObjs(aviCtrl)
+		reloaded = append(reloaded, cname)
+		gslbutils.Logf("cluster: %s, deleted: %d, msg: reloaded the cluster with the new credentials", cname, deleted)
+	}
+	if len(failed) != 0 {
+		return reloaded, errors.New("couldn't reload the clusters " + strings.Join(failed, ", "))
+	}
+	return reloaded, nil
+}
+
+func setMemberCredentialChecksum(cname string, checksum uint32) {
+	memberCredentials.lock.Lock()
+	defer memberCredentials.lock.Unlock()
+	memberCredentials.checksums[cname] = checksum
+}
+
+// getInformerIndexer returns the indexer of the informer of objType of the controller, false if the
+// controller doesn't watch objType.
+func (c *GSLBMemberController) getInformerIndexer(objType string) (cache.Indexer, bool) {
+	if !c.watchesObjectType(objType) {
+		return nil, false
+	}
+	switch objType {
+	case gslbutils.RouteType:
+		if c.informers.RouteInformer != nil {
+			return c.informers.RouteInformer.Informer().GetIndexer(), true
+		}
+	case gslbutils.IngressType:
+		if c.informers.IngressInformer != nil {
+			return c.informers.IngressInformer.Informer().GetIndexer(), true
+		}
+	case gslbutils.SvcType:
+		if c.informers.ServiceInformer != nil {
+			return c.informers.ServiceInformer.Informer().GetIndexer(), true
+		}
+	case gslbutils.HTTPRouteType:
+		if c.gwInformers != nil {
+			return c.gwInformers.HTTPRouteInformer.GetIndexer(), true
+		}
+	}
+	return nil, false
+}
+
+// getKubeObjName returns the name of the kubernetes object of a meta object, the meta objects of
+// the ingresses and the HTTPRoutes are per host.
+func getKubeObjName(metaObj k8sobjects.MetaObject) string {
+	switch obj := metaObj.(type) {
+	case k8sobjects.IngressHostMeta:
+		return obj.IngName
+	case k8sobjects.HTTPRouteHostMeta:
+		return obj.RouteName
+	}
+	return metaObj.GetName()
+}
+
+// DeleteStaleClusterObjs deletes the objects of the cluster of the controller from the accepted and
+// the rejected stores, if they aren't in the caches of its informers. DELETE keys are added for the
+// accepted objects. Must be called after the caches of the informers sync. Returns the number of the
+// objects deleted.
+func DeleteStaleClusterObjs(c *GSLBMemberController) int {
+	k8sQueue := utils.SharedWorkQueue().GetQueueByName(utils.ObjectIngestionLayer)
+	deleted := 0
+	for _, objType := range gslbutils.GetObjTypes() {
+		indexer, ok := c.getInformerIndexer(objType)
+		if !ok {
+			continue
+		}
+		objKey, acceptedStore, rejectedStore, err := GetObjTypeStores(objType)
+		if err != nil {
+			continue
+		}
+		for _, store := range []*gslbutils.ClusterStore{acceptedStore, rejectedStore} {
+			if store == nil {
+				continue
+			}
+			for _, nsObj := range store.GetClusterStore(c.name).GetAllNSObjects() {
+				segments, err := gslbutils.SplitKey(nsObj)
+				if err != nil || len(segments) != 2 {
+					continue
+				}
+				ns, objName := segments[0], segments[1]
+				obj, found := store.GetClusterNSObjectByName(c.name, ns, objName)
+				if !found {
+					continue
+				}
+				metaObj := obj.(k8sobjects.MetaObject)
+				if _, exists, err := indexer.GetByKey(ns + "/" + getKubeObjName(metaObj)); err != nil || exists {
+					continue
+				}
+				store.DeleteClusterNSObj(c.name, ns, objName)
+				gslbutils.DeleteRejectedObj(objType, c.name, ns, objName)
+				deleted++
+				if store != acceptedStore {
+					continue
+				}
+				gslbutils.GetGlobalFilter().ReleaseClusterObj(c.name, objType, ns, objName)
+				gslbutils.Logf("cluster: %s, ns: %s, objType: %s, name: %s, msg: object not in the cluster after the reload",
+					c.name, ns, objType, objName)
+				publishKeyToGraphLayer(k8sQueue.NumWorkers, objKey, c.name, ns, objName, gslbutils.ObjectDelete,
+					metaObj.GetHostname(), k8sQueue.Workqueue)
+			}
+		}
+	}
+	return deleted
+}
+
+// reloadFromMembersSecret reloads the member clusters from the kubeconfig in the members secret.
+func reloadFromMembersSecret(obj interface{}) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok || secret.Name != MembersSecretName {
+		return
+	}
+	kubeconfig, ok := secret.Data[MembersSecretKey]
+	if !ok {
+		gslbutils.Warnf("ns: %s, secret: %s, msg: key %s not found in the secret, won't reload the member clusters",
+			secret.Namespace, secret.Name, MembersSecretKey)
+		return
+	}
+	if _, err := ReloadMemberClusters(string(kubeconfig)); err != nil {
+		gslbutils.Warnf("ns: %s, secret: %s, msg: %s", secret.Namespace, secret.Name, err.Error())
+	}
+}
+
+// StartMembersSecretInformer starts an informer for the members secret, the member clusters are
+// reloaded as and when their credentials in the secret change.
+func StartMembersSecretInformer(kubeClient kubernetes.Interface, stopCh <-chan struct{}) {
+	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, MembersSecretResyncPeriod,
+		kubeinformers.WithNamespace(gslbutils.AVISystem),
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", MembersSecretName).String()
+		}))
+	secretInformer := informerFactory.Core().V1().Secrets().Informer()
+	secretInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: reloadFromMembersSecret,
+		UpdateFunc: func(old, cur interface{}) {
+			reloadFromMembersSecret(cur)
+		},
+	})
+	gslbutils.Logf("ns: %s, secret: %s, msg: starting the members secret informer", gslbutils.AVISystem, MembersSecretName)
+	go secretInformer.Run(stopCh)
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"k8s.io/client-go/tools/cache"
+)
+
+// ErrMemberControllerRegistered is returned when a member controller is registered for a cluster
+// which already has a different member controller registered.
+var ErrMemberControllerRegistered = errors.New("a different member controller is registered for the cluster")
+
+type registeredMemberController struct {
+	ctrl     *GSLBMemberController
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func (r *registeredMemberController) stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopCh)
+	})
+}
+
+// memberControllers are the member controllers started so far, keyed by the cluster name.
+var memberControllers = struct {
+	controllers map[string]*registeredMemberController
+	lock        sync.Mutex
+}{controllers: make(map[string]*registeredMemberController)}
+
+// RegisterMemberController registers member controller c against its cluster name, and returns the
+// stop channel of the controller. The stop channel is closed when stopCh is closed, which deregisters
+// the controller as well, or when the controller is deregistered. Registering a controller again
+// returns its existing stop channel. To replace the controller of a cluster, the existing one must be
+// deregistered first.
+func RegisterMemberController(c *GSLBMemberController, stopCh <-chan struct{}) (<-chan struct{}, error) {
+	memberControllers.lock.Lock()
+	defer memberControllers.lock.Unlock()
+
+	if existing, ok := memberControllers.controllers[c.name]; ok {
+		if existing.ctrl != c {
+			return nil, ErrMemberControllerRegistered
+		}
+		return existing.stopCh, nil
+	}
+	registered := &registeredMemberController{ctrl: c, stopCh: make(chan struct{})}
+	memberControllers.controllers[c.name] = registered
+	go deregisterOnStop(registered, stopCh)
+	gslbutils.Logf("cluster: %s, msg: registered the member controller", c.name)
+	return registered.stopCh, nil
+}
+
+// deregisterOnStop deregisters the registered controller once stopCh is closed.
+func deregisterOnStop(registered *registeredMemberController, stopCh <-chan struct{}) {
+	select {
+	case <-stopCh:
+		// the cluster may have been renamed since the registration
+		memberControllers.lock.Lock()
+		cname := registered.ctrl.name
+		memberControllers.lock.Unlock()
+		deregisterMemberController(cname, registered)
+	case <-registered.stopCh:
+	}
+}
+
+// ReplaceMemberController replaces the registered member controller of the cluster of c with c.
+// The informers of c are started and the existing controller keeps running till they sync, it is
+// stopped only after c is registered in its place. If the informers of c don't sync within
+// timeout, c is stopped and the existing controller is retained. Returns an error if the informers
+// didn't sync, or if the cluster has no registered controller.
+func ReplaceMemberController(c *GSLBMemberController, stopCh <-chan struct{}, timeout time.Duration) error {
+	cname := c.name
+	memberControllers.lock.Lock()
+	existing, ok := memberControllers.controllers[cname]
+	memberControllers.lock.Unlock()
+	if !ok {
+		return errors.New("no member controller is registered for the cluster")
+	}
+
+	// the readiness of the cluster is as per the informers of the existing controller till c is
+	// registered
+	gslbutils.SetClusterInformersSynced(cname, existing.ctrl.syncFuncs)
+	registered := &registeredMemberController{ctrl: c, stopCh: make(chan struct{})}
+	syncFuncs := c.startInformers(registered.stopCh)
+	if !waitForCacheSync(stopCh, timeout, syncFuncs) {
+		registered.stop()
+		return errors.New("timed out waiting for the caches of the new informers to sync")
+	}
+
+	memberControllers.lock.Lock()
+	if memberControllers.controllers[cname] != existing {
+		memberControllers.lock.Unlock()
+		registered.stop()
+		return errors.New("the member controller of the cluster changed while the new informers synced")
+	}
+	memberControllers.controllers[cname] = registered
+	memberControllers.lock.Unlock()
+	go deregisterOnStop(registered, stopCh)
+
+	existing.stop()
+	c.registerEndpointsGetter()
+	gslbutils.SetClusterInformersSynced(cname, syncFuncs)
+	gslbutils.Logf("cluster: %s, msg: replaced the member controller", cname)
+	return nil
+}
+
+// waitForCacheSync waits for syncFuncs till timeout, or till stopCh is closed. Returns true if all
+// of them synced.
+func waitForCacheSync(stopCh <-chan struct{}, timeout time.Duration, syncFuncs []cache.InformerSynced) bool {
+	waitCh := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(waitCh)
+		select {
+		case <-stopCh:
+		case <-time.After(timeout):
+		case <-done:
+		}
+	}()
+	return cache.WaitForCacheSync(waitCh, syncFuncs...)
+}
+
+// DeregisterMemberController stops the member controller of cluster cname and removes it from the
+// registry. Returns false if no controller is registered for the cluster.
+func DeregisterMemberController(cname string) bool {
+	memberControllers.lock.Lock()
+	registered, ok := memberControllers.controllers[cname]
+	memberControllers.lock.Unlock()
+
+	if !ok {
+		return false
+	}
+	return deregisterMemberController(cname, registered)
+}
+
+// deregisterMemberController stops the member controller registered as registered for cluster
+// cname and removes it from the registry. Returns false if a different controller (or none) is
+// registered for the cluster by now.
+func deregisterMemberController(cname string, registered *registeredMemberController) bool {
+	memberControllers.lock.Lock()
+	if memberControllers.controllers[cname] != registered {
+		memberControllers.lock.Unlock()
+		return false
+	}
+	delete(memberControllers.controllers, cname)
+	memberControllers.lock.Unlock()
+
+	registered.stop()
+	DeregisterEndpointsGetter(cname)
+	gslbutils.ClearClusterInformerErrors(cname)
+	gslbutils.DeleteClusterRejectedObjs(cname)
+	gslbutils.Logf("cluster: %s, msg: stopped and deregistered the member controller", cname)
+	return true
+}
+
+// StopAll stops and deregisters all the registered member controllers, it is safe to call more
+// than once.
+func StopAll() {
+	for _, cname := range GetMemberControllerNames() {
+		DeregisterMemberController(cname)
+	}
+}
+
+// GetMemberController returns the member controller registered for cluster cname.
+func GetMemberController(cname string) (*GSLBMemberController, bool) {
+	memberControllers.lock.Lock()
+	defer memberControllers.lock.Unlock()
+	registered, ok := memberControllers.controllers[cname]
+	if !ok {
+		return nil, false
+	}
+	return registered.ctrl, true
+}
+
+// GetMemberControllerNames returns the sorted cluster names of the registered member controllers.
+func GetMemberControllerNames() []string {
+	memberControllers.lock.Lock()
+	defer memberControllers.lock.Unlock()
+	names := make([]string, 0, len(memberControllers.controllers))
+	for cname := range memberControllers.controllers {
+		names = append(names, cname)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func init() {
+	gslbutils.RegisterClusterRenameHook(renameMemberController)
+}
+
+// renameMemberController re-keys the member controller of cluster oldName, and the state saved for
+// it by the ingestion layer, to newName. The controller is renamed in place, its event handlers pick
+// up newName with their next event, as the ingestion is quiesced during the rename.
+func renameMemberController(oldName, newName string) {
+	memberControllers.lock.Lock()
+	if registered, ok := memberControllers.controllers[oldName]; ok {
+		delete(memberControllers.controllers, oldName)
+		registered.ctrl.name = newName
+		memberControllers.controllers[newName] = registered
+	}
+	memberControllers.lock.Unlock()
+
+	clusterEndpoints.lock.Lock()
+	if getter, ok := clusterEndpoints.getters[oldName]; ok {
+		delete(clusterEndpoints.getters, oldName)
+		clusterEndpoints.getters[newName] = getter
+	}
+	clusterEndpoints.lock.Unlock()
+
+	memberCredentials.lock.Lock()
+	if checksum, ok := memberCredentials.checksums[oldName]; ok {
+		delete(memberCredentials.checksums, oldName)
+		memberCredentials.checksums[newName] = checksum
+	}
+	memberCredentials.lock.Unlock()
+	gslbutils.Logf("cluster: %s, newName: %s, msg: renamed the member controller", oldName, newName)
+}
@@ -0,0 +1,180 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+
+	gdpalphav1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	// TrafficSplitConfigMapName is the ConfigMap in the AVISystem namespace with the traffic split
+	// which overrides the traffic split of the GDP object.
+	TrafficSplitConfigMapName = "amko-traffic-split"
+	// TrafficSplitConfigMapKey is the key of the traffic split in the traffic split ConfigMap, the
+	// value is a JSON list in the format of the trafficSplit of the GDP object.
+	TrafficSplitConfigMapKey = "trafficSplit"
+	// TrafficSplitConfigMapResyncPeriod is the resync period of the traffic split ConfigMap informer.
+	TrafficSplitConfigMapResyncPeriod = 60 * time.Second
+)
+
+// ParseTrafficSplitConfigMap returns the traffic split in the traffic split ConfigMap cm, validated
+// as the traffic split of a GDP object with weightMode.
+func ParseTrafficSplitConfigMap(cm *corev1.ConfigMap, weightMode string) ([]gdpalphav1.TrafficSplitElem, error) {
+	val, ok := cm.Data[TrafficSplitConfigMapKey]
+	if !ok {
+		return nil, errors.New("key " + TrafficSplitConfigMapKey + " not found in the ConfigMap")
+	}
+	var trafficSplit []gdpalphav1.TrafficSplitElem
+	decoder := json.NewDecoder(bytes.NewReader([]byte(val)))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&trafficSplit); err != nil {
+		return nil, errors.New("invalid traffic split: " + err.Error())
+	}
+	if len(trafficSplit) == 0 {
+		return nil, errors.New("traffic split must have at least one cluster")
+	}
+	clusters := make(map[string]bool, len(trafficSplit))
+	for _, ts := range trafficSplit {
+		if ts.Cluster == "" {
+			return nil, errors.New("empty cluster in the traffic split")
+		}
+		if clusters[ts.Cluster] {
+			return nil, errors.New("cluster " + ts.Cluster + " repeated in the traffic split")
+		}
+		clusters[ts.Cluster] = true
+	}
+	if err := validTrafficSplit(trafficSplit, weightMode); err != nil {
+		return nil, err
+	}
+	return trafficSplit, nil
+}
+
+// getFilterWeightMode returns the weight mode of the accepted GDP object, as per the filter.
+func getFilterWeightMode() string {
+	if mode := gslbutils.GetGlobalFilter().GetWeightMode(); mode != "" {
+		return mode
+	}
+	return gdpalphav1.WeightModeWeight
+}
+
+// getWeightMode returns the weight mode of gdp, the default weight mode if gdp is nil.
+func getWeightMode(gdp *gdpalphav1.GlobalDeploymentPolicy) string {
+	if gdp == nil || gdp.Spec.WeightMode == "" {
+		return gdpalphav1.WeightModeWeight
+	}
+	return gdp.Spec.WeightMode
+}
+
+// checkTrafficSplitOverride removes the traffic split override if it isn't valid for the weight mode
+// of gdp, the traffic split of gdp applies then, till the traffic split ConfigMap is fixed.
+func checkTrafficSplitOverride(gdp *gdpalphav1.GlobalDeploymentPolicy) {
+	trafficSplit, ok := gslbutils.GetTrafficSplitOverride()
+	if !ok {
+		return
+	}
+	if err := validTrafficSplit(trafficSplit, getWeightMode(gdp)); err != nil {
+		gslbutils.Warnf("ns: %s, configmap: %s, msg: traffic split override not valid for the GDP object, will use the traffic split of the GDP object, %s",
+			gslbutils.AVISystem, TrafficSplitConfigMapName, err.Error())
+		gslbutils.ClearTrafficSplitOverride()
+	}
+}
+
+// applyTrafficSplitOverride applies the traffic split override to the filter. Only the traffic split
+// of the filter is rebuilt, the rest of the filter stays as per the accepted GDP object. The member
+// ratios of the accepted objects are updated if the traffic weights changed.
+func applyTrafficSplitOverride(k8swq []workqueue.RateLimitingInterface, numWorkers uint32) {
+	changes := gslbutils.GetGlobalFilter().ApplyTrafficSplitOverride()
+	if changes.Has(gslbutils.FilterChangeTraffic) && k8swq != nil {
+		gslbutils.Logf("ns: %s, configmap: %s, msg: traffic split changed, will update the member ratios",
+			gslbutils.AVISystem, TrafficSplitConfigMapName)
+		WriteRatioUpdatesToQueue(k8swq, numWorkers)
+	}
+}
+
+// AddOrUpdateTrafficSplitConfigMap sets the traffic split in the traffic split ConfigMap as the
+// traffic split override and applies it. An invalid ConfigMap removes the override.
+func AddOrUpdateTrafficSplitConfigMap(obj interface{}, k8swq []workqueue.RateLimitingInterface, numWorkers uint32) {
+
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm.Name != TrafficSplitConfigMapName {
+		return
+	}
+	trafficSplit, err := ParseTrafficSplitConfigMap(cm, getFilterWeightMode())
+	if err != nil {
+		gslbutils.Errf("ns: %s, configmap: %s, msg: invalid traffic split ConfigMap, will use the traffic split of the GDP object, %s",
+			cm.Namespace, cm.Name, err.Error())
+		gslbutils.ClearTrafficSplitOverride()
+	} else {
+		gslbutils.Logf("ns: %s, configmap: %s, trafficSplit: %v, msg: traffic split override set", cm.Namespace,
+			cm.Name, trafficSplit)
+		gslbutils.SetTrafficSplitOverride(trafficSplit)
+	}
+	applyTrafficSplitOverride(k8swq, numWorkers)
+}
+
+// DeleteTrafficSplitConfigMap removes the traffic split override, the traffic split of the GDP object
+// applies again.
+func DeleteTrafficSplitConfigMap(k8swq []workqueue.RateLimitingInterface, numWorkers uint32) {
+
+	if _, ok := gslbutils.GetTrafficSplitOverride(); !ok {
+		return
+	}
+	gslbutils.Logf("ns: %s, configmap: %s, msg: traffic split ConfigMap deleted, will use the traffic split of the GDP object",
+		gslbutils.AVISystem, TrafficSplitConfigMapName)
+	gslbutils.ClearTrafficSplitOverride()
+	applyTrafficSplitOverride(k8swq, numWorkers)
+}
+
+// StartTrafficSplitConfigMapInformer starts an informer for the traffic split ConfigMap, the traffic
+// split override is set, updated and removed along with the ConfigMap.
+func StartTrafficSplitConfigMapInformer(kubeClient kubernetes.Interface, k8swq []workqueue.RateLimitingInterface,
+	numWorkers uint32, stopCh <-chan struct{}) {
+
+	informerFactory := kubeinformers.NewSharedInformerFactoryWithOptions(kubeClient, TrafficSplitConfigMapResyncPeriod,
+		kubeinformers.WithNamespace(gslbutils.AVISystem),
+		kubeinformers.WithTweakListOptions(func(options *metav1.ListOptions) {
+			options.FieldSelector = fields.OneTermEqualSelector("metadata.name", TrafficSplitConfigMapName).String()
+		}))
+	cmInformer := informerFactory.Core().V1().ConfigMaps().Informer()
+	cmInformer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			AddOrUpdateTrafficSplitConfigMap(obj, k8swq, numWorkers)
+		},
+		UpdateFunc: func(old, cur interface{}) {
+			AddOrUpdateTrafficSplitConfigMap(cur, k8swq, numWorkers)
+		},
+		DeleteFunc: func(obj interface{}) {
+			DeleteTrafficSplitConfigMap(k8swq, numWorkers)
+		},
+	})
+	gslbutils.Logf("ns: %s, configmap: %s, msg: starting the traffic split ConfigMap informer", gslbutils.AVISystem,
+		TrafficSplitConfigMapName)
+	go cmInformer.Run(stopCh)
+}
@@ -0,0 +1,207 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package k8sobjects
+
+import (
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// applyGlobalFilter evaluates the global filter for any meta object. The decision is logged and
+// sent to the filter observers along with the reason.
+func applyGlobalFilter(obj MetaObject) bool {
+	accepted, reason := evaluateGlobalFilter(obj)
+	if accepted {
+		accepted, reason = applyAcceptancePredicates(obj, reason)
+	}
+	return applyFilterDecision(obj, accepted, reason, "")
+}
+
+// applyAcceptancePredicates evaluates the registered acceptance predicates for obj, which was
+// accepted by the built-in checks for reason.
+func applyAcceptancePredicates(obj MetaObject, reason string) (bool, string) {
+	if !gslbutils.HasAcceptancePredicates() {
+		return true, reason
+	}
+	accepted, predicateReason := gslbutils.EvaluateAcceptancePredicates(GetAcceptanceMeta(obj))
+	if !accepted {
+		return false, predicateReason
+	}
+	return true, reason
+}
+
+// GetAcceptanceMeta returns the view of obj passed to the acceptance predicates.
+func GetAcceptanceMeta(obj MetaObject) gslbutils.AcceptanceMeta {
+	return gslbutils.AcceptanceMeta{
+		ObjType:   obj.GetType(),
+		Cluster:   obj.GetCluster(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Hostname:  obj.GetHostname(),
+		Labels:    copyLabels(obj.GetLabels()),
+	}
+}
+
+// applyFilterDecision applies the object limit of the cluster to a filter decision for obj, logs the
+// final decision and sends it to the filter observers.
+func applyFilterDecision(obj MetaObject, accepted bool, reason, eventReason string) bool {
+	accepted, reason, eventReason = applyClusterObjLimit(obj, accepted, reason, eventReason)
+	objType, cname, ns, name := obj.GetType(), obj.GetCluster(), obj.GetNamespace(), obj.GetName()
+	gslbutils.Debugf("objType: %s, cluster: %s, namespace: %s, name: %s, msg: %s", objType, cname, ns, name, reason)
+	notifyFilterDecision(obj, accepted, reason, eventReason)
+	return accepted
+}
+
+// applyClusterObjLimit counts an object accepted by the filter against the object limit of its
+// cluster, the object is rejected if the cluster has reached its limit. A rejected object is
+// removed from the count, so that the limit is a live ceiling on the accepted objects. The rejection
+// for the limit is recorded with the ClusterObjLimitExceeded event reason.
+func applyClusterObjLimit(obj MetaObject, accepted bool, reason, eventReason string) (bool, string, string) {
+	gf := gslbutils.GetGlobalFilter()
+	objType, cname, ns, name := obj.GetType(), obj.GetCluster(), obj.GetNamespace(), obj.GetName()
+	if !accepted {
+		gf.ReleaseClusterObj(cname, objType, ns, name)
+		return accepted, reason, eventReason
+	}
+	if gf.AdmitClusterObj(cname, objType, ns, name) {
+		return accepted, reason, eventReason
+	}
+	msg := "cluster object limit exceeded"
+	gslbutils.Warnf("objType: %s, cluster: %s, namespace: %s, name: %s, limit: %d, msg: %s", objType, cname, ns,
+		name, gf.GetClusterObjLimit(), msg)
+	return false, msg, gslbutils.ClusterObjLimitExceeded
+}
+
+// RejectIfExplicitlyDisabled rejects obj if it has the deny label, before any other checks of the
+// filter. The decision is logged and sent to the filter observers. Returns false if obj is not a
+// meta object with the deny label.
+func RejectIfExplicitlyDisabled(obj interface{}) bool {
+	metaObj, ok := obj.(MetaObject)
+	if !ok {
+		return false
+	}
+	check := gslbutils.CheckDenyLabel(metaObj.GetLabels())
+	if check.Passed {
+		return false
+	}
+	applyFilterDecision(metaObj, false, check.Reason(), "")
+	return true
+}
+
+// EmptyHostnameReason is the reason of the filter decision for the objects without a hostname.
+const EmptyHostnameReason = gslbutils.EmptyHostnameReason
+
+// RejectIfEmptyHostname rejects obj if it doesn't have a hostname, e.g. a passthrough route without
+// a host, since a GSLB service can't be built for it. The decision is logged, sent to the filter
+// observers and recorded as an EmptyHostname warning event on the object. Returns false if obj is
+// not a meta object without a hostname.
+func RejectIfEmptyHostname(obj interface{}) bool {
+	metaObj, ok := obj.(MetaObject)
+	if !ok {
+		return false
+	}
+	check := gslbutils.CheckHostname(metaObj.GetHostname())
+	if check.Passed {
+		return false
+	}
+	objType, cname, ns, name := metaObj.GetType(), metaObj.GetCluster(), metaObj.GetNamespace(), metaObj.GetName()
+	gslbutils.Warnf("objType: %s, cluster: %s, namespace: %s, name: %s, msg: object has no hostname", objType,
+		cname, ns, name)
+	applyFilterDecision(metaObj, false, check.Reason(), gslbutils.EmptyHostname)
+	return true
+}
+
+// RejectIfNonRoutableIP rejects obj if any of its IP addresses is non-routable, as per the
+// disallowed ranges of the non-routable IP filter. All the VIPs of the objects exposing more than
+// one VIP are checked. The decision is logged, sent to the filter observers and recorded as a
+// NonRoutableIP warning event on the object. Returns false if obj is not a meta object with a
+// non-routable IP address, the objects without an IP address aren't checked.
+func RejectIfNonRoutableIP(obj interface{}) bool {
+	metaObj, ok := obj.(MetaObject)
+	if !ok {
+		return false
+	}
+	check := gslbutils.CheckRoutableIPs(gslbutils.GetFilterObjectIPs(metaObj))
+	if check.Passed {
+		return false
+	}
+	objType, cname, ns, name := metaObj.GetType(), metaObj.GetCluster(), metaObj.GetNamespace(), metaObj.GetName()
+	gslbutils.Warnf("objType: %s, cluster: %s, namespace: %s, name: %s, ip: %s, msg: object has a non-routable IP",
+		objType, cname, ns, name, check.Actual)
+	applyFilterDecision(metaObj, false, check.Reason(), gslbutils.NonRoutableIP)
+	return true
+}
+
+// getObjUID returns the UID of obj, empty if obj doesn't carry the UID of its object.
+func getObjUID(obj interface{}) types.UID {
+	if uidObj, ok := obj.(UIDObject); ok {
+		return uidObj.GetUID()
+	}
+	return ""
+}
+
+// NotifyFilterDecision records the filter decision for obj, a meta object or a namespace meta
+// object, in the cache of the rejected objects, and sends it to the filter observers. The decision
+// for a meta object is recorded as an event on the object as well.
+func NotifyFilterDecision(obj interface{}, accepted bool, reason string) {
+	notifyFilterDecision(obj, accepted, reason, "")
+}
+
+// notifyFilterDecision notifies the filter decision for obj, a rejection is recorded as a warning
+// event with eventReason, if set.
+func notifyFilterDecision(obj interface{}, accepted bool, reason, eventReason string) {
+	decision := gslbutils.FilterDecision{
+		Accepted:    accepted,
+		Reason:      reason,
+		EventReason: eventReason,
+	}
+	switch o := obj.(type) {
+	case MetaObject:
+		decision.ObjType, decision.Cluster, decision.Namespace, decision.Name = o.GetType(), o.GetCluster(),
+			o.GetNamespace(), o.GetName()
+		decision.Key = gslbutils.GetClusterKey(decision.Cluster, decision.Namespace, decision.Name)
+		decision.UID = getObjUID(o)
+	case NSMeta:
+		decision.ObjType, decision.Cluster, decision.Name = o.GetType(), o.GetCluster(), o.GetName()
+		decision.Key = gslbutils.JoinKey(decision.Cluster, decision.Name)
+	default:
+		return
+	}
+	gslbutils.RecordRejectedObj(decision)
+	if _, ok := obj.(MetaObject); ok {
+		gslbutils.RecordFilterDecisionEvent(decision)
+	}
+	if gslbutils.HasFilterObservers() {
+		gslbutils.NotifyFilterObservers(decision)
+	}
+}
+
+// evaluateGlobalFilter returns the decision of the global filter for an object, and the reason
+// for it, as evaluated by the checks of the global filter (see GlobalFilter.EvaluateObject). The
+// same checks explain the decision via the debug endpoint.
+func evaluateGlobalFilter(obj MetaObject) (bool, string) {
+	fe := gslbutils.GetGlobalFilter().EvaluateObject(obj)
+	return fe.Accepted, fe.Reason
+}
+
+// copyLabels returns a copy of the labels map, so that the callers can't modify the
+// labels of a meta object.
+func copyLabels(labels map[string]string) map[string]string {
+	lblCopy := make(map[string]string, len(labels))
+	for k, v := range labels {
+		lblCopy[k] = v
+	}
+	return lblCopy
+}
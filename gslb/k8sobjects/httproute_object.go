@@ -0,0 +1,379 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package k8sobjects
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+	gwv1 "github.com/avinetworks/amko/internal/apis/gateway/v1"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var hrhMapInit sync.Once
+var hrhMap ObjHostMap
+
+func getHTTPRouteHostMap() *ObjHostMap {
+	hrhMapInit.Do(func() {
+		hrhMap.HostMap = make(map[string]IPHostname)
+	})
+	return &hrhMap
+}
+
+// GatewayGetter returns the Gateway name in namespace ns, if present.
+type GatewayGetter func(ns, name string) (*gwv1.Gateway, bool)
+
+// parentGateway is a Gateway which an HTTPRoute attaches to, sectionName is the listener of the
+// Gateway that the route attaches to, all the listeners if empty.
+type parentGateway struct {
+	gateway     *gwv1.Gateway
+	sectionName string
+}
+
+// getParentGateways returns the parent Gateways of route which are present in the cluster, the
+// parents of other kinds are ignored.
+func getParentGateways(route *gwv1.HTTPRoute, getGateway GatewayGetter) []parentGateway {
+	parents := []parentGateway{}
+	for _, ref := range route.Spec.ParentRefs {
+		if ref.Group != nil && *ref.Group != gwv1.GroupName {
+			continue
+		}
+		if ref.Kind != nil && *ref.Kind != gwv1.KindGateway {
+			continue
+		}
+		ns := route.Namespace
+		if ref.Namespace != nil {
+			ns = *ref.Namespace
+		}
+		gw, ok := getGateway(ns, ref.Name)
+		if !ok {
+			continue
+		}
+		parent := parentGateway{gateway: gw}
+		if ref.SectionName != nil {
+			parent.sectionName = *ref.SectionName
+		}
+		parents = append(parents, parent)
+	}
+	return parents
+}
+
+// listeners returns the listeners of the Gateway which the route attaches to.
+func (p parentGateway) listeners() []gwv1.Listener {
+	if p.sectionName == "" {
+		return p.gateway.Spec.Listeners
+	}
+	for _, listener := range p.gateway.Spec.Listeners {
+		if listener.Name == p.sectionName {
+			return []gwv1.Listener{listener}
+		}
+	}
+	return nil
+}
+
+// getGatewayIPAddr returns the first IP address in the status of the parent Gateways.
+func getGatewayIPAddr(parents []parentGateway) string {
+	for _, parent := range parents {
+		for _, addr := range parent.gateway.Status.Addresses {
+			if addr.Type != nil && *addr.Type != gwv1.AddressTypeIPAddress {
+				continue
+			}
+			if addr.Value != "" {
+				return addr.Value
+			}
+		}
+	}
+	return ""
+}
+
+// listenerMatchesHost returns true if the hostname of the listener (if any) matches host, a
+// wildcard hostname of a listener matches all the hosts of its domain.
+func listenerMatchesHost(listener gwv1.Listener, host string) bool {
+	if listener.Hostname == nil || *listener.Hostname == "" || *listener.Hostname == host {
+		return true
+	}
+	if strings.HasPrefix(*listener.Hostname, "*.") {
+		return strings.HasSuffix(host, (*listener.Hostname)[1:])
+	}
+	return false
+}
+
+// getHostsForHTTPRoute returns the hostnames of the route, the route inherits the hostnames of
+// the listeners if it has none. The wildcard hostnames can't be used for GSLB services, so they are
+// skipped.
+func getHostsForHTTPRoute(route *gwv1.HTTPRoute, parents []parentGateway) []string {
+	hostList := []string{}
+	candidates := route.Spec.Hostnames
+	if len(candidates) == 0 {
+		for _, parent := range parents {
+			for _, listener := range parent.listeners() {
+				if listener.Hostname != nil {
+					candidates = append(candidates, *listener.Hostname)
+				}
+			}
+		}
+	}
+	for _, host := range candidates {
+		if host == "" || strings.HasPrefix(host, "*") || gslbutils.PresentInList(host, hostList) {
+			continue
+		}
+		hostList = append(hostList, host)
+	}
+	return hostList
+}
+
+// isHTTPRouteHostTLS returns true if an HTTPS listener of the parent Gateways serves host.
+func isHTTPRouteHostTLS(host string, parents []parentGateway) bool {
+	for _, parent := range parents {
+		for _, listener := range parent.listeners() {
+			if listener.Protocol == gwv1.HTTPSProtocolType && listenerMatchesHost(listener, host) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getPathsForHTTPRoute returns the paths of route, and true if the route has no path matches and
+// only gets the default path "/".
+func getPathsForHTTPRoute(route *gwv1.HTTPRoute) ([]string, bool) {
+	pathList := []string{}
+	seen := make(map[string]struct{})
+	for _, rule := range route.Spec.Rules {
+		for _, match := range rule.Matches {
+			if match.Path == nil || match.Path.Value == nil {
+				continue
+			}
+			if _, ok := seen[*match.Path.Value]; ok {
+				continue
+			}
+			seen[*match.Path.Value] = struct{}{}
+			pathList = append(pathList, *match.Path.Value)
+		}
+	}
+	// a route without any path matches, matches all the paths
+	if len(pathList) == 0 {
+		return []string{"/"}, true
+	}
+	return gslbutils.LimitPaths(route.Namespace+"/"+route.Name, pathList), false
+}
+
+// GetHTTPRouteHostMeta returns an HTTPRoute split into its hosts. The IP address of the hosts is
+// taken from the status of the parent Gateways, fetched via getGateway.
+func GetHTTPRouteHostMeta(route *gwv1.HTTPRoute, getGateway GatewayGetter, cname string) []HTTPRouteHostMeta {
+	parents := getParentGateways(route, getGateway)
+	hostList := getHostsForHTTPRoute(route, parents)
+	hostMetaList := make([]HTTPRouteHostMeta, 0, len(hostList))
+	if len(hostList) == 0 {
+		return hostMetaList
+	}
+	ipAddr := getGatewayIPAddr(parents)
+	paths, defaultPath := getPathsForHTTPRoute(route)
+	// the labels and the paths are never modified after a meta object is built, so all the hosts of
+	// this route can share them
+	labels := make(map[string]string, len(route.GetLabels()))
+	for key, value := range route.GetLabels() {
+		labels[key] = value
+	}
+	weight := gslbutils.GetObjectWeight(route.GetAnnotations())
+	for _, host := range hostList {
+		hostMetaList = append(hostMetaList, HTTPRouteHostMeta{
+			Cluster:     cname,
+			RouteName:   route.Name,
+			ObjName:     route.Name + "/" + host,
+			Namespace:   route.Namespace,
+			Hostname:    host,
+			IPAddr:      ipAddr,
+			Labels:      labels,
+			Paths:       paths,
+			DefaultPath: defaultPath,
+			TLS:         isHTTPRouteHostTLS(host, parents),
+			Ready:       ipAddr != "",
+			Weight:      weight,
+
+			CreationTime: route.CreationTimestamp.Time,
+			UID:          route.UID,
+		})
+	}
+	return hostMetaList
+}
+
+// HTTPRouteHostMeta is the metadata for a host of a Gateway API HTTPRoute, analogous to
+// IngressHostMeta.
+type HTTPRouteHostMeta struct {
+	Cluster   string
+	RouteName string
+	ObjName   string
+	Namespace string
+	Hostname  string
+	IPAddr    string
+	Labels    map[string]string
+	Paths     []string
+	// DefaultPath is set if the route has no path matches, and Paths only has the default path "/"
+	DefaultPath bool
+	TLS         bool
+	// Ready is set if a parent Gateway of the route has an IP address in its status
+	Ready bool
+	// Weight is the weight of the route from the ObjectWeightAnnotation, 0 if it isn't set
+	Weight int32
+	// CreationTime is the creation timestamp of the route
+	CreationTime time.Time
+	// UID is the UID of the route, set in the references to the route for its events
+	UID types.UID
+}
+
+// GetObjectWeight returns the weight of the route from the ObjectWeightAnnotation, 0 if it isn't set.
+func (hrh HTTPRouteHostMeta) GetObjectWeight() int32 {
+	return hrh.Weight
+}
+
+// GetCreationTime returns the creation timestamp of the route.
+func (hrh HTTPRouteHostMeta) GetCreationTime() time.Time {
+	return hrh.CreationTime
+}
+
+// GetUID returns the UID of the route.
+func (hrh HTTPRouteHostMeta) GetUID() types.UID {
+	return hrh.UID
+}
+
+func (hrh HTTPRouteHostMeta) GetType() string {
+	return gdpv1alpha1.HTTPRouteObj
+}
+
+func (hrh HTTPRouteHostMeta) GetName() string {
+	return hrh.ObjName
+}
+
+func (hrh HTTPRouteHostMeta) GetNamespace() string {
+	return hrh.Namespace
+}
+
+func (hrh HTTPRouteHostMeta) GetCluster() string {
+	return hrh.Cluster
+}
+
+// CopyWithCluster returns a copy of the HTTPRouteHostMeta object with cname as its cluster,
+// implements gslbutils.ClusterRenamer.
+func (hrh HTTPRouteHostMeta) CopyWithCluster(cname string) interface{} {
+	hrh.Cluster = cname
+	return hrh
+}
+
+func (hrh HTTPRouteHostMeta) GetHostname() string {
+	return hrh.Hostname
+}
+
+func (hrh HTTPRouteHostMeta) GetIPAddr() string {
+	return hrh.IPAddr
+}
+
+// GetLabels returns a copy of the labels of the HTTPRoute.
+func (hrh HTTPRouteHostMeta) GetLabels() map[string]string {
+	return copyLabels(hrh.Labels)
+}
+
+func (hrh HTTPRouteHostMeta) GetPort() (int32, error) {
+	return 0, fmt.Errorf("port of HTTPRoute %s: %w", hrh.ObjName, ErrNotSupported)
+}
+
+func (hrh HTTPRouteHostMeta) GetProtocol() (string, error) {
+	return "", fmt.Errorf("protocol of HTTPRoute %s: %w", hrh.ObjName, ErrNotSupported)
+}
+
+func (hrh HTTPRouteHostMeta) GetPaths() ([]string, error) {
+	pathList := getEffectivePaths(gslbutils.HTTPRouteType, hrh.Paths, hrh.DefaultPath)
+	if len(pathList) == 0 {
+		return pathList, fmt.Errorf("HTTPRoute %s: %w", hrh.ObjName, ErrNoPaths)
+	}
+	return pathList, nil
+}
+
+func (hrh HTTPRouteHostMeta) GetTLS() (bool, error) {
+	return hrh.TLS, nil
+}
+
+func (hrh HTTPRouteHostMeta) IsReady() bool {
+	return hrh.Ready
+}
+
+func (hrh HTTPRouteHostMeta) IsPassthrough() bool {
+	return false
+}
+
+// HTTPRouteHostInList returns the host meta object for the same hostname from hrhList, if present.
+func (hrh HTTPRouteHostMeta) HTTPRouteHostInList(hrhList []HTTPRouteHostMeta) (HTTPRouteHostMeta, bool) {
+	for _, h := range hrhList {
+		if hrh.Hostname == h.Hostname {
+			return h, true
+		}
+	}
+	return HTTPRouteHostMeta{}, false
+}
+
+func (hrh HTTPRouteHostMeta) GetHTTPRouteHostCksum() uint32 {
+	var cksum uint32
+	for lblKey, lblValue := range hrh.Labels {
+		cksum += utils.Hash(lblKey) + utils.Hash(lblValue)
+	}
+	paths, _ := hrh.GetPaths()
+	sort.Strings(paths)
+	cksum += utils.Hash(hrh.Cluster) + utils.Hash(hrh.Namespace) +
+		utils.Hash(hrh.RouteName) + utils.Hash(hrh.Hostname) +
+		utils.Hash(hrh.IPAddr) + utils.Hash(utils.Stringify(paths)) +
+		utils.Hash("tls"+strconv.FormatBool(hrh.TLS)) +
+		utils.Hash("ready"+strconv.FormatBool(hrh.Ready)) + utils.Hash("weight"+strconv.Itoa(int(hrh.Weight)))
+	return cksum
+}
+
+func (hrh HTTPRouteHostMeta) UpdateHostMap(key string) {
+	hostMap := getHTTPRouteHostMap()
+	hostMap.Lock.Lock()
+	defer hostMap.Lock.Unlock()
+	hostMap.HostMap[key] = IPHostname{
+		IP:       hrh.IPAddr,
+		Hostname: hrh.Hostname,
+	}
+}
+
+func (hrh HTTPRouteHostMeta) GetHostnameFromHostMap(key string) string {
+	hostMap := getHTTPRouteHostMap()
+	hostMap.Lock.Lock()
+	defer hostMap.Lock.Unlock()
+	ipHostname, ok := hostMap.HostMap[key]
+	if !ok {
+		return ""
+	}
+	return ipHostname.Hostname
+}
+
+func (hrh HTTPRouteHostMeta) DeleteMapByKey(key string) {
+	hostMap := getHTTPRouteHostMap()
+	hostMap.Lock.Lock()
+	defer hostMap.Lock.Unlock()
+	delete(hostMap.HostMap, key)
+}
+
+func (hrh HTTPRouteHostMeta) ApplyFilter() bool {
+	return applyGlobalFilter(hrh)
+}
@@ -15,15 +15,20 @@
 package k8sobjects
 
 import (
-	"errors"
+	"fmt"
 	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
 
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 var ihMapInit sync.Once
@@ -36,8 +41,12 @@ func getIngHostMap() *ObjHostMap {
 	return &ihMap
 }
 
-func getPathsForHost(host string, ingress *v1beta1.Ingress) []string {
+// getPathsForHost returns the paths of host, truncated to the paths per host limit, and true if the
+// host has no paths and only gets the default path "/".
+func getPathsForHost(host string, ingress *v1beta1.Ingress) ([]string, bool) {
 	pathList := []string{}
+	// an ingress can have thousands of paths for a host, so the duplicates are tracked via a set
+	seen := make(map[string]struct{})
 	for _, rule := range ingress.Spec.Rules {
 		if rule.Host != host {
 			continue
@@ -50,9 +59,10 @@ func getPathsForHost(host string, ingress *v1beta1.Ingress) []string {
 				} else {
 					pathKey = "/"
 				}
-				if gslbutils.PresentInList(pathKey, pathList) {
+				if _, ok := seen[pathKey]; ok {
 					continue
 				}
+				seen[pathKey] = struct{}{}
 				pathList = append(pathList, pathKey)
 			}
 		}
@@ -62,10 +72,78 @@ func getPathsForHost(host string, ingress *v1beta1.Ingress) []string {
 	}
 
 	// if nothing in the pathList, always add "/"
+	defaultPath := false
 	if len(pathList) == 0 {
 		pathList = append(pathList, "/")
+		defaultPath = true
 	}
-	return pathList
+	pathList = gslbutils.LimitPaths(ingress.Namespace+"/"+ingress.Name+"/"+host,
+		removeExcludedPaths(pathList, ingress))
+	return pathList, defaultPath && len(pathList) != 0
+}
+
+// getServicesForHost returns the names of the services backing the paths of host, the default
+// backend of the ingress backs a host without any paths.
+func getServicesForHost(host string, ingress *v1beta1.Ingress) []string {
+	var services []string
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != host || rule.HTTP == nil {
+			continue
+		}
+		for _, path := range rule.HTTP.Paths {
+			if path.Backend.ServiceName == "" || gslbutils.PresentInList(path.Backend.ServiceName, services) {
+				continue
+			}
+			services = append(services, path.Backend.ServiceName)
+		}
+	}
+	if len(services) == 0 && ingress.Spec.Backend != nil && ingress.Spec.Backend.ServiceName != "" {
+		services = append(services, ingress.Spec.Backend.ServiceName)
+	}
+	return services
+}
+
+// removeExcludedPaths removes the paths listed in the ExcludePathsAnnotation of the ingress from
+// pathList. The returned list is empty if all the paths are excluded.
+func removeExcludedPaths(pathList []string, ingress *v1beta1.Ingress) []string {
+	excludedPaths, ok := getAnnotationList(ingress, gslbutils.ExcludePathsAnnotation)
+	if !ok {
+		return pathList
+	}
+	effectivePaths := []string{}
+	for _, path := range pathList {
+		if gslbutils.PresentInList(path, excludedPaths) {
+			continue
+		}
+		effectivePaths = append(effectivePaths, path)
+	}
+	return effectivePaths
+}
+
+// getAnnotationList returns the non-empty values of a comma separated annotation of the ingress, and
+// false if the ingress doesn't have the annotation.
+func getAnnotationList(ingress *v1beta1.Ingress, annotation string) ([]string, bool) {
+	annotatedList, ok := ingress.GetAnnotations()[annotation]
+	if !ok {
+		return nil, false
+	}
+	values := []string{}
+	for _, value := range strings.Split(annotatedList, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values, true
+}
+
+// isHostSelected returns true if host is a part of the GSLB services as per the
+// IncludeHostsAnnotation and the ExcludeHostsAnnotation of the ingress. An empty include list
+// includes all the hosts.
+func isHostSelected(host string, includedHosts, excludedHosts []string) bool {
+	if len(includedHosts) != 0 && !gslbutils.PresentInList(host, includedHosts) {
+		return false
+	}
+	return !gslbutils.PresentInList(host, excludedHosts)
 }
 
 func getTLSHosts(ingress *v1beta1.Ingress) []string {
@@ -82,30 +160,105 @@ func getTLSHosts(ingress *v1beta1.Ingress) []string {
 	return tlsHosts
 }
 
-// GetIngressHostMeta returns a ingress split into its backends
+// getPortsForHost returns the ports on which an ingress serves host: the HTTPS port for a TLS host
+// whose ingress disallows plain HTTP, both the HTTP and the HTTPS ports for the other TLS hosts,
+// and the HTTP port for the hosts without TLS.
+func getPortsForHost(tls bool, ingress *v1beta1.Ingress) []int32 {
+	if !tls {
+		return []int32{gslbutils.DefaultHTTPHealthMonitorPort}
+	}
+	if strings.EqualFold(strings.TrimSpace(ingress.GetAnnotations()[gslbutils.IngressAllowHTTPAnnotation]), "false") {
+		return []int32{gslbutils.DefaultHTTPSHealthMonitorPort}
+	}
+	return []int32{gslbutils.DefaultHTTPHealthMonitorPort, gslbutils.DefaultHTTPSHealthMonitorPort}
+}
+
+// RejectEmptyHostRules logs a warning and records a warning event for an ingress which has rules
+// without a host, these rules are left out of the GSLB services, as GetIngressHostMeta only returns
+// the hosts of an ingress. Returns true if the ingress has any such rules.
+func RejectEmptyHostRules(ingress *v1beta1.Ingress, cname string) bool {
+	emptyHostRules := 0
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host == "" {
+			emptyHostRules++
+		}
+	}
+	if emptyHostRules == 0 {
+		return false
+	}
+	msg := fmt.Sprintf("%d rule(s) without a host not selected for a GSLB service, the hostname is empty",
+		emptyHostRules)
+	gslbutils.Warnf("cluster: %s, namespace: %s, ingress: %s, msg: %s", cname, ingress.Namespace, ingress.Name, msg)
+	gslbutils.RecordObjectEvent(cname, ingress.Namespace, ingress.Name, gslbutils.IngressType, ingress.UID,
+		corev1.EventTypeWarning, gslbutils.EmptyHostname, msg)
+	return true
+}
+
+// GetIngressHostMeta returns a ingress split into its backends, only the hosts selected via the
+// IncludeHostsAnnotation and the ExcludeHostsAnnotation of the ingress are a part of the list.
 func GetIngressHostMeta(ingress *v1beta1.Ingress, cname string) []IngressHostMeta {
-	ingHostMetaList := []IngressHostMeta{}
+	return GetIngressHostMetaInto(nil, ingress, cname)
+}
+
+// GetIngressHostMetaInto returns a ingress split into its backends, like GetIngressHostMeta, but
+// reuses the backing array of buf for the returned list, if it has enough capacity. This is meant
+// for the event handlers which build these lists for every update: the returned list is valid only
+// till the next call with the same buf, so the callers must copy the elements they want to keep.
+// The elements themselves don't share any state with buf, so copying an element is enough.
+func GetIngressHostMetaInto(buf []IngressHostMeta, ingress *v1beta1.Ingress, cname string) []IngressHostMeta {
 	hostIPList := gslbutils.IngressGetIPAddrs(ingress)
+	ingHostMetaList := buf[:0]
+	if ingHostMetaList == nil {
+		ingHostMetaList = make([]IngressHostMeta, 0, len(ingress.Spec.Rules))
+	}
+	// the hosts without an IP address (e.g., the ingress is yet to be admitted by its ingress
+	// controller) are returned as well, without an IP address and not ready
+	for _, rule := range ingress.Spec.Rules {
+		if rule.Host != "" && !isHostInHostIPs(rule.Host, hostIPList) {
+			hostIPList = append(hostIPList, gslbutils.IngressHostIP{Hostname: rule.Host})
+		}
+	}
+	if len(hostIPList) == 0 {
+		return ingHostMetaList
+	}
 	tlsHosts := getTLSHosts(ingress)
+	ready := gslbutils.IsIngressReady(ingress)
+	// the labels are never modified after a meta object is built, so all the hosts of this ingress
+	// can share the same copy of the labels
+	labels := make(map[string]string, len(ingress.GetLabels()))
+	for key, value := range ingress.GetLabels() {
+		labels[key] = value
+	}
+	includedHosts, _ := getAnnotationList(ingress, gslbutils.IncludeHostsAnnotation)
+	excludedHosts, _ := getAnnotationList(ingress, gslbutils.ExcludeHostsAnnotation)
+	weight := gslbutils.GetObjectWeight(ingress.GetAnnotations())
 	for _, hip := range hostIPList {
-		metaObj := IngressHostMeta{
-			IngName:   ingress.Name,
-			Namespace: ingress.ObjectMeta.Namespace,
-			Hostname:  hip.Hostname,
-			IPAddr:    hip.IPAddr,
-			Cluster:   cname,
-			ObjName:   ingress.Name + "/" + hip.Hostname,
-			TLS:       false,
-		}
-		metaObj.Paths = make([]string, 0)
-		metaObj.Labels = make(map[string]string)
-		for key, value := range ingress.GetLabels() {
-			metaObj.Labels[key] = value
+		if !isHostSelected(hip.Hostname, includedHosts, excludedHosts) {
+			continue
 		}
-		metaObj.Paths = getPathsForHost(hip.Hostname, ingress)
-
-		if gslbutils.PresentInList(hip.Hostname, tlsHosts) {
-			metaObj.TLS = true
+		tls := gslbutils.PresentInList(hip.Hostname, tlsHosts)
+		paths, defaultPath := getPathsForHost(hip.Hostname, ingress)
+		metaObj := IngressHostMeta{
+			IngName:     ingress.Name,
+			Namespace:   ingress.ObjectMeta.Namespace,
+			Hostname:    hip.Hostname,
+			IPAddr:      hip.IPAddr,
+			LBHostname:  hip.LBHostname,
+			VIPs:        gslbutils.GetVIPs(hip.IPAddrs, ingress.GetAnnotations()),
+			Cluster:     cname,
+			ObjName:     ingress.Name + "/" + hip.Hostname,
+			Labels:      labels,
+			Paths:       paths,
+			DefaultPath: defaultPath,
+			TLS:         tls,
+			Ports:       getPortsForHost(tls, ingress),
+			Protocol:    gslbutils.ProtocolTCP,
+			Ready:       ready && (hip.IPAddr != "" || hip.LBHostname != ""),
+			Services:    getServicesForHost(hip.Hostname, ingress),
+			Weight:      weight,
+
+			CreationTime: ingress.CreationTimestamp.Time,
+			UID:          ingress.UID,
 		}
 		ingHostMetaList = append(ingHostMetaList, metaObj)
 	}
@@ -113,6 +266,15 @@ func GetIngressHostMeta(ingress *v1beta1.Ingress, cname string) []IngressHostMet
 	return ingHostMetaList
 }
 
+func isHostInHostIPs(hostname string, hostIPs []gslbutils.IngressHostIP) bool {
+	for _, hip := range hostIPs {
+		if hip.Hostname == hostname {
+			return true
+		}
+	}
+	return false
+}
+
 // IngressHostMeta is the metadata for an ingress. It is the minimal information
 // that we maintain for each ingress, accepted or rejected.
 type IngressHostMeta struct {
@@ -122,13 +284,48 @@ type IngressHostMeta struct {
 	Namespace string
 	Hostname  string
 	IPAddr    string
-	Labels    map[string]string
-	Paths     []string
-	TLS       bool
+	// LBHostname is the hostname of the load balancer, when it doesn't expose an IP address
+	LBHostname string
+	// VIPs are all the IP addresses of the host with their weights, IPAddr is the first of these
+	VIPs   []gslbutils.VIP
+	Labels map[string]string
+	Paths  []string
+	// DefaultPath is set if the host has no paths, and Paths only has the default path "/"
+	DefaultPath bool
+	TLS         bool
+	// Ports are the ports on which the ingress serves the host, sorted, the HTTPS port is the only
+	// port of a TLS host if the ingress disallows plain HTTP
+	Ports    []int32
+	Protocol string
+	// Ready is set if the status of the ingress was populated by its ingress controller
+	Ready bool
+	// Services are the services backing the paths of the host
+	Services []string
+	// Weight is the weight of the ingress from the ObjectWeightAnnotation, 0 if it isn't set
+	Weight int32
+	// CreationTime is the creation timestamp of the ingress
+	CreationTime time.Time
+	// UID is the UID of the ingress, set in the references to the ingress for its events
+	UID types.UID
 }
 
 var clusterHostMeta map[string]map[string]IngressHostMeta
 
+// GetObjectWeight returns the weight of the ingress from the ObjectWeightAnnotation, 0 if it isn't set.
+func (ing IngressHostMeta) GetObjectWeight() int32 {
+	return ing.Weight
+}
+
+// GetCreationTime returns the creation timestamp of the ingress.
+func (ing IngressHostMeta) GetCreationTime() time.Time {
+	return ing.CreationTime
+}
+
+// GetUID returns the UID of the ingress.
+func (ing IngressHostMeta) GetUID() types.UID {
+	return ing.UID
+}
+
 func (ing IngressHostMeta) GetType() string {
 	return gdpv1alpha1.IngressObj
 }
@@ -146,13 +343,20 @@ func (ing IngressHostMeta) GetIngressHostMetaKey() string {
 }
 
 func (ing IngressHostMeta) GetClusterKey() string {
-	return ing.Cluster + "/" + ing.Namespace + "/" + ing.GetIngressHostMetaKey()
+	return gslbutils.GetClusterKey(ing.Cluster, ing.Namespace, ing.GetIngressHostMetaKey())
 }
 
 func (ing IngressHostMeta) GetCluster() string {
 	return ing.Cluster
 }
 
+// CopyWithCluster returns a copy of the IngressHostMeta object with cname as its cluster, implements
+// gslbutils.ClusterRenamer.
+func (ing IngressHostMeta) CopyWithCluster(cname string) interface{} {
+	ing.Cluster = cname
+	return ing
+}
+
 func (ing IngressHostMeta) GetHostname() string {
 	return ing.Hostname
 }
@@ -161,27 +365,69 @@ func (ing IngressHostMeta) GetIPAddr() string {
 	return ing.IPAddr
 }
 
+// GetLBHostname returns the hostname of the load balancer, if the load balancer doesn't
+// expose an IP address.
+func (ing IngressHostMeta) GetLBHostname() string {
+	return ing.LBHostname
+}
+
+// HasAddr returns true if the host has an IP address, or the hostname of its load balancer.
+func (ing IngressHostMeta) HasAddr() bool {
+	return ing.IPAddr != "" || ing.LBHostname != ""
+}
+
+// GetVIPs returns the IP addresses of the host with their weights.
+func (ing IngressHostMeta) GetVIPs() []gslbutils.VIP {
+	return append([]gslbutils.VIP{}, ing.VIPs...)
+}
+
+// GetLabels returns a copy of the labels of the ingress.
+func (ing IngressHostMeta) GetLabels() map[string]string {
+	return copyLabels(ing.Labels)
+}
+
+// GetPort returns the port to be health monitored for the host, the HTTPS port if the host has
+// TLS, the HTTP port otherwise.
 func (ing IngressHostMeta) GetPort() (int32, error) {
-	return 0, errors.New("ingress object doesn't support GetPort function")
+	if len(ing.Ports) == 0 {
+		return 0, fmt.Errorf("port of ingress %s: %w", ing.ObjName, ErrNotSupported)
+	}
+	return ing.Ports[len(ing.Ports)-1], nil
+}
+
+// GetPorts returns all the ports on which the ingress serves the host.
+func (ing IngressHostMeta) GetPorts() []int32 {
+	return append([]int32{}, ing.Ports...)
 }
 
 func (ing IngressHostMeta) GetProtocol() (string, error) {
-	return "", errors.New("ingress object doesn't support GetProtocol function")
+	if ing.Protocol == "" {
+		return "", fmt.Errorf("protocol of ingress %s: %w", ing.ObjName, ErrNotSupported)
+	}
+	return ing.Protocol, nil
 }
 
 func (ing IngressHostMeta) GetPaths() ([]string, error) {
-	pathList := []string{}
-	if len(ing.Paths) == 0 {
-		return pathList, errors.New("no paths for this ingress " + ing.ObjName)
+	pathList := getEffectivePaths(gslbutils.IngressType, ing.Paths, ing.DefaultPath)
+	if len(pathList) == 0 {
+		return pathList, fmt.Errorf("ingress %s: %w", ing.ObjName, ErrNoPaths)
 	}
-	copy(pathList, ing.Paths)
-	return ing.Paths, nil
+	return pathList, nil
 }
 
 func (ing IngressHostMeta) GetTLS() (bool, error) {
 	return ing.TLS, nil
 }
 
+func (ing IngressHostMeta) IsReady() bool {
+	return ing.Ready
+}
+
+// GetBackendServices returns the services backing the paths of the host.
+func (ing IngressHostMeta) GetBackendServices() []string {
+	return ing.Services
+}
+
 func (ing IngressHostMeta) IsPassthrough() bool {
 	return false
 }
@@ -201,12 +447,21 @@ func (ing IngressHostMeta) GetIngressHostCksum() uint32 {
 	for lblKey, lblValue := range ing.Labels {
 		cksum += utils.Hash(lblKey) + utils.Hash(lblValue)
 	}
-	paths := ing.Paths
+	paths, _ := ing.GetPaths()
 	sort.Strings(paths)
 	// TODO: annotations will be checked in later
 	cksum += utils.Hash(ing.Cluster) + utils.Hash(ing.Namespace) +
 		utils.Hash(ing.IngName) + utils.Hash(ing.Hostname) +
-		utils.Hash(ing.IPAddr) + utils.Hash(utils.Stringify(paths))
+		utils.Hash(ing.IPAddr) + utils.Hash(ing.LBHostname) + utils.Hash(utils.Stringify(paths)) +
+		utils.Hash("ready"+strconv.FormatBool(ing.Ready))
+	for _, svc := range ing.Services {
+		cksum += utils.Hash("svc" + svc)
+	}
+	for _, port := range ing.Ports {
+		cksum += utils.Hash("port" + strconv.Itoa(int(port)))
+	}
+	cksum += utils.Hash(ing.Protocol) + utils.Hash(gslbutils.GetVIPsKey(ing.VIPs)) +
+		utils.Hash("weight"+strconv.Itoa(int(ing.Weight)))
 	return cksum
 }
 
@@ -215,8 +470,9 @@ func (ing IngressHostMeta) UpdateHostMap(key string) {
 	rhm.Lock.Lock()
 	defer rhm.Lock.Unlock()
 	rhm.HostMap[key] = IPHostname{
-		IP:       ing.IPAddr,
-		Hostname: ing.Hostname,
+		IP:         ing.IPAddr,
+		Hostname:   ing.Hostname,
+		LBHostname: ing.LBHostname,
 	}
 }
 
@@ -238,71 +494,12 @@ func (ing IngressHostMeta) DeleteMapByKey(key string) {
 	delete(ihm.HostMap, key)
 }
 
-func (ihm IngressHostMeta) ApplyFilter() bool {
-	gf := gslbutils.GetGlobalFilter()
-	gf.GlobalLock.RLock()
-	gf.GlobalLock.RUnlock()
-
-	if !gslbutils.PresentInList(ihm.Cluster, gf.ApplicableClusters) {
-		gslbutils.Logf("objType: Ingress, cluster: %s, namespace: %s, name: %s, msg: rejected because cluster is not selected",
-			ihm.Cluster, ihm.Namespace, ihm.ObjName)
-		return false
-	}
-	nsFilter := gf.NSFilter
-	// will check the namespaces first, whether the namespace for ihm is selected
-	if nsFilter != nil {
-		nsFilter.Lock.RLock()
-		defer nsFilter.Lock.RUnlock()
-		nsList, ok := gf.NSFilter.SelectedNS[ihm.Cluster]
-		if !ok {
-			gslbutils.Logf("objType: Ingress, cluster: %s, namespace: %s, name: %s, msg: rejected because of namespaceSelector",
-				ihm.Cluster, ihm.Namespace, ihm.ObjName)
-			return false
-		}
-		if gslbutils.PresentInList(ihm.Namespace, nsList) {
-			appFilter := gf.AppFilter
-			if appFilter == nil {
-				gslbutils.Logf("objType: ingress, cluster: %s, namespace: %s, name: %s, msg: accepted because of namespaceSelector",
-					ihm.Cluster, ihm.Namespace, ihm.ObjName)
-				return true
-			}
-			// Check the appFilter now for this object
-			if applyAppFilter(ihm.Labels, appFilter) {
-				gslbutils.Logf("objType: ingress, cluster: %s, namespace: %s, name: %s, msg: accepted because of namespaceSelector and appSelector",
-					ihm.Cluster, ihm.Namespace, ihm.ObjName)
-				return true
-			}
-			gslbutils.Logf("objType: ingress, cluster: %s, namespace: %s, name: %s, msg: rejected because of appSelector",
-				ihm.Cluster, ihm.Namespace, ihm.ObjName)
-			return false
-		}
-		// this means that the namespace is not selected in the filter
-		gslbutils.Logf("objType: ingress, cluster: %s, namespace: %s, name: %s, msg: rejected because namespace is not selected",
-			ihm.Cluster, ihm.Namespace, ihm.ObjName)
-		return false
-	}
-	// check for app filter
-	if gf.AppFilter == nil {
-		gslbutils.Logf("objType: ingress, cluster: %s, namespace: %s, name: %s, msg: rejected because no appSelector",
-			ihm.Cluster, ihm.Namespace, ihm.ObjName)
-		return false
-	}
-	if !applyAppFilter(ihm.Labels, gf.AppFilter) {
-		gslbutils.Logf("objType: ingress, cluster: %s, namespace: %s, name: %s, msg: rejected because of appSelector",
-			ihm.Cluster, ihm.Namespace, ihm.ObjName)
-		return false
-	}
-	gslbutils.Logf("objType: ingress, cluster: %s, namespace: %s, name: %s, msg: accepted because of appSelector",
-		ihm.Cluster, ihm.Namespace, ihm.ObjName)
-
-	return true
+// AllPathsExcluded returns true if all the paths of the ingress host are excluded via the
+// ExcludePathsAnnotation, such a host is always rejected by the global filter.
+func (ihm IngressHostMeta) AllPathsExcluded() bool {
+	return len(ihm.Paths) == 0
 }
 
-func applyAppFilter(ihmLabels map[string]string, appFilter *gslbutils.AppFilter) bool {
-	for k, v := range ihmLabels {
-		if k == appFilter.Key && v == appFilter.Value {
-			return true
-		}
-	}
-	return false
+func (ihm IngressHostMeta) ApplyFilter() bool {
+	return applyGlobalFilter(ihm)
 }
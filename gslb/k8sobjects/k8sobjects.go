@@ -15,9 +15,102 @@
 package k8sobjects
 
 import (
-	"sync"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	gwv1 "github.com/avinetworks/amko/internal/apis/gateway/v1"
+
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
+// The errors returned by the meta objects, wrapped with the context, the callers can check them
+// via errors.Is.
+var (
+	// ErrNotSupported is returned for an attribute which the object type doesn't have, e.g. the
+	// port of a non-passthrough route.
+	ErrNotSupported = errors.New("not supported for this object type")
+	// ErrNoPaths is returned for an object without any paths.
+	ErrNoPaths = errors.New("no paths")
+	// ErrNoPort is returned for a service without any ports.
+	ErrNoPort = errors.New("no ports")
+	// ErrUnknownObjType is returned for an object type which isn't registered.
+	ErrUnknownObjType = errors.New("unrecognised object type")
+)
+
+// The object types from which the GSLB members are built, a new object type has to be registered
+// here, so that the layers dispatch its objects via gslbutils.GetObjTypeHandlers.
+func init() {
+	gslbutils.RegisterObjType(gslbutils.ObjTypeHandlers{
+		ObjType:       gslbutils.RouteType,
+		Kind:          "Route",
+		APIVersion:    routev1.SchemeGroupVersion.String(),
+		AcceptedStore: gslbutils.GetAcceptedRouteStore,
+		RejectedStore: gslbutils.GetRejectedRouteStore,
+		NewMeta:       func() interface{} { return RouteMeta{} },
+		HostMap:       getRouteHostMap,
+	})
+	gslbutils.RegisterObjType(gslbutils.ObjTypeHandlers{
+		ObjType:       gslbutils.SvcType,
+		Kind:          "Service",
+		APIVersion:    corev1.SchemeGroupVersion.String(),
+		AcceptedStore: gslbutils.GetAcceptedLBSvcStore,
+		RejectedStore: gslbutils.GetRejectedLBSvcStore,
+		NewMeta:       func() interface{} { return SvcMeta{} },
+		HostMap:       getSvcHostMap,
+	})
+	gslbutils.RegisterObjType(gslbutils.ObjTypeHandlers{
+		ObjType:       gslbutils.IngressType,
+		Kind:          "Ingress",
+		APIVersion:    v1beta1.SchemeGroupVersion.String(),
+		HostScoped:    true,
+		AcceptedStore: gslbutils.GetAcceptedIngressStore,
+		RejectedStore: gslbutils.GetRejectedIngressStore,
+		NewMeta:       func() interface{} { return IngressHostMeta{} },
+		HostMap:       getIngHostMap,
+	})
+	gslbutils.RegisterObjType(gslbutils.ObjTypeHandlers{
+		ObjType:       gslbutils.HTTPRouteType,
+		Kind:          "HTTPRoute",
+		APIVersion:    gwv1.SchemeGroupVersion.String(),
+		HostScoped:    true,
+		AcceptedStore: gslbutils.GetAcceptedHTTPRouteStore,
+		RejectedStore: gslbutils.GetRejectedHTTPRouteStore,
+		NewMeta:       func() interface{} { return HTTPRouteHostMeta{} },
+		HostMap:       getHTTPRouteHostMap,
+	})
+	gslbutils.RegisterClusterRenameHook(renameClusterInHostMaps)
+}
+
+// getEffectivePaths returns the paths of an object of objType. The default path of an object without
+// any paths is dropped if the object type is advertised with the host only.
+func getEffectivePaths(objType string, paths []string, defaultPath bool) []string {
+	if defaultPath && gslbutils.IsHostOnlyObjType(objType) {
+		return []string{}
+	}
+	pathList := make([]string, len(paths))
+	copy(pathList, paths)
+	return pathList
+}
+
+// GetNewMetaObj returns an empty meta object of objType, as registered for the object type.
+func GetNewMetaObj(objType string) (MetaObject, error) {
+	handlers, ok := gslbutils.GetObjTypeHandlers(objType)
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", objType, ErrUnknownObjType)
+	}
+	metaObj, ok := handlers.NewMeta().(MetaObject)
+	if !ok {
+		return nil, errors.New("registered meta object isn't a MetaObject for object: " + objType)
+	}
+	return metaObj, nil
+}
+
 // Interface for k8s/openshift objects(e.g. route, service, ingress) with minimal information
 type MetaObject interface {
 	GetType() string
@@ -26,6 +119,7 @@ type MetaObject interface {
 	GetHostname() string
 	GetIPAddr() string
 	GetCluster() string
+	GetLabels() map[string]string
 	UpdateHostMap(string)
 	GetHostnameFromHostMap(string) string
 	DeleteMapByKey(string)
@@ -34,19 +128,178 @@ type MetaObject interface {
 	GetProtocol() (string, error)
 	GetTLS() (bool, error)
 	IsPassthrough() bool
+	// IsReady returns true if the object is ready to serve traffic, as per its status
+	IsReady() bool
+}
+
+// BackendServicesObject is implemented by the meta objects which know the services backing them,
+// the ready backends of these services decide the weights of the clusters in the backends weight
+// mode.
+type BackendServicesObject interface {
+	GetBackendServices() []string
+}
+
+// TLSServerNameObject is implemented by the meta objects which know the SNI host to be sent by
+// their HTTPS health monitors.
+type TLSServerNameObject interface {
+	GetTLSServerName() string
+}
+
+// TrafficPolicyObject is implemented by the meta objects which have an external traffic policy,
+// the objects with the Local policy are served only by the nodes running their pods.
+type TrafficPolicyObject interface {
+	GetExternalTrafficPolicy() string
+	IsLocalTrafficPolicy() bool
+}
+
+// UIDObject is implemented by the meta objects which know the UID of their objects, the events
+// recorded on these objects refer to the UID.
+type UIDObject interface {
+	GetUID() types.UID
+}
+
+// CreationTimeObject is implemented by the meta objects which know the creation timestamp of their
+// objects, the objects younger than the min age of the filter aren't advertised yet.
+type CreationTimeObject interface {
+	GetCreationTime() time.Time
+}
+
+// WeightedObject is implemented by the meta objects which can have their own weight, which takes
+// precedence over the weight of their cluster in the traffic split.
+type WeightedObject interface {
+	GetObjectWeight() int32
+}
+
+// MultiVIPObject is implemented by the meta objects which can expose more than one VIP, each VIP
+// is a member of the GSLB service, weighted relative to the other VIPs of the object.
+type MultiVIPObject interface {
+	GetVIPs() []gslbutils.VIP
+}
+
+// LBHostnameObject is implemented by the meta objects whose load balancer can expose a hostname
+// instead of an IP address, the hostname is the address of their GSLB members.
+type LBHostnameObject interface {
+	GetLBHostname() string
 }
 
 type FilterableObject interface {
 	ApplyFilter() bool
 }
 
-type IPHostname struct {
-	IP       string
-	Hostname string
+// IPHostname and ObjHostMap are defined in gslbutils, so that the host maps can be registered with
+// the handlers of the object types.
+type IPHostname = gslbutils.IPHostname
+
+type ObjHostMap = gslbutils.ObjHostMap
+
+// getHostMap returns the host map registered for objType.
+func getHostMap(objType string) (*ObjHostMap, bool) {
+	handlers, ok := gslbutils.GetObjTypeHandlers(objType)
+	if !ok || handlers.HostMap == nil {
+		return nil, false
+	}
+	return handlers.HostMap(), true
+}
+
+// GetHostMapKeys returns the cluster keys (cluster/ns/objName) of the entries in the host map of
+// objType.
+func GetHostMapKeys(objType string) []string {
+	hostMap, ok := getHostMap(objType)
+	if !ok {
+		return nil
+	}
+	hostMap.Lock.Lock()
+	defer hostMap.Lock.Unlock()
+	keys := make([]string, 0, len(hostMap.HostMap))
+	for key := range hostMap.HostMap {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// GetHostMapEntry returns the entry for a cluster key (cluster/ns/objName) in the host map of
+// objType.
+func GetHostMapEntry(objType, key string) (IPHostname, bool) {
+	hostMap, ok := getHostMap(objType)
+	if !ok {
+		return IPHostname{}, false
+	}
+	hostMap.Lock.Lock()
+	defer hostMap.Lock.Unlock()
+	ipHostname, ok := hostMap.HostMap[key]
+	return ipHostname, ok
+}
+
+// renameClusterInHostMaps re-keys the entries of the cluster oldName in the host maps of all the
+// object types to newName.
+func renameClusterInHostMaps(oldName, newName string) {
+	for _, objType := range gslbutils.GetObjTypes() {
+		hostMap, ok := getHostMap(objType)
+		if !ok {
+			continue
+		}
+		hostMap.Lock.Lock()
+		for key, ipHostname := range hostMap.HostMap {
+			cname, ns, objName, err := gslbutils.ParseClusterKey(key)
+			if err != nil || cname != oldName {
+				continue
+			}
+			delete(hostMap.HostMap, key)
+			hostMap.HostMap[gslbutils.GetClusterKey(newName, ns, objName)] = ipHostname
+		}
+		hostMap.Lock.Unlock()
+	}
+}
+
+// HostnameSource is an object from which a GSLB hostname is advertised.
+type HostnameSource struct {
+	ObjType   string
+	Cluster   string
+	Namespace string
+	Name      string
 }
 
-// ObjHostMap stores a mapping between cluster+ns+objName to it's hostname
-type ObjHostMap struct {
-	HostMap map[string]IPHostname
-	Lock    sync.Mutex
+// GetAdvertisedHostnames returns a snapshot of the hostnames in the host maps of all the object
+// types, i.e. the hostnames of the GSLB services built by AMKO, along with the objects advertising
+// each of them. The extra hosts of the routes are included.
+func GetAdvertisedHostnames() map[string][]HostnameSource {
+	hostnames := make(map[string][]HostnameSource)
+	for _, objType := range gslbutils.GetObjTypes() {
+		hostMap, ok := getHostMap(objType)
+		if !ok {
+			continue
+		}
+		hostMap.Lock.Lock()
+		for key, ipHostname := range hostMap.HostMap {
+			cname, ns, objName, err := gslbutils.ParseClusterKey(key)
+			if err != nil {
+				gslbutils.Warnf("objType: %s, key: %s, msg: couldn't parse the host map key, %s", objType, key,
+					err.Error())
+				continue
+			}
+			source := HostnameSource{ObjType: objType, Cluster: cname, Namespace: ns, Name: objName}
+			for _, hostname := range append([]string{ipHostname.Hostname}, ipHostname.ExtraHosts...) {
+				if hostname != "" {
+					hostnames[hostname] = append(hostnames[hostname], source)
+				}
+			}
+		}
+		hostMap.Lock.Unlock()
+	}
+	for _, sources := range hostnames {
+		sort.Slice(sources, func(i, j int) bool {
+			a, b := sources[i], sources[j]
+			if a.ObjType != b.ObjType {
+				return a.ObjType < b.ObjType
+			}
+			if a.Cluster != b.Cluster {
+				return a.Cluster < b.Cluster
+			}
+			if a.Namespace != b.Namespace {
+				return a.Namespace < b.Namespace
+			}
+			return a.Name < b.Name
+		})
+	}
+	return hostnames
 }
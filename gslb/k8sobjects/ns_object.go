@@ -54,56 +54,69 @@ func (nsObj NSMeta) GetCluster() string {
 	return nsObj.Cluster
 }
 
+// CopyWithCluster returns a copy of the NSMeta object with cname as its cluster, implements
+// gslbutils.ClusterRenamer.
+func (nsObj NSMeta) CopyWithCluster(cname string) interface{} {
+	nsObj.Cluster = cname
+	return nsObj
+}
+
+// ApplyFilter evaluates the namespace filter for the namespace, a selected namespace is added to
+// the namespace filter. The decision is sent to the filter observers along with the reason.
 func (ns NSMeta) ApplyFilter() bool {
+	accepted, reason := ns.applyNSFilter()
+	NotifyFilterDecision(ns, accepted, reason)
+	return accepted
+}
+
+func (ns NSMeta) applyNSFilter() (bool, string) {
 	gf := gslbutils.GetGlobalFilter()
+	if !gf.HasPolicy() {
+		return false, "rejected because no GDP object is applied"
+	}
 	gf.GlobalLock.RLock()
 	defer gf.GlobalLock.RUnlock()
 
 	if !gslbutils.PresentInList(ns.Cluster, gf.ApplicableClusters) {
-		gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: namespace rejected because cluster was not selected",
+		gslbutils.Debugf("objType: Namespace, cluster: %s, name: %s, msg: namespace rejected because cluster was not selected",
 			ns.Cluster, ns.Name)
-		return false
+		return false, "rejected because cluster was not selected"
 	}
 	nsFilter := gf.NSFilter
 	if nsFilter != nil {
 		nsFilter.Lock.Lock()
 		defer nsFilter.Lock.Unlock()
-		lblMatch := false
-		for k, v := range ns.Labels {
-			if k == nsFilter.Key && v == nsFilter.Value {
-				lblMatch = true
-			}
-		}
-		if !lblMatch {
-			gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: namespace rejected because it was not selected via label",
+		if !nsFilter.Matches(ns.Labels) {
+			gslbutils.Debugf("objType: Namespace, cluster: %s, name: %s, msg: namespace rejected because it was not selected via label",
 				ns.Cluster, ns.Name)
-			return false
+			return false, "rejected because it was not selected via label"
 		}
-		nsList, ok := nsFilter.SelectedNS[ns.Cluster]
+		key := nsFilter.SelectionKey(ns.Cluster)
+		nsList, ok := nsFilter.SelectedNS[key]
 		if !ok {
 			if len(nsFilter.SelectedNS) == 0 {
 				gf.NSFilter.SelectedNS = make(map[string][]string)
 			}
-			gf.NSFilter.SelectedNS[ns.Cluster] = []string{ns.Name}
+			gf.NSFilter.SelectedNS[key] = []string{ns.Name}
 			gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: namespace added to filter",
 				ns.Cluster, ns.Name)
-			return true
+			return true, "accepted and added to the namespace filter"
 		}
 		// cluster already exists, check for namespace
 		if !gslbutils.PresentInList(ns.Name, nsList) {
-			gf.NSFilter.SelectedNS[ns.Cluster] = append(gf.NSFilter.SelectedNS[ns.Cluster], ns.Name)
+			gf.NSFilter.SelectedNS[key] = append(gf.NSFilter.SelectedNS[key], ns.Name)
 			gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: namespace added to filter",
 				ns.Cluster, ns.Name)
-			return true
+			return true, "accepted and added to the namespace filter"
 		}
 		gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: namespace already exists in filter, nothing to update",
 			ns.Cluster, ns.Name)
-		return true
+		return true, "accepted, already present in the namespace filter"
 	}
 	gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: no namespace filter present, returning false",
 		ns.Cluster, ns.Name)
 
-	return false
+	return false, "rejected because no namespace filter present"
 }
 
 func (ns NSMeta) DeleteFromFilter() bool {
@@ -116,7 +129,8 @@ func (ns NSMeta) DeleteFromFilter() bool {
 	if nsFilter != nil {
 		nsFilter.Lock.Lock()
 		defer nsFilter.Lock.Unlock()
-		nsList, ok := nsFilter.SelectedNS[ns.Cluster]
+		key := nsFilter.SelectionKey(ns.Cluster)
+		nsList, ok := nsFilter.SelectedNS[key]
 		if !ok {
 			// cluster not found, nothing to be done
 			gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: namespace not part of filter, nothing to be done",
@@ -130,14 +144,19 @@ func (ns NSMeta) DeleteFromFilter() bool {
 				ns.Cluster, ns.Name)
 			return false
 		}
+		if nsFilter.Global && isNSAcceptedInOtherClusters(ns.Cluster, ns.Name) {
+			gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: namespace still selected in the other clusters, nothing to be done",
+				ns.Cluster, ns.Name)
+			return false
+		}
 		// Delete the index
-		nsFilter.SelectedNS[ns.Cluster] = append(nsList[:idx], nsList[idx+1:]...)
+		nsFilter.SelectedNS[key] = append(nsList[:idx], nsList[idx+1:]...)
 		gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: namespace part of filter, deleted",
 			ns.Cluster, ns.Name)
 
 		// Check if this was the last namespace, if yes, remove that cluster from the map
-		if len(nsFilter.SelectedNS[ns.Cluster]) == 0 {
-			delete(nsFilter.SelectedNS, ns.Cluster)
+		if len(nsFilter.SelectedNS[key]) == 0 {
+			delete(nsFilter.SelectedNS, key)
 			gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: last namespace for cluster, deleted cluster from filter",
 				ns.Cluster, ns.Name)
 		}
@@ -150,10 +169,39 @@ func (ns NSMeta) DeleteFromFilter() bool {
 	return false
 }
 
-// UpdateFilter returns true if there was a change in the filter
+// isNSAcceptedInOtherClusters returns true if a namespace named ns is accepted in any cluster
+// other than cname. A global namespace filter selects ns until it is rejected in all the clusters.
+func isNSAcceptedInOtherClusters(cname, ns string) bool {
+	acceptedNSStore := gslbutils.GetAcceptedNSStore()
+	for _, cluster := range acceptedNSStore.GetAllNamespaces() {
+		if cluster == cname {
+			continue
+		}
+		if _, ok := acceptedNSStore.GetNSObjectByName(cluster, ns); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// updateNSStores moves the namespace to the accepted or the rejected namespace store.
+func (ns NSMeta) updateNSStores(accepted bool) {
+	fromStore, toStore := gslbutils.GetAcceptedNSStore(), gslbutils.GetRejectedNSStore()
+	if accepted {
+		fromStore, toStore = toStore, fromStore
+	}
+	fromStore.DeleteNSObj(ns.Cluster, ns.Name)
+	toStore.AddOrUpdate(ns.Cluster, ns.Name, ns)
+}
+
+// UpdateFilter returns true if there was a change in the filter. The namespace is moved to the
+// accepted or the rejected namespace store as per its new labels, even if the filter didn't change,
+// e.g. a namespace unselected in one cluster stays selected by a global namespace filter while it
+// is selected in the other clusters.
 func (ns NSMeta) UpdateFilter(old NSMeta) bool {
 	oldApplied := old.ApplyFilter()
 	newApplied := ns.ApplyFilter()
+	ns.updateNSStores(newApplied)
 
 	if oldApplied == newApplied {
 		gslbutils.Logf("objType: Namespace, cluster: %s, name: %s, msg: no changes", ns.Cluster, ns.Name)
@@ -15,13 +15,19 @@
 package k8sobjects
 
 import (
-	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
 
 	routev1 "github.com/openshift/api/route/v1"
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 var rhMapInit sync.Once
@@ -44,6 +50,12 @@ func GetRouteMeta(route *routev1.Route, cname string) RouteMeta {
 		IPAddr:    ipAddr,
 		Cluster:   cname,
 		TLS:       false,
+		Ready:     gslbutils.IsRouteAdmitted(route),
+		Services:  getRouteServices(route),
+		Weight:    gslbutils.GetObjectWeight(route.GetAnnotations()),
+
+		CreationTime: route.CreationTimestamp.Time,
+		UID:          route.UID,
 	}
 	metaObj.Labels = make(map[string]string)
 	routeLabels := route.GetLabels()
@@ -57,10 +69,15 @@ func GetRouteMeta(route *routev1.Route, cname string) RouteMeta {
 			metaObj.Port = gslbutils.DefaultHTTPSHealthMonitorPort
 			metaObj.Protocol = gslbutils.ProtocolTCP
 			metaObj.Passthrough = true
+			metaObj.TLSTermination = string(route.Spec.TLS.Termination)
+			metaObj.SNIHosts = getAnnotatedHosts(route, gslbutils.SNIHostsAnnotation, nil)
+			metaObj.AdditionalHosts = getAnnotatedHosts(route, gslbutils.AdditionalHostsAnnotation, metaObj.SNIHosts)
 			return metaObj
 		}
 		// route is a TLS type
 		metaObj.TLS = true
+		metaObj.TLSTermination = string(route.Spec.TLS.Termination)
+		metaObj.HmSNIHost = strings.TrimSpace(route.GetAnnotations()[gslbutils.HmSNIHostAnnotation])
 	}
 
 	pathList := []string{}
@@ -68,27 +85,217 @@ func GetRouteMeta(route *routev1.Route, cname string) RouteMeta {
 		pathList = append(pathList, route.Spec.Path)
 	} else {
 		pathList = append(pathList, "/")
+		metaObj.DefaultPath = true
 	}
 	// only for passthrough routes, we won't add any paths
 	metaObj.Paths = pathList
+	metaObj.AdditionalHosts = getAnnotatedHosts(route, gslbutils.AdditionalHostsAnnotation, nil)
 
 	return metaObj
 }
 
+// getRouteServices returns the names of the services which the route sends the traffic to.
+func getRouteServices(route *routev1.Route) []string {
+	var services []string
+	targets := append([]routev1.RouteTargetReference{route.Spec.To}, route.Spec.AlternateBackends...)
+	for _, target := range targets {
+		if target.Kind != "" && target.Kind != "Service" {
+			continue
+		}
+		if target.Name == "" || gslbutils.PresentInList(target.Name, services) {
+			continue
+		}
+		services = append(services, target.Name)
+	}
+	return services
+}
+
+// getAnnotatedHosts returns a sorted list of the hosts of a route, as specified in the annotation.
+// The route's host and the hosts in exclude are not a part of this list. The alternate backends of
+// a route are services and don't add any hosts, so the additional hosts can only be specified via
+// the annotations.
+func getAnnotatedHosts(route *routev1.Route, annotation string, exclude []string) []string {
+	hostList, ok := route.GetAnnotations()[annotation]
+	if !ok {
+		return nil
+	}
+	hosts := []string{}
+	for _, host := range strings.Split(hostList, ",") {
+		host = strings.TrimSpace(host)
+		if host == "" || host == route.Spec.Host || gslbutils.PresentInList(host, hosts) ||
+			gslbutils.PresentInList(host, exclude) {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	sort.Strings(hosts)
+	return hosts
+}
+
 // RouteMeta is the metadata for a route. It is the minimal information
 // that we maintain for each route, accepted or rejected.
 type RouteMeta struct {
-	Cluster     string
-	Name        string
-	Namespace   string
-	Hostname    string
-	IPAddr      string
-	Labels      map[string]string
-	Paths       []string
+	Cluster   string
+	Name      string
+	Namespace string
+	Hostname  string
+	IPAddr    string
+	Labels    map[string]string
+	Paths     []string
+	// DefaultPath is set if the route has no path, and Paths only has the default path "/"
+	DefaultPath bool
 	TLS         bool
 	Port        int32
 	Protocol    string
 	Passthrough bool
+	// SNIHosts are the additional hosts of a passthrough route, a GSLB service is created for
+	// each of these hosts
+	SNIHosts []string
+	// AdditionalHosts are the hosts of a route from the AdditionalHostsAnnotation, a GSLB service
+	// is created for each of these hosts
+	AdditionalHosts []string
+	// Ready is set if the route was admitted by its router
+	Ready bool
+	// Services are the services backing the route, its target and its alternate backends
+	Services []string
+	// TLSTermination is the TLS termination type (edge, reencrypt or passthrough) of a TLS route
+	TLSTermination string
+	// HmSNIHost is the SNI host for the health monitors of a TLS route, as specified in the
+	// HmSNIHostAnnotation
+	HmSNIHost string
+	// Weight is the weight of the route from the ObjectWeightAnnotation, 0 if it isn't set
+	Weight int32
+	// CreationTime is the creation timestamp of the route
+	CreationTime time.Time
+	// UID is the UID of the route, set in the references to the route for its events
+	UID types.UID
+}
+
+// GetObjectWeight returns the weight of the route from the ObjectWeightAnnotation, 0 if it isn't set.
+func (route RouteMeta) GetObjectWeight() int32 {
+	return route.Weight
+}
+
+// GetCreationTime returns the creation timestamp of the route.
+func (route RouteMeta) GetCreationTime() time.Time {
+	return route.CreationTime
+}
+
+// GetUID returns the UID of the route.
+func (route RouteMeta) GetUID() types.UID {
+	return route.UID
+}
+
+// GetTLSServerName returns the SNI host to be sent by the HTTPS health monitors of a TLS route,
+// which defaults to the route's host. Returns an empty string for the non-TLS routes.
+func (route RouteMeta) GetTLSServerName() string {
+	if !route.TLS {
+		return ""
+	}
+	if route.HmSNIHost != "" {
+		return route.HmSNIHost
+	}
+	return route.Hostname
+}
+
+// GetRouteCksum returns the checksum of all the fields of the route meta which are relevant
+// for the GSLB services.
+func (route RouteMeta) GetRouteCksum() uint32 {
+	var cksum uint32
+	for lblKey, lblValue := range route.Labels {
+		cksum += utils.Hash(lblKey) + utils.Hash(lblValue)
+	}
+	paths, _ := route.GetPaths()
+	for _, path := range paths {
+		cksum += utils.Hash(path)
+	}
+	for _, host := range route.SNIHosts {
+		cksum += utils.Hash("sni" + host)
+	}
+	for _, host := range route.AdditionalHosts {
+		cksum += utils.Hash("host" + host)
+	}
+	for _, svc := range route.Services {
+		cksum += utils.Hash("svc" + svc)
+	}
+	cksum += utils.Hash(route.Cluster) + utils.Hash(route.Namespace) + utils.Hash(route.Name) +
+		utils.Hash(route.Hostname) + utils.Hash(route.IPAddr) + utils.Hash(strconv.FormatBool(route.TLS)) +
+		utils.Hash(strconv.Itoa(int(route.Port))) + utils.Hash(route.Protocol) +
+		utils.Hash(strconv.FormatBool(route.Passthrough)) + utils.Hash("ready"+strconv.FormatBool(route.Ready)) +
+		utils.Hash("termination"+route.TLSTermination) + utils.Hash("hmSNIHost"+route.HmSNIHost) +
+		utils.Hash("weight"+strconv.Itoa(int(route.Weight)))
+	return cksum
+}
+
+// GetSNIHostMetas returns a route meta object for each of the SNI hosts of a passthrough route.
+func (route RouteMeta) GetSNIHostMetas() []RouteMeta {
+	if !route.Passthrough {
+		return nil
+	}
+	sniMetas := []RouteMeta{}
+	for _, host := range route.SNIHosts {
+		sniMeta := route
+		sniMeta.Hostname = host
+		sniMeta.Labels = copyLabels(route.Labels)
+		sniMeta.SNIHosts = nil
+		sniMeta.AdditionalHosts = nil
+		sniMetas = append(sniMetas, sniMeta)
+	}
+	return sniMetas
+}
+
+// GetExtraHosts returns the hosts of a route other than its host, i.e. the SNI hosts of a
+// passthrough route and the additional hosts.
+func (route RouteMeta) GetExtraHosts() []string {
+	hosts := []string{}
+	if route.Passthrough {
+		hosts = append(hosts, route.SNIHosts...)
+	}
+	return append(hosts, route.AdditionalHosts...)
+}
+
+// GetExtraHostMetas returns a route meta object for each of the extra hosts of a route.
+func (route RouteMeta) GetExtraHostMetas() []RouteMeta {
+	hostMetas := route.GetSNIHostMetas()
+	for _, host := range route.AdditionalHosts {
+		hostMeta := route
+		hostMeta.Hostname = host
+		hostMeta.Labels = copyLabels(route.Labels)
+		hostMeta.SNIHosts = nil
+		hostMeta.AdditionalHosts = nil
+		hostMetas = append(hostMetas, hostMeta)
+	}
+	return hostMetas
+}
+
+// GetAcceptedExtraHostMetas returns the meta objects of the extra hosts of a route which are accepted
+// by the checks of the global filter, the same checks as for the route's host (e.g. the hostname and
+// the gslbDomain checks). A GSLB service is built only for the accepted extra hosts, the rejected
+// ones are logged.
+func (route RouteMeta) GetAcceptedExtraHostMetas() []RouteMeta {
+	hostMetas := []RouteMeta{}
+	for _, hostMeta := range route.GetExtraHostMetas() {
+		if accepted, reason := evaluateGlobalFilter(hostMeta); !accepted {
+			gslbutils.Warnf("cluster: %s, namespace: %s, route: %s, hostname: %s, msg: extra host rejected, %s",
+				route.Cluster, route.Namespace, route.Name, hostMeta.Hostname, reason)
+			continue
+		}
+		hostMetas = append(hostMetas, hostMeta)
+	}
+	return hostMetas
+}
+
+// GetAcceptedExtraHosts returns the extra hosts of a route which are accepted by the checks of the
+// global filter, see GetAcceptedExtraHostMetas.
+func (route RouteMeta) GetAcceptedExtraHosts() []string {
+	hosts := []string{}
+	for _, hostMeta := range route.GetAcceptedExtraHostMetas() {
+		hosts = append(hosts, hostMeta.Hostname)
+	}
+	return hosts
 }
 
 func (route RouteMeta) GetType() string {
@@ -115,12 +322,24 @@ func (route RouteMeta) GetCluster() string {
 	return route.Cluster
 }
 
+// CopyWithCluster returns a copy of the RouteMeta object with cname as its cluster, implements
+// gslbutils.ClusterRenamer.
+func (route RouteMeta) CopyWithCluster(cname string) interface{} {
+	route.Cluster = cname
+	return route
+}
+
+// GetLabels returns a copy of the labels of the route.
+func (route RouteMeta) GetLabels() map[string]string {
+	return copyLabels(route.Labels)
+}
+
 func (route RouteMeta) GetPort() (int32, error) {
 	// we send the port (to be used only for passthrough routes)
 	if route.Passthrough {
 		return route.Port, nil
 	}
-	return 0, errors.New("route object doesn't support GetPort function")
+	return 0, fmt.Errorf("port of non-passthrough route %s: %w", route.Name, ErrNotSupported)
 }
 
 func (route RouteMeta) GetProtocol() (string, error) {
@@ -128,20 +347,30 @@ func (route RouteMeta) GetProtocol() (string, error) {
 	if route.Passthrough {
 		return route.Protocol, nil
 	}
-	return "", errors.New("route object doesn't support GetProtocol support")
+	return "", fmt.Errorf("protocol of non-passthrough route %s: %w", route.Name, ErrNotSupported)
 }
 
 func (route RouteMeta) GetPaths() ([]string, error) {
-	if len(route.Paths) == 0 {
-		return route.Paths, errors.New("path list is empty for route " + route.Name)
+	paths := getEffectivePaths(gslbutils.RouteType, route.Paths, route.DefaultPath)
+	if len(paths) == 0 {
+		return paths, fmt.Errorf("route %s: %w", route.Name, ErrNoPaths)
 	}
-	return route.Paths, nil
+	return paths, nil
 }
 
 func (route RouteMeta) GetTLS() (bool, error) {
 	return route.TLS, nil
 }
 
+func (route RouteMeta) IsReady() bool {
+	return route.Ready
+}
+
+// GetBackendServices returns the target and the alternate backends of the route.
+func (route RouteMeta) GetBackendServices() []string {
+	return route.Services
+}
+
 func (route RouteMeta) IsPassthrough() bool {
 	return route.Passthrough
 }
@@ -151,8 +380,9 @@ func (route RouteMeta) UpdateHostMap(key string) {
 	rhm.Lock.Lock()
 	defer rhm.Lock.Unlock()
 	rhm.HostMap[key] = IPHostname{
-		IP:       route.IPAddr,
-		Hostname: route.Hostname,
+		IP:         route.IPAddr,
+		Hostname:   route.Hostname,
+		ExtraHosts: route.GetAcceptedExtraHosts(),
 	}
 }
 
@@ -167,6 +397,18 @@ func (route RouteMeta) GetHostnameFromHostMap(key string) string {
 	return ipHostname.Hostname
 }
 
+// GetExtraHostsFromHostMap returns the extra hosts saved in the route host map for key.
+func (route RouteMeta) GetExtraHostsFromHostMap(key string) []string {
+	rhm := getRouteHostMap()
+	rhm.Lock.Lock()
+	defer rhm.Lock.Unlock()
+	ipHostname, ok := rhm.HostMap[key]
+	if !ok {
+		return nil
+	}
+	return append([]string{}, ipHostname.ExtraHosts...)
+}
+
 func (route RouteMeta) DeleteMapByKey(key string) {
 	rhm := getRouteHostMap()
 	rhm.Lock.Lock()
@@ -175,63 +417,5 @@ func (route RouteMeta) DeleteMapByKey(key string) {
 }
 
 func (route RouteMeta) ApplyFilter() bool {
-	gf := gslbutils.GetGlobalFilter()
-	gf.GlobalLock.RLock()
-	defer gf.GlobalLock.RUnlock()
-
-	if !gslbutils.PresentInList(route.Cluster, gf.ApplicableClusters) {
-		gslbutils.Logf("objType: Route, cluster: %s, namespace: %s, name: %s, msg: rejected because cluster is not selected",
-			route.Cluster, route.Namespace, route.Name)
-		return false
-	}
-
-	nsFilter := gf.NSFilter
-	// will check the namespaces first, whether the namespace for ihm is selected
-	if nsFilter != nil {
-		nsFilter.Lock.RLock()
-		defer nsFilter.Lock.RUnlock()
-		nsList, ok := gf.NSFilter.SelectedNS[route.Cluster]
-		if !ok {
-			gslbutils.Logf("objType: Route, cluster: %s, namespace: %s, name: %s, msg: rejected because of namespace selector",
-				route.Cluster, route.Namespace, route.Name)
-			return false
-		}
-		if gslbutils.PresentInList(route.Namespace, nsList) {
-			appFilter := gf.AppFilter
-			if appFilter == nil {
-				gslbutils.Logf("objType: Route, cluster: %s, namespace: %s, name: %s, msg: accepted because of namespaceSelector",
-					route.Cluster, route.Namespace, route.Name)
-				return true
-			}
-			// Check the appFilter now for this object
-			if applyAppFilter(route.Labels, appFilter) {
-				gslbutils.Logf("objType: Route, cluster: %s, namespace: %s, name: %s, msg: accepted because of namespaceSelector and appSelector",
-					route.Cluster, route.Namespace, route.Name)
-				return true
-			}
-			gslbutils.Logf("objType: Route, cluster: %s, namespace: %s, name: %s, msg: rejected because of appSelector",
-				route.Cluster, route.Namespace, route.Name)
-			return false
-		}
-		// this means that the namespace is not selected in the filter
-		gslbutils.Logf("objType: route, cluster: %s, namespace: %s, name: %s, msg: rejected because namespace is not selected",
-			route.Cluster, route.Namespace, route.Name)
-		return false
-	}
-
-	// check for app filter
-	if gf.AppFilter == nil {
-		gslbutils.Logf("objType: route, cluster: %s, namespace: %s, name: %s, msg: rejected because no appSelector",
-			route.Cluster, route.Namespace, route.Name)
-		return false
-	}
-	if !applyAppFilter(route.Labels, gf.AppFilter) {
-		gslbutils.Logf("objType: route, cluster: %s, namespace: %s, name: %s, msg: rejected because of appSelector",
-			route.Cluster, route.Namespace, route.Name)
-		return false
-	}
-	gslbutils.Logf("objType: route, cluster: %s, namespace: %s, name: %s, msg: accepted because of appSelector",
-		route.Cluster, route.Namespace, route.Name)
-
-	return true
+	return applyGlobalFilter(route)
 }
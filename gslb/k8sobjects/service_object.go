@@ -15,53 +15,78 @@
 package k8sobjects
 
 import (
-	"errors"
+	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
 
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 var shMapInit sync.Once
 var shMap ObjHostMap
 
-func getSvcPortProtocol(svc *corev1.Service) (int32, string, error) {
-	if svc == nil {
-		gslbutils.Errf("service not found, returning")
-		return 0, "", nil
+// SvcPort is a port of a service, as required for the health monitor of a GSLB service.
+type SvcPort struct {
+	Name     string
+	Port     int32
+	Protocol string
+}
+
+func getSvcPorts(svc *corev1.Service) []SvcPort {
+	ports := make([]SvcPort, 0, len(svc.Spec.Ports))
+	for _, port := range svc.Spec.Ports {
+		ports = append(ports, SvcPort{Name: port.Name, Port: port.Port, Protocol: string(port.Protocol)})
 	}
+	return ports
+}
 
+func getSvcPortProtocol(ns, name string, ports []SvcPort) (int32, string, error) {
 	var minPort int32
 	var minProto string
 
-	if len(svc.Spec.Ports) == 0 {
-		return 0, "", errors.New("service has no ports, will ignore")
+	if len(ports) == 0 {
+		return 0, "", fmt.Errorf("service %s/%s: %w", ns, name, ErrNoPort)
 	}
-	for idx, port := range svc.Spec.Ports {
+	for idx, port := range ports {
 		if port.Protocol != "" && (port.Protocol != gslbutils.ProtocolTCP && port.Protocol != gslbutils.ProtocolUDP) {
 			gslbutils.Errf("ns: %s, svc: %s, msg: can't enable health monitor for protocol %s, will use the default TCP health monitor",
-				svc.ObjectMeta.Namespace, svc.ObjectMeta.Name, port.Protocol)
+				ns, name, port.Protocol)
 			return port.Port, gslbutils.ProtocolTCP, nil
 		}
 		if idx == 0 {
 			minPort = port.Port
-			minProto = string(port.Protocol)
+			minProto = port.Protocol
 		}
 		if minPort > port.Port {
 			minPort = port.Port
-			minProto = string(port.Protocol)
+			minProto = port.Protocol
 		}
 	}
 	return minPort, minProto, nil
 }
 
+// getMatchingSvcPorts returns the ports of ports named in portNames.
+func getMatchingSvcPorts(ports []SvcPort, portNames []string) []SvcPort {
+	var matching []SvcPort
+	for _, port := range ports {
+		if gslbutils.PresentInList(port.Name, portNames) {
+			matching = append(matching, port)
+		}
+	}
+	return matching
+}
+
 func getSvcHostMap() *ObjHostMap {
-	rhMapInit.Do(func() {
-		rhMap.HostMap = make(map[string]IPHostname)
+	shMapInit.Do(func() {
+		shMap.HostMap = make(map[string]IPHostname)
 	})
-	return &rhMap
+	return &shMap
 }
 
 type SvcMeta struct {
@@ -70,9 +95,31 @@ type SvcMeta struct {
 	Namespace string
 	Hostname  string
 	IPAddr    string
-	Labels    map[string]string
-	Port      int32
-	Protocol  string
+	// VIPs are all the IP addresses exposed by the load balancer of the service with their weights,
+	// IPAddr is the first of these
+	VIPs []gslbutils.VIP
+	// LBHostname is the hostname exposed by the load balancer, when it doesn't expose an IP
+	// address (e.g. AWS load balancers). This is used as the address of the GSLB member.
+	LBHostname string
+	Labels     map[string]string
+	// Port and Protocol are the lowest port of the service and its protocol
+	Port     int32
+	Protocol string
+	// Ports are all the ports of the service, the port of the GSLB member is chosen from the ports
+	// whose names are selected by the GDP object, if any.
+	Ports []SvcPort
+	// Deleting is set for a service with a deletion timestamp, which is still present because of
+	// its finalizers, such a service is rejected, so that its GSLB member is removed
+	Deleting bool
+	// ExternalTrafficPolicy is the external traffic policy of the service, the load balancer of a
+	// service with the Local policy only forwards to the nodes running its pods.
+	ExternalTrafficPolicy string
+	// Weight is the weight of the service from the ObjectWeightAnnotation, 0 if it isn't set
+	Weight int32
+	// CreationTime is the creation timestamp of the service
+	CreationTime time.Time
+	// UID is the UID of the service, set in the references to the service for its events
+	UID types.UID
 }
 
 // GetSvcMeta returns a trimmed down version of a svc
@@ -83,20 +130,35 @@ func GetSvcMeta(svc *corev1.Service, cname string) (SvcMeta, bool) {
 		Namespace: svc.ObjectMeta.Namespace,
 		Hostname:  hostname,
 		IPAddr:    ip,
+		VIPs:      gslbutils.GetVIPs(GetSvcStatusIPs(svc), svc.GetAnnotations()),
 		Cluster:   cname,
+		Deleting:  svc.ObjectMeta.DeletionTimestamp != nil,
+		Weight:    gslbutils.GetObjectWeight(svc.GetAnnotations()),
+
+		ExternalTrafficPolicy: string(svc.Spec.ExternalTrafficPolicy),
+		CreationTime:          svc.CreationTimestamp.Time,
+		UID:                   svc.UID,
 	}
 	metaObj.Labels = make(map[string]string)
 	for key, value := range svc.GetLabels() {
 		metaObj.Labels[key] = value
 	}
 
-	if ip == "" || hostname == "" {
+	if ip == "" && hostname != "" {
+		// the load balancer only exposes a hostname, so the member has to be added via this hostname
+		gslbutils.Debugf("cluster: %s, msg: service object %s, ns: %s, no status IP, will use the hostname %s as the member address",
+			cname, svc.Name, svc.Namespace, hostname)
+		metaObj.LBHostname = hostname
+	}
+
+	if (ip == "" && metaObj.LBHostname == "") || hostname == "" {
 		gslbutils.Logf("cluster: %s, msg: service object %s, ns: %s, empty status IP %s or hostname %s",
 			cname, svc.Name, svc.Namespace, ip, hostname)
 		return metaObj, false
 	}
 
-	port, protocol, err := getSvcPortProtocol(svc)
+	metaObj.Ports = getSvcPorts(svc)
+	port, protocol, err := getSvcPortProtocol(svc.Namespace, svc.Name, metaObj.Ports)
 	if err != nil {
 		gslbutils.Errf("service rejected because of error: %s", err.Error())
 		return metaObj, false
@@ -109,15 +171,102 @@ func GetSvcMeta(svc *corev1.Service, cname string) (SvcMeta, bool) {
 	return metaObj, true
 }
 
+// GetSvcStatusIPHostname returns the first IP address and the first hostname found in the
+// load balancer status of the service. If both are present, the IP address is used as the member
+// address and the hostname as the GSLB service's domain name.
 func GetSvcStatusIPHostname(svc *corev1.Service) (string, string) {
-	if len(svc.Status.LoadBalancer.Ingress) == 0 {
-		return "", ""
+	var ip, hostname string
+	for _, lbIngress := range svc.Status.LoadBalancer.Ingress {
+		if ip == "" {
+			ip = lbIngress.IP
+		}
+		if hostname == "" {
+			hostname = lbIngress.Hostname
+		}
 	}
+	return ip, hostname
+}
 
-	ip := svc.Status.LoadBalancer.Ingress[0].IP
-	hostname := svc.Status.LoadBalancer.Ingress[0].Hostname
+// GetSvcStatusIPs returns all the IP addresses found in the load balancer status of the service,
+// in order.
+func GetSvcStatusIPs(svc *corev1.Service) []string {
+	ips := make([]string, 0, len(svc.Status.LoadBalancer.Ingress))
+	for _, lbIngress := range svc.Status.LoadBalancer.Ingress {
+		if lbIngress.IP != "" {
+			ips = append(ips, lbIngress.IP)
+		}
+	}
+	return ips
+}
 
-	return ip, hostname
+// GetSvcCksum returns the checksum of the fields of a service meta object which
+// are relevant for a GSLB service.
+func (svc SvcMeta) GetSvcCksum() uint32 {
+	return svc.getSvcNonPortCksum() + svc.GetSvcPortCksum()
+}
+
+// getSvcNonPortCksum returns the checksum of all the fields of the service except for its ports.
+func (svc SvcMeta) getSvcNonPortCksum() uint32 {
+	var cksum uint32
+	for lblKey, lblValue := range svc.Labels {
+		cksum += utils.Hash(lblKey) + utils.Hash(lblValue)
+	}
+	cksum += utils.Hash(svc.Cluster) + utils.Hash(svc.Namespace) + utils.Hash(svc.Name) +
+		utils.Hash(svc.Hostname) + utils.Hash(svc.IPAddr) + utils.Hash(svc.LBHostname) +
+		utils.Hash(strconv.FormatBool(svc.Deleting)) + utils.Hash(svc.ExternalTrafficPolicy) +
+		utils.Hash(gslbutils.GetVIPsKey(svc.VIPs)) + utils.Hash("weight"+strconv.Itoa(int(svc.Weight)))
+	return cksum
+}
+
+// GetSvcPortCksum returns the checksum of the ports of the service, and of the port and protocol
+// chosen from these.
+func (svc SvcMeta) GetSvcPortCksum() uint32 {
+	cksum := utils.Hash(strconv.Itoa(int(svc.Port))) + utils.Hash(svc.Protocol)
+	for _, port := range svc.Ports {
+		cksum += utils.Hash(port.Name + ":" + strconv.Itoa(int(port.Port)) + "/" + port.Protocol)
+	}
+	return cksum
+}
+
+// IsPortOnlyChange returns true if the service changed from old only in its ports, such a change
+// only requires the port of its GSLB members and the health monitors to be updated.
+func (svc SvcMeta) IsPortOnlyChange(old SvcMeta) bool {
+	return svc.GetSvcPortCksum() != old.GetSvcPortCksum() && svc.getSvcNonPortCksum() == old.getSvcNonPortCksum()
+}
+
+// GetLBHostname returns the hostname of the load balancer, if the load balancer doesn't
+// expose an IP address.
+func (svc SvcMeta) GetLBHostname() string {
+	return svc.LBHostname
+}
+
+// GetObjectWeight returns the weight of the service from the ObjectWeightAnnotation, 0 if it isn't set.
+func (svc SvcMeta) GetObjectWeight() int32 {
+	return svc.Weight
+}
+
+// GetCreationTime returns the creation timestamp of the service.
+func (svc SvcMeta) GetCreationTime() time.Time {
+	return svc.CreationTime
+}
+
+// GetUID returns the UID of the service.
+func (svc SvcMeta) GetUID() types.UID {
+	return svc.UID
+}
+
+// GetExternalTrafficPolicy returns the external traffic policy of the service, Cluster if unset.
+func (svc SvcMeta) GetExternalTrafficPolicy() string {
+	if svc.ExternalTrafficPolicy == "" {
+		return string(corev1.ServiceExternalTrafficPolicyTypeCluster)
+	}
+	return svc.ExternalTrafficPolicy
+}
+
+// IsLocalTrafficPolicy returns true if the service has the Local external traffic policy, i.e. its
+// traffic is served only by the endpoints local to the nodes receiving it.
+func (svc SvcMeta) IsLocalTrafficPolicy() bool {
+	return svc.ExternalTrafficPolicy == string(corev1.ServiceExternalTrafficPolicyTypeLocal)
 }
 
 func (svc SvcMeta) GetType() string {
@@ -136,6 +285,13 @@ func (svc SvcMeta) GetCluster() string {
 	return svc.Cluster
 }
 
+// CopyWithCluster returns a copy of the SvcMeta object with cname as its cluster, implements
+// gslbutils.ClusterRenamer.
+func (svc SvcMeta) CopyWithCluster(cname string) interface{} {
+	svc.Cluster = cname
+	return svc
+}
+
 func (svc SvcMeta) GetHostname() string {
 	return svc.Hostname
 }
@@ -144,20 +300,73 @@ func (svc SvcMeta) GetIPAddr() string {
 	return svc.IPAddr
 }
 
+// GetVIPs returns the IP addresses exposed by the load balancer of the service with their weights.
+func (svc SvcMeta) GetVIPs() []gslbutils.VIP {
+	return append([]gslbutils.VIP{}, svc.VIPs...)
+}
+
+// GetLabels returns a copy of the labels of the service.
+func (svc SvcMeta) GetLabels() map[string]string {
+	return copyLabels(svc.Labels)
+}
+
+// getSelectedPortProtocol returns the port (and its protocol) selected by the port names of the GDP
+// object, if more than one of the ports of the service are named in the GDP object, the port name
+// listed first in the GDP object is selected. Without any port names in the GDP object, the lowest
+// port of the service is selected.
+func (svc SvcMeta) getSelectedPortProtocol() (int32, string) {
+	portNames := gslbutils.GetGlobalFilter().GetPortNames()
+	if len(portNames) == 0 {
+		return svc.Port, svc.Protocol
+	}
+	matching := getMatchingSvcPorts(svc.Ports, portNames)
+	for _, portName := range portNames {
+		for _, svcPort := range matching {
+			if svcPort.Name != portName {
+				continue
+			}
+			if len(matching) > 1 {
+				gslbutils.Debugf("ns: %s, svc: %s, portNames: %v, port: %s, msg: more than one port matches the portNames, using the port listed first",
+					svc.Namespace, svc.Name, portNames, portName)
+			}
+			port, protocol, _ := getSvcPortProtocol(svc.Namespace, svc.Name, []SvcPort{svcPort})
+			return port, protocol
+		}
+	}
+	// the service is rejected by the filter without a matching port
+	return svc.Port, svc.Protocol
+}
+
 func (svc SvcMeta) GetPort() (int32, error) {
-	return svc.Port, nil
+	port, _ := svc.getSelectedPortProtocol()
+	return port, nil
 }
 
 func (svc SvcMeta) GetProtocol() (string, error) {
-	return svc.Protocol, nil
+	_, protocol := svc.getSelectedPortProtocol()
+	return protocol, nil
+}
+
+// GetPortNames returns the names of the ports of the service.
+func (svc SvcMeta) GetPortNames() []string {
+	portNames := make([]string, 0, len(svc.Ports))
+	for _, port := range svc.Ports {
+		portNames = append(portNames, port.Name)
+	}
+	return portNames
 }
 
 func (svc SvcMeta) GetPaths() ([]string, error) {
-	return []string{}, errors.New("service object has no paths configured")
+	return []string{}, fmt.Errorf("paths of service %s: %w", svc.Name, ErrNotSupported)
 }
 
 func (svc SvcMeta) GetTLS() (bool, error) {
-	return false, errors.New("service object doesn't have attribute TLS")
+	return false, fmt.Errorf("TLS of service %s: %w", svc.Name, ErrNotSupported)
+}
+
+// IsReady returns true if the load balancer of the service exposes an address.
+func (svc SvcMeta) IsReady() bool {
+	return svc.IPAddr != "" || svc.LBHostname != ""
 }
 
 func (svc SvcMeta) IsPassthrough() bool {
@@ -165,12 +374,13 @@ func (svc SvcMeta) IsPassthrough() bool {
 }
 
 func (svc SvcMeta) UpdateHostMap(key string) {
-	rhm := getSvcHostMap()
-	rhm.Lock.Lock()
-	defer rhm.Lock.Unlock()
-	rhm.HostMap[key] = IPHostname{
-		IP:       svc.IPAddr,
-		Hostname: svc.Hostname,
+	shm := getSvcHostMap()
+	shm.Lock.Lock()
+	defer shm.Lock.Unlock()
+	shm.HostMap[key] = IPHostname{
+		IP:         svc.IPAddr,
+		Hostname:   svc.Hostname,
+		LBHostname: svc.LBHostname,
 	}
 }
 
@@ -185,6 +395,16 @@ func (svc SvcMeta) GetHostnameFromHostMap(key string) string {
 	return ipHostname.Hostname
 }
 
+// GetBackendServices returns the service itself, as the backends of the service are its endpoints.
+func (svc SvcMeta) GetBackendServices() []string {
+	return []string{svc.Name}
+}
+
+// IsDeleting returns true if the service has a deletion timestamp.
+func (svc SvcMeta) IsDeleting() bool {
+	return svc.Deleting
+}
+
 func (svc SvcMeta) DeleteMapByKey(key string) {
 	shm := getSvcHostMap()
 	shm.Lock.Lock()
@@ -192,63 +412,8 @@ func (svc SvcMeta) DeleteMapByKey(key string) {
 	delete(shm.HostMap, key)
 }
 
+// ApplyFilter applies the global filter, which rejects a service which is being deleted or without
+// any of the ports named in the GDP object.
 func (svc SvcMeta) ApplyFilter() bool {
-	gf := gslbutils.GetGlobalFilter()
-	gf.GlobalLock.RLock()
-	gf.GlobalLock.RUnlock()
-
-	if !gslbutils.PresentInList(svc.Cluster, gf.ApplicableClusters) {
-		gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: rejected because cluster is not selected",
-			svc.Cluster, svc.Namespace, svc.Name)
-		return false
-	}
-	nsFilter := gf.NSFilter
-	// will check the namespaces first, whether the namespace for svc is selected
-	if nsFilter != nil {
-		nsFilter.Lock.RLock()
-		defer nsFilter.Lock.RUnlock()
-		nsList, ok := gf.NSFilter.SelectedNS[svc.Cluster]
-		if !ok {
-			gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: rejected because namespace is not selected",
-				svc.Cluster, svc.Namespace, svc.Name)
-			return false
-		}
-		if gslbutils.PresentInList(svc.Namespace, nsList) {
-			appFilter := gf.AppFilter
-			if appFilter == nil {
-				gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: accepted because namespace is selected",
-					svc.Cluster, svc.Namespace, svc.Name)
-				return true
-			}
-			// Check the appFilter now for this object
-			if applyAppFilter(svc.Labels, appFilter) {
-				gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: accepted because of namespaceSelector and appSelector",
-					svc.Cluster, svc.Namespace, svc.Name)
-				return true
-			}
-			gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: rejected because of appSelector",
-				svc.Cluster, svc.Namespace, svc.Name)
-			return false
-		}
-		// this means that the namespace is not selected in the filter
-		gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: rejected because namespace is not selected",
-			svc.Cluster, svc.Namespace, svc.Name)
-		return false
-	}
-
-	// Check for app filter
-	if gf.AppFilter == nil {
-		gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: rejected because no appSelector",
-			svc.Cluster, svc.Namespace, svc.Name)
-		return false
-	}
-	if !applyAppFilter(svc.Labels, gf.AppFilter) {
-		gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: rejected because of appSelector",
-			svc.Cluster, svc.Namespace, svc.Name)
-		return false
-	}
-
-	gslbutils.Logf("objType: LBSvc, cluster: %s, namespace: %s, name: %s, msg: accepted because of appSelector",
-		svc.Cluster, svc.Namespace, svc.Name)
-	return true
+	return applyGlobalFilter(svc)
 }
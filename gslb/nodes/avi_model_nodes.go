@@ -15,13 +15,12 @@
 package nodes
 
 import (
-	"strconv"
+	"errors"
+	"sort"
 	"sync"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 	"github.com/avinetworks/amko/gslb/k8sobjects"
-
-	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
 )
 
 var aviGSGraphInstance *AviGSGraphLister
@@ -79,42 +78,132 @@ type AviGSK8sObj struct {
 	Name      string
 	Namespace string
 	IPAddr    string
-	Weight    int32
+	// VIPs are all the IP addresses of the object with their weights, if the object exposes more
+	// than one, IPAddr is the first of these. Each VIP is a separate member of the GSLB service.
+	VIPs []gslbutils.VIP
+	// Fqdn is the address of the member, for members without an IP address
+	Fqdn   string
+	Weight int32
+	// Priority is the priority of the GSLB pool of this member
+	Priority int32
 	// Port and protocol will be only used by LB service
 	Port  int32
 	Proto string
 	TLS   bool
-	Paths []string
+	// TLSServerName is the SNI host for the HTTPS health monitors of a TLS member
+	TLSServerName string
+	Paths         []string
+	// HostOnly is set for a route or an ingress member without any paths, as its object type is
+	// advertised with the host only
+	HostOnly bool
 }
 
 func (gsk8sObj AviGSK8sObj) getCopy() AviGSK8sObj {
 	paths := make([]string, len(gsk8sObj.Paths))
 	copy(paths, gsk8sObj.Paths)
+	var vips []gslbutils.VIP
+	if gsk8sObj.VIPs != nil {
+		vips = append([]gslbutils.VIP{}, gsk8sObj.VIPs...)
+	}
 	obj := AviGSK8sObj{
-		Cluster:   gsk8sObj.Cluster,
-		ObjType:   gsk8sObj.ObjType,
-		Name:      gsk8sObj.Name,
-		Namespace: gsk8sObj.Namespace,
-		IPAddr:    gsk8sObj.IPAddr,
-		Weight:    gsk8sObj.Weight,
-		Port:      gsk8sObj.Port,
-		Proto:     gsk8sObj.Proto,
-		TLS:       gsk8sObj.TLS,
-		Paths:     paths,
+		Cluster:       gsk8sObj.Cluster,
+		ObjType:       gsk8sObj.ObjType,
+		Name:          gsk8sObj.Name,
+		Namespace:     gsk8sObj.Namespace,
+		IPAddr:        gsk8sObj.IPAddr,
+		VIPs:          vips,
+		Fqdn:          gsk8sObj.Fqdn,
+		Weight:        gsk8sObj.Weight,
+		Priority:      gsk8sObj.Priority,
+		Port:          gsk8sObj.Port,
+		Proto:         gsk8sObj.Proto,
+		TLS:           gsk8sObj.TLS,
+		Paths:         paths,
+		TLSServerName: gsk8sObj.TLSServerName,
+		HostOnly:      gsk8sObj.HostOnly,
 	}
 	return obj
 }
 
+// GetAddr returns the address of the member, which is the IP address if present, else the FQDN.
+func (gsk8sObj AviGSK8sObj) GetAddr() string {
+	if gsk8sObj.IPAddr != "" {
+		return gsk8sObj.IPAddr
+	}
+	return gsk8sObj.Fqdn
+}
+
+// GetVIPMembers returns a member for each VIP of this member, with the VIP as the IP address and
+// the member weight shared as per the weights of the VIPs. A member with at most one VIP is
+// returned as is.
+func (gsk8sObj AviGSK8sObj) GetVIPMembers() []AviGSK8sObj {
+	if len(gsk8sObj.VIPs) <= 1 {
+		return []AviGSK8sObj{gsk8sObj}
+	}
+	ratios := gslbutils.GetVIPRatios(gsk8sObj.Weight, gsk8sObj.VIPs)
+	members := make([]AviGSK8sObj, 0, len(gsk8sObj.VIPs))
+	for idx, vip := range gsk8sObj.VIPs {
+		member := gsk8sObj.getCopy()
+		member.IPAddr = vip.IP
+		member.VIPs = nil
+		member.Weight = ratios[idx]
+		members = append(members, member)
+	}
+	return members
+}
+
+// getMemberVIPs returns the VIPs of the objects which can expose more than one VIP, nil for the
+// other objects.
+func getMemberVIPs(metaObj k8sobjects.MetaObject) []gslbutils.VIP {
+	obj, ok := metaObj.(k8sobjects.MultiVIPObject)
+	if !ok {
+		return nil
+	}
+	return obj.GetVIPs()
+}
+
+// getObjectWeight returns the weight of an object from its annotation, 0 if the object doesn't have
+// one.
+func getObjectWeight(metaObj k8sobjects.MetaObject) int32 {
+	obj, ok := metaObj.(k8sobjects.WeightedObject)
+	if !ok {
+		return 0
+	}
+	return obj.GetObjectWeight()
+}
+
+// getTLSServerName returns the SNI host for the HTTPS health monitors of a TLS object, or an empty
+// string if the object doesn't specify one.
+func getTLSServerName(metaObj k8sobjects.MetaObject) string {
+	obj, ok := metaObj.(k8sobjects.TLSServerNameObject)
+	if !ok {
+		return ""
+	}
+	return obj.GetTLSServerName()
+}
+
+// getMemberFqdn returns the FQDN to be used as the member address for objects which don't
+// have an IP address, i.e. the hostname of their load balancer.
+func getMemberFqdn(metaObj k8sobjects.MetaObject) string {
+	obj, ok := metaObj.(k8sobjects.LBHostnameObject)
+	if !ok {
+		return ""
+	}
+	return obj.GetLBHostname()
+}
+
 type HealthMonitor struct {
 	Name      string
 	Protocol  string
 	Port      int32
 	Custom    bool
 	PathNames []string
+	// ServerName is the SNI host sent by the path based HTTPS health monitors
+	ServerName string
 }
 
 func (hm HealthMonitor) getChecksum() uint32 {
-	return gslbutils.GetGSLBHmChecksum(hm.Name, hm.Protocol, hm.Port)
+	return gslbutils.GetGSLBHmChecksum(hm.Name, hm.Protocol, hm.Port, hm.ServerName)
 }
 
 func (hm HealthMonitor) getCopy() HealthMonitor {
@@ -122,11 +211,12 @@ func (hm HealthMonitor) getCopy() HealthMonitor {
 	copy(pathNames, hm.PathNames)
 
 	hmObj := HealthMonitor{
-		Name:      hm.Name,
-		Protocol:  hm.Protocol,
-		Port:      hm.Port,
-		Custom:    hm.Custom,
-		PathNames: pathNames,
+		Name:       hm.Name,
+		Protocol:   hm.Protocol,
+		Port:       hm.Port,
+		Custom:     hm.Custom,
+		PathNames:  pathNames,
+		ServerName: hm.ServerName,
 	}
 	return hmObj
 }
@@ -142,6 +232,11 @@ type AviGSObjectGraph struct {
 	GraphChecksum uint32
 	RetryCount    int
 	Hm            HealthMonitor
+	// TTL, PoolAlgorithm and HmRefs are set from the HostOverride of the hostname of this GS,
+	// if present. HmRefs take precedence over the health monitors created for this GS.
+	TTL           *int32
+	PoolAlgorithm string
+	HmRefs        []string
 	Lock          sync.RWMutex
 }
 
@@ -185,17 +280,65 @@ func (v *AviGSObjectGraph) CalculateChecksum() {
 	var memberObjs []string
 
 	for _, gsMember := range v.MemberObjs {
-		memberIPs = append(memberIPs, gsMember.IPAddr+"-"+strconv.Itoa(int(gsMember.Weight)))
+		for _, vipMember := range gsMember.GetVIPMembers() {
+			memberIPs = append(memberIPs, gslbutils.GetGSMemberKey(vipMember.GetAddr(), vipMember.Weight, vipMember.Priority))
+		}
 		memberObjs = append(memberObjs, gsMember.ObjType+"/"+gsMember.Cluster+"/"+gsMember.Namespace+"/"+gsMember.Name)
 	}
 
 	hmNames := []string{}
-	if v.Hm.Name != "" {
+	if len(v.HmRefs) > 0 {
+		hmNames = append(hmNames, v.HmRefs...)
+	} else if v.Hm.Name != "" {
 		hmNames = append(hmNames, v.Hm.Name)
 	} else {
 		hmNames = v.Hm.PathNames
 	}
-	v.GraphChecksum = gslbutils.GetGSLBServiceChecksum(memberIPs, v.DomainNames, memberObjs, hmNames)
+	// the domain names are sorted for the checksum, the hostname of this GS must stay the first one
+	domainNames := append([]string{}, v.DomainNames...)
+	v.GraphChecksum = gslbutils.GetGSLBServiceChecksum(memberIPs, domainNames, memberObjs, hmNames) +
+		gslbutils.GetGSLBServicePropsChecksum(v.TTL, v.PoolAlgorithm)
+}
+
+// applyHostOverride sets the parameters of this GS from the HostOverride of its hostname. Without
+// a HostOverride, the parameters are reset to their defaults. The hostname is the first of the
+// domain names, followed by its aliases. The aliases which are the hostnames of the GS members are
+// skipped, as these are the domain names of their own GS graphs.
+func (v *AviGSObjectGraph) applyHostOverride() {
+	v.TTL = nil
+	v.PoolAlgorithm = gslbutils.DefaultPoolAlgorithm
+	v.HmRefs = nil
+	if len(v.DomainNames) == 0 {
+		return
+	}
+	ho, ok := gslbutils.GetHostOverride(v.DomainNames[0])
+	if !ok {
+		v.DomainNames = v.DomainNames[:1]
+		return
+	}
+	v.DomainNames = v.DomainNames[:1]
+	for _, alias := range ho.Aliases {
+		if IsGSMemberHostname(alias) {
+			gslbutils.Warnf("gsName: %s, alias: %s, msg: alias is the hostname of a GS member, skipping it",
+				v.Name, alias)
+			continue
+		}
+		v.DomainNames = append(v.DomainNames, alias)
+	}
+	v.TTL = ho.TTL
+	if ho.PoolAlgorithm != "" {
+		v.PoolAlgorithm = ho.PoolAlgorithm
+	}
+	if len(ho.HealthMonitorRefs) > 0 {
+		v.HmRefs = ho.HealthMonitorRefs
+	}
+}
+
+// UpdateHostOverride re-applies the HostOverride of the hostname of this GS.
+func (v *AviGSObjectGraph) UpdateHostOverride() {
+	v.Lock.Lock()
+	defer v.Lock.Unlock()
+	v.applyHostOverride()
 }
 
 // GetMemberRouteList returns a list of member objects
@@ -220,6 +363,17 @@ func (v *AviGSObjectGraph) buildHmPathList() {
 			ifSec = true
 		}
 	}
+	// the HTTPS health monitors send the smallest SNI host of the TLS members, so that the health
+	// monitors don't depend on the order of the members
+	v.Hm.ServerName = ""
+	for _, member := range v.MemberObjs {
+		if !member.TLS || member.TLSServerName == "" {
+			continue
+		}
+		if v.Hm.ServerName == "" || member.TLSServerName < v.Hm.ServerName {
+			v.Hm.ServerName = member.TLSServerName
+		}
+	}
 	// clear out all path based HM names first
 	v.Hm.PathNames = make([]string, 0)
 
@@ -236,10 +390,38 @@ func (v *AviGSObjectGraph) buildHmPathList() {
 	gslbutils.Debugf("gsName: %s, pathList: %v, msg: rebuilt path list for GS", v.Name, v.Hm.PathNames)
 }
 
+// isHostOnly returns true if metaObj is a route or an ingress, which is advertised with the host only
+// as it has no paths.
+func isHostOnly(metaObj k8sobjects.MetaObject, paths []string) bool {
+	return metaObj.GetType() != gslbutils.SvcType && !metaObj.IsPassthrough() && len(paths) == 0
+}
+
+// buildHostOnlyHealthMonitor builds a non-path TCP health monitor for the GS, for the route or
+// ingress members without any paths. The health monitor checks the HTTPS port if any member is TLS,
+// else the HTTP port.
+func (v *AviGSObjectGraph) buildHostOnlyHealthMonitor() {
+	v.Hm.Name = gslbutils.BuildNonPathHmName(v.Name)
+	v.Hm.Custom = true
+	v.Hm.Protocol = gslbutils.SystemHealthMonitorTypeTCP
+	v.Hm.Port = gslbutils.DefaultHTTPHealthMonitorPort
+	for _, member := range v.MemberObjs {
+		if member.TLS {
+			v.Hm.Port = gslbutils.DefaultHTTPSHealthMonitorPort
+			break
+		}
+	}
+}
+
+// isPathListUnavailable returns true if the error for the paths of an object is expected, i.e. the
+// object type has no paths (LB services, passthrough routes) or the object has no paths.
+func isPathListUnavailable(err error) bool {
+	return errors.Is(err, k8sobjects.ErrNotSupported) || errors.Is(err, k8sobjects.ErrNoPaths)
+}
+
 func (v *AviGSObjectGraph) buildNonPathHealthMonitor(metaObj k8sobjects.MetaObject, key string) {
 	port, err := metaObj.GetPort()
 	if err != nil {
-		gslbutils.Errf("key: %s, gsName: %s, msg: port not found for this object", key, v.Name)
+		gslbutils.Errf("key: %s, gsName: %s, msg: port not found for this object, %s", key, v.Name, err.Error())
 		return
 	}
 
@@ -253,7 +435,7 @@ func (v *AviGSObjectGraph) buildNonPathHealthMonitor(metaObj k8sobjects.MetaObje
 	v.Hm.Custom = true
 	protocol, err := metaObj.GetProtocol()
 	if err != nil {
-		gslbutils.Errf("key: %s, gsName: %s, msg: protocol not found for this object", key, v.Name)
+		gslbutils.Errf("key: %s, gsName: %s, msg: protocol not found for this object, %s", key, v.Name, err.Error())
 		return
 	}
 	v.MemberObjs[0].Proto = protocol
@@ -281,6 +463,11 @@ func (v *AviGSObjectGraph) buildAndAttachHealthMonitors(metaObj k8sobjects.MetaO
 		v.buildNonPathHealthMonitor(metaObj, key)
 		return
 	}
+	if len(v.Hm.PathNames) == 0 {
+		gslbutils.Debugf("key: %s, gsName: %s, msg: host only member, will build a non-path hm", key, v.Name)
+		v.buildHostOnlyHealthMonitor()
+		return
+	}
 	// else other secure/insecure route
 	v.Hm.Custom = true
 	tls, err := metaObj.GetTLS()
@@ -295,31 +482,40 @@ func (v *AviGSObjectGraph) buildAndAttachHealthMonitors(metaObj k8sobjects.MetaO
 	}
 }
 
-func (v *AviGSObjectGraph) ConstructAviGSGraph(gsName, key string, metaObj k8sobjects.MetaObject, memberWeight int32) {
+func (v *AviGSObjectGraph) ConstructAviGSGraph(gsName, key string, metaObj k8sobjects.MetaObject, memberWeight,
+	memberPriority int32) {
 	v.Lock.Lock()
 	defer v.Lock.Unlock()
 	hosts := []string{metaObj.GetHostname()}
 	tls, _ := metaObj.GetTLS()
 	paths, err := metaObj.GetPaths()
-	if err != nil {
+	if isPathListUnavailable(err) {
 		// for LB type services and passthrough routes, the path list will be empty
 		gslbutils.Debugf("key: %s, gsName: %s, msg: path list not available for object %s", key, gsName, err.Error())
+	} else if err != nil {
+		gslbutils.Errf("key: %s, gsName: %s, msg: error in getting the path list for object %s", key, gsName,
+			err.Error())
 	}
 	memberRoutes := []AviGSK8sObj{
 		{
-			Cluster:   metaObj.GetCluster(),
-			ObjType:   metaObj.GetType(),
-			IPAddr:    metaObj.GetIPAddr(),
-			Weight:    memberWeight,
-			Name:      metaObj.GetName(),
-			Namespace: metaObj.GetNamespace(),
-			TLS:       tls,
-			Paths:     paths,
+			Cluster:       metaObj.GetCluster(),
+			ObjType:       metaObj.GetType(),
+			IPAddr:        metaObj.GetIPAddr(),
+			VIPs:          getMemberVIPs(metaObj),
+			Fqdn:          getMemberFqdn(metaObj),
+			Weight:        memberWeight,
+			Priority:      memberPriority,
+			Name:          metaObj.GetName(),
+			Namespace:     metaObj.GetNamespace(),
+			TLS:           tls,
+			Paths:         paths,
+			TLSServerName: getTLSServerName(metaObj),
+			HostOnly:      isHostOnly(metaObj, paths),
 		},
 	}
-	// The GSLB service will be put into the admin tenant
+	// The GSLB service is put into the tenant of the first of its member clusters, see UpdateTenant
 	v.Name = gsName
-	v.Tenant = utils.ADMIN_NS
+	v.Tenant = gslbutils.GetClusterTenant(metaObj.GetCluster())
 	v.DomainNames = hosts
 	v.MemberObjs = memberRoutes
 	v.RetryCount = gslbutils.DefaultRetryCount
@@ -327,6 +523,7 @@ func (v *AviGSObjectGraph) ConstructAviGSGraph(gsName, key string, metaObj k8sob
 	v.buildHmPathList()
 	// Determine the health monitor(s) for this GS
 	v.buildAndAttachHealthMonitors(metaObj, key)
+	v.applyHostOverride()
 
 	v.GetChecksum()
 	gslbutils.Logf("key: %s, AviGSGraph: %s, msg: %s", key, v.Name, "created a new Avi GS graph")
@@ -373,6 +570,17 @@ func (v *AviGSObjectGraph) checkAndUpdateNonPathHealthMonitor(objType string, is
 func (v *AviGSObjectGraph) updateGSHmPathListAndProtocol() {
 	v.buildHmPathList()
 	gslbutils.Debugf("gsName: %s, added path HMs to the gslb hm path list, path hm list: %v", v.Name, v.Hm.PathNames)
+	if len(v.Hm.PathNames) == 0 {
+		// all the members are advertised with the host only
+		v.buildHostOnlyHealthMonitor()
+		return
+	}
+	if v.Hm.Name == gslbutils.BuildNonPathHmName(v.Name) {
+		// the members had no paths earlier, the path HMs replace the non-path HM
+		v.Hm.Name = ""
+		v.Hm.Port = 0
+		v.Hm.Protocol = gslbutils.GetHmTypeForTLS(v.MemberObjs[0].TLS)
+	}
 
 	// protocol change required?
 	// protocol will only be changed only if the current protocol doesn't match any of the members' protocol
@@ -393,7 +601,58 @@ func (v *AviGSObjectGraph) updateGSHmPathListAndProtocol() {
 	}
 }
 
-func (v *AviGSObjectGraph) UpdateGSMember(metaObj k8sobjects.MetaObject, weight int32) {
+// getConflictOrderKey returns the key which decides the member to be kept among the conflicting
+// objects of a cluster, the object with the smallest key is kept. The segments are escaped, so that
+// the names with a "/" (e.g. of the ingress hosts) don't collide.
+func getConflictOrderKey(ns, name, objType string) string {
+	return gslbutils.JoinKey(ns, name, objType)
+}
+
+// resolveClusterConflicts checks the members from the cluster of metaObj for a conflict, i.e. a
+// different object of the same cluster with this hostname, but with a different address. Only one
+// of the conflicting objects is kept as a member, the one with the lexicographically smallest
+// namespace/name, so that the members don't depend on the order in which the objects were added.
+// Returns false if metaObj is not to be kept as a member. The caller must hold the lock of the graph.
+func (v *AviGSObjectGraph) resolveClusterConflicts(metaObj k8sobjects.MetaObject) bool {
+	cname, ns, name, objType := metaObj.GetCluster(), metaObj.GetNamespace(), metaObj.GetName(), metaObj.GetType()
+	addr := metaObj.GetIPAddr()
+	if addr == "" {
+		addr = getMemberFqdn(metaObj)
+	}
+	objKey := getConflictOrderKey(ns, name, objType)
+	losers := []AviGSK8sObj{}
+	for _, memberObj := range v.MemberObjs {
+		if memberObj.Cluster != cname || memberObj.GetAddr() == addr {
+			continue
+		}
+		if memberObj.ObjType == objType && memberObj.Namespace == ns && memberObj.Name == name {
+			continue
+		}
+		if getConflictOrderKey(memberObj.Namespace, memberObj.Name, memberObj.ObjType) < objKey {
+			gslbutils.Warnf("gsName: %s, cluster: %s, msg: hostname conflict between %s %s/%s (%s) and %s %s/%s (%s), keeping %s/%s",
+				v.Name, cname, memberObj.ObjType, memberObj.Namespace, memberObj.Name, memberObj.GetAddr(), objType,
+				ns, name, addr, memberObj.Namespace, memberObj.Name)
+			// metaObj may have been a member before its address changed
+			for _, existing := range v.MemberObjs {
+				if existing.ObjType == objType && existing.Namespace == ns && existing.Name == name && existing.Cluster == cname {
+					v.deleteMember(cname, ns, name, objType)
+					break
+				}
+			}
+			return false
+		}
+		losers = append(losers, memberObj)
+	}
+	for _, loser := range losers {
+		gslbutils.Warnf("gsName: %s, cluster: %s, msg: hostname conflict between %s %s/%s (%s) and %s %s/%s (%s), keeping %s/%s",
+			v.Name, cname, loser.ObjType, loser.Namespace, loser.Name, loser.GetAddr(), objType, ns, name, addr,
+			ns, name)
+		v.deleteMember(loser.Cluster, loser.Namespace, loser.Name, loser.ObjType)
+	}
+	return true
+}
+
+func (v *AviGSObjectGraph) UpdateGSMember(metaObj k8sobjects.MetaObject, weight, priority int32) {
 	v.Lock.Lock()
 	defer v.Lock.Unlock()
 
@@ -401,9 +660,15 @@ func (v *AviGSObjectGraph) UpdateGSMember(metaObj k8sobjects.MetaObject, weight
 	var svcProtocol, objType string
 
 	paths, err := metaObj.GetPaths()
-	if err != nil {
+	if isPathListUnavailable(err) {
 		// for LB type services and passthrough routes
 		gslbutils.Debugf("gsName: %s, msg: path list not available for object %s", v.Name, err.Error())
+	} else if err != nil {
+		gslbutils.Errf("gsName: %s, msg: error in getting the path list for object %s", v.Name, err.Error())
+	}
+
+	if !v.resolveClusterConflicts(metaObj) {
+		return
 	}
 
 	objType = metaObj.GetType()
@@ -428,7 +693,10 @@ func (v *AviGSObjectGraph) UpdateGSMember(metaObj k8sobjects.MetaObject, weight
 		}
 		// if we reach here, it means this is the member we need to update
 		v.MemberObjs[idx].IPAddr = metaObj.GetIPAddr()
+		v.MemberObjs[idx].VIPs = getMemberVIPs(metaObj)
+		v.MemberObjs[idx].Fqdn = getMemberFqdn(metaObj)
 		v.MemberObjs[idx].Weight = weight
+		v.MemberObjs[idx].Priority = priority
 		gslbutils.Debugf("gsName: %s, msg: updating member for type %s", v.Name, metaObj.GetType())
 		if objType == gslbutils.SvcType || metaObj.IsPassthrough() {
 			v.MemberObjs[idx].Port = svcPort
@@ -437,11 +705,13 @@ func (v *AviGSObjectGraph) UpdateGSMember(metaObj k8sobjects.MetaObject, weight
 		} else {
 			tls, err := metaObj.GetTLS()
 			if err != nil {
-				gslbutils.Errf("gsName: %s, msg: didn't get tls value for this object %s", err.Error())
+				gslbutils.Errf("gsName: %s, msg: didn't get tls value for this object %s", v.Name, err.Error())
 				return
 			}
 			v.MemberObjs[idx].TLS = tls
+			v.MemberObjs[idx].TLSServerName = getTLSServerName(metaObj)
 			v.MemberObjs[idx].Paths = paths
+			v.MemberObjs[idx].HostOnly = isHostOnly(metaObj, paths)
 			v.updateGSHmPathListAndProtocol()
 		}
 		return
@@ -453,11 +723,19 @@ func (v *AviGSObjectGraph) UpdateGSMember(metaObj k8sobjects.MetaObject, weight
 		Namespace: metaObj.GetNamespace(),
 		Name:      metaObj.GetName(),
 		IPAddr:    metaObj.GetIPAddr(),
+		VIPs:      getMemberVIPs(metaObj),
+		Fqdn:      getMemberFqdn(metaObj),
 		Weight:    weight,
+		Priority:  priority,
 		ObjType:   metaObj.GetType(),
 		Port:      svcPort,
 		Proto:     svcProtocol,
 		Paths:     paths,
+		HostOnly:  isHostOnly(metaObj, paths),
+	}
+	if objType != gslbutils.SvcType && !metaObj.IsPassthrough() {
+		gsMember.TLS, _ = metaObj.GetTLS()
+		gsMember.TLSServerName = getTLSServerName(metaObj)
 	}
 	v.MemberObjs = append(v.MemberObjs, gsMember)
 	if objType == gslbutils.SvcType || metaObj.IsPassthrough() {
@@ -467,10 +745,73 @@ func (v *AviGSObjectGraph) UpdateGSMember(metaObj k8sobjects.MetaObject, weight
 	}
 }
 
+// UpdateMemberRatio updates the weight and the priority of the member for an object. Returns true
+// if the member was found and its weight or priority changed.
+func (v *AviGSObjectGraph) UpdateMemberRatio(cname, ns, name, objType string, weight, priority int32) bool {
+	v.Lock.Lock()
+	defer v.Lock.Unlock()
+	for idx, memberObj := range v.MemberObjs {
+		if objType != memberObj.ObjType || cname != memberObj.Cluster || ns != memberObj.Namespace || name != memberObj.Name {
+			continue
+		}
+		if memberObj.Weight == weight && memberObj.Priority == priority {
+			return false
+		}
+		v.MemberObjs[idx].Weight = weight
+		v.MemberObjs[idx].Priority = priority
+		return true
+	}
+	return false
+}
+
+// UpdateMemberPort updates the port and the protocol of the member for an LB service, and the
+// health monitor of the GS. Returns false if no member was found for the service, the caller has
+// to add the member.
+func (v *AviGSObjectGraph) UpdateMemberPort(cname, ns, name string, port int32, protocol string) bool {
+	v.Lock.Lock()
+	defer v.Lock.Unlock()
+	for idx, memberObj := range v.MemberObjs {
+		if memberObj.ObjType != gslbutils.SvcType || cname != memberObj.Cluster || ns != memberObj.Namespace || name != memberObj.Name {
+			continue
+		}
+		v.MemberObjs[idx].Port = port
+		v.MemberObjs[idx].Proto = protocol
+		v.checkAndUpdateNonPathHealthMonitor(gslbutils.SvcType, false)
+		return true
+	}
+	return false
+}
+
+// UpdateTenant moves the GS to the Avi tenant of the first of its member clusters in the sorted
+// order, so that the GS of a hostname is in a single tenant irrespective of the tenants of its
+// member clusters. A GS without members stays in its tenant. Returns the previous and the current
+// tenants.
+func (v *AviGSObjectGraph) UpdateTenant() (string, string) {
+	v.Lock.Lock()
+	defer v.Lock.Unlock()
+	prevTenant := v.Tenant
+	cname := ""
+	for _, memberObj := range v.MemberObjs {
+		if cname == "" || memberObj.Cluster < cname {
+			cname = memberObj.Cluster
+		}
+	}
+	if cname != "" {
+		v.Tenant = gslbutils.GetClusterTenant(cname)
+	}
+	return prevTenant, v.Tenant
+}
+
 func (v *AviGSObjectGraph) DeleteMember(cname, ns, name, objType string) {
-	idx := -1
 	v.Lock.Lock()
 	defer v.Lock.Unlock()
+	v.deleteMember(cname, ns, name, objType)
+}
+
+// deleteMember removes the member for an object and updates the health monitors, the caller must
+// hold the lock of the graph.
+func (v *AviGSObjectGraph) deleteMember(cname, ns, name, objType string) {
+	idx := -1
 	for i, memberObj := range v.MemberObjs {
 		if objType == memberObj.ObjType && cname == memberObj.Cluster && ns == memberObj.Namespace && name == memberObj.Name {
 			idx = i
@@ -491,7 +832,7 @@ func (v *AviGSObjectGraph) DeleteMember(cname, ns, name, objType string) {
 	for _, member := range v.MemberObjs {
 		// update non path based health monitor only for LB services or non-path based members
 		isPassthrough := false
-		if member.ObjType != gslbutils.SvcType && len(member.Paths) == 0 {
+		if member.ObjType != gslbutils.SvcType && len(member.Paths) == 0 && !member.HostOnly {
 			// this is a passthrough member
 			isPassthrough = true
 		}
@@ -504,6 +845,38 @@ func (v *AviGSObjectGraph) DeleteMember(cname, ns, name, objType string) {
 	v.updateGSHmPathListAndProtocol()
 }
 
+// RenameMemberCluster renames the cluster oldName of the members to newName. A member of oldName
+// is dropped if the same object already has a member for newName. Returns true if any member was
+// renamed or dropped.
+func (v *AviGSObjectGraph) RenameMemberCluster(oldName, newName string) bool {
+	v.Lock.Lock()
+	defer v.Lock.Unlock()
+	changed := false
+	memberObjs := v.MemberObjs[:0]
+	for _, member := range v.MemberObjs {
+		if member.Cluster == oldName {
+			changed = true
+			if v.hasMember(newName, member.Namespace, member.Name, member.ObjType) {
+				continue
+			}
+			member.Cluster = newName
+		}
+		memberObjs = append(memberObjs, member)
+	}
+	v.MemberObjs = memberObjs
+	return changed
+}
+
+// hasMember returns true if the object has a member, the caller must hold the lock of the graph.
+func (v *AviGSObjectGraph) hasMember(cname, ns, name, objType string) bool {
+	for _, member := range v.MemberObjs {
+		if member.ObjType == objType && member.Cluster == cname && member.Namespace == ns && member.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 func (v *AviGSObjectGraph) IsHmTypeCustom() bool {
 	v.Lock.RLock()
 	defer v.Lock.RUnlock()
@@ -524,6 +897,22 @@ func (v *AviGSObjectGraph) MembersLen() int {
 	return len(v.MemberObjs)
 }
 
+// GetHealthyClusters returns the number of clusters which have a member with a non-zero weight in
+// this GS, the members with a weight of 0 don't get any traffic. A cluster is healthy as per the
+// weights of its members (e.g. the backends weight mode sets a weight of 0 for a member without
+// ready backends), not as per the health monitors of the GS, which aren't known to AMKO.
+func (v *AviGSObjectGraph) GetHealthyClusters() int {
+	v.Lock.RLock()
+	defer v.Lock.RUnlock()
+	clusters := []string{}
+	for _, member := range v.MemberObjs {
+		if member.Weight > 0 && !gslbutils.PresentInList(member.Cluster, clusters) {
+			clusters = append(clusters, member.Cluster)
+		}
+	}
+	return len(clusters)
+}
+
 func (v *AviGSObjectGraph) GetGSMember(cname, ns, name string) AviGSK8sObj {
 	v.Lock.RLock()
 	defer v.Lock.RUnlock()
@@ -544,13 +933,16 @@ func (v *AviGSObjectGraph) GetMemberObjs() []AviGSK8sObj {
 		objs[idx].Name = v.MemberObjs[idx].Name
 		objs[idx].Namespace = v.MemberObjs[idx].Namespace
 		objs[idx].IPAddr = v.MemberObjs[idx].IPAddr
+		objs[idx].VIPs = append([]gslbutils.VIP{}, v.MemberObjs[idx].VIPs...)
+		objs[idx].Fqdn = v.MemberObjs[idx].Fqdn
 		objs[idx].Weight = v.MemberObjs[idx].Weight
+		objs[idx].Priority = v.MemberObjs[idx].Priority
 		objs[idx].ObjType = v.MemberObjs[idx].ObjType
 	}
 	return objs
 }
 
-// GetUniqueMemberList returns a non-duplicated list of objects, uniqueness is checked by the IPAddr
+// GetUniqueMemberList returns a non-duplicated list of objects, uniqueness is checked by the member address
 func (v *AviGSObjectGraph) GetUniqueMemberObjs() []AviGSK8sObj {
 	v.Lock.RLock()
 	defer v.Lock.RUnlock()
@@ -558,23 +950,46 @@ func (v *AviGSObjectGraph) GetUniqueMemberObjs() []AviGSK8sObj {
 	memberVips := []string{}
 	uniqueObjs := []AviGSK8sObj{}
 
-	for _, memberObj := range v.MemberObjs {
-		if gslbutils.PresentInList(memberObj.IPAddr, memberVips) {
-			continue
+	for _, gsMember := range v.MemberObjs {
+		// each VIP of a member is a separate member
+		for _, memberObj := range gsMember.GetVIPMembers() {
+			if gslbutils.PresentInList(memberObj.GetAddr(), memberVips) {
+				continue
+			}
+			uniqueObjs = append(uniqueObjs, AviGSK8sObj{
+				Cluster:   memberObj.Cluster,
+				ObjType:   memberObj.ObjType,
+				Name:      memberObj.Name,
+				Namespace: memberObj.Namespace,
+				IPAddr:    memberObj.IPAddr,
+				Fqdn:      memberObj.Fqdn,
+				Weight:    memberObj.Weight,
+				Priority:  memberObj.Priority,
+			})
+			memberVips = append(memberVips, memberObj.GetAddr())
 		}
-		uniqueObjs = append(uniqueObjs, AviGSK8sObj{
-			Cluster:   memberObj.Cluster,
-			ObjType:   memberObj.ObjType,
-			Name:      memberObj.Name,
-			Namespace: memberObj.Namespace,
-			IPAddr:    memberObj.IPAddr,
-			Weight:    memberObj.Weight,
-		})
-		memberVips = append(memberVips, memberObj.IPAddr)
+	}
+	if v.PoolAlgorithm == gslbutils.ConsistentHashPoolAlgorithm {
+		sortMembersByHashRing(uniqueObjs)
 	}
 	return uniqueObjs
 }
 
+// sortMembersByHashRing sorts the members in the stable order of the hash ring of their clusters,
+// so that adding or removing the members of a cluster doesn't reorder the members of the other
+// clusters for the consistent hash algorithm.
+func sortMembersByHashRing(members []AviGSK8sObj) {
+	sort.SliceStable(members, func(i, j int) bool {
+		if members[i].Cluster != members[j].Cluster {
+			return gslbutils.HashRingLess(members[i].Cluster, members[j].Cluster)
+		}
+		if members[i].Namespace != members[j].Namespace {
+			return members[i].Namespace < members[j].Namespace
+		}
+		return members[i].Name < members[j].Name
+	})
+}
+
 func (v *AviGSObjectGraph) GetCopy() *AviGSObjectGraph {
 	v.Lock.RLock()
 	defer v.Lock.RUnlock()
@@ -589,6 +1004,15 @@ func (v *AviGSObjectGraph) GetCopy() *AviGSObjectGraph {
 		GraphChecksum: v.GraphChecksum,
 		RetryCount:    v.RetryCount,
 		Hm:            v.Hm.getCopy(),
+		PoolAlgorithm: v.PoolAlgorithm,
+	}
+	if v.TTL != nil {
+		ttl := *v.TTL
+		gsObjCopy.TTL = &ttl
+	}
+	if v.HmRefs != nil {
+		gsObjCopy.HmRefs = make([]string, len(v.HmRefs))
+		copy(gsObjCopy.HmRefs, v.HmRefs)
 	}
 
 	gsObjCopy.MemberObjs = make([]AviGSK8sObj, 0)
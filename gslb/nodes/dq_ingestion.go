@@ -17,82 +17,184 @@ package nodes
 import (
 	"errors"
 	"sync"
+	"time"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
 	"github.com/davecgh/go-spew/spew"
 
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
 )
 
-func DeriveGSLBServiceName(hostname string) string {
-	// This function is a place-holder for deriving the GSLB service name
-	// For now, the hostname of a route is the GSLB Service name
-	return hostname
+// gslbNameHost is the GSLB service name pinned to a hostname, and the members which refer to it.
+type gslbNameHost struct {
+	gsName  string
+	members map[string]struct{}
+}
+
+// gslbNameHosts are the hostnames of the GS members, keyed by the GSLB service name they map to. A
+// hostname keeps the name it was resolved to while any object has a member for it, so that the
+// names of the GS graphs don't change with the order in which the other hostnames are seen.
+var gslbNameHosts = struct {
+	hosts map[string]map[string]*gslbNameHost
+	lock  sync.Mutex
+}{hosts: make(map[string]map[string]*gslbNameHost)}
+
+// DeriveGSLBServiceName returns the GSLB service name pinned to hostname, or the name it would be
+// resolved to against the hostnames pinned so far. It doesn't pin the name, only the members added
+// to the GS graphs do.
+func DeriveGSLBServiceName(hostname string) (string, error) {
+	gslbNameHosts.lock.Lock()
+	defer gslbNameHosts.lock.Unlock()
+	key := gslbutils.GslbNameForHostname(hostname)
+	if host, ok := gslbNameHosts.hosts[key][hostname]; ok {
+		return host.gsName, nil
+	}
+	return resolveGSLBServiceName(hostname, key)
+}
+
+// resolveGSLBServiceName resolves the name of hostname, the hostname pinned to key owns it.
+func resolveGSLBServiceName(hostname, key string) (string, error) {
+	for _, host := range gslbNameHosts.hosts[key] {
+		if host.gsName == key {
+			return gslbutils.ResolveGslbNameCollision(hostname)
+		}
+	}
+	return gslbutils.ResolveGslbName([]string{hostname})
+}
+
+// IsGSMemberHostname returns true if hostname is the hostname of a member of a GS graph.
+func IsGSMemberHostname(hostname string) bool {
+	gslbNameHosts.lock.Lock()
+	defer gslbNameHosts.lock.Unlock()
+	_, ok := gslbNameHosts.hosts[gslbutils.GslbNameForHostname(hostname)][hostname]
+	return ok
+}
+
+// pinGSLBServiceName returns the GSLB service name of hostname, and pins it for the member
+// memberKey, unless it is rejected with gslbutils.ErrGslbNameCollision.
+func pinGSLBServiceName(hostname, memberKey string) (string, error) {
+	gslbNameHosts.lock.Lock()
+	key := gslbutils.GslbNameForHostname(hostname)
+	host, ok := gslbNameHosts.hosts[key][hostname]
+	if !ok {
+		gsName, err := resolveGSLBServiceName(hostname, key)
+		if err != nil {
+			gslbNameHosts.lock.Unlock()
+			return "", err
+		}
+		if _, ok := gslbNameHosts.hosts[key]; !ok {
+			gslbNameHosts.hosts[key] = make(map[string]*gslbNameHost)
+		}
+		host = &gslbNameHost{gsName: gsName, members: make(map[string]struct{})}
+		gslbNameHosts.hosts[key][hostname] = host
+	}
+	host.members[memberKey] = struct{}{}
+	gsName := host.gsName
+	gslbNameHosts.lock.Unlock()
+
+	if !ok {
+		// the hostname can't be an alias anymore, as it has a GS graph of its own
+		applyAliasedHostOverride(hostname)
+	}
+	return gsName, nil
+}
+
+// releaseGSLBServiceName releases the name of hostname for the member memberKey, the name is
+// unpinned once no member refers to it.
+func releaseGSLBServiceName(hostname, memberKey string) {
+	gslbNameHosts.lock.Lock()
+	key := gslbutils.GslbNameForHostname(hostname)
+	host, ok := gslbNameHosts.hosts[key][hostname]
+	if !ok {
+		gslbNameHosts.lock.Unlock()
+		return
+	}
+	delete(host.members, memberKey)
+	if len(host.members) != 0 {
+		gslbNameHosts.lock.Unlock()
+		return
+	}
+	delete(gslbNameHosts.hosts[key], hostname)
+	if len(gslbNameHosts.hosts[key]) == 0 {
+		delete(gslbNameHosts.hosts, key)
+	}
+	gslbNameHosts.lock.Unlock()
+
+	// the hostname can be an alias again
+	applyAliasedHostOverride(hostname)
+}
+
+// applyAliasedHostOverride re-applies the HostOverride which has hostname as an alias, if any.
+func applyAliasedHostOverride(hostname string) {
+	if fqdn, ok := gslbutils.GetAliasedFqdn(hostname); ok {
+		ApplyHostOverride(fqdn)
+	}
+}
+
+// getGSMemberKey returns the key of the member of an object in the GS graph of a hostname.
+func getGSMemberKey(cname, ns, objName, objType string) string {
+	return gslbutils.JoinKey(objType, cname, ns, objName)
 }
 
 func PublishKeyToRestLayer(tenant, gsName, key string, sharedQueue *utils.WorkerQueue) {
 	// First see if there's another instance of the same model in the store
-	modelName := tenant + "/" + gsName
+	modelName := gslbutils.GetModelKey(tenant, gsName)
 	bkt := utils.Bkt(modelName, sharedQueue.NumWorkers)
 	sharedQueue.Workqueue[bkt].AddRateLimited(modelName)
 	gslbutils.Logf("key: %s, modelName: %s, msg: %s", key, modelName, "published key to rest layer")
 }
 
-func GetObjTrafficRatio(ns, cname string) int32 {
+func GetObjTrafficRatio(ns, cname string, labels map[string]string, objWeight int32) int32 {
 	globalFilter := gslbutils.GetGlobalFilter()
 	if globalFilter == nil {
 		// return default traffic ratio
 		gslbutils.Errf("ns: %s, cname: %s, msg: global filter can't be nil at this stage", ns, cname)
 		return 1
 	}
-	val, err := globalFilter.GetTrafficWeight(ns, cname)
+	val, err := globalFilter.GetTrafficWeight(ns, cname, labels, objWeight)
+	if errors.Is(err, gslbutils.ErrNoTrafficWeight) {
+		gslbutils.Warnf("ns: %s, cname: %s, msg: no traffic weight for this cluster, using the default ratio, %s",
+			ns, cname, err.Error())
+		return 1
+	}
 	if err != nil {
-		gslbutils.Warnf("ns: %s, cname: %s, msg: error occured while fetching traffic info for this cluster, %s",
+		gslbutils.Errf("ns: %s, cname: %s, msg: error occured while fetching traffic info for this cluster, %s",
 			ns, cname, err.Error())
 		return 1
 	}
+	if globalFilter.GetWeightMode() == gdpv1alpha1.WeightModePercentage {
+		return gslbutils.PercentageToRatio(val)
+	}
 	return val
 }
 
+// GetObjTrafficPriority returns the priority of the GSLB pool for the members of cluster cname.
+func GetObjTrafficPriority(cname string, labels map[string]string) int32 {
+	globalFilter := gslbutils.GetGlobalFilter()
+	if globalFilter == nil {
+		return gslbutils.DefaultPriority
+	}
+	return int32(globalFilter.GetTrafficPriority(cname, labels))
+}
+
 func getObjFromStore(objType, cname, ns, objName, key, storeType string) interface{} {
+	handlers, ok := gslbutils.GetObjTypeHandlers(objType)
+	if !ok {
+		gslbutils.Errf("key: %s, objType: %s, msg: unrecognised object type, can't fetch the object", key, objType)
+		return nil
+	}
 	var store *gslbutils.ClusterStore
-	switch objType {
-	case gslbutils.RouteType:
-		if storeType == gslbutils.AcceptedStore {
-			store = gslbutils.GetAcceptedRouteStore()
-		} else {
-			store = gslbutils.GetRejectedRouteStore()
-		}
-		if store == nil {
-			// Error state, the route store is not updated, so we can't do anything here
-			gslbutils.Errf("key: %s, msg: %s", key, "accepted route store is empty, can't add route")
-			return nil
-		}
-		break
-	case gslbutils.IngressType:
-		if storeType == gslbutils.AcceptedStore {
-			store = gslbutils.GetAcceptedIngressStore()
-		} else {
-			store = gslbutils.GetRejectedIngressStore()
-		}
-		if store == nil {
-			gslbutils.Errf("key: %s, msg: %s", key, "accepted ingress store is empty, can't add ingress")
-			return nil
-		}
-		break
-
-	case gslbutils.SvcType:
-		if storeType == gslbutils.AcceptedStore {
-			store = gslbutils.GetAcceptedLBSvcStore()
-		} else {
-			store = gslbutils.GetRejectedLBSvcStore()
-		}
-		if store == nil {
-			gslbutils.Errf("key: %s, msg: %s", key, "accepted svc store is empty, can't add svc")
-			return nil
-		}
-		break
+	if storeType == gslbutils.AcceptedStore {
+		store = handlers.AcceptedStore()
+	} else {
+		store = handlers.RejectedStore()
+	}
+	if store == nil {
+		// Error state, the store is not updated, so we can't do anything here
+		gslbutils.Errf("key: %s, objType: %s, msg: %s store is empty, can't add the object", key, objType, storeType)
+		return nil
 	}
 	obj, ok := store.GetClusterNSObjectByName(cname, ns, objName)
 	if !ok {
@@ -117,7 +219,6 @@ func PublishAllGraphKeys() {
 func AddUpdateObjOperation(key, cname, ns, objType, objName string, wq *utils.WorkerQueue,
 	fullSync bool, agl *AviGSGraphLister) {
 
-	var prevChecksum, newChecksum uint32
 	obj := getObjFromStore(objType, cname, ns, objName, key, gslbutils.AcceptedStore)
 	if obj == nil {
 		// error message already logged in the above function
@@ -128,32 +229,69 @@ func AddUpdateObjOperation(key, cname, ns, objType, objName string, wq *utils.Wo
 		gslbutils.Errf("key: %s, msg: %s", key, "no hostname for object, not supported")
 		return
 	}
-	if metaObj.GetIPAddr() == "" {
+	if remaining := getRemainingMinAge(metaObj); remaining > 0 {
+		gslbutils.Logf("key: %s, remaining: %s, msg: object is younger than the min age, will be added once old enough",
+			key, remaining)
+		requeueIngestionKey(key, metaObj.GetHostname(), remaining)
+		return
+	}
+	if metaObj.GetIPAddr() == "" && getMemberFqdn(metaObj) == "" {
 		// IP Address not found, no use adding this as a GS
 		gslbutils.Errf("key: %s, msg: %s", key, "no IP address found for the object")
 		return
 	}
 	// get the traffic ratio for this member
-	memberWeight := GetObjTrafficRatio(ns, cname)
-	gsName := DeriveGSLBServiceName(metaObj.GetHostname())
-	modelName := utils.ADMIN_NS + "/" + gsName
-	found, aviGS := agl.Get(modelName)
+	memberWeight := GetObjTrafficRatio(ns, cname, metaObj.GetLabels(), getObjectWeight(metaObj))
+	memberPriority := GetObjTrafficPriority(cname, metaObj.GetLabels())
+	clusterObj := gslbutils.GetClusterKey(cname, ns, objName)
+
+	// remove the object from the GSs of the extra hosts which are no longer a part of this object
+	for _, host := range getStaleExtraHosts(metaObj, clusterObj) {
+		gslbutils.Logf("key: %s, hostname: %s, msg: host removed from the object", key, host)
+		deleteMemberFromGS(key, host, cname, ns, objName, objType, wq)
+	}
+	addUpdateGSMember(key, metaObj, memberWeight, memberPriority, wq, fullSync, agl)
+	for _, hostMetaObj := range getExtraHostMetaObjs(metaObj) {
+		addUpdateGSMember(key, hostMetaObj, memberWeight, memberPriority, wq, fullSync, agl)
+	}
+	// Update the hostname in the RouteHostMap
+	metaObj.UpdateHostMap(clusterObj)
+}
+
+// addUpdateGSMember adds or updates the member for metaObj in the GS graph of its hostname and
+// publishes the GS graph to the rest layer if it changed.
+func addUpdateGSMember(key string, metaObj k8sobjects.MetaObject, memberWeight, memberPriority int32,
+	wq *utils.WorkerQueue, fullSync bool, agl *AviGSGraphLister) {
+
+	var prevChecksum, newChecksum uint32
+	gsName, err := pinGSLBServiceName(metaObj.GetHostname(), getGSMemberKey(metaObj.GetCluster(),
+		metaObj.GetNamespace(), metaObj.GetName(), metaObj.GetType()))
+	if err != nil {
+		gslbutils.Warnf("key: %s, hostname: %s, msg: can't derive the GSLB service name, won't add the member: %s",
+			key, metaObj.GetHostname(), err.Error())
+		return
+	}
+	tenant, gsGraph, found := getGSGraph(agl, gsName)
 	if !found {
+		tenant = gslbutils.GetClusterTenant(metaObj.GetCluster())
+		modelName := gslbutils.GetModelKey(tenant, gsName)
 		gslbutils.Logf("key: %s, modelName: %s, msg: %s", key, modelName, "generating new model")
-		aviGS = NewAviGSObjectGraph()
+		gsGraph = NewAviGSObjectGraph()
 		// Note: For now, the hostname is used as a way to create the GSLB services. This is on the
 		// assumption that the hostnames are same for a route across all clusters.
-		aviGS.(*AviGSObjectGraph).ConstructAviGSGraph(gsName, key, metaObj, memberWeight)
+		gsGraph.ConstructAviGSGraph(gsName, key, metaObj, memberWeight, memberPriority)
 		gslbutils.Debugf(spew.Sprintf("key: %s, gsName: %s, model: %v, msg: constructed new model", key, modelName,
-			*(aviGS.(*AviGSObjectGraph))))
-		agl.Save(modelName, aviGS.(*AviGSObjectGraph))
+			*gsGraph))
+		agl.Save(modelName, gsGraph)
 	} else {
-		gsGraph := aviGS.(*AviGSObjectGraph)
 		prevHmChecksum := gsGraph.GetHmChecksum()
 		// since the object was found, fetch the current checksum
 		prevChecksum = gsGraph.GetChecksum()
 		// GSGraph found, so, only need to update the member of the GSGraph's GSNode
-		aviGS.(*AviGSObjectGraph).UpdateGSMember(metaObj, memberWeight)
+		gsGraph.UpdateGSMember(metaObj, memberWeight, memberPriority)
+		// the first member cluster may have changed, and with it, the tenant of the GS
+		var moved bool
+		tenant, moved = updateGSGraphTenant(key, gsGraph, agl, wq)
 		// Get the new checksum after the updates
 		newChecksum = gsGraph.GetChecksum()
 		newHmChecksum := gsGraph.GetHmChecksum()
@@ -161,37 +299,100 @@ func AddUpdateObjOperation(key, cname, ns, objType, objName string, wq *utils.Wo
 		gslbutils.Debugf("prevChecksum: %d, newChecksum: %d, prevHmChecksum: %d, newHmChecksum: %d, key: %s", prevChecksum,
 			newChecksum, prevHmChecksum, newHmChecksum, key)
 
-		if (prevChecksum == newChecksum) && (prevHmChecksum == newHmChecksum) {
+		if !moved && (prevChecksum == newChecksum) && (prevHmChecksum == newHmChecksum) {
 			// Checksums are same, return
 			gslbutils.Debugf(spew.Sprintf("key: %s, gsName: %s, model: %v, msg: %s", key, gsName, *gsGraph,
 				"the model for this key has identical checksums"))
 			return
 		}
-		aviGS.(*AviGSObjectGraph).SetRetryCounter()
+		gsGraph.SetRetryCounter()
 		gslbutils.Debugf(spew.Sprintf("key: %s, gsName: %s, model: %v, msg: %s", key, gsName, *gsGraph,
 			"updated the model"))
-		agl.Save(modelName, aviGS.(*AviGSObjectGraph))
+		agl.Save(gslbutils.GetModelKey(tenant, gsName), gsGraph)
 	}
-	// Update the hostname in the RouteHostMap
-	metaObj.UpdateHostMap(cname + "/" + ns + "/" + objName)
-
 	if !fullSync || gslbutils.IsControllerLeader() {
 
-		PublishKeyToRestLayer(utils.ADMIN_NS, gsName, key, wq)
+		PublishKeyToRestLayer(tenant, gsName, key, wq)
 	}
 }
 
-func GetNewObj(objType string) (k8sobjects.MetaObject, error) {
-	switch objType {
-	case gslbutils.RouteType:
-		return k8sobjects.RouteMeta{}, nil
-	case gslbutils.IngressType:
-		return k8sobjects.IngressHostMeta{}, nil
-	case gslbutils.SvcType:
-		return k8sobjects.SvcMeta{}, nil
-	default:
-		return nil, errors.New("unrecognised object: " + objType)
+// getGSGraph returns the tenant and the GS graph of gsName from agl, the GS graph of a GSLB service
+// name is in a single tenant.
+func getGSGraph(agl *AviGSGraphLister, gsName string) (string, *AviGSObjectGraph, bool) {
+	for _, tenant := range gslbutils.GetAllTenants() {
+		found, aviGS := agl.Get(gslbutils.GetModelKey(tenant, gsName))
+		if found && aviGS != nil {
+			return tenant, aviGS.(*AviGSObjectGraph), true
+		}
+	}
+	return "", nil, false
+}
+
+// updateGSGraphTenant moves gsGraph to the tenant of its first member cluster, if that changed. The
+// GS in the previous tenant is deleted, the caller saves and publishes the GS graph in the new
+// tenant. Returns the tenant of the GS graph, and true if it was moved.
+func updateGSGraphTenant(key string, gsGraph *AviGSObjectGraph, agl *AviGSGraphLister,
+	wq *utils.WorkerQueue) (string, bool) {
+	prevTenant, tenant := gsGraph.UpdateTenant()
+	if prevTenant == tenant {
+		return tenant, false
+	}
+	prevGraph := gsGraph.GetCopy()
+	prevGraph.Tenant = prevTenant
+	prevModelName := gslbutils.GetModelKey(prevTenant, prevGraph.Name)
+	SharedDeleteGSGraphLister().Save(prevModelName, prevGraph)
+	agl.Delete(prevModelName)
+	gslbutils.Logf("key: %s, gsName: %s, prevTenant: %s, tenant: %s, msg: moved the GS graph to the tenant of its first member cluster",
+		key, prevGraph.Name, prevTenant, tenant)
+	if gslbutils.IsControllerLeader() {
+		PublishKeyToRestLayer(prevTenant, prevGraph.Name, key, wq)
+	}
+	return tenant, true
+}
+
+// getExtraHostMetaObjs returns a meta object for each of the extra hosts (the SNI hosts of a
+// passthrough route and the additional hosts) accepted by the global filter, if metaObj is a route.
+func getExtraHostMetaObjs(metaObj k8sobjects.MetaObject) []k8sobjects.MetaObject {
+	route, ok := metaObj.(k8sobjects.RouteMeta)
+	if !ok {
+		return nil
+	}
+	metaObjs := []k8sobjects.MetaObject{}
+	for _, hostMeta := range route.GetAcceptedExtraHostMetas() {
+		metaObjs = append(metaObjs, hostMeta)
+	}
+	return metaObjs
+}
+
+// getExtraHostsFromHostMap returns the extra hosts saved in the host map for a route.
+func getExtraHostsFromHostMap(metaObj k8sobjects.MetaObject, clusterObj string) []string {
+	route, ok := metaObj.(k8sobjects.RouteMeta)
+	if !ok {
+		return nil
+	}
+	return route.GetExtraHostsFromHostMap(clusterObj)
+}
+
+// getStaleExtraHosts returns the extra hosts saved in the host map for a route, which are no longer
+// the accepted hosts of the route.
+func getStaleExtraHosts(metaObj k8sobjects.MetaObject, clusterObj string) []string {
+	route, ok := metaObj.(k8sobjects.RouteMeta)
+	if !ok {
+		return nil
+	}
+	staleHosts := []string{}
+	extraHosts := route.GetAcceptedExtraHosts()
+	for _, host := range route.GetExtraHostsFromHostMap(clusterObj) {
+		if host == route.Hostname || gslbutils.PresentInList(host, extraHosts) {
+			continue
+		}
+		staleHosts = append(staleHosts, host)
 	}
+	return staleHosts
+}
+
+func GetNewObj(objType string) (k8sobjects.MetaObject, error) {
+	return k8sobjects.GetNewMetaObj(objType)
 }
 
 func deleteObjOperation(key, cname, ns, objType, objName string, wq *utils.WorkerQueue) {
@@ -203,74 +404,414 @@ func deleteObjOperation(key, cname, ns, objType, objName string, wq *utils.Worke
 		return
 	}
 
-	clusterObj := cname + "/" + ns + "/" + objName
+	clusterObj := gslbutils.GetClusterKey(cname, ns, objName)
 	// TODO: revisit this section to see if we really need this, or can we make do with metaObj
 	hostname := metaObj.GetHostnameFromHostMap(clusterObj)
 	if hostname == "" {
 		gslbutils.Logf("key: %s, msg: no hostname for the %s object", key, objType)
 		return
 	}
-	gsName := hostname
-	modelName := utils.ADMIN_NS + "/" + hostname
-
-	deleteGs := false
-	agl := SharedAviGSGraphLister()
-	found, aviGS := agl.Get(modelName)
-	if found {
-		if aviGS == nil {
-			gslbutils.Warnf("key: %s, msg: no avi graph found for this key", key)
-			return
+	hostnames := append([]string{hostname}, getExtraHostsFromHostMap(metaObj, clusterObj)...)
+	membersDeleted := false
+	for _, host := range hostnames {
+		if deleteMemberFromGS(key, host, cname, ns, objName, objType, wq) {
+			membersDeleted = true
 		}
-		uniqueMembersLen := len(aviGS.(*AviGSObjectGraph).GetUniqueMemberObjs())
-		aviGS.(*AviGSObjectGraph).DeleteMember(cname, ns, objName, objType)
+	}
+	if membersDeleted {
 		// delete the obj from the hostname map
-		newUniqueMemberLen := len(aviGS.(*AviGSObjectGraph).GetUniqueMemberObjs())
-		if uniqueMembersLen != newUniqueMemberLen {
-			metaObj.DeleteMapByKey(clusterObj)
-		}
-		gslbutils.Debugf("key: %s, gsMembers: %d, msg: checking if its a GS deletion case", key,
-			aviGS.(*AviGSObjectGraph).GetUniqueMemberObjs())
-		if len(aviGS.(*AviGSObjectGraph).GetUniqueMemberObjs()) == 0 {
-			deleteGs = true
-		}
-	} else {
+		metaObj.DeleteMapByKey(clusterObj)
+	}
+}
+
+// deleteMemberFromGS deletes the member for an object from the GS graph of hostname and publishes
+// the GS graph to the rest layer. Returns true if the number of unique members of the GS changed.
+func deleteMemberFromGS(key, hostname, cname, ns, objName, objType string, wq *utils.WorkerQueue) bool {
+	gsName, err := DeriveGSLBServiceName(hostname)
+	if err != nil {
+		gslbutils.Debugf("key: %s, hostname: %s, msg: no GSLB service name for the member deletion: %s", key,
+			hostname, err.Error())
+		return false
+	}
+	defer releaseGSLBServiceName(hostname, getGSMemberKey(cname, ns, objName, objType))
+	agl := SharedAviGSGraphLister()
+	tenant, gsGraph, found := getGSGraph(agl, gsName)
+	if !found {
 		// avi graph not found, return
 		gslbutils.Warnf("key: %s, msg: no gs key found in gs models", key)
-		return
+		return false
+	}
+	modelName := gslbutils.GetModelKey(tenant, gsName)
+	membersChanged := false
+	uniqueMembersLen := len(gsGraph.GetUniqueMemberObjs())
+	membersLen := gsGraph.MembersLen()
+	gsGraph.DeleteMember(cname, ns, objName, objType)
+	if gsGraph.MembersLen() != membersLen {
+		// the deleted member may have been kept over the other objects of this cluster, due to a
+		// hostname conflict, so the other objects are evaluated again
+		addClusterObjsForHostname(gsGraph, cname, hostname, ns, objName, objType)
+	}
+	newUniqueMemberLen := len(gsGraph.GetUniqueMemberObjs())
+	if uniqueMembersLen != newUniqueMemberLen {
+		membersChanged = true
 	}
-	aviGS.(*AviGSObjectGraph).SetRetryCounter()
-	if deleteGs {
+	gslbutils.Debugf("key: %s, gsMembers: %d, msg: checking if its a GS deletion case", key,
+		gsGraph.GetUniqueMemberObjs())
+	gsGraph.SetRetryCounter()
+	if newUniqueMemberLen == 0 {
 		// add the object to the delete cache and remove from the model cache
-		SharedDeleteGSGraphLister().Save(modelName, aviGS)
-		SharedAviGSGraphLister().Delete(modelName)
+		SharedDeleteGSGraphLister().Save(modelName, gsGraph)
+		agl.Delete(modelName)
 	} else {
-		SharedAviGSGraphLister().Save(modelName, aviGS)
+		// the deleted member may have been of the first member cluster, which decides the tenant
+		tenant, _ = updateGSGraphTenant(key, gsGraph, agl, wq)
+		agl.Save(gslbutils.GetModelKey(tenant, gsName), gsGraph)
 	}
 	if gslbutils.IsControllerLeader() {
-		PublishKeyToRestLayer(utils.ADMIN_NS, gsName, key, wq)
+		PublishKeyToRestLayer(tenant, gsName, key, wq)
+	}
+	return membersChanged
+}
+
+// getClusterObjsForHostname returns the accepted objects of the cluster cname with the hostname,
+// including the extra hosts of the routes.
+func getClusterObjsForHostname(cname, hostname string) []k8sobjects.MetaObject {
+	metaObjs := []k8sobjects.MetaObject{}
+	for _, store := range getAcceptedStores() {
+		objStore := store.GetClusterStore(cname)
+		for _, nsObj := range objStore.GetAllNSObjects() {
+			segments, err := gslbutils.SplitKey(nsObj)
+			if err != nil || len(segments) != 2 {
+				continue
+			}
+			obj, ok := objStore.GetNSObjectByName(segments[0], segments[1])
+			if !ok {
+				continue
+			}
+			metaObj, ok := obj.(k8sobjects.MetaObject)
+			if !ok {
+				continue
+			}
+			for _, hostObj := range append([]k8sobjects.MetaObject{metaObj}, getExtraHostMetaObjs(metaObj)...) {
+				if hostObj.GetHostname() == hostname {
+					metaObjs = append(metaObjs, hostObj)
+				}
+			}
+		}
+	}
+	return metaObjs
+}
+
+// addClusterObjsForHostname adds or updates the members for the accepted objects of the cluster cname
+// with the hostname of the GS graph, except the deleted object ns/objName of type objType.
+func addClusterObjsForHostname(gsGraph *AviGSObjectGraph, cname, hostname, ns, objName, objType string) {
+	for _, member := range getClusterMembersForHostname(cname, hostname) {
+		if member.ObjType == objType && member.Namespace == ns && member.Name == objName {
+			continue
+		}
+		gsGraph.UpdateGSMember(member.metaObj, member.Weight, member.Priority)
+	}
+}
+
+// updateObjRatioOperation updates the weight and the priority of the members of an object in the
+// GS graphs of its hosts as per the current traffic split. Unlike AddUpdateObjOperation, the
+// members of the GS graphs are not re-computed, objects without a member are left as they are.
+func updateObjRatioOperation(key, cname, ns, objType, objName string, wq *utils.WorkerQueue) {
+	obj := getObjFromStore(objType, cname, ns, objName, key, gslbutils.AcceptedStore)
+	if obj == nil {
+		// error message already logged in the above function
+		return
+	}
+	metaObj := obj.(k8sobjects.MetaObject)
+	memberWeight := GetObjTrafficRatio(ns, cname, metaObj.GetLabels(), getObjectWeight(metaObj))
+	memberPriority := GetObjTrafficPriority(cname, metaObj.GetLabels())
+	for _, hostMetaObj := range append([]k8sobjects.MetaObject{metaObj}, getExtraHostMetaObjs(metaObj)...) {
+		gsName, err := DeriveGSLBServiceName(hostMetaObj.GetHostname())
+		if err != nil {
+			gslbutils.Debugf("key: %s, hostname: %s, msg: no GSLB service name for the ratio update: %s", key,
+				hostMetaObj.GetHostname(), err.Error())
+			continue
+		}
+		tenant, gsGraph, found := getGSGraph(SharedAviGSGraphLister(), gsName)
+		modelName := gslbutils.GetModelKey(tenant, gsName)
+		if !found {
+			gslbutils.Debugf("key: %s, gsName: %s, msg: no GS graph for the ratio update", key, gsName)
+			continue
+		}
+		if !gsGraph.UpdateMemberRatio(cname, ns, hostMetaObj.GetName(), objType, memberWeight, memberPriority) {
+			gslbutils.Debugf("key: %s, modelName: %s, msg: no change in the member ratio", key, modelName)
+			continue
+		}
+		gsGraph.SetRetryCounter()
+		gslbutils.Logf("key: %s, modelName: %s, weight: %d, priority: %d, msg: updated the member ratio",
+			key, modelName, memberWeight, memberPriority)
+		PublishKeyToRestLayer(tenant, gsName, key, wq)
+	}
+}
+
+// updateObjPortOperation updates the port and the protocol of the member of an LB service in its GS
+// graph, along with the health monitor. Unlike AddUpdateObjOperation, the members of the GS graph
+// are not re-computed. If the GS graph has no member for the service, the object is added as usual.
+func updateObjPortOperation(key, cname, ns, objType, objName string, wq *utils.WorkerQueue) {
+	obj := getObjFromStore(objType, cname, ns, objName, key, gslbutils.AcceptedStore)
+	if obj == nil {
+		// error message already logged in the above function
+		return
+	}
+	metaObj := obj.(k8sobjects.MetaObject)
+	port, _ := metaObj.GetPort()
+	protocol, _ := metaObj.GetProtocol()
+	gsName, err := DeriveGSLBServiceName(metaObj.GetHostname())
+	if err != nil {
+		gslbutils.Warnf("key: %s, hostname: %s, msg: can't derive the GSLB service name for the port update: %s",
+			key, metaObj.GetHostname(), err.Error())
+		return
+	}
+	tenant, gsGraph, found := getGSGraph(SharedAviGSGraphLister(), gsName)
+	if !found {
+		gslbutils.Logf("key: %s, gsName: %s, msg: no GS graph for the port update, will add the object", key, gsName)
+		AddUpdateObjOperation(key, cname, ns, objType, objName, wq, false, SharedAviGSGraphLister())
+		return
+	}
+	modelName := gslbutils.GetModelKey(tenant, gsName)
+	prevChecksum, prevHmChecksum := gsGraph.GetChecksum(), gsGraph.GetHmChecksum()
+	if !gsGraph.UpdateMemberPort(cname, ns, objName, port, protocol) {
+		gslbutils.Logf("key: %s, modelName: %s, msg: no member for the port update, will add the object", key, modelName)
+		AddUpdateObjOperation(key, cname, ns, objType, objName, wq, false, SharedAviGSGraphLister())
+		return
+	}
+	if prevChecksum == gsGraph.GetChecksum() && prevHmChecksum == gsGraph.GetHmChecksum() {
+		gslbutils.Debugf("key: %s, modelName: %s, msg: no change in the GS graph for the port update", key, modelName)
+		return
+	}
+	gsGraph.SetRetryCounter()
+	gslbutils.Logf("key: %s, modelName: %s, port: %d, protocol: %s, msg: updated the member port", key, modelName,
+		port, protocol)
+	PublishKeyToRestLayer(tenant, gsName, key, wq)
+}
+
+// ApplyHostOverride re-applies the HostOverride of the hostname fqdn to its GS graph, and publishes
+// the GS graph to the rest layer if it changed.
+func ApplyHostOverride(fqdn string) {
+	gsName, err := DeriveGSLBServiceName(fqdn)
+	if err != nil {
+		gslbutils.Debugf("fqdn: %s, msg: no GSLB service name for the HostOverride: %s", fqdn, err.Error())
+		return
+	}
+	tenant, gsGraph, found := getGSGraph(SharedAviGSGraphLister(), gsName)
+	if !found {
+		gslbutils.Debugf("fqdn: %s, gsName: %s, msg: no GS graph for the HostOverride", fqdn, gsName)
+		return
+	}
+	modelName := gslbutils.GetModelKey(tenant, gsName)
+	prevChecksum := gsGraph.GetChecksum()
+	gsGraph.UpdateHostOverride()
+	if prevChecksum == gsGraph.GetChecksum() {
+		gslbutils.Debugf("fqdn: %s, modelName: %s, msg: no change in the GS graph for the HostOverride", fqdn, modelName)
+		return
+	}
+	gsGraph.SetRetryCounter()
+	gslbutils.Logf("fqdn: %s, modelName: %s, msg: applied the HostOverride to the GS graph", fqdn, modelName)
+	PublishKeyToRestLayer(tenant, gsName, modelName, utils.SharedWorkQueue().GetQueueByName(utils.GraphLayer))
+}
+
+func init() {
+	gslbutils.RegisterClusterRenameHook(renameClusterInGSGraphs)
+}
+
+// renameClusterInGSGraphs renames the cluster oldName of the members of all the GS graphs to
+// newName, and publishes the keys of the changed graphs to the rest layer, as the descriptions of
+// the GSLB services carry the cluster names of the members.
+func renameClusterInGSGraphs(oldName, newName string) {
+	agl := SharedAviGSGraphLister()
+	for _, modelName := range agl.GetAll() {
+		found, obj := agl.Get(modelName)
+		if !found || obj == nil {
+			continue
+		}
+		gsGraph := obj.(*AviGSObjectGraph)
+		if !gsGraph.RenameMemberCluster(oldName, newName) {
+			continue
+		}
+		gsGraph.SetRetryCounter()
+		gslbutils.Logf("cluster: %s, newName: %s, modelName: %s, msg: renamed the cluster of the GS graph members",
+			oldName, newName, modelName)
+		sharedQ := utils.SharedWorkQueue().GetQueueByName(utils.GraphLayer)
+		bkt := utils.Bkt(modelName, sharedQ.NumWorkers)
+		sharedQ.Workqueue[bkt].AddRateLimited(modelName)
 	}
 }
 
 func isAcceptableObject(objType string) bool {
-	return objType == gslbutils.RouteType || objType == gslbutils.IngressType || objType == gslbutils.SvcType
+	return gslbutils.IsObjTypeRegistered(objType)
+}
+
+// pendingDeletes holds the deadlines for the deletion of the GSLB members of the deleted objects,
+// which are retained for the member removal grace period.
+type pendingDeletes struct {
+	deadlines map[string]time.Time
+	lock      sync.Mutex
+}
+
+var objPendingDeletes = pendingDeletes{deadlines: make(map[string]time.Time)}
+
+func isObjInStores(objType, cname, ns, objName string) bool {
+	handlers, ok := gslbutils.GetObjTypeHandlers(objType)
+	if !ok {
+		return false
+	}
+	for _, store := range []*gslbutils.ClusterStore{handlers.AcceptedStore(), handlers.RejectedStore()} {
+		if _, found := store.GetClusterNSObjectByName(cname, ns, objName); found {
+			return true
+		}
+	}
+	return false
+}
+
+// deferObjDelete defers the deletion of the GSLB members of a deleted object by the member removal
+// grace period, and returns true if the deletion is deferred. The members of an object which is
+// still present in the stores (e.g. on a hostname change, or a rejection by the filter) are deleted
+// right away.
+func deferObjDelete(key, objType, cname, ns, objName string) bool {
+	gracePeriod := gslbutils.GetGlobalFilter().GetMemberRemovalGracePeriod()
+	if gracePeriod <= 0 || isObjInStores(objType, cname, ns, objName) {
+		return false
+	}
+	delayedKey := gslbutils.MultiClusterKey(gslbutils.ObjectDelayedDelete, objType, cname, ns, objName)
+	objPendingDeletes.lock.Lock()
+	deadline, pending := objPendingDeletes.deadlines[delayedKey]
+	if !pending {
+		deadline = time.Now().Add(gracePeriod)
+		objPendingDeletes.deadlines[delayedKey] = deadline
+	}
+	objPendingDeletes.lock.Unlock()
+
+	gslbutils.Logf("key: %s, gracePeriod: %s, msg: object deleted, will delete its GSLB members after the grace period",
+		key, gracePeriod)
+	requeueDelayedDelete(delayedKey, objType, cname, ns, objName, time.Until(deadline))
+	return true
+}
+
+// requeueDelayedDelete adds the delayed delete key to the ingestion queue after the duration after.
+func requeueDelayedDelete(delayedKey, objType, cname, ns, objName string, after time.Duration) {
+	var hostname string
+	if metaObj, err := GetNewObj(objType); err == nil {
+		hostname = metaObj.GetHostnameFromHostMap(gslbutils.GetClusterKey(cname, ns, objName))
+	}
+	requeueIngestionKey(delayedKey, hostname, after)
+}
+
+// requeueIngestionKey adds key to the ingestion queue bucket of hostname after the duration after.
+func requeueIngestionKey(key, hostname string, after time.Duration) {
+	ingestionQueue := utils.SharedWorkQueue().GetQueueByName(utils.ObjectIngestionLayer)
+	bkt := utils.Bkt(hostname, ingestionQueue.NumWorkers)
+	ingestionQueue.Workqueue[bkt].AddAfter(key, after)
+}
+
+// getRemainingMinAge returns the time left for metaObj to reach the min age of the filter, 0 if the
+// object is old enough or its creation time isn't known.
+func getRemainingMinAge(metaObj k8sobjects.MetaObject) time.Duration {
+	ctObj, ok := metaObj.(k8sobjects.CreationTimeObject)
+	if !ok || ctObj.GetCreationTime().IsZero() {
+		return 0
+	}
+	minAge := gslbutils.GetGlobalFilter().GetMinObjectAge()
+	if minAge <= 0 {
+		return 0
+	}
+	return time.Until(ctObj.GetCreationTime().Add(minAge))
+}
+
+// cancelObjDelete cancels the deferred deletion of the GSLB members of an object which reappeared,
+// and returns true if a deletion was pending.
+func cancelObjDelete(objType, cname, ns, objName string) bool {
+	delayedKey := gslbutils.MultiClusterKey(gslbutils.ObjectDelayedDelete, objType, cname, ns, objName)
+	objPendingDeletes.lock.Lock()
+	defer objPendingDeletes.lock.Unlock()
+	if _, pending := objPendingDeletes.deadlines[delayedKey]; !pending {
+		return false
+	}
+	delete(objPendingDeletes.deadlines, delayedKey)
+	return true
+}
+
+// delayedDeleteObjOperation deletes the GSLB members of an object once its grace period is over,
+// unless the object reappeared within the grace period.
+func delayedDeleteObjOperation(key, cname, ns, objType, objName string, wq *utils.WorkerQueue) {
+	objPendingDeletes.lock.Lock()
+	deadline, pending := objPendingDeletes.deadlines[key]
+	expired := pending && !time.Now().Before(deadline)
+	if expired {
+		delete(objPendingDeletes.deadlines, key)
+	}
+	objPendingDeletes.lock.Unlock()
+
+	if !pending {
+		gslbutils.Logf("key: %s, msg: object reappeared within the grace period, won't delete its GSLB members", key)
+		return
+	}
+	if !expired {
+		requeueDelayedDelete(key, objType, cname, ns, objName, time.Until(deadline))
+		return
+	}
+	deleteObjOperation(key, cname, ns, objType, objName, wq)
+}
+
+// deleteMembersForChangedHostname deletes the GSLB members of an object which reappeared with a
+// different hostname within the grace period, as the deletion of its earlier members was cancelled.
+func deleteMembersForChangedHostname(key, cname, ns, objType, objName string, wq *utils.WorkerQueue) {
+	obj := getObjFromStore(objType, cname, ns, objName, key, gslbutils.AcceptedStore)
+	metaObj, ok := obj.(k8sobjects.MetaObject)
+	if !ok {
+		return
+	}
+	oldHostname := metaObj.GetHostnameFromHostMap(gslbutils.GetClusterKey(cname, ns, objName))
+	if oldHostname == "" || oldHostname == metaObj.GetHostname() {
+		return
+	}
+	gslbutils.Logf("key: %s, oldHostname: %s, newHostname: %s, msg: object reappeared with a different hostname",
+		key, oldHostname, metaObj.GetHostname())
+	deleteObjOperation(key, cname, ns, objType, objName, wq)
 }
 
 func DequeueIngestion(key string) {
 	// The key format expected here is: operation/objectType/clusterName/Namespace/objName
 	gslbutils.Logf("key: %s, msg: %s", key, "starting graph sync")
 	objectOperation, objType, cname, ns, objName := gslbutils.ExtractMultiClusterKey(key)
+	// the cluster isn't renamed while its object is processed, and a key queued before the rename
+	// of its cluster is processed for the renamed cluster
+	gslbutils.RLockClusterNames()
+	defer gslbutils.RUnlockClusterNames()
+	cname = gslbutils.ResolveClusterName(cname)
 	sharedQueue := utils.SharedWorkQueue().GetQueueByName(utils.GraphLayer)
 	if !isAcceptableObject(objType) {
 		gslbutils.Warnf("key: %s, msg: %s", key, "not an acceptable object, can't process")
 		return
 	}
+	if objectOperation == gslbutils.ObjectAdd || objectOperation == gslbutils.ObjectUpdate {
+		if cancelObjDelete(objType, cname, ns, objName) {
+			gslbutils.Logf("key: %s, msg: object reappeared, cancelled the deletion of its GSLB members", key)
+			deleteMembersForChangedHostname(key, cname, ns, objType, objName, sharedQueue)
+		}
+	}
 	switch objectOperation {
 	case gslbutils.ObjectAdd:
 		AddUpdateObjOperation(key, cname, ns, objType, objName, sharedQueue, false, SharedAviGSGraphLister())
 	case gslbutils.ObjectDelete:
+		if deferObjDelete(key, objType, cname, ns, objName) {
+			return
+		}
 		deleteObjOperation(key, cname, ns, objType, objName, sharedQueue)
+	case gslbutils.ObjectDelayedDelete:
+		delayedDeleteObjOperation(key, cname, ns, objType, objName, sharedQueue)
+	case gslbutils.ObjectStaleDelete:
+		staleDeleteObjOperation(key, cname, ns, objType, objName, sharedQueue)
 	case gslbutils.ObjectUpdate:
 		AddUpdateObjOperation(key, cname, ns, objType, objName, sharedQueue, false, SharedAviGSGraphLister())
+	case gslbutils.ObjectRatioUpdate:
+		updateObjRatioOperation(key, cname, ns, objType, objName, sharedQueue)
+	case gslbutils.ObjectPortUpdate:
+		updateObjPortOperation(key, cname, ns, objType, objName, sharedQueue)
 	}
 }
 
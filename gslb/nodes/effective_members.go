@@ -0,0 +1,120 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package nodes
+
+import (
+	"sort"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+)
+
+// EffectiveMember is a member of the GS for a hostname, as assembled from the accepted objects of
+// a member cluster.
+type EffectiveMember struct {
+	Cluster   string
+	Namespace string
+	Name      string
+	ObjType   string
+	// Tenant is the Avi tenant of the GS of the member, as per the first member cluster of the GS
+	Tenant string
+	IPAddr string
+	// VIPs are all the IP addresses of an object exposing more than one, each is a GSLB member
+	VIPs []gslbutils.VIP
+	// Fqdn is the hostname of the load balancer of a service without an IP address
+	Fqdn     string
+	Weight   int32
+	Priority int32
+	metaObj  k8sobjects.MetaObject
+}
+
+// getAcceptedStores returns the stores of the accepted objects of all the registered object types,
+// in the order of their registration.
+func getAcceptedStores() []*gslbutils.ClusterStore {
+	var stores []*gslbutils.ClusterStore
+	for _, objType := range gslbutils.GetObjTypes() {
+		handlers, ok := gslbutils.GetObjTypeHandlers(objType)
+		if !ok {
+			continue
+		}
+		stores = append(stores, handlers.AcceptedStore())
+	}
+	return stores
+}
+
+// getClusterMembersForHostname returns the members of the GS for hostname from the accepted objects
+// of cluster cname, the objects without an address are skipped. The weights and the priorities are
+// as per the current traffic split.
+func getClusterMembersForHostname(cname, hostname string) []EffectiveMember {
+	members := []EffectiveMember{}
+	for _, metaObj := range getClusterObjsForHostname(cname, hostname) {
+		fqdn := getMemberFqdn(metaObj)
+		if metaObj.GetIPAddr() == "" && fqdn == "" {
+			continue
+		}
+		members = append(members, EffectiveMember{
+			Cluster:   cname,
+			Namespace: metaObj.GetNamespace(),
+			Name:      metaObj.GetName(),
+			ObjType:   metaObj.GetType(),
+			IPAddr:    metaObj.GetIPAddr(),
+			VIPs:      getMemberVIPs(metaObj),
+			Fqdn:      fqdn,
+			Weight:    GetObjTrafficRatio(metaObj.GetNamespace(), cname, metaObj.GetLabels(), getObjectWeight(metaObj)),
+			Priority:  GetObjTrafficPriority(cname, metaObj.GetLabels()),
+			metaObj:   metaObj,
+		})
+	}
+	return members
+}
+
+// GetEffectiveMembers returns the members which AMKO assembles for hostname from the accepted
+// objects of all the member clusters, sorted by the clusters, the object types, the namespaces and
+// the names. This is the member set of the GS graph for hostname before the HostOverride and the
+// grace period for the removed members are applied.
+func GetEffectiveMembers(hostname string) []EffectiveMember {
+	clusters := []string{}
+	for _, store := range getAcceptedStores() {
+		for _, cname := range store.GetAllClusters() {
+			if !gslbutils.PresentInList(cname, clusters) {
+				clusters = append(clusters, cname)
+			}
+		}
+	}
+	members := []EffectiveMember{}
+	for _, cname := range clusters {
+		members = append(members, getClusterMembersForHostname(cname, hostname)...)
+	}
+	sort.Slice(members, func(i, j int) bool {
+		mi, mj := members[i], members[j]
+		if mi.Cluster != mj.Cluster {
+			return mi.Cluster < mj.Cluster
+		}
+		if mi.ObjType != mj.ObjType {
+			return mi.ObjType < mj.ObjType
+		}
+		if mi.Namespace != mj.Namespace {
+			return mi.Namespace < mj.Namespace
+		}
+		return mi.Name < mj.Name
+	})
+	if len(members) != 0 {
+		tenant := gslbutils.GetClusterTenant(members[0].Cluster)
+		for idx := range members {
+			members[idx].Tenant = tenant
+		}
+	}
+	return members
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package nodes
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+)
+
+const (
+	// DefaultStaleObjectsGCInterval is the default interval (in seconds) of the GC sweep of the
+	// stale objects
+	DefaultStaleObjectsGCInterval = 600
+	// MinStaleObjectsGCInterval and MaxStaleObjectsGCInterval bound the interval of the GC sweep,
+	// an interval of 0 disables the sweep
+	MinStaleObjectsGCInterval = 60
+	MaxStaleObjectsGCInterval = 86400
+)
+
+var staleObjectsGCInterval = DefaultStaleObjectsGCInterval
+
+// SetStaleObjectsGCInterval sets the interval (in seconds) of the GC sweep of the stale objects,
+// 0 disables the sweep.
+func SetStaleObjectsGCInterval(seconds int) error {
+	if seconds != 0 && (seconds < MinStaleObjectsGCInterval || seconds > MaxStaleObjectsGCInterval) {
+		return errors.New("stale objects GC interval " + strconv.Itoa(seconds) + " must be 0 or between " +
+			strconv.Itoa(MinStaleObjectsGCInterval) + " and " + strconv.Itoa(MaxStaleObjectsGCInterval))
+	}
+	staleObjectsGCInterval = seconds
+	return nil
+}
+
+// GetStaleObjectsGCInterval returns the interval (in seconds) of the GC sweep of the stale objects.
+func GetStaleObjectsGCInterval() int {
+	return staleObjectsGCInterval
+}
+
+// staleCandidates are the stale objects found by the last GC sweep. An object is reclaimed only if
+// it is found stale by two consecutive sweeps, so that the objects whose delete keys are still in
+// the queues are left alone.
+var staleCandidates = struct {
+	keys map[string]bool
+	lock sync.Mutex
+}{keys: make(map[string]bool)}
+
+func isObjDeletePending(objType, cname, ns, objName string) bool {
+	delayedKey := gslbutils.MultiClusterKey(gslbutils.ObjectDelayedDelete, objType, cname, ns, objName)
+	objPendingDeletes.lock.Lock()
+	defer objPendingDeletes.lock.Unlock()
+	_, pending := objPendingDeletes.deadlines[delayedKey]
+	return pending
+}
+
+// isObjStale returns true if an object in the host map has no backing object in the accepted store,
+// and its GSLB members aren't retained for the member removal grace period.
+func isObjStale(objType, cname, ns, objName string) bool {
+	handlers, ok := gslbutils.GetObjTypeHandlers(objType)
+	if !ok {
+		return false
+	}
+	if _, found := handlers.AcceptedStore().GetClusterNSObjectByName(cname, ns, objName); found {
+		return false
+	}
+	return !isObjDeletePending(objType, cname, ns, objName)
+}
+
+// SweepStaleObjects checks the entries of the host maps of all the object types against the
+// accepted stores, and adds a stale delete key to the ingestion queue for each object found stale
+// by two consecutive sweeps. Returns the number of the stale objects reclaimed.
+func SweepStaleObjects() int {
+	staleCandidates.lock.Lock()
+	defer staleCandidates.lock.Unlock()
+
+	ingestionQueue := utils.SharedWorkQueue().GetQueueByName(utils.ObjectIngestionLayer)
+	candidates := make(map[string]bool)
+	reclaimed := 0
+	for _, objType := range gslbutils.GetObjTypes() {
+		metaObj, err := GetNewObj(objType)
+		if err != nil {
+			gslbutils.Errf("objType: %s, msg: %s", objType, err.Error())
+			continue
+		}
+		for _, clusterObj := range k8sobjects.GetHostMapKeys(objType) {
+			cname, ns, objName, err := gslbutils.ParseClusterKey(clusterObj)
+			if err != nil {
+				gslbutils.Warnf("objType: %s, key: %s, msg: couldn't parse the host map key, %s", objType,
+					clusterObj, err.Error())
+				continue
+			}
+			if !isObjStale(objType, cname, ns, objName) {
+				continue
+			}
+			key := gslbutils.MultiClusterKey(gslbutils.ObjectStaleDelete, objType, cname, ns, objName)
+			candidates[key] = true
+			if !staleCandidates.keys[key] {
+				gslbutils.Debugf("key: %s, msg: object without a backing object, will recheck in the next sweep", key)
+				continue
+			}
+			hostname := metaObj.GetHostnameFromHostMap(clusterObj)
+			gslbutils.Logf("key: %s, hostname: %s, msg: reclaiming the stale object", key, hostname)
+			bkt := utils.Bkt(hostname, ingestionQueue.NumWorkers)
+			ingestionQueue.Workqueue[bkt].AddRateLimited(key)
+			delete(candidates, key)
+			reclaimed++
+		}
+	}
+	staleCandidates.keys = candidates
+	gslbutils.Logf("reclaimed: %d, candidates: %d, msg: stale objects GC sweep completed", reclaimed, len(candidates))
+	return reclaimed
+}
+
+// staleDeleteObjOperation deletes the GSLB members and the host map entry of a stale object, unless
+// the object reappeared after the GC sweep.
+func staleDeleteObjOperation(key, cname, ns, objType, objName string, wq *utils.WorkerQueue) {
+	if !isObjStale(objType, cname, ns, objName) {
+		gslbutils.Logf("key: %s, msg: object reappeared after the GC sweep, won't delete its GSLB members", key)
+		return
+	}
+	metaObj, err := GetNewObj(objType)
+	if err != nil {
+		gslbutils.Errf("key: %s, msg: %s", key, err.Error())
+		return
+	}
+	deleteObjOperation(key, cname, ns, objType, objName, wq)
+	// the host map entry is removed even if the GS graphs had no members for the object
+	metaObj.DeleteMapByKey(gslbutils.GetClusterKey(cname, ns, objName))
+}
@@ -16,6 +16,8 @@ package rest
 
 import (
 	"errors"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -55,12 +57,10 @@ func NewRestOperations(cache *avicache.AviCache, hmCache *avicache.AviHmCache, a
 
 func (restOp *RestOperations) deleteAllStaleHMsForGS(key string) {
 	gslbutils.Debugf("key: %s, msg: checking if any stale health monitors present for this key", key)
-	keySplit := strings.Split(key, "/")
-	if len(keySplit) != 2 {
-		gslbutils.Warnf("key: %s, msg: wrong key format, expecting the key to be <tenant>/<gsName>", key)
+	tenant, gsName := gslbutils.ExtractTenantAndGSName(key)
+	if gsName == "" {
 		return
 	}
-	tenant, gsName := keySplit[0], keySplit[1]
 	hmObjs := restOp.hmCache.AviHmCacheGetHmsForGS(tenant, gsName)
 	if len(hmObjs) == 0 {
 		gslbutils.Debugf("key: %s, msg: no more health monitors for this key", key)
@@ -115,7 +115,7 @@ func (restOp *RestOperations) DqNodes(key string) {
 		aviModelCopy = aviModel.GetCopy()
 	}
 
-	tenant, gsName := utils.ExtractNamespaceObjectName(key)
+	tenant, gsName := gslbutils.ExtractTenantAndGSName(key)
 	gsKey := avicache.TenantName{Tenant: tenant, Name: gsName}
 	gsCacheObj := restOp.getGSCacheObj(gsKey, key)
 
@@ -132,6 +132,7 @@ func (restOp *RestOperations) DqNodes(key string) {
 	// 2. Layer 2 might have pushed a key deciding its a UPDATE operation at the time, but before we
 	//    get to Layer 3, layer 2 could have again set the members to 0.
 	if deleteOp || (aviModelCopy != nil && aviModelCopy.MembersLen() == 0) {
+		gslbutils.ClearWithheldService(tenant, gsName)
 		gslbutils.Logf("key: %s, msg: %s", key, "no model or members found, will delete the GslbService")
 		if gsCacheObj == nil {
 			gslbutils.Errf("key: %s, msg: %s", key, "no cache object for this GS was found, can't delete")
@@ -149,9 +150,31 @@ func (restOp *RestOperations) DqNodes(key string) {
 		gslbutils.Errf("key: %s, msg: %s", key, "unexpected error, no model exists for this GslbService")
 		return
 	}
+	if isGSWithheld(aviModelCopy, tenant, gsName, key) {
+		if gsCacheObj != nil {
+			restOp.deleteGSOper(gsCacheObj, tenant, key)
+		}
+		return
+	}
 	restOp.RestOperation(gsName, tenant, aviModelCopy, gsCacheObj, key)
 }
 
+// isGSWithheld returns true if the GS graph has fewer healthy clusters than the min healthy
+// clusters of the GDP object, such a GS isn't advertised till it has enough healthy clusters. The
+// GS is recorded as withheld or not, for the status of the GDP object.
+func isGSWithheld(aviModel *nodes.AviGSObjectGraph, tenant, gsName, key string) bool {
+	minHealthy := gslbutils.GetGlobalFilter().GetMinHealthyClusters()
+	healthy := aviModel.GetHealthyClusters()
+	if healthy >= minHealthy {
+		gslbutils.ClearWithheldService(tenant, gsName)
+		return false
+	}
+	gslbutils.Warnf("key: %s, healthyClusters: %d, minHealthyClusters: %d, msg: not enough healthy clusters, GslbService will be withheld",
+		key, healthy, minHealthy)
+	gslbutils.SetWithheldService(tenant, gsName, healthy)
+	return true
+}
+
 func (restOp *RestOperations) getHmPathDiff(aviGSGraph *nodes.AviGSObjectGraph, gsCacheObj *avicache.AviGSCache) ([]string, []string) {
 	hmNameList := aviGSGraph.GetHmPathNamesList()
 	toBeAdded := []string{}
@@ -218,7 +241,7 @@ func (restOp *RestOperations) createOrDeletePathHm(aviGSGraph *nodes.AviGSObject
 					gslbutils.Errf("key: %s, msg: couldn't build a rest operation for health monitor, returning", key)
 					return errors.New("couldn't build a rest operation")
 				}
-				hmKey := avicache.TenantName{Tenant: utils.ADMIN_NS, Name: hmName}
+				hmKey := avicache.TenantName{Tenant: aviGSGraph.Tenant, Name: hmName}
 				restOp.ExecuteRestAndPopulateCache(op, nil, &hmKey, key)
 				if op.Err != nil {
 					gslbutils.Errf("key: %s, hmKey: %v, msg: error while performing rest operation", key, hmKey)
@@ -237,7 +260,7 @@ func (restOp *RestOperations) createOrDeletePathHm(aviGSGraph *nodes.AviGSObject
 	if len(toBeDelPathHms) != 0 {
 		// we have to delete path based HMs for these paths
 		for _, hmName := range toBeDelPathHms {
-			err := restOp.deleteHmIfRequired(gsCacheObj.Name, utils.ADMIN_NS, key, gsCacheObj, gsKey, hmName)
+			err := restOp.deleteHmIfRequired(gsCacheObj.Name, aviGSGraph.Tenant, key, gsCacheObj, gsKey, hmName)
 			if err != nil {
 				// the key has been already published to the retry queue for an error event, so just return
 				return errors.New("couldn't build a rest operation")
@@ -251,7 +274,7 @@ func (restOp *RestOperations) createOrUpdateNonPathHm(aviGSGraph *nodes.AviGSObj
 	gsKey avicache.TenantName, key string) error {
 	hm := restOp.getGSHmCacheObj(aviGSGraph.Hm.Name, aviGSGraph.Tenant, key)
 	if hm != nil {
-		hmKey := avicache.TenantName{Tenant: utils.ADMIN_NS, Name: hm.Name}
+		hmKey := avicache.TenantName{Tenant: aviGSGraph.Tenant, Name: hm.Name}
 		hmCksum := aviGSGraph.GetHmChecksum()
 		gslbutils.Debugf(spew.Sprintf("key: %s, hmKey: %v, aviGSGraph: %v, hmChecksum: %d, hmCloudConfigChecksum: %d, msg: will check if hm needs to change",
 			key, hmKey, *aviGSGraph, hmCksum, hm.CloudConfigCksum))
@@ -263,7 +286,7 @@ func (restOp *RestOperations) createOrUpdateNonPathHm(aviGSGraph *nodes.AviGSObj
 				gslbutils.Errf("key: %s, hmKey: %s, msg: error in rest operation: %v", key, hmKey, op)
 				return op.Err
 			}
-			op = restOp.AviGsHmDel(hm.UUID, utils.ADMIN_NS, key, hm.Name)
+			op = restOp.AviGsHmDel(hm.UUID, aviGSGraph.Tenant, key, hm.Name)
 			restOp.ExecuteRestAndPopulateCache(op, nil, &hmKey, key)
 			if op.Err != nil {
 				gslbutils.Errf("key: %s, hmKey: %s, error in rest operation: %v", key, hmKey, op)
@@ -292,7 +315,7 @@ func (restOp *RestOperations) createOrUpdateNonPathHm(aviGSGraph *nodes.AviGSObj
 			gslbutils.Errf("key: %s, error in building avi hm object, won't retry", key)
 			return errors.New("error in building avi hm object")
 		}
-		hmKey := avicache.TenantName{Tenant: utils.ADMIN_NS, Name: op.ObjName}
+		hmKey := avicache.TenantName{Tenant: aviGSGraph.Tenant, Name: op.ObjName}
 		restOp.ExecuteRestAndPopulateCache(op, nil, &hmKey, key)
 		if op.Err != nil {
 			gslbutils.Errf("key: %s, hmKey: %v, error in rest operation: %v", key, hmKey, op)
@@ -309,6 +332,30 @@ func (restOp *RestOperations) createOrUpdateNonPathHm(aviGSGraph *nodes.AviGSObj
 	return nil
 }
 
+// deleteReplacedHms deletes the health monitors which the GS referred before an update, when the
+// members switch between the path based HMs and the non-path HM of the host only members. The GS
+// must have been updated already, so that these HMs aren't referred anymore.
+func (restOp *RestOperations) deleteReplacedHms(aviGSGraph *nodes.AviGSObjectGraph, prevHmNames []string,
+	gsCacheObj *avicache.AviGSCache, gsKey avicache.TenantName, key string) {
+	pathNames := aviGSGraph.GetHmPathNamesList()
+	nonPathHm := gslbutils.BuildNonPathHmName(gsCacheObj.Name)
+	for _, hmName := range prevHmNames {
+		replaced := false
+		if len(pathNames) == 0 {
+			replaced = gslbutils.IsPathHmName(gsCacheObj.Name, hmName)
+		} else {
+			replaced = hmName == nonPathHm && aviGSGraph.Hm.Name != nonPathHm
+		}
+		if !replaced {
+			continue
+		}
+		if err := restOp.deleteHmIfRequired(gsCacheObj.Name, aviGSGraph.Tenant, key, gsCacheObj, gsKey, hmName); err != nil {
+			gslbutils.Errf("key: %s, hmName: %s, msg: error in deleting the replaced health monitor", key, hmName)
+			return
+		}
+	}
+}
+
 func (restOp *RestOperations) RestOperation(gsName, tenant string, aviGSGraph *nodes.AviGSObjectGraph,
 	gsCacheObj *avicache.AviGSCache, key string) {
 	gsKey := avicache.TenantName{Tenant: tenant, Name: gsName}
@@ -321,6 +368,8 @@ func (restOp *RestOperations) RestOperation(gsName, tenant string, aviGSGraph *n
 	}
 	var err error
 	if gsCacheObj != nil {
+		// the health monitors referred by the GS before this update
+		prevHmNames := append([]string{}, gsCacheObj.HealthMonitorNames...)
 		if len(pathNames) > 0 {
 			// path based HMs
 			err = restOp.createOrDeletePathHm(aviGSGraph, gsCacheObj, key, gsKey)
@@ -333,6 +382,7 @@ func (restOp *RestOperations) RestOperation(gsName, tenant string, aviGSGraph *n
 		}
 		// PUT on GS if required
 		restOp.updateGsIfRequired(aviGSGraph, gsCacheObj, gsKey, key)
+		restOp.deleteReplacedHms(aviGSGraph, prevHmNames, gsCacheObj, gsKey, key)
 		return
 	}
 	// its a post operation for a GS
@@ -376,8 +426,8 @@ func (restOp *RestOperations) RestOperation(gsName, tenant string, aviGSGraph *n
 				key, gsKey, *aviGSGraph, hmCksum, hm.CloudConfigCksum))
 			if hm.CloudConfigCksum != hmCksum {
 				// delete hm, create new hm and update gs
-				hmKey := avicache.TenantName{Tenant: utils.ADMIN_NS, Name: hm.Name}
-				op := restOp.AviGsHmDel(hm.UUID, utils.ADMIN_NS, key, hm.Name)
+				hmKey := avicache.TenantName{Tenant: aviGSGraph.Tenant, Name: hm.Name}
+				op := restOp.AviGsHmDel(hm.UUID, aviGSGraph.Tenant, key, hm.Name)
 				restOp.ExecuteRestAndPopulateCache(op, nil, &hmKey, key)
 				if op.Err != nil {
 					gslbutils.Errf("key: %s, hmKey: %s, error in rest operation: %v", key, hmKey, op)
@@ -403,7 +453,11 @@ func (restOp *RestOperations) RestOperation(gsName, tenant string, aviGSGraph *n
 func AviRestOperateWrapper(restOp *RestOperations, aviClient *clients.AviClient, operation *utils.RestOp) error {
 	restTimeoutChan := make(chan error, 1)
 
+	// the slot is held till the rest call completes, even if it times out here, so that the calls
+	// which are still in flight in the controller are counted
+	gslbutils.AcquireRestSlot(operation.Model + "/" + operation.Path)
 	go func() {
+		defer gslbutils.ReleaseRestSlot()
 		err := restOp.aviRestPoolClient.AviRestOperate(aviClient, []*utils.RestOp{operation})
 		restTimeoutChan <- err
 	}()
@@ -426,6 +480,9 @@ func (restOp *RestOperations) ExecuteRestAndPopulateCache(operation *utils.RestO
 
 	if len(restOp.aviRestPoolClient.AviClient) > 0 {
 		aviClient := restOp.aviRestPoolClient.AviClient[bkt]
+		// the rest calls of all the workers share the rate limits, so that a flush of many keys
+		// doesn't overwhelm the controller
+		gslbutils.WaitForRestRateLimit(key)
 		err := AviRestOperateWrapper(restOp, aviClient, operation)
 		gslbutils.Debugf("key: %s, queue: %d, msg: avi rest operate wrapper response, %v", key, bkt, err)
 		if err != nil {
@@ -444,6 +501,9 @@ func (restOp *RestOperations) ExecuteRestAndPopulateCache(operation *utils.RestO
 		}
 		// rest call executed successfully
 		gslbutils.Logf("key: %s, msg: rest call executed successfully, will update cache", key)
+		if gslbutils.DeleteFromDeadLetter(key) {
+			gslbutils.Logf("key: %s, msg: removed key from the dead letter store", key)
+		}
 		if operation.Err == nil && (operation.Method == utils.RestPost || operation.Method == utils.RestPut) {
 			switch operation.Model {
 			case "HealthMonitor":
@@ -548,10 +608,14 @@ func setRetryCounterForGraph(key string) error {
 	return nil
 }
 
-func (restOp *RestOperations) PublishKeyToRetryLayer(gsKey, hmKey *avicache.TenantName, webApiErr error, key string) {
-	var bkt uint32
-	bkt = 0
+func getAviErrMsg(aviError session.AviError) string {
+	if aviError.Message == nil {
+		return ""
+	}
+	return *aviError.Message
+}
 
+func (restOp *RestOperations) PublishKeyToRetryLayer(gsKey, hmKey *avicache.TenantName, webApiErr error, key string) {
 	gslbutils.Debugf("key: %s, gsKey: %v, hmKey: %v, msg: evaluating whether to publish to retry queue",
 		key, gsKey, hmKey)
 	if webApiErr.Error() == "rest timeout occured" {
@@ -564,9 +628,8 @@ func (restOp *RestOperations) PublishKeyToRetryLayer(gsKey, hmKey *avicache.Tena
 			gslbutils.SetResyncRequired(true)
 			return
 		}
-		slowRetryQueue := utils.SharedWorkQueue().GetQueueByName(gslbutils.SlowRetryQueue)
-		slowRetryQueue.Workqueue[bkt].AddRateLimited(key)
-		gslbutils.Logf("key: %s, msg: Published key to slow path retry queue", key)
+		gslbutils.PublishToRetryQueue(gslbutils.SlowRetryQueue, key, gslbutils.RestError{Message: webApiErr.Error(),
+			Transient: true})
 		return
 	}
 	aviError, ok := webApiErr.(session.AviError)
@@ -578,36 +641,23 @@ func (restOp *RestOperations) PublishKeyToRetryLayer(gsKey, hmKey *avicache.Tena
 			gslbutils.SetResyncRequired(true)
 			return
 		}
-		slowRetryQueue := utils.SharedWorkQueue().GetQueueByName(gslbutils.FastRetryQueue)
-		slowRetryQueue.Workqueue[bkt].AddRateLimited(key)
-		gslbutils.Logf("key: %s, msg: Published key to fast path retry queue", key)
+		gslbutils.PublishToRetryQueue(gslbutils.FastRetryQueue, key, gslbutils.RestError{Message: webApiErr.Error(),
+			Transient: true})
 		return
 	}
 
 	gslbutils.Logf("key: %s, msg: Status code retrieved: %d", key, aviError.HttpStatusCode)
-	switch aviError.HttpStatusCode {
-	case 500, 501, 502, 503:
-		// Server errors, so we should keep on retrying
-		err := setRetryCounterForGraph(key)
-		if err != nil {
-			gslbutils.Errf("can't set the retry counter for this key, will re-sync in the next full sync")
-			gslbutils.SetResyncRequired(true)
-			return
-		}
-		slowRetryQueue := utils.SharedWorkQueue().GetQueueByName(gslbutils.SlowRetryQueue)
-		slowRetryQueue.Workqueue[bkt].AddRateLimited(key)
-		gslbutils.Logf("key: %s, msg: Published key to slow path retry queue", key)
-
-	case 400:
+	restErr := gslbutils.NewRestError(aviError.HttpStatusCode, getAviErrMsg(aviError))
+	if aviError.HttpStatusCode == 400 {
 		// check if the message contains: "not a leader"
 		// if the controller is not the leader anymore, stop syncing from layer 3.
-		if strings.Contains(*aviError.Message, ControllerNotLeaderErr) {
+		if strings.Contains(restErr.Message, ControllerNotLeaderErr) {
 			gslbutils.Errf("can't execute operations on a non-leader controller, will wait for it to become a leader in the next full sync")
 			gslbutils.SetControllerAsFollower()
 			// don't retry
 			return
 		}
-		if strings.Contains(*aviError.Message, ControllerInMaintenanceMode) {
+		if strings.Contains(restErr.Message, ControllerInMaintenanceMode) {
 			gslbutils.Errf("can't execute rest operations on a leader in maintenance mode, will retry")
 			// will retry indefinitely for this error, so reset the retryCounter
 			err := setRetryCounterForGraph(key)
@@ -617,13 +667,21 @@ func (restOp *RestOperations) PublishKeyToRetryLayer(gsKey, hmKey *avicache.Tena
 				return
 			}
 			// else, publish the key to slowRetryQueue
-			slowRetryQueue := utils.SharedWorkQueue().GetQueueByName(gslbutils.SlowRetryQueue)
-			slowRetryQueue.Workqueue[bkt].AddRateLimited(key)
-			gslbutils.Logf("key: %s, msg: Published key to slow path retry queue", key)
+			restErr.Transient = true
+			gslbutils.PublishToRetryQueue(gslbutils.SlowRetryQueue, key, restErr)
 			return
 		}
-		gslbutils.Errf("can't handle error code 400: %s, won't retry", *aviError.Message)
+	}
 
+	if !restErr.Transient {
+		// a retry won't succeed, the retry layer will move the key to the dead letter
+		gslbutils.Errf("key: %s, msg: can't handle error code %d: %s, won't retry", key, aviError.HttpStatusCode,
+			restErr.Message)
+		gslbutils.PublishToRetryQueue(gslbutils.FastRetryQueue, key, restErr)
+		return
+	}
+
+	switch aviError.HttpStatusCode {
 	case 404, 409:
 		// however, if this controller is still the leader, we should retry
 		// for these error codes, we should first update the cache and put the key back to rest layer
@@ -632,14 +690,17 @@ func (restOp *RestOperations) PublishKeyToRetryLayer(gsKey, hmKey *avicache.Tena
 		} else {
 			restOp.handleErrAndUpdateCacheForHm(aviError.HttpStatusCode, *hmKey, key)
 		}
-		fastRetryQueue := utils.SharedWorkQueue().GetQueueByName(gslbutils.FastRetryQueue)
-		fastRetryQueue.Workqueue[bkt].AddRateLimited(key)
-		gslbutils.Logf("key: %s, msg: Published gskey to fast path retry queue", key)
+		gslbutils.PublishToRetryQueue(gslbutils.FastRetryQueue, key, restErr)
 
 	default:
-		gslbutils.Warnf("key: %s, msg: unhandled status code %d", key, aviError.HttpStatusCode)
-		// no retry, but this will be taken care of in the next full sync
-		gslbutils.SetResyncRequired(true)
+		// Server errors and the other transient errors, so we should keep on retrying
+		err := setRetryCounterForGraph(key)
+		if err != nil {
+			gslbutils.Errf("can't set the retry counter for this key, will re-sync in the next full sync")
+			gslbutils.SetResyncRequired(true)
+			return
+		}
+		gslbutils.PublishToRetryQueue(gslbutils.SlowRetryQueue, key, restErr)
 	}
 }
 
@@ -653,7 +714,7 @@ func (restOp *RestOperations) AviGsHmBuild(gsMeta *nodes.AviGSObjectGraph, restM
 	hmProto := gsMeta.Hm.Protocol
 	isFederated := true
 	allowDup := true
-	tenantRef := gslbutils.GetAviAdminTenantRef()
+	tenantRef := gslbutils.GetAviTenantRef(gsMeta.Tenant)
 	description := "created by: amko"
 	sendInterval := int32(10)
 	receiveTimeout := int32(4)
@@ -692,6 +753,15 @@ func (restOp *RestOperations) AviGsHmBuild(gsMeta *nodes.AviGSObjectGraph, restM
 			aviGsHm.HTTPMonitor = &hmHTTP
 		case gslbutils.SystemGslbHealthMonitorHTTPS:
 			monitorPort = gslbutils.DefaultHTTPSHealthMonitorPort
+			if gsMeta.Hm.ServerName != "" {
+				// send the SNI host of the members, so that the TLS routes are looked up by their hosts
+				serverName := gsMeta.Hm.ServerName
+				sslProfileRef := "/api/sslprofile?name=" + gslbutils.SystemHealthMonitorSSLProfile
+				hmHTTP.SslAttributes = &avimodels.HealthMonitorSSlattributes{
+					ServerName:    &serverName,
+					SslProfileRef: &sslProfileRef,
+				}
+			}
 			aviGsHm.HTTPSMonitor = &hmHTTP
 		default:
 			gslbutils.Errf("key: %s, msg: can't build a path based health monitor for an unknown protocol %s", key, hmProto)
@@ -742,40 +812,67 @@ func (restOp *RestOperations) AviGSBuild(gsMeta *nodes.AviGSObjectGraph, restMet
 	cacheObj *avicache.AviGSCache, key string, hmRequired bool) *utils.RestOp {
 	gslbutils.Logf("key: %s, msg: creating rest operation", key)
 	// description field needs references
-	var gslbPoolMembers []*avimodels.GslbPoolMember
 	var gslbSvcGroups []*avimodels.GslbPool
+	// members are grouped into GSLB pools by their priorities
+	poolMembers := make(map[int32][]*avimodels.GslbPoolMember)
 	memberObjs := gsMeta.GetUniqueMemberObjs()
 	for _, member := range memberObjs {
-		if member.IPAddr == "" {
+		if member.IPAddr == "" && member.Fqdn == "" {
 			continue
 		}
 		enabled := true
 		ipVersion := "V4"
 		ipAddr := member.IPAddr
 		ratio := member.Weight
+		if ratio <= 0 {
+			// a member without any share of the traffic (e.g., a cluster without ready backends) is
+			// disabled, as the ratio of a GSLB pool member can't be less than 1
+			enabled = false
+			ratio = 1
+		}
 
 		gslbPoolMember := avimodels.GslbPoolMember{
 			Enabled: &enabled,
-			IP:      &avimodels.IPAddr{Addr: &ipAddr, Type: &ipVersion},
 			Ratio:   &ratio,
 		}
-		gslbPoolMembers = append(gslbPoolMembers, &gslbPoolMember)
-	}
-	// Now, build a GSLB pool
-	algorithm := "GSLB_ALGORITHM_ROUND_ROBIN"
-	poolEnabled := true
-	poolName := gsMeta.Name + "-10"
-	priority := int32(10)
-	minHealthMonUp := int32(2)
-	gslbPool := avimodels.GslbPool{
-		Algorithm:           &algorithm,
-		Enabled:             &poolEnabled,
-		Members:             gslbPoolMembers,
-		Name:                &poolName,
-		Priority:            &priority,
-		MinHealthMonitorsUp: &minHealthMonUp,
-	}
-	gslbSvcGroups = append(gslbSvcGroups, &gslbPool)
+		if ipAddr != "" {
+			gslbPoolMember.IP = &avimodels.IPAddr{Addr: &ipAddr, Type: &ipVersion}
+		} else {
+			// the member doesn't have an IP address, the controller will resolve the fqdn
+			fqdn := member.Fqdn
+			gslbPoolMember.Fqdn = &fqdn
+		}
+		poolMembers[member.Priority] = append(poolMembers[member.Priority], &gslbPoolMember)
+	}
+	// Now, build a GSLB pool for each priority, the pools with higher priorities come first
+	algorithm := gsMeta.PoolAlgorithm
+	if algorithm == "" {
+		algorithm = gslbutils.DefaultPoolAlgorithm
+	}
+	priorities := []int32{}
+	for priority := range poolMembers {
+		priorities = append(priorities, priority)
+	}
+	sort.Slice(priorities, func(i, j int) bool { return priorities[i] > priorities[j] })
+	if len(priorities) == 0 {
+		// a GS must have a pool, even if there are no members
+		priorities = append(priorities, gslbutils.DefaultPriority)
+	}
+	for _, p := range priorities {
+		poolEnabled := true
+		poolName := gsMeta.Name + "-" + strconv.Itoa(int(p))
+		priority := p
+		minHealthMonUp := int32(2)
+		gslbPool := avimodels.GslbPool{
+			Algorithm:           &algorithm,
+			Enabled:             &poolEnabled,
+			Members:             poolMembers[p],
+			Name:                &poolName,
+			Priority:            &priority,
+			MinHealthMonitorsUp: &minHealthMonUp,
+		}
+		gslbSvcGroups = append(gslbSvcGroups, &gslbPool)
+	}
 
 	// Now, build the GSLB service
 	ctrlHealthStatusEnabled := true
@@ -788,7 +885,7 @@ func (restOp *RestOperations) AviGSBuild(gsMeta *nodes.AviGSObjectGraph, restMet
 	poolAlgorithm := "GSLB_SERVICE_ALGORITHM_PRIORITY"
 	resolveCname := false
 	sitePersistenceEnabled := false
-	tenantRef := gslbutils.GetAviAdminTenantRef()
+	tenantRef := gslbutils.GetAviTenantRef(gsMeta.Tenant)
 	useEdnsClientSubnet := true
 	wildcardMatch := false
 	description := strings.Join(gsMeta.GetMemberObjList(), ",")
@@ -810,11 +907,19 @@ func (restOp *RestOperations) AviGSBuild(gsMeta *nodes.AviGSObjectGraph, restMet
 		WildcardMatch:                 &wildcardMatch,
 		TenantRef:                     &tenantRef,
 		Description:                   &description,
+		TTL:                           gsMeta.TTL,
 	}
 
 	hmApi := "/api/healthmonitor?name="
 
-	if hmRequired {
+	if len(gsMeta.HmRefs) > 0 {
+		// the health monitors of the HostOverride take precedence over the health monitors
+		// created for this GS
+		aviGslbSvc.HealthMonitorRefs = []string{}
+		for _, hmName := range gsMeta.HmRefs {
+			aviGslbSvc.HealthMonitorRefs = append(aviGslbSvc.HealthMonitorRefs, hmApi+hmName)
+		}
+	} else if hmRequired {
 		// check if path based (HTTP(S)) HMs are required or just a single non-path based (TCP/UDP) HM
 		if len(gsMeta.Hm.PathNames) == 0 {
 			if gsMeta.Hm.Name == "" {
@@ -912,7 +1017,7 @@ func (restOp *RestOperations) deleteHmIfRequired(gsName, tenant, key string, gsC
 		gslbutils.Debugf("key: %s, hmName: %s, msg: won't delete the passthrough health monitor", key, hmName)
 		return nil
 	}
-	hmCacheObjIntf, found := restOp.hmCache.AviHmCacheGet(avicache.TenantName{Tenant: utils.ADMIN_NS, Name: hmName})
+	hmCacheObjIntf, found := restOp.hmCache.AviHmCacheGet(avicache.TenantName{Tenant: tenant, Name: hmName})
 	if !found {
 		gslbutils.Warnf("key: %s, gsKey: %v, msg: health monitor object not found in the hm cache, can't delete",
 			key, gsKey)
@@ -927,7 +1032,7 @@ func (restOp *RestOperations) deleteHmIfRequired(gsName, tenant, key string, gsC
 			key, gsKey, hmCacheObj)
 		return errors.New("hm cache object malformed")
 	}
-	hmKey := avicache.TenantName{Tenant: utils.ADMIN_NS, Name: hmName}
+	hmKey := avicache.TenantName{Tenant: tenant, Name: hmName}
 	operation := restOp.AviGsHmDel(hmCacheObj.UUID, hmCacheObj.Tenant, key, hmCacheObj.Name)
 	restOps = operation
 	err := AviRestOperateWrapper(restOp, aviclient, restOps)
@@ -1013,6 +1118,21 @@ func (restOp *RestOperations) AviGSCacheDel(gsCache *avicache.AviCache, op *util
 	gsCache.AviCacheDelete(gsKey)
 }
 
+// getHmServerName returns the SNI host of the HTTPS health monitor in respElem, an empty string if
+// the health monitor doesn't send one.
+func getHmServerName(respElem map[string]interface{}) string {
+	httpsMonitor, ok := respElem["https_monitor"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	sslAttributes, ok := httpsMonitor["ssl_attributes"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	serverName, _ := sslAttributes["server_name"].(string)
+	return serverName
+}
+
 func (restOp *RestOperations) AviGSHmCacheAdd(operation *utils.RestOp, key string) error {
 	if (operation.Err != nil) || (operation.Response == nil) {
 		gslbutils.Warnf("key: %s, response: %s, msg: rest operation has err or no response for health monitor: %s", key,
@@ -1047,7 +1167,7 @@ func (restOp *RestOperations) AviGSHmCacheAdd(operation *utils.RestOp, key strin
 	}
 	port := int32(portF)
 
-	cksum := gslbutils.GetGSLBHmChecksum(name, hmType, port)
+	cksum := gslbutils.GetGSLBHmChecksum(name, hmType, port, getHmServerName(respElem))
 	k := avicache.TenantName{Tenant: operation.Tenant, Name: name}
 	addNew := false
 	hmCache, ok := restOp.hmCache.AviHmCacheGet(k)
@@ -1166,12 +1286,8 @@ func (restOp *RestOperations) AviGSCacheAdd(operation *utils.RestOp, key string)
 }
 
 func SyncFromNodesLayer(key string, wg *sync.WaitGroup) error {
-	cache := avicache.GetAviCache()
-	hmCache := avicache.GetAviHmCache()
-	aviclient := avicache.SharedAviClients()
-	restLayerF := NewRestOperations(cache, hmCache, aviclient)
 	gslbutils.Debugf("key: %s, msg: processing for key in rest layer", key)
-	restLayerF.DqNodes(key)
+	GetPublisher().Publish(key)
 	gslbutils.Debugf("key: %s, msg: processing for key is done in rest layer", key)
 	return nil
 }
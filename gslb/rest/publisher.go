@@ -0,0 +1,162 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package rest
+
+import (
+	"sort"
+	"sync"
+
+	avicache "github.com/avinetworks/amko/gslb/cache"
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/nodes"
+
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+)
+
+// Publisher syncs the GS graph of a key (tenant/gsName) from the graph layer to the GSLB services.
+// The keys published to the rest layer, including the keys retried by the retry layer, are synced
+// via the publisher.
+type Publisher interface {
+	Publish(key string)
+}
+
+// Publish syncs the GS graph of key to the Avi controller.
+func (restOp *RestOperations) Publish(key string) {
+	restOp.DqNodes(key)
+}
+
+var publisher = struct {
+	publisher Publisher
+	lock      sync.RWMutex
+}{}
+
+// SetPublisher sets the publisher for the keys of the rest layer, a nil publisher restores the
+// default publisher, which syncs the GS graphs to the Avi controller.
+func SetPublisher(p Publisher) {
+	publisher.lock.Lock()
+	defer publisher.lock.Unlock()
+	publisher.publisher = p
+}
+
+// GetPublisher returns the publisher for the keys of the rest layer.
+func GetPublisher() Publisher {
+	publisher.lock.RLock()
+	p := publisher.publisher
+	publisher.lock.RUnlock()
+	if p != nil {
+		return p
+	}
+	return NewRestOperations(avicache.GetAviCache(), avicache.GetAviHmCache(), avicache.SharedAviClients())
+}
+
+// PublishOperation is a GSLB service operation recorded by an InMemoryPublisher.
+type PublishOperation struct {
+	Key    string
+	Method utils.RestMethod
+	Tenant string
+	GSName string
+	// DomainNames and Members (as ip-weight[-priority]) are the state of the GSLB service after
+	// the operation, empty for a delete
+	DomainNames []string
+	Members     []string
+	Checksum    uint32
+}
+
+// InMemoryPublisher is a Publisher which doesn't need an Avi controller. It keeps the GSLB services
+// in memory and records the operations which would have been made on the Avi controller, so that
+// the tests can assert the GSLB operations resulting from a set of objects and a GDP object.
+type InMemoryPublisher struct {
+	// gsList are the GSLB services published so far, keyed by their keys
+	gsList map[string]PublishOperation
+	ops    []PublishOperation
+	lock   sync.Mutex
+}
+
+// NewInMemoryPublisher returns an InMemoryPublisher without any GSLB services.
+func NewInMemoryPublisher() *InMemoryPublisher {
+	return &InMemoryPublisher{gsList: make(map[string]PublishOperation)}
+}
+
+// Publish records a POST for a GS graph not published before, a PUT for a GS graph whose checksum
+// changed, and a DELETE for a GS graph which was published before, but was deleted or has no
+// members. Nothing is recorded if the GS graph didn't change.
+func (p *InMemoryPublisher) Publish(key string) {
+	tenant, gsName := gslbutils.ExtractTenantAndGSName(key)
+	var gsGraph *nodes.AviGSObjectGraph
+	if ok, aviModelIntf := nodes.SharedAviGSGraphLister().Get(key); ok && aviModelIntf != nil {
+		gsGraph = aviModelIntf.(*nodes.AviGSObjectGraph).GetCopy()
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	existing, published := p.gsList[key]
+	if gsGraph == nil || gsGraph.MembersLen() == 0 {
+		if !published {
+			gslbutils.Debugf("key: %s, msg: no GS published for the key, nothing to delete", key)
+			return
+		}
+		delete(p.gsList, key)
+		nodes.SharedDeleteGSGraphLister().Delete(key)
+		p.ops = append(p.ops, PublishOperation{Key: key, Method: utils.RestDelete, Tenant: tenant, GSName: gsName})
+		return
+	}
+
+	op := PublishOperation{
+		Key:         key,
+		Method:      utils.RestPost,
+		Tenant:      tenant,
+		GSName:      gsName,
+		DomainNames: gsGraph.DomainNames,
+		Checksum:    gsGraph.GetChecksum(),
+	}
+	for _, gsMember := range gsGraph.MemberObjs {
+		for _, member := range gsMember.GetVIPMembers() {
+			op.Members = append(op.Members, gslbutils.GetGSMemberKey(member.GetAddr(), member.Weight, member.Priority))
+		}
+	}
+	sort.Strings(op.Members)
+	if published {
+		if existing.Checksum == op.Checksum {
+			gslbutils.Debugf("key: %s, msg: GS didn't change, nothing to publish", key)
+			return
+		}
+		op.Method = utils.RestPut
+	}
+	p.gsList[key] = op
+	p.ops = append(p.ops, op)
+}
+
+// GetOperations returns the operations recorded so far, in order.
+func (p *InMemoryPublisher) GetOperations() []PublishOperation {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]PublishOperation{}, p.ops...)
+}
+
+// GetGS returns the last operation which created or updated the GSLB service of key, if the
+// GSLB service exists.
+func (p *InMemoryPublisher) GetGS(key string) (PublishOperation, bool) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	op, ok := p.gsList[key]
+	return op, ok
+}
+
+// ClearOperations removes the operations recorded so far, the GSLB services are retained.
+func (p *InMemoryPublisher) ClearOperations() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.ops = nil
+}
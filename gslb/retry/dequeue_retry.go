@@ -21,16 +21,54 @@ import (
 	"github.com/avinetworks/amko/gslb/nodes"
 
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 )
 
+// moveToDeadLetter adds the key to the dead letter store and records an event on each of the
+// member objects of the GS.
+func moveToDeadLetter(key string, restErr gslbutils.RestError) {
+	gslbutils.AddToDeadLetter(key, restErr)
+	found, aviGS := nodes.SharedAviGSGraphLister().Get(key)
+	if !found {
+		gslbutils.Warnf("key: %s, msg: no model found for this key, can't record events", key)
+		return
+	}
+	gsGraph, ok := aviGS.(*nodes.AviGSObjectGraph)
+	if !ok {
+		gslbutils.Errf("key: %s, msg: model malformed for this key", key)
+		return
+	}
+	msg := "GSLB service for hostname " + gsGraph.Name + " can't be synced, " + restErr.Error()
+	for _, member := range gsGraph.GetMemberObjs() {
+		gslbutils.RecordObjectEvent(member.Cluster, member.Namespace, member.Name, member.ObjType, "",
+			corev1.EventTypeWarning, gslbutils.GSLBServiceSyncFailed, msg)
+	}
+}
+
 func SyncFromRetryLayer(key string, wg *sync.WaitGroup) error {
 	// Retrieve the Key and note the time.
 	gslbutils.Logf("key: %s, msg: Retrieved the key in Retry layer", key)
-	tenant, gsName := utils.ExtractNamespaceObjectName(key)
+	// the key can be published to the retry queues again from here on
+	if !gslbutils.ClearRetryPending(key) {
+		gslbutils.Warnf("key: %s, msg: key was dropped from the full retry queues, won't retry", key)
+		return nil
+	}
+	// Only the transient errors are retried, keys with permanent errors are moved to the dead letter
+	restErr, ok := gslbutils.GetAndDeleteRetryError(key)
+	if ok && !restErr.Transient {
+		gslbutils.Errf("key: %s, msg: permanent error for key, won't retry, %s", key, restErr.Error())
+		moveToDeadLetter(key, restErr)
+		return nil
+	}
+	gsKey, err := gslbutils.ParseGSKey(key)
+	if err != nil {
+		gslbutils.Warnf("key: %s, msg: can't parse the key, won't retry, %v", key, err)
+		return nil
+	}
 
 	// At this point, we re-enqueue the key back to the rest layer.
 	sharedQueue := utils.SharedWorkQueue().GetQueueByName(utils.GraphLayer)
 
-	nodes.PublishKeyToRestLayer(tenant, gsName, "retry", sharedQueue)
+	nodes.PublishKeyToRestLayer(gsKey.GetTenant(), gsKey.GSName, "retry", sharedQueue)
 	return nil
 }
@@ -0,0 +1,2277 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package filter
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	filter "github.com/avinetworks/amko/gslb/gdp_filter"
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gslbalphav1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	Cluster1 = "cluster1"
+	Cluster2 = "cluster2"
+	Cluster3 = "cluster3"
+	TestNS   = "default"
+)
+
+func getTestGDP(trafficSplit []gslbalphav1.TrafficSplitElem) *gslbalphav1.GlobalDeploymentPolicy {
+	return &gslbalphav1.GlobalDeploymentPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:       gslbutils.AVISystem,
+			Name:            "test-gdp",
+			ResourceVersion: "100",
+		},
+		Spec: gslbalphav1.GDPSpec{
+			MatchRules: gslbalphav1.MatchRules{
+				AppSelector: gslbalphav1.AppSelector{
+					Label: map[string]string{"key": "value"},
+				},
+			},
+			MatchClusters: []string{Cluster1, Cluster2, Cluster3},
+			TrafficSplit:  trafficSplit,
+		},
+	}
+}
+
+func getTestFilter(trafficSplit []gslbalphav1.TrafficSplitElem) *gslbutils.GlobalFilter {
+	gf := gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(getTestGDP(trafficSplit))
+	return gf
+}
+
+func TestDefaultWeightPolicy(t *testing.T) {
+	gf := getTestFilter([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 6},
+		{Cluster: Cluster2, Weight: 2},
+	})
+
+	if gf.GetDefaultWeightPolicy() != gslbutils.DefaultWeightEqualShare {
+		t.Fatalf("expected default weight policy %s, got %s", gslbutils.DefaultWeightEqualShare,
+			gf.GetDefaultWeightPolicy())
+	}
+
+	// clusters with weights should always get their weights
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); err != nil || w != 6 {
+		t.Fatalf("expected weight 6 for %s, got %d, err: %v", Cluster1, w, err)
+	}
+
+	// equal share gets the average of the others
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster3, nil, 0); err != nil || w != 4 {
+		t.Fatalf("expected weight 4 for %s, got %d, err: %v", Cluster3, w, err)
+	}
+
+	if err := gf.SetDefaultWeightPolicy(gslbutils.DefaultWeightZero); err != nil {
+		t.Fatalf("error in setting default weight policy: %v", err)
+	}
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster3, nil, 0); err != nil || w != 0 {
+		t.Fatalf("expected weight 0 for %s, got %d, err: %v", Cluster3, w, err)
+	}
+
+	if err := gf.SetDefaultWeightPolicy(gslbutils.DefaultWeightError); err != nil {
+		t.Fatalf("error in setting default weight policy: %v", err)
+	}
+	if _, err := gf.GetTrafficWeight(TestNS, Cluster3, nil, 0); !errors.Is(err, gslbutils.ErrNoTrafficWeight) {
+		t.Fatalf("expected ErrNoTrafficWeight for %s, got %v", Cluster3, err)
+	}
+
+	if err := gf.SetDefaultWeightPolicy("random"); err == nil {
+		t.Fatalf("expected an error for an invalid policy")
+	}
+	if gf.GetDefaultWeightPolicy() != gslbutils.DefaultWeightError {
+		t.Fatalf("default weight policy shouldn't change for an invalid policy")
+	}
+}
+
+func TestDefaultWeightPolicyNoTrafficSplit(t *testing.T) {
+	gf := getTestFilter(nil)
+	for _, c := range []string{Cluster1, Cluster2, Cluster3} {
+		if w, err := gf.GetTrafficWeight(TestNS, c, nil, 0); err != nil || w != 1 {
+			t.Fatalf("expected weight 1 for %s, got %d, err: %v", c, w, err)
+		}
+	}
+}
+
+func TestDefaultWeightPolicyRetainedOnUpdate(t *testing.T) {
+	oldGDP := getTestGDP(nil)
+	newGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}})
+	gf := gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(oldGDP)
+	gf.SetDefaultWeightPolicy(gslbutils.DefaultWeightZero)
+
+	if changed, _ := gf.UpdateGlobalFilter(oldGDP, newGDP); !changed {
+		t.Fatalf("expected the filter to change")
+	}
+	if gf.GetDefaultWeightPolicy() != gslbutils.DefaultWeightZero {
+		t.Fatalf("expected default weight policy to be retained, got %s", gf.GetDefaultWeightPolicy())
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster2, nil, 0); w != 0 {
+		t.Fatalf("expected weight 0 for %s, got %d", Cluster2, w)
+	}
+}
+
+// Test that the weight of an object takes precedence over the weight of its cluster, except in the
+// percentage mode.
+func TestObjectWeight(t *testing.T) {
+	gf := getTestFilter([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 6}})
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster1, nil, 3); err != nil || w != 3 {
+		t.Fatalf("expected the object weight 3 for %s, got %d, err: %v", Cluster1, w, err)
+	}
+	// a cluster without an entry in the traffic split also gets the object weight
+	gf.SetDefaultWeightPolicy(gslbutils.DefaultWeightZero)
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster2, nil, 5); err != nil || w != 5 {
+		t.Fatalf("expected the object weight 5 for %s, got %d, err: %v", Cluster2, w, err)
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); w != 6 {
+		t.Fatalf("expected the cluster weight 6 for an object without a weight, got %d", w)
+	}
+
+	gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 70},
+		{Cluster: Cluster2, Weight: 30},
+	})
+	gdp.Spec.WeightMode = gslbalphav1.WeightModePercentage
+	gf = gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(gdp)
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, nil, 3); w != 70 {
+		t.Fatalf("expected the object weight to be ignored in the percentage mode, got %d", w)
+	}
+
+	for _, val := range []string{"0", "21", "-1", "a", ""} {
+		if _, err := gslbutils.ParseObjectWeight(val); err == nil {
+			t.Fatalf("expected an error for the object weight %q", val)
+		}
+	}
+	if w, err := gslbutils.ParseObjectWeight(" 20 "); err != nil || w != 20 {
+		t.Fatalf("expected the object weight 20, got %d, err: %v", w, err)
+	}
+}
+
+func TestTrafficSplitOverride(t *testing.T) {
+	defer gslbutils.ClearTrafficSplitOverride()
+
+	gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}})
+	gslbutils.SetTrafficSplitOverride([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 8}})
+	gf := gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(gdp)
+	if !gf.TrafficSplitOverridden {
+		t.Fatalf("expected the traffic split to be overridden")
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); w != 8 {
+		t.Fatalf("expected the override weight 8 for %s, got %d", Cluster1, w)
+	}
+
+	// the changes to the traffic split of the GDP object don't apply while the override is set
+	newGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 3}})
+	if changes := gf.UpdateFilter(gdp, newGDP); changes != 0 {
+		t.Fatalf("expected no changes with the traffic split overridden, got %s", changes.String())
+	}
+
+	gslbutils.SetTrafficSplitOverride([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 10}})
+	if changes := gf.UpdateFilter(newGDP, newGDP); changes != gslbutils.FilterChangeTraffic {
+		t.Fatalf("expected only the traffic to change with the override, got %s", changes.String())
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); w != 10 {
+		t.Fatalf("expected the override weight 10 for %s, got %d", Cluster1, w)
+	}
+
+	gslbutils.ClearTrafficSplitOverride()
+	if changes := gf.UpdateFilter(newGDP, newGDP); changes != gslbutils.FilterChangeTraffic {
+		t.Fatalf("expected only the traffic to change with the override removed, got %s", changes.String())
+	}
+	if gf.TrafficSplitOverridden {
+		t.Fatalf("expected the traffic split not to be overridden")
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); w != 3 {
+		t.Fatalf("expected the GDP weight 3 for %s, got %d", Cluster1, w)
+	}
+}
+
+// Test that applying the traffic split override rebuilds only the traffic split of the filter.
+func TestApplyTrafficSplitOverride(t *testing.T) {
+	defer gslbutils.ClearTrafficSplitOverride()
+
+	gf := gslbutils.GetNewGlobalFilter()
+	gslbutils.SetTrafficSplitOverride([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 8}})
+	if changes := gf.ApplyTrafficSplitOverride(); changes != 0 {
+		t.Fatalf("expected no changes without an accepted GDP object, got %s", changes.String())
+	}
+	gslbutils.ClearTrafficSplitOverride()
+
+	gf.AddToFilter(getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}}))
+	gslbutils.SetTrafficSplitOverride([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 8}})
+	if changes := gf.ApplyTrafficSplitOverride(); changes != gslbutils.FilterChangeTraffic {
+		t.Fatalf("expected only the traffic to change with the override, got %s", changes.String())
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); w != 8 {
+		t.Fatalf("expected the override weight 8 for %s, got %d", Cluster1, w)
+	}
+
+	// removing the override restores the traffic split of the accepted GDP object
+	gslbutils.ClearTrafficSplitOverride()
+	if changes := gf.ApplyTrafficSplitOverride(); changes != gslbutils.FilterChangeTraffic {
+		t.Fatalf("expected only the traffic to change with the override removed, got %s", changes.String())
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); w != 5 {
+		t.Fatalf("expected the GDP weight 5 for %s, got %d", Cluster1, w)
+	}
+}
+
+func TestGetLabelsReturnsCopy(t *testing.T) {
+	metaObjs := []k8sobjects.MetaObject{
+		k8sobjects.IngressHostMeta{Labels: map[string]string{"key": "value"}},
+		k8sobjects.RouteMeta{Labels: map[string]string{"key": "value"}},
+		k8sobjects.SvcMeta{Labels: map[string]string{"key": "value"}},
+	}
+	for _, metaObj := range metaObjs {
+		lbls := metaObj.GetLabels()
+		if len(lbls) != 1 || lbls["key"] != "value" {
+			t.Fatalf("%s: unexpected labels %v", metaObj.GetType(), lbls)
+		}
+		lbls["key"] = "changed"
+		if metaObj.GetLabels()["key"] != "value" {
+			t.Fatalf("%s: labels of the meta object changed via GetLabels", metaObj.GetType())
+		}
+	}
+}
+
+func TestTrafficRules(t *testing.T) {
+	gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 5},
+		{Cluster: Cluster2, Weight: 5},
+	})
+	gdp.Spec.TrafficRules = []gslbalphav1.TrafficRule{
+		{
+			AppSelector: gslbalphav1.AppSelector{Label: map[string]string{"app": "app1"}},
+			TrafficSplit: []gslbalphav1.TrafficSplitElem{
+				{Cluster: Cluster1, Weight: 10},
+				{Cluster: Cluster2, Weight: 2},
+			},
+		},
+		{
+			AppSelector: gslbalphav1.AppSelector{Label: map[string]string{"app": "app2"}},
+			TrafficSplit: []gslbalphav1.TrafficSplitElem{
+				{Cluster: Cluster1, Weight: 1},
+			},
+		},
+	}
+	gf := gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(gdp)
+
+	app1Labels := map[string]string{"key": "value", "app": "app1"}
+	app2Labels := map[string]string{"key": "value", "app": "app2"}
+	otherLabels := map[string]string{"key": "value", "app": "app3"}
+
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster1, app1Labels, 0); err != nil || w != 10 {
+		t.Fatalf("expected weight 10 for %s, got %d, err: %v", Cluster1, w, err)
+	}
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster2, app1Labels, 0); err != nil || w != 2 {
+		t.Fatalf("expected weight 2 for %s, got %d, err: %v", Cluster2, w, err)
+	}
+	// clusters missing in a rule's split follow the default weight policy for that split
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster2, app2Labels, 0); err != nil || w != 1 {
+		t.Fatalf("expected weight 1 for %s, got %d, err: %v", Cluster2, w, err)
+	}
+	// objects not matching any rule fall back to the default traffic split
+	for _, labels := range []map[string]string{otherLabels, nil} {
+		if w, err := gf.GetTrafficWeight(TestNS, Cluster1, labels, 0); err != nil || w != 5 {
+			t.Fatalf("expected weight 5 for %s, got %d, err: %v", Cluster1, w, err)
+		}
+	}
+}
+
+func TestTrafficRulesChange(t *testing.T) {
+	split := []gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}}
+	oldGDP := getTestGDP(split)
+	gf := gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(oldGDP)
+
+	newGDP := oldGDP.DeepCopy()
+	newGDP.Spec.TrafficRules = []gslbalphav1.TrafficRule{
+		{
+			AppSelector:  gslbalphav1.AppSelector{Label: map[string]string{"app": "app1"}},
+			TrafficSplit: []gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 15}},
+		},
+	}
+	changed, weightChanged := gf.UpdateGlobalFilter(oldGDP, newGDP)
+	if !changed || !weightChanged {
+		t.Fatalf("expected the filter and the traffic weights to change, got: %v, %v", changed, weightChanged)
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, map[string]string{"app": "app1"}, 0); w != 15 {
+		t.Fatalf("expected weight 15 for %s, got %d", Cluster1, w)
+	}
+}
+
+// TestTrafficWeightsAcrossUpdates reads the traffic weights while the GDP object is updated without
+// changing the weights, run with -race to catch the races between the updates and the readers.
+func TestTrafficWeightsAcrossUpdates(t *testing.T) {
+	split := []gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 6}, {Cluster: Cluster2, Weight: 2}}
+	gdp := getTestGDP(split)
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	route := k8sobjects.RouteMeta{Cluster: Cluster1, Namespace: TestNS, Name: "route1", Hostname: "route1.avi.com",
+		Labels: map[string]string{"key": "value"}}
+	otherGDP := gdp.DeepCopy()
+	otherGDP.Spec.MatchRules.AppSelector.Label = map[string]string{"key": "other"}
+
+	done := make(chan struct{})
+	var readers sync.WaitGroup
+	var wrongWeights int32
+	for i := 0; i < 4; i++ {
+		readers.Add(1)
+		go func() {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				w1, err1 := gf.GetTrafficWeight(TestNS, Cluster1, map[string]string{"key": "value"}, 0)
+				w2, err2 := gf.GetTrafficWeight(TestNS, Cluster2, nil, 0)
+				if err1 != nil || err2 != nil || w1 != 6 || w2 != 2 {
+					atomic.AddInt32(&wrongWeights, 1)
+				}
+				gf.GetChecksum()
+				gf.GetTrafficPriority(Cluster1, nil)
+				// the route is accepted only with one of the app selectors
+				route.ApplyFilter()
+				gf.GlobalLock.RLock()
+				gf.IsObjTypeSelected(gslbutils.RouteType)
+				gf.GlobalLock.RUnlock()
+			}
+		}()
+	}
+
+	// the app selector flips on every update, while the weights stay the same
+	oldGDP, newGDP := gdp, otherGDP
+	for i := 0; i < 500; i++ {
+		changed, weightChanged := gf.UpdateGlobalFilter(oldGDP, newGDP)
+		if !changed || weightChanged {
+			close(done)
+			t.Fatalf("expected only the app selector to change, got: %v, %v", changed, weightChanged)
+		}
+		oldGDP, newGDP = newGDP, oldGDP
+	}
+	close(done)
+	readers.Wait()
+	if wrongWeights != 0 {
+		t.Fatalf("expected the weights to persist across the updates, got %d wrong reads", wrongWeights)
+	}
+}
+
+func TestCompareAndSetGDPObj(t *testing.T) {
+	gslbutils.SetGDPObj("", "")
+	defer gslbutils.SetGDPObj("", "")
+
+	// out of many concurrent claims for an empty GDP object, only one must succeed
+	var wg sync.WaitGroup
+	var successCount int32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			if gslbutils.CompareAndSetGDPObj("", "", "gdp-"+strconv.Itoa(idx), gslbutils.AVISystem) {
+				atomic.AddInt32(&successCount, 1)
+			}
+		}(i)
+	}
+	wg.Wait()
+	if successCount != 1 {
+		t.Fatalf("expected exactly one successful claim, got %d", successCount)
+	}
+
+	name, ns := gslbutils.GetGDPObj()
+	if gslbutils.CompareAndSetGDPObj("", "", "another-gdp", gslbutils.AVISystem) {
+		t.Fatalf("expected the claim to fail for an already set GDP object")
+	}
+	if !gslbutils.CompareAndSetGDPObj(name, ns, "", "") {
+		t.Fatalf("expected the GDP object %s/%s to be reset", ns, name)
+	}
+	if !gslbutils.IsEmpty() {
+		t.Fatalf("expected the GDP object to be empty")
+	}
+
+	// the accepted GDP object is tracked apart from the GDP objects in the store, and is kept whole
+	store := gslbutils.GetDefaultGDPStore()
+	accepted := &gslbalphav1.GlobalDeploymentPolicy{ObjectMeta: metav1.ObjectMeta{Name: "gdp-b",
+		Namespace: gslbutils.AVISystem}, Spec: gslbalphav1.GDPSpec{MatchClusters: []string{Cluster1}}}
+	if !gslbutils.CompareAndSetGDPObj("", "", accepted.Name, accepted.Namespace) {
+		t.Fatalf("expected the GDP object %s to be accepted", accepted.Name)
+	}
+	store.AddOrUpdate(accepted)
+	store.AddOrUpdate(&gslbalphav1.GlobalDeploymentPolicy{ObjectMeta: metav1.ObjectMeta{Name: "gdp-a",
+		Namespace: gslbutils.AVISystem}})
+	defer store.Delete(gslbutils.AVISystem, "gdp-a")
+	if name, ns := gslbutils.GetGDPObj(); name != accepted.Name || ns != accepted.Namespace {
+		t.Fatalf("expected the accepted GDP object %s, got %s/%s", accepted.Name, ns, name)
+	}
+	if stored, ok := store.Get(accepted.Namespace, accepted.Name); !ok || len(stored.Spec.MatchClusters) != 1 {
+		t.Fatalf("expected the whole accepted GDP object in the store, got %v", stored)
+	}
+	gslbutils.SetGDPObj("", "")
+	if _, ok := store.Get(accepted.Namespace, accepted.Name); ok || !gslbutils.IsEmpty() {
+		t.Fatalf("expected the GDP object %s to be removed once it isn't accepted", accepted.Name)
+	}
+}
+
+func TestGDPStore(t *testing.T) {
+	store := gslbutils.NewGDPStore()
+	gdp1 := &gslbalphav1.GlobalDeploymentPolicy{ObjectMeta: metav1.ObjectMeta{Name: "gdp1", Namespace: "ns2"}}
+	gdp2 := &gslbalphav1.GlobalDeploymentPolicy{ObjectMeta: metav1.ObjectMeta{Name: "gdp2", Namespace: "ns1"}}
+
+	if store.Update(gdp1) {
+		t.Fatalf("expected the update of a missing GDP object to fail")
+	}
+	if !store.Add(gdp1) || !store.Add(gdp2) {
+		t.Fatalf("expected the GDP objects to be added")
+	}
+	if store.Add(gdp1) {
+		t.Fatalf("expected the add of an existing GDP object to fail")
+	}
+	if store.Len() != 2 {
+		t.Fatalf("expected 2 GDP objects, got %d", store.Len())
+	}
+	if gdps := store.List(); gdps[0].Name != "gdp2" || gdps[1].Name != "gdp1" {
+		t.Fatalf("expected the GDP objects sorted by their namespaces, got %s, %s", gdps[0].Name, gdps[1].Name)
+	}
+
+	// the store keeps its own copies of the GDP objects
+	gdp1.Spec.MatchClusters = []string{Cluster1}
+	if stored, _ := store.Get("ns2", "gdp1"); len(stored.Spec.MatchClusters) != 0 {
+		t.Fatalf("expected the stored GDP object to be unchanged, got %v", stored.Spec.MatchClusters)
+	}
+	if !store.Update(gdp1) {
+		t.Fatalf("expected the GDP object to be updated")
+	}
+	if stored, _ := store.Get("ns2", "gdp1"); len(stored.Spec.MatchClusters) != 1 {
+		t.Fatalf("expected the stored GDP object to be updated, got %v", stored.Spec.MatchClusters)
+	}
+
+	if !store.Delete("ns2", "gdp1") || store.Delete("ns2", "gdp1") {
+		t.Fatalf("expected the GDP object to be deleted only once")
+	}
+	if _, ok := store.Get("ns2", "gdp1"); ok || store.Len() != 1 {
+		t.Fatalf("expected only the GDP object ns1/gdp2 in the store")
+	}
+}
+
+func TestHasPolicy(t *testing.T) {
+	gf := gslbutils.GetNewGlobalFilter()
+	if gf.HasPolicy() {
+		t.Fatalf("expected no policy for a new filter")
+	}
+	gdp := getTestGDP(nil)
+	gf.AddToFilter(gdp)
+	if !gf.HasPolicy() {
+		t.Fatalf("expected a policy after adding a GDP object")
+	}
+	if changed, _ := gf.UpdateGlobalFilter(gdp, getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}})); !changed {
+		t.Fatalf("expected the filter to change")
+	}
+	if !gf.HasPolicy() {
+		t.Fatalf("expected the policy to be retained on update")
+	}
+	gf.DeleteFromGlobalFilter(gdp)
+	if gf.HasPolicy() {
+		t.Fatalf("expected no policy after deleting the GDP object")
+	}
+}
+
+func TestObjectsRejectedWithoutPolicy(t *testing.T) {
+	if gslbutils.GetGlobalFilter().HasPolicy() {
+		t.Fatalf("expected no policy for the global filter")
+	}
+	route := k8sobjects.RouteMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "route1",
+		Hostname:  "route1.avi.com",
+		Labels:    map[string]string{"key": "value"},
+	}
+	if filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route to be rejected without a policy")
+	}
+	if route.ApplyFilter() {
+		t.Fatalf("expected the route to be rejected without a policy")
+	}
+}
+
+func TestResetGlobalFilter(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}}))
+	if !gslbutils.GetGlobalFilter().HasPolicy() {
+		t.Fatalf("expected a policy for the global filter")
+	}
+	route := k8sobjects.RouteMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "route1",
+		Hostname:  "route1.avi.com",
+		Labels:    map[string]string{"key": "value"},
+	}
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route to be accepted")
+	}
+
+	gslbutils.ResetGlobalFilter()
+	gf = gslbutils.GetGlobalFilter()
+	if gf.HasPolicy() || gf.AppFilter != nil || len(gf.ApplicableClusters) != 0 || len(gf.TrafficSplit) != 0 {
+		t.Fatalf("expected an empty global filter after reset")
+	}
+	if filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route to be rejected after reset")
+	}
+}
+
+func getTestLBSvc(name string, labels map[string]string) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: TestNS,
+			Labels:    labels,
+		},
+		Spec: corev1.ServiceSpec{
+			Type:  corev1.ServiceTypeLoadBalancer,
+			Ports: []corev1.ServicePort{{Port: 80, Protocol: corev1.ProtocolTCP}},
+		},
+		Status: corev1.ServiceStatus{
+			LoadBalancer: corev1.LoadBalancerStatus{
+				Ingress: []corev1.LoadBalancerIngress{{IP: "10.10.10.10", Hostname: name + ".avi.com"}},
+			},
+		},
+	}
+}
+
+func TestLBSvcAppSelector(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getTestGDP(nil)
+	gdp.Spec.MatchRules.NamespaceSelector = gslbalphav1.NamespaceSelector{
+		Label: map[string]string{"ns": "prod"},
+	}
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	if err := gf.AddNSToNSFilter(Cluster1, TestNS); err != nil {
+		t.Fatalf("error in selecting the namespace: %v", err)
+	}
+
+	labeledSvc, ok := k8sobjects.GetSvcMeta(getTestLBSvc("labeled-svc", map[string]string{"key": "value"}), Cluster1)
+	if !ok {
+		t.Fatalf("expected a valid service meta")
+	}
+	if labeledSvc.GetLabels()["key"] != "value" {
+		t.Fatalf("expected the service meta to capture the service labels, got %v", labeledSvc.GetLabels())
+	}
+	if !filter.ApplyFilter(labeledSvc, Cluster1) {
+		t.Fatalf("expected the labeled service to be accepted")
+	}
+
+	unlabeledSvc, ok := k8sobjects.GetSvcMeta(getTestLBSvc("unlabeled-svc", nil), Cluster1)
+	if !ok {
+		t.Fatalf("expected a valid service meta")
+	}
+	if filter.ApplyFilter(unlabeledSvc, Cluster1) {
+		t.Fatalf("expected the unlabeled service to be rejected")
+	}
+}
+
+func TestGetNamespacesForCluster(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gf := gslbutils.GetGlobalFilter()
+	if _, err := gf.GetNamespacesForCluster(Cluster1); err != gslbutils.ErrNoNSFilter {
+		t.Fatalf("expected an error without a namespace filter, got %v", err)
+	}
+
+	gdp := getTestGDP(nil)
+	gdp.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"ns": "prod"}
+	gf.AddToFilter(gdp)
+	if err := gf.AddNSToNSFilter(Cluster1, TestNS); err != nil {
+		t.Fatalf("error in selecting the namespace: %v", err)
+	}
+
+	nsList, err := gf.GetNamespacesForCluster(Cluster1)
+	if err != nil {
+		t.Fatalf("unexpected error in fetching the namespaces: %v", err)
+	}
+	if !reflect.DeepEqual(nsList, []string{TestNS}) {
+		t.Fatalf("expected namespaces %v, got %v", []string{TestNS}, nsList)
+	}
+	nsList[0] = "modified"
+	if nsList, _ = gf.GetNamespacesForCluster(Cluster1); nsList[0] != TestNS {
+		t.Fatalf("expected the returned namespaces to be a copy, got %v", nsList)
+	}
+
+	nsList, err = gf.GetNamespacesForCluster(Cluster2)
+	if err != nil {
+		t.Fatalf("unexpected error in fetching the namespaces: %v", err)
+	}
+	if len(nsList) != 0 {
+		t.Fatalf("expected no namespaces for %s, got %v", Cluster2, nsList)
+	}
+}
+
+func TestFilterDecisionCounts(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	gslbutils.GetAndResetFilterDecisions()
+
+	route := k8sobjects.RouteMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "route1",
+		Hostname:  "route1.avi.com",
+		Labels:    map[string]string{"key": "value"},
+	}
+	// no policy yet, the route must be rejected
+	filter.ApplyFilter(route, Cluster1)
+	gslbutils.GetGlobalFilter().AddToFilter(getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}}))
+	filter.ApplyFilter(route, Cluster1)
+	filter.ApplyFilter(route, Cluster1)
+
+	accepted, rejected := gslbutils.GetAndResetFilterDecisions()
+	if accepted != 2 || rejected != 1 {
+		t.Fatalf("expected 2 accepted and 1 rejected objects, got %d accepted and %d rejected", accepted, rejected)
+	}
+	if accepted, rejected = gslbutils.GetAndResetFilterDecisions(); accepted != 0 || rejected != 0 {
+		t.Fatalf("expected the counts to be reset, got %d accepted and %d rejected", accepted, rejected)
+	}
+}
+
+func TestSetLogLevel(t *testing.T) {
+	if err := gslbutils.SetLogLevel("DEBUG"); err != nil {
+		t.Fatalf("unexpected error in setting the log level: %v", err)
+	}
+	defer gslbutils.SetLogLevel("INFO")
+	if err := gslbutils.SetLogLevel("VERBOSE"); err == nil {
+		t.Fatalf("expected an error for an unrecognized log level")
+	}
+}
+
+func TestPriorityGroups(t *testing.T) {
+	gf := getTestFilter([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 5, Priority: 20},
+		{Cluster: Cluster2, Weight: 5, Priority: 20},
+		{Cluster: Cluster3, Weight: 2},
+	})
+	groups := gf.GetPriorityGroups(nil)
+	if len(groups) != 2 || len(groups[20]) != 2 || len(groups[gslbutils.DefaultPriority]) != 1 {
+		t.Fatalf("expected 2 clusters with priority 20 and 1 with the default priority, got %v", groups)
+	}
+	if groups[gslbutils.DefaultPriority][0].ClusterName != Cluster3 {
+		t.Fatalf("expected %s to have the default priority, got %v", Cluster3, groups)
+	}
+	if p := gf.GetTrafficPriority(Cluster1, nil); p != 20 {
+		t.Fatalf("expected priority 20 for %s, got %d", Cluster1, p)
+	}
+	if p := gf.GetTrafficPriority("unknown", nil); p != gslbutils.DefaultPriority {
+		t.Fatalf("expected the default priority for a cluster without an entry, got %d", p)
+	}
+}
+
+func TestPriorityGroupsForTrafficRules(t *testing.T) {
+	gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 5},
+		{Cluster: Cluster2, Weight: 5},
+	})
+	gdp.Spec.TrafficRules = []gslbalphav1.TrafficRule{{
+		AppSelector: gslbalphav1.AppSelector{Label: map[string]string{"app": "app1"}},
+		TrafficSplit: []gslbalphav1.TrafficSplitElem{
+			{Cluster: Cluster1, Weight: 5, Priority: 20},
+			{Cluster: Cluster2, Weight: 5},
+		},
+	}}
+	gf := gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(gdp)
+
+	groups := gf.GetPriorityGroups(map[string]string{"key": "value", "app": "app1"})
+	if len(groups) != 2 || len(groups[20]) != 1 || groups[20][0].ClusterName != Cluster1 {
+		t.Fatalf("expected %s with priority 20 from the traffic rule, got %v", Cluster1, groups)
+	}
+	groups = gf.GetPriorityGroups(map[string]string{"key": "value"})
+	if len(groups) != 1 || len(groups[gslbutils.DefaultPriority]) != 2 {
+		t.Fatalf("expected both the clusters with the default priority from the default traffic split, got %v", groups)
+	}
+}
+
+func TestPriorityChangeUpdatesFilter(t *testing.T) {
+	oldGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}, {Cluster: Cluster2, Weight: 5}})
+	gf := gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(oldGDP)
+
+	newGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5, Priority: 20}, {Cluster: Cluster2, Weight: 5}})
+	changed, trafficChanged := gf.UpdateGlobalFilter(oldGDP, newGDP)
+	if !changed || !trafficChanged {
+		t.Fatalf("expected a priority change to change the filter and the traffic, got %v, %v", changed, trafficChanged)
+	}
+	if p := gf.GetTrafficPriority(Cluster1, nil); p != 20 {
+		t.Fatalf("expected priority 20 for %s after the update, got %d", Cluster1, p)
+	}
+}
+
+func TestRequireReadyFilter(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getTestGDP(nil)
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	route := k8sobjects.RouteMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "route1",
+		Hostname:  "route1.avi.com",
+		Labels:    map[string]string{"key": "value"},
+	}
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected a route which is not ready to be accepted without requireReady")
+	}
+
+	newGDP := getTestGDP(nil)
+	newGDP.Spec.MatchRules.RequireReady = true
+	if changed, _ := gf.UpdateGlobalFilter(gdp, newGDP); !changed {
+		t.Fatalf("expected the filter to change with requireReady")
+	}
+	if filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected a route which is not ready to be rejected with requireReady")
+	}
+	route.Ready = true
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected a ready route to be accepted with requireReady")
+	}
+}
+
+func TestFilterObservers(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.ClearFilterObservers()
+
+	var first, second []gslbutils.FilterDecision
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { first = append(first, d) })
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { second = append(second, d) })
+
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(getTestGDP(nil))
+	route := k8sobjects.RouteMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "route1",
+		Hostname:  "route1.avi.com",
+		Labels:    map[string]string{"key": "value"},
+	}
+	filter.ApplyFilter(route, Cluster1)
+	route.Labels = map[string]string{"key": "other"}
+	filter.ApplyFilter(route, Cluster1)
+
+	if len(first) != 2 || len(second) != 2 {
+		t.Fatalf("expected 2 decisions for each observer, got %d and %d", len(first), len(second))
+	}
+	key := gslbutils.GetClusterKey(Cluster1, TestNS, "route1")
+	if !first[0].Accepted || first[0].Key != key || first[0].Reason != "accepted because of appSelector" {
+		t.Fatalf("unexpected decision for the accepted route: %v", first[0])
+	}
+	if first[1].Accepted || first[1].Key != key || first[1].Reason != "rejected because of appSelector" {
+		t.Fatalf("unexpected decision for the rejected route: %v", first[1])
+	}
+	if second[0] != first[0] || second[1] != first[1] {
+		t.Fatalf("expected both observers to get the same decisions")
+	}
+
+	gslbutils.ClearFilterObservers()
+	if gslbutils.HasFilterObservers() {
+		t.Fatalf("expected no observers after clearing them")
+	}
+	filter.ApplyFilter(route, Cluster1)
+	if len(first) != 2 {
+		t.Fatalf("expected no decisions after clearing the observers")
+	}
+}
+
+func TestIsHostnameInGslbDomain(t *testing.T) {
+	defer gslbutils.SetGslbDomains(nil)
+
+	if !gslbutils.IsHostnameInGslbDomain("foo.example.com") {
+		t.Fatalf("expected all the hostnames to be allowed without any GSLB domains")
+	}
+	if _, err := gslbutils.SetGslbDomains([]string{""}); err == nil {
+		t.Fatalf("expected an error for an empty GSLB domain")
+	}
+	changed, err := gslbutils.SetGslbDomains([]string{"GSLB.avi.com.", "apps.avi.com"})
+	if err != nil || !changed {
+		t.Fatalf("expected the GSLB domains to change, err: %v", err)
+	}
+	if changed, _ := gslbutils.SetGslbDomains([]string{"apps.avi.com", "gslb.avi.com"}); changed {
+		t.Fatalf("expected no change for the same GSLB domains")
+	}
+	testCases := map[string]bool{
+		"gslb.avi.com":          true,
+		"foo.gslb.avi.com":      true,
+		"Foo.Apps.Avi.Com.":     true,
+		"foogslb.avi.com":       false,
+		"foo.avi.com":           false,
+		"gslb.avi.com.evil.com": false,
+	}
+	for host, expected := range testCases {
+		if gslbutils.IsHostnameInGslbDomain(host) != expected {
+			t.Fatalf("expected %v for hostname %s", expected, host)
+		}
+	}
+}
+
+func TestGslbDomainFilter(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.SetGslbDomains(nil)
+
+	gslbutils.GetGlobalFilter().AddToFilter(getTestGDP(nil))
+	route := k8sobjects.RouteMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "route1",
+		Hostname:  "foo.avi.com",
+		Labels:    map[string]string{"key": "value"},
+	}
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	defer gslbutils.ClearFilterObservers()
+
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route to be accepted without any GSLB domains")
+	}
+	gslbutils.SetGslbDomains([]string{"gslb.avi.com"})
+	if filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route outside the GSLB domains to be rejected")
+	}
+	if reason != "hostname outside GSLB domain" {
+		t.Fatalf("unexpected reason for the rejection: %s", reason)
+	}
+	route.Hostname = "foo.gslb.avi.com"
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route in the GSLB domain to be accepted")
+	}
+}
+
+func TestGslbDomainFilterExtraHosts(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.SetGslbDomains(nil)
+
+	gslbutils.GetGlobalFilter().AddToFilter(getTestGDP(nil))
+	gslbutils.SetGslbDomains([]string{"gslb.avi.com"})
+	route := k8sobjects.RouteMeta{
+		Cluster:         Cluster1,
+		Namespace:       TestNS,
+		Name:            "route1",
+		Hostname:        "foo.gslb.avi.com",
+		Labels:          map[string]string{"key": "value"},
+		AdditionalHosts: []string{"bar.gslb.avi.com", "bar.example.com"},
+	}
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route in the GSLB domain to be accepted")
+	}
+	// the extra host outside the GSLB domains doesn't get a GSLB service
+	if hosts := route.GetAcceptedExtraHosts(); !reflect.DeepEqual(hosts, []string{"bar.gslb.avi.com"}) {
+		t.Fatalf("expected only the extra host in the GSLB domain to be accepted, got %v", hosts)
+	}
+	hostMetas := route.GetAcceptedExtraHostMetas()
+	if len(hostMetas) != 1 || hostMetas[0].Hostname != "bar.gslb.avi.com" {
+		t.Fatalf("expected a meta object only for the extra host in the GSLB domain, got %v", hostMetas)
+	}
+}
+
+func TestGetSameRegionClusters(t *testing.T) {
+	for _, cname := range []string{Cluster1, Cluster2, Cluster3} {
+		gslbutils.AddClusterContext(cname)
+	}
+	for cname, region := range map[string]string{Cluster1: "us-west", Cluster2: "us-east", Cluster3: "us-west"} {
+		if err := gslbutils.SetClusterRegion(cname, region); err != nil {
+			t.Fatalf("error in setting the region for %s: %v", cname, err)
+		}
+		defer gslbutils.SetClusterRegion(cname, "")
+	}
+	if err := gslbutils.SetClusterRegion("unknown-cluster", "us-west"); err == nil {
+		t.Fatalf("expected an error in setting the region for an unknown cluster")
+	}
+
+	// the clusters without an entry in the traffic split are in the region too
+	gf := getTestFilter([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 5},
+		{Cluster: Cluster2, Weight: 5},
+	})
+	clusters := gf.GetSameRegionClusters("us-west")
+	if len(clusters) != 2 || clusters[0] != Cluster1 || clusters[1] != Cluster3 {
+		t.Fatalf("expected clusters %s and %s in us-west, got %v", Cluster1, Cluster3, clusters)
+	}
+	if clusters := gf.GetSameRegionClusters("eu-central"); len(clusters) != 0 {
+		t.Fatalf("expected no clusters in eu-central, got %v", clusters)
+	}
+	if clusters := gf.GetSameRegionClusters(""); len(clusters) != 0 {
+		t.Fatalf("expected no clusters for an empty region, got %v", clusters)
+	}
+}
+
+func TestFilterExplain(t *testing.T) {
+	gf := getTestFilter(nil)
+	fe := gf.Explain(gslbutils.RouteType, Cluster1, TestNS, map[string]string{"key": "value"})
+	if !fe.Accepted || len(fe.Checks) != 3 {
+		t.Fatalf("expected the route to be accepted after 3 checks, got %+v", fe)
+	}
+
+	fe = gf.Explain(gslbutils.RouteType, "unknown", TestNS, map[string]string{"key": "other"})
+	if fe.Accepted {
+		t.Fatalf("expected the route to be rejected, got %+v", fe)
+	}
+	for _, check := range fe.Checks {
+		switch check.Name {
+		case gslbutils.FilterCheckCluster:
+			if check.Passed || check.Actual != "unknown" {
+				t.Fatalf("expected the cluster check to fail for the unknown cluster, got %+v", check)
+			}
+		case gslbutils.FilterCheckApp:
+			if check.Passed || check.Expected != "key=value" || check.Actual != "key=other" {
+				t.Fatalf("expected the app check to fail with the compared labels, got %+v", check)
+			}
+		}
+	}
+
+	fe = gslbutils.GetNewGlobalFilter().Explain(gslbutils.RouteType, Cluster1, TestNS, nil)
+	if fe.Accepted || len(fe.Checks) != 1 || fe.Checks[0].Name != gslbutils.FilterCheckPolicy {
+		t.Fatalf("expected only the policy check to fail without a GDP object, got %+v", fe)
+	}
+}
+
+// The explanation of an object runs the same checks as the filter, including the ones evaluated
+// before the selectors.
+func TestFilterExplainObject(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.SetNonRoutableIPFilter(false, nil)
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	defer gslbutils.ClearFilterObservers()
+
+	route := k8sobjects.RouteMeta{Cluster: Cluster1, Namespace: TestNS, Name: "route1", Hostname: "route1.avi.com",
+		IPAddr: "10.10.10.10", Labels: map[string]string{"key": "value"}}
+	gf := gslbutils.GetGlobalFilter()
+	fe := gf.EvaluateObject(route)
+	if filter.ApplyFilter(route, Cluster1) || fe.Accepted || fe.Reason != reason ||
+		fe.Reason != gslbutils.NoPolicyReason {
+		t.Fatalf("expected the route to be rejected without a GDP object, reason: %s, got %+v", reason, fe)
+	}
+
+	gf.AddToFilter(getTestGDP(nil))
+	fe = gf.EvaluateObject(route)
+	if !filter.ApplyFilter(route, Cluster1) || !fe.Accepted || fe.Reason != reason {
+		t.Fatalf("expected the route to be accepted, reason: %s, got %+v", reason, fe)
+	}
+
+	if err := gslbutils.SetNonRoutableIPFilter(true, []string{"10.0.0.0/8"}); err != nil {
+		t.Fatalf("error in enabling the non-routable IP filter: %v", err)
+	}
+	fe = gf.EvaluateObject(route)
+	if filter.ApplyFilter(route, Cluster1) || fe.Accepted || fe.Reason != reason ||
+		fe.Reason != gslbutils.NonRoutableIPReason {
+		t.Fatalf("expected the route with a non-routable IP to be rejected, reason: %s, got %+v", reason, fe)
+	}
+
+	route.Hostname = ""
+	fe = gf.EvaluateObject(route)
+	if filter.ApplyFilter(route, Cluster1) || fe.Accepted || fe.Reason != reason ||
+		fe.Reason != k8sobjects.EmptyHostnameReason {
+		t.Fatalf("expected the route without a hostname to be rejected, reason: %s, got %+v", reason, fe)
+	}
+	if fe.Checks[0].Name != gslbutils.FilterCheckHostname || fe.Checks[1].Name != gslbutils.FilterCheckRoutableIP {
+		t.Fatalf("expected the hostname and the IP checks first, got %+v", fe.Checks)
+	}
+}
+
+func TestSelectorModeNamespaceOverride(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getTestGDP(nil)
+	gdp.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"ns": "prod"}
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	if err := gf.AddNSToNSFilter(Cluster1, TestNS); err != nil {
+		t.Fatalf("error in selecting the namespace: %v", err)
+	}
+	if gf.SelectorMode != gslbalphav1.SelectorModeAnd {
+		t.Fatalf("expected the default selector mode %s, got %s", gslbalphav1.SelectorModeAnd, gf.SelectorMode)
+	}
+
+	unlabeledRoute := k8sobjects.RouteMeta{Cluster: Cluster1, Namespace: TestNS, Name: "route1",
+		Hostname: "route1.avi.com", Labels: map[string]string{}}
+	labeledRoute := k8sobjects.RouteMeta{Cluster: Cluster1, Namespace: "other-ns", Name: "route2",
+		Hostname: "route2.avi.com", Labels: map[string]string{"key": "value"}}
+	if filter.ApplyFilter(unlabeledRoute, Cluster1) || filter.ApplyFilter(labeledRoute, Cluster1) {
+		t.Fatalf("expected both the selectors to be required in the %s mode", gslbalphav1.SelectorModeAnd)
+	}
+
+	newGDP := getTestGDP(nil)
+	newGDP.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"ns": "prod"}
+	newGDP.Spec.MatchRules.SelectorMode = gslbalphav1.SelectorModeNamespaceOverride
+	changes := gf.UpdateFilter(gdp, newGDP)
+	if changes != gslbutils.FilterChangeMatchOptions || !changes.NeedsReevaluation() {
+		t.Fatalf("expected only the match options to change with the selector mode, got %s", changes.String())
+	}
+	if err := gf.AddNSToNSFilter(Cluster1, TestNS); err != nil {
+		t.Fatalf("error in selecting the namespace: %v", err)
+	}
+	if !filter.ApplyFilter(unlabeledRoute, Cluster1) {
+		t.Fatalf("expected the route in the selected namespace to be accepted regardless of its labels")
+	}
+	if filter.ApplyFilter(labeledRoute, Cluster1) {
+		t.Fatalf("expected the route matching the appSelector to be rejected outside the selected namespaces")
+	}
+	unlabeledRoute.Namespace = "other-ns"
+	if filter.ApplyFilter(unlabeledRoute, Cluster1) {
+		t.Fatalf("expected the route outside the selected namespaces and without the labels to be rejected")
+	}
+
+	if fe := gf.Explain(gslbutils.RouteType, Cluster1, TestNS, nil); !fe.Accepted {
+		t.Fatalf("expected the explanation to accept the route in the selected namespace, got %+v", fe)
+	}
+	if fe := gf.Explain(gslbutils.RouteType, Cluster1, "other-ns", map[string]string{"key": "value"}); fe.Accepted {
+		t.Fatalf("expected the explanation to reject the route outside the selected namespaces, got %+v", fe)
+	}
+	if fe := gf.Explain(gslbutils.RouteType, Cluster1, "other-ns", nil); fe.Accepted {
+		t.Fatalf("expected the explanation to reject the route without the labels, got %+v", fe)
+	}
+	if view := gf.GetView(); view.SelectorMode != gslbalphav1.SelectorModeNamespaceOverride {
+		t.Fatalf("expected the selector mode %s in the view, got %s", gslbalphav1.SelectorModeNamespaceOverride,
+			view.SelectorMode)
+	}
+}
+
+func getFilterExplanation(t *testing.T, query string) (int, gslbutils.FilterExplanation) {
+	req := httptest.NewRequest("GET", gslbutils.FilterExplainPath+"?"+query, nil)
+	rec := httptest.NewRecorder()
+	gslbutils.FilterExplainHandler(rec, req)
+	var fe gslbutils.FilterExplanation
+	if rec.Code != http.StatusBadRequest {
+		if err := json.Unmarshal(rec.Body.Bytes(), &fe); err != nil {
+			t.Fatalf("error in decoding the explanation: %v", err)
+		}
+	}
+	return rec.Code, fe
+}
+
+func TestFilterExplainHandler(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	gdp := getTestGDP(nil)
+	gdp.Spec.MatchRules.RequireReady = true
+	gslbutils.GetGlobalFilter().AddToFilter(gdp)
+
+	ihm := k8sobjects.IngressHostMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		IngName:   "ing1",
+		ObjName:   "ing1/foo.avi.com",
+		Hostname:  "foo.avi.com",
+		Paths:     []string{"/"},
+		Labels:    map[string]string{"key": "value"},
+	}
+	rejectedStore := gslbutils.GetRejectedIngressStore()
+	rejectedStore.AddOrUpdate(ihm, Cluster1, TestNS, ihm.ObjName)
+	defer rejectedStore.DeleteClusterNSObj(Cluster1, TestNS, ihm.ObjName)
+
+	code, fe := getFilterExplanation(t, "objtype=ingress&cluster="+Cluster1+"&namespace="+TestNS+"&name=ing1/foo.avi.com")
+	if code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", code)
+	}
+	if fe.Accepted {
+		t.Fatalf("expected the ingress which is not ready to be rejected, got %+v", fe)
+	}
+	if fe.Reason != "rejected because object is not ready" {
+		t.Fatalf("expected the readiness check to fail, got %+v", fe.Checks)
+	}
+
+	if code, _ := getFilterExplanation(t, "objtype=ingress&cluster="+Cluster1+"&namespace="+TestNS+"&name=unknown"); code != http.StatusNotFound {
+		t.Fatalf("expected status 404 for an unknown object, got %d", code)
+	}
+	if code, _ := getFilterExplanation(t, "objtype=ingress&cluster="+Cluster1); code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 without the name, got %d", code)
+	}
+}
+
+func TestNamespaceSelectorOperators(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getTestGDP(nil)
+	gdp.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod", "team": "payments"}
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+
+	prodNS := k8sobjects.NSMeta{Cluster: Cluster1, Name: "prod", Labels: map[string]string{"env": "prod"}}
+	paymentsNS := k8sobjects.NSMeta{Cluster: Cluster1, Name: "payments",
+		Labels: map[string]string{"env": "prod", "team": "payments"}}
+	if prodNS.ApplyFilter() {
+		t.Fatalf("expected a namespace with only one of the labels to be rejected with the default AND operator")
+	}
+	if !paymentsNS.ApplyFilter() {
+		t.Fatalf("expected a namespace with all the labels to be accepted with the default AND operator")
+	}
+
+	newGDP := getTestGDP(nil)
+	newGDP.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod", "team": "payments"}
+	newGDP.Spec.MatchRules.NamespaceSelector.Operator = gslbalphav1.LabelOperatorOr
+	if changes := gf.UpdateFilter(gdp, newGDP); !changes.NeedsReevaluation() {
+		t.Fatalf("expected the operator change to require a full re-evaluation, got %s", changes)
+	}
+	if !prodNS.ApplyFilter() {
+		t.Fatalf("expected a namespace with one of the labels to be accepted with the OR operator")
+	}
+	otherNS := k8sobjects.NSMeta{Cluster: Cluster1, Name: "other", Labels: map[string]string{"env": "dev"}}
+	if otherNS.ApplyFilter() {
+		t.Fatalf("expected a namespace with none of the labels to be rejected with the OR operator")
+	}
+	labels, err := gf.GetNSFilterLabels()
+	if err != nil || len(labels) != 2 {
+		t.Fatalf("expected 2 namespace filter labels, got %v, %v", labels, err)
+	}
+}
+
+func TestLabelMatchingIgnoreCase(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getTestGDP(nil)
+	gdp.Spec.MatchRules.AppSelector.Label = map[string]string{"env": "prod"}
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+
+	prodSvc, ok := k8sobjects.GetSvcMeta(getTestLBSvc("prod-svc", map[string]string{"env": "Prod"}), Cluster1)
+	if !ok {
+		t.Fatalf("expected a valid service meta")
+	}
+	if filter.ApplyFilter(prodSvc, Cluster1) {
+		t.Fatalf("expected the label values to be matched case-sensitively by default")
+	}
+
+	newGDP := getTestGDP(nil)
+	newGDP.Spec.MatchRules.AppSelector.Label = map[string]string{"env": "prod"}
+	newGDP.Spec.MatchRules.AppSelector.IgnoreCase = true
+	if changed, _ := gf.UpdateGlobalFilter(gdp, newGDP); !changed {
+		t.Fatalf("expected the filter to change with ignoreCase")
+	}
+	if !filter.ApplyFilter(prodSvc, Cluster1) {
+		t.Fatalf("expected the label value to be matched case-insensitively")
+	}
+	upperKeySvc, _ := k8sobjects.GetSvcMeta(getTestLBSvc("upper-key-svc", map[string]string{"ENV": "prod"}), Cluster1)
+	if filter.ApplyFilter(upperKeySvc, Cluster1) {
+		t.Fatalf("expected the label keys to be matched exactly")
+	}
+
+	// the namespace selector has its own setting
+	nsGDP := getTestGDP(nil)
+	nsGDP.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod"}
+	nsFilter := gslbutils.GetNewGlobalFilter()
+	nsFilter.AddToFilter(nsGDP)
+	prodNS := k8sobjects.NSMeta{Cluster: Cluster1, Name: "prod", Labels: map[string]string{"env": "PROD"}}
+	if nsFilter.NSFilter.Matches(prodNS.Labels) {
+		t.Fatalf("expected the namespace label values to be matched case-sensitively by default")
+	}
+	nsGDP.Spec.MatchRules.NamespaceSelector.IgnoreCase = true
+	ignoreCaseFilter := gslbutils.GetNewGlobalFilter()
+	ignoreCaseFilter.AddToFilter(nsGDP)
+	if !ignoreCaseFilter.NSFilter.Matches(prodNS.Labels) {
+		t.Fatalf("expected the namespace label value to be matched case-insensitively")
+	}
+	if nsFilter.Checksum == ignoreCaseFilter.Checksum {
+		t.Fatalf("expected ignoreCase to be a part of the checksum")
+	}
+	expectedFields := []gslbutils.FieldChange{
+		{Field: gslbutils.FilterFieldNamespaceSelector, Old: "env=prod", New: "env=prod (ignoreCase)"},
+	}
+	if diff := nsFilter.Diff(ignoreCaseFilter); !reflect.DeepEqual(diff.FieldChanges, expectedFields) {
+		t.Fatalf("expected the field changes %v, got %v", expectedFields, diff.FieldChanges)
+	}
+}
+
+func TestClusterObjLimit(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(getTestGDP(nil))
+	if err := gf.SetClusterObjLimit(-1); err == nil {
+		t.Fatalf("expected an error for a negative limit")
+	}
+	if err := gf.SetClusterObjLimit(2); err != nil {
+		t.Fatalf("error in setting the cluster object limit: %v", err)
+	}
+
+	routes := []k8sobjects.RouteMeta{}
+	for i := 0; i < 3; i++ {
+		routes = append(routes, k8sobjects.RouteMeta{
+			Cluster:   Cluster1,
+			Namespace: TestNS,
+			Name:      "route" + strconv.Itoa(i),
+			Hostname:  "route" + strconv.Itoa(i) + ".avi.com",
+			Labels:    map[string]string{"key": "value"},
+		})
+	}
+	if !filter.ApplyFilter(routes[0], Cluster1) || !filter.ApplyFilter(routes[1], Cluster1) {
+		t.Fatalf("expected the routes within the limit to be accepted")
+	}
+	// an object already counted must be accepted again on re-evaluation
+	if !filter.ApplyFilter(routes[0], Cluster1) {
+		t.Fatalf("expected an accepted route to be accepted again")
+	}
+	if filter.ApplyFilter(routes[2], Cluster1) {
+		t.Fatalf("expected the route over the limit to be rejected")
+	}
+	fe := gf.Explain(gslbutils.RouteType, Cluster1, TestNS, routes[2].Labels)
+	if !fe.Accepted {
+		t.Fatalf("expected the filter checks to pass for the route over the limit, got %+v", fe)
+	}
+	otherCluster := routes[2]
+	otherCluster.Cluster = Cluster2
+	if !filter.ApplyFilter(otherCluster, Cluster2) {
+		t.Fatalf("expected the limit to apply per cluster")
+	}
+
+	// a rejected object is removed from the count
+	routes[1].Labels = map[string]string{"key": "other"}
+	if filter.ApplyFilter(routes[1], Cluster1) {
+		t.Fatalf("expected the route with changed labels to be rejected")
+	}
+	if count := gf.GetClusterObjCount(Cluster1); count != 1 {
+		t.Fatalf("expected 1 object counted for %s, got %d", Cluster1, count)
+	}
+	if !filter.ApplyFilter(routes[2], Cluster1) {
+		t.Fatalf("expected the route to be accepted once the cluster is below the limit")
+	}
+	gf.ReleaseClusterObj(Cluster1, gslbutils.RouteType, TestNS, routes[0].Name)
+	if count := gf.GetClusterObjCount(Cluster1); count != 1 {
+		t.Fatalf("expected 1 object counted for %s after a delete, got %d", Cluster1, count)
+	}
+}
+
+func TestPercentageWeightMode(t *testing.T) {
+	gf := getTestFilter([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 6}})
+	if mode := gf.GetWeightMode(); mode != gslbalphav1.WeightModeWeight {
+		t.Fatalf("expected the weight mode by default, got %s", mode)
+	}
+
+	gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 70},
+		{Cluster: Cluster2, Weight: 30},
+	})
+	gdp.Spec.WeightMode = gslbalphav1.WeightModePercentage
+	gf = gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(gdp)
+	if mode := gf.GetWeightMode(); mode != gslbalphav1.WeightModePercentage {
+		t.Fatalf("expected the percentage mode, got %s", mode)
+	}
+	if w, _ := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); w != 70 {
+		t.Fatalf("expected a percentage of 70 for %s, got %d", Cluster1, w)
+	}
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster3, nil, 0); err != nil || w != 0 {
+		t.Fatalf("expected a percentage of 0 for %s, got %d, %v", Cluster3, w, err)
+	}
+
+	// switching the mode alone changes the traffic weights
+	weightGDP := getTestGDP(gdp.Spec.TrafficSplit)
+	if changed, weightChanged := gf.UpdateGlobalFilter(gdp, weightGDP); !changed || !weightChanged {
+		t.Fatalf("expected the mode change to change the traffic weights, got %v, %v", changed, weightChanged)
+	}
+	if mode := gf.GetWeightMode(); mode != gslbalphav1.WeightModeWeight {
+		t.Fatalf("expected the weight mode after the update, got %s", mode)
+	}
+
+	for percentage, ratio := range map[int32]int32{0: 0, 1: 1, 30: 6, 33: 7, 70: 14, 100: gslbutils.MaxRatio} {
+		if r := gslbutils.PercentageToRatio(percentage); r != ratio {
+			t.Fatalf("expected a ratio of %d for %d%%, got %d", ratio, percentage, r)
+		}
+	}
+}
+
+func TestBackendsWeightMode(t *testing.T) {
+	gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 6},
+		{Cluster: Cluster2, Weight: 2},
+		{Cluster: Cluster3, Weight: 4},
+	})
+	gdp.Spec.WeightMode = gslbalphav1.WeightModeBackends
+	interval := 30
+	gdp.Spec.WeightRecomputeInterval = &interval
+	gf := gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(gdp)
+	if ri := gf.GetWeightRecomputeInterval(); ri != 30*time.Second {
+		t.Fatalf("expected a recompute interval of 30s, got %v", ri)
+	}
+
+	// without any backend counts, the traffic split applies
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster1, nil, 0); err != nil || w != 6 {
+		t.Fatalf("expected weight 6 for %s, got %d, err: %v", Cluster1, w, err)
+	}
+
+	if !gf.SetBackendCounts(map[string]int32{Cluster1: 10, Cluster2: 3}) {
+		t.Fatalf("expected the backend counts to change")
+	}
+	if gf.SetBackendCounts(map[string]int32{Cluster1: 10, Cluster2: 3}) {
+		t.Fatalf("expected the backend counts to be the same")
+	}
+	expected := map[string]int32{Cluster1: gslbutils.MaxRatio, Cluster2: 6, Cluster3: 4}
+	for cname, weight := range expected {
+		if w, err := gf.GetTrafficWeight(TestNS, cname, nil, 0); err != nil || w != weight {
+			t.Fatalf("expected weight %d for %s, got %d, err: %v", weight, cname, w, err)
+		}
+	}
+
+	// a cluster without any ready backends gets no traffic
+	gf.SetBackendCounts(map[string]int32{Cluster1: 10, Cluster2: 0})
+	if w, err := gf.GetTrafficWeight(TestNS, Cluster2, nil, 0); err != nil || w != 0 {
+		t.Fatalf("expected weight 0 for %s, got %d, err: %v", Cluster2, w, err)
+	}
+
+	// the backend counts are dropped on switching the mode
+	weightGDP := getTestGDP(gdp.Spec.TrafficSplit)
+	if changed, weightChanged := gf.UpdateGlobalFilter(gdp, weightGDP); !changed || !weightChanged {
+		t.Fatalf("expected the mode change to change the traffic weights, got %v, %v", changed, weightChanged)
+	}
+	if _, ok := gf.GetBackendCount(Cluster1); ok {
+		t.Fatalf("expected no backend counts after switching the mode")
+	}
+	if ri := gf.GetWeightRecomputeInterval(); ri != gslbutils.DefaultWeightRecomputeInterval*time.Second {
+		t.Fatalf("expected the default recompute interval, got %v", ri)
+	}
+}
+
+func TestLBSvcPortNames(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getTestGDP(nil)
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+
+	svc := getTestLBSvc("multi-port-svc", map[string]string{"key": "value"})
+	svc.Spec.Ports = []corev1.ServicePort{
+		{Name: "metrics", Port: 9090, Protocol: corev1.ProtocolTCP},
+		{Name: "web", Port: 8080, Protocol: corev1.ProtocolTCP},
+		{Name: "dns", Port: 53, Protocol: corev1.ProtocolUDP},
+	}
+	svcMeta, ok := k8sobjects.GetSvcMeta(svc, Cluster1)
+	if !ok {
+		t.Fatalf("expected a valid service meta")
+	}
+	if !filter.ApplyFilter(svcMeta, Cluster1) {
+		t.Fatalf("expected the service to be accepted without portNames")
+	}
+	if port, _ := svcMeta.GetPort(); port != 53 {
+		t.Fatalf("expected the lowest port 53 without portNames, got %d", port)
+	}
+
+	newGDP := getTestGDP(nil)
+	newGDP.Spec.MatchRules.PortNames = []string{"web", "metrics"}
+	if changed, _ := gf.UpdateGlobalFilter(gdp, newGDP); !changed {
+		t.Fatalf("expected the filter to change with portNames")
+	}
+	if !filter.ApplyFilter(svcMeta, Cluster1) {
+		t.Fatalf("expected the service with a matching port to be accepted")
+	}
+	port, _ := svcMeta.GetPort()
+	protocol, _ := svcMeta.GetProtocol()
+	if port != 8080 || protocol != gslbutils.ProtocolTCP {
+		t.Fatalf("expected the port 8080/TCP listed first in portNames, got %d/%s", port, protocol)
+	}
+
+	gdp, newGDP = newGDP, getTestGDP(nil)
+	newGDP.Spec.MatchRules.PortNames = []string{"metrics", "web"}
+	if changed, _ := gf.UpdateGlobalFilter(gdp, newGDP); !changed {
+		t.Fatalf("expected the filter to change with the order of portNames")
+	}
+	if port, _ := svcMeta.GetPort(); port != 9090 {
+		t.Fatalf("expected the port 9090 listed first in portNames, got %d", port)
+	}
+
+	gdp, newGDP = newGDP, getTestGDP(nil)
+	newGDP.Spec.MatchRules.PortNames = []string{"grpc"}
+	if changed, _ := gf.UpdateGlobalFilter(gdp, newGDP); !changed {
+		t.Fatalf("expected the filter to change with portNames")
+	}
+	if filter.ApplyFilter(svcMeta, Cluster1) {
+		t.Fatalf("expected the service without a matching port to be rejected")
+	}
+}
+
+func TestFilterDiff(t *testing.T) {
+	oldFilter := getTestFilter([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 5},
+		{Cluster: Cluster2, Weight: 10},
+	})
+	if diff := oldFilter.Diff(getTestFilter([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 5},
+		{Cluster: Cluster2, Weight: 10},
+	})); !diff.IsEmpty() {
+		t.Fatalf("expected no diff for the same filters, got %s", diff)
+	}
+
+	newGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster2, Weight: 15, Priority: 5},
+		{Cluster: Cluster3, Weight: 2},
+	})
+	newGDP.Spec.MatchClusters = []string{Cluster2, Cluster3, "cluster4"}
+	newGDP.Spec.MatchRules.AppSelector.Label = map[string]string{"app": "gslb"}
+	newGDP.Spec.MatchRules.RequireReady = true
+	newFilter := gslbutils.GetNewGlobalFilter()
+	newFilter.AddToFilter(newGDP)
+
+	diff := oldFilter.Diff(newFilter)
+	if !reflect.DeepEqual(diff.AddedClusters, []string{"cluster4"}) {
+		t.Fatalf("expected cluster4 to be added, got %v", diff.AddedClusters)
+	}
+	if !reflect.DeepEqual(diff.RemovedClusters, []string{Cluster1}) {
+		t.Fatalf("expected cluster1 to be removed, got %v", diff.RemovedClusters)
+	}
+	if len(diff.TrafficChanges) != 3 {
+		t.Fatalf("expected 3 traffic changes, got %s", diff)
+	}
+	removed, changed, added := diff.TrafficChanges[0], diff.TrafficChanges[1], diff.TrafficChanges[2]
+	if removed.Cluster != Cluster1 || *removed.OldWeight != 5 || removed.NewWeight != nil {
+		t.Fatalf("expected the traffic split of cluster1 to be removed, got %s", diff)
+	}
+	if changed.Cluster != Cluster2 || *changed.OldWeight != 10 || *changed.NewWeight != 15 ||
+		*changed.OldPriority != gslbutils.DefaultPriority || *changed.NewPriority != 5 {
+		t.Fatalf("expected the traffic split of cluster2 to be changed, got %s", diff)
+	}
+	if added.Cluster != Cluster3 || added.OldWeight != nil || *added.NewWeight != 2 {
+		t.Fatalf("expected the traffic split of cluster3 to be added, got %s", diff)
+	}
+	expectedFields := []gslbutils.FieldChange{
+		{Field: gslbutils.FilterFieldAppSelector, Old: "key=value", New: "app=gslb"},
+		{Field: gslbutils.FilterFieldRequireReady, Old: "false", New: "true"},
+	}
+	if !reflect.DeepEqual(diff.FieldChanges, expectedFields) {
+		t.Fatalf("expected the field changes %v, got %v", expectedFields, diff.FieldChanges)
+	}
+	if !newFilter.Diff(newFilter).IsEmpty() {
+		t.Fatalf("expected no diff of a filter with itself")
+	}
+}
+
+func TestDenyLabel(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.SetDenyLabel("")
+	gslbutils.GetAndResetFilterDecisions()
+	gslbutils.GetAndResetDisabledObjs()
+
+	for _, invalid := range []string{"gslb.avi.io/enabled", "=false"} {
+		if err := gslbutils.SetDenyLabel(invalid); err == nil {
+			t.Fatalf("expected an error for the deny label %s", invalid)
+		}
+	}
+	if err := gslbutils.SetDenyLabel("gslb.avi.io/enabled=false"); err != nil {
+		t.Fatalf("unexpected error in setting the deny label: %v", err)
+	}
+
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	defer gslbutils.ClearFilterObservers()
+
+	route := k8sobjects.RouteMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "route1",
+		Hostname:  "route1.avi.com",
+		Labels:    map[string]string{"key": "value", "gslb.avi.io/enabled": "false"},
+	}
+	// the deny label is checked before the policy
+	if filter.ApplyFilter(route, Cluster1) || reason != gslbutils.DisabledReason {
+		t.Fatalf("expected the route with the deny label to be rejected as explicitly disabled, reason: %s", reason)
+	}
+	gslbutils.GetGlobalFilter().AddToFilter(getTestGDP(nil))
+	if filter.ApplyFilter(route, Cluster1) || reason != gslbutils.DisabledReason {
+		t.Fatalf("expected the route matching the appSelector to be rejected as explicitly disabled, reason: %s", reason)
+	}
+	route.Labels["gslb.avi.io/enabled"] = "true"
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route without the deny label to be accepted")
+	}
+
+	accepted, rejected := gslbutils.GetAndResetFilterDecisions()
+	if accepted != 1 || rejected != 2 || gslbutils.GetAndResetDisabledObjs() != 2 {
+		t.Fatalf("expected 1 accepted and 2 rejected (explicitly disabled) objects, got %d accepted and %d rejected",
+			accepted, rejected)
+	}
+
+	fe := gslbutils.GetGlobalFilter().Explain(gslbutils.RouteType, Cluster1, TestNS,
+		map[string]string{"key": "value", "gslb.avi.io/enabled": "false"})
+	if fe.Accepted || fe.Checks[0].Name != gslbutils.FilterCheckDenyLabel || fe.Checks[0].Passed ||
+		fe.Checks[0].Expected != "gslb.avi.io/enabled=false" {
+		t.Fatalf("expected the deny label check to fail first, got %+v", fe)
+	}
+}
+
+func TestNonRoutableIPFilter(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.SetNonRoutableIPFilter(false, nil)
+
+	if err := gslbutils.SetNonRoutableIPFilter(true, []string{"10.0.0.0/8", "10.1.1.1"}); err == nil {
+		t.Fatalf("expected an error for an invalid CIDR")
+	}
+	if err := gslbutils.SetNonRoutableIPFilter(true, nil); err != nil {
+		t.Fatalf("unexpected error in enabling the non-routable IP filter: %v", err)
+	}
+	if !reflect.DeepEqual(gslbutils.GetNonRoutableCIDRs(), gslbutils.DefaultNonRoutableCIDRs) {
+		t.Fatalf("expected the default CIDRs %v, got %v", gslbutils.DefaultNonRoutableCIDRs,
+			gslbutils.GetNonRoutableCIDRs())
+	}
+	nonRoutable := map[string]bool{
+		"10.10.10.10":    true,
+		"172.20.1.1":     true,
+		"192.168.1.1":    true,
+		"fd12:3456::1":   true,
+		"172.32.1.1":     false,
+		"8.8.8.8":        false,
+		"2001:db8::1":    false,
+		"":               false,
+		"lb.example.com": false,
+	}
+	for ip, expected := range nonRoutable {
+		if gslbutils.IsNonRoutableIP(ip) != expected {
+			t.Fatalf("expected the IP %q to be non-routable: %t", ip, expected)
+		}
+	}
+
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	defer gslbutils.ClearFilterObservers()
+	gslbutils.GetGlobalFilter().AddToFilter(getTestGDP(nil))
+
+	route := k8sobjects.RouteMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "route1",
+		Hostname:  "route1.avi.com",
+		IPAddr:    "10.10.10.10",
+		Labels:    map[string]string{"key": "value"},
+	}
+	if filter.ApplyFilter(route, Cluster1) || reason != gslbutils.NonRoutableIPReason {
+		t.Fatalf("expected the route with a private IP to be rejected as non-routable, reason: %s", reason)
+	}
+	route.IPAddr = "8.8.8.8"
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route with a public IP to be accepted, reason: %s", reason)
+	}
+
+	if err := gslbutils.SetNonRoutableIPFilter(true, []string{"8.8.8.0/24"}); err != nil {
+		t.Fatalf("unexpected error in setting the CIDRs: %v", err)
+	}
+	if filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route with an IP in the configured CIDRs to be rejected")
+	}
+	if err := gslbutils.SetNonRoutableIPFilter(false, nil); err != nil {
+		t.Fatalf("unexpected error in disabling the non-routable IP filter: %v", err)
+	}
+	route.IPAddr = "10.10.10.10"
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route with a private IP to be accepted with the filter disabled")
+	}
+
+	// all the VIPs of a service exposing more than one VIP are checked, not just the first one
+	if err := gslbutils.SetNonRoutableIPFilter(true, nil); err != nil {
+		t.Fatalf("unexpected error in enabling the non-routable IP filter: %v", err)
+	}
+	svc := k8sobjects.SvcMeta{
+		Cluster:   Cluster1,
+		Namespace: TestNS,
+		Name:      "svc1",
+		Hostname:  "svc1.avi.com",
+		IPAddr:    "8.8.8.8",
+		VIPs:      []gslbutils.VIP{{IP: "8.8.8.8", Weight: 1}, {IP: "192.168.1.1", Weight: 1}},
+		Labels:    map[string]string{"key": "value"},
+	}
+	if filter.ApplyFilter(svc, Cluster1) || reason != gslbutils.NonRoutableIPReason {
+		t.Fatalf("expected the service with a private VIP to be rejected as non-routable, reason: %s", reason)
+	}
+	svc.VIPs = svc.VIPs[:1]
+	if !filter.ApplyFilter(svc, Cluster1) {
+		t.Fatalf("expected the service with only public VIPs to be accepted, reason: %s", reason)
+	}
+}
+
+func TestNamespaceSelectorGlobalScope(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getTestGDP(nil)
+	gdp.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod"}
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	clusterChecksum := gf.GetChecksum()
+
+	newGDP := getTestGDP(nil)
+	newGDP.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod"}
+	newGDP.Spec.MatchRules.NamespaceSelector.Scope = gslbalphav1.NamespaceScopeGlobal
+	if changed, _ := gf.UpdateGlobalFilter(gdp, newGDP); !changed {
+		t.Fatalf("expected the filter to change from the cluster scope to the global scope")
+	}
+	if gf.GetChecksum() == clusterChecksum {
+		t.Fatalf("expected the checksum to change with the scope")
+	}
+	if !gf.IsNSFilterGlobal() {
+		t.Fatalf("expected the namespace filter to be global")
+	}
+
+	acceptedNSStore := gslbutils.GetAcceptedNSStore()
+	defer acceptedNSStore.DeleteNSObj(Cluster1, TestNS)
+	defer acceptedNSStore.DeleteNSObj(Cluster2, TestNS)
+
+	labelledNS1 := k8sobjects.NSMeta{Cluster: Cluster1, Name: TestNS, Labels: map[string]string{"env": "prod"}}
+	if !labelledNS1.ApplyFilter() {
+		t.Fatalf("expected the labelled namespace to be accepted")
+	}
+	acceptedNSStore.AddOrUpdate(Cluster1, TestNS, labelledNS1)
+
+	svc, ok := k8sobjects.GetSvcMeta(getTestLBSvc("test-svc", map[string]string{"key": "value"}), Cluster2)
+	if !ok {
+		t.Fatalf("expected a valid service meta")
+	}
+	if !filter.ApplyFilter(svc, Cluster2) {
+		t.Fatalf("expected the service to be accepted in a cluster where the namespace isn't labelled")
+	}
+
+	labelledNS2 := k8sobjects.NSMeta{Cluster: Cluster2, Name: TestNS, Labels: map[string]string{"env": "prod"}}
+	if !labelledNS2.ApplyFilter() {
+		t.Fatalf("expected the labelled namespace to be accepted")
+	}
+	acceptedNSStore.AddOrUpdate(Cluster2, TestNS, labelledNS2)
+
+	// the namespace is still labelled in the other cluster, so it stays selected
+	acceptedNSStore.DeleteNSObj(Cluster1, TestNS)
+	if labelledNS1.DeleteFromFilter() {
+		t.Fatalf("expected the namespace to stay selected while it's labelled in another cluster")
+	}
+	if !filter.ApplyFilter(svc, Cluster2) {
+		t.Fatalf("expected the service to be accepted while the namespace is selected")
+	}
+
+	acceptedNSStore.DeleteNSObj(Cluster2, TestNS)
+	if !labelledNS2.DeleteFromFilter() {
+		t.Fatalf("expected the namespace to be removed from the filter")
+	}
+	if filter.ApplyFilter(svc, Cluster2) {
+		t.Fatalf("expected the service to be rejected after the namespace is removed from the filter")
+	}
+}
+
+// Test that a namespace unselected in one of two clusters moves to the rejected namespace store,
+// so that the global namespace filter drops it once it is unselected in the other cluster too.
+func TestNamespaceUnselectedInOneCluster(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getTestGDP(nil)
+	gdp.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod"}
+	gdp.Spec.MatchRules.NamespaceSelector.Scope = gslbalphav1.NamespaceScopeGlobal
+	gslbutils.GetGlobalFilter().AddToFilter(gdp)
+
+	acceptedNSStore := gslbutils.GetAcceptedNSStore()
+	rejectedNSStore := gslbutils.GetRejectedNSStore()
+	for _, cname := range []string{Cluster1, Cluster2} {
+		defer acceptedNSStore.DeleteNSObj(cname, TestNS)
+		defer rejectedNSStore.DeleteNSObj(cname, TestNS)
+	}
+	svc, ok := k8sobjects.GetSvcMeta(getTestLBSvc("test-svc", map[string]string{"key": "value"}), Cluster1)
+	if !ok {
+		t.Fatalf("expected a valid service meta")
+	}
+
+	unlabelled := map[string]k8sobjects.NSMeta{}
+	for _, cname := range []string{Cluster1, Cluster2} {
+		unlabelled[cname] = k8sobjects.NSMeta{Cluster: cname, Name: TestNS, Labels: map[string]string{}}
+		labelled := k8sobjects.NSMeta{Cluster: cname, Name: TestNS, Labels: map[string]string{"env": "prod"}}
+		labelled.UpdateFilter(unlabelled[cname])
+		if _, found := acceptedNSStore.GetNSObjectByName(cname, TestNS); !found {
+			t.Fatalf("expected the labelled namespace of %s to be in the accepted store", cname)
+		}
+	}
+
+	// unselected in cluster1, the namespace stays selected as it is labelled in cluster2
+	labelled1 := k8sobjects.NSMeta{Cluster: Cluster1, Name: TestNS, Labels: map[string]string{"env": "prod"}}
+	if unlabelled[Cluster1].UpdateFilter(labelled1) {
+		t.Fatalf("expected the namespace to stay selected while it's labelled in another cluster")
+	}
+	if _, found := acceptedNSStore.GetNSObjectByName(Cluster1, TestNS); found {
+		t.Fatalf("expected the unselected namespace to be removed from the accepted store")
+	}
+	if _, found := rejectedNSStore.GetNSObjectByName(Cluster1, TestNS); !found {
+		t.Fatalf("expected the unselected namespace to be in the rejected store")
+	}
+	if !filter.ApplyFilter(svc, Cluster1) {
+		t.Fatalf("expected the service to be accepted while the namespace is selected")
+	}
+
+	// unselected in cluster2 as well, the namespace is removed from the filter
+	labelled2 := k8sobjects.NSMeta{Cluster: Cluster2, Name: TestNS, Labels: map[string]string{"env": "prod"}}
+	if !unlabelled[Cluster2].UpdateFilter(labelled2) {
+		t.Fatalf("expected the namespace to be removed from the filter")
+	}
+	if filter.ApplyFilter(svc, Cluster1) {
+		t.Fatalf("expected the service to be rejected after the namespace is removed from the filter")
+	}
+}
+
+func TestRejectedObjsCache(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	gslbutils.ClearRejectedObjs()
+	defer gslbutils.ClearRejectedObjs()
+	defer gslbutils.SetRejectedObjsCacheSize(gslbutils.DefaultRejectedObjsCacheSize)
+
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(getTestGDP(nil))
+
+	rejectedSvc, _ := k8sobjects.GetSvcMeta(getTestLBSvc("rejected-svc", map[string]string{"key": "other"}), Cluster1)
+	if filter.ApplyFilter(rejectedSvc, Cluster1) {
+		t.Fatalf("expected the service without the app labels to be rejected")
+	}
+	rejected := gslbutils.GetRejectedObjs()
+	if len(rejected) != 1 || rejected[0].Name != "rejected-svc" || rejected[0].Cluster != Cluster1 ||
+		rejected[0].ObjType != gslbalphav1.LBSvcObj || rejected[0].Reason == "" || rejected[0].Timestamp.IsZero() {
+		t.Fatalf("unexpected rejected objects: %v", rejected)
+	}
+
+	// the same object is accepted after a label change, and is removed from the cache
+	acceptedSvc, _ := k8sobjects.GetSvcMeta(getTestLBSvc("rejected-svc", map[string]string{"key": "value"}), Cluster1)
+	if !filter.ApplyFilter(acceptedSvc, Cluster1) {
+		t.Fatalf("expected the service with the app labels to be accepted")
+	}
+	if rejected := gslbutils.GetRejectedObjs(); len(rejected) != 0 {
+		t.Fatalf("expected the accepted service to be removed from the cache, got %v", rejected)
+	}
+
+	if err := gslbutils.SetRejectedObjsCacheSize(0); err == nil {
+		t.Fatalf("expected an error for a cache size of 0")
+	}
+	if err := gslbutils.SetRejectedObjsCacheSize(2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		svc, _ := k8sobjects.GetSvcMeta(getTestLBSvc("svc-"+strconv.Itoa(i), nil), Cluster2)
+		filter.ApplyFilter(svc, Cluster2)
+	}
+	rejected = gslbutils.GetRejectedObjs()
+	if len(rejected) != 2 || rejected[0].Name != "svc-2" || rejected[1].Name != "svc-1" {
+		t.Fatalf("expected the oldest rejected service to be evicted, got %v", rejected)
+	}
+
+	req := httptest.NewRequest("GET", gslbutils.RejectedObjsPath+"?objtype=lbsvc&cluster="+Cluster2, nil)
+	rec := httptest.NewRecorder()
+	gslbutils.RejectedObjsHandler(rec, req)
+	var served []gslbutils.RejectedObj
+	if err := json.NewDecoder(rec.Body).Decode(&served); err != nil {
+		t.Fatalf("couldn't decode the response: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(served) != 2 {
+		t.Fatalf("expected 2 rejected objects with 200, got %d, %v", rec.Code, served)
+	}
+	req = httptest.NewRequest("GET", gslbutils.RejectedObjsPath+"?cluster="+Cluster1, nil)
+	rec = httptest.NewRecorder()
+	gslbutils.RejectedObjsHandler(rec, req)
+	served = nil
+	json.NewDecoder(rec.Body).Decode(&served)
+	if len(served) != 0 {
+		t.Fatalf("expected no rejected objects in %s, got %v", Cluster1, served)
+	}
+
+	// a deleted object is removed from the cache, and so are all the objects of a removed cluster
+	filter.ApplyFilter(rejectedSvc, Cluster1)
+	gslbutils.DeleteRejectedObj(gslbalphav1.LBSvcObj, Cluster1, rejectedSvc.Namespace, rejectedSvc.Name)
+	rejected = gslbutils.GetRejectedObjs()
+	if len(rejected) != 1 || rejected[0].Name != "svc-2" {
+		t.Fatalf("expected the deleted service to be removed from the cache, got %v", rejected)
+	}
+	gslbutils.DeleteClusterRejectedObjs(Cluster2)
+	if rejected := gslbutils.GetRejectedObjs(); len(rejected) != 0 {
+		t.Fatalf("expected the objects of the removed cluster to be removed from the cache, got %v", rejected)
+	}
+}
+
+func TestFilterComponentChanges(t *testing.T) {
+	gf := getTestFilter([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}})
+	oldGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}})
+	checksum := gf.GetChecksum()
+
+	if changes := gf.UpdateFilter(oldGDP, oldGDP); changes != 0 {
+		t.Fatalf("expected no changes for the same GDP object, got %s", changes)
+	}
+
+	trafficGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 7}})
+	changes := gf.UpdateFilter(oldGDP, trafficGDP)
+	if changes != gslbutils.FilterChangeTraffic || changes.NeedsReevaluation() {
+		t.Fatalf("expected only the traffic to change, got %s", changes)
+	}
+	if gf.GetChecksum() == checksum {
+		t.Fatalf("expected the checksum of the filter to change")
+	}
+
+	settingsGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 7}})
+	gracePeriod := 30
+	settingsGDP.Spec.MemberRemovalGracePeriod = &gracePeriod
+	changes = gf.UpdateFilter(trafficGDP, settingsGDP)
+	if changes != gslbutils.FilterChangeSettings || changes.NeedsReevaluation() {
+		t.Fatalf("expected only the settings to change, got %s", changes)
+	}
+
+	selectorGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 7}})
+	selectorGDP.Spec.MemberRemovalGracePeriod = &gracePeriod
+	selectorGDP.Spec.MatchRules.AppSelector.Label = map[string]string{"key": "other"}
+	selectorGDP.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod"}
+	selectorGDP.Spec.MatchClusters = []string{Cluster1, Cluster2}
+	changes = gf.UpdateFilter(settingsGDP, selectorGDP)
+	expected := gslbutils.FilterChangeApp | gslbutils.FilterChangeNS | gslbutils.FilterChangeClusters
+	if changes != expected || !changes.NeedsReevaluation() {
+		t.Fatalf("expected the app, namespace and clusters to change, got %s", changes)
+	}
+	if changes.String() != "app,namespace,clusters" {
+		t.Fatalf("unexpected names of the changes: %s", changes.String())
+	}
+
+	optionsGDP := selectorGDP.DeepCopy()
+	optionsGDP.Spec.MatchRules.PortNames = []string{"https"}
+	changes = gf.UpdateFilter(selectorGDP, optionsGDP)
+	if changes != gslbutils.FilterChangeMatchOptions || !changes.NeedsReevaluation() {
+		t.Fatalf("expected only the match options to change, got %s", changes)
+	}
+}
+
+func TestAcceptancePredicates(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.ClearAcceptancePredicates()
+
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(getTestGDP(nil))
+
+	nsPattern := regexp.MustCompile("^def")
+	var evaluated []string
+	gslbutils.AddAcceptancePredicate(func(meta gslbutils.AcceptanceMeta) (bool, string) {
+		evaluated = append(evaluated, "namespace")
+		if nsPattern.MatchString(meta.Namespace) {
+			return false, "rejected because namespace " + meta.Namespace + " is restricted"
+		}
+		return true, ""
+	})
+	gslbutils.AddAcceptancePredicate(func(meta gslbutils.AcceptanceMeta) (bool, string) {
+		evaluated = append(evaluated, "hostname")
+		return true, ""
+	})
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	defer gslbutils.ClearFilterObservers()
+
+	// the predicates are evaluated only after the built-in checks pass
+	unlabelledSvc, _ := k8sobjects.GetSvcMeta(getTestLBSvc("unlabelled-svc", nil), Cluster1)
+	if filter.ApplyFilter(unlabelledSvc, Cluster1) || len(evaluated) != 0 {
+		t.Fatalf("expected the service to be rejected without evaluating the predicates, evaluated: %v", evaluated)
+	}
+
+	svc, _ := k8sobjects.GetSvcMeta(getTestLBSvc("test-svc", map[string]string{"key": "value"}), Cluster1)
+	if filter.ApplyFilter(svc, Cluster1) {
+		t.Fatalf("expected the service to be rejected by the predicate")
+	}
+	if reason != "rejected because namespace "+TestNS+" is restricted" {
+		t.Fatalf("unexpected reason: %s", reason)
+	}
+	if !reflect.DeepEqual(evaluated, []string{"namespace"}) {
+		t.Fatalf("expected the predicates after the rejecting one to be skipped, evaluated: %v", evaluated)
+	}
+
+	evaluated = nil
+	nsPattern = regexp.MustCompile("^prod")
+	if !filter.ApplyFilter(svc, Cluster1) {
+		t.Fatalf("expected the service to be accepted by the predicates")
+	}
+	if !reflect.DeepEqual(evaluated, []string{"namespace", "hostname"}) {
+		t.Fatalf("expected the predicates to be evaluated in order, evaluated: %v", evaluated)
+	}
+}
+
+func TestObjectTypesFilter(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.ClearFilterObservers()
+
+	gdp := getTestGDP(nil)
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	route := k8sobjects.RouteMeta{Cluster: Cluster1, Namespace: TestNS, Name: "route1", Hostname: "route1.avi.com",
+		Labels: map[string]string{"key": "value"}}
+	svc, _ := k8sobjects.GetSvcMeta(getTestLBSvc("svc1", map[string]string{"key": "value"}), Cluster1)
+	if !filter.ApplyFilter(route, Cluster1) || !filter.ApplyFilter(svc, Cluster1) {
+		t.Fatalf("expected all the object types to be selected without objectTypes")
+	}
+	checksum := gf.GetChecksum()
+
+	newGDP := getTestGDP(nil)
+	newGDP.Spec.MatchRules.ObjectTypes = []string{gslbalphav1.RouteObj}
+	changes := gf.UpdateFilter(gdp, newGDP)
+	if changes != gslbutils.FilterChangeMatchOptions {
+		t.Fatalf("expected only the match options to change with objectTypes, got %s", changes)
+	}
+	if gf.GetChecksum() == checksum {
+		t.Fatalf("expected the checksum to change with objectTypes")
+	}
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	if !filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route to be selected")
+	}
+	if filter.ApplyFilter(svc, Cluster1) {
+		t.Fatalf("expected the service to be rejected")
+	}
+	if reason != "object type not selected" {
+		t.Fatalf("unexpected reason: %s", reason)
+	}
+	fe := gf.Explain(gslbalphav1.LBSvcObj, Cluster1, TestNS, map[string]string{"key": "value"})
+	if fe.Accepted {
+		t.Fatalf("expected the explanation to reject the service")
+	}
+	found := false
+	for _, check := range fe.Checks {
+		if check.Name == gslbutils.FilterCheckObjectType {
+			found = true
+			if check.Passed || check.Expected != gslbalphav1.RouteObj {
+				t.Fatalf("unexpected object type check: %+v", check)
+			}
+		}
+	}
+	if !found {
+		t.Fatalf("expected an object type check in %+v", fe.Checks)
+	}
+
+	// the order of the types doesn't matter
+	reorderedGDP := getTestGDP(nil)
+	reorderedGDP.Spec.MatchRules.ObjectTypes = []string{gslbalphav1.LBSvcObj, gslbalphav1.RouteObj}
+	sortedGDP := getTestGDP(nil)
+	sortedGDP.Spec.MatchRules.ObjectTypes = []string{gslbalphav1.RouteObj, gslbalphav1.LBSvcObj}
+	gf.UpdateFilter(newGDP, reorderedGDP)
+	if changes := gf.UpdateFilter(reorderedGDP, sortedGDP); changes != 0 {
+		t.Fatalf("expected no changes for the reordered object types, got %s", changes)
+	}
+	if !filter.ApplyFilter(svc, Cluster1) {
+		t.Fatalf("expected the service to be selected")
+	}
+}
+
+func TestGlobalFilterValidate(t *testing.T) {
+	gf := getTestFilter([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}, {Cluster: Cluster2, Weight: 10}})
+	if err := gf.Validate(); err != nil {
+		t.Fatalf("expected a consistent filter, got %v", err)
+	}
+
+	// a traffic split cluster which isn't applicable, an app selector without a namespace selector
+	// and a traffic rule for a cluster which isn't applicable
+	gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 5}, {Cluster: "cluster4", Weight: 10}})
+	gdp.Spec.TrafficRules = []gslbalphav1.TrafficRule{{
+		AppSelector:  gslbalphav1.AppSelector{Label: map[string]string{"tier": "web"}},
+		TrafficSplit: []gslbalphav1.TrafficSplitElem{{Cluster: "cluster5", Weight: 1}},
+	}}
+	gf = gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(gdp)
+	var validationErr *gslbutils.FilterValidationError
+	if err := gf.Validate(); !errors.As(err, &validationErr) || len(validationErr.Violations) != 2 {
+		t.Fatalf("expected 2 violations for the traffic clusters, got %v", err)
+	}
+
+	// namespaces selected for a cluster which isn't applicable
+	nsGDP := getTestGDP(nil)
+	nsGDP.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"ns": "gslb"}
+	gf = gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(nsGDP)
+	gf.AddNSToNSFilter(Cluster1, TestNS)
+	if err := gf.Validate(); err != nil {
+		t.Fatalf("expected a consistent filter, got %v", err)
+	}
+	gf.AddNSToNSFilter("cluster4", TestNS)
+	if err := gf.Validate(); !errors.As(err, &validationErr) || len(validationErr.Violations) != 1 {
+		t.Fatalf("expected a violation for the namespaces of cluster4, got %v", err)
+	}
+
+	// an applied policy without any selectors
+	emptyGDP := getTestGDP(nil)
+	emptyGDP.Spec.MatchRules.AppSelector.Label = nil
+	gf = gslbutils.GetNewGlobalFilter()
+	gf.AddToFilter(emptyGDP)
+	if err := gf.Validate(); !errors.As(err, &validationErr) || len(validationErr.Violations) != 1 {
+		t.Fatalf("expected a violation for the missing selectors, got %v", err)
+	}
+
+	// the update is validated once applied
+	if changed, _ := gf.UpdateGlobalFilter(emptyGDP, getTestGDP(nil)); !changed {
+		t.Fatalf("expected the filter to change")
+	}
+	if err := gf.Validate(); err != nil {
+		t.Fatalf("expected a consistent filter after the update, got %v", err)
+	}
+	gf.DeleteFromGlobalFilter(emptyGDP)
+	if err := gf.Validate(); err != nil {
+		t.Fatalf("expected a consistent filter without a policy, got %v", err)
+	}
+}
+
+func TestSelfScopeFilter(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	defer gslbutils.ClearFilterObservers()
+
+	// a self scoped GDP object selects the objects in its namespace without any selectors
+	gdp := getTestGDP(nil)
+	gdp.Namespace = TestNS
+	gdp.Spec.MatchRules.AppSelector = gslbalphav1.AppSelector{}
+	gdp.Spec.MatchRules.SelfScope = true
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	if err := gf.Validate(); err != nil {
+		t.Fatalf("unexpected error in validating a self scoped filter: %v", err)
+	}
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	route := k8sobjects.RouteMeta{Cluster: Cluster1, Namespace: TestNS, Name: "route1", Hostname: "route1.avi.com"}
+	if !filter.ApplyFilter(route, Cluster1) || reason != "accepted because of selfScope" {
+		t.Fatalf("expected the route in the namespace of the GDP object to be selected, reason: %s", reason)
+	}
+	otherRoute := route
+	otherRoute.Namespace = "other"
+	if filter.ApplyFilter(otherRoute, Cluster1) {
+		t.Fatalf("expected the route in the other namespace to be rejected")
+	}
+	fe := gf.Explain(gslbalphav1.RouteObj, Cluster1, "other", nil)
+	if fe.Accepted || len(fe.Checks) == 0 {
+		t.Fatalf("expected the explanation to reject the route in the other namespace: %+v", fe)
+	}
+
+	// the app selector narrows down the objects in the namespace, and the self scope is a match option
+	checksum := gf.GetChecksum()
+	newGDP := gdp.DeepCopy()
+	newGDP.Spec.MatchRules.AppSelector = gslbalphav1.AppSelector{Label: map[string]string{"key": "value"}}
+	gf.UpdateFilter(gdp, newGDP)
+	if filter.ApplyFilter(route, Cluster1) {
+		t.Fatalf("expected the route without the app label to be rejected")
+	}
+	route.Labels = map[string]string{"key": "value"}
+	otherRoute.Labels = map[string]string{"key": "value"}
+	if !filter.ApplyFilter(route, Cluster1) || filter.ApplyFilter(otherRoute, Cluster1) {
+		t.Fatalf("expected only the route with the app label in the namespace of the GDP object to be selected")
+	}
+
+	unscopedGDP := newGDP.DeepCopy()
+	unscopedGDP.Spec.MatchRules.SelfScope = false
+	if changes := gf.UpdateFilter(newGDP, unscopedGDP); changes != gslbutils.FilterChangeMatchOptions {
+		t.Fatalf("expected only the match options to change with selfScope, got %s", changes)
+	}
+	if gf.GetChecksum() == checksum {
+		t.Fatalf("expected the checksum to change")
+	}
+	if !filter.ApplyFilter(otherRoute, Cluster1) {
+		t.Fatalf("expected the route in the other namespace to be selected without selfScope")
+	}
+}
+
+func TestFilterChecksumStability(t *testing.T) {
+	gf := getTestFilter([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster1, Weight: 6},
+		{Cluster: Cluster2, Weight: 2, Priority: 5},
+	})
+
+	// the order of the clusters, the traffic split and the ports doesn't matter
+	reordered := getTestGDP([]gslbalphav1.TrafficSplitElem{
+		{Cluster: Cluster2, Weight: 2, Priority: 5},
+		{Cluster: Cluster1, Weight: 6},
+	})
+	reordered.Spec.MatchClusters = []string{Cluster3, Cluster1, Cluster2}
+	reorderedFilter := gslbutils.GetNewGlobalFilter()
+	reorderedFilter.AddToFilter(reordered)
+	if gf.GetChecksum() != reorderedFilter.GetChecksum() {
+		t.Fatalf("expected the same checksum for the reordered GDP object, got %+v and %+v", gf.Checksums,
+			reorderedFilter.Checksums)
+	}
+
+	// any real change changes the checksum
+	changes := map[string]func(gdp *gslbalphav1.GlobalDeploymentPolicy){
+		"weight": func(gdp *gslbalphav1.GlobalDeploymentPolicy) { gdp.Spec.TrafficSplit[0].Weight = 7 },
+		"priority": func(gdp *gslbalphav1.GlobalDeploymentPolicy) {
+			gdp.Spec.TrafficSplit[1].Priority = 6
+		},
+		"cluster":      func(gdp *gslbalphav1.GlobalDeploymentPolicy) { gdp.Spec.MatchClusters = gdp.Spec.MatchClusters[:2] },
+		"appSelector":  func(gdp *gslbalphav1.GlobalDeploymentPolicy) { gdp.Spec.MatchRules.AppSelector.Label["key"] = "other" },
+		"requireReady": func(gdp *gslbalphav1.GlobalDeploymentPolicy) { gdp.Spec.MatchRules.RequireReady = true },
+		"namespaceSelector": func(gdp *gslbalphav1.GlobalDeploymentPolicy) {
+			gdp.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod"}
+		},
+	}
+	for name, change := range changes {
+		gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{
+			{Cluster: Cluster1, Weight: 6},
+			{Cluster: Cluster2, Weight: 2, Priority: 5},
+		})
+		change(gdp)
+		changedFilter := gslbutils.GetNewGlobalFilter()
+		changedFilter.AddToFilter(gdp)
+		if changedFilter.GetChecksum() == gf.GetChecksum() {
+			t.Fatalf("expected the checksum to change with the %s", name)
+		}
+	}
+
+	// cluster1 with weight 11 and cluster11 with weight 1 collided with the sum of the hashes of
+	// their concatenated fields
+	collidingGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: "cluster11", Weight: 1}})
+	collidingGDP.Spec.MatchClusters = []string{Cluster1, "cluster11"}
+	collidingFilter := gslbutils.GetNewGlobalFilter()
+	collidingFilter.AddToFilter(collidingGDP)
+	otherGDP := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster1, Weight: 11}})
+	otherGDP.Spec.MatchClusters = []string{Cluster1, "cluster11"}
+	otherFilter := gslbutils.GetNewGlobalFilter()
+	otherFilter.AddToFilter(otherGDP)
+	if collidingFilter.Checksums.Traffic == otherFilter.Checksums.Traffic {
+		t.Fatalf("expected the traffic checksums not to collide")
+	}
+}
+
+func TestChecksumBuilder(t *testing.T) {
+	var cb, reordered, regrouped, empty gslbutils.ChecksumBuilder
+	cb.Add("a", "bc")
+	cb.Add("d")
+	reordered.Add("d")
+	reordered.Add("a", "bc")
+	if cb.Sum() != reordered.Sum() {
+		t.Fatalf("expected the same checksum for the reordered components")
+	}
+	// the boundaries between the fields and the components are a part of the checksum
+	regrouped.Add("ab", "c")
+	regrouped.Add("d")
+	if cb.Sum() == regrouped.Sum() {
+		t.Fatalf("expected a different checksum for the regrouped fields")
+	}
+	var joined gslbutils.ChecksumBuilder
+	joined.Add("a", "bc", "d")
+	if cb.Sum() == joined.Sum() {
+		t.Fatalf("expected a different checksum for the joined components")
+	}
+	if empty.Sum() != 0 {
+		t.Fatalf("expected a zero checksum without any components")
+	}
+}
+
+func TestMinAgeFilterOption(t *testing.T) {
+	gf := getTestFilter(nil)
+	if minAge := gf.GetMinObjectAge(); minAge != 0 {
+		t.Fatalf("expected the min age to be disabled by default, got %v", minAge)
+	}
+
+	minAge := 30
+	newGDP := getTestGDP(nil)
+	newGDP.Spec.MatchRules.MinAge = &minAge
+	changes := gf.UpdateFilter(getTestGDP(nil), newGDP)
+	if !changes.Has(gslbutils.FilterChangeMatchOptions) || !changes.NeedsReevaluation() {
+		t.Fatalf("expected the min age to change the match options, got %s", changes)
+	}
+	if got := gf.GetMinObjectAge(); got != 30*time.Second {
+		t.Fatalf("expected the min age of the GDP object, got %v", got)
+	}
+	expectedFields := []gslbutils.FieldChange{{Field: gslbutils.FilterFieldMinAge, Old: "", New: "30"}}
+	if diff := getTestFilter(nil).Diff(gf); !reflect.DeepEqual(diff.FieldChanges, expectedFields) {
+		t.Fatalf("expected the field changes %v, got %v", expectedFields, diff.FieldChanges)
+	}
+}
+
+func TestFilterViewHandler(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	getView := func() gslbutils.FilterView {
+		req := httptest.NewRequest("GET", gslbutils.FilterViewPath, nil)
+		rec := httptest.NewRecorder()
+		gslbutils.FilterViewHandler(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rec.Code)
+		}
+		var view gslbutils.FilterView
+		if err := json.Unmarshal(rec.Body.Bytes(), &view); err != nil {
+			t.Fatalf("error in decoding the filter view: %v", err)
+		}
+		return view
+	}
+
+	// without a GDP object, the lists are empty and not null
+	view := getView()
+	if view.PolicyApplied || view.Clusters == nil || view.TrafficSplit == nil || view.AppSelector != nil {
+		t.Fatalf("unexpected view of an empty filter: %+v", view)
+	}
+
+	gdp := getTestGDP([]gslbalphav1.TrafficSplitElem{{Cluster: Cluster2, Weight: 2}, {Cluster: Cluster1, Weight: 6}})
+	gdp.Spec.MatchClusters = []string{Cluster2, Cluster1}
+	gdp.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"env": "prod"}
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	gf.NSFilter.AddNS(Cluster1, "ns2")
+	gf.NSFilter.AddNS(Cluster1, "ns1")
+
+	view = getView()
+	if !view.PolicyApplied || !reflect.DeepEqual(view.Clusters, []string{Cluster1, Cluster2}) {
+		t.Fatalf("unexpected clusters in the view: %+v", view)
+	}
+	if view.AppSelector == nil || view.AppSelector.Key != "key" || view.AppSelector.Value != "value" {
+		t.Fatalf("unexpected app selector in the view: %+v", view.AppSelector)
+	}
+	expectedSplit := []gslbutils.ClusterTrafficView{
+		{Cluster: Cluster1, Weight: 6, Priority: gslbutils.DefaultPriority},
+		{Cluster: Cluster2, Weight: 2, Priority: gslbutils.DefaultPriority},
+	}
+	if !reflect.DeepEqual(view.TrafficSplit, expectedSplit) {
+		t.Fatalf("expected the traffic split %+v, got %+v", expectedSplit, view.TrafficSplit)
+	}
+	nsView := view.NamespaceSelector
+	if nsView == nil || !reflect.DeepEqual(nsView.Labels, []gslbutils.LabelView{{Key: "env", Value: "prod"}}) {
+		t.Fatalf("unexpected namespace selector in the view: %+v", nsView)
+	}
+	if !reflect.DeepEqual(nsView.SelectedNamespaces[gf.NSFilter.SelectionKey(Cluster1)], []string{"ns1", "ns2"}) {
+		t.Fatalf("expected the selected namespaces ns1 and ns2, got %+v", nsView.SelectedNamespaces)
+	}
+	if view.Checksum != gf.GetChecksum() || view.Checksums.Traffic == 0 {
+		t.Fatalf("expected the checksums of the filter, got %d and %+v", view.Checksum, view.Checksums)
+	}
+}
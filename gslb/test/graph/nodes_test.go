@@ -16,17 +16,21 @@ package graph
 
 import (
 	"os"
+	"strconv"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
+	gslbingestion "github.com/avinetworks/amko/gslb/ingestion"
 	"github.com/avinetworks/amko/gslb/k8sobjects"
 	"github.com/avinetworks/amko/gslb/nodes"
 	"github.com/avinetworks/amko/gslb/test/ingestion"
+	gdpalphav1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
 
 	"github.com/onsi/gomega"
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 const (
@@ -59,6 +63,8 @@ func setupQueue(testCh <-chan struct{}) {
 func setUp() {
 	os.Setenv("INGRESS_API", "extensionv1")
 
+	// the GSLB members are removed right away, unless a test sets a grace period
+	gslbutils.SetDefaultMemberRemovalGracePeriod(0)
 	testStopCh = utils.SetupSignalHandler()
 	keyChan = make(chan string)
 
@@ -164,7 +170,12 @@ func GetSvcKey(op string, svc k8sobjects.SvcMeta) string {
 func verifyGsGraph(t *testing.T, metaObj k8sobjects.MetaObject, present bool, nMembers int, memberCheck bool) {
 	g := gomega.NewGomegaWithT(t)
 
-	modelName := utils.ADMIN_NS + "/" + nodes.DeriveGSLBServiceName(metaObj.GetHostname())
+	gsName, err := nodes.DeriveGSLBServiceName(metaObj.GetHostname())
+	if err != nil {
+		g.Expect(present).To(gomega.BeFalse())
+		return
+	}
+	modelName := utils.ADMIN_NS + "/" + gsName
 	ok, aviModelIntf := nodes.SharedAviGSGraphLister().Get(modelName)
 	if present == false {
 		g.Expect(ok).To(gomega.Equal(present))
@@ -172,7 +183,7 @@ func verifyGsGraph(t *testing.T, metaObj k8sobjects.MetaObject, present bool, nM
 	}
 	aviGsModel := aviModelIntf.(*nodes.AviGSObjectGraph)
 	g.Expect(aviGsModel.Tenant).To(gomega.Equal(utils.ADMIN_NS))
-	g.Expect(aviGsModel.Name).To(gomega.Equal(metaObj.GetHostname()))
+	g.Expect(aviGsModel.Name).To(gomega.Equal(gsName))
 	g.Expect(aviGsModel.MembersLen()).To(gomega.Equal(nMembers))
 
 	if !memberCheck || nMembers == 0 {
@@ -522,3 +533,1092 @@ func TestGSGraphsForMultiSvcUpdate(t *testing.T) {
 	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+updatedSvc2.Hostname, false)
 	verifyGsGraph(t, updatedSvc2, false, 0, false)
 }
+
+func TestGSGraphsForSvcPortUpdate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	prefix := "spu-"
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	svc1 := AddSvcMeta(t, prefix+"svc1", DefNS, prefix+"host1.avi.com", DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, utils.ADMIN_NS+"/"+svc1.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+
+	// only the port of the member and the health monitor are updated
+	svc1.Port = 8080
+	acceptedSvcStore.AddOrUpdate(svc1, svc1.Cluster, svc1.Namespace, svc1.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectPortUpdate, svc1))
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+svc1.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	verifyGsGraph(t, svc1, true, 1, true)
+	_, aviGS := nodes.SharedAviGSGraphLister().Get(utils.ADMIN_NS + "/" + svc1.Hostname)
+	gsGraph := aviGS.(*nodes.AviGSObjectGraph)
+	g.Expect(gsGraph.MemberObjs[0].Port).To(gomega.Equal(int32(8080)))
+	g.Expect(gsGraph.Hm.Port).To(gomega.Equal(int32(8080)))
+
+	// a service without a GS graph is added as usual
+	svc2 := k8sobjects.SvcMeta{Name: prefix + "svc2", Namespace: DefNS, Hostname: prefix + "host2.avi.com",
+		IPAddr: "10.10.10.20", Cluster: BarCluster, Port: 443, Protocol: "TCP"}
+	acceptedSvcStore.AddOrUpdate(svc2, svc2.Cluster, svc2.Namespace, svc2.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectPortUpdate, svc2))
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+svc2.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	verifyGsGraph(t, svc2, true, 1, true)
+
+	for _, svc := range []k8sobjects.SvcMeta{svc1, svc2} {
+		acceptedSvcStore.DeleteClusterNSObj(svc.Cluster, svc.Namespace, svc.Name)
+		addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, svc))
+		waitAndVerify(t, utils.ADMIN_NS+"/"+svc.Hostname, false)
+		verifyGsGraph(t, svc, false, 0, false)
+	}
+}
+
+func AddPassthroughRouteMeta(t *testing.T, name, ns, host, ip, cname string, sniHosts []string,
+	create bool) k8sobjects.RouteMeta {
+	acceptedRouteStore := gslbutils.GetAcceptedRouteStore()
+	op := gslbutils.ObjectAdd
+	if !create {
+		op = gslbutils.ObjectUpdate
+	}
+	key := ingestion.GetRouteKey(op, cname, ns, name)
+	routeMeta := k8sobjects.RouteMeta{
+		Name:        name,
+		Namespace:   ns,
+		Hostname:    host,
+		IPAddr:      ip,
+		Cluster:     cname,
+		Port:        gslbutils.DefaultHTTPSHealthMonitorPort,
+		Protocol:    gslbutils.ProtocolTCP,
+		Passthrough: true,
+		SNIHosts:    sniHosts,
+		Labels:      map[string]string{"key": "value"},
+	}
+	acceptedRouteStore.AddOrUpdate(routeMeta, cname, ns, name)
+	addKeyToIngestionQueue(ns, key)
+	return routeMeta
+}
+
+// waitAndVerifyKeys waits for all the keys in keyList to be published to the graph layer, in
+// any order.
+func waitAndVerifyKeys(t *testing.T, keyList []string) {
+	pending := make(map[string]bool)
+	for _, key := range keyList {
+		pending[key] = true
+	}
+	for len(pending) > 0 {
+		select {
+		case data := <-keyChan:
+			t.Logf("got data: %s\n", data)
+			if !pending[data] {
+				t.Fatalf("unexpected key: %s", data)
+			}
+			delete(pending, data)
+		case <-time.After(10 * time.Second):
+			t.Fatalf("timed out waiting for keys: %v", pending)
+		}
+	}
+}
+
+func TestGSGraphsForPassthroughRouteSNIHosts(t *testing.T) {
+	prefix := "sni-"
+	acceptedRouteStore := gslbutils.GetAcceptedRouteStore()
+	hostname := prefix + "host.avi.com"
+	sniHost1 := prefix + "sni1.avi.com"
+	sniHost2 := prefix + "sni2.avi.com"
+	routeName := prefix + "foo-route"
+	// the deleted GSs are published to the rest layer only by the leader
+	gslbutils.SetControllerAsLeader()
+	defer gslbutils.SetControllerAsFollower()
+	// the SNI hosts get a GSLB service only if accepted by the global filter
+	gdp := &gdpalphav1.GlobalDeploymentPolicy{}
+	gdp.Spec.MatchClusters = []string{FooCluster}
+	gdp.Spec.MatchRules.AppSelector.Label = map[string]string{"key": "value"}
+	gslbutils.GetGlobalFilter().AddToFilter(gdp)
+	defer gslbutils.ResetGlobalFilter()
+
+	route := AddPassthroughRouteMeta(t, routeName, DefNS, hostname, "10.10.10.10", FooCluster,
+		[]string{sniHost1, sniHost2}, true)
+	waitAndVerifyKeys(t, []string{utils.ADMIN_NS + "/" + hostname, utils.ADMIN_NS + "/" + sniHost1,
+		utils.ADMIN_NS + "/" + sniHost2})
+	verifyGsGraph(t, route, true, 1, true)
+	sniMetas := route.GetSNIHostMetas()
+	for _, sniMeta := range sniMetas {
+		verifyGsGraph(t, sniMeta, true, 1, true)
+	}
+
+	// remove one of the SNI hosts, its GS should be removed
+	updatedRoute := AddPassthroughRouteMeta(t, routeName, DefNS, hostname, "10.10.10.10", FooCluster,
+		[]string{sniHost1}, false)
+	waitAndVerifyKeys(t, []string{utils.ADMIN_NS + "/" + sniHost2})
+	verifyGsGraph(t, updatedRoute, true, 1, true)
+	verifyGsGraph(t, sniMetas[0], true, 1, true)
+	verifyGsGraph(t, sniMetas[1], false, 0, false)
+
+	// delete the route, all the GSs should be removed
+	acceptedRouteStore.DeleteClusterNSObj(FooCluster, DefNS, routeName)
+	addKeyToIngestionQueue(DefNS, ingestion.GetRouteKey(gslbutils.ObjectDelete, FooCluster, DefNS, routeName))
+	waitAndVerifyKeys(t, []string{utils.ADMIN_NS + "/" + hostname, utils.ADMIN_NS + "/" + sniHost1})
+	verifyGsGraph(t, updatedRoute, false, 0, false)
+	verifyGsGraph(t, sniMetas[0], false, 0, false)
+}
+
+func getTestHostOverride(name, fqdn string, ttl int, hmRefs []string, algorithm string) *gdpalphav1.HostOverride {
+	return &gdpalphav1.HostOverride{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: gslbutils.AVISystem,
+		},
+		Spec: gdpalphav1.HostOverrideSpec{
+			Fqdn:              fqdn,
+			TTL:               &ttl,
+			HealthMonitorRefs: hmRefs,
+			PoolAlgorithm:     algorithm,
+		},
+	}
+}
+
+func TestHostOverrideValidation(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	_, err := gslbutils.GetHostOverrideFromObj(getTestHostOverride("ho", "", 10, nil, ""))
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = gslbutils.GetHostOverrideFromObj(getTestHostOverride("ho", "ho.avi.com", -1, nil, ""))
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = gslbutils.GetHostOverrideFromObj(getTestHostOverride("ho", "ho.avi.com", 10, nil, "GSLB_ALGORITHM_RANDOM"))
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	ho, err := gslbutils.GetHostOverrideFromObj(getTestHostOverride("ho", "ho.avi.com", 10, []string{"hm1"},
+		"GSLB_ALGORITHM_GEO"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(gslbutils.AddOrUpdateHostOverride(ho)).To(gomega.Succeed())
+	defer gslbutils.DeleteHostOverride(ho.Fqdn, ho.Namespace, ho.Name)
+
+	// only one HostOverride object is allowed per fqdn
+	another, _ := gslbutils.GetHostOverrideFromObj(getTestHostOverride("ho2", "ho.avi.com", 20, nil, ""))
+	g.Expect(gslbutils.AddOrUpdateHostOverride(another)).NotTo(gomega.Succeed())
+	g.Expect(gslbutils.DeleteHostOverride(another.Fqdn, another.Namespace, another.Name)).To(gomega.Equal(false))
+
+	fetched, ok := gslbutils.GetHostOverride("ho.avi.com")
+	g.Expect(ok).To(gomega.Equal(true))
+	g.Expect(*fetched.TTL).To(gomega.Equal(int32(10)))
+	// the fetched HostOverride is a copy
+	fetched.HealthMonitorRefs[0] = "changed"
+	fetched, _ = gslbutils.GetHostOverride("ho.avi.com")
+	g.Expect(fetched.HealthMonitorRefs).To(gomega.Equal([]string{"hm1"}))
+}
+
+func TestPendingHostOverride(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	fqdn := "pho-host1.avi.com"
+	winner := getTestHostOverride("pho-obj1", fqdn, 10, nil, "")
+	loser := getTestHostOverride("pho-obj2", fqdn, 20, nil, "")
+	gslbingestion.AddHostOverrideObj(winner)
+	gslbingestion.AddHostOverrideObj(loser)
+	defer gslbingestion.DeleteHostOverrideObj(loser)
+	fetched, ok := gslbutils.GetHostOverride(fqdn)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(fetched.Name).To(gomega.Equal(winner.Name))
+
+	// the HostOverride object which lost the conflict is applied once the winner is deleted
+	gslbingestion.DeleteHostOverrideObj(winner)
+	fetched, ok = gslbutils.GetHostOverride(fqdn)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(fetched.Name).To(gomega.Equal(loser.Name))
+	g.Expect(*fetched.TTL).To(gomega.Equal(int32(20)))
+
+	// a deleted object isn't applied again
+	gslbingestion.AddHostOverrideObj(winner)
+	gslbingestion.DeleteHostOverrideObj(winner)
+	gslbingestion.DeleteHostOverrideObj(loser)
+	_, ok = gslbutils.GetHostOverride(fqdn)
+	g.Expect(ok).To(gomega.BeFalse())
+}
+
+func TestGSGraphsForHostOverride(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	prefix := "ho-"
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	hostname := prefix + "host1.avi.com"
+	svcName := prefix + "foo-svc"
+	modelName := utils.ADMIN_NS + "/" + hostname
+
+	svc := AddSvcMeta(t, svcName, DefNS, hostname, DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, modelName, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	verifyGsGraph(t, svc, true, 1, true)
+	getGraph := func() *nodes.AviGSObjectGraph {
+		_, aviGS := nodes.SharedAviGSGraphLister().Get(modelName)
+		return aviGS.(*nodes.AviGSObjectGraph)
+	}
+	g.Expect(getGraph().TTL).To(gomega.BeNil())
+	g.Expect(getGraph().PoolAlgorithm).To(gomega.Equal(gslbutils.DefaultPoolAlgorithm))
+	prevChecksum := getGraph().GetChecksum()
+
+	// add a HostOverride for the hostname, the GS graph should be updated and published
+	ho, err := gslbutils.GetHostOverrideFromObj(getTestHostOverride("ho-obj", hostname, 30, []string{"custom-hm"},
+		"GSLB_ALGORITHM_GEO"))
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(gslbutils.AddOrUpdateHostOverride(ho)).To(gomega.Succeed())
+	nodes.ApplyHostOverride(hostname)
+	ok, msg = waitAndVerify(t, modelName, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	g.Expect(*getGraph().TTL).To(gomega.Equal(int32(30)))
+	g.Expect(getGraph().PoolAlgorithm).To(gomega.Equal("GSLB_ALGORITHM_GEO"))
+	g.Expect(getGraph().HmRefs).To(gomega.Equal([]string{"custom-hm"}))
+	g.Expect(getGraph().GetChecksum()).NotTo(gomega.Equal(prevChecksum))
+
+	// the GS graph must retain the HostOverride on a member update
+	AddSvcMeta(t, svcName, DefNS, hostname, DefSvc, "10.10.10.11", FooCluster, false)
+	ok, msg = waitAndVerify(t, modelName, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	g.Expect(*getGraph().TTL).To(gomega.Equal(int32(30)))
+
+	// delete the HostOverride, the GS graph should get the default values
+	g.Expect(gslbutils.DeleteHostOverride(hostname, ho.Namespace, ho.Name)).To(gomega.Equal(true))
+	nodes.ApplyHostOverride(hostname)
+	ok, msg = waitAndVerify(t, modelName, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	g.Expect(getGraph().TTL).To(gomega.BeNil())
+	g.Expect(getGraph().HmRefs).To(gomega.BeNil())
+	g.Expect(getGraph().PoolAlgorithm).To(gomega.Equal(gslbutils.DefaultPoolAlgorithm))
+
+	acceptedSvcStore.DeleteClusterNSObj(FooCluster, DefNS, svcName)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, svc))
+	waitAndVerify(t, modelName, false)
+}
+
+func TestGSGraphsForHostAliases(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	prefix := "ha-"
+	// the deleted GSs are published to the rest layer only by the leader
+	gslbutils.SetControllerAsLeader()
+	defer gslbutils.SetControllerAsFollower()
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	hostname := prefix + "host1.avi.com"
+	svcName := prefix + "foo-svc"
+	modelName := utils.ADMIN_NS + "/" + hostname
+
+	hoObj := getTestHostOverride("ha-obj", hostname, 30, nil, "")
+	hoObj.Spec.Aliases = []string{""}
+	_, err := gslbutils.GetHostOverrideFromObj(hoObj)
+	g.Expect(err).To(gomega.HaveOccurred())
+	hoObj.Spec.Aliases = []string{hostname}
+	_, err = gslbutils.GetHostOverrideFromObj(hoObj)
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// the aliases are de-duplicated and sorted
+	hoObj.Spec.Aliases = []string{prefix + "zz.avi.com", prefix + "aa.avi.com", prefix + "zz.avi.com"}
+	ho, err := gslbutils.GetHostOverrideFromObj(hoObj)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	aliases := []string{prefix + "aa.avi.com", prefix + "zz.avi.com"}
+	g.Expect(ho.Aliases).To(gomega.Equal(aliases))
+	_, found := gslbutils.GetAliasedFqdn(aliases[0])
+	g.Expect(found).To(gomega.BeFalse())
+
+	svc := AddSvcMeta(t, svcName, DefNS, hostname, DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, modelName, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	getGraph := func() *nodes.AviGSObjectGraph {
+		_, aviGS := nodes.SharedAviGSGraphLister().Get(modelName)
+		return aviGS.(*nodes.AviGSObjectGraph)
+	}
+	prevChecksum := getGraph().GetChecksum()
+
+	// the aliases are added as the domain names of the GS, after the hostname
+	g.Expect(gslbutils.AddOrUpdateHostOverride(ho)).To(gomega.Succeed())
+	fqdn, _ := gslbutils.GetAliasedFqdn(aliases[0])
+	g.Expect(fqdn).To(gomega.Equal(hostname))
+	nodes.ApplyHostOverride(hostname)
+	ok, msg = waitAndVerify(t, modelName, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	g.Expect(getGraph().GetChecksum()).NotTo(gomega.Equal(prevChecksum))
+	g.Expect(getGraph().DomainNames).To(gomega.Equal(append([]string{hostname}, aliases...)))
+	// no separate GSs are created for the aliases
+	found, _ = nodes.SharedAviGSGraphLister().Get(utils.ADMIN_NS + "/" + aliases[0])
+	g.Expect(found).To(gomega.BeFalse())
+
+	// an alias can't be the fqdn or an alias of another HostOverride
+	conflictObj := getTestHostOverride("ha-obj2", prefix+"host2.avi.com", 30, nil, "")
+	conflictObj.Spec.Aliases = []string{aliases[1]}
+	conflict, err := gslbutils.GetHostOverrideFromObj(conflictObj)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(gslbutils.AddOrUpdateHostOverride(conflict)).NotTo(gomega.Succeed())
+	conflictObj.Spec.Aliases = []string{hostname}
+	conflict, _ = gslbutils.GetHostOverrideFromObj(conflictObj)
+	g.Expect(gslbutils.AddOrUpdateHostOverride(conflict)).NotTo(gomega.Succeed())
+
+	// the GS graph must retain the aliases on a member update
+	AddSvcMeta(t, svcName, DefNS, hostname, DefSvc, "10.10.10.11", FooCluster, false)
+	ok, msg = waitAndVerify(t, modelName, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	g.Expect(getGraph().DomainNames).To(gomega.Equal(append([]string{hostname}, aliases...)))
+
+	// an object with the hostname of an alias gets a GS of its own, the alias is removed from the GS
+	// of the hostname till the object is deleted
+	aliasSvc := AddSvcMeta(t, prefix+"alias-svc", DefNS, aliases[0], DefSvc, "10.10.10.12", FooCluster, true)
+	waitAndVerifyKeys(t, []string{modelName, utils.ADMIN_NS + "/" + aliases[0]})
+	g.Expect(getGraph().DomainNames).To(gomega.Equal([]string{hostname, aliases[1]}))
+	// and a HostOverride with such an alias is rejected
+	aliasObj := getTestHostOverride("ha-obj3", prefix+"host3.avi.com", 30, nil, "")
+	aliasObj.Spec.Aliases = []string{aliases[0]}
+	gslbingestion.AddHostOverrideObj(aliasObj)
+	_, found = gslbutils.GetHostOverride(prefix + "host3.avi.com")
+	g.Expect(found).To(gomega.BeFalse())
+	acceptedSvcStore.DeleteClusterNSObj(FooCluster, DefNS, aliasSvc.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, aliasSvc))
+	waitAndVerifyKeys(t, []string{modelName, utils.ADMIN_NS + "/" + aliases[0]})
+	g.Expect(getGraph().DomainNames).To(gomega.Equal(append([]string{hostname}, aliases...)))
+
+	// delete the HostOverride, the aliases are removed from the GS
+	g.Expect(gslbutils.DeleteHostOverride(hostname, ho.Namespace, ho.Name)).To(gomega.Equal(true))
+	nodes.ApplyHostOverride(hostname)
+	ok, msg = waitAndVerify(t, modelName, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	g.Expect(getGraph().DomainNames).To(gomega.Equal([]string{hostname}))
+
+	acceptedSvcStore.DeleteClusterNSObj(FooCluster, DefNS, svcName)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, svc))
+	waitAndVerify(t, modelName, false)
+}
+
+func TestGSGraphsForHostnameConflict(t *testing.T) {
+	prefix := "hc-"
+	hostname := prefix + "host1.avi.com"
+	acceptedIngStore := gslbutils.GetAcceptedIngressStore()
+	// the second ingress has the smaller name, so it is kept even though it is added later
+	ihm1 := AddIngressMeta(t, prefix+"z-ing", DefNS, hostname, FooCluster+"-"+DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, utils.ADMIN_NS+"/"+hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	ihm2 := AddIngressMeta(t, prefix+"a-ing", DefNS, hostname, FooCluster+"-"+DefSvc, "10.10.10.20", FooCluster, true)
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	verifyGsGraph(t, ihm2, true, 1, true)
+
+	// an update to the ingress with the bigger name must not change the member
+	ihm1 = AddIngressMeta(t, prefix+"z-ing", DefNS, hostname, FooCluster+"-"+DefSvc, "10.10.10.11", FooCluster, false)
+	waitAndVerify(t, utils.ADMIN_NS+"/"+hostname, true)
+	verifyGsGraph(t, ihm2, true, 1, true)
+
+	// an ingress with the same address is not a conflict
+	ihm3 := AddIngressMeta(t, prefix+"b-ing", DefNS, hostname, FooCluster+"-"+DefSvc, "10.10.10.20", FooCluster, true)
+	waitAndVerify(t, utils.ADMIN_NS+"/"+hostname, false)
+	verifyGsGraph(t, ihm3, true, 2, true)
+
+	// once the kept ingresses are deleted, the other ingress becomes the member
+	for _, ihm := range []k8sobjects.IngressHostMeta{ihm2, ihm3} {
+		acceptedIngStore.DeleteClusterNSObj(ihm.Cluster, ihm.Namespace, ihm.ObjName)
+		addKeyToIngestionQueue(DefNS, GetIhmKey(gslbutils.ObjectDelete, ihm))
+		waitAndVerify(t, utils.ADMIN_NS+"/"+hostname, false)
+	}
+	verifyGsGraph(t, ihm1, true, 1, true)
+
+	acceptedIngStore.DeleteClusterNSObj(ihm1.Cluster, ihm1.Namespace, ihm1.ObjName)
+	addKeyToIngestionQueue(DefNS, GetIhmKey(gslbutils.ObjectDelete, ihm1))
+	waitAndVerify(t, utils.ADMIN_NS+"/"+hostname, false)
+	verifyGsGraph(t, ihm1, false, 0, false)
+}
+
+// drainKeyChan receives the keys published to the graph layer for the duration d, so that the graph
+// layer isn't blocked.
+func drainKeyChan(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	for {
+		select {
+		case <-keyChan:
+		case <-timer.C:
+			return
+		}
+	}
+}
+
+func TestMemberRemovalGracePeriod(t *testing.T) {
+	prefix := "grace-"
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	hostname := prefix + "host1.avi.com"
+	if err := gslbutils.SetDefaultMemberRemovalGracePeriod(2); err != nil {
+		t.Fatalf("error in setting the grace period: %v", err)
+	}
+	defer gslbutils.SetDefaultMemberRemovalGracePeriod(0)
+
+	svc := AddSvcMeta(t, prefix+"svc1", DefNS, hostname, DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, utils.ADMIN_NS+"/"+svc.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+
+	// the member is retained within the grace period, and removed after it
+	acceptedSvcStore.DeleteClusterNSObj(svc.Cluster, svc.Namespace, svc.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, svc))
+	drainKeyChan(500 * time.Millisecond)
+	verifyGsGraph(t, svc, true, 1, true)
+	drainKeyChan(3 * time.Second)
+	verifyGsGraph(t, svc, false, 0, false)
+
+	// the deletion is cancelled if the object reappears within the grace period
+	svc = AddSvcMeta(t, prefix+"svc1", DefNS, hostname, DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+svc.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	acceptedSvcStore.DeleteClusterNSObj(svc.Cluster, svc.Namespace, svc.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, svc))
+	drainKeyChan(500 * time.Millisecond)
+	svc = AddSvcMeta(t, prefix+"svc1", DefNS, hostname, DefSvc, "10.10.10.10", FooCluster, true)
+	drainKeyChan(3 * time.Second)
+	verifyGsGraph(t, svc, true, 1, true)
+
+	gslbutils.SetDefaultMemberRemovalGracePeriod(0)
+	acceptedSvcStore.DeleteClusterNSObj(svc.Cluster, svc.Namespace, svc.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, svc))
+	drainKeyChan(500 * time.Millisecond)
+	verifyGsGraph(t, svc, false, 0, false)
+}
+
+func TestMinObjectAge(t *testing.T) {
+	prefix := "minage-"
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	if err := gslbutils.SetDefaultMinObjectAge(-1); err == nil {
+		t.Fatalf("expected an error for a negative min age")
+	}
+	if err := gslbutils.SetDefaultMinObjectAge(2); err != nil {
+		t.Fatalf("error in setting the min age: %v", err)
+	}
+	defer gslbutils.SetDefaultMinObjectAge(0)
+
+	// the objects whose creation time isn't known are added right away
+	oldSvc := AddSvcMeta(t, prefix+"svc1", DefNS, prefix+"host1.avi.com", DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, utils.ADMIN_NS+"/"+oldSvc.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+
+	// a new object is deferred till it is old enough, it isn't rejected
+	newSvc := k8sobjects.SvcMeta{Name: prefix + "svc2", Namespace: DefNS, Hostname: prefix + "host2.avi.com",
+		IPAddr: "10.10.10.11", Cluster: FooCluster, Port: 80, Protocol: "TCP", CreationTime: time.Now()}
+	acceptedSvcStore.AddOrUpdate(newSvc, newSvc.Cluster, newSvc.Namespace, newSvc.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectAdd, newSvc))
+	drainKeyChan(500 * time.Millisecond)
+	verifyGsGraph(t, newSvc, false, 0, false)
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+newSvc.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	verifyGsGraph(t, newSvc, true, 1, true)
+
+	gslbutils.SetDefaultMinObjectAge(0)
+	for _, svc := range []k8sobjects.SvcMeta{oldSvc, newSvc} {
+		acceptedSvcStore.DeleteClusterNSObj(svc.Cluster, svc.Namespace, svc.Name)
+		addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, svc))
+	}
+	drainKeyChan(500 * time.Millisecond)
+	verifyGsGraph(t, newSvc, false, 0, false)
+}
+
+func TestStaleObjectsGC(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	prefix := "gc-"
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	g.Expect(nodes.SetStaleObjectsGCInterval(10)).To(gomega.HaveOccurred())
+	g.Expect(nodes.GetStaleObjectsGCInterval()).To(gomega.Equal(nodes.DefaultStaleObjectsGCInterval))
+
+	staleSvc := AddSvcMeta(t, prefix+"svc1", DefNS, prefix+"host1.avi.com", DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, utils.ADMIN_NS+"/"+staleSvc.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	liveSvc := AddSvcMeta(t, prefix+"svc2", DefNS, prefix+"host2.avi.com", DefSvc, "10.10.10.11", FooCluster, true)
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+liveSvc.Hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+
+	// the delete event of the service is missed
+	acceptedSvcStore.DeleteClusterNSObj(staleSvc.Cluster, staleSvc.Namespace, staleSvc.Name)
+	staleClusterObj := gslbutils.GetClusterKey(staleSvc.Cluster, staleSvc.Namespace, staleSvc.Name)
+
+	t.Log("the first sweep only marks the stale object")
+	g.Expect(nodes.SweepStaleObjects()).To(gomega.Equal(0))
+	drainKeyChan(500 * time.Millisecond)
+	verifyGsGraph(t, staleSvc, true, 1, true)
+
+	t.Log("the second sweep reclaims the stale object")
+	g.Expect(nodes.SweepStaleObjects()).To(gomega.BeNumerically(">=", 1))
+	drainKeyChan(500 * time.Millisecond)
+	verifyGsGraph(t, staleSvc, false, 0, false)
+	g.Expect(k8sobjects.SvcMeta{}.GetHostnameFromHostMap(staleClusterObj)).To(gomega.Equal(""))
+	verifyGsGraph(t, liveSvc, true, 1, true)
+
+	// an object in the host map which is back in the store isn't reclaimed
+	acceptedSvcStore.DeleteClusterNSObj(liveSvc.Cluster, liveSvc.Namespace, liveSvc.Name)
+	nodes.SweepStaleObjects()
+	acceptedSvcStore.AddOrUpdate(liveSvc, liveSvc.Cluster, liveSvc.Namespace, liveSvc.Name)
+	nodes.SweepStaleObjects()
+	drainKeyChan(500 * time.Millisecond)
+	verifyGsGraph(t, liveSvc, true, 1, true)
+
+	acceptedSvcStore.DeleteClusterNSObj(liveSvc.Cluster, liveSvc.Namespace, liveSvc.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, liveSvc))
+	drainKeyChan(500 * time.Millisecond)
+	verifyGsGraph(t, liveSvc, false, 0, false)
+}
+
+func TestGSGraphHmServerName(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	host := "sni.avi.com"
+	getTLSRouteMeta := func(cname, sniHost string) k8sobjects.RouteMeta {
+		return k8sobjects.RouteMeta{Cluster: cname, Namespace: DefNS, Name: "route1", Hostname: host,
+			IPAddr: "10.10.10.10", Paths: []string{"/"}, TLS: true, HmSNIHost: sniHost}
+	}
+
+	gsGraph := nodes.NewAviGSObjectGraph()
+	gsGraph.ConstructAviGSGraph(host, "test", getTLSRouteMeta(FooCluster, "z.avi.com"), 1, 0)
+	g.Expect(gsGraph.Hm.Protocol).To(gomega.Equal(gslbutils.SystemGslbHealthMonitorHTTPS))
+	g.Expect(gsGraph.Hm.ServerName).To(gomega.Equal("z.avi.com"))
+
+	hmChecksum := gsGraph.GetHmChecksum()
+
+	// the smallest SNI host of the members is sent, irrespective of the order of the members
+	barMeta := getTLSRouteMeta(BarCluster, "")
+	barMeta.IPAddr = "10.10.10.11"
+	gsGraph.UpdateGSMember(barMeta, 1, 0)
+	g.Expect(gsGraph.Hm.ServerName).To(gomega.Equal(host))
+	// a change of the SNI host alone must update the health monitor
+	g.Expect(gsGraph.GetHmChecksum()).NotTo(gomega.Equal(hmChecksum))
+	gsGraph.DeleteMember(BarCluster, DefNS, "route1", gslbutils.RouteType)
+	g.Expect(gsGraph.Hm.ServerName).To(gomega.Equal("z.avi.com"))
+	g.Expect(gsGraph.GetHmChecksum()).To(gomega.Equal(hmChecksum))
+}
+
+func TestGSGraphIngressLBHostname(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	host := "lb-hostname.avi.com"
+	ihm := k8sobjects.IngressHostMeta{Cluster: FooCluster, Namespace: DefNS, IngName: "ing1",
+		ObjName: "ing1/" + host, Hostname: host, LBHostname: "lb.example.com", Paths: []string{"/"}}
+
+	// the member of an ingress host whose load balancer only exposes a hostname is added via it
+	gsGraph := nodes.NewAviGSObjectGraph()
+	gsGraph.ConstructAviGSGraph(host, "test", ihm, 1, 0)
+	g.Expect(gsGraph.MemberObjs).To(gomega.HaveLen(1))
+	g.Expect(gsGraph.MemberObjs[0].IPAddr).To(gomega.Equal(""))
+	g.Expect(gsGraph.MemberObjs[0].Fqdn).To(gomega.Equal("lb.example.com"))
+}
+
+func TestGSGraphHostOnlyMembers(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	host := "host-only.avi.com"
+	gf := gslbutils.GetGlobalFilter()
+	gf.GlobalLock.Lock()
+	gf.HostOnlyObjectTypes = []string{gslbutils.RouteType}
+	gf.GlobalLock.Unlock()
+	defer func() {
+		gf.GlobalLock.Lock()
+		gf.HostOnlyObjectTypes = []string{}
+		gf.GlobalLock.Unlock()
+	}()
+
+	// a route without a path is advertised with the host only, and gets a non-path health monitor
+	fooMeta := k8sobjects.RouteMeta{Cluster: FooCluster, Namespace: DefNS, Name: "route1", Hostname: host,
+		IPAddr: "10.10.10.10", Paths: []string{"/"}, DefaultPath: true}
+	gsGraph := nodes.NewAviGSObjectGraph()
+	gsGraph.ConstructAviGSGraph(host, "test", fooMeta, 1, 0)
+	g.Expect(gsGraph.GetHmPathNamesList()).To(gomega.BeEmpty())
+	g.Expect(gsGraph.Hm.Name).To(gomega.Equal(gslbutils.BuildNonPathHmName(host)))
+	g.Expect(gsGraph.Hm.Protocol).To(gomega.Equal(gslbutils.SystemHealthMonitorTypeTCP))
+	g.Expect(gsGraph.Hm.Port).To(gomega.Equal(int32(gslbutils.DefaultHTTPHealthMonitorPort)))
+
+	// a member with a path brings back the path based health monitors
+	barMeta := k8sobjects.RouteMeta{Cluster: BarCluster, Namespace: DefNS, Name: "route1", Hostname: host,
+		IPAddr: "10.10.10.11", Paths: []string{"/foo"}}
+	gsGraph.UpdateGSMember(barMeta, 1, 0)
+	g.Expect(gsGraph.GetHmPathNamesList()).To(gomega.Equal([]string{gslbutils.BuildHmPathName(host, "/foo", false)}))
+	g.Expect(gsGraph.Hm.Name).To(gomega.BeEmpty())
+	g.Expect(gsGraph.Hm.Protocol).To(gomega.Equal(gslbutils.SystemGslbHealthMonitorHTTP))
+
+	gsGraph.DeleteMember(BarCluster, DefNS, "route1", gslbutils.RouteType)
+	g.Expect(gsGraph.GetHmPathNamesList()).To(gomega.BeEmpty())
+	g.Expect(gsGraph.Hm.Name).To(gomega.Equal(gslbutils.BuildNonPathHmName(host)))
+}
+
+func TestGSGraphsForClusterTenant(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	prefix := "ct-"
+	hostname := prefix + "host1.avi.com"
+	tenant := "team-a"
+	gslbutils.SetClusterTenant(FooCluster, tenant)
+	defer gslbutils.SetClusterTenant(FooCluster, "")
+	getGSGraph := func(tenant string) (*nodes.AviGSObjectGraph, bool) {
+		found, aviGS := nodes.SharedAviGSGraphLister().Get(tenant + "/" + hostname)
+		if !found || aviGS == nil {
+			return nil, false
+		}
+		return aviGS.(*nodes.AviGSObjectGraph), true
+	}
+
+	// the GS is in the tenant of its only member cluster
+	fooSvc := AddSvcMeta(t, prefix+"foo-svc1", DefNS, hostname, DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, tenant+"/"+hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	gsGraph, found := getGSGraph(tenant)
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(gsGraph.Tenant).To(gomega.Equal(tenant))
+
+	// the GS moves to the tenant of the first member cluster, with the members of both the clusters
+	barSvc := AddSvcMeta(t, prefix+"bar-svc1", DefNS, hostname, DefSvc, "10.10.10.20", BarCluster, true)
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+hostname, false)
+	if !ok {
+		t.Fatalf("%s", msg)
+	}
+	_, found = getGSGraph(tenant)
+	g.Expect(found).To(gomega.BeFalse())
+	found, _ = nodes.SharedDeleteGSGraphLister().Get(tenant + "/" + hostname)
+	g.Expect(found).To(gomega.BeTrue())
+	gsGraph, found = getGSGraph(utils.ADMIN_NS)
+	g.Expect(found).To(gomega.BeTrue())
+	g.Expect(gsGraph.Tenant).To(gomega.Equal(utils.ADMIN_NS))
+	g.Expect(gsGraph.MembersLen()).To(gomega.Equal(2))
+
+	// the GS moves back once the first member cluster has no members
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	acceptedSvcStore.DeleteClusterNSObj(barSvc.Cluster, barSvc.Namespace, barSvc.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, barSvc))
+	g.Eventually(func() bool {
+		_, found := getGSGraph(tenant)
+		return found
+	}, 5*time.Second).Should(gomega.BeTrue())
+	_, found = getGSGraph(utils.ADMIN_NS)
+	g.Expect(found).To(gomega.BeFalse())
+	gsGraph, _ = getGSGraph(tenant)
+	g.Expect(gsGraph.Tenant).To(gomega.Equal(tenant))
+	g.Expect(gsGraph.MembersLen()).To(gomega.Equal(1))
+
+	acceptedSvcStore.DeleteClusterNSObj(fooSvc.Cluster, fooSvc.Namespace, fooSvc.Name)
+	addKeyToIngestionQueue(DefNS, GetSvcKey(gslbutils.ObjectDelete, fooSvc))
+	g.Eventually(func() bool {
+		_, found := getGSGraph(tenant)
+		return found
+	}, 5*time.Second).Should(gomega.BeFalse())
+	drainKeyChan(500 * time.Millisecond)
+}
+
+func TestValidateClusterTenants(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	gslbutils.SetClusterTenant(FooCluster, "team-a")
+	gslbutils.SetClusterTenant(BarCluster, "team-b")
+	defer gslbutils.SetClusterTenant(FooCluster, "")
+	defer gslbutils.SetClusterTenant(BarCluster, "")
+	g.Expect(gslbutils.GetAllTenants()).To(gomega.Equal([]string{utils.ADMIN_NS, "team-a", "team-b"}))
+
+	// the clusters with unknown tenants fall back to the default tenant
+	gslbutils.ValidateClusterTenants(func(tenant string) (string, bool, error) {
+		if tenant == "team-a" {
+			return "tenant-a-uuid", true, nil
+		}
+		return "", false, nil
+	})
+	g.Expect(gslbutils.GetClusterTenant(FooCluster)).To(gomega.Equal("team-a"))
+	g.Expect(gslbutils.GetClusterTenant(BarCluster)).To(gomega.Equal(utils.ADMIN_NS))
+
+	g.Expect(gslbutils.GetTenantFromRef("https://10.10.10.10/api/tenant/tenant-a-uuid")).To(gomega.Equal("team-a"))
+	g.Expect(gslbutils.GetTenantFromRef("https://10.10.10.10/api/tenant/tenant-b-uuid#team-b")).To(gomega.Equal("team-b"))
+	g.Expect(gslbutils.GetTenantFromRef("https://10.10.10.10/api/tenant/admin")).To(gomega.Equal(utils.ADMIN_NS))
+	g.Expect(gslbutils.GetTenantFromRef("https://10.10.10.10/api/tenant/unknown-uuid")).To(gomega.Equal(utils.ADMIN_NS))
+}
+
+// Test the resolution of the hostnames which map to the same GSLB service name, for each of the
+// collision strategies.
+func TestResolveGslbName(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer gslbutils.SetGslbNameCollisionStrategy(gslbutils.DefaultGslbNameCollisionStrategy)
+	g.Expect(gslbutils.GetGslbNameCollisionStrategy()).To(gomega.Equal(gslbutils.GslbNameCollisionMerge))
+	g.Expect(gslbutils.SetGslbNameCollisionStrategy("abc")).NotTo(gomega.Succeed())
+	g.Expect(gslbutils.GetGslbNameCollisionStrategy()).To(gomega.Equal(gslbutils.GslbNameCollisionMerge))
+
+	// no collision
+	name, err := gslbutils.ResolveGslbName([]string{"App.avi.com."})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(name).To(gomega.Equal("app.avi.com"))
+
+	_, err = gslbutils.ResolveGslbName(nil)
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = gslbutils.ResolveGslbName([]string{"app.avi.com", "foo.avi.com"})
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// "App.avi.com" sorts first and owns the name, irrespective of the order of the candidates
+	owner, other := "App.avi.com", "app.avi.com."
+	for _, strategy := range []string{gslbutils.GslbNameCollisionMerge, gslbutils.GslbNameCollisionHashSuffix,
+		gslbutils.GslbNameCollisionReject} {
+		g.Expect(gslbutils.SetGslbNameCollisionStrategy(strategy)).To(gomega.Succeed())
+		name, err = gslbutils.ResolveGslbName([]string{owner, other})
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(name).To(gomega.Equal("app.avi.com"))
+	}
+
+	g.Expect(gslbutils.SetGslbNameCollisionStrategy(gslbutils.GslbNameCollisionMerge)).To(gomega.Succeed())
+	name, err = gslbutils.ResolveGslbName([]string{other, owner})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(name).To(gomega.Equal("app.avi.com"))
+
+	g.Expect(gslbutils.SetGslbNameCollisionStrategy(gslbutils.GslbNameCollisionHashSuffix)).To(gomega.Succeed())
+	name, err = gslbutils.ResolveGslbName([]string{other, owner})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(name).To(gomega.MatchRegexp(`^app\.avi\.com-[0-9a-f]{8}$`))
+	// the suffix is stable, and differs per hostname
+	sameName, _ := gslbutils.ResolveGslbName([]string{other, owner, "APP.avi.com"})
+	g.Expect(sameName).To(gomega.Equal(name))
+	thirdName, _ := gslbutils.ResolveGslbName([]string{"app.avi.com", owner, other})
+	g.Expect(thirdName).NotTo(gomega.Equal(name))
+	g.Expect(thirdName).NotTo(gomega.Equal("app.avi.com"))
+
+	g.Expect(gslbutils.SetGslbNameCollisionStrategy(gslbutils.GslbNameCollisionReject)).To(gomega.Succeed())
+	_, err = gslbutils.ResolveGslbName([]string{other, owner})
+	g.Expect(err).To(gomega.Equal(gslbutils.ErrGslbNameCollision))
+}
+
+// deleteIngressMeta deletes ihm and waits for the GS graph modelName to be left with nMembers, or
+// to be deleted if nMembers is 0.
+func deleteIngressMeta(t *testing.T, ihm k8sobjects.IngressHostMeta, modelName string, nMembers int) {
+	g := gomega.NewGomegaWithT(t)
+	gslbutils.GetAcceptedIngressStore().DeleteClusterNSObj(ihm.Cluster, ihm.Namespace, ihm.ObjName)
+	addKeyToIngestionQueue(ihm.Namespace, GetIhmKey(gslbutils.ObjectDelete, ihm))
+	g.Eventually(func() int {
+		found, aviGS := nodes.SharedAviGSGraphLister().Get(modelName)
+		if !found {
+			return 0
+		}
+		return aviGS.(*nodes.AviGSObjectGraph).MembersLen()
+	}, 5*time.Second).Should(gomega.Equal(nMembers))
+}
+
+// Test that renaming a cluster renames the members of the GS graphs and the host map entries of
+// its objects.
+func TestGSGraphsForClusterRename(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	prefix := "cr-"
+	oldName, newName := prefix+"old-cluster", prefix+"new-cluster"
+	ihm := AddIngressMeta(t, prefix+"ing1", DefNS, prefix+"host1.avi.com", DefSvc, "10.10.10.10", oldName, true)
+	modelName := utils.ADMIN_NS + "/" + ihm.Hostname
+	if ok, msg := waitAndVerify(t, modelName, false); !ok {
+		t.Fatalf("%s", msg)
+	}
+
+	if err := gslbutils.RenameCluster(oldName, newName); err != nil {
+		t.Fatalf("error in renaming the cluster: %v", err)
+	}
+	// the GS graph is published again, as the description of the GS has the member clusters
+	if ok, msg := waitAndVerify(t, modelName, false); !ok {
+		t.Fatalf("%s", msg)
+	}
+	renamed := ihm
+	renamed.Cluster = newName
+	verifyGsGraph(t, renamed, true, 1, true)
+	g.Expect(ihm.GetHostnameFromHostMap(gslbutils.GetClusterKey(newName, DefNS, ihm.ObjName))).To(gomega.Equal(ihm.Hostname))
+	g.Expect(ihm.GetHostnameFromHostMap(gslbutils.GetClusterKey(oldName, DefNS, ihm.ObjName))).To(gomega.Equal(""))
+
+	deleteIngressMeta(t, renamed, modelName, 0)
+}
+
+// Test the GS graphs of two hostnames which map to the same GSLB service name, for each of the
+// collision strategies.
+func TestGSGraphsForGslbNameCollision(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	defer gslbutils.SetGslbNameCollisionStrategy(gslbutils.DefaultGslbNameCollisionStrategy)
+
+	// merge: both the hostnames are members of the same GS graph
+	ihm1 := AddIngressMeta(t, "gnc-merge-foo", DefNS, "Gnc-merge.avi.com", DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, utils.ADMIN_NS+"/gnc-merge.avi.com", false)
+	g.Expect(ok).To(gomega.BeTrue(), msg)
+	ihm2 := AddIngressMeta(t, "gnc-merge-bar", DefNS, "gnc-merge.avi.com.", DefSvc, "10.10.10.20", BarCluster, true)
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/gnc-merge.avi.com", false)
+	g.Expect(ok).To(gomega.BeTrue(), msg)
+	verifyGsGraph(t, ihm1, true, 2, true)
+	verifyGsGraph(t, ihm2, true, 2, true)
+	deleteIngressMeta(t, ihm2, utils.ADMIN_NS+"/gnc-merge.avi.com", 1)
+	deleteIngressMeta(t, ihm1, utils.ADMIN_NS+"/gnc-merge.avi.com", 0)
+	verifyGsGraph(t, ihm1, false, 0, false)
+
+	// hash-suffix: the second hostname gets a separate GS graph
+	g.Expect(gslbutils.SetGslbNameCollisionStrategy(gslbutils.GslbNameCollisionHashSuffix)).To(gomega.Succeed())
+	ihm1 = AddIngressMeta(t, "gnc-hash-foo", DefNS, "Gnc-hash.avi.com", DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/gnc-hash.avi.com", false)
+	g.Expect(ok).To(gomega.BeTrue(), msg)
+	ihm2 = AddIngressMeta(t, "gnc-hash-bar", DefNS, "gnc-hash.avi.com.", DefSvc, "10.10.10.20", BarCluster, true)
+	hashName, err := gslbutils.ResolveGslbName([]string{ihm2.Hostname, ihm1.Hostname})
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(hashName).NotTo(gomega.Equal("gnc-hash.avi.com"))
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+hashName, false)
+	g.Expect(ok).To(gomega.BeTrue(), msg)
+	verifyGsGraph(t, ihm1, true, 1, true)
+	verifyGsGraph(t, ihm2, true, 1, true)
+	deleteIngressMeta(t, ihm2, utils.ADMIN_NS+"/"+hashName, 0)
+	deleteIngressMeta(t, ihm1, utils.ADMIN_NS+"/gnc-hash.avi.com", 0)
+
+	// reject: the second hostname has no GS graph
+	g.Expect(gslbutils.SetGslbNameCollisionStrategy(gslbutils.GslbNameCollisionReject)).To(gomega.Succeed())
+	ihm1 = AddIngressMeta(t, "gnc-reject-foo", DefNS, "Gnc-reject.avi.com", DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/gnc-reject.avi.com", false)
+	g.Expect(ok).To(gomega.BeTrue(), msg)
+	ihm2 = AddIngressMeta(t, "gnc-reject-bar", DefNS, "gnc-reject.avi.com.", DefSvc, "10.10.10.20", BarCluster, true)
+	ok, msg = waitAndVerify(t, "", true)
+	g.Expect(ok).To(gomega.BeTrue(), msg)
+	verifyGsGraph(t, ihm1, true, 1, true)
+	verifyGsGraph(t, ihm2, false, 0, false)
+	gslbutils.GetAcceptedIngressStore().DeleteClusterNSObj(ihm2.Cluster, ihm2.Namespace, ihm2.ObjName)
+	deleteIngressMeta(t, ihm1, utils.ADMIN_NS+"/gnc-reject.avi.com", 0)
+}
+
+// Test that a hostname keeps the GSLB service name it was resolved to, even if a hostname which owns
+// the name in the sorted order is seen later, and that the name is released with its last member.
+func TestGSGraphsForPinnedGslbName(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(gslbutils.SetGslbNameCollisionStrategy(gslbutils.GslbNameCollisionHashSuffix)).To(gomega.Succeed())
+	defer gslbutils.SetGslbNameCollisionStrategy(gslbutils.DefaultGslbNameCollisionStrategy)
+
+	// the lookups don't pin a name
+	gsName, err := nodes.DeriveGSLBServiceName("gnc-pin.avi.com.")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(gsName).To(gomega.Equal("gnc-pin.avi.com"))
+	gsName, err = nodes.DeriveGSLBServiceName("Gnc-pin.avi.com")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(gsName).To(gomega.Equal("gnc-pin.avi.com"))
+
+	ihm1 := AddIngressMeta(t, "gnc-pin-foo", DefNS, "gnc-pin.avi.com.", DefSvc, "10.10.10.10", FooCluster, true)
+	ok, msg := waitAndVerify(t, utils.ADMIN_NS+"/gnc-pin.avi.com", false)
+	g.Expect(ok).To(gomega.BeTrue(), msg)
+	ihm2 := AddIngressMeta(t, "gnc-pin-bar", DefNS, "Gnc-pin.avi.com", DefSvc, "10.10.10.20", BarCluster, true)
+	hashName, err := gslbutils.ResolveGslbNameCollision(ihm2.Hostname)
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	ok, msg = waitAndVerify(t, utils.ADMIN_NS+"/"+hashName, false)
+	g.Expect(ok).To(gomega.BeTrue(), msg)
+	gsName, _ = nodes.DeriveGSLBServiceName(ihm1.Hostname)
+	g.Expect(gsName).To(gomega.Equal("gnc-pin.avi.com"))
+	verifyGsGraph(t, ihm1, true, 1, true)
+	verifyGsGraph(t, ihm2, true, 1, true)
+
+	deleteIngressMeta(t, ihm1, utils.ADMIN_NS+"/gnc-pin.avi.com", 0)
+	gsName, _ = nodes.DeriveGSLBServiceName(ihm2.Hostname)
+	g.Expect(gsName).To(gomega.Equal(hashName))
+	deleteIngressMeta(t, ihm2, utils.ADMIN_NS+"/"+hashName, 0)
+	gsName, _ = nodes.DeriveGSLBServiceName(ihm2.Hostname)
+	g.Expect(gsName).To(gomega.Equal("gnc-pin.avi.com"))
+}
+
+// Test that adding or removing a cluster on the hash ring moves only a bounded fraction of the keys,
+// and only to or from that cluster.
+func TestHashRingBoundedMoves(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	clusters := []string{"cluster1", "cluster2", "cluster3", "cluster4"}
+	ring := gslbutils.NewHashRing(clusters, 0)
+	reversed := gslbutils.NewHashRing([]string{"cluster4", "cluster3", "cluster2", "cluster1"}, 0)
+	g.Expect(ring.GetClusters()).To(gomega.Equal(reversed.GetClusters()))
+
+	const numKeys = 10000
+	owners := make([]string, numKeys)
+	for i := 0; i < numKeys; i++ {
+		owners[i] = ring.GetCluster("10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256))
+		g.Expect(owners[i]).To(gomega.Equal(reversed.GetCluster("10.0." + strconv.Itoa(i/256) + "." +
+			strconv.Itoa(i%256))))
+	}
+
+	added := gslbutils.NewHashRing(append(clusters, "cluster5"), 0)
+	moved := 0
+	for i := 0; i < numKeys; i++ {
+		owner := added.GetCluster("10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256))
+		if owner != owners[i] {
+			g.Expect(owner).To(gomega.Equal("cluster5"))
+			moved++
+		}
+	}
+	// about 1/5 of the keys are expected to move to the new cluster
+	g.Expect(moved).To(gomega.BeNumerically(">", numKeys/10))
+	g.Expect(moved).To(gomega.BeNumerically("<", numKeys*3/10))
+
+	removed := gslbutils.NewHashRing(clusters[1:], 0)
+	moved = 0
+	for i := 0; i < numKeys; i++ {
+		owner := removed.GetCluster("10.0." + strconv.Itoa(i/256) + "." + strconv.Itoa(i%256))
+		if owner != owners[i] {
+			g.Expect(owners[i]).To(gomega.Equal("cluster1"))
+			moved++
+		}
+	}
+	g.Expect(moved).To(gomega.BeNumerically("<", numKeys*4/10))
+
+	// the relative order of the existing clusters stays the same
+	order := []string{}
+	for _, cname := range added.GetClusters() {
+		if cname != "cluster5" {
+			order = append(order, cname)
+		}
+	}
+	g.Expect(order).To(gomega.Equal(ring.GetClusters()))
+}
+
+// Test that the members of a GS graph with the consistent hash algorithm are ordered by the hash
+// ring of their clusters.
+func TestGSMembersConsistentHashOrder(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	members := func(clusters ...string) []nodes.AviGSK8sObj {
+		var objs []nodes.AviGSK8sObj
+		for idx, cname := range clusters {
+			objs = append(objs, nodes.AviGSK8sObj{Cluster: cname, Namespace: DefNS, Name: "ing/host.avi.com",
+				ObjType: gdpalphav1.IngressObj, IPAddr: "10.10.10." + strconv.Itoa(idx+1), Weight: 1})
+		}
+		return objs
+	}
+	clusterOrder := func(objs []nodes.AviGSK8sObj) []string {
+		var cnames []string
+		for _, obj := range objs {
+			cnames = append(cnames, obj.Cluster)
+		}
+		return cnames
+	}
+
+	gsGraph := nodes.AviGSObjectGraph{Name: "host.avi.com", PoolAlgorithm: gslbutils.ConsistentHashPoolAlgorithm,
+		MemberObjs: members("cluster1", "cluster2", "cluster3")}
+	reversedGraph := nodes.AviGSObjectGraph{Name: "host.avi.com", PoolAlgorithm: gslbutils.ConsistentHashPoolAlgorithm,
+		MemberObjs: members("cluster3", "cluster2", "cluster1")}
+	order := clusterOrder(gsGraph.GetUniqueMemberObjs())
+	g.Expect(clusterOrder(reversedGraph.GetUniqueMemberObjs())).To(gomega.Equal(order))
+	g.Expect(order).To(gomega.Equal(gslbutils.NewHashRing([]string{"cluster1", "cluster2", "cluster3"}, 0).GetClusters()))
+
+	// the members keep the order of the graph with the other algorithms
+	rrGraph := nodes.AviGSObjectGraph{Name: "host.avi.com", PoolAlgorithm: gslbutils.DefaultPoolAlgorithm,
+		MemberObjs: members("cluster3", "cluster2", "cluster1")}
+	g.Expect(clusterOrder(rrGraph.GetUniqueMemberObjs())).To(gomega.Equal([]string{"cluster3", "cluster2", "cluster1"}))
+}
+
+func TestEffectiveMembers(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	hostname := "em-host1.avi.com"
+	gf := gslbutils.GetGlobalFilter()
+	gf.GlobalLock.Lock()
+	oldTrafficSplit := gf.TrafficSplit
+	gf.TrafficSplit = []gslbutils.ClusterTraffic{{ClusterName: FooCluster, Weight: 3}, {ClusterName: BarCluster, Weight: 7}}
+	gf.GlobalLock.Unlock()
+	defer func() {
+		gf.GlobalLock.Lock()
+		gf.TrafficSplit = oldTrafficSplit
+		gf.GlobalLock.Unlock()
+	}()
+
+	// the objects are only added to the accepted stores, the members don't need the GS graphs
+	acceptedIngStore := gslbutils.GetAcceptedIngressStore()
+	acceptedSvcStore := gslbutils.GetAcceptedLBSvcStore()
+	fooIhm := k8sobjects.IngressHostMeta{IngName: "em-ing1", Namespace: DefNS, Hostname: hostname,
+		IPAddr: "10.10.10.10", Cluster: FooCluster, ObjName: "em-ing1/" + hostname, Paths: []string{"/"}}
+	otherIhm := k8sobjects.IngressHostMeta{IngName: "em-ing2", Namespace: DefNS, Hostname: "em-host2.avi.com",
+		IPAddr: "10.10.10.11", Cluster: FooCluster, ObjName: "em-ing2/em-host2.avi.com", Paths: []string{"/"}}
+	barSvc := k8sobjects.SvcMeta{Name: "em-svc1", Namespace: DefNS, Hostname: hostname, IPAddr: "10.10.10.20",
+		Cluster: BarCluster, Port: 80, Protocol: "TCP"}
+	noIPSvc := k8sobjects.SvcMeta{Name: "em-svc2", Namespace: DefNS, Hostname: hostname, Cluster: BarCluster,
+		Port: 80, Protocol: "TCP"}
+	for _, ihm := range []k8sobjects.IngressHostMeta{fooIhm, otherIhm} {
+		acceptedIngStore.AddOrUpdate(ihm, ihm.Cluster, ihm.Namespace, ihm.ObjName)
+	}
+	for _, svc := range []k8sobjects.SvcMeta{barSvc, noIPSvc} {
+		acceptedSvcStore.AddOrUpdate(svc, svc.Cluster, svc.Namespace, svc.Name)
+	}
+	defer func() {
+		for _, ihm := range []k8sobjects.IngressHostMeta{fooIhm, otherIhm} {
+			acceptedIngStore.DeleteClusterNSObj(ihm.Cluster, ihm.Namespace, ihm.ObjName)
+		}
+		for _, svc := range []k8sobjects.SvcMeta{barSvc, noIPSvc} {
+			acceptedSvcStore.DeleteClusterNSObj(svc.Cluster, svc.Namespace, svc.Name)
+		}
+	}()
+
+	members := nodes.GetEffectiveMembers(hostname)
+	g.Expect(members).To(gomega.HaveLen(2))
+	g.Expect(members[0].Cluster).To(gomega.Equal(BarCluster))
+	g.Expect(members[0].ObjType).To(gomega.Equal(gslbutils.SvcType))
+	g.Expect(members[0].IPAddr).To(gomega.Equal("10.10.10.20"))
+	g.Expect(members[0].Weight).To(gomega.Equal(int32(7)))
+	g.Expect(members[0].Tenant).To(gomega.Equal(utils.ADMIN_NS))
+	g.Expect(members[1].Cluster).To(gomega.Equal(FooCluster))
+	g.Expect(members[1].ObjType).To(gomega.Equal(gslbutils.IngressType))
+	g.Expect(members[1].Name).To(gomega.Equal(fooIhm.ObjName))
+	g.Expect(members[1].IPAddr).To(gomega.Equal("10.10.10.10"))
+	g.Expect(members[1].Weight).To(gomega.Equal(int32(3)))
+
+	g.Expect(nodes.GetEffectiveMembers("em-host3.avi.com")).To(gomega.BeEmpty())
+}
+
+func TestGSMemberVIPs(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	svc := k8sobjects.SvcMeta{Name: "vip-svc", Namespace: DefNS, Hostname: "vip-host.avi.com", IPAddr: "10.10.10.1",
+		VIPs:    []gslbutils.VIP{{IP: "10.10.10.1", Weight: 4}, {IP: "10.10.10.2", Weight: 1}},
+		Cluster: FooCluster, Port: 80, Protocol: "TCP"}
+	gsGraph := nodes.NewAviGSObjectGraph()
+	gsGraph.ConstructAviGSGraph("vip-host.avi.com", "test-key", svc, 10, gslbutils.DefaultPriority)
+	cksum := gsGraph.GetChecksum()
+
+	// each VIP is a member, the member weight is split as per the weights of the VIPs
+	members := gsGraph.GetUniqueMemberObjs()
+	g.Expect(members).To(gomega.HaveLen(2))
+	g.Expect(members[0].IPAddr).To(gomega.Equal("10.10.10.1"))
+	g.Expect(members[0].Weight).To(gomega.Equal(int32(8)))
+	g.Expect(members[1].IPAddr).To(gomega.Equal("10.10.10.2"))
+	g.Expect(members[1].Weight).To(gomega.Equal(int32(2)))
+
+	// the VIPs of equal weight share the member weight equally
+	svc.VIPs = []gslbutils.VIP{{IP: "10.10.10.1", Weight: 1}, {IP: "10.10.10.2", Weight: 1}}
+	gsGraph.UpdateGSMember(svc, 10, gslbutils.DefaultPriority)
+	members = gsGraph.GetUniqueMemberObjs()
+	g.Expect(members).To(gomega.HaveLen(2))
+	g.Expect(members[0].Weight).To(gomega.Equal(int32(5)))
+	g.Expect(members[1].Weight).To(gomega.Equal(int32(5)))
+	g.Expect(gsGraph.GetChecksum()).NotTo(gomega.Equal(cksum))
+
+	// the weights of the members of the VIPs add up to the member weight
+	svc.VIPs = []gslbutils.VIP{{IP: "10.10.10.1", Weight: 3}, {IP: "10.10.10.2", Weight: 2},
+		{IP: "10.10.10.3", Weight: 2}}
+	gsGraph.UpdateGSMember(svc, 10, gslbutils.DefaultPriority)
+	members = gsGraph.GetUniqueMemberObjs()
+	g.Expect(members).To(gomega.HaveLen(3))
+	var total int32
+	for _, member := range members {
+		total += member.Weight
+	}
+	g.Expect(total).To(gomega.Equal(int32(10)))
+
+	// a member with a single VIP is unchanged
+	svc.VIPs = []gslbutils.VIP{{IP: "10.10.10.1", Weight: 1}}
+	gsGraph.UpdateGSMember(svc, 10, gslbutils.DefaultPriority)
+	members = gsGraph.GetUniqueMemberObjs()
+	g.Expect(members).To(gomega.HaveLen(1))
+	g.Expect(members[0].Weight).To(gomega.Equal(int32(10)))
+
+	vips := []gslbutils.VIP{{IP: "10.10.10.1", Weight: 20}, {IP: "10.10.10.2", Weight: 1},
+		{IP: "10.10.10.3", Weight: 1}}
+	g.Expect(gslbutils.GetVIPRatios(0, vips)).To(gomega.Equal([]int32{0, 0, 0}))
+	// no VIP is left out, if the member weight allows
+	g.Expect(gslbutils.GetVIPRatios(3, vips)).To(gomega.Equal([]int32{1, 1, 1}))
+	g.Expect(gslbutils.GetVIPRatios(2, vips)).To(gomega.Equal([]int32{2, 0, 0}))
+	g.Expect(gslbutils.GetVIPRatios(20, vips)).To(gomega.Equal([]int32{18, 1, 1}))
+}
@@ -0,0 +1,89 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"testing"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	gslbingestion "github.com/avinetworks/amko/gslb/ingestion"
+	gwv1 "github.com/avinetworks/amko/internal/apis/gateway/v1"
+
+	containerutils "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestClusterObjectTypes(t *testing.T) {
+	cname := "objtypes-cluster"
+
+	for _, objTypes := range [][]string{{"FOO"}, {gslbutils.IngressType, gslbutils.IngressType}} {
+		if err := gslbutils.SetClusterObjectTypes(cname, objTypes); err == nil {
+			t.Fatalf("expected an error for the object types %v", objTypes)
+		}
+	}
+	// an invalid update clears the object types set earlier
+	if err := gslbutils.SetClusterObjectTypes(cname, []string{gslbutils.RouteType}); err != nil {
+		t.Fatalf("error in setting the object types: %v", err)
+	}
+	if err := gslbutils.SetClusterObjectTypes(cname, []string{gslbutils.RouteType, "FOO"}); err == nil {
+		t.Fatalf("expected an error for the invalid object type FOO")
+	}
+	if objTypes := gslbutils.GetClusterObjectTypes(cname); objTypes != nil {
+		t.Fatalf("expected the object types to be cleared after an invalid update, got %v", objTypes)
+	}
+	if !gslbutils.IsObjectTypeWatched(gslbutils.HTTPRouteType, gslbutils.GetClusterObjectTypes(cname)) {
+		t.Fatalf("expected all the object types to be watched without any object types")
+	}
+	if err := gslbutils.SetClusterObjectTypes(cname, []string{gslbutils.IngressType}); err != nil {
+		t.Fatalf("error in setting the object types: %v", err)
+	}
+	defer gslbutils.SetClusterObjectTypes(cname, nil)
+	if gslbutils.IsObjectTypeWatched(gslbutils.HTTPRouteType, gslbutils.GetClusterObjectTypes(cname)) {
+		t.Fatalf("expected the HTTPRoutes not to be watched with only the ingresses enabled")
+	}
+
+	// the HTTPRoute informers of a cluster watching only the ingresses are not started, even if
+	// these are available
+	gwInformer, _ := getFakeInformer(&gwv1.Gateway{}, &gwv1.GatewayList{})
+	routeInformer, _ := getFakeInformer(&gwv1.HTTPRoute{}, &gwv1.HTTPRouteList{})
+	ctrl := gslbingestion.GetGSLBMemberController(cname, &containerutils.Informers{}, nil)
+	ctrl.SetGatewayInformers(&gslbingestion.GatewayInformers{GatewayInformer: gwInformer,
+		HTTPRouteInformer: routeInformer})
+	ctrl.SetupEventHandlers(gslbingestion.K8SInformers{Cs: k8sfake.NewSimpleClientset()})
+	if err := ctrl.Start(testStopCh); err != nil {
+		t.Fatalf("error in starting the member controller: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if gwInformer.HasSynced() || routeInformer.HasSynced() {
+		t.Fatalf("expected the HTTPRoute informers not to be started")
+	}
+
+	// the informers are started for a cluster watching the HTTPRoutes
+	gslbingestion.DeregisterMemberController(cname)
+	gslbutils.SetClusterObjectTypes(cname, []string{gslbutils.HTTPRouteType})
+	gwInformer, _ = getFakeInformer(&gwv1.Gateway{}, &gwv1.GatewayList{})
+	routeInformer, _ = getFakeInformer(&gwv1.HTTPRoute{}, &gwv1.HTTPRouteList{})
+	httpRouteCtrl := gslbingestion.GetGSLBMemberController(cname, &containerutils.Informers{}, nil)
+	httpRouteCtrl.SetGatewayInformers(&gslbingestion.GatewayInformers{GatewayInformer: gwInformer,
+		HTTPRouteInformer: routeInformer})
+	if err := httpRouteCtrl.Start(testStopCh); err != nil {
+		t.Fatalf("error in starting the member controller: %v", err)
+	}
+	defer gslbingestion.DeregisterMemberController(cname)
+	if !gwInformer.HasSynced() || !routeInformer.HasSynced() {
+		t.Fatalf("expected the HTTPRoute informers to be started and synced")
+	}
+}
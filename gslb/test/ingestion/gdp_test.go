@@ -15,6 +15,7 @@
 package ingestion
 
 import (
+	"reflect"
 	"testing"
 	"time"
 
@@ -27,7 +28,9 @@ import (
 
 	"github.com/onsi/gomega"
 	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
 	extensionv1beta1 "k8s.io/api/extensions/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/util/workqueue"
 )
@@ -110,6 +113,83 @@ func TestGDPSelectAllObjsFromOneCluster(t *testing.T) {
 	DeleteTestGDPObj(gdp)
 }
 
+// A forced resync writes the accepted objects to the queue again.
+func TestGDPForcedResync(t *testing.T) {
+	testPrefix := "frs-"
+	ingNameList := []string{testPrefix + "def-ing1", testPrefix + "def-ing2"}
+	hosts := []string{testPrefix + TestDomain1, testPrefix + TestDomain2}
+	ipAddrs := []string{"10.10.10.10", "10.10.10.11"}
+	cname := "cluster1"
+	ns := "default"
+	svc := "test-svc"
+
+	buildAndAddTestGSLBObject(t)
+	ingList, allKeys := CreateMultipleIngresses(t, fooKubeClient, ingNameList, hosts, ipAddrs, ns, svc, cname)
+
+	gdp := getTestGDPObject(true, false)
+	AddTestGDPObj(gdp)
+	VerifyAllKeys(t, allKeys, false)
+
+	t.Log("forcing a resync")
+	ingestionQueue := utils.SharedWorkQueue().GetQueueByName(utils.ObjectIngestionLayer)
+	gslbingestion.ResyncAllObjects(ingestionQueue.Workqueue, 2)
+	allKeys = []string{}
+	for _, ing := range ingList {
+		allKeys = append(allKeys, GetIngressKey("UPDATE", cname, ns, ing.ObjectMeta.Name,
+			ing.Status.LoadBalancer.Ingress[0].Hostname))
+	}
+	VerifyAllKeys(t, allKeys, false)
+
+	DeleteMultipleIngresses(t, fooKubeClient, ingList)
+	VerifyAllKeys(t, GetMultipleIngDeleteKeys(t, ingList, cname, ns), false)
+	DeleteTestGDPObj(gdp)
+}
+
+// Deleting the GDP object writes the DELETE keys of all the accepted objects, and moves these to the
+// rejected store, to be selected again by a new GDP object.
+func TestGDPDeleteTearsDownObjs(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testPrefix := "gdt-"
+	ingNameList := []string{testPrefix + "def-ing1", testPrefix + "def-ing2"}
+	hosts := []string{testPrefix + TestDomain1, testPrefix + TestDomain2}
+	ipAddrs := []string{"10.10.10.10", "10.10.10.11"}
+	cname := "cluster1"
+	ns := "default"
+	svc := "test-svc"
+
+	buildAndAddTestGSLBObject(t)
+	ingList, allKeys := CreateMultipleIngresses(t, fooKubeClient, ingNameList, hosts, ipAddrs, ns, svc, cname)
+
+	gdp := getTestGDPObject(true, false)
+	AddTestGDPObj(gdp)
+	VerifyAllKeys(t, allKeys, false)
+
+	t.Log("deleting the GDP object")
+	DeleteTestGDPObj(gdp)
+	VerifyAllKeys(t, GetMultipleIngDeleteKeys(t, ingList, cname, ns), false)
+	for idx, ing := range ingList {
+		verifyInIngStore(g, true, false, ing.ObjectMeta.Name, ns, cname, hosts[idx], ipAddrs[idx])
+		verifyInIngStore(g, false, true, ing.ObjectMeta.Name, ns, cname, hosts[idx], ipAddrs[idx])
+	}
+	// the removals are notified as rejections of the filter
+	rejectedHosts := map[string]string{}
+	for _, obj := range gslbutils.GetRejectedObjs() {
+		rejectedHosts[obj.Name] = obj.Reason
+	}
+	for idx, ing := range ingList {
+		objName := ing.ObjectMeta.Name + "/" + hosts[idx]
+		g.Expect(rejectedHosts).To(gomega.HaveKeyWithValue(objName, gslbutils.NoPolicyReason))
+	}
+
+	t.Log("adding the GDP object again")
+	AddTestGDPObj(gdp)
+	VerifyAllKeys(t, allKeys, false)
+
+	DeleteMultipleIngresses(t, fooKubeClient, ingList)
+	VerifyAllKeys(t, GetMultipleIngDeleteKeys(t, ingList, cname, ns), false)
+	DeleteTestGDPObj(gdp)
+}
+
 func TestGDPSelectFewObjsFromOneCluster(t *testing.T) {
 	testPrefix := "sfo-"
 	ingNameList := []string{testPrefix + "def-ing1", testPrefix + "def-ing2"}
@@ -246,6 +326,51 @@ func TestMultipleGDPObjectsForSameNS(t *testing.T) {
 	DeleteTestGDPObj(anotherGdp)
 }
 
+// GDP objects are accepted only in the configured GDP namespaces.
+func TestGDPNamespaces(t *testing.T) {
+	otherNS := "gdp-other-ns"
+	defer gslbutils.SetGDPNamespaces([]string{gslbutils.AVISystem})
+
+	if err := gslbutils.SetGDPNamespaces([]string{gslbutils.AVISystem, " "}); err == nil {
+		t.Fatalf("expected an error for an empty GDP namespace")
+	}
+	if !gslbutils.IsGDPNamespaceAllowed(gslbutils.AVISystem) || gslbutils.IsGDPNamespaceAllowed(otherNS) {
+		t.Fatalf("expected only %s to be allowed, allowed namespaces: %v", gslbutils.AVISystem,
+			gslbutils.GetGDPNamespaces())
+	}
+
+	buildAndAddTestGSLBObject(t)
+	gdp := getTestGDPObject(true, false)
+	gdp.ObjectMeta.Namespace = otherNS
+	AddTestGDPObj(gdp)
+	if !gslbutils.IsEmpty() {
+		t.Fatalf("GDP object in namespace %s should have been ignored", otherNS)
+	}
+
+	if err := gslbutils.SetGDPNamespaces([]string{otherNS}); err != nil {
+		t.Fatalf("error in setting the GDP namespaces: %v", err)
+	}
+	AddTestGDPObj(gdp)
+	if name, ns := gslbutils.GetGDPObj(); name != gdp.ObjectMeta.Name || ns != otherNS {
+		t.Fatalf("expected GDP object %s/%s to be accepted, got %s/%s", otherNS, gdp.ObjectMeta.Name, ns, name)
+	}
+	if stored, ok := gslbutils.GetDefaultGDPStore().Get(otherNS, gdp.ObjectMeta.Name); !ok ||
+		!reflect.DeepEqual(stored.Spec, gdp.Spec) {
+		t.Fatalf("expected the accepted GDP object %s/%s in the GDP store", otherNS, gdp.ObjectMeta.Name)
+	}
+
+	// the delete of a GDP object outside the GDP namespaces is ignored
+	DeleteTestGDPObj(getTestGDPObject(true, false))
+	if name, ns := gslbutils.GetGDPObj(); name != gdp.ObjectMeta.Name || ns != otherNS {
+		t.Fatalf("expected GDP object %s/%s to be retained, got %s/%s", otherNS, gdp.ObjectMeta.Name, ns, name)
+	}
+
+	DeleteTestGDPObj(gdp)
+	if !gslbutils.IsEmpty() {
+		t.Fatalf("GDP object %s/%s should have been deleted", otherNS, gdp.ObjectMeta.Name)
+	}
+}
+
 func TestUpdateGDPSelectFew(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	testPrefix := "mgo-"
@@ -472,11 +597,42 @@ func TestGDPMisnameClusters(t *testing.T) {
 	DeleteMultipleIngresses(t, fooKubeClient, ingList1)
 	t.Logf("Deleting ingresses for cluster2")
 	DeleteMultipleIngresses(t, barKubeClient, ingList2)
+	WaitForIngressHostsRemoved(t, cname1, ingList1)
+	WaitForIngressHostsRemoved(t, cname2, ingList2)
 	DeleteTestGDPObj(gdp)
 
 	// no need to verify delete keys, as no objects were added
 }
 
+func TestGDPUnconnectedClusters(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	buildAndAddTestGSLBObject(t)
+	// cluster3 is a member cluster in the GSLBConfig object, but isn't connected
+	gslbutils.SetMemberClusters([]string{"cluster1", "cluster2", "cluster3"})
+	defer gslbutils.SetMemberClusters(nil)
+
+	gdp := getTestGDPObject(true, false)
+	gdp.ObjectMeta.SetNamespace(gslbutils.AVISystem)
+	// select no objects, the objects of the previous tests might still be in the stores
+	UpdateGDPMatchRuleAppLabel(gdp, "key", "ucc-value")
+	gdp.Spec.MatchClusters = []string{"cluster1", "cluster3"}
+
+	AddTestGDPObj(gdp)
+
+	t.Logf("verifying status message and warnings")
+	g.Expect(gdp.Status.ErrorStatus).To(gomega.Equal("success"))
+	g.Expect(gdp.Status.Warnings).To(gomega.Equal([]string{"cluster cluster3 is configured in matchClusters but not connected"}))
+
+	t.Logf("removing cluster3 from matchClusters, the warnings should go away")
+	newGdp := gdp.DeepCopy()
+	newGdp.ObjectMeta.ResourceVersion = "200"
+	newGdp.Spec.MatchClusters = []string{"cluster1"}
+	UpdateTestGDPObj(gdp, newGdp)
+	g.Expect(newGdp.Status.ErrorStatus).To(gomega.Equal("success"))
+	g.Expect(newGdp.Status.Warnings).To(gomega.BeEmpty())
+	DeleteTestGDPObj(newGdp)
+}
+
 func TestGDPSelectNoClusters(t *testing.T) {
 	testPrefix := "snc-"
 	ingNameList := []string{testPrefix + "def-ing1", testPrefix + "def-ing2"}
@@ -512,6 +668,7 @@ func TestGDPSelectNoClusters(t *testing.T) {
 	}
 
 	DeleteMultipleIngresses(t, fooKubeClient, ingList)
+	WaitForIngressHostsRemoved(t, cname, ingList)
 	DeleteTestGDPObj(gdp)
 
 	// No need to verify delete keys, since the objects weren't added previously, the delete keys won't
@@ -574,6 +731,22 @@ func DeleteMultipleIngresses(t *testing.T, kc *k8sfake.Clientset, ingList []*ext
 	}
 }
 
+// WaitForIngressHostsRemoved waits until the hosts of the ingresses are removed from the ingress stores,
+// required by the tests which don't verify the delete keys, before the next GDP object is added.
+func WaitForIngressHostsRemoved(t *testing.T, cname string, ingList []*extensionv1beta1.Ingress) {
+	g := gomega.NewGomegaWithT(t)
+	for _, ingObj := range ingList {
+		for _, rule := range ingObj.Spec.Rules {
+			objName := ingObj.ObjectMeta.Name + "/" + rule.Host
+			g.Eventually(func() bool {
+				_, accepted := gslbutils.GetAcceptedIngressStore().GetClusterNSObjectByName(cname, ingObj.ObjectMeta.Namespace, objName)
+				_, rejected := gslbutils.GetRejectedIngressStore().GetClusterNSObjectByName(cname, ingObj.ObjectMeta.Namespace, objName)
+				return accepted || rejected
+			}, 5*time.Second).Should(gomega.BeFalse())
+		}
+	}
+}
+
 func UpdateGDPMatchRuleAppLabel(gdp *gslbalphav1.GlobalDeploymentPolicy, key, value string) {
 	if len(gdp.Spec.MatchRules.AppSelector.Label) == 0 {
 		gdp.Spec.MatchRules.AppSelector.Label = make(map[string]string)
@@ -619,3 +792,148 @@ func GetMultipleIngDeleteKeys(t *testing.T, ingList []*extensionv1beta1.Ingress,
 	}
 	return allKeys
 }
+
+// Test the validation of the traffic splits expressed as percentages.
+func TestGDPPercentageTrafficSplit(t *testing.T) {
+	gslbutils.AddClusterContext("cluster1")
+	gslbutils.AddClusterContext("cluster2")
+	gdp := &gslbalphav1.GlobalDeploymentPolicy{
+		Spec: gslbalphav1.GDPSpec{
+			MatchClusters: []string{"cluster1", "cluster2"},
+			WeightMode:    gslbalphav1.WeightModePercentage,
+			TrafficSplit: []gslbalphav1.TrafficSplitElem{
+				{Cluster: "cluster1", Weight: 70},
+				{Cluster: "cluster2", Weight: 30},
+			},
+		},
+	}
+	if err := gslbingestion.GDPSanityChecks(gdp); err != nil {
+		t.Fatalf("expected percentages summing to 100 to be valid, got %v", err)
+	}
+
+	gdp.Spec.TrafficSplit[1].Weight = 20
+	if err := gslbingestion.GDPSanityChecks(gdp); err == nil {
+		t.Fatalf("expected an error for percentages summing to 90")
+	}
+
+	// weights above 20 are only allowed in the percentage mode
+	gdp.Spec.TrafficSplit[1].Weight = 30
+	gdp.Spec.WeightMode = gslbalphav1.WeightModeWeight
+	if err := gslbingestion.GDPSanityChecks(gdp); err == nil {
+		t.Fatalf("expected an error for a weight of 70 in the weight mode")
+	}
+
+	gdp.Spec.WeightMode = "ratio"
+	if err := gslbingestion.GDPSanityChecks(gdp); err == nil {
+		t.Fatalf("expected an error for an invalid weight mode")
+	}
+}
+
+// Test the parsing and the validation of the traffic split ConfigMap, and the override set from it.
+func TestTrafficSplitConfigMap(t *testing.T) {
+	gslbutils.AddClusterContext("cluster1")
+	gslbutils.AddClusterContext("cluster2")
+	defer gslbutils.ClearTrafficSplitOverride()
+
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: gslbingestion.TrafficSplitConfigMapName, Namespace: gslbutils.AVISystem},
+		Data: map[string]string{
+			gslbingestion.TrafficSplitConfigMapKey: `[{"cluster": "cluster1", "weight": 8}, {"cluster": "cluster2", "weight": 2, "priority": 5}]`,
+		},
+	}
+	trafficSplit, err := gslbingestion.ParseTrafficSplitConfigMap(cm, gslbalphav1.WeightModeWeight)
+	if err != nil {
+		t.Fatalf("expected the traffic split to be valid, got %v", err)
+	}
+	expected := []gslbalphav1.TrafficSplitElem{
+		{Cluster: "cluster1", Weight: 8},
+		{Cluster: "cluster2", Weight: 2, Priority: 5},
+	}
+	if !reflect.DeepEqual(trafficSplit, expected) {
+		t.Fatalf("expected traffic split %v, got %v", expected, trafficSplit)
+	}
+	if _, err := gslbingestion.ParseTrafficSplitConfigMap(cm, gslbalphav1.WeightModePercentage); err == nil {
+		t.Fatalf("expected an error for the weights not summing to 100 in the percentage mode")
+	}
+
+	invalidSplits := map[string]string{
+		"invalid json":        `[{"cluster": "cluster1"`,
+		"unknown field":       `[{"cluster": "cluster1", "ratio": 8}]`,
+		"empty list":          `[]`,
+		"empty cluster":       `[{"weight": 8}]`,
+		"repeated cluster":    `[{"cluster": "cluster1", "weight": 8}, {"cluster": "cluster1", "weight": 2}]`,
+		"unknown cluster":     `[{"cluster": "cluster3", "weight": 8}]`,
+		"out of range weight": `[{"cluster": "cluster1", "weight": 21}]`,
+	}
+	for desc, val := range invalidSplits {
+		invalidCM := cm.DeepCopy()
+		invalidCM.Data[gslbingestion.TrafficSplitConfigMapKey] = val
+		if _, err := gslbingestion.ParseTrafficSplitConfigMap(invalidCM, gslbalphav1.WeightModeWeight); err == nil {
+			t.Fatalf("expected an error for the traffic split with %s", desc)
+		}
+	}
+	noKeyCM := cm.DeepCopy()
+	noKeyCM.Data = nil
+	if _, err := gslbingestion.ParseTrafficSplitConfigMap(noKeyCM, gslbalphav1.WeightModeWeight); err == nil {
+		t.Fatalf("expected an error for a ConfigMap without the traffic split key")
+	}
+
+	// without an accepted GDP object, the override is only recorded
+	gslbingestion.AddOrUpdateTrafficSplitConfigMap(cm, nil, 0)
+	if override, ok := gslbutils.GetTrafficSplitOverride(); !ok || !reflect.DeepEqual(override, expected) {
+		t.Fatalf("expected the traffic split override %v, got %v, %t", expected, override, ok)
+	}
+	invalidCM := cm.DeepCopy()
+	invalidCM.Data[gslbingestion.TrafficSplitConfigMapKey] = `[]`
+	gslbingestion.AddOrUpdateTrafficSplitConfigMap(invalidCM, nil, 0)
+	if _, ok := gslbutils.GetTrafficSplitOverride(); ok {
+		t.Fatalf("expected an invalid ConfigMap to remove the traffic split override")
+	}
+	gslbingestion.AddOrUpdateTrafficSplitConfigMap(cm, nil, 0)
+	gslbingestion.DeleteTrafficSplitConfigMap(nil, 0)
+	if _, ok := gslbutils.GetTrafficSplitOverride(); ok {
+		t.Fatalf("expected the deletion of the ConfigMap to remove the traffic split override")
+	}
+}
+
+// Test the validation of the object types of the match rules.
+func TestGDPObjectTypes(t *testing.T) {
+	gslbutils.AddClusterContext("cluster1")
+	gdp := &gslbalphav1.GlobalDeploymentPolicy{
+		Spec: gslbalphav1.GDPSpec{
+			MatchClusters: []string{"cluster1"},
+			MatchRules: gslbalphav1.MatchRules{
+				ObjectTypes: []string{gslbalphav1.RouteObj, gslbalphav1.LBSvcObj},
+			},
+		},
+	}
+	if err := gslbingestion.GDPSanityChecks(gdp); err != nil {
+		t.Fatalf("expected the object types to be valid, got %v", err)
+	}
+
+	gdp.Spec.MatchRules.ObjectTypes = append(gdp.Spec.MatchRules.ObjectTypes, "Namespace")
+	if err := gslbingestion.GDPSanityChecks(gdp); err == nil {
+		t.Fatalf("expected an error for an invalid object type")
+	}
+}
+
+// Test the validation of the selector mode of the match rules.
+func TestGDPSelectorMode(t *testing.T) {
+	gslbutils.AddClusterContext("cluster1")
+	gdp := &gslbalphav1.GlobalDeploymentPolicy{
+		Spec: gslbalphav1.GDPSpec{
+			MatchClusters: []string{"cluster1"},
+			MatchRules: gslbalphav1.MatchRules{
+				SelectorMode: gslbalphav1.SelectorModeNamespaceOverride,
+			},
+		},
+	}
+	if err := gslbingestion.GDPSanityChecks(gdp); err != nil {
+		t.Fatalf("expected the selector mode to be valid, got %v", err)
+	}
+
+	gdp.Spec.MatchRules.SelectorMode = "OR"
+	if err := gslbingestion.GDPSanityChecks(gdp); err == nil {
+		t.Fatalf("expected an error for an invalid selector mode")
+	}
+}
@@ -15,18 +15,34 @@
 package ingestion
 
 import (
+	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/avinetworks/amko/gslb/gslbutils"
 	gslbingestion "github.com/avinetworks/amko/gslb/ingestion"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gslbalphav1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
 
 	gslbfake "github.com/avinetworks/amko/internal/client/clientset/versioned/fake"
 
 	gslbinformers "github.com/avinetworks/amko/internal/client/informers/externalversions"
 
+	"github.com/onsi/gomega"
+	containerutils "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeinformers "k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
 	k8sfake "k8s.io/client-go/kubernetes/fake"
+	restclient "k8s.io/client-go/rest"
 )
 
 type GSLBTestConfigAddfn func(obj interface{})
@@ -56,3 +72,365 @@ func TestGSLBKubeConfig(t *testing.T) {
 		t.Fatalf("Failure in generating GSLB Kube config: %s", err.Error())
 	}
 }
+
+// Unit test to see if the resync periods of the informers are picked up from the environment.
+func TestInformerResyncPeriods(t *testing.T) {
+	os.Setenv("ROUTE_RESYNC_PERIOD", "600")
+	os.Setenv("SERVICE_RESYNC_PERIOD", "0")
+	os.Setenv("INGRESS_RESYNC_PERIOD", "invalid")
+	defer os.Unsetenv("ROUTE_RESYNC_PERIOD")
+	defer os.Unsetenv("SERVICE_RESYNC_PERIOD")
+	defer os.Unsetenv("INGRESS_RESYNC_PERIOD")
+
+	resyncPeriods := gslbingestion.GetInformerResyncPeriods()
+	if period, ok := resyncPeriods[containerutils.RouteInformer]; !ok || period != 600*time.Second {
+		t.Fatalf("expected a resync period of 600s for routes, got %v", resyncPeriods)
+	}
+	if period, ok := resyncPeriods[containerutils.ServiceInformer]; !ok || period != 0 {
+		t.Fatalf("expected the resync to be disabled for services, got %v", resyncPeriods)
+	}
+	if _, ok := resyncPeriods[containerutils.IngressInformer]; ok {
+		t.Fatalf("expected the default resync period for an invalid value, got %v", resyncPeriods)
+	}
+	if _, ok := resyncPeriods[containerutils.NSInformer]; ok {
+		t.Fatalf("expected the default resync period for namespaces, got %v", resyncPeriods)
+	}
+}
+
+// Unit test to see if the cluster unreachable policy decides whether a member cluster which can't
+// be initialized fails the initialization, or is returned to be retried later.
+func TestUnreachableClusterPolicy(t *testing.T) {
+	defer gslbutils.SetClusterUnreachablePolicy(gslbutils.ClusterUnreachableFail)
+	if err := gslbutils.SetClusterUnreachablePolicy("invalid"); err == nil {
+		t.Fatalf("expected an error for an invalid policy")
+	}
+	memberClusters := []gslbalphav1.MemberCluster{{ClusterContext: "unreachable-cluster"}}
+
+	if err := gslbutils.SetClusterUnreachablePolicy(gslbutils.ClusterUnreachableFail); err != nil {
+		t.Fatalf("error in setting the policy: %v", err)
+	}
+	if _, _, err := gslbingestion.InitializeGSLBClusters("./testdata/test-kube-config", memberClusters); err == nil {
+		t.Fatalf("expected an error for an unreachable cluster with the fail policy")
+	}
+
+	if err := gslbutils.SetClusterUnreachablePolicy("DEGRADE"); err != nil {
+		t.Fatalf("error in setting the policy: %v", err)
+	}
+	ctrls, unreachable, err := gslbingestion.InitializeGSLBClusters("./testdata/test-kube-config", memberClusters)
+	if err != nil {
+		t.Fatalf("expected no error for an unreachable cluster with the degrade policy, got %v", err)
+	}
+	if len(ctrls) != 0 || len(unreachable) != 1 || unreachable[0].ClusterContext != "unreachable-cluster" {
+		t.Fatalf("expected the cluster to be unreachable, got controllers: %d, unreachable: %v", len(ctrls), unreachable)
+	}
+}
+
+// Unit test for the backoff between the retries for an unreachable member cluster.
+func TestClusterRetryDelay(t *testing.T) {
+	if delay := gslbutils.NextClusterRetryDelay(0); delay != gslbutils.ClusterRetryInitialDelay {
+		t.Fatalf("expected the initial delay, got %v", delay)
+	}
+	if delay := gslbutils.NextClusterRetryDelay(gslbutils.ClusterRetryInitialDelay); delay != 2*gslbutils.ClusterRetryInitialDelay {
+		t.Fatalf("expected the delay to double, got %v", delay)
+	}
+	if delay := gslbutils.NextClusterRetryDelay(gslbutils.ClusterRetryMaxDelay); delay != gslbutils.ClusterRetryMaxDelay {
+		t.Fatalf("expected the delay to be capped at %v, got %v", gslbutils.ClusterRetryMaxDelay, delay)
+	}
+}
+
+func isStopped(stopCh <-chan struct{}) bool {
+	select {
+	case <-stopCh:
+		return true
+	case <-time.After(2 * time.Second):
+		return false
+	}
+}
+
+func TestMemberControllerRegistry(t *testing.T) {
+	stopCh := make(chan struct{})
+	ctrl := gslbingestion.GetGSLBMemberController("registry-cluster1", &containerutils.Informers{}, nil)
+	ctrlStopCh, err := gslbingestion.RegisterMemberController(&ctrl, stopCh)
+	if err != nil {
+		t.Fatalf("unexpected error in registering the member controller: %v", err)
+	}
+	if registered, ok := gslbingestion.GetMemberController("registry-cluster1"); !ok || registered != &ctrl {
+		t.Fatalf("expected the member controller to be registered")
+	}
+	// registering the same controller again is a no-op
+	if againStopCh, err := gslbingestion.RegisterMemberController(&ctrl, stopCh); err != nil || againStopCh != ctrlStopCh {
+		t.Fatalf("expected the same stop channel for the registered controller, error: %v", err)
+	}
+	other := gslbingestion.GetGSLBMemberController("registry-cluster1", &containerutils.Informers{}, nil)
+	if _, err := gslbingestion.RegisterMemberController(&other, stopCh); !errors.Is(err, gslbingestion.ErrMemberControllerRegistered) {
+		t.Fatalf("expected ErrMemberControllerRegistered, got %v", err)
+	}
+	// a duplicate controller isn't started
+	if err := other.Start(stopCh); !errors.Is(err, gslbingestion.ErrMemberControllerRegistered) {
+		t.Fatalf("expected ErrMemberControllerRegistered in starting a duplicate controller, got %v", err)
+	}
+
+	if !gslbingestion.DeregisterMemberController("registry-cluster1") {
+		t.Fatalf("expected the member controller to be deregistered")
+	}
+	if !isStopped(ctrlStopCh) {
+		t.Fatalf("expected the deregistered member controller to be stopped")
+	}
+	if _, ok := gslbingestion.GetMemberController("registry-cluster1"); ok {
+		t.Fatalf("expected no member controller after deregistration")
+	}
+	if gslbingestion.DeregisterMemberController("registry-cluster1") {
+		t.Fatalf("expected no member controller to deregister")
+	}
+
+	// the controller is stopped and deregistered along with its parent stop channel
+	ctrl2 := gslbingestion.GetGSLBMemberController("registry-cluster2", &containerutils.Informers{}, nil)
+	ctrl2StopCh, _ := gslbingestion.RegisterMemberController(&ctrl2, stopCh)
+	close(stopCh)
+	if !isStopped(ctrl2StopCh) {
+		t.Fatalf("expected the member controller to be stopped with the parent stop channel")
+	}
+	g := gomega.NewGomegaWithT(t)
+	g.Eventually(func() bool {
+		_, ok := gslbingestion.GetMemberController("registry-cluster2")
+		return ok
+	}).Should(gomega.BeFalse())
+	if gslbingestion.DeregisterMemberController("registry-cluster2") {
+		t.Fatalf("expected the stopped member controller to be deregistered already")
+	}
+}
+
+func TestRenameMemberCluster(t *testing.T) {
+	oldGc := &gslbalphav1.GSLBConfig{}
+	oldGc.Spec.MemberClusters = []gslbalphav1.MemberCluster{
+		{ClusterContext: "rename-cluster1", Region: "east"},
+		{ClusterContext: "rename-cluster2"},
+	}
+	newGc := oldGc.DeepCopy()
+	newGc.Spec.MemberClusters[0].ClusterContext = "rename-cluster3"
+	renamed := gslbingestion.GetRenamedMemberClusters(oldGc, newGc)
+	if !reflect.DeepEqual(renamed, map[string]string{"rename-cluster1": "rename-cluster3"}) {
+		t.Fatalf("expected rename-cluster1 to be renamed to rename-cluster3, got %v", renamed)
+	}
+	// a member cluster with the other fields changed as well isn't a rename
+	changedGc := newGc.DeepCopy()
+	changedGc.Spec.MemberClusters[0].Region = "west"
+	if renamed := gslbingestion.GetRenamedMemberClusters(oldGc, changedGc); len(renamed) != 0 {
+		t.Fatalf("expected no renamed clusters with the region changed, got %v", renamed)
+	}
+
+	stopCh := make(chan struct{})
+	ctrl := gslbingestion.GetGSLBMemberController("rename-cluster1", &containerutils.Informers{}, nil)
+	if _, err := gslbingestion.RegisterMemberController(&ctrl, stopCh); err != nil {
+		t.Fatalf("unexpected error in registering the member controller: %v", err)
+	}
+	gslbutils.AddClusterContext("rename-cluster1")
+	if err := gslbutils.RenameCluster("rename-cluster1", "rename-cluster3"); err != nil {
+		t.Fatalf("error in renaming the cluster: %v", err)
+	}
+	if _, ok := gslbingestion.GetMemberController("rename-cluster1"); ok {
+		t.Fatalf("expected no member controller for the old cluster name")
+	}
+	if registered, ok := gslbingestion.GetMemberController("rename-cluster3"); !ok || registered != &ctrl ||
+		ctrl.GetName() != "rename-cluster3" {
+		t.Fatalf("expected the member controller to be renamed")
+	}
+	// the keys queued with the old cluster name are processed for the renamed cluster
+	if cname := gslbutils.ResolveClusterName("rename-cluster1"); cname != "rename-cluster3" {
+		t.Fatalf("expected rename-cluster1 to resolve to rename-cluster3, got %s", cname)
+	}
+
+	// the renamed controller is deregistered along with its parent stop channel
+	close(stopCh)
+	g := gomega.NewGomegaWithT(t)
+	g.Eventually(func() bool {
+		_, ok := gslbingestion.GetMemberController("rename-cluster3")
+		return ok
+	}).Should(gomega.BeFalse())
+}
+
+// Unit test to see if only the member clusters whose credentials changed are reloaded, and that a
+// cluster which can't be reloaded with the new credentials keeps its existing member controller.
+func TestReloadMemberClusters(t *testing.T) {
+	kubeconfigData, err := ioutil.ReadFile("./testdata/test-kube-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	kubeconfig := string(kubeconfigData)
+	if err := gslbingestion.SetMemberCredentials(kubeconfig, []string{"dev-default", "exp-scratch"}); err != nil {
+		t.Fatalf("error in setting the member credentials: %v", err)
+	}
+	if changed, err := gslbingestion.GetChangedMemberClusters(kubeconfig); err != nil || len(changed) != 0 {
+		t.Fatalf("expected no changed clusters for the same kubeconfig, got %v, error: %v", changed, err)
+	}
+	if _, err := gslbingestion.ReloadMemberClusters("invalid kubeconfig"); err == nil {
+		t.Fatalf("expected an error for an invalid kubeconfig")
+	}
+
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	ctrl := gslbingestion.GetGSLBMemberController("dev-default", &containerutils.Informers{}, nil)
+	ctrlStopCh, err := gslbingestion.RegisterMemberController(&ctrl, stopCh)
+	if err != nil {
+		t.Fatalf("unexpected error in registering the member controller: %v", err)
+	}
+	defer gslbingestion.DeregisterMemberController("dev-default")
+
+	// the user of dev-default and the server of exp-scratch change, the cluster not initialized yet
+	// just picks up the new credentials
+	rotated := strings.Replace(kubeconfig, "user: developer", "user: missing-user", 1)
+	rotated = strings.Replace(rotated, "https://10.52.3.70:8443", "https://10.52.3.71:8443", 1)
+	changed, err := gslbingestion.GetChangedMemberClusters(rotated)
+	if err != nil || !reflect.DeepEqual(changed, []string{"dev-default", "exp-scratch"}) {
+		t.Fatalf("expected dev-default and exp-scratch to be changed, got %v, error: %v", changed, err)
+	}
+	reloaded, err := gslbingestion.ReloadMemberClusters(rotated)
+	if err == nil || len(reloaded) != 0 {
+		t.Fatalf("expected dev-default to fail the reload, reloaded: %v, error: %v", reloaded, err)
+	}
+	if registered, ok := gslbingestion.GetMemberController("dev-default"); !ok || registered != &ctrl {
+		t.Fatalf("expected the existing member controller to be retained")
+	}
+	if isStopped(ctrlStopCh) {
+		t.Fatalf("expected the existing member controller to keep running")
+	}
+	// the failed reload is tried again with the next kubeconfig
+	changed, err = gslbingestion.GetChangedMemberClusters(rotated)
+	if err != nil || !reflect.DeepEqual(changed, []string{"dev-default"}) {
+		t.Fatalf("expected only dev-default to be changed, got %v, error: %v", changed, err)
+	}
+}
+
+// Unit test to see if a member controller is replaced only after the informers of the new controller
+// sync, and that the existing controller keeps running if they don't.
+func TestReplaceMemberController(t *testing.T) {
+	cname := "replace-cluster"
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+
+	unregistered := gslbingestion.GetGSLBMemberController(cname, &containerutils.Informers{}, nil)
+	if err := gslbingestion.ReplaceMemberController(&unregistered, stopCh, time.Second); err == nil {
+		t.Fatalf("expected an error in replacing the controller of a cluster without one")
+	}
+
+	ctrl := gslbingestion.GetGSLBMemberController(cname, &containerutils.Informers{}, nil)
+	ctrlStopCh, err := gslbingestion.RegisterMemberController(&ctrl, stopCh)
+	if err != nil {
+		t.Fatalf("unexpected error in registering the member controller: %v", err)
+	}
+	defer gslbingestion.DeregisterMemberController(cname)
+
+	unsynced := gslbingestion.GetGSLBMemberController(cname, &containerutils.Informers{}, nil)
+	unsynced.SetGatewayInformers(getTestGatewayInformers(false))
+	if err := gslbingestion.ReplaceMemberController(&unsynced, stopCh, time.Second); err == nil {
+		t.Fatalf("expected an error in replacing the controller with one whose informers don't sync")
+	}
+	if registered, ok := gslbingestion.GetMemberController(cname); !ok || registered != &ctrl {
+		t.Fatalf("expected the existing member controller to be retained")
+	}
+	if isStopped(ctrlStopCh) {
+		t.Fatalf("expected the existing member controller to keep running")
+	}
+
+	synced := gslbingestion.GetGSLBMemberController(cname, &containerutils.Informers{}, nil)
+	synced.SetGatewayInformers(getTestGatewayInformers(true))
+	if err := gslbingestion.ReplaceMemberController(&synced, stopCh, 5*time.Second); err != nil {
+		t.Fatalf("unexpected error in replacing the member controller: %v", err)
+	}
+	if registered, ok := gslbingestion.GetMemberController(cname); !ok || registered != &synced {
+		t.Fatalf("expected the new member controller to be registered")
+	}
+	if !isStopped(ctrlStopCh) {
+		t.Fatalf("expected the replaced member controller to be stopped")
+	}
+}
+
+// Unit test to see if the objects of a reloaded cluster which are no longer in the cluster are
+// deleted from the stores, once the new informers sync.
+func TestDeleteStaleClusterObjs(t *testing.T) {
+	cname := "stale-objs-cluster"
+	kubeClient := k8sfake.NewSimpleClientset(&corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{Name: "present-svc", Namespace: "default"},
+		Spec:       corev1.ServiceSpec{Type: corev1.ServiceTypeLoadBalancer},
+	})
+	informerFactory := kubeinformers.NewSharedInformerFactory(kubeClient, 0)
+	svcInformer := informerFactory.Core().V1().Services()
+	svcInformer.Informer()
+	stopCh := make(chan struct{})
+	defer close(stopCh)
+	informerFactory.Start(stopCh)
+	informerFactory.WaitForCacheSync(stopCh)
+
+	acceptedStore := gslbutils.GetAcceptedLBSvcStore()
+	rejectedStore := gslbutils.GetRejectedLBSvcStore()
+	for _, name := range []string{"present-svc", "deleted-svc"} {
+		acceptedStore.AddOrUpdate(k8sobjects.SvcMeta{Name: name, Namespace: "default", Cluster: cname}, cname,
+			"default", name)
+	}
+	rejectedStore.AddOrUpdate(k8sobjects.SvcMeta{Name: "rejected-svc", Namespace: "default", Cluster: cname}, cname,
+		"default", "rejected-svc")
+	defer acceptedStore.DeleteClusterNSObj(cname, "default", "present-svc")
+
+	ctrl := gslbingestion.GetGSLBMemberController(cname, &containerutils.Informers{ServiceInformer: svcInformer}, nil)
+	if deleted := gslbingestion.DeleteStaleClusterObjs(&ctrl); deleted != 2 {
+		t.Fatalf("expected 2 stale objects to be deleted, got %d", deleted)
+	}
+	// only the accepted stale service has a DELETE key
+	buildSvcKeyAndVerify(t, false, "DELETE", cname, "default", "deleted-svc")
+	if _, found := acceptedStore.GetClusterNSObjectByName(cname, "default", "present-svc"); !found {
+		t.Fatalf("expected the service in the cluster to be retained")
+	}
+	if _, found := acceptedStore.GetClusterNSObjectByName(cname, "default", "deleted-svc"); found {
+		t.Fatalf("expected the accepted service not in the cluster to be deleted")
+	}
+	if _, found := rejectedStore.GetClusterNSObjectByName(cname, "default", "rejected-svc"); found {
+		t.Fatalf("expected the rejected service not in the cluster to be deleted")
+	}
+}
+
+func TestInformerErrors(t *testing.T) {
+	cname := "informer-err-cluster"
+	status := http.StatusForbidden
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(`{"kind":"ServiceList","apiVersion":"v1","metadata":{},"items":[]}`))
+			return
+		}
+		w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","code":` + strconv.Itoa(status) + `}`))
+	}))
+	defer server.Close()
+	defer gslbutils.ClearClusterInformerErrors(cname)
+
+	cfg := &restclient.Config{Host: server.URL}
+	gslbingestion.ObserveInformerErrors(cfg, cname)
+	kubeClient, err := kubernetes.NewForConfig(cfg)
+	if err != nil {
+		t.Fatalf("error in creating the kube client: %v", err)
+	}
+
+	// a denied list of the services is recorded for the service informer
+	kubeClient.CoreV1().Services("").List(metav1.ListOptions{})
+	expected := []gslbutils.InformerError{{Cluster: cname, Informer: "service", Error: "forbidden"}}
+	if errs := gslbutils.GetInformerErrors(); !reflect.DeepEqual(errs, expected) {
+		t.Fatalf("expected the informer errors %v, got %v", expected, errs)
+	}
+	if msg := expected[0].String(); msg != "service informer for "+cname+": forbidden" {
+		t.Fatalf("unexpected informer error message: %s", msg)
+	}
+	// the requests other than the lists and the watches of the informers are ignored
+	kubeClient.CoreV1().Endpoints("default").Get("svc1", metav1.GetOptions{})
+	if errs := gslbutils.GetInformerErrors(); len(errs) != 1 {
+		t.Fatalf("expected only the service informer error, got %v", errs)
+	}
+
+	// the error is cleared once the informer lists the services successfully
+	status = http.StatusOK
+	if _, err := kubeClient.CoreV1().Services("").List(metav1.ListOptions{}); err != nil {
+		t.Fatalf("unexpected error in listing the services: %v", err)
+	}
+	if errs := gslbutils.GetInformerErrors(); len(errs) != 0 {
+		t.Fatalf("expected no informer errors, got %v", errs)
+	}
+}
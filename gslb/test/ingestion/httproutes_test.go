@@ -0,0 +1,151 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package ingestion
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	gslbingestion "github.com/avinetworks/amko/gslb/ingestion"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gwv1 "github.com/avinetworks/amko/internal/apis/gateway/v1"
+
+	containerutils "github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	k8sfake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+// getFakeInformer returns an informer backed by an empty list and a fake watcher, which is used to
+// send the events for the objects.
+func getFakeInformer(objType runtime.Object, emptyList runtime.Object) (cache.SharedIndexInformer, *watch.FakeWatcher) {
+	watcher := watch.NewFake()
+	lw := &cache.ListWatch{
+		ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+			return emptyList, nil
+		},
+		WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+			return watcher, nil
+		},
+	}
+	return cache.NewSharedIndexInformer(lw, objType, 0, cache.Indexers{}), watcher
+}
+
+// getTestGatewayInformers returns fake Gateway API informers, if listable is false the informers
+// can't list the objects and never sync.
+func getTestGatewayInformers(listable bool) *gslbingestion.GatewayInformers {
+	gwInformer, _ := getFakeInformer(&gwv1.Gateway{}, &gwv1.GatewayList{})
+	routeInformer, _ := getFakeInformer(&gwv1.HTTPRoute{}, &gwv1.HTTPRouteList{})
+	if !listable {
+		lw := &cache.ListWatch{
+			ListFunc: func(options metav1.ListOptions) (runtime.Object, error) {
+				return nil, errors.New("forbidden")
+			},
+			WatchFunc: func(options metav1.ListOptions) (watch.Interface, error) {
+				return nil, errors.New("forbidden")
+			},
+		}
+		routeInformer = cache.NewSharedIndexInformer(lw, &gwv1.HTTPRoute{}, 0, cache.Indexers{})
+	}
+	return &gslbingestion.GatewayInformers{GatewayInformer: gwInformer, HTTPRouteInformer: routeInformer}
+}
+
+func getTestGateway(name, ns, ipAddr string) *gwv1.Gateway {
+	return &gwv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, ResourceVersion: ipAddr},
+		Spec: gwv1.GatewaySpec{
+			GatewayClassName: "avi",
+			Listeners:        []gwv1.Listener{{Name: "http", Port: 80, Protocol: "HTTP"}},
+		},
+		Status: gwv1.GatewayStatus{Addresses: []gwv1.GatewayStatusAddress{{Value: ipAddr}}},
+	}
+}
+
+func getTestHTTPRoute(name, ns, gwName, host string, labels map[string]string) *gwv1.HTTPRoute {
+	return &gwv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: ns, Labels: labels},
+		Spec: gwv1.HTTPRouteSpec{
+			ParentRefs: []gwv1.ParentReference{{Name: gwName}},
+			Hostnames:  []string{host},
+		},
+	}
+}
+
+func buildHTTPRouteKeyAndVerify(t *testing.T, timeoutExpected bool, op, cname, ns, name, host string) {
+	key := gslbutils.MultiClusterKey(op, gslbutils.HTTPRouteType, cname, ns, name+"/"+host)
+	passed, errStr := waitAndVerify(t, []string{key}, timeoutExpected)
+	if !passed {
+		t.Fatal(errStr)
+	}
+}
+
+func TestHTTPRouteCUD(t *testing.T) {
+	cname := "cluster1"
+	ns := "default"
+	routeName := "gw-route"
+	host := "gw-" + TestDomain1
+
+	gdp := addGDPAndGSLBForIngress(t)
+	defer DeleteTestGDPObj(gdp)
+
+	gwInformer, gwWatcher := getFakeInformer(&gwv1.Gateway{}, &gwv1.GatewayList{})
+	routeInformer, routeWatcher := getFakeInformer(&gwv1.HTTPRoute{}, &gwv1.HTTPRouteList{})
+	gslbingestion.DeregisterMemberController(cname)
+	ctrl := gslbingestion.GetGSLBMemberController(cname, &containerutils.Informers{}, nil)
+	ctrl.SetGatewayInformers(&gslbingestion.GatewayInformers{GatewayInformer: gwInformer,
+		HTTPRouteInformer: routeInformer})
+	ctrl.SetupEventHandlers(gslbingestion.K8SInformers{Cs: k8sfake.NewSimpleClientset()})
+	if err := ctrl.Start(testStopCh); err != nil {
+		t.Fatalf("error in starting the member controller: %v", err)
+	}
+
+	// a route without a gateway has no IP address, so no keys are published
+	route := getTestHTTPRoute(routeName, ns, "gw1", host, map[string]string{"key": "value"})
+	routeWatcher.Add(route)
+	buildHTTPRouteKeyAndVerify(t, true, "ADD", cname, ns, routeName, host)
+
+	// the route's host is added once the gateway has an address
+	gwWatcher.Add(getTestGateway("gw1", ns, "10.10.10.10"))
+	buildHTTPRouteKeyAndVerify(t, false, "ADD", cname, ns, routeName, host)
+	obj, ok := gslbutils.GetAcceptedHTTPRouteStore().GetClusterNSObjectByName(cname, ns, routeName+"/"+host)
+	if !ok {
+		t.Fatalf("expected the HTTPRoute host in the accepted store")
+	}
+	if hrh := obj.(k8sobjects.HTTPRouteHostMeta); hrh.IPAddr != "10.10.10.10" {
+		t.Fatalf("expected the IP address of the gateway, got %s", hrh.IPAddr)
+	}
+
+	// a change in the gateway address updates the host
+	gwWatcher.Modify(getTestGateway("gw1", ns, "10.10.10.11"))
+	buildHTTPRouteKeyAndVerify(t, false, "UPDATE", cname, ns, routeName, host)
+
+	// a route which doesn't pass the filter is moved to the rejected store
+	routeWatcher.Modify(getTestHTTPRoute(routeName, ns, "gw1", host, map[string]string{"key": "other"}))
+	buildHTTPRouteKeyAndVerify(t, false, "DELETE", cname, ns, routeName, host)
+	if _, ok := gslbutils.GetRejectedHTTPRouteStore().GetClusterNSObjectByName(cname, ns, routeName+"/"+host); !ok {
+		t.Fatalf("expected the HTTPRoute host in the rejected store")
+	}
+
+	routeWatcher.Modify(route)
+	buildHTTPRouteKeyAndVerify(t, false, "ADD", cname, ns, routeName, host)
+	routeWatcher.Delete(route)
+	buildHTTPRouteKeyAndVerify(t, false, "DELETE", cname, ns, routeName, host)
+	if _, ok := gslbutils.GetAcceptedHTTPRouteStore().GetClusterNSObjectByName(cname, ns, routeName+"/"+host); ok {
+		t.Fatalf("expected the HTTPRoute host to be deleted from the accepted store")
+	}
+}
@@ -161,8 +161,12 @@ func addGSLBTestConfigObject(obj interface{}) {
 
 	fooRegisteredInformers := []string{containerutils.RouteInformer, containerutils.IngressInformer, containerutils.ServiceInformer}
 	fooInformerInstance := containerutils.NewInformers(containerutils.KubeClientIntf{fooKubeClient}, fooRegisteredInformers, fooInformersArg)
-	fooCtrl := gslbingestion.GetGSLBMemberController("cluster1", fooInformerInstance)
-	fooCtrl.Start(testStopCh)
+	// stop the controller of an earlier test, a cluster can have only one running controller
+	gslbingestion.DeregisterMemberController("cluster1")
+	fooCtrl := gslbingestion.GetGSLBMemberController("cluster1", fooInformerInstance, nil)
+	if err := fooCtrl.Start(testStopCh); err != nil {
+		panic(err)
+	}
 	fooCtrl.SetupEventHandlers(gslbingestion.K8SInformers{fooKubeClient})
 
 	// Initialize a bar kube client
@@ -174,13 +178,16 @@ func addGSLBTestConfigObject(obj interface{}) {
 
 	barRegisteredInformers := []string{containerutils.RouteInformer, containerutils.IngressInformer, containerutils.ServiceInformer}
 	barInformerInstance := containerutils.NewInformers(containerutils.KubeClientIntf{barKubeClient}, barRegisteredInformers, barInformersArg)
-	barCtrl := gslbingestion.GetGSLBMemberController("cluster2", barInformerInstance)
-	barCtrl.Start(testStopCh)
+	gslbingestion.DeregisterMemberController("cluster2")
+	barCtrl := gslbingestion.GetGSLBMemberController("cluster2", barInformerInstance, nil)
+	if err := barCtrl.Start(testStopCh); err != nil {
+		panic(err)
+	}
 	barCtrl.SetupEventHandlers(gslbingestion.K8SInformers{barKubeClient})
 }
 
 func GetIngressKey(op, cname, ns, name, host string) string {
-	return op + "/" + gslbutils.IngressType + "/" + cname + "/" + ns + "/" + name + "/" + host
+	return gslbutils.MultiClusterKey(op, gslbutils.IngressType, cname, ns, name+"/"+host)
 }
 
 func buildIngressKeyAndVerify(t *testing.T, timeoutExpected bool, op, cname, ns, name, hostname string) {
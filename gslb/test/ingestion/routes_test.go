@@ -194,6 +194,74 @@ func TestBasicRouteCUD(t *testing.T) {
 	DeleteTestGDPObj(gdp)
 }
 
+func TestRouteNoOpUpdate(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testPrefix := "rnu-"
+	routeName := testPrefix + "def-route"
+	ns := "default"
+	host := testPrefix + TestDomain1
+	ipAddr := "10.10.20.20"
+	cname := "cluster1"
+
+	gdp := addGDPAndGSLBForIngress(t)
+
+	route := ocAddRoute(t, fooOshiftClient, routeName, ns, TestSvc, cname, host, ipAddr)
+	buildRouteKeyAndVerify(t, false, "ADD", cname, ns, routeName)
+	verifyInRouteStore(g, acceptedRouteStore, true, routeName, ns, cname, host, ipAddr)
+
+	// an update which doesn't change any of the fields of the route meta mustn't publish a key
+	t.Log("updating an annotation of the route")
+	route.Annotations = map[string]string{"unrelated": "value"}
+	ocUpdateRoute(t, fooOshiftClient, ns, cname, route)
+	buildRouteKeyAndVerify(t, true, "UPDATE", cname, ns, routeName)
+	verifyInRouteStore(g, acceptedRouteStore, true, routeName, ns, cname, host, ipAddr)
+
+	ocDeleteRoute(t, fooOshiftClient, routeName, ns)
+	buildRouteKeyAndVerify(t, false, "DELETE", cname, ns, routeName)
+
+	DeleteTestGDPObj(gdp)
+}
+
+func TestRouteHostnameChange(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testPrefix := "rhc-"
+	routeName := testPrefix + "def-route"
+	ns := "default"
+	host := testPrefix + TestDomain1
+	ipAddr := "10.10.20.20"
+	cname := "cluster1"
+
+	gdp := addGDPAndGSLBForIngress(t)
+
+	t.Log("adding and testing route")
+	route := ocAddRoute(t, fooOshiftClient, routeName, ns, TestSvc, cname, host, ipAddr)
+	buildRouteKeyAndVerify(t, false, "ADD", cname, ns, routeName)
+	verifyInRouteStore(g, acceptedRouteStore, true, routeName, ns, cname, host, ipAddr)
+
+	// the graph layer isn't running in these tests, so update the host map as the graph layer
+	// would have done after processing the ADD key
+	routeMeta := k8sobjects.GetRouteMeta(route, cname)
+	routeMeta.UpdateHostMap(cname + "/" + ns + "/" + routeName)
+
+	newHost := testPrefix + TestDomain2
+	route.Spec.Host = newHost
+	route.Status.Ingress[0].Host = newHost
+
+	t.Log("updating route hostname")
+	ocUpdateRoute(t, fooOshiftClient, ns, cname, route)
+	// a DELETE key for the old hostname must be published before the UPDATE key
+	buildRouteKeyAndVerify(t, false, "DELETE", cname, ns, routeName)
+	buildRouteKeyAndVerify(t, false, "UPDATE", cname, ns, routeName)
+	verifyInRouteStore(g, acceptedRouteStore, true, routeName, ns, cname, newHost, ipAddr)
+	routeMeta.DeleteMapByKey(cname + "/" + ns + "/" + routeName)
+
+	// delete and verify
+	ocDeleteRoute(t, fooOshiftClient, routeName, ns)
+	buildRouteKeyAndVerify(t, false, "DELETE", cname, ns, routeName)
+
+	DeleteTestGDPObj(gdp)
+}
+
 func TestBasicRouteLabelChange(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	testPrefix := "rlu-"
@@ -90,6 +90,124 @@ func TestSvcWithoutHostInStatus(t *testing.T) {
 	DeleteTestGDPObj(gdp)
 }
 
+func TestSvcWithOnlyHostnameInStatus(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testPrefix := "ohis-"
+	svcName := testPrefix + "def-svc"
+	ns := "default"
+	host := testPrefix + TestDomain1
+	cname := "cluster1"
+
+	gdp := addGDPAndGSLBForSvc(t)
+	// Add a service for which the load balancer only exposes a hostname
+	t.Log("Adding and testing service")
+	K8sAddSvc(t, fooKubeClient, svcName, ns, cname, host, "", corev1.ServiceTypeLoadBalancer)
+	buildSvcKeyAndVerify(t, false, "ADD", cname, ns, svcName)
+
+	verifyInSvcStore(g, acceptedSvcStore, true, svcName, ns, cname, host, "")
+	obj, _ := gslbutils.GetAcceptedLBSvcStore().GetClusterNSObjectByName(cname, ns, svcName)
+	g.Expect(obj.(k8sobjects.SvcMeta).LBHostname).To(gomega.Equal(host))
+
+	K8sDeleteSvc(t, fooKubeClient, svcName, ns)
+	buildSvcKeyAndVerify(t, false, "DELETE", cname, ns, svcName)
+	verifyInSvcStore(g, acceptedSvcStore, false, svcName, ns, cname, host, "")
+	DeleteTestGDPObj(gdp)
+}
+
+func TestSvcMetaIPAndHostname(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	cname := "cluster1"
+	host := "iah-" + TestDomain1
+	ipAddr := "10.10.10.10"
+
+	// both IP and hostname present, IP is the member address
+	svcObj := BuildSvcObj("iah-svc", "default", cname, host, ipAddr, true, corev1.ServiceTypeLoadBalancer)
+	svcMeta, ok := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(ok).To(gomega.Equal(true))
+	g.Expect(svcMeta.IPAddr).To(gomega.Equal(ipAddr))
+	g.Expect(svcMeta.LBHostname).To(gomega.Equal(""))
+
+	// only the hostname present
+	svcObj.Status.LoadBalancer.Ingress[0].IP = ""
+	hostOnlyMeta, ok := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(ok).To(gomega.Equal(true))
+	g.Expect(hostOnlyMeta.LBHostname).To(gomega.Equal(host))
+	g.Expect(hostOnlyMeta.GetSvcCksum()).NotTo(gomega.Equal(svcMeta.GetSvcCksum()))
+
+	// a change in the hostname should change the checksum
+	svcObj.Status.LoadBalancer.Ingress[0].Hostname = "iah-" + TestDomain2
+	newHostMeta, _ := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(newHostMeta.GetSvcCksum()).NotTo(gomega.Equal(hostOnlyMeta.GetSvcCksum()))
+}
+
+func TestSvcMetaExternalTrafficPolicy(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	cname := "cluster1"
+	svcObj := BuildSvcObj("etp-svc", "default", cname, "etp-"+TestDomain1, "10.10.10.10", true,
+		corev1.ServiceTypeLoadBalancer)
+
+	// the policy defaults to Cluster
+	svcMeta, ok := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(ok).To(gomega.Equal(true))
+	g.Expect(svcMeta.GetExternalTrafficPolicy()).To(gomega.Equal(string(corev1.ServiceExternalTrafficPolicyTypeCluster)))
+	g.Expect(svcMeta.IsLocalTrafficPolicy()).To(gomega.Equal(false))
+
+	// a change in the policy should change the checksum
+	svcObj.Spec.ExternalTrafficPolicy = corev1.ServiceExternalTrafficPolicyTypeLocal
+	localMeta, ok := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(ok).To(gomega.Equal(true))
+	var tpObj k8sobjects.TrafficPolicyObject = localMeta
+	g.Expect(tpObj.GetExternalTrafficPolicy()).To(gomega.Equal(string(corev1.ServiceExternalTrafficPolicyTypeLocal)))
+	g.Expect(tpObj.IsLocalTrafficPolicy()).To(gomega.Equal(true))
+	g.Expect(localMeta.GetSvcCksum()).NotTo(gomega.Equal(svcMeta.GetSvcCksum()))
+}
+
+func TestSvcMetaPortChange(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	cname := "cluster1"
+	svcObj := BuildSvcObj("pc-svc", "default", cname, "pc-"+TestDomain1, "10.10.10.10", true,
+		corev1.ServiceTypeLoadBalancer)
+	svcMeta, ok := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(ok).To(gomega.Equal(true))
+	g.Expect(svcMeta.IsPortOnlyChange(svcMeta)).To(gomega.Equal(false))
+
+	// a change in the port should change the checksum, and is a port only change
+	svcObj.Spec.Ports[0].Port = 8080
+	portMeta, _ := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(portMeta.GetSvcCksum()).NotTo(gomega.Equal(svcMeta.GetSvcCksum()))
+	g.Expect(portMeta.GetSvcPortCksum()).NotTo(gomega.Equal(svcMeta.GetSvcPortCksum()))
+	g.Expect(portMeta.IsPortOnlyChange(svcMeta)).To(gomega.Equal(true))
+
+	// a change in the port along with the IP address isn't a port only change
+	svcObj.Status.LoadBalancer.Ingress[0].IP = "10.10.10.11"
+	ipMeta, _ := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(ipMeta.IsPortOnlyChange(svcMeta)).To(gomega.Equal(false))
+	// nor is a change in the IP address alone
+	g.Expect(ipMeta.GetSvcPortCksum()).To(gomega.Equal(portMeta.GetSvcPortCksum()))
+	g.Expect(ipMeta.IsPortOnlyChange(portMeta)).To(gomega.Equal(false))
+}
+
+func TestSvcMetaVIPs(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	cname := "cluster1"
+	svcObj := BuildSvcObj("vips-svc", "default", cname, "vips-"+TestDomain1, "10.10.10.10", true,
+		corev1.ServiceTypeLoadBalancer)
+	svcMeta, ok := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(ok).To(gomega.Equal(true))
+	g.Expect(svcMeta.GetVIPs()).To(gomega.Equal([]gslbutils.VIP{{IP: "10.10.10.10", Weight: 1}}))
+
+	// all the IP addresses of the load balancer are the VIPs, weighted as per the annotation
+	svcObj.Status.LoadBalancer.Ingress = append(svcObj.Status.LoadBalancer.Ingress,
+		corev1.LoadBalancerIngress{IP: "10.10.10.11"}, corev1.LoadBalancerIngress{IP: "10.10.10.10"})
+	svcObj.Annotations = map[string]string{gslbutils.VIPWeightsAnnotation: "10.10.10.11=3"}
+	multiMeta, ok := k8sobjects.GetSvcMeta(svcObj, cname)
+	g.Expect(ok).To(gomega.Equal(true))
+	var vipObj k8sobjects.MultiVIPObject = multiMeta
+	g.Expect(vipObj.GetVIPs()).To(gomega.Equal([]gslbutils.VIP{{IP: "10.10.10.10", Weight: 1}, {IP: "10.10.10.11", Weight: 3}}))
+	g.Expect(multiMeta.GetIPAddr()).To(gomega.Equal("10.10.10.10"))
+	g.Expect(multiMeta.GetSvcCksum()).NotTo(gomega.Equal(svcMeta.GetSvcCksum()))
+}
+
 func TestSvcWithLabelNotSelected(t *testing.T) {
 	testPrefix := "lns-"
 	svcName := testPrefix + "def-svc"
@@ -150,6 +268,35 @@ func TestBasicSvcCUD(t *testing.T) {
 	DeleteTestGDPObj(gdp)
 }
 
+func TestSvcPortOnlyUpdate(t *testing.T) {
+	testPrefix := "pou-"
+	svcName := testPrefix + "def-svc"
+	ns := "default"
+	host := testPrefix + TestDomain1
+	cname := "cluster1"
+
+	gdp := addGDPAndGSLBForSvc(t)
+	svcObj := K8sAddSvc(t, fooKubeClient, svcName, ns, cname, host, "10.10.10.10", corev1.ServiceTypeLoadBalancer)
+	buildSvcKeyAndVerify(t, false, gslbutils.ObjectAdd, cname, ns, svcName)
+
+	// a change in only the port of an accepted service is a port update
+	svcObj.Spec.Ports[0].Port = 8080
+	svcObj.ResourceVersion = "101"
+	K8sUpdateSvc(t, fooKubeClient, ns, cname, svcObj)
+	buildSvcKeyAndVerify(t, false, gslbutils.ObjectPortUpdate, cname, ns, svcName)
+
+	// any other change is an update as usual
+	svcObj.Spec.Ports[0].Port = 8443
+	svcObj.Status.LoadBalancer.Ingress[0].IP = "10.10.10.11"
+	svcObj.ResourceVersion = "102"
+	K8sUpdateSvc(t, fooKubeClient, ns, cname, svcObj)
+	buildSvcKeyAndVerify(t, false, gslbutils.ObjectUpdate, cname, ns, svcName)
+
+	K8sDeleteSvc(t, fooKubeClient, svcName, ns)
+	buildSvcKeyAndVerify(t, false, gslbutils.ObjectDelete, cname, ns, svcName)
+	DeleteTestGDPObj(gdp)
+}
+
 func TestSvcToNoHost(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	testPrefix := "tnh-"
@@ -181,6 +328,113 @@ func TestSvcToNoHost(t *testing.T) {
 	DeleteTestGDPObj(gdp)
 }
 
+// A service with a deletion timestamp still has its IP in the status until its finalizers are
+// removed, its GSLB member must be deleted.
+func TestSvcBeingDeleted(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	testPrefix := "sbd-"
+	svcName := testPrefix + "def-svc"
+	ns := "default"
+	host := testPrefix + TestDomain1
+	ipAddr := "10.10.10.10"
+	cname := "cluster1"
+
+	gdp := addGDPAndGSLBForSvc(t)
+
+	t.Log("Adding and testing service")
+	svcObj := K8sAddSvc(t, fooKubeClient, svcName, ns, cname, host, ipAddr, corev1.ServiceTypeLoadBalancer)
+	buildSvcKeyAndVerify(t, false, "ADD", cname, ns, svcName)
+	verifyInSvcStore(g, acceptedSvcStore, true, svcName, ns, cname, host, ipAddr)
+
+	t.Log("Marking the service for deletion")
+	now := metav1.Now()
+	svcObj.ObjectMeta.DeletionTimestamp = &now
+	svcObj.ObjectMeta.Finalizers = []string{"service.kubernetes.io/load-balancer-cleanup"}
+	svcObj.ResourceVersion = "101"
+	K8sUpdateSvc(t, fooKubeClient, ns, cname, svcObj)
+	buildSvcKeyAndVerify(t, false, "DELETE", cname, ns, svcName)
+	verifyInSvcStore(g, acceptedSvcStore, false, svcName, ns, cname, host, ipAddr)
+	verifyInSvcStore(g, rejectedSvcStore, true, svcName, ns, cname, host, ipAddr)
+
+	K8sDeleteSvc(t, fooKubeClient, svcName, ns)
+	buildSvcKeyAndVerify(t, false, "DELETE", cname, ns, svcName)
+	verifyInSvcStore(g, rejectedSvcStore, false, svcName, ns, cname, host, ipAddr)
+
+	t.Log("Adding a service which is being deleted")
+	svcObj = BuildSvcObj(svcName, ns, cname, host, ipAddr, true, corev1.ServiceTypeLoadBalancer)
+	svcObj.ObjectMeta.DeletionTimestamp = &now
+	svcObj.ObjectMeta.Finalizers = []string{"service.kubernetes.io/load-balancer-cleanup"}
+	if _, err := fooKubeClient.CoreV1().Services(ns).Create(svcObj); err != nil {
+		t.Fatalf("error in creating service: %v", err)
+	}
+	buildSvcKeyAndVerify(t, true, "ADD", cname, ns, svcName)
+	verifyInSvcStore(g, rejectedSvcStore, true, svcName, ns, cname, host, ipAddr)
+
+	K8sDeleteSvc(t, fooKubeClient, svcName, ns)
+	buildSvcKeyAndVerify(t, false, "DELETE", cname, ns, svcName)
+	DeleteTestGDPObj(gdp)
+}
+
+func TestSvcBackendsWeightMode(t *testing.T) {
+	testPrefix := "sbw-"
+	svcName := testPrefix + "def-svc"
+	ns := "default"
+	host := testPrefix + TestDomain1
+	ipAddr := "10.10.10.10"
+	cname := "cluster1"
+
+	ingestionQ := utils.SharedWorkQueue().GetQueueByName(utils.ObjectIngestionLayer)
+	gdp := getTestGDPObject(true, false)
+	gdp.Spec.WeightMode = gslbalphav1.WeightModeBackends
+	gslbingestion.AddGDPObj(gdp, ingestionQ.Workqueue, 2)
+	gc, err := gslbingestion.IsGSLBConfigValid(getTestGSLBObject())
+	if err != nil {
+		t.Fatal("GSLB object invalid")
+	}
+	addGSLBTestConfigObject(gc)
+
+	K8sAddSvc(t, fooKubeClient, svcName, ns, cname, host, ipAddr, corev1.ServiceTypeLoadBalancer)
+	buildSvcKeyAndVerify(t, false, "ADD", cname, ns, svcName)
+
+	endpoints := &corev1.Endpoints{
+		ObjectMeta: metav1.ObjectMeta{Name: svcName, Namespace: ns},
+		Subsets: []corev1.EndpointSubset{{
+			Addresses: []corev1.EndpointAddress{{IP: "192.168.1.1"}, {IP: "192.168.1.2"}},
+		}},
+	}
+	if _, err := fooKubeClient.CoreV1().Endpoints(ns).Create(endpoints); err != nil {
+		t.Fatalf("error in creating endpoints: %v", err)
+	}
+
+	t.Log("Recomputing the backend weights")
+	gslbingestion.RecomputeBackendWeights(ingestionQ.Workqueue, 2)
+	buildSvcKeyAndVerify(t, false, gslbutils.ObjectRatioUpdate, cname, ns, svcName)
+	gf := gslbutils.GetGlobalFilter()
+	if count, ok := gf.GetBackendCount(cname); !ok || count != 2 {
+		t.Fatalf("expected 2 ready backends for %s, got %d, %v", cname, count, ok)
+	}
+
+	t.Log("Recomputing the unchanged backend weights")
+	gslbingestion.RecomputeBackendWeights(ingestionQ.Workqueue, 2)
+	buildSvcKeyAndVerify(t, true, gslbutils.ObjectRatioUpdate, cname, ns, svcName)
+
+	t.Log("Removing a ready backend")
+	endpoints.Subsets[0].Addresses = endpoints.Subsets[0].Addresses[:1]
+	if _, err := fooKubeClient.CoreV1().Endpoints(ns).Update(endpoints); err != nil {
+		t.Fatalf("error in updating endpoints: %v", err)
+	}
+	gslbingestion.RecomputeBackendWeights(ingestionQ.Workqueue, 2)
+	buildSvcKeyAndVerify(t, false, gslbutils.ObjectRatioUpdate, cname, ns, svcName)
+	if count, _ := gf.GetBackendCount(cname); count != 1 {
+		t.Fatalf("expected 1 ready backend for %s, got %d", cname, count)
+	}
+
+	fooKubeClient.CoreV1().Endpoints(ns).Delete(svcName, nil)
+	K8sDeleteSvc(t, fooKubeClient, svcName, ns)
+	buildSvcKeyAndVerify(t, false, "DELETE", cname, ns, svcName)
+	DeleteTestGDPObj(gdp)
+}
+
 func TestSvcToDiffLabel(t *testing.T) {
 	g := gomega.NewGomegaWithT(t)
 	testPrefix := "dl-"
@@ -0,0 +1,103 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package k8sobjects
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gwv1 "github.com/avinetworks/amko/internal/apis/gateway/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func getTestGatewayGetter(gateways ...*gwv1.Gateway) k8sobjects.GatewayGetter {
+	return func(ns, name string) (*gwv1.Gateway, bool) {
+		for _, gw := range gateways {
+			if gw.Namespace == ns && gw.Name == name {
+				return gw, true
+			}
+		}
+		return nil, false
+	}
+}
+
+func TestHTTPRouteHostMeta(t *testing.T) {
+	gw := &gwv1.Gateway{
+		ObjectMeta: metav1.ObjectMeta{Name: "gw1", Namespace: "infra"},
+		Spec: gwv1.GatewaySpec{
+			Listeners: []gwv1.Listener{
+				{Name: "http", Port: 80, Protocol: "HTTP"},
+				{Name: "https", Port: 443, Protocol: gwv1.HTTPSProtocolType, Hostname: strPtr("*.secure.avi.com")},
+			},
+		},
+		Status: gwv1.GatewayStatus{Addresses: []gwv1.GatewayStatusAddress{
+			{Type: strPtr("Hostname"), Value: "gw1.avi.com"},
+			{Type: strPtr(gwv1.AddressTypeIPAddress), Value: "10.10.10.1"},
+		}},
+	}
+	route := &gwv1.HTTPRoute{
+		ObjectMeta: metav1.ObjectMeta{Name: "route1", Namespace: TestNS, Labels: map[string]string{"app": "gslb"}},
+		Spec: gwv1.HTTPRouteSpec{
+			ParentRefs: []gwv1.ParentReference{{Name: "gw1", Namespace: strPtr("infra")}},
+			Hostnames:  []string{"app.avi.com", "app.secure.avi.com", "*.avi.com", "app.avi.com"},
+			Rules: []gwv1.HTTPRouteRule{
+				{Matches: []gwv1.HTTPRouteMatch{{Path: &gwv1.HTTPPathMatch{Value: strPtr("/foo")}}}},
+				{Matches: []gwv1.HTTPRouteMatch{{Path: &gwv1.HTTPPathMatch{Value: strPtr("/bar")}}, {}}},
+			},
+		},
+	}
+
+	hrhs := k8sobjects.GetHTTPRouteHostMeta(route, getTestGatewayGetter(gw), TestCluster)
+	if len(hrhs) != 2 {
+		t.Fatalf("expected 2 hosts, the wildcard and the duplicate hosts must be skipped, got %v", hrhs)
+	}
+	for _, hrh := range hrhs {
+		if hrh.IPAddr != "10.10.10.1" || !hrh.IsReady() {
+			t.Fatalf("expected a ready host with the IP address of the gateway, got %v", hrh)
+		}
+		if !reflect.DeepEqual(hrh.Paths, []string{"/foo", "/bar"}) {
+			t.Fatalf("expected the paths /foo and /bar, got %v", hrh.Paths)
+		}
+		if hrh.GetName() != "route1/"+hrh.Hostname || hrh.GetLabels()["app"] != "gslb" {
+			t.Fatalf("unexpected name or labels for the host: %v", hrh)
+		}
+	}
+	if hrhs[0].Hostname != "app.avi.com" || hrhs[0].TLS {
+		t.Fatalf("expected a non-TLS host app.avi.com, got %v", hrhs[0])
+	}
+	if hrhs[1].Hostname != "app.secure.avi.com" || !hrhs[1].TLS {
+		t.Fatalf("expected a TLS host app.secure.avi.com, got %v", hrhs[1])
+	}
+
+	// a route attached to a gateway without an address isn't ready
+	hrhs = k8sobjects.GetHTTPRouteHostMeta(route, getTestGatewayGetter(), TestCluster)
+	if len(hrhs) != 2 || hrhs[0].IPAddr != "" || hrhs[0].IsReady() {
+		t.Fatalf("expected the hosts without an IP address, got %v", hrhs)
+	}
+
+	// a route without hostnames inherits the non-wildcard hostnames of the listeners
+	gw.Spec.Listeners[0].Hostname = strPtr("listener.avi.com")
+	route.Spec.Hostnames = nil
+	route.Spec.ParentRefs[0].SectionName = strPtr("http")
+	hrhs = k8sobjects.GetHTTPRouteHostMeta(route, getTestGatewayGetter(gw), TestCluster)
+	if len(hrhs) != 1 || hrhs[0].Hostname != "listener.avi.com" {
+		t.Fatalf("expected the hostname of the listener, got %v", hrhs)
+	}
+}
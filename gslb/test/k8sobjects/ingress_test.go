@@ -0,0 +1,477 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package k8sobjects
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/api/networking/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+const (
+	TestCluster = "cluster1"
+	TestNS      = "default"
+	NumHosts    = 10
+)
+
+func getTestIngress(name string, numHosts int) *v1beta1.Ingress {
+	ing := &v1beta1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: TestNS,
+			Labels:    map[string]string{"key": "value", "app": "gslb"},
+		},
+	}
+	for i := 0; i < numHosts; i++ {
+		host := "host" + strconv.Itoa(i) + ".avi.com"
+		ing.Spec.Rules = append(ing.Spec.Rules, v1beta1.IngressRule{
+			Host: host,
+			IngressRuleValue: v1beta1.IngressRuleValue{
+				HTTP: &v1beta1.HTTPIngressRuleValue{
+					Paths: []v1beta1.HTTPIngressPath{{Path: "/foo"}, {Path: "/bar"}},
+				},
+			},
+		})
+		ing.Status.LoadBalancer.Ingress = append(ing.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{
+			IP:       "10.10.10." + strconv.Itoa(i+1),
+			Hostname: host,
+		})
+	}
+	ing.Spec.TLS = []v1beta1.IngressTLS{{Hosts: []string{"host0.avi.com"}}}
+	return ing
+}
+
+func TestGetIngressHostMetaInto(t *testing.T) {
+	ing := getTestIngress("ing1", NumHosts)
+	expected := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(expected) != NumHosts {
+		t.Fatalf("expected %d ingress host metas, got %d", NumHosts, len(expected))
+	}
+	if !expected[0].TLS || expected[1].TLS {
+		t.Fatalf("expected only the first host to have TLS, got %v, %v", expected[0].TLS, expected[1].TLS)
+	}
+
+	buf := make([]k8sobjects.IngressHostMeta, 0, NumHosts)
+	ihms := k8sobjects.GetIngressHostMetaInto(buf, ing, TestCluster)
+	if !reflect.DeepEqual(expected, ihms) {
+		t.Fatalf("expected %v, got %v", expected, ihms)
+	}
+	if &ihms[0] != &buf[:1][0] {
+		t.Fatalf("expected the buffer to be reused")
+	}
+
+	// the elements copied from an earlier call must not change when the buffer is reused
+	kept := ihms[0]
+	ihms = k8sobjects.GetIngressHostMetaInto(ihms, getTestIngress("ing2", 1), TestCluster)
+	if len(ihms) != 1 || ihms[0].IngName != "ing2" {
+		t.Fatalf("expected only the host meta of ing2, got %v", ihms)
+	}
+	if !reflect.DeepEqual(kept, expected[0]) {
+		t.Fatalf("expected the copied element to be retained, got %v", kept)
+	}
+
+	// an ingress without any hosts gives an empty list
+	if ihms = k8sobjects.GetIngressHostMetaInto(ihms, &v1beta1.Ingress{}, TestCluster); len(ihms) != 0 {
+		t.Fatalf("expected no host metas, got %v", ihms)
+	}
+
+	// the hosts of an ingress without a status are returned without an IP address and not ready
+	ing = getTestIngress("ing3", NumHosts)
+	admitted := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	ing.Status.LoadBalancer.Ingress = nil
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != NumHosts {
+		t.Fatalf("expected %d ingress host metas, got %v", NumHosts, ihms)
+	}
+	for _, ihm := range ihms {
+		if ihm.IPAddr != "" || ihm.Ready {
+			t.Fatalf("expected the host %s to be without an IP address and not ready, got %v", ihm.Hostname, ihm)
+		}
+	}
+	// a host without an IP address gets a different checksum, so the admission updates the host
+	if ihms[0].GetIngressHostCksum() == admitted[0].GetIngressHostCksum() {
+		t.Fatalf("expected the checksums of the host to differ before and after the admission")
+	}
+}
+
+func BenchmarkGetIngressHostMeta(b *testing.B) {
+	ing := getTestIngress("ing1", NumHosts)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	}
+}
+
+func BenchmarkGetIngressHostMetaInto(b *testing.B) {
+	ing := getTestIngress("ing1", NumHosts)
+	var buf []k8sobjects.IngressHostMeta
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buf = k8sobjects.GetIngressHostMetaInto(buf, ing, TestCluster)
+	}
+}
+
+func TestIngressReady(t *testing.T) {
+	ihms := k8sobjects.GetIngressHostMeta(getTestIngress("ing1", 1), TestCluster)
+	if len(ihms) != 1 || !ihms[0].IsReady() {
+		t.Fatalf("expected an ingress with a status to be ready, got %v", ihms)
+	}
+	notReady := ihms[0]
+	notReady.Ready = false
+	if notReady.GetIngressHostCksum() == ihms[0].GetIngressHostCksum() {
+		t.Fatalf("expected the checksum to change with the ready flag")
+	}
+}
+
+func TestIngressIPSource(t *testing.T) {
+	defer gslbutils.SetIngressIPSource([]string{gslbutils.IngressIPSourceStatus}, "")
+
+	if err := gslbutils.SetIngressIPSource([]string{"spec"}, ""); err == nil {
+		t.Fatalf("expected an error for an unrecognized ingress ip source")
+	}
+	if err := gslbutils.SetIngressIPSource([]string{}, ""); err == nil {
+		t.Fatalf("expected an error for no ingress ip sources")
+	}
+	if err := gslbutils.SetIngressIPSource(gslbutils.ParseIngressIPSource("status, status"), ""); err == nil {
+		t.Fatalf("expected an error for a repeated ingress ip source")
+	}
+
+	ing := getTestIngress("ing1", 2)
+	ing.Annotations = map[string]string{gslbutils.DefaultIngressIPAnnotation: "10.20.20.1"}
+
+	// the status is used by default
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 2 || ihms[0].IPAddr != "10.10.10.1" || ihms[1].IPAddr != "10.10.10.2" {
+		t.Fatalf("expected the addresses from the status, got %v", ihms)
+	}
+
+	if err := gslbutils.SetIngressIPSource(gslbutils.ParseIngressIPSource("annotation,status"), ""); err != nil {
+		t.Fatalf("error in setting the ingress ip source: %s", err.Error())
+	}
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 2 || ihms[0].IPAddr != "10.20.20.1" || ihms[1].IPAddr != "10.20.20.1" {
+		t.Fatalf("expected the address from the annotation for all the hosts, got %v", ihms)
+	}
+
+	// an invalid annotation value falls back to the status
+	ing.Annotations[gslbutils.DefaultIngressIPAnnotation] = "not_an_address"
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 2 || ihms[0].IPAddr != "10.10.10.1" {
+		t.Fatalf("expected the addresses from the status for an invalid annotation, got %v", ihms)
+	}
+
+	// a hostname in the annotation is the hostname of the load balancer of all the hosts
+	ing.Annotations[gslbutils.DefaultIngressIPAnnotation] = "lb.example.com"
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 2 || ihms[0].IPAddr != "" || ihms[0].GetLBHostname() != "lb.example.com" ||
+		ihms[1].GetLBHostname() != "lb.example.com" || !ihms[0].IsReady() {
+		t.Fatalf("expected ready host metas with the load balancer hostname from the annotation, got %v", ihms)
+	}
+
+	// an ingress without a status is ready if the annotation has the address
+	if err := gslbutils.SetIngressIPSource([]string{gslbutils.IngressIPSourceAnnotation}, "vip"); err != nil {
+		t.Fatalf("error in setting the ingress ip source: %s", err.Error())
+	}
+	ing.Status.LoadBalancer.Ingress = nil
+	ing.Annotations = map[string]string{"vip": "10.20.20.2"}
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 2 || ihms[0].IPAddr != "10.20.20.2" || !ihms[0].IsReady() {
+		t.Fatalf("expected ready host metas with the address from the annotation, got %v", ihms)
+	}
+}
+
+func TestIngressExcludePaths(t *testing.T) {
+	ing := getTestIngress("ing1", 1)
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 || !reflect.DeepEqual(ihms[0].Paths, []string{"/foo", "/bar"}) {
+		t.Fatalf("expected all the paths of the host, got %v", ihms)
+	}
+	allPathsCksum := ihms[0].GetIngressHostCksum()
+
+	ing.Annotations = map[string]string{gslbutils.ExcludePathsAnnotation: " /bar, /baz"}
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 || !reflect.DeepEqual(ihms[0].Paths, []string{"/foo"}) {
+		t.Fatalf("expected only the path /foo, got %v", ihms)
+	}
+	if ihms[0].GetIngressHostCksum() == allPathsCksum {
+		t.Fatalf("expected the checksum to change with the excluded paths")
+	}
+
+	// a host with all its paths excluded is rejected
+	ing.Annotations[gslbutils.ExcludePathsAnnotation] = "/foo,/bar"
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 || len(ihms[0].Paths) != 0 {
+		t.Fatalf("expected no paths for the host, got %v", ihms)
+	}
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	defer gslbutils.ClearFilterObservers()
+	if ihms[0].ApplyFilter() {
+		t.Fatalf("expected the host with all paths excluded to be rejected")
+	}
+	if reason != "rejected because all paths are excluded" {
+		t.Fatalf("unexpected reason for the rejection: %s", reason)
+	}
+	// the explanation of the filter reports the same decision
+	fe := gslbutils.GetGlobalFilter().EvaluateObject(ihms[0])
+	if fe.Accepted || fe.Reason != reason || fe.Checks[0].Name != gslbutils.FilterCheckPaths || fe.Checks[0].Passed {
+		t.Fatalf("expected the explanation to reject the host for its excluded paths, got %+v", fe)
+	}
+}
+
+func TestIngressPathsPerHostLimit(t *testing.T) {
+	const numPaths = 5000
+	const limit = 100
+	ing := getTestIngress("ing1", 1)
+	paths := make([]v1beta1.HTTPIngressPath, 0, numPaths)
+	for i := numPaths - 1; i >= 0; i-- {
+		paths = append(paths, v1beta1.HTTPIngressPath{Path: "/path" + strconv.Itoa(i)})
+	}
+	ing.Spec.Rules[0].HTTP.Paths = paths
+
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 || len(ihms[0].Paths) != numPaths {
+		t.Fatalf("expected all the %d paths without a limit, got %d", numPaths, len(ihms[0].Paths))
+	}
+
+	if err := gslbutils.SetPathsPerHostLimit(-1); err == nil {
+		t.Fatalf("expected an error for a negative limit")
+	}
+	if err := gslbutils.SetPathsPerHostLimit(limit); err != nil {
+		t.Fatalf("error in setting the paths per host limit: %v", err)
+	}
+	defer gslbutils.SetPathsPerHostLimit(0)
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 || len(ihms[0].Paths) != limit {
+		t.Fatalf("expected the paths to be truncated to %d, got %d", limit, len(ihms[0].Paths))
+	}
+	if ihms[0].Paths[0] != "/path0" || ihms[0].Paths[1] != "/path1" || ihms[0].Paths[2] != "/path10" {
+		t.Fatalf("expected the first paths in the sorted order, got %v", ihms[0].Paths[:3])
+	}
+	cksum := ihms[0].GetIngressHostCksum()
+
+	// the same paths are retained, with the same checksum, irrespective of the order of the paths
+	for i, j := 0, len(paths)-1; i < j; i, j = i+1, j-1 {
+		paths[i], paths[j] = paths[j], paths[i]
+	}
+	reversed := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if !reflect.DeepEqual(reversed[0].Paths, ihms[0].Paths) {
+		t.Fatalf("expected the same truncated paths for the reversed paths")
+	}
+	if reversed[0].GetIngressHostCksum() != cksum {
+		t.Fatalf("expected the same checksum for the reversed paths")
+	}
+
+	// a host within the limit keeps its paths as is
+	ing.Spec.Rules[0].HTTP.Paths = paths[:limit]
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms[0].Paths) != limit || ihms[0].Paths[0] != "/path0" || ihms[0].Paths[1] != "/path1" {
+		t.Fatalf("expected the paths within the limit in their order, got %v", ihms[0].Paths[:2])
+	}
+}
+
+func TestIngressIncludeHosts(t *testing.T) {
+	ing := getTestIngress("ing1", 3)
+	if ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster); len(ihms) != 3 {
+		t.Fatalf("expected all the 3 hosts, got %v", ihms)
+	}
+
+	ing.Annotations = map[string]string{gslbutils.IncludeHostsAnnotation: " host1.avi.com "}
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 || ihms[0].Hostname != "host1.avi.com" || ihms[0].IPAddr != "10.10.10.2" {
+		t.Fatalf("expected only the included host host1.avi.com, got %v", ihms)
+	}
+
+	// an empty include list includes all the hosts
+	ing.Annotations[gslbutils.IncludeHostsAnnotation] = ""
+	if ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster); len(ihms) != 3 {
+		t.Fatalf("expected all the 3 hosts for an empty include list, got %v", ihms)
+	}
+
+	ing.Annotations[gslbutils.ExcludeHostsAnnotation] = "host0.avi.com,host2.avi.com"
+	ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 || ihms[0].Hostname != "host1.avi.com" {
+		t.Fatalf("expected only the host which isn't excluded, got %v", ihms)
+	}
+}
+
+func TestIngressEmptyHostRule(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	gslbutils.SetClusterEventRecorder(TestCluster, recorder)
+	// the events of the other tests are discarded
+	defer gslbutils.SetClusterEventRecorder(TestCluster, &record.FakeRecorder{})
+
+	ing := getTestIngress("ing1", 2)
+	if k8sobjects.RejectEmptyHostRules(ing, TestCluster) {
+		t.Fatalf("expected no rules without a host")
+	}
+	ing.Spec.Rules = append(ing.Spec.Rules, v1beta1.IngressRule{
+		IngressRuleValue: v1beta1.IngressRuleValue{
+			HTTP: &v1beta1.HTTPIngressRuleValue{Paths: []v1beta1.HTTPIngressPath{{Path: "/foo"}}},
+		},
+	})
+	ing.Status.LoadBalancer.Ingress = append(ing.Status.LoadBalancer.Ingress, corev1.LoadBalancerIngress{
+		IP: "10.10.10.3",
+	})
+
+	// the rule without a host doesn't produce a host meta
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 2 {
+		t.Fatalf("expected only the 2 hosts, got %v", ihms)
+	}
+	for _, ihm := range ihms {
+		if ihm.Hostname == "" {
+			t.Fatalf("unexpected host meta without a hostname: %v", ihm)
+		}
+	}
+	if !k8sobjects.RejectEmptyHostRules(ing, TestCluster) {
+		t.Fatalf("expected the rule without a host to be rejected")
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, gslbutils.EmptyHostname) {
+			t.Fatalf("unexpected event %s", event)
+		}
+	default:
+		t.Fatalf("expected an event for the rule without a host")
+	}
+}
+
+func TestIngressPortAndProtocol(t *testing.T) {
+	ing := getTestIngress("ing1", 2)
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	// host0 has TLS, and the ingress allows plain HTTP
+	if ports := ihms[0].GetPorts(); !reflect.DeepEqual(ports, []int32{80, 443}) {
+		t.Fatalf("expected both the HTTP and the HTTPS ports for a TLS host, got %v", ports)
+	}
+	if port, err := ihms[0].GetPort(); err != nil || port != 443 {
+		t.Fatalf("expected the HTTPS port to be monitored for a TLS host, got %d, %v", port, err)
+	}
+	if ports := ihms[1].GetPorts(); !reflect.DeepEqual(ports, []int32{80}) {
+		t.Fatalf("expected only the HTTP port for a host without TLS, got %v", ports)
+	}
+	if port, err := ihms[1].GetPort(); err != nil || port != 80 {
+		t.Fatalf("expected the HTTP port to be monitored for a host without TLS, got %d, %v", port, err)
+	}
+	for _, ihm := range ihms {
+		if proto, err := ihm.GetProtocol(); err != nil || proto != gslbutils.ProtocolTCP {
+			t.Fatalf("expected the TCP protocol, got %s, %v", proto, err)
+		}
+	}
+
+	ing.Annotations = map[string]string{gslbutils.IngressAllowHTTPAnnotation: "false"}
+	tlsOnly := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if ports := tlsOnly[0].GetPorts(); !reflect.DeepEqual(ports, []int32{443}) {
+		t.Fatalf("expected only the HTTPS port for a TLS host without plain HTTP, got %v", ports)
+	}
+	if ports := tlsOnly[1].GetPorts(); !reflect.DeepEqual(ports, []int32{80}) {
+		t.Fatalf("expected only the HTTP port for a host without TLS, got %v", ports)
+	}
+	if tlsOnly[0].GetIngressHostCksum() == ihms[0].GetIngressHostCksum() {
+		t.Fatalf("expected the checksum to change with the ports")
+	}
+}
+
+func TestIngressMultiHostMultiVIP(t *testing.T) {
+	ing := getTestIngress("ing1", 3)
+	// the status entries are in a different order from the rules, with a differently cased hostname,
+	// a fully qualified hostname and an additional entry for a host
+	ing.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{
+		{IP: "10.10.10.3", Hostname: "host2.avi.com."},
+		{IP: "10.10.10.1", Hostname: "HOST0.avi.com"},
+		{IP: "10.10.10.2", Hostname: "host1.avi.com"},
+		{IP: "10.10.10.4", Hostname: "host1.avi.com"},
+		{IP: "10.10.10.5", Hostname: "host3.avi.com"},
+	}
+	expected := map[string]string{
+		"host0.avi.com": "10.10.10.1",
+		"host1.avi.com": "10.10.10.2",
+		"host2.avi.com": "10.10.10.3",
+	}
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != len(expected) {
+		t.Fatalf("expected %d ingress host metas, got %v", len(expected), ihms)
+	}
+	for _, ihm := range ihms {
+		ip, ok := expected[ihm.Hostname]
+		if !ok {
+			t.Fatalf("unexpected host %s", ihm.Hostname)
+		}
+		if ihm.IPAddr != ip {
+			t.Fatalf("expected the VIP %s for host %s, got %s", ip, ihm.Hostname, ihm.IPAddr)
+		}
+		if ihm.ObjName != "ing1/"+ihm.Hostname {
+			t.Fatalf("expected the object name to have the rule host, got %s", ihm.ObjName)
+		}
+		if ihm.Hostname == "host1.avi.com" {
+			// the additional entry of a host is an additional VIP of equal weight
+			expectedVIPs := []gslbutils.VIP{{IP: "10.10.10.2", Weight: 1}, {IP: "10.10.10.4", Weight: 1}}
+			if !reflect.DeepEqual(ihm.GetVIPs(), expectedVIPs) {
+				t.Fatalf("expected the VIPs %v for host %s, got %v", expectedVIPs, ihm.Hostname, ihm.GetVIPs())
+			}
+		} else if len(ihm.VIPs) != 1 || ihm.VIPs[0].IP != ip {
+			t.Fatalf("expected the only VIP %s for host %s, got %v", ip, ihm.Hostname, ihm.VIPs)
+		}
+		delete(expected, ihm.Hostname)
+	}
+}
+
+func TestIngressVIPWeights(t *testing.T) {
+	ing := getTestIngress("ing1", 1)
+	ing.Status.LoadBalancer.Ingress = []corev1.LoadBalancerIngress{
+		{IP: "10.10.10.1", Hostname: "host0.avi.com"},
+		{IP: "10.10.10.2", Hostname: "host0.avi.com"},
+		{IP: "10.10.10.3", Hostname: "host0.avi.com"},
+	}
+	ing.Annotations = map[string]string{gslbutils.VIPWeightsAnnotation: "10.10.10.1=4, 10.10.10.2=2"}
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 {
+		t.Fatalf("expected 1 ingress host meta, got %v", ihms)
+	}
+	// the VIP without a weight gets the default weight
+	expectedVIPs := []gslbutils.VIP{{IP: "10.10.10.1", Weight: 4}, {IP: "10.10.10.2", Weight: 2}, {IP: "10.10.10.3", Weight: 1}}
+	if !reflect.DeepEqual(ihms[0].VIPs, expectedVIPs) {
+		t.Fatalf("expected the VIPs %v, got %v", expectedVIPs, ihms[0].VIPs)
+	}
+	cksum := ihms[0].GetIngressHostCksum()
+
+	// an invalid annotation is ignored, all the VIPs get equal weights
+	for _, val := range []string{"10.10.10.1=0", "10.10.10.1=21", "10.10.10.1", "foo=2", "10.10.10.1=a",
+		"10.10.10.1=2,10.10.10.1=3"} {
+		if _, err := gslbutils.ParseVIPWeights(val); err == nil {
+			t.Fatalf("expected an error for the vip weights %s", val)
+		}
+		ing.Annotations[gslbutils.VIPWeightsAnnotation] = val
+		ihms = k8sobjects.GetIngressHostMeta(ing, TestCluster)
+		for _, vip := range ihms[0].VIPs {
+			if vip.Weight != gslbutils.DefaultVIPWeight {
+				t.Fatalf("expected the default weight for the vips with the annotation %s, got %v", val, ihms[0].VIPs)
+			}
+		}
+		if ihms[0].GetIngressHostCksum() == cksum {
+			t.Fatalf("expected the checksum to change with the weights of the VIPs")
+		}
+	}
+}
@@ -0,0 +1,214 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package k8sobjects
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestObjTypeRegistry(t *testing.T) {
+	expectedTypes := []string{gslbutils.RouteType, gslbutils.SvcType, gslbutils.IngressType, gslbutils.HTTPRouteType}
+	if objTypes := gslbutils.GetObjTypes(); !reflect.DeepEqual(objTypes, expectedTypes) {
+		t.Fatalf("expected the object types %v, got %v", expectedTypes, objTypes)
+	}
+	for _, objType := range gslbutils.GetObjTypes() {
+		handlers, ok := gslbutils.GetObjTypeHandlers(objType)
+		if !ok {
+			t.Fatalf("expected the handlers for %s to be registered", objType)
+		}
+		if handlers.AcceptedStore() == nil || handlers.RejectedStore() == nil {
+			t.Fatalf("expected the stores for %s", objType)
+		}
+		if handlers.HostMap == nil || handlers.HostMap() == nil {
+			t.Fatalf("expected the host map for %s", objType)
+		}
+		// the registry is keyed by the type of the meta objects
+		metaObj, err := k8sobjects.GetNewMetaObj(objType)
+		if err != nil {
+			t.Fatalf("expected a meta object for %s, got error: %v", objType, err)
+		}
+		if metaObj.GetType() != objType {
+			t.Fatalf("expected the meta object for %s to be of the same type, got %s", objType, metaObj.GetType())
+		}
+	}
+	if _, err := k8sobjects.GetNewMetaObj("UNKNOWN"); err == nil {
+		t.Fatalf("expected an error for an unregistered object type")
+	}
+	if gslbutils.IsObjTypeRegistered("UNKNOWN") {
+		t.Fatalf("expected UNKNOWN to be unregistered")
+	}
+}
+
+func TestMetaObjErrors(t *testing.T) {
+	route := k8sobjects.RouteMeta{Name: "route1"}
+	if _, err := route.GetPort(); !errors.Is(err, k8sobjects.ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported for the port of a non-passthrough route, got %v", err)
+	}
+	if _, err := route.GetProtocol(); !errors.Is(err, k8sobjects.ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported for the protocol of a non-passthrough route, got %v", err)
+	}
+	if _, err := route.GetPaths(); !errors.Is(err, k8sobjects.ErrNoPaths) {
+		t.Fatalf("expected ErrNoPaths for a route without paths, got %v", err)
+	}
+	route.Passthrough = true
+	route.Port = 443
+	if port, err := route.GetPort(); err != nil || port != 443 {
+		t.Fatalf("expected port 443 for a passthrough route, got %d, %v", port, err)
+	}
+
+	ing := k8sobjects.IngressHostMeta{ObjName: "ing1/host1.avi.com"}
+	if _, err := ing.GetPort(); !errors.Is(err, k8sobjects.ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported for the port of an ingress, got %v", err)
+	}
+	if _, err := ing.GetPaths(); !errors.Is(err, k8sobjects.ErrNoPaths) {
+		t.Fatalf("expected ErrNoPaths for an ingress without paths, got %v", err)
+	}
+
+	svc := k8sobjects.SvcMeta{Name: "svc1"}
+	if _, err := svc.GetPaths(); !errors.Is(err, k8sobjects.ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported for the paths of a service, got %v", err)
+	}
+	if _, err := svc.GetTLS(); !errors.Is(err, k8sobjects.ErrNotSupported) {
+		t.Fatalf("expected ErrNotSupported for the TLS of a service, got %v", err)
+	}
+
+	if _, err := k8sobjects.GetNewMetaObj("UNKNOWN"); !errors.Is(err, k8sobjects.ErrUnknownObjType) {
+		t.Fatalf("expected ErrUnknownObjType for an unknown object type, got %v", err)
+	}
+}
+
+func TestHostMapLBHostname(t *testing.T) {
+	svc := k8sobjects.SvcMeta{Name: "svc1", Namespace: TestNS, Cluster: TestCluster, Hostname: "app.avi.com",
+		LBHostname: "lb-1234.elb.amazonaws.com"}
+	ing := k8sobjects.IngressHostMeta{IngName: "ing1", Namespace: TestNS, Cluster: TestCluster,
+		Hostname: "ing.avi.com", LBHostname: "lb-5678.elb.amazonaws.com"}
+	svcKey := gslbutils.GetClusterKey(TestCluster, TestNS, svc.Name)
+	ingKey := gslbutils.GetClusterKey(TestCluster, TestNS, "ing1/ing.avi.com")
+	svc.UpdateHostMap(svcKey)
+	ing.UpdateHostMap(ingKey)
+	defer svc.DeleteMapByKey(svcKey)
+	defer ing.DeleteMapByKey(ingKey)
+
+	entry, ok := k8sobjects.GetHostMapEntry(gslbutils.SvcType, svcKey)
+	if !ok || entry.Hostname != svc.Hostname || entry.IP != "" || entry.LBHostname != svc.LBHostname {
+		t.Fatalf("expected the LB hostname of the service in the host map, got %+v, %v", entry, ok)
+	}
+	entry, ok = k8sobjects.GetHostMapEntry(gslbutils.IngressType, ingKey)
+	if !ok || entry.Hostname != ing.Hostname || entry.LBHostname != ing.LBHostname {
+		t.Fatalf("expected the LB hostname of the ingress in the host map, got %+v, %v", entry, ok)
+	}
+	if _, ok := k8sobjects.GetHostMapEntry("UNKNOWN", svcKey); ok {
+		t.Fatalf("expected no entry for an unknown object type")
+	}
+}
+
+func TestGetAdvertisedHostnames(t *testing.T) {
+	// the extra hosts of a route are added to the host map only if accepted by the global filter
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	gdp := &gdpv1alpha1.GlobalDeploymentPolicy{}
+	gdp.ObjectMeta.Name = "test-gdp"
+	gdp.ObjectMeta.Namespace = gslbutils.AVISystem
+	gdp.Spec.MatchClusters = []string{TestCluster}
+	gdp.Spec.MatchRules.AppSelector.Label = map[string]string{"key": "value"}
+	gslbutils.GetGlobalFilter().AddToFilter(gdp)
+
+	svc := k8sobjects.SvcMeta{Name: "svc1", Namespace: TestNS, Cluster: TestCluster, Hostname: "app.avi.com",
+		IPAddr: "10.10.10.10"}
+	route := k8sobjects.RouteMeta{Name: "route1", Namespace: TestNS, Cluster: TestCluster, Hostname: "app.avi.com",
+		IPAddr: "10.10.10.11", AdditionalHosts: []string{"alt.avi.com"}, Labels: map[string]string{"key": "value"}}
+	svcKey := gslbutils.GetClusterKey(TestCluster, TestNS, svc.Name)
+	routeKey := gslbutils.GetClusterKey(TestCluster, TestNS, route.Name)
+	svc.UpdateHostMap(svcKey)
+	route.UpdateHostMap(routeKey)
+	defer svc.DeleteMapByKey(svcKey)
+	defer route.DeleteMapByKey(routeKey)
+
+	routeSource := k8sobjects.HostnameSource{ObjType: gslbutils.RouteType, Cluster: TestCluster, Namespace: TestNS,
+		Name: route.Name}
+	svcSource := k8sobjects.HostnameSource{ObjType: gslbutils.SvcType, Cluster: TestCluster, Namespace: TestNS,
+		Name: svc.Name}
+	hostnames := k8sobjects.GetAdvertisedHostnames()
+	if !reflect.DeepEqual(hostnames["app.avi.com"], []k8sobjects.HostnameSource{svcSource, routeSource}) {
+		t.Fatalf("unexpected sources for app.avi.com: %v", hostnames["app.avi.com"])
+	}
+	if !reflect.DeepEqual(hostnames["alt.avi.com"], []k8sobjects.HostnameSource{routeSource}) {
+		t.Fatalf("unexpected sources for alt.avi.com: %v", hostnames["alt.avi.com"])
+	}
+
+	// the snapshot isn't affected by the later changes to the host maps
+	route.DeleteMapByKey(routeKey)
+	if len(hostnames["alt.avi.com"]) != 1 {
+		t.Fatalf("expected the snapshot to be unchanged, got %v", hostnames["alt.avi.com"])
+	}
+	if _, ok := k8sobjects.GetAdvertisedHostnames()["alt.avi.com"]; ok {
+		t.Fatalf("expected alt.avi.com to be removed with the route")
+	}
+}
+
+// refRecorder is a fake event recorder which keeps the references to the objects of the events.
+type refRecorder struct {
+	*record.FakeRecorder
+	refs []*corev1.ObjectReference
+}
+
+func (r *refRecorder) Event(object runtime.Object, eventtype, reason, message string) {
+	if ref, ok := object.(*corev1.ObjectReference); ok {
+		r.refs = append(r.refs, ref)
+	}
+}
+
+func TestObjectEventReference(t *testing.T) {
+	recorder := &refRecorder{FakeRecorder: record.NewFakeRecorder(10)}
+	gslbutils.SetClusterEventRecorder(TestCluster, recorder)
+	// the events of the other tests are discarded
+	defer gslbutils.SetClusterEventRecorder(TestCluster, &record.FakeRecorder{})
+
+	ing := getTestIngress("ing1", 1)
+	ing.UID = types.UID("ing1-uid")
+	ihms := k8sobjects.GetIngressHostMeta(ing, TestCluster)
+	if len(ihms) != 1 || ihms[0].GetUID() != ing.UID {
+		t.Fatalf("expected the UID of the ingress in its host meta, got %v", ihms)
+	}
+	gslbutils.RecordObjectEvent(TestCluster, TestNS, ihms[0].GetName(), gslbutils.IngressType, ihms[0].GetUID(),
+		corev1.EventTypeNormal, gslbutils.GSLBAccepted, "accepted")
+	if len(recorder.refs) != 1 {
+		t.Fatalf("expected an event for the ingress, got %d", len(recorder.refs))
+	}
+	// the event refers to the ingress, and not to its host
+	expectedRef := corev1.ObjectReference{Kind: "Ingress", APIVersion: "networking.k8s.io/v1beta1",
+		Namespace: TestNS, Name: ing.Name, UID: ing.UID}
+	if *recorder.refs[0] != expectedRef {
+		t.Fatalf("expected the reference %+v, got %+v", expectedRef, *recorder.refs[0])
+	}
+
+	// an unknown object type is referred to as is
+	gslbutils.RecordObjectEvent(TestCluster, TestNS, "obj1", "UNKNOWN", "", corev1.EventTypeNormal,
+		gslbutils.GSLBAccepted, "accepted")
+	expectedRef = corev1.ObjectReference{Kind: "UNKNOWN", Namespace: TestNS, Name: "obj1"}
+	if len(recorder.refs) != 2 || *recorder.refs[1] != expectedRef {
+		t.Fatalf("expected the reference %+v, got %+v", expectedRef, recorder.refs)
+	}
+}
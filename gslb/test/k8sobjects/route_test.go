@@ -0,0 +1,312 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package k8sobjects
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+	routev1 "github.com/openshift/api/route/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func getTestRoute(name, host string, conditions ...routev1.RouteIngressCondition) *routev1.Route {
+	return &routev1.Route{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: TestNS,
+		},
+		Spec: routev1.RouteSpec{
+			Host: host,
+		},
+		Status: routev1.RouteStatus{
+			Ingress: []routev1.RouteIngress{
+				{
+					Host:       host,
+					RouterName: "ako-test",
+					Conditions: conditions,
+				},
+			},
+		},
+	}
+}
+
+func admittedCondition(status corev1.ConditionStatus) routev1.RouteIngressCondition {
+	return routev1.RouteIngressCondition{
+		Type:    routev1.RouteAdmitted,
+		Status:  status,
+		Message: "10.10.10.10",
+	}
+}
+
+func TestRouteReady(t *testing.T) {
+	admitted := k8sobjects.GetRouteMeta(getTestRoute("route1", "route1.avi.com", admittedCondition(corev1.ConditionTrue)), TestCluster)
+	if !admitted.IsReady() {
+		t.Fatalf("expected an admitted route to be ready")
+	}
+	rejected := k8sobjects.GetRouteMeta(getTestRoute("route1", "route1.avi.com", admittedCondition(corev1.ConditionFalse)), TestCluster)
+	if rejected.IsReady() {
+		t.Fatalf("expected a rejected route to not be ready")
+	}
+	noStatus := k8sobjects.GetRouteMeta(getTestRoute("route1", "route1.avi.com"), TestCluster)
+	if noStatus.IsReady() {
+		t.Fatalf("expected a route without the admitted condition to not be ready")
+	}
+	// a route is not ready if the admitted condition is for a different host
+	otherHost := getTestRoute("route1", "route1.avi.com", admittedCondition(corev1.ConditionTrue))
+	otherHost.Status.Ingress[0].Host = "route2.avi.com"
+	if k8sobjects.GetRouteMeta(otherHost, TestCluster).IsReady() {
+		t.Fatalf("expected a route admitted for a different host to not be ready")
+	}
+	// the readiness transition must change the checksum
+	if admitted.GetRouteCksum() == rejected.GetRouteCksum() {
+		t.Fatalf("expected the checksum to change with the ready flag")
+	}
+}
+
+func TestRouteObjectWeight(t *testing.T) {
+	route := getTestRoute("route1", "route1.avi.com")
+	noWeight := k8sobjects.GetRouteMeta(route, TestCluster)
+	if noWeight.GetObjectWeight() != 0 {
+		t.Fatalf("expected no weight for a route without the annotation, got %d", noWeight.GetObjectWeight())
+	}
+
+	route.Annotations = map[string]string{gslbutils.ObjectWeightAnnotation: "5"}
+	weighted := k8sobjects.GetRouteMeta(route, TestCluster)
+	if weighted.GetObjectWeight() != 5 {
+		t.Fatalf("expected the weight 5, got %d", weighted.GetObjectWeight())
+	}
+	// a change of the weight must change the checksum, so that the GSLB member is updated
+	if weighted.GetRouteCksum() == noWeight.GetRouteCksum() {
+		t.Fatalf("expected the checksum to change with the weight")
+	}
+
+	// an invalid weight is ignored
+	route.Annotations[gslbutils.ObjectWeightAnnotation] = "50"
+	if w := k8sobjects.GetRouteMeta(route, TestCluster).GetObjectWeight(); w != 0 {
+		t.Fatalf("expected an out of range weight to be ignored, got %d", w)
+	}
+}
+
+func TestRouteAdditionalHosts(t *testing.T) {
+	route := getTestRoute("route1", "route1.avi.com")
+	single := k8sobjects.GetRouteMeta(route, TestCluster)
+	if len(single.AdditionalHosts) != 0 || len(single.GetExtraHostMetas()) != 0 {
+		t.Fatalf("expected no additional hosts for a route without the annotation")
+	}
+
+	route.Annotations = map[string]string{
+		gslbutils.AdditionalHostsAnnotation: "route3.avi.com, route2.avi.com,route1.avi.com,,route2.avi.com",
+	}
+	multi := k8sobjects.GetRouteMeta(route, TestCluster)
+	expectedHosts := []string{"route2.avi.com", "route3.avi.com"}
+	if !reflect.DeepEqual(multi.AdditionalHosts, expectedHosts) {
+		t.Fatalf("expected additional hosts %v, got %v", expectedHosts, multi.AdditionalHosts)
+	}
+	hostMetas := multi.GetExtraHostMetas()
+	if len(hostMetas) != len(expectedHosts) {
+		t.Fatalf("expected a meta object per additional host, got %d", len(hostMetas))
+	}
+	for idx, hostMeta := range hostMetas {
+		if hostMeta.Hostname != expectedHosts[idx] || hostMeta.Name != "route1" || len(hostMeta.AdditionalHosts) != 0 {
+			t.Fatalf("unexpected meta object for an additional host: %v", hostMeta)
+		}
+	}
+	if single.GetRouteCksum() == multi.GetRouteCksum() {
+		t.Fatalf("expected the checksum to change with the additional hosts")
+	}
+
+	// the SNI hosts of a passthrough route are not repeated as the additional hosts
+	route.Spec.TLS = &routev1.TLSConfig{Termination: gslbutils.PassthroughRoute}
+	route.Annotations[gslbutils.SNIHostsAnnotation] = "route2.avi.com"
+	passthrough := k8sobjects.GetRouteMeta(route, TestCluster)
+	if !reflect.DeepEqual(passthrough.AdditionalHosts, []string{"route3.avi.com"}) {
+		t.Fatalf("expected the additional hosts without the SNI hosts, got %v", passthrough.AdditionalHosts)
+	}
+	if !reflect.DeepEqual(passthrough.GetExtraHosts(), expectedHosts) {
+		t.Fatalf("expected the extra hosts %v, got %v", expectedHosts, passthrough.GetExtraHosts())
+	}
+}
+
+func TestRouteTLSServerName(t *testing.T) {
+	route := getTestRoute("route1", "route1.avi.com")
+	if sni := k8sobjects.GetRouteMeta(route, TestCluster).GetTLSServerName(); sni != "" {
+		t.Fatalf("expected no SNI host for a non-TLS route, got %s", sni)
+	}
+
+	route.Spec.TLS = &routev1.TLSConfig{Termination: routev1.TLSTerminationReencrypt}
+	reencrypt := k8sobjects.GetRouteMeta(route, TestCluster)
+	if reencrypt.TLSTermination != string(routev1.TLSTerminationReencrypt) {
+		t.Fatalf("expected the reencrypt termination, got %s", reencrypt.TLSTermination)
+	}
+	if sni := reencrypt.GetTLSServerName(); sni != "route1.avi.com" {
+		t.Fatalf("expected the route's host as the SNI host, got %s", sni)
+	}
+
+	route.Annotations = map[string]string{gslbutils.HmSNIHostAnnotation: " backend.avi.com "}
+	annotated := k8sobjects.GetRouteMeta(route, TestCluster)
+	if sni := annotated.GetTLSServerName(); sni != "backend.avi.com" {
+		t.Fatalf("expected the annotated SNI host, got %s", sni)
+	}
+	if reencrypt.GetRouteCksum() == annotated.GetRouteCksum() {
+		t.Fatalf("expected the checksum to change with the SNI host")
+	}
+
+	route.Annotations = nil
+	route.Spec.TLS = &routev1.TLSConfig{Termination: routev1.TLSTerminationEdge}
+	edge := k8sobjects.GetRouteMeta(route, TestCluster)
+	if edge.GetTLSServerName() != "route1.avi.com" || edge.GetRouteCksum() == reencrypt.GetRouteCksum() {
+		t.Fatalf("expected the edge route to have the route's host as the SNI host and a different checksum")
+	}
+}
+
+func TestRouteHostOnlyPaths(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	route := k8sobjects.GetRouteMeta(getTestRoute("route1", "route1.avi.com", admittedCondition(corev1.ConditionTrue)), TestCluster)
+	if paths, err := route.GetPaths(); err != nil || !reflect.DeepEqual(paths, []string{"/"}) {
+		t.Fatalf("expected the default path / for a route without a path, got %v, %v", paths, err)
+	}
+	defaultPathCksum := route.GetRouteCksum()
+
+	gdp := &gdpv1alpha1.GlobalDeploymentPolicy{}
+	gdp.ObjectMeta.Name = "test-gdp"
+	gdp.ObjectMeta.Namespace = gslbutils.AVISystem
+	gdp.Spec.MatchRules.AppSelector.Label = map[string]string{"key": "value"}
+	gdp.Spec.HostOnlyObjectTypes = []string{gdpv1alpha1.RouteObj}
+	gslbutils.GetGlobalFilter().AddToFilter(gdp)
+
+	if paths, err := route.GetPaths(); !errors.Is(err, k8sobjects.ErrNoPaths) || len(paths) != 0 {
+		t.Fatalf("expected no paths for a host only route, got %v, %v", paths, err)
+	}
+	if route.GetRouteCksum() == defaultPathCksum {
+		t.Fatalf("expected the checksum to change with the effective paths")
+	}
+	// the explicit paths of a route are always advertised
+	withPath := getTestRoute("route2", "route2.avi.com", admittedCondition(corev1.ConditionTrue))
+	withPath.Spec.Path = "/foo"
+	if paths, err := k8sobjects.GetRouteMeta(withPath, TestCluster).GetPaths(); err != nil || !reflect.DeepEqual(paths, []string{"/foo"}) {
+		t.Fatalf("expected the path /foo for a host only route with a path, got %v, %v", paths, err)
+	}
+	// the other object types still get the default path
+	ing := k8sobjects.IngressHostMeta{ObjName: "ing1/host1.avi.com", Paths: []string{"/"}, DefaultPath: true}
+	if paths, err := ing.GetPaths(); err != nil || !reflect.DeepEqual(paths, []string{"/"}) {
+		t.Fatalf("expected the default path / for an ingress, got %v, %v", paths, err)
+	}
+}
+
+func TestRouteEmptyHost(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	gslbutils.SetClusterEventRecorder(TestCluster, recorder)
+	// the events of the other tests are discarded
+	defer gslbutils.SetClusterEventRecorder(TestCluster, &record.FakeRecorder{})
+	var reason string
+	gslbutils.RegisterFilterObserver(func(d gslbutils.FilterDecision) { reason = d.Reason })
+	defer gslbutils.ClearFilterObservers()
+
+	route := getTestRoute("route1", "", admittedCondition(corev1.ConditionTrue))
+	route.Spec.TLS = &routev1.TLSConfig{Termination: routev1.TLSTerminationPassthrough}
+	routeMeta := k8sobjects.GetRouteMeta(route, TestCluster)
+	if !routeMeta.Passthrough || routeMeta.Hostname != "" {
+		t.Fatalf("expected a passthrough route without a hostname, got %v", routeMeta)
+	}
+	if !k8sobjects.RejectIfEmptyHostname(routeMeta) {
+		t.Fatalf("expected the route without a host to be rejected")
+	}
+	if reason != k8sobjects.EmptyHostnameReason {
+		t.Fatalf("unexpected reason for the rejection: %s", reason)
+	}
+	select {
+	case event := <-recorder.Events:
+		if !strings.Contains(event, gslbutils.EmptyHostname) {
+			t.Fatalf("unexpected event %s", event)
+		}
+	default:
+		t.Fatalf("expected an event for the route without a host")
+	}
+	// the rejection is recorded only once, with the specific reason
+	select {
+	case event := <-recorder.Events:
+		t.Fatalf("unexpected second event %s", event)
+	default:
+	}
+	if routeMeta.ApplyFilter() {
+		t.Fatalf("expected the filter to reject the route without a host")
+	}
+
+	withHost := k8sobjects.GetRouteMeta(getTestRoute("route1", "route1.avi.com"), TestCluster)
+	if k8sobjects.RejectIfEmptyHostname(withHost) {
+		t.Fatalf("expected the route with a host not to be rejected")
+	}
+}
+
+func TestFilterDecisionEvents(t *testing.T) {
+	recorder := record.NewFakeRecorder(10)
+	gslbutils.SetClusterEventRecorder(TestCluster, recorder)
+	defer gslbutils.SetClusterEventRecorder(TestCluster, &record.FakeRecorder{})
+	if err := gslbutils.SetFilterEventInterval(time.Minute); err != nil {
+		t.Fatalf("unexpected error in setting the filter event interval: %v", err)
+	}
+	defer gslbutils.SetFilterEventInterval(gslbutils.DefaultFilterEventInterval)
+
+	expectEvent := func(expected ...string) {
+		select {
+		case event := <-recorder.Events:
+			for _, e := range expected {
+				if !strings.Contains(event, e) {
+					t.Fatalf("expected the event %s to contain %s", event, e)
+				}
+			}
+		default:
+			t.Fatalf("expected an event with %v", expected)
+		}
+	}
+	expectNoEvent := func() {
+		select {
+		case event := <-recorder.Events:
+			t.Fatalf("unexpected event %s", event)
+		default:
+		}
+	}
+
+	routeMeta := k8sobjects.GetRouteMeta(getTestRoute("route1", "route1.avi.com"), TestCluster)
+	k8sobjects.NotifyFilterDecision(routeMeta, false, "rejected because of appSelector")
+	expectEvent(corev1.EventTypeWarning, gslbutils.GSLBRejected, "reason=rejected because of appSelector")
+	// the same decision isn't recorded again within the interval
+	k8sobjects.NotifyFilterDecision(routeMeta, false, "rejected because of appSelector")
+	expectNoEvent()
+	k8sobjects.NotifyFilterDecision(routeMeta, true, "accepted because of appSelector")
+	expectEvent(corev1.EventTypeNormal, gslbutils.GSLBAccepted, "accepted because of appSelector")
+	k8sobjects.NotifyFilterDecision(routeMeta, false, "rejected because namespace is not selected")
+	expectEvent(corev1.EventTypeWarning, gslbutils.GSLBRejected, "namespace is not selected")
+
+	if err := gslbutils.SetFilterEventInterval(-time.Second); err == nil {
+		t.Fatalf("expected an error for a negative interval")
+	}
+	if err := gslbutils.SetFilterEventInterval(0); err != nil {
+		t.Fatalf("unexpected error in disabling the filter events: %v", err)
+	}
+	k8sobjects.NotifyFilterDecision(routeMeta, true, "accepted because of appSelector")
+	expectNoEvent()
+}
@@ -0,0 +1,78 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package readiness
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/onsi/gomega"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	Cluster1 = "cluster1"
+	Cluster2 = "cluster2"
+)
+
+func getReadyz(verbose bool) (int, string) {
+	url := gslbutils.ReadyzPath
+	if verbose {
+		url += "?verbose"
+	}
+	req := httptest.NewRequest("GET", url, nil)
+	rec := httptest.NewRecorder()
+	gslbutils.ReadyzHandler(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+func TestReadyzWithClusterSync(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// no member clusters initialized yet
+	code, body := getReadyz(true)
+	g.Expect(code).To(gomega.Equal(http.StatusServiceUnavailable))
+	g.Expect(body).To(gomega.ContainSubstring("no member clusters initialized"))
+
+	gslbutils.RegisterClusterForReadiness(Cluster1)
+	gslbutils.RegisterClusterForReadiness(Cluster2)
+	code, _ = getReadyz(false)
+	g.Expect(code).To(gomega.Equal(http.StatusServiceUnavailable))
+
+	cluster2Synced := false
+	gslbutils.SetClusterInformersSynced(Cluster1, []cache.InformerSynced{func() bool { return true }})
+	gslbutils.SetClusterInformersSynced(Cluster2, []cache.InformerSynced{
+		func() bool { return true },
+		func() bool { return cluster2Synced },
+	})
+	code, body = getReadyz(true)
+	g.Expect(code).To(gomega.Equal(http.StatusServiceUnavailable))
+	g.Expect(body).To(gomega.ContainSubstring("[+]" + Cluster1 + " synced"))
+	g.Expect(body).To(gomega.ContainSubstring("[-]" + Cluster2 + " not synced"))
+	g.Expect(gslbutils.IsAmkoReady()).To(gomega.Equal(false))
+
+	cluster2Synced = true
+	code, body = getReadyz(true)
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(body).To(gomega.ContainSubstring("[+]" + Cluster2 + " synced"))
+	g.Expect(gslbutils.IsAmkoReady()).To(gomega.Equal(true))
+
+	// without verbose, only the result is written
+	code, body = getReadyz(false)
+	g.Expect(code).To(gomega.Equal(http.StatusOK))
+	g.Expect(body).To(gomega.Equal("ok\n"))
+}
@@ -18,6 +18,7 @@ import (
 	"os"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/avinetworks/amko/gslb/gslbutils"
 	"github.com/avinetworks/amko/gslb/nodes"
@@ -203,3 +204,223 @@ func TestDeleteGS(t *testing.T) {
 
 	saveSyncAndVerify(t, modelName, gsGraph, true)
 }
+
+func TestCreateGSWithPriorities(t *testing.T) {
+	host := "host4.avi.com"
+	clusterList := []string{"foo", "bar"}
+	ipList := []string{"10.10.10.41", "10.10.10.42"}
+	names := []string{"ing1/" + host, "ing2/" + host}
+	modelName := utils.ADMIN_NS + "/" + host
+	gsGraph := buildTestGSGraph(clusterList, ipList, names, host, v1alpha1.IngressObj)
+	// "foo" is the primary cluster, "bar" gets the traffic only if "foo" has no healthy members
+	gsGraph.MemberObjs[0].Priority = 20
+	gsGraph.MemberObjs[1].Priority = gslbutils.DefaultPriority
+	gsGraph.SetRetryCounter()
+	nodes.SharedAviGSGraphLister().Save(modelName, &gsGraph)
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+
+	gsCache, found := avicache.GetAviCache().AviCacheGet(avicache.TenantName{Tenant: utils.ADMIN_NS, Name: host})
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(found).To(gomega.Equal(true))
+	gsCacheObj := gsCache.(*avicache.AviGSCache)
+	g.Expect(gsCacheObj.Members).To(gomega.HaveLen(2))
+	// the cache built from the GS with two pools must have the same checksum as the graph
+	g.Expect(gsCacheObj.CloudConfigCksum).To(gomega.Equal(gsGraph.GetChecksum()))
+}
+
+func TestCreateGSWithZeroWeightMember(t *testing.T) {
+	host := "host5.avi.com"
+	clusterList := []string{"foo", "bar"}
+	ipList := []string{"10.10.10.51", "10.10.10.52"}
+	names := []string{"ing1/" + host, "ing2/" + host}
+	modelName := utils.ADMIN_NS + "/" + host
+	gsGraph := buildTestGSGraph(clusterList, ipList, names, host, v1alpha1.IngressObj)
+	// "bar" has no share of the traffic (e.g., a percentage of 0), its member must be disabled
+	// instead of getting a ratio of 0
+	gsGraph.MemberObjs[1].Weight = gslbutils.PercentageToRatio(0)
+	gsGraph.SetRetryCounter()
+	nodes.SharedAviGSGraphLister().Save(modelName, &gsGraph)
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+
+	gsCache, found := avicache.GetAviCache().AviCacheGet(avicache.TenantName{Tenant: utils.ADMIN_NS, Name: host})
+	g := gomega.NewGomegaWithT(t)
+	g.Expect(found).To(gomega.Equal(true))
+	gsCacheObj := gsCache.(*avicache.AviGSCache)
+	g.Expect(gsCacheObj.Members).To(gomega.HaveLen(2))
+	for _, member := range gsCacheObj.Members {
+		if member.IPAddr == ipList[1] {
+			g.Expect(member.Weight).To(gomega.Equal(int32(0)))
+		}
+	}
+	// the disabled member is read back with a weight of 0, so the checksums must match
+	g.Expect(gsCacheObj.CloudConfigCksum).To(gomega.Equal(gsGraph.GetChecksum()))
+}
+
+func TestRestRateLimit(t *testing.T) {
+	qps, burst := gslbutils.GetRestRateLimit()
+	defer gslbutils.SetRestRateLimit(qps, burst)
+
+	if err := gslbutils.SetRestRateLimit(0, 1); err == nil {
+		t.Fatalf("expected an error for an invalid qps")
+	}
+	if err := gslbutils.SetRestRateLimit(1, 0); err == nil {
+		t.Fatalf("expected an error for an invalid burst")
+	}
+	if err := gslbutils.SetRestRateLimit(20, 2); err != nil {
+		t.Fatalf("error in setting the rate limits: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if gslbutils.WaitForRestRateLimit("admin/foo.avi.com") {
+			t.Fatalf("expected the calls within the burst not to be throttled")
+		}
+	}
+	start := time.Now()
+	if !gslbutils.WaitForRestRateLimit("admin/foo.avi.com") {
+		t.Fatalf("expected the call after the burst to be throttled")
+	}
+	if elapsed := time.Since(start); elapsed < 25*time.Millisecond {
+		t.Fatalf("expected the throttled call to wait, waited for %v", elapsed)
+	}
+}
+
+func TestRestMaxInFlight(t *testing.T) {
+	defer gslbutils.SetRestMaxInFlight(gslbutils.GetRestMaxInFlight())
+	g := gomega.NewGomegaWithT(t)
+
+	g.Expect(gslbutils.SetRestMaxInFlight(-1)).NotTo(gomega.Succeed())
+	g.Expect(gslbutils.SetRestMaxInFlight(2)).To(gomega.Succeed())
+	g.Expect(gslbutils.AcquireRestSlot("admin/foo.avi.com")).To(gomega.BeFalse())
+	g.Expect(gslbutils.AcquireRestSlot("admin/bar.avi.com")).To(gomega.BeFalse())
+	g.Expect(gslbutils.GetRestInFlight()).To(gomega.Equal(2))
+
+	waited := make(chan bool, 1)
+	go func() {
+		waited <- gslbutils.AcquireRestSlot("admin/baz.avi.com")
+	}()
+	g.Consistently(waited, 100*time.Millisecond).ShouldNot(gomega.Receive())
+	gslbutils.ReleaseRestSlot()
+	g.Eventually(waited, time.Second).Should(gomega.Receive(gomega.BeTrue()))
+	g.Expect(gslbutils.GetRestInFlight()).To(gomega.Equal(2))
+	gslbutils.ReleaseRestSlot()
+	gslbutils.ReleaseRestSlot()
+	g.Expect(gslbutils.GetRestInFlight()).To(gomega.Equal(0))
+
+	// the rest calls of a sync release their slots once they complete
+	host := "inflight.avi.com"
+	modelName := utils.ADMIN_NS + "/" + host
+	gsGraph := buildTestGSGraph([]string{"foo", "bar"}, []string{"10.10.10.51", "10.10.10.52"},
+		[]string{"ing1/" + host, "ing2/" + host}, host, v1alpha1.IngressObj)
+	gsGraph.SetRetryCounter()
+	nodes.SharedAviGSGraphLister().Save(modelName, &gsGraph)
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+	g.Eventually(gslbutils.GetRestInFlight, time.Second).Should(gomega.Equal(0))
+}
+
+func TestInMemoryPublisher(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	publisher := rest.NewInMemoryPublisher()
+	rest.SetPublisher(publisher)
+	defer rest.SetPublisher(nil)
+
+	host := "publisher.avi.com"
+	modelName := utils.ADMIN_NS + "/" + host
+	gsGraph := buildTestGSGraph([]string{"foo"}, []string{"10.10.10.51"}, []string{"ing1/" + host}, host,
+		v1alpha1.IngressObj)
+	gsGraph.MemberObjs[0].Priority = gslbutils.DefaultPriority
+	agl := nodes.SharedAviGSGraphLister()
+	agl.Save(modelName, &gsGraph)
+	defer agl.Delete(modelName)
+
+	// a new GS is created, and syncing it again without any changes is a no-op
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+	ops := publisher.GetOperations()
+	g.Expect(ops).To(gomega.HaveLen(1))
+	g.Expect(ops[0].Method).To(gomega.Equal(utils.RestPost))
+	g.Expect(ops[0].GSName).To(gomega.Equal(host))
+	g.Expect(ops[0].DomainNames).To(gomega.Equal([]string{host}))
+	g.Expect(ops[0].Members).To(gomega.Equal([]string{"10.10.10.51-10"}))
+	// nothing is synced to the Avi controller
+	_, found := avicache.GetAviCache().AviCacheGet(avicache.TenantName{Tenant: utils.ADMIN_NS, Name: host})
+	g.Expect(found).To(gomega.BeFalse())
+
+	// a changed GS is updated
+	publisher.ClearOperations()
+	gsGraph.MemberObjs = append(gsGraph.MemberObjs, nodes.AviGSK8sObj{Cluster: "bar", ObjType: v1alpha1.IngressObj,
+		Name: "ing2/" + host, Namespace: DefaultNS, IPAddr: "10.10.10.52", Weight: 5, Priority: gslbutils.DefaultPriority})
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+	ops = publisher.GetOperations()
+	g.Expect(ops).To(gomega.HaveLen(1))
+	g.Expect(ops[0].Method).To(gomega.Equal(utils.RestPut))
+	g.Expect(ops[0].Members).To(gomega.Equal([]string{"10.10.10.51-10", "10.10.10.52-5"}))
+	gs, ok := publisher.GetGS(modelName)
+	g.Expect(ok).To(gomega.BeTrue())
+	g.Expect(gs.Checksum).To(gomega.Equal(gsGraph.GetChecksum()))
+
+	// a deleted GS is deleted once, and a GS never published isn't deleted
+	publisher.ClearOperations()
+	agl.Delete(modelName)
+	nodes.SharedDeleteGSGraphLister().Save(modelName, &gsGraph)
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+	rest.SyncFromNodesLayer(utils.ADMIN_NS+"/unknown.avi.com", &sync.WaitGroup{})
+	ops = publisher.GetOperations()
+	g.Expect(ops).To(gomega.HaveLen(1))
+	g.Expect(ops[0].Method).To(gomega.Equal(utils.RestDelete))
+	_, ok = publisher.GetGS(modelName)
+	g.Expect(ok).To(gomega.BeFalse())
+	found, _ = nodes.SharedDeleteGSGraphLister().Get(modelName)
+	g.Expect(found).To(gomega.BeFalse())
+}
+
+// syncWithheldAndVerify syncs a GS graph which is expected to be withheld, i.e. not in the cache.
+func syncWithheldAndVerify(t *testing.T, modelName string, gsGraph nodes.AviGSObjectGraph) {
+	gsGraph.SetRetryCounter()
+	nodes.SharedAviGSGraphLister().Save(modelName, &gsGraph)
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+	verifyInAviCache(t, gsGraph, true)
+}
+
+func TestMinHealthyClusters(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	minHealthy := 2
+	gf := gslbutils.GetGlobalFilter()
+	gf.GlobalLock.Lock()
+	gf.MinHealthyClusters = &minHealthy
+	gf.GlobalLock.Unlock()
+	defer gslbutils.ResetGlobalFilter()
+
+	host := "minhealthy.avi.com"
+	modelName := utils.ADMIN_NS + "/" + host
+	names := []string{"ing1/" + host, "ing2/" + host}
+	withheld := []v1alpha1.WithheldService{{Name: host, Tenant: utils.ADMIN_NS, HealthyClusters: 1}}
+
+	// a GS with a single healthy cluster is withheld
+	gsGraph := buildTestGSGraph([]string{"foo"}, []string{"10.10.10.61"}, names[:1], host, v1alpha1.IngressObj)
+	syncWithheldAndVerify(t, modelName, gsGraph)
+	g.Expect(gslbutils.GetWithheldServices()).To(gomega.Equal(withheld))
+
+	// a member with a weight of 0 doesn't make its cluster healthy
+	gsGraph = buildTestGSGraph([]string{"foo", "bar"}, []string{"10.10.10.61", "10.10.10.62"}, names, host,
+		v1alpha1.IngressObj)
+	gsGraph.MemberObjs[1].Weight = 0
+	syncWithheldAndVerify(t, modelName, gsGraph)
+	g.Expect(gslbutils.GetWithheldServices()).To(gomega.Equal(withheld))
+
+	// the GS is created once it has enough healthy clusters
+	gsGraph.MemberObjs[1].Weight = 10
+	saveSyncAndVerify(t, modelName, gsGraph, false)
+	g.Expect(gslbutils.GetWithheldServices()).To(gomega.BeEmpty())
+
+	// and deleted again once it doesn't
+	gsGraph = buildTestGSGraph([]string{"foo"}, []string{"10.10.10.61"}, names[:1], host, v1alpha1.IngressObj)
+	syncWithheldAndVerify(t, modelName, gsGraph)
+	g.Expect(gslbutils.GetWithheldServices()).To(gomega.Equal(withheld))
+
+	// a deleted GS isn't withheld anymore
+	gsGraph.SetRetryCounter()
+	nodes.SharedAviGSGraphLister().Delete(modelName)
+	nodes.SharedDeleteGSGraphLister().Save(modelName, &gsGraph)
+	rest.SyncFromNodesLayer(modelName, &sync.WaitGroup{})
+	g.Expect(gslbutils.GetWithheldServices()).To(gomega.BeEmpty())
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package resync
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/onsi/gomega"
+)
+
+func requestResync(method string) int {
+	req := httptest.NewRequest(method, gslbutils.ForceResyncPath, nil)
+	rec := httptest.NewRecorder()
+	gslbutils.ForceResyncHandler(rec, req)
+	return rec.Code
+}
+
+func postResync() int {
+	return requestResync("POST")
+}
+
+func TestResyncAPIEnabled(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	os.Unsetenv(gslbutils.EnableResyncAPIEnv)
+	g.Expect(gslbutils.ResyncAPIEnabled()).To(gomega.BeFalse())
+	os.Setenv(gslbutils.EnableResyncAPIEnv, "invalid")
+	g.Expect(gslbutils.ResyncAPIEnabled()).To(gomega.BeFalse())
+	os.Setenv(gslbutils.EnableResyncAPIEnv, "true")
+	g.Expect(gslbutils.ResyncAPIEnabled()).To(gomega.BeTrue())
+	os.Unsetenv(gslbutils.EnableResyncAPIEnv)
+
+	// only POST is allowed
+	g.Expect(requestResync("GET")).To(gomega.Equal(http.StatusMethodNotAllowed))
+}
+
+func TestForceResyncCoalesce(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+
+	// no resync function set yet
+	g.Expect(postResync()).To(gomega.Equal(http.StatusServiceUnavailable))
+	_, err := gslbutils.ForceResync()
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	var syncCount int32
+	started := make(chan struct{}, 10)
+	release := make(chan struct{})
+	gslbutils.SetForceResyncFunc(func() {
+		atomic.AddInt32(&syncCount, 1)
+		started <- struct{}{}
+		<-release
+	})
+
+	done := make(chan bool)
+	go func() {
+		ran, _ := gslbutils.ForceResync()
+		done <- ran
+	}()
+	<-started
+
+	// the requests during a running resync are coalesced into one more resync
+	for i := 0; i < 3; i++ {
+		ran, err := gslbutils.ForceResync()
+		g.Expect(err).NotTo(gomega.HaveOccurred())
+		g.Expect(ran).To(gomega.Equal(false))
+	}
+
+	close(release)
+	g.Expect(<-done).To(gomega.Equal(true))
+	g.Expect(atomic.LoadInt32(&syncCount)).To(gomega.Equal(int32(2)))
+
+	// a resync after the completion of the previous ones runs again
+	ran, err := gslbutils.ForceResync()
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	g.Expect(ran).To(gomega.Equal(true))
+	g.Expect(atomic.LoadInt32(&syncCount)).To(gomega.Equal(int32(3)))
+
+	// the endpoint runs the resync in the background
+	g.Expect(postResync()).To(gomega.Equal(http.StatusAccepted))
+	g.Eventually(func() int32 {
+		return atomic.LoadInt32(&syncCount)
+	}, 2*time.Second).Should(gomega.Equal(int32(4)))
+}
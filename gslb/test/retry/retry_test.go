@@ -0,0 +1,352 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package retry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	avicache "github.com/avinetworks/amko/gslb/cache"
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/nodes"
+	"github.com/avinetworks/amko/gslb/rest"
+	"github.com/avinetworks/amko/gslb/retry"
+
+	"github.com/avinetworks/sdk/go/session"
+	"github.com/onsi/gomega"
+	"github.com/vmware/load-balancer-and-ingress-services-for-kubernetes/pkg/utils"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	TestNS      = "default"
+	TestCluster = "cluster1"
+
+	NumRetryWorkers = 2
+)
+
+func TestMain(m *testing.M) {
+	// the retry queues have more than one worker, so that the keys are spread across the workers
+	gslbutils.SetNumRetryWorkers(NumRetryWorkers)
+	// the queue parameters are built in place, as a utils.WorkerQueue can't be copied
+	queueParams := append([]utils.WorkerQueue{{NumWorkers: 1, WorkqueueName: utils.GraphLayer}},
+		gslbutils.GetRetryQueueParams()...)
+	utils.SharedWorkQueue(queueParams...)
+	os.Exit(m.Run())
+}
+
+func getGraphQueueLen() int {
+	return utils.SharedWorkQueue().GetQueueByName(utils.GraphLayer).Workqueue[0].Len()
+}
+
+func getRetryQueueLen(queueName string) int {
+	qlen := 0
+	for _, wq := range utils.SharedWorkQueue().GetQueueByName(queueName).Workqueue {
+		qlen += wq.Len()
+	}
+	return qlen
+}
+
+func getRetryQueuesLen() int {
+	return getRetryQueueLen(gslbutils.SlowRetryQueue) + getRetryQueueLen(gslbutils.FastRetryQueue)
+}
+
+// dequeueRetryKey fetches a key from the retry queue queueName the way the retry layer's worker does,
+// from the first worker's queue which has a key.
+func dequeueRetryKey(queueName string) string {
+	var wq workqueue.RateLimitingInterface
+	for _, wq = range utils.SharedWorkQueue().GetQueueByName(queueName).Workqueue {
+		if wq.Len() > 0 {
+			break
+		}
+	}
+	item, _ := wq.Get()
+	wq.Forget(item)
+	wq.Done(item)
+	return item.(string)
+}
+
+func drainGraphQueue() {
+	wq := utils.SharedWorkQueue().GetQueueByName(utils.GraphLayer).Workqueue[0]
+	for wq.Len() > 0 {
+		item, _ := wq.Get()
+		wq.Forget(item)
+		wq.Done(item)
+	}
+}
+
+func addTestGSGraph(host string) string {
+	key := utils.ADMIN_NS + "/" + host
+	gsGraph := &nodes.AviGSObjectGraph{
+		Name:   host,
+		Tenant: utils.ADMIN_NS,
+		MemberObjs: []nodes.AviGSK8sObj{
+			{
+				Cluster:   TestCluster,
+				ObjType:   gslbutils.RouteType,
+				Name:      "route1",
+				Namespace: TestNS,
+				IPAddr:    "10.10.10.10",
+				Weight:    1,
+			},
+		},
+	}
+	nodes.SharedAviGSGraphLister().Save(key, gsGraph)
+	return key
+}
+
+func TestRestErrorClassification(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	for _, code := range []int{404, 409, 500, 501, 502, 503, 504} {
+		g.Expect(gslbutils.NewRestError(code, "").Transient).To(gomega.Equal(true))
+	}
+	g.Expect(gslbutils.NewRestError(400, "bad request").Transient).To(gomega.Equal(false))
+	g.Expect(gslbutils.NewRestError(403, "forbidden").Transient).To(gomega.Equal(false))
+
+	gslbutils.SetTransientStatusCodes([]int{500})
+	defer gslbutils.SetTransientStatusCodes([]int{404, 409, 500, 501, 502, 503, 504})
+	g.Expect(gslbutils.NewRestError(503, "").Transient).To(gomega.Equal(false))
+	g.Expect(gslbutils.NewRestError(500, "").Transient).To(gomega.Equal(true))
+}
+
+func TestPublishKeyToRetryLayer(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	restOp := rest.NewRestOperations(nil, nil, nil)
+
+	_, err := gslbutils.ParseTransientStatusCodes("500, 503")
+	g.Expect(err).NotTo(gomega.HaveOccurred())
+	_, err = gslbutils.ParseTransientStatusCodes("500,abc")
+	g.Expect(err).To(gomega.HaveOccurred())
+	_, err = gslbutils.ParseTransientStatusCodes("200")
+	g.Expect(err).To(gomega.HaveOccurred())
+
+	// a transient error is retried via the slow retry queue, any other error is moved to the dead
+	// letter by the retry layer
+	for code, expected := range map[int]gslbutils.RestError{
+		504: {StatusCode: 504, Transient: true},
+		403: {StatusCode: 403, Transient: false},
+		422: {StatusCode: 422, Transient: false},
+	} {
+		key := addTestGSGraph("publish-" + strconv.Itoa(code) + ".avi.com")
+		gsKey := avicache.TenantName{Tenant: utils.ADMIN_NS, Name: "publish-" + strconv.Itoa(code) + ".avi.com"}
+		restOp.PublishKeyToRetryLayer(&gsKey, nil, session.AviError{HttpStatusCode: code}, key)
+
+		queue, pending := gslbutils.GetRetryPendingQueue(key)
+		g.Expect(pending).To(gomega.BeTrue())
+		if expected.Transient {
+			g.Expect(queue).To(gomega.Equal(gslbutils.SlowRetryQueue))
+		}
+		restErr, found := gslbutils.GetAndDeleteRetryError(key)
+		g.Expect(found).To(gomega.BeTrue())
+		g.Expect(restErr.StatusCode).To(gomega.Equal(expected.StatusCode))
+		g.Expect(restErr.Transient).To(gomega.Equal(expected.Transient))
+
+		queueName := gslbutils.SlowRetryQueue
+		if !expected.Transient {
+			queueName = gslbutils.FastRetryQueue
+		}
+		g.Eventually(func() int { return getRetryQueueLen(queueName) }, 5*time.Second).Should(gomega.Equal(1))
+		dequeueRetryKey(queueName)
+		gslbutils.ClearRetryPending(key)
+		nodes.SharedAviGSGraphLister().Delete(key)
+	}
+}
+
+func TestTransientErrorIsRetried(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	drainGraphQueue()
+	key := addTestGSGraph("transient.avi.com")
+	defer nodes.SharedAviGSGraphLister().Delete(key)
+
+	gslbutils.SetRetryError(key, gslbutils.NewRestError(503, "service unavailable"))
+	retry.SyncFromRetryLayer(key, &sync.WaitGroup{})
+
+	// the key is added to the graph layer after the rate limiter's delay
+	g.Eventually(getGraphQueueLen, 5*time.Second).Should(gomega.Equal(1))
+	_, found := gslbutils.GetAndDeleteRetryError(key)
+	g.Expect(found).To(gomega.Equal(false))
+	for _, entry := range gslbutils.GetDeadLetterEntries() {
+		g.Expect(entry.Key).NotTo(gomega.Equal(key))
+	}
+	drainGraphQueue()
+}
+
+func TestPermanentErrorMovedToDeadLetter(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	drainGraphQueue()
+	key := addTestGSGraph("permanent.avi.com")
+	defer nodes.SharedAviGSGraphLister().Delete(key)
+	defer gslbutils.DeleteFromDeadLetter(key)
+
+	recorder := record.NewFakeRecorder(10)
+	gslbutils.SetClusterEventRecorder(TestCluster, recorder)
+
+	gslbutils.SetRetryError(key, gslbutils.NewRestError(400, "invalid field"))
+	retry.SyncFromRetryLayer(key, &sync.WaitGroup{})
+
+	g.Consistently(getGraphQueueLen, 2*time.Second).Should(gomega.Equal(0))
+	found := false
+	for _, entry := range gslbutils.GetDeadLetterEntries() {
+		if entry.Key == key {
+			found = true
+			g.Expect(entry.Err.StatusCode).To(gomega.Equal(400))
+		}
+	}
+	g.Expect(found).To(gomega.Equal(true))
+	g.Expect(recorder.Events).To(gomega.HaveLen(1))
+	g.Expect(<-recorder.Events).To(gomega.ContainSubstring(gslbutils.GSLBServiceSyncFailed))
+
+	g.Expect(gslbutils.DeleteFromDeadLetter(key)).To(gomega.Equal(true))
+}
+
+func TestDuplicateRetryKeysCollapse(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	drainGraphQueue()
+	key := addTestGSGraph("duplicate.avi.com")
+	defer nodes.SharedAviGSGraphLister().Delete(key)
+
+	// the same GS fails repeatedly, with errors to be retried in both the retry queues
+	gslbutils.PublishToRetryQueue(gslbutils.FastRetryQueue, key, gslbutils.NewRestError(409, "conflict"))
+	gslbutils.PublishToRetryQueue(gslbutils.FastRetryQueue, key, gslbutils.NewRestError(409, "conflict"))
+	gslbutils.PublishToRetryQueue(gslbutils.SlowRetryQueue, key, gslbutils.NewRestError(503, "service unavailable"))
+
+	g.Eventually(getRetryQueuesLen, 5*time.Second).Should(gomega.Equal(1))
+	g.Consistently(getRetryQueuesLen, 2*time.Second).Should(gomega.Equal(1))
+	queueName, pending := gslbutils.GetRetryPendingQueue(key)
+	g.Expect(pending).To(gomega.Equal(true))
+	g.Expect(queueName).To(gomega.Equal(gslbutils.FastRetryQueue))
+
+	// a single retry is processed for the GS
+	g.Expect(dequeueRetryKey(queueName)).To(gomega.Equal(key))
+	retry.SyncFromRetryLayer(key, &sync.WaitGroup{})
+	g.Eventually(getGraphQueueLen, 5*time.Second).Should(gomega.Equal(1))
+	_, pending = gslbutils.GetRetryPendingQueue(key)
+	g.Expect(pending).To(gomega.Equal(false))
+	g.Expect(getRetryQueuesLen()).To(gomega.Equal(0))
+	drainGraphQueue()
+
+	// once dequeued by the retry layer, the key can be published for retry again
+	gslbutils.PublishToRetryQueue(gslbutils.SlowRetryQueue, key, gslbutils.NewRestError(503, "service unavailable"))
+	g.Eventually(getRetryQueuesLen, 5*time.Second).Should(gomega.Equal(1))
+	g.Expect(dequeueRetryKey(gslbutils.SlowRetryQueue)).To(gomega.Equal(key))
+	gslbutils.ClearRetryPending(key)
+	gslbutils.GetAndDeleteRetryError(key)
+}
+
+func TestRetryQueueOverflow(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	drainGraphQueue()
+	g.Expect(gslbutils.SetRetryQueueMaxDepth(-1)).NotTo(gomega.Succeed())
+	g.Expect(gslbutils.SetRetryQueueOverflowPolicy("drop-all")).NotTo(gomega.Succeed())
+	g.Expect(gslbutils.SetRetryQueueMaxDepth(2)).To(gomega.Succeed())
+	defer gslbutils.SetRetryQueueMaxDepth(0)
+	defer gslbutils.SetRetryQueueOverflowPolicy(gslbutils.RetryQueueOverflowRejectNew)
+
+	keys := []string{}
+	for _, host := range []string{"overflow1.avi.com", "overflow2.avi.com", "overflow3.avi.com"} {
+		key := addTestGSGraph(host)
+		defer nodes.SharedAviGSGraphLister().Delete(key)
+		keys = append(keys, key)
+	}
+	restErr := gslbutils.NewRestError(503, "service unavailable")
+	overflows := gslbutils.GetRetryQueueOverflows()
+
+	// the new keys are rejected once the retry queues are full
+	for _, key := range keys {
+		gslbutils.PublishToRetryQueue(gslbutils.SlowRetryQueue, key, restErr)
+	}
+	g.Expect(gslbutils.GetRetryQueueDepth()).To(gomega.Equal(2))
+	g.Expect(gslbutils.GetRetryQueueOverflows()).To(gomega.Equal(overflows + 1))
+	_, pending := gslbutils.GetRetryPendingQueue(keys[2])
+	g.Expect(pending).To(gomega.BeFalse())
+	_, found := gslbutils.GetAndDeleteRetryError(keys[2])
+	g.Expect(found).To(gomega.BeFalse())
+
+	// the oldest key is dropped to make room for a new key, and isn't retried once dequeued
+	g.Expect(gslbutils.SetRetryQueueOverflowPolicy(gslbutils.RetryQueueOverflowDropOldest)).To(gomega.Succeed())
+	drops := gslbutils.GetRetryQueueDrops()
+	gslbutils.PublishToRetryQueue(gslbutils.SlowRetryQueue, keys[2], restErr)
+	g.Expect(gslbutils.GetRetryQueueDepth()).To(gomega.Equal(2))
+	g.Expect(gslbutils.GetRetryQueueOverflows()).To(gomega.Equal(overflows + 2))
+	g.Expect(gslbutils.GetRetryQueueDrops()).To(gomega.Equal(drops + 1))
+	_, pending = gslbutils.GetRetryPendingQueue(keys[0])
+	g.Expect(pending).To(gomega.BeFalse())
+	// the dropped key is forgotten by the rate limiter of its queue
+	slowQueue := utils.SharedWorkQueue().GetQueueByName(gslbutils.SlowRetryQueue)
+	g.Expect(slowQueue.Workqueue[utils.Bkt(keys[0], slowQueue.NumWorkers)].NumRequeues(keys[0])).To(gomega.Equal(0))
+
+	g.Eventually(getRetryQueuesLen, 5*time.Second).Should(gomega.Equal(3))
+	for i := 0; i < 3; i++ {
+		retry.SyncFromRetryLayer(dequeueRetryKey(gslbutils.SlowRetryQueue), &sync.WaitGroup{})
+	}
+	g.Eventually(getGraphQueueLen, 5*time.Second).Should(gomega.Equal(2))
+	g.Consistently(getGraphQueueLen, time.Second).Should(gomega.Equal(2))
+	g.Expect(gslbutils.GetRetryQueueDepth()).To(gomega.Equal(0))
+	drainGraphQueue()
+
+	rr := httptest.NewRecorder()
+	gslbutils.MetricsHandler(rr, httptest.NewRequest("GET", gslbutils.MetricsPath, nil))
+	g.Expect(rr.Code).To(gomega.Equal(http.StatusOK))
+	g.Expect(rr.Body.String()).To(gomega.ContainSubstring("amko_retry_queue_depth 0\n"))
+	g.Expect(rr.Body.String()).To(gomega.ContainSubstring("amko_retry_queue_max_depth 2\n"))
+	g.Expect(rr.Body.String()).To(gomega.ContainSubstring("amko_retry_queue_drops_total "))
+}
+
+func TestRetryWorkers(t *testing.T) {
+	g := gomega.NewGomegaWithT(t)
+	drainGraphQueue()
+	g.Expect(gslbutils.SetNumRetryWorkers(0)).NotTo(gomega.Succeed())
+	g.Expect(gslbutils.SetNumRetryWorkers(gslbutils.MaxNumRetryWorkers + 1)).NotTo(gomega.Succeed())
+	g.Expect(gslbutils.GetNumRetryWorkers()).To(gomega.Equal(uint32(NumRetryWorkers)))
+
+	// the keys are spread across the workers of the queue, each key always to the same worker
+	fastQueue := utils.SharedWorkQueue().GetQueueByName(gslbutils.FastRetryQueue)
+	g.Expect(fastQueue.NumWorkers).To(gomega.Equal(uint32(NumRetryWorkers)))
+	restErr := gslbutils.NewRestError(503, "service unavailable")
+	bktLens := make([]int, NumRetryWorkers)
+	keys := []string{}
+	for i := 0; i < 10; i++ {
+		key := addTestGSGraph("worker" + strconv.Itoa(i) + ".avi.com")
+		defer nodes.SharedAviGSGraphLister().Delete(key)
+		keys = append(keys, key)
+		gslbutils.PublishToRetryQueue(gslbutils.FastRetryQueue, key, restErr)
+		bktLens[utils.Bkt(key, fastQueue.NumWorkers)]++
+	}
+	g.Expect(bktLens).NotTo(gomega.ContainElement(0))
+	for bkt, wq := range fastQueue.Workqueue {
+		g.Eventually(wq.Len, 5*time.Second).Should(gomega.Equal(bktLens[bkt]))
+	}
+	g.Expect(gslbutils.GetRetryQueueDepthByName(gslbutils.FastRetryQueue)).To(gomega.Equal(len(keys)))
+	g.Expect(gslbutils.GetRetryQueueDepthByName(gslbutils.SlowRetryQueue)).To(gomega.Equal(0))
+
+	rr := httptest.NewRecorder()
+	gslbutils.MetricsHandler(rr, httptest.NewRequest("GET", gslbutils.MetricsPath, nil))
+	g.Expect(rr.Body.String()).To(gomega.ContainSubstring("amko_fast_retry_queue_depth 10\n"))
+	g.Expect(rr.Body.String()).To(gomega.ContainSubstring("amko_slow_retry_queue_depth 0\n"))
+	g.Expect(rr.Body.String()).To(gomega.ContainSubstring("amko_retry_workers 2\n"))
+
+	for range keys {
+		retry.SyncFromRetryLayer(dequeueRetryKey(gslbutils.FastRetryQueue), &sync.WaitGroup{})
+	}
+	g.Expect(gslbutils.GetRetryQueueDepthByName(gslbutils.FastRetryQueue)).To(gomega.Equal(0))
+	g.Eventually(getGraphQueueLen, 5*time.Second).Should(gomega.Equal(len(keys)))
+	drainGraphQueue()
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package store
+
+import (
+	"testing"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+)
+
+func TestClusterKeyRoundTrip(t *testing.T) {
+	testCases := [][3]string{
+		{"cluster1", "default", "route1"},
+		{"cluster1", "ns/with/slashes", "obj%name"},
+		{"cluster/x", "default", "ing1/foo.avi.com"},
+		{"cluster1", "", "%2F"},
+	}
+	for _, tc := range testCases {
+		key := gslbutils.GetClusterKey(tc[0], tc[1], tc[2])
+		cname, ns, name, err := gslbutils.ParseClusterKey(key)
+		if err != nil {
+			t.Fatalf("error in parsing key %s: %v", key, err)
+		}
+		if cname != tc[0] || ns != tc[1] || name != tc[2] {
+			t.Fatalf("expected %v, got [%s %s %s] for key %s", tc, cname, ns, name, key)
+		}
+	}
+
+	// keys without any special characters remain unchanged
+	if key := gslbutils.GetClusterKey("cluster1", "default", "route1"); key != "cluster1/default/route1" {
+		t.Fatalf("unexpected key %s", key)
+	}
+}
+
+func TestParseClusterKeyErrors(t *testing.T) {
+	for _, key := range []string{"", "cluster1/default", "cluster1/default/a/b", "cluster1/default/bad%2"} {
+		if _, _, _, err := gslbutils.ParseClusterKey(key); err == nil {
+			t.Fatalf("expected an error for key %s", key)
+		}
+	}
+}
+
+func TestMultiClusterKeyRoundTrip(t *testing.T) {
+	key := gslbutils.MultiClusterKey(gslbutils.ObjectAdd, gslbutils.IngressType, "cluster1", "default",
+		"ing1/foo.avi.com")
+	op, objType, cname, ns, name := gslbutils.ExtractMultiClusterKey(key)
+	if op != gslbutils.ObjectAdd || objType != gslbutils.IngressType || cname != "cluster1" || ns != "default" ||
+		name != "ing1/foo.avi.com" {
+		t.Fatalf("unexpected values extracted from key %s: %s, %s, %s, %s, %s", key, op, objType, cname, ns, name)
+	}
+
+	tenant, gsName := gslbutils.ExtractTenantAndGSName(gslbutils.GetModelKey("admin", "foo.avi.com"))
+	if tenant != "admin" || gsName != "foo.avi.com" {
+		t.Fatalf("unexpected tenant %s and gs name %s", tenant, gsName)
+	}
+}
+
+func TestParseGSKey(t *testing.T) {
+	testCases := []struct {
+		key    string
+		tenant string
+		gsName string
+	}{
+		{gslbutils.GetModelKey("admin", "foo.avi.com"), "admin", "foo.avi.com"},
+		{gslbutils.GetModelKey("tenant/x", "foo.avi.com"), "tenant/x", "foo.avi.com"},
+		{"foo.avi.com", "", "foo.avi.com"},
+	}
+	for _, tc := range testCases {
+		gsKey, err := gslbutils.ParseGSKey(tc.key)
+		if err != nil {
+			t.Fatalf("error in parsing key %s: %v", tc.key, err)
+		}
+		if gsKey.Tenant != tc.tenant || gsKey.GSName != tc.gsName {
+			t.Fatalf("expected tenant %s and gs name %s, got %v for key %s", tc.tenant, tc.gsName, gsKey, tc.key)
+		}
+		if gsKey.String() != tc.key {
+			t.Fatalf("expected key %s, got %s", tc.key, gsKey.String())
+		}
+	}
+
+	// the keys without a tenant belong to the default tenant
+	tenant, gsName := gslbutils.ExtractTenantAndGSName("foo.avi.com")
+	if tenant != gslbutils.DefaultTenant || gsName != "foo.avi.com" {
+		t.Fatalf("unexpected tenant %s and gs name %s", tenant, gsName)
+	}
+
+	for _, key := range []string{"", "admin/", "admin/foo.avi.com/x", "admin/bad%2"} {
+		if _, err := gslbutils.ParseGSKey(key); err == nil {
+			t.Fatalf("expected an error for key %s", key)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package store
+
+import (
+	"testing"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+)
+
+const (
+	OldCluster = "rename-old"
+	NewCluster = "rename-new"
+	TestNS     = "default"
+)
+
+func getRenameTestGDP() *gdpv1alpha1.GlobalDeploymentPolicy {
+	gdp := &gdpv1alpha1.GlobalDeploymentPolicy{}
+	gdp.ObjectMeta.Name = "test-gdp"
+	gdp.ObjectMeta.Namespace = gslbutils.AVISystem
+	gdp.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"key": "value"}
+	gdp.Spec.MatchClusters = []string{OldCluster, "other-cluster"}
+	gdp.Spec.TrafficSplit = []gdpv1alpha1.TrafficSplitElem{
+		{Cluster: OldCluster, Weight: 5},
+		{Cluster: "other-cluster", Weight: 10},
+	}
+	return gdp
+}
+
+func TestRenameCluster(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+
+	gdp := getRenameTestGDP()
+	gf := gslbutils.GetGlobalFilter()
+	gf.AddToFilter(gdp)
+	if err := gf.AddNSToNSFilter(OldCluster, TestNS); err != nil {
+		t.Fatalf("error in adding namespace to the filter: %v", err)
+	}
+	route := k8sobjects.RouteMeta{Cluster: OldCluster, Namespace: TestNS, Name: "route1", Hostname: "route1.avi.com"}
+	gslbutils.GetAcceptedRouteStore().AddOrUpdate(route, OldCluster, TestNS, route.Name)
+	route.UpdateHostMap(gslbutils.GetClusterKey(OldCluster, TestNS, route.Name))
+	defer route.DeleteMapByKey(gslbutils.GetClusterKey(NewCluster, TestNS, route.Name))
+	nsMeta := k8sobjects.NSMeta{Cluster: OldCluster, Name: TestNS}
+	gslbutils.GetAcceptedNSStore().AddOrUpdate(OldCluster, TestNS, nsMeta)
+	oldCksum := gf.GetChecksum()
+	sub := gslbutils.GetAcceptedRouteStore().Subscribe()
+	defer gslbutils.GetAcceptedRouteStore().Unsubscribe(sub)
+
+	if err := gslbutils.RenameCluster(OldCluster, NewCluster); err != nil {
+		t.Fatalf("error in renaming the cluster: %v", err)
+	}
+	// the subscribers see the route deleted for the old cluster name and added for the new one
+	for _, expected := range []gslbutils.StoreEvent{
+		{Type: gslbutils.StoreEventDelete, Cluster: OldCluster, Namespace: TestNS, Name: route.Name},
+		{Type: gslbutils.StoreEventAdd, Cluster: NewCluster, Namespace: TestNS, Name: route.Name},
+	} {
+		select {
+		case ev := <-sub:
+			if ev.Type != expected.Type || ev.Cluster != expected.Cluster || ev.Namespace != expected.Namespace ||
+				ev.Name != expected.Name {
+				t.Fatalf("expected event %+v, got %+v", expected, ev)
+			}
+			if ev.Obj.(k8sobjects.RouteMeta).Cluster != expected.Cluster {
+				t.Fatalf("expected the object of the event to be of cluster %s, got %+v", expected.Cluster, ev.Obj)
+			}
+		default:
+			t.Fatalf("expected event %+v, got none", expected)
+		}
+	}
+
+	if _, ok := gslbutils.GetAcceptedRouteStore().GetClusterNSObjectByName(OldCluster, TestNS, route.Name); ok {
+		t.Fatalf("expected the route to be removed for the old cluster name")
+	}
+	obj, ok := gslbutils.GetAcceptedRouteStore().GetClusterNSObjectByName(NewCluster, TestNS, route.Name)
+	if !ok {
+		t.Fatalf("expected the route to be present for the new cluster name")
+	}
+	if obj.(k8sobjects.RouteMeta).Cluster != NewCluster {
+		t.Fatalf("expected the cluster of the route to be %s, got %s", NewCluster, obj.(k8sobjects.RouteMeta).Cluster)
+	}
+	if route.GetHostnameFromHostMap(gslbutils.GetClusterKey(NewCluster, TestNS, route.Name)) != route.Hostname ||
+		route.GetHostnameFromHostMap(gslbutils.GetClusterKey(OldCluster, TestNS, route.Name)) != "" {
+		t.Fatalf("expected the host map entry of the route to be re-keyed to the new cluster name")
+	}
+	obj, ok = gslbutils.GetAcceptedNSStore().GetNSObjectByName(NewCluster, TestNS)
+	if !ok || obj.(k8sobjects.NSMeta).Cluster != NewCluster {
+		t.Fatalf("expected the namespace to be present for the new cluster name")
+	}
+
+	if !gf.IsClusterAllowed(NewCluster) || gf.IsClusterAllowed(OldCluster) {
+		t.Fatalf("expected only the new cluster name in the applicable clusters: %v", gf.ApplicableClusters)
+	}
+	if gdp.Spec.MatchClusters[0] != OldCluster {
+		t.Fatalf("expected the GDP object to be unchanged, got %v", gdp.Spec.MatchClusters)
+	}
+	if gf.TrafficSplit[0].ClusterName != NewCluster || gf.TrafficSplit[0].Weight != 5 {
+		t.Fatalf("expected the traffic split to be renamed, got %v", gf.TrafficSplit[0])
+	}
+	if _, ok := gf.NSFilter.SelectedNS[OldCluster]; ok {
+		t.Fatalf("expected no selected namespaces for the old cluster name")
+	}
+	if !gslbutils.PresentInList(TestNS, gf.NSFilter.SelectedNS[NewCluster]) {
+		t.Fatalf("expected the selected namespaces for the new cluster name")
+	}
+	if gf.GetChecksum() == oldCksum {
+		t.Fatalf("expected the checksum of the filter to change")
+	}
+
+	if err := gslbutils.RenameCluster(NewCluster, "other-cluster"); err == nil {
+		t.Fatalf("expected an error in renaming to an existing cluster")
+	}
+	if err := gslbutils.RenameCluster(OldCluster, "unknown-cluster"); err == nil {
+		t.Fatalf("expected an error in renaming an unknown cluster")
+	}
+}
@@ -0,0 +1,233 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package store
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/ingestion"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+)
+
+const (
+	TestCluster = "cluster1"
+	NumObjs     = 5000
+	NumNS       = 10
+)
+
+func getStoreItems(n int) []gslbutils.StoreItem {
+	items := make([]gslbutils.StoreItem, 0, n)
+	for i := 0; i < n; i++ {
+		items = append(items, gslbutils.StoreItem{
+			Namespace: "ns-" + strconv.Itoa(i%NumNS),
+			ObjName:   "obj-" + strconv.Itoa(i),
+			Obj:       i,
+		})
+	}
+	return items
+}
+
+func TestAddOrUpdateBatch(t *testing.T) {
+	cs := gslbutils.NewClusterStore()
+	items := getStoreItems(100)
+	cs.AddOrUpdateBatch(TestCluster, items)
+
+	for _, item := range items {
+		obj, ok := cs.GetClusterNSObjectByName(TestCluster, item.Namespace, item.ObjName)
+		if !ok {
+			t.Fatalf("object %s/%s not found in the store", item.Namespace, item.ObjName)
+		}
+		if obj.(int) != item.Obj.(int) {
+			t.Fatalf("object %s/%s mismatch, expected %v, got %v", item.Namespace, item.ObjName, item.Obj, obj)
+		}
+	}
+	if objs := cs.GetAllClusterNSObjects(); len(objs) != len(items) {
+		t.Fatalf("expected %d objects in the store, got %d", len(items), len(objs))
+	}
+
+	// an update via the batch should overwrite the existing objects
+	items[0].Obj = -1
+	cs.AddOrUpdateBatch(TestCluster, items[:1])
+	obj, _ := cs.GetClusterNSObjectByName(TestCluster, items[0].Namespace, items[0].ObjName)
+	if obj.(int) != -1 {
+		t.Fatalf("expected the object to be updated to -1, got %v", obj)
+	}
+
+	// an empty batch shouldn't initialize a cluster
+	cs.AddOrUpdateBatch("cluster2", nil)
+	if len(cs.GetAllClusters()) != 1 {
+		t.Fatalf("expected only %s in the store, got %v", TestCluster, cs.GetAllClusters())
+	}
+}
+
+// The baseline for adding NumObjs objects to a new store, per object vs. in a batch, via
+// "go test ./gslb/test/store -run xxx -bench AddOrUpdate -benchmem -count 3" (linux/amd64, 1 CPU):
+//
+//	BenchmarkAddOrUpdate         2.69-2.89 ms/op    1710856 B/op    359 allocs/op
+//	BenchmarkAddOrUpdateBatch    2.07-2.42 ms/op    1716232 B/op    360 allocs/op
+//
+// The batch takes the lock of the store once, which saves about 20% of the time for the initial
+// sync of a large cluster.
+func BenchmarkAddOrUpdate(b *testing.B) {
+	items := getStoreItems(NumObjs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cs := gslbutils.NewClusterStore()
+		for _, item := range items {
+			cs.AddOrUpdate(item.Obj, TestCluster, item.Namespace, item.ObjName)
+		}
+	}
+}
+
+func BenchmarkAddOrUpdateBatch(b *testing.B) {
+	items := getStoreItems(NumObjs)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cs := gslbutils.NewClusterStore()
+		cs.AddOrUpdateBatch(TestCluster, items)
+	}
+}
+
+func TestDeleteFromIngressStore(t *testing.T) {
+	cname := "ing-delete-cluster"
+	store := gslbutils.NewClusterStore()
+	ingHost := k8sobjects.IngressHostMeta{
+		Cluster:   cname,
+		IngName:   "ing1",
+		Namespace: "default",
+		Hostname:  "ing1.avi.com",
+		ObjName:   "ing1/ing1.avi.com",
+	}
+	ingestion.AddOrUpdateIngressStore(store, ingHost, cname)
+	if _, ok := store.GetClusterNSObjectByName(cname, ingHost.Namespace, ingHost.ObjName); !ok {
+		t.Fatalf("expected the ingress host to be added to the store")
+	}
+	if !ingestion.DeleteFromIngressStore(store, ingHost, cname) {
+		t.Fatalf("expected the ingress host to be present in the store while deleting")
+	}
+	if _, ok := store.GetClusterNSObjectByName(cname, ingHost.Namespace, ingHost.ObjName); ok {
+		t.Fatalf("expected the ingress host to be removed from the store")
+	}
+	if ingestion.DeleteFromIngressStore(store, ingHost, cname) {
+		t.Fatalf("expected the ingress host to be absent from the store after the delete")
+	}
+}
+
+func TestStoreLastUpdated(t *testing.T) {
+	cs := gslbutils.NewClusterStore()
+	before := time.Now()
+	cs.AddOrUpdate(1, TestCluster, "ns1", "obj1")
+	cs.AddOrUpdateBatch(TestCluster, []gslbutils.StoreItem{{Namespace: "ns1", ObjName: "obj0", Obj: 0}})
+	firstUpdate, ok := cs.GetClusterNSObjectLastUpdated(TestCluster, "ns1", "obj1")
+	if !ok || firstUpdate.Before(before) {
+		t.Fatalf("expected the last updated time of the object to be set, got %v", firstUpdate)
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	cs.AddOrUpdate(2, TestCluster, "ns1", "obj1")
+	lastUpdate, _ := cs.GetClusterNSObjectLastUpdated(TestCluster, "ns1", "obj1")
+	if !lastUpdate.After(firstUpdate) {
+		t.Fatalf("expected the last updated time to move ahead with the update, got %v", lastUpdate)
+	}
+
+	snapshot := cs.GetSnapshot()
+	if len(snapshot) != 2 || snapshot[0].Name != "obj0" || snapshot[1].Name != "obj1" {
+		t.Fatalf("expected a sorted snapshot of the objects, got %+v", snapshot)
+	}
+	if snapshot[1].Obj.(int) != 2 || !snapshot[1].LastUpdated.Equal(lastUpdate) || snapshot[0].LastUpdated.IsZero() {
+		t.Fatalf("expected the objects along with their last updated times, got %+v", snapshot)
+	}
+
+	cs.DeleteClusterNSObj(TestCluster, "ns1", "obj1")
+	if _, ok := cs.GetClusterNSObjectLastUpdated(TestCluster, "ns1", "obj1"); ok {
+		t.Fatalf("expected no last updated time for a deleted object")
+	}
+}
+
+func expectStoreEvent(t *testing.T, sub <-chan gslbutils.StoreEvent, evType, ns, name string) {
+	select {
+	case ev := <-sub:
+		if ev.Type != evType || ev.Cluster != TestCluster || ev.Namespace != ns || ev.Name != name {
+			t.Fatalf("expected event %s for %s/%s/%s, got %+v", evType, TestCluster, ns, name, ev)
+		}
+	default:
+		t.Fatalf("expected event %s for %s/%s/%s, got none", evType, TestCluster, ns, name)
+	}
+}
+
+func TestClusterStoreSubscribe(t *testing.T) {
+	cs := gslbutils.NewClusterStore()
+	sub1 := cs.Subscribe()
+	sub2 := cs.Subscribe()
+
+	cs.AddOrUpdate(1, TestCluster, "default", "obj1")
+	cs.AddOrUpdate(2, TestCluster, "default", "obj1")
+	cs.AddOrUpdateBatch(TestCluster, []gslbutils.StoreItem{{Namespace: "default", ObjName: "obj2", Obj: 3}})
+	cs.DeleteClusterNSObj(TestCluster, "default", "obj1")
+	// deleting an absent object shouldn't publish anything
+	cs.DeleteClusterNSObj(TestCluster, "default", "obj1")
+
+	for _, sub := range []<-chan gslbutils.StoreEvent{sub1, sub2} {
+		expectStoreEvent(t, sub, gslbutils.StoreEventAdd, "default", "obj1")
+		expectStoreEvent(t, sub, gslbutils.StoreEventUpdate, "default", "obj1")
+		expectStoreEvent(t, sub, gslbutils.StoreEventAdd, "default", "obj2")
+		expectStoreEvent(t, sub, gslbutils.StoreEventDelete, "default", "obj1")
+		if len(sub) != 0 {
+			t.Fatalf("expected no more events, got %d", len(sub))
+		}
+	}
+
+	// a subscriber which doesn't drain its channel shouldn't block the store
+	cs.Unsubscribe(sub2)
+	if _, ok := <-sub2; ok {
+		t.Fatalf("expected the unsubscribed channel to be closed")
+	}
+	cs.AddOrUpdateBatch(TestCluster, getStoreItems(gslbutils.StoreEventBufferSize+10))
+	if len(sub1) != gslbutils.StoreEventBufferSize {
+		t.Fatalf("expected a full buffer of %d events, got %d", gslbutils.StoreEventBufferSize, len(sub1))
+	}
+	if cs.DroppedEvents() != 10 {
+		t.Fatalf("expected 10 dropped events, got %d", cs.DroppedEvents())
+	}
+}
+
+// Unit test to see if the events of the concurrent writers of an object are published in the order
+// of their changes, i.e. the last event has the object in the store.
+func TestClusterStoreEventOrder(t *testing.T) {
+	cs := gslbutils.NewClusterStore()
+	sub := cs.Subscribe()
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(val int) {
+			defer wg.Done()
+			cs.AddOrUpdate(val, TestCluster, "default", "obj1")
+		}(i)
+	}
+	wg.Wait()
+
+	var last gslbutils.StoreEvent
+	for len(sub) > 0 {
+		last = <-sub
+	}
+	obj, ok := cs.GetClusterNSObjectByName(TestCluster, "default", "obj1")
+	if !ok || last.Obj != obj {
+		t.Fatalf("expected the last event to have the object in the store %v, got %v", obj, last.Obj)
+	}
+}
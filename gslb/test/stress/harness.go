@@ -0,0 +1,65 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package stress
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sync"
+)
+
+// Worker is an operation run concurrently by the harness, in Goroutines goroutines with
+// Iterations calls of Op each. Op gets the index of its goroutine and the iteration.
+type Worker struct {
+	Name       string
+	Goroutines int
+	Iterations int
+	Op         func(goroutine, iteration int)
+}
+
+// Run runs all the goroutines of all the workers at once, and waits for them to finish. All the
+// goroutines start together, so that the operations of the different workers overlap as much as
+// possible. A panic in a goroutine stops that goroutine, and is returned as an error along with the
+// stack. Run it with the race detector (go test -race) to catch the data races as well.
+func Run(workers ...Worker) []error {
+	var wg sync.WaitGroup
+	var errLock sync.Mutex
+	var errs []error
+	start := make(chan struct{})
+
+	for _, w := range workers {
+		for g := 0; g < w.Goroutines; g++ {
+			wg.Add(1)
+			go func(w Worker, g int) {
+				defer wg.Done()
+				defer func() {
+					if r := recover(); r != nil {
+						errLock.Lock()
+						errs = append(errs, fmt.Errorf("worker: %s, goroutine: %d, panic: %v\n%s", w.Name, g, r,
+							debug.Stack()))
+						errLock.Unlock()
+					}
+				}()
+				<-start
+				for i := 0; i < w.Iterations; i++ {
+					w.Op(g, i)
+				}
+			}(w, g)
+		}
+	}
+	close(start)
+	wg.Wait()
+	return errs
+}
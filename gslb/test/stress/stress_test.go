@@ -0,0 +1,223 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package stress
+
+import (
+	"strconv"
+	"testing"
+
+	filter "github.com/avinetworks/amko/gslb/gdp_filter"
+	"github.com/avinetworks/amko/gslb/gslbutils"
+	"github.com/avinetworks/amko/gslb/k8sobjects"
+	gdpv1alpha1 "github.com/avinetworks/amko/internal/apis/amko/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	Cluster1   = "cluster1"
+	Cluster2   = "cluster2"
+	NumNS      = 4
+	NumObjs    = 200
+	NumWriters = 4
+	Iterations = 500
+)
+
+var clusters = []string{Cluster1, Cluster2}
+
+func getTestGDP(app string, weight uint32) *gdpv1alpha1.GlobalDeploymentPolicy {
+	return &gdpv1alpha1.GlobalDeploymentPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: gslbutils.AVISystem,
+			Name:      "test-gdp",
+		},
+		Spec: gdpv1alpha1.GDPSpec{
+			MatchRules: gdpv1alpha1.MatchRules{
+				AppSelector: gdpv1alpha1.AppSelector{
+					Label: map[string]string{"app": app},
+				},
+			},
+			MatchClusters: clusters,
+			TrafficSplit: []gdpv1alpha1.TrafficSplitElem{
+				{Cluster: Cluster1, Weight: weight},
+				{Cluster: Cluster2, Weight: 1},
+			},
+		},
+	}
+}
+
+// getTestRoute returns the route meta for the object idx of cluster cname, the even objects have
+// the label app=a and the odd ones app=b.
+func getTestRoute(cname string, idx int) k8sobjects.RouteMeta {
+	app := "a"
+	if idx%2 == 1 {
+		app = "b"
+	}
+	name := "route-" + strconv.Itoa(idx)
+	return k8sobjects.RouteMeta{
+		Cluster:   cname,
+		Name:      name,
+		Namespace: "ns-" + strconv.Itoa(idx%NumNS),
+		Hostname:  name + ".avi.com",
+		IPAddr:    "10.10.10." + strconv.Itoa(idx%250),
+		Labels:    map[string]string{"app": app},
+	}
+}
+
+// writerOp returns the object and the operation of the writer goroutine g in iteration i, every
+// third operation is a delete. Each goroutine owns a disjoint set of objects, so that the final
+// state of the store is known.
+func writerOp(g, i int) (idx int, cname string, del bool) {
+	idx = g + NumWriters*(i%(NumObjs/NumWriters))
+	return idx, clusters[i%len(clusters)], i%3 == 2
+}
+
+// Test concurrent updates of a cluster store with the filter being applied to its objects and the
+// GDP object being updated, followed by the checks of the final state of the store and the filter.
+// Run with -race to catch the data races.
+func TestConcurrentStoreAndFilter(t *testing.T) {
+	gslbutils.ResetGlobalFilter()
+	defer gslbutils.ResetGlobalFilter()
+	gf := gslbutils.GetGlobalFilter()
+	gdpA, gdpB := getTestGDP("a", 5), getTestGDP("b", 10)
+	// gdpB selects the namespaces as well, so that the namespace filter is swapped by the updates
+	gdpB.Spec.MatchRules.NamespaceSelector.Label = map[string]string{"team": "b"}
+	gf.AddToFilter(gdpA)
+	cs := gslbutils.NewClusterStore()
+
+	errs := Run(
+		Worker{
+			Name:       "store writer",
+			Goroutines: NumWriters,
+			Iterations: Iterations,
+			Op: func(g, i int) {
+				idx, cname, del := writerOp(g, i)
+				route := getTestRoute(cname, idx)
+				if del {
+					cs.DeleteClusterNSObj(cname, route.Namespace, route.Name)
+					return
+				}
+				cs.AddOrUpdate(route, cname, route.Namespace, route.Name)
+			},
+		},
+		Worker{
+			Name:       "filter",
+			Goroutines: 4,
+			Iterations: Iterations,
+			Op: func(g, i int) {
+				cname := clusters[(g+i)%len(clusters)]
+				route := getTestRoute(cname, i%NumObjs)
+				if obj, ok := cs.GetClusterNSObjectByName(cname, route.Namespace, route.Name); ok {
+					filter.ApplyFilter(obj, cname)
+				}
+				gf.GetTrafficWeight(route.Namespace, cname, route.Labels, 0)
+			},
+		},
+		Worker{
+			Name:       "store reader",
+			Goroutines: 2,
+			Iterations: Iterations / 10,
+			Op: func(g, i int) {
+				cs.GetAllFilteredClusterNSObjects(filter.ApplyFilter)
+				cs.GetAllClusterNSObjects()
+			},
+		},
+		Worker{
+			Name:       "gdp updater",
+			Goroutines: 2,
+			Iterations: Iterations / 5,
+			Op: func(g, i int) {
+				if (g+i)%2 == 0 {
+					gf.UpdateGlobalFilter(gdpA, gdpB)
+				} else {
+					gf.UpdateGlobalFilter(gdpB, gdpA)
+				}
+			},
+		},
+		Worker{
+			Name:       "namespace filter",
+			Goroutines: 2,
+			Iterations: Iterations,
+			Op: func(g, i int) {
+				gf.AddNSToNSFilter(clusters[(g+i)%len(clusters)], "ns-"+strconv.Itoa(i%NumNS))
+				gf.GetNamespacesForCluster(clusters[(g+i)%len(clusters)])
+			},
+		},
+		Worker{
+			Name:       "gdp delete",
+			Goroutines: 1,
+			Iterations: Iterations / 10,
+			Op: func(g, i int) {
+				gf.DeleteFromGlobalFilter(gdpA)
+				gf.AddToFilter(gdpA)
+			},
+		},
+		Worker{
+			Name:       "filter view",
+			Goroutines: 2,
+			Iterations: Iterations / 10,
+			Op: func(g, i int) {
+				gf.GetView()
+				gf.Explain(gslbutils.RouteType, Cluster1, "ns-0", map[string]string{"app": "a"})
+			},
+		},
+	)
+	for _, err := range errs {
+		t.Error(err)
+	}
+	if len(errs) != 0 {
+		t.FailNow()
+	}
+
+	// the last operation on each object decides whether it is in the store
+	expected := make(map[string]bool)
+	for g := 0; g < NumWriters; g++ {
+		for i := 0; i < Iterations; i++ {
+			idx, cname, del := writerOp(g, i)
+			route := getTestRoute(cname, idx)
+			expected[gslbutils.GetClusterKey(cname, route.Namespace, route.Name)] = !del
+		}
+	}
+	for key, present := range expected {
+		cname, ns, name, _ := gslbutils.SplitMultiClusterObjectName(key)
+		if _, ok := cs.GetClusterNSObjectByName(cname, ns, name); ok != present {
+			t.Errorf("object %s, expected present: %t, got: %t", key, present, ok)
+		}
+	}
+
+	// the filter must be consistent with the last GDP object applied
+	gf.UpdateGlobalFilter(gdpB, gdpA)
+	if w, err := gf.GetTrafficWeight("ns-0", Cluster1, nil, 0); err != nil || w != 5 {
+		t.Fatalf("expected the weight 5 for %s, got %d, err: %v", Cluster1, w, err)
+	}
+	accepted, rejected := cs.GetAllFilteredClusterNSObjects(filter.ApplyFilter)
+	if len(accepted)+len(rejected) != len(cs.GetAllClusterNSObjects()) {
+		t.Fatalf("expected all the objects to be filtered, accepted: %d, rejected: %d", len(accepted),
+			len(rejected))
+	}
+	for _, key := range accepted {
+		cname, ns, name, _ := gslbutils.SplitMultiClusterObjectName(key)
+		obj, _ := cs.GetClusterNSObjectByName(cname, ns, name)
+		if obj.(k8sobjects.RouteMeta).Labels["app"] != "a" {
+			t.Errorf("object %s accepted, expected to be rejected by the app selector", key)
+		}
+	}
+	for _, key := range rejected {
+		cname, ns, name, _ := gslbutils.SplitMultiClusterObjectName(key)
+		obj, _ := cs.GetClusterNSObjectByName(cname, ns, name)
+		if obj.(k8sobjects.RouteMeta).Labels["app"] == "a" {
+			t.Errorf("object %s rejected, expected to be accepted by the app selector", key)
+		}
+	}
+}
@@ -48,6 +48,9 @@ type GSLBConfigSpec struct {
 	MemberClusters  []MemberCluster `json:"memberClusters,omitempty"`
 	RefreshInterval int             `json:"refreshInterval,omitempty"`
 	LogLevel        string          `json:"logLevel,omitempty"`
+	// GSLBDomains are the DNS subdomains under which the GSLB services are allowed, all the
+	// hostnames are allowed if empty
+	GSLBDomains []string `json:"gslbDomains,omitempty"`
 }
 
 // GSLBLeader is the leader node in the GSLB cluster
@@ -60,11 +63,22 @@ type GSLBLeader struct {
 // MemberCluster defines a GSLB member cluster details
 type MemberCluster struct {
 	ClusterContext string `json:"clusterContext,omitempty"`
+	// Region is the location of the cluster, used to prefer the members in the same region
+	Region string `json:"region,omitempty"`
+	// Tenant is the Avi tenant of the GSLB services of the cluster, the admin tenant if empty
+	Tenant string `json:"tenant,omitempty"`
+	// ObjectTypes are the types of the objects (ROUTE, INGRESS, LBSVC, HTTPROUTE) watched in the
+	// cluster, only the informers of these types are run. The types are detected from the APIs
+	// served by the cluster if empty.
+	ObjectTypes []string `json:"objectTypes,omitempty"`
 }
 
 // GSLBConfigStatus represents the state and status message of the GSLB cluster
 type GSLBConfigStatus struct {
 	State string `json:"state,omitempty"`
+	// InformerErrors are the last errors of the informers of the member clusters which can't list
+	// or watch their objects, e.g. "route informer for cluster-eu: forbidden"
+	InformerErrors []string `json:"informerErrors,omitempty"`
 }
 
 // how the Global services are going to be named
@@ -113,30 +127,110 @@ type GDPSpec struct {
 	MatchRules    MatchRules         `json:"matchRules,omitempty"`
 	MatchClusters []string           `json:"matchClusters,omitempty"`
 	TrafficSplit  []TrafficSplitElem `json:"trafficSplit,omitempty"`
+	TrafficRules  []TrafficRule      `json:"trafficRules,omitempty"`
+	// WeightMode decides how the weights of the traffic splits are interpreted, "weight" (default)
+	// for the relative weights and "percentage" for the percentages which must sum to 100. With
+	// "backends", the weights of the clusters are computed from their ready backends, and the
+	// traffic splits are used for the clusters whose backends can't be counted.
+	WeightMode string `json:"weightMode,omitempty"`
+	// WeightRecomputeInterval is the interval (in seconds) at which the weights of the clusters are
+	// recomputed in the "backends" weight mode
+	WeightRecomputeInterval *int `json:"weightRecomputeInterval,omitempty"`
+	// MemberRemovalGracePeriod is the time (in seconds) for which the GSLB member of a deleted
+	// object is retained, the member isn't removed if the object reappears within this period
+	MemberRemovalGracePeriod *int `json:"memberRemovalGracePeriod,omitempty"`
+	// HostOnlyObjectTypes are the object types (ROUTE, INGRESS and HTTPROUTE) for which the objects
+	// without any paths are advertised with the host only, instead of the default path "/"
+	HostOnlyObjectTypes []string `json:"hostOnlyObjectTypes,omitempty"`
+	// MinHealthyClusters is the minimum number of member clusters with healthy members for a GSLB
+	// service to be advertised, the GSLB services with fewer healthy clusters are withheld. A
+	// cluster is healthy for a GSLB service if any of its members has a non-zero weight. All the
+	// GSLB services are advertised if nil or 0.
+	MinHealthyClusters *int `json:"minHealthyClusters,omitempty"`
 }
 
+// Modes for the weights of the traffic splits
+const (
+	WeightModeWeight     = "weight"
+	WeightModePercentage = "percentage"
+	WeightModeBackends   = "backends"
+)
+
 // MatchRules is the match criteria needed to select the kubernetes/openshift objects.
 type MatchRules struct {
 	AppSelector       `json:"appSelector,omitempty"`
 	NamespaceSelector `json:"namespaceSelector,omitempty"`
+	// RequireReady selects only the objects which are ready, i.e., routes admitted by their router
+	// and ingresses with a status populated by their ingress controller.
+	RequireReady bool `json:"requireReady,omitempty"`
+	// PortNames selects the ports of the LoadBalancer services by their names, the GSLB member of
+	// a service uses the port named first in this list, and the services without any of these ports
+	// are not selected. All the ports qualify if empty.
+	PortNames []string `json:"portNames,omitempty"`
+	// ObjectTypes selects the objects by their types (ROUTE, INGRESS, HTTPROUTE and LBSVC), the
+	// objects of the other types are not selected. All the types qualify if empty.
+	ObjectTypes []string `json:"objectTypes,omitempty"`
+	// SelfScope restricts the selectors to the objects in the namespace of the GDP object, across
+	// all the applicable clusters. The objects in this namespace are selected without an
+	// appSelector or a namespaceSelector, and the selectors further narrow down these objects.
+	SelfScope bool `json:"selfScope,omitempty"`
+	// MinAge is the minimum age (in seconds) of an object for it to be advertised, the younger
+	// objects are deferred till they are old enough. The default min age applies if nil.
+	MinAge *int `json:"minAge,omitempty"`
+	// SelectorMode combines the appSelector and the namespaceSelector, if both are set. For "AND"
+	// (default), an object must be in a selected namespace and match the appSelector. For
+	// "NamespaceOverride", the objects in the selected namespaces are selected regardless of their
+	// labels, and the objects in the other namespaces are rejected.
+	SelectorMode string `json:"selectorMode,omitempty"`
 }
 
 // AppSelector selects the applications based on their labels
 type AppSelector struct {
 	Label map[string]string `json:"label,omitempty"`
+	// IgnoreCase matches the label values case-insensitively, the keys are always matched exactly
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
 }
 
 // NamespaceSelector selects the applications based on their labels
 type NamespaceSelector struct {
 	Label map[string]string `json:"label,omitempty"`
+	// Operator combines the label pairs, a namespace must have all the pairs for "AND" (default)
+	// and any of the pairs for "OR"
+	Operator string `json:"operator,omitempty"`
+	// IgnoreCase matches the label values case-insensitively, the keys are always matched exactly
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
+	// Scope is "Cluster" (default), where a namespace is selected only in the clusters in which it
+	// has the labels, or "Global", where a namespace with the labels in any cluster selects the
+	// namespaces with the same name in all the clusters
+	Scope string `json:"scope,omitempty"`
 }
 
+// Scopes of the namespace selector
+const (
+	NamespaceScopeCluster = "Cluster"
+	NamespaceScopeGlobal  = "Global"
+)
+
+// Modes for combining the appSelector and the namespaceSelector
+const (
+	SelectorModeAnd               = "AND"
+	SelectorModeNamespaceOverride = "NamespaceOverride"
+)
+
+// Operators for combining the label pairs of a selector
+const (
+	LabelOperatorAnd = "AND"
+	LabelOperatorOr  = "OR"
+)
+
 // Objects on which rules will be applied
 const (
 	// RouteObj only applies to openshift Routes
 	RouteObj = "ROUTE"
 	// IngressObj applies to K8S Ingresses
 	IngressObj = "INGRESS"
+	// HTTPRouteObj applies to Gateway API HTTPRoutes
+	HTTPRouteObj = "HTTPROUTE"
 	// LBSvc applies to service type LoadBalancer
 	LBSvcObj = "LBSVC"
 	// NSObj applies to namespaces
@@ -148,9 +242,82 @@ type TrafficSplitElem struct {
 	// Cluster is the cluster context
 	Cluster string `json:"cluster,omitempty"`
 	Weight  uint32 `json:"weight,omitempty"`
+	// Priority groups the clusters into tiers, the traffic is routed to a lower priority tier
+	// only if the higher priority tiers have no healthy members.
+	Priority int `json:"priority,omitempty"`
+}
+
+// TrafficRule determines the traffic split for the applications selected via its AppSelector.
+// Applications not selected by any of the rules follow the TrafficSplit of the GDP.
+type TrafficRule struct {
+	AppSelector  `json:"appSelector,omitempty"`
+	TrafficSplit []TrafficSplitElem `json:"trafficSplit,omitempty"`
 }
 
 // GDPStatus gives the current status of the policy object.
 type GDPStatus struct {
 	ErrorStatus string `json:"errorStatus,omitempty"`
+	// Warnings are the conditions which don't stop the GDP object from being applied, but need
+	// the attention of the operators, e.g. the member clusters which aren't connected yet
+	Warnings []string `json:"warnings,omitempty"`
+	// MinHealthyClusters is the minimum number of healthy clusters of the GSLB services, as set in
+	// the spec
+	MinHealthyClusters int `json:"minHealthyClusters,omitempty"`
+	// WithheldServices are the GSLB services withheld for having fewer healthy clusters than
+	// MinHealthyClusters
+	WithheldServices []WithheldService `json:"withheldServices,omitempty"`
+}
+
+// WithheldService is a GSLB service which isn't advertised for having fewer healthy clusters than
+// the MinHealthyClusters of the GDP object.
+type WithheldService struct {
+	Name            string `json:"name"`
+	Tenant          string `json:"tenant,omitempty"`
+	HealthyClusters int    `json:"healthyClusters"`
+}
+
+// +genclient
+// +genclient:noStatus
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +k8s:openapi-gen=true
+
+// HostOverride overrides the GSLB service parameters for a single hostname.
+type HostOverride struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+	// spec for the HostOverride
+	Spec   HostOverrideSpec   `json:"spec,omitempty"`
+	Status HostOverrideStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HostOverrideList is a list of HostOverride objects.
+type HostOverrideList struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []HostOverride `json:"items"`
+}
+
+// HostOverrideSpec defines the GSLB service parameters for the hostname Fqdn. The parameters
+// which are not set, retain their default values.
+type HostOverrideSpec struct {
+	Fqdn string `json:"fqdn,omitempty"`
+	// TTL is the time to live (in seconds) for the DNS responses of the GSLB service
+	TTL *int `json:"ttl,omitempty"`
+	// HealthMonitorRefs are the names of the health monitors to be used for the GSLB service,
+	// instead of the health monitors created by AMKO
+	HealthMonitorRefs []string `json:"healthMonitorRefs,omitempty"`
+	// PoolAlgorithm is the load balancing algorithm for the members of the GSLB service pool
+	PoolAlgorithm string `json:"poolAlgorithm,omitempty"`
+	// Aliases are the additional hostnames of the GSLB service of Fqdn, these are added as the
+	// domain names of the same GSLB service instead of separate GSLB services
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// HostOverrideStatus gives the current status of the HostOverride object.
+type HostOverrideStatus struct {
+	ErrorStatus string `json:"errorStatus,omitempty"`
 }
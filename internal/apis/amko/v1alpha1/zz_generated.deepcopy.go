@@ -61,6 +61,33 @@ func (in *GDPSpec) DeepCopyInto(out *GDPSpec) {
 		*out = make([]TrafficSplitElem, len(*in))
 		copy(*out, *in)
 	}
+	if in.TrafficRules != nil {
+		in, out := &in.TrafficRules, &out.TrafficRules
+		*out = make([]TrafficRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WeightRecomputeInterval != nil {
+		in, out := &in.WeightRecomputeInterval, &out.WeightRecomputeInterval
+		*out = new(int)
+		**out = **in
+	}
+	if in.MemberRemovalGracePeriod != nil {
+		in, out := &in.MemberRemovalGracePeriod, &out.MemberRemovalGracePeriod
+		*out = new(int)
+		**out = **in
+	}
+	if in.HostOnlyObjectTypes != nil {
+		in, out := &in.HostOnlyObjectTypes, &out.HostOnlyObjectTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MinHealthyClusters != nil {
+		in, out := &in.MinHealthyClusters, &out.MinHealthyClusters
+		*out = new(int)
+		**out = **in
+	}
 	return
 }
 
@@ -77,6 +104,16 @@ func (in *GDPSpec) DeepCopy() *GDPSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GDPStatus) DeepCopyInto(out *GDPStatus) {
 	*out = *in
+	if in.Warnings != nil {
+		in, out := &in.Warnings, &out.Warnings
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.WithheldServices != nil {
+		in, out := &in.WithheldServices, &out.WithheldServices
+		*out = make([]WithheldService, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -96,7 +133,7 @@ func (in *GSLBConfig) DeepCopyInto(out *GSLBConfig) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -158,6 +195,13 @@ func (in *GSLBConfigSpec) DeepCopyInto(out *GSLBConfigSpec) {
 	if in.MemberClusters != nil {
 		in, out := &in.MemberClusters, &out.MemberClusters
 		*out = make([]MemberCluster, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.GSLBDomains != nil {
+		in, out := &in.GSLBDomains, &out.GSLBDomains
+		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
 	return
@@ -209,6 +253,11 @@ func (in *GSLBConfigSpecList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GSLBConfigStatus) DeepCopyInto(out *GSLBConfigStatus) {
 	*out = *in
+	if in.InformerErrors != nil {
+		in, out := &in.InformerErrors, &out.InformerErrors
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -244,7 +293,7 @@ func (in *GlobalDeploymentPolicy) DeepCopyInto(out *GlobalDeploymentPolicy) {
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 	return
 }
 
@@ -299,11 +348,134 @@ func (in *GlobalDeploymentPolicyList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostOverride) DeepCopyInto(out *HostOverride) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostOverride.
+func (in *HostOverride) DeepCopy() *HostOverride {
+	if in == nil {
+		return nil
+	}
+	out := new(HostOverride)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostOverride) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostOverrideList) DeepCopyInto(out *HostOverrideList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]HostOverride, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostOverrideList.
+func (in *HostOverrideList) DeepCopy() *HostOverrideList {
+	if in == nil {
+		return nil
+	}
+	out := new(HostOverrideList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *HostOverrideList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostOverrideSpec) DeepCopyInto(out *HostOverrideSpec) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(int)
+		**out = **in
+	}
+	if in.HealthMonitorRefs != nil {
+		in, out := &in.HealthMonitorRefs, &out.HealthMonitorRefs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Aliases != nil {
+		in, out := &in.Aliases, &out.Aliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostOverrideSpec.
+func (in *HostOverrideSpec) DeepCopy() *HostOverrideSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(HostOverrideSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostOverrideStatus) DeepCopyInto(out *HostOverrideStatus) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostOverrideStatus.
+func (in *HostOverrideStatus) DeepCopy() *HostOverrideStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HostOverrideStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MatchRules) DeepCopyInto(out *MatchRules) {
 	*out = *in
 	in.AppSelector.DeepCopyInto(&out.AppSelector)
 	in.NamespaceSelector.DeepCopyInto(&out.NamespaceSelector)
+	if in.PortNames != nil {
+		in, out := &in.PortNames, &out.PortNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ObjectTypes != nil {
+		in, out := &in.ObjectTypes, &out.ObjectTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MinAge != nil {
+		in, out := &in.MinAge, &out.MinAge
+		*out = new(int)
+		**out = **in
+	}
 	return
 }
 
@@ -320,6 +492,11 @@ func (in *MatchRules) DeepCopy() *MatchRules {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *MemberCluster) DeepCopyInto(out *MemberCluster) {
 	*out = *in
+	if in.ObjectTypes != nil {
+		in, out := &in.ObjectTypes, &out.ObjectTypes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 	return
 }
 
@@ -356,6 +533,28 @@ func (in *NamespaceSelector) DeepCopy() *NamespaceSelector {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TrafficRule) DeepCopyInto(out *TrafficRule) {
+	*out = *in
+	in.AppSelector.DeepCopyInto(&out.AppSelector)
+	if in.TrafficSplit != nil {
+		in, out := &in.TrafficSplit, &out.TrafficSplit
+		*out = make([]TrafficSplitElem, len(*in))
+		copy(*out, *in)
+	}
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TrafficRule.
+func (in *TrafficRule) DeepCopy() *TrafficRule {
+	if in == nil {
+		return nil
+	}
+	out := new(TrafficRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TrafficSplitElem) DeepCopyInto(out *TrafficSplitElem) {
 	*out = *in
@@ -371,3 +570,19 @@ func (in *TrafficSplitElem) DeepCopy() *TrafficSplitElem {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WithheldService) DeepCopyInto(out *WithheldService) {
+	*out = *in
+	return
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WithheldService.
+func (in *WithheldService) DeepCopy() *WithheldService {
+	if in == nil {
+		return nil
+	}
+	out := new(WithheldService)
+	in.DeepCopyInto(out)
+	return out
+}
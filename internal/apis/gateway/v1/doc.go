@@ -0,0 +1,20 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+// Package v1 has a read-only subset of the Gateway API (gateway.networking.k8s.io/v1) types,
+// the fields which AMKO needs to build the GSLB members for the HTTPRoutes.
+package v1
+
+// +k8s:deepcopy-gen=package
+// +groupName=gateway.networking.k8s.io
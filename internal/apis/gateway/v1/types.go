@@ -0,0 +1,151 @@
+/*
+ * Copyright 2019-2020 VMware, Inc.
+ * All Rights Reserved.
+* Licensed under the Apache License, Version 2.0 (the "License");
+* you may not use this file except in compliance with the License.
+* You may obtain a copy of the License at
+*   http://www.apache.org/licenses/LICENSE-2.0
+* Unless required by applicable law or agreed to in writing, software
+* distributed under the License is distributed on an "AS IS" BASIS,
+* WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+* See the License for the specific language governing permissions and
+* limitations under the License.
+*/
+
+package v1
+
+import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+const (
+	// KindGateway is the kind of the Gateway objects, the default kind of the parents of a route
+	KindGateway = "Gateway"
+	// AddressTypeIPAddress is the type of the Gateway addresses which are IP addresses
+	AddressTypeIPAddress = "IPAddress"
+	// HTTPSProtocolType is the protocol of the Gateway listeners which terminate TLS
+	HTTPSProtocolType = "HTTPS"
+)
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// Gateway represents an instance of a service-traffic handling infrastructure, only its
+// listeners and the addresses in its status are read.
+type Gateway struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec GatewaySpec `json:"spec"`
+	// +optional
+	Status GatewayStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// GatewayList is a list of Gateway resources
+type GatewayList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []Gateway `json:"items"`
+}
+
+// GatewaySpec is the desired state of a Gateway.
+type GatewaySpec struct {
+	GatewayClassName string `json:"gatewayClassName"`
+	// Listeners are the logical endpoints bound on the addresses of the Gateway
+	Listeners []Listener `json:"listeners"`
+}
+
+// Listener is a logical endpoint of a Gateway.
+type Listener struct {
+	Name string `json:"name"`
+	// Hostname restricts the hostnames served by the listener, all the hostnames are served if
+	// not set
+	// +optional
+	Hostname *string `json:"hostname,omitempty"`
+	Port     int32   `json:"port"`
+	Protocol string  `json:"protocol"`
+}
+
+// GatewayStatus is the observed state of a Gateway.
+type GatewayStatus struct {
+	// Addresses are the network addresses bound to the Gateway
+	// +optional
+	Addresses []GatewayStatusAddress `json:"addresses,omitempty"`
+}
+
+// GatewayStatusAddress is a network address bound to a Gateway.
+type GatewayStatusAddress struct {
+	// Type of the address, IPAddress if not set
+	// +optional
+	Type  *string `json:"type,omitempty"`
+	Value string  `json:"value"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HTTPRoute routes the HTTP requests from a Gateway listener to the backends, only the parents,
+// the hostnames and the path matches are read.
+type HTTPRoute struct {
+	metav1.TypeMeta `json:",inline"`
+	// +optional
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec HTTPRouteSpec `json:"spec"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// HTTPRouteList is a list of HTTPRoute resources
+type HTTPRouteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []HTTPRoute `json:"items"`
+}
+
+// HTTPRouteSpec is the desired state of an HTTPRoute.
+type HTTPRouteSpec struct {
+	// ParentRefs are the Gateways which the route attaches to
+	// +optional
+	ParentRefs []ParentReference `json:"parentRefs,omitempty"`
+	// Hostnames are matched against the Host header of the requests
+	// +optional
+	Hostnames []string `json:"hostnames,omitempty"`
+	// +optional
+	Rules []HTTPRouteRule `json:"rules,omitempty"`
+}
+
+// ParentReference identifies a parent of a route, a Gateway by default.
+type ParentReference struct {
+	// +optional
+	Group *string `json:"group,omitempty"`
+	// +optional
+	Kind *string `json:"kind,omitempty"`
+	// Namespace of the parent, the namespace of the route if not set
+	// +optional
+	Namespace *string `json:"namespace,omitempty"`
+	Name      string  `json:"name"`
+	// +optional
+	SectionName *string `json:"sectionName,omitempty"`
+}
+
+// HTTPRouteRule is a rule of an HTTPRoute, only its matches are read.
+type HTTPRouteRule struct {
+	// +optional
+	Matches []HTTPRouteMatch `json:"matches,omitempty"`
+}
+
+// HTTPRouteMatch is a predicate for the requests of a rule.
+type HTTPRouteMatch struct {
+	// +optional
+	Path *HTTPPathMatch `json:"path,omitempty"`
+}
+
+// HTTPPathMatch matches the path of the requests.
+type HTTPPathMatch struct {
+	// +optional
+	Type *string `json:"type,omitempty"`
+	// +optional
+	Value *string `json:"value,omitempty"`
+}
@@ -29,6 +29,7 @@ type AmkoV1alpha1Interface interface {
 	RESTClient() rest.Interface
 	GSLBConfigsGetter
 	GlobalDeploymentPoliciesGetter
+	HostOverridesGetter
 }
 
 // AmkoV1alpha1Client is used to interact with features provided by the amko.vmware.com group.
@@ -44,6 +45,10 @@ func (c *AmkoV1alpha1Client) GlobalDeploymentPolicies(namespace string) GlobalDe
 	return newGlobalDeploymentPolicies(c, namespace)
 }
 
+func (c *AmkoV1alpha1Client) HostOverrides(namespace string) HostOverrideInterface {
+	return newHostOverrides(c, namespace)
+}
+
 // NewForConfig creates a new AmkoV1alpha1Client for the given config.
 func NewForConfig(c *rest.Config) (*AmkoV1alpha1Client, error) {
 	config := *c
@@ -37,6 +37,10 @@ func (c *FakeAmkoV1alpha1) GlobalDeploymentPolicies(namespace string) v1alpha1.G
 	return &FakeGlobalDeploymentPolicies{c, namespace}
 }
 
+func (c *FakeAmkoV1alpha1) HostOverrides(namespace string) v1alpha1.HostOverrideInterface {
+	return &FakeHostOverrides{c, namespace}
+}
+
 // RESTClient returns a RESTClient that is used to communicate
 // with API server by this client implementation.
 func (c *FakeAmkoV1alpha1) RESTClient() rest.Interface {
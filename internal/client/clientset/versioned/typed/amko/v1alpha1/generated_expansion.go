@@ -21,3 +21,5 @@ package v1alpha1
 type GSLBConfigExpansion interface{}
 
 type GlobalDeploymentPolicyExpansion interface{}
+
+type HostOverrideExpansion interface{}
@@ -28,6 +28,8 @@ type Interface interface {
 	GSLBConfigs() GSLBConfigInformer
 	// GlobalDeploymentPolicies returns a GlobalDeploymentPolicyInformer.
 	GlobalDeploymentPolicies() GlobalDeploymentPolicyInformer
+	// HostOverrides returns a HostOverrideInformer.
+	HostOverrides() HostOverrideInformer
 }
 
 type version struct {
@@ -50,3 +52,8 @@ func (v *version) GSLBConfigs() GSLBConfigInformer {
 func (v *version) GlobalDeploymentPolicies() GlobalDeploymentPolicyInformer {
 	return &globalDeploymentPolicyInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
 }
+
+// HostOverrides returns a HostOverrideInformer.
+func (v *version) HostOverrides() HostOverrideInformer {
+	return &hostOverrideInformer{factory: v.factory, namespace: v.namespace, tweakListOptions: v.tweakListOptions}
+}
@@ -58,6 +58,8 @@ func (f *sharedInformerFactory) ForResource(resource schema.GroupVersionResource
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Amko().V1alpha1().GSLBConfigs().Informer()}, nil
 	case v1alpha1.SchemeGroupVersion.WithResource("globaldeploymentpolicies"):
 		return &genericInformer{resource: resource.GroupResource(), informer: f.Amko().V1alpha1().GlobalDeploymentPolicies().Informer()}, nil
+	case v1alpha1.SchemeGroupVersion.WithResource("hostoverrides"):
+		return &genericInformer{resource: resource.GroupResource(), informer: f.Amko().V1alpha1().HostOverrides().Informer()}, nil
 
 	}
 
@@ -33,3 +33,11 @@ type GlobalDeploymentPolicyListerExpansion interface{}
 // GlobalDeploymentPolicyNamespaceListerExpansion allows custom methods to be added to
 // GlobalDeploymentPolicyNamespaceLister.
 type GlobalDeploymentPolicyNamespaceListerExpansion interface{}
+
+// HostOverrideListerExpansion allows custom methods to be added to
+// HostOverrideLister.
+type HostOverrideListerExpansion interface{}
+
+// HostOverrideNamespaceListerExpansion allows custom methods to be added to
+// HostOverrideNamespaceLister.
+type HostOverrideNamespaceListerExpansion interface{}